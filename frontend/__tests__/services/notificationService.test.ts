@@ -40,7 +40,12 @@ jest.mock('../../services/contactService', () => ({
 
 jest.spyOn(Alert, 'alert').mockImplementation(() => {});
 
-const { notificationService } = require('../../services/notificationService');
+const {
+  notificationService,
+  isCoveredByReadSync,
+  parseReadSync,
+  setConversationReadHandler,
+} = require('../../services/notificationService');
 
 describe('notificationService video circle publish push', () => {
   beforeEach(() => {
@@ -123,3 +128,28 @@ describe('notificationService video circle publish push', () => {
     });
   });
 });
+
+describe('notificationService messages_read push', () => {
+  it('hands the read watermark to the handler instead of recording it', () => {
+    const onRead = jest.fn();
+    setConversationReadHandler(onRead);
+
+    notificationService.onMessageReceived({
+      data: { type: 'messages_read', chatType: 'room', roomId: '42', upToMessageId: '100' },
+    });
+
+    expect(onRead).toHaveBeenCalledWith({ roomId: 42, peerId: 0, upToMessageId: 100 });
+  });
+
+  it('matches message notifications up to the watermark', () => {
+    const roomSync = parseReadSync({ type: 'messages_read', roomId: '42', upToMessageId: '100' });
+    const peerSync = parseReadSync({ type: 'messages_read', peerId: '7', upToMessageId: '100' });
+
+    expect(isCoveredByReadSync({ type: 'room_message', roomId: '42', messageId: '100' }, roomSync)).toBe(true);
+    expect(isCoveredByReadSync({ type: 'room_message', roomId: '42', messageId: '101' }, roomSync)).toBe(false);
+    expect(isCoveredByReadSync({ type: 'room_message', roomId: '43', messageId: '90' }, roomSync)).toBe(false);
+    expect(isCoveredByReadSync({ type: 'new_message', senderId: '7', messageId: '90' }, peerSync)).toBe(true);
+    expect(isCoveredByReadSync({ type: 'new_message', senderId: '8', messageId: '90' }, peerSync)).toBe(false);
+    expect(parseReadSync({ type: 'messages_read', upToMessageId: '100' })).toBeNull();
+  });
+});
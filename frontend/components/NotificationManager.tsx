@@ -1,6 +1,6 @@
 import React, { useEffect } from 'react';
 import AsyncStorage from '@react-native-async-storage/async-storage';
-import { notificationService, setNotificationAdder } from '../services/notificationService';
+import { notificationService, setConversationReadHandler, setNotificationAdder } from '../services/notificationService';
 import { useUser } from '../context/UserContext';
 import { useNotifications } from '../context/NotificationContext';
 
@@ -8,12 +8,13 @@ import { useNotifications } from '../context/NotificationContext';
 // flush any notifications that arrived while the app was in the background.
 export const NotificationManager: React.FC = () => {
     const { isLoggedIn } = useUser();
-    const { addNotification } = useNotifications();
+    const { addNotification, markConversationRead } = useNotifications();
 
     // Register addNotification once on mount
     useEffect(() => {
         setNotificationAdder(addNotification);
-    }, [addNotification]);
+        setConversationReadHandler(markConversationRead);
+    }, [addNotification, markConversationRead]);
 
     // Flush pending background notifications to history
     useEffect(() => {
@@ -8,6 +8,7 @@ import React, {
     type ReactNode,
 } from 'react';
 import AsyncStorage from '@react-native-async-storage/async-storage';
+import { isCoveredByReadSync, type ReadSync } from '../services/notificationService';
 
 const STORAGE_KEY = 'notification_history';
 const MAX_NOTIFICATIONS = 100;
@@ -47,6 +48,7 @@ interface NotificationContextType {
     addNotification: (notif: Omit<AppNotification, 'id' | 'receivedAt' | 'isRead'>) => void;
     markAsRead: (id: string) => void;
     markAllAsRead: () => void;
+    markConversationRead: (sync: ReadSync) => void;
     clearAll: () => void;
     isPanelVisible: boolean;
     setPanelVisible: (visible: boolean) => void;
@@ -124,6 +126,20 @@ export const NotificationProvider = ({ children }: { children: ReactNode }) => {
         });
     }, [persist]);
 
+    const markConversationRead = useCallback(
+        (sync: ReadSync) => {
+            setNotifications(prev => {
+                if (!prev.some(n => !n.isRead && isCoveredByReadSync(n.data, sync))) {
+                    return prev;
+                }
+                const updated = prev.map(n => (isCoveredByReadSync(n.data, sync) ? { ...n, isRead: true } : n));
+                persist(updated);
+                return updated;
+            });
+        },
+        [persist],
+    );
+
     const clearAll = useCallback(() => {
         setNotifications([]);
         AsyncStorage.removeItem(STORAGE_KEY).catch(() => { });
@@ -139,6 +155,7 @@ export const NotificationProvider = ({ children }: { children: ReactNode }) => {
                 addNotification,
                 markAsRead,
                 markAllAsRead,
+                markConversationRead,
                 clearAll,
                 isPanelVisible,
                 setPanelVisible,
@@ -62,6 +62,8 @@ export const EditDatingProfileScreen: React.FC<Props> = ({ navigation, route })
         maritalStatus: '',
         birthTime: '',
         birthPlaceLink: '',
+        birthLatitude: null as number | null,
+        birthLongitude: null as number | null,
         city: '',
         dob: '',
         madh: '',
@@ -122,6 +124,8 @@ export const EditDatingProfileScreen: React.FC<Props> = ({ navigation, route })
                     maritalStatus: me.maritalStatus || '',
                     birthTime: me.birthTime || '',
                     birthPlaceLink: me.birthPlaceLink || '',
+                    birthLatitude: me.birthLatitude ?? null,
+                    birthLongitude: me.birthLongitude ?? null,
                     city: me.city || '',
                     dob: me.dob || '',
                     madh: me.madh || '',
@@ -265,7 +269,14 @@ export const EditDatingProfileScreen: React.FC<Props> = ({ navigation, route })
         if (citySearchType === 'current') {
             setProfile(prev => ({ ...prev, city: item.display_name }));
         } else {
-            setProfile(prev => ({ ...prev, birthPlaceLink: item.display_name }));
+            const lat = parseFloat(item.lat);
+            const lon = parseFloat(item.lon);
+            setProfile(prev => ({
+                ...prev,
+                birthPlaceLink: item.display_name,
+                birthLatitude: Number.isFinite(lat) ? lat : null,
+                birthLongitude: Number.isFinite(lon) ? lon : null,
+            }));
         }
         setCitySearchModal(false);
         setCityQuery('');
@@ -1,6 +1,6 @@
 import axios from 'axios';
 import { API_PATH } from '../config/api.config';
-import { ScriptureBook, ScriptureVerse, ChapterInfo } from '../types/library';
+import { ScriptureBook, ScriptureVerse, ChapterInfo, LibrarySearchFilters } from '../types/library';
 import { getGodModeQueryParams } from './godModeService';
 
 class LibraryService {
@@ -53,13 +53,14 @@ class LibraryService {
         }
     }
 
-    async search(query: string): Promise<ScriptureVerse[]> {
+    async search(query: string, filters: LibrarySearchFilters = {}): Promise<ScriptureVerse[]> {
         try {
             const godModeParams = await getGodModeQueryParams();
             const response = await axios.get(`${API_PATH}/library/search`, {
-                params: { q: query, ...godModeParams }
+                params: { q: query, ...filters, ...godModeParams }
             });
-            return response.data;
+            // Paged envelope when limit/offset are passed, a bare array otherwise
+            return Array.isArray(response.data) ? response.data : response.data.items;
         } catch (error) {
             console.error('Error searching library:', error);
             throw error;
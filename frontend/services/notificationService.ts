@@ -23,6 +23,38 @@ type AddNotificationFn = (notif: { type: string; title: string; body: string; da
 let _addNotification: AddNotificationFn | null = null;
 export const setNotificationAdder = (fn: AddNotificationFn) => { _addNotification = fn; };
 
+// Silent "messages_read" push: the user read the conversation on another device
+export type ReadSync = { roomId: number; peerId: number; upToMessageId: number };
+type ReadSyncFn = (sync: ReadSync) => void;
+let _markConversationRead: ReadSyncFn | null = null;
+export const setConversationReadHandler = (fn: ReadSyncFn) => { _markConversationRead = fn; };
+
+const toPositiveInt = (raw: any): number => {
+    const value = Number.parseInt(String(raw || ''), 10);
+    return Number.isFinite(value) && value > 0 ? value : 0;
+};
+
+export const parseReadSync = (data: any): ReadSync | null => {
+    if (data?.type !== 'messages_read') return null;
+    const sync = {
+        roomId: toPositiveInt(data.roomId),
+        peerId: toPositiveInt(data.peerId),
+        upToMessageId: toPositiveInt(data.upToMessageId),
+    };
+    if (!sync.upToMessageId || (!sync.roomId && !sync.peerId)) return null;
+    return sync;
+};
+
+// isCoveredByReadSync reports whether a message notification was read elsewhere
+export const isCoveredByReadSync = (data: any, sync: ReadSync): boolean => {
+    const messageId = toPositiveInt(data?.messageId);
+    if (!messageId || messageId > sync.upToMessageId) return false;
+    if (sync.roomId) {
+        return data?.type === 'room_message' && toPositiveInt(data.roomId) === sync.roomId;
+    }
+    return data?.type === 'new_message' && toPositiveInt(data.senderId) === sync.peerId;
+};
+
 // Lazy-loaded messaging instance to prevent initialization race conditions.
 let messagingInstance: any = null;
 const getMessagingInstance = () => {
@@ -132,6 +164,13 @@ export const notificationService = {
         console.log('[NotificationService] Foreground message:', message);
 
         const data = message?.data || {};
+        if (data?.type === 'messages_read') {
+            const sync = parseReadSync(data);
+            if (sync && _markConversationRead) {
+                _markConversationRead(sync);
+            }
+            return;
+        }
         const isCirclePublishResult = data?.type === 'video_circle_publish_result';
         const fallback = isCirclePublishResult ? getVideoCirclePublishCopy(data) : null;
 
@@ -284,6 +323,12 @@ export const notificationService = {
             // fully up, so we store them in a staging key and flush later.
             const raw = await AsyncStorage.getItem('pending_notifications');
             const pending: any[] = raw ? JSON.parse(raw) : [];
+            if (data?.type === 'messages_read') {
+                const sync = parseReadSync(data);
+                const remaining = sync ? pending.filter(item => !isCoveredByReadSync(item?.data, sync)) : pending;
+                await AsyncStorage.setItem('pending_notifications', JSON.stringify(remaining));
+                return;
+            }
             pending.push({
                 type: data?.type || 'general',
                 title: remoteMessage?.notification?.title || 'Уведомление',
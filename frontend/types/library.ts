@@ -25,6 +25,19 @@ export interface ScriptureVerse {
     verse_reference: string;
     created_at: string;
     updated_at: string;
+    // Present on search results
+    rank?: number;
+    snippet?: string; // HTML-escaped, matches wrapped in <mark>
+    match_type?: 'fulltext' | 'fuzzy';
+}
+
+export interface LibrarySearchFilters {
+    bookCode?: string;
+    canto?: number;
+    chapter?: number;
+    language?: 'ru' | 'en';
+    limit?: number;
+    offset?: number;
 }
 
 export interface ChapterInfo {
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"rag-agent-server/internal/config"
 	"rag-agent-server/internal/database"
 	"rag-agent-server/internal/handlers"
 	"rag-agent-server/internal/middleware"
@@ -84,6 +85,9 @@ func main() {
 	// Start Booking Reminder Worker
 	workers.StartBookingReminderWorker()
 
+	// Start Reading Plan Reminder Worker (daily push at each reader's local hour)
+	workers.StartReadingPlanReminderWorker()
+
 	// Start Donation Auto-Confirm Worker (confirms donations after 24h cooling-off period)
 	workers.StartDonationConfirmWorker()
 
@@ -93,6 +97,21 @@ func main() {
 	// Start Yatra billing worker (daily LKM charging, pause/resume).
 	workers.StartYatraBillingWorker()
 
+	// Start Order Escrow Worker (refunds escrow orders not shipped within the configured days)
+	workers.StartOrderEscrowWorker()
+
+	// Start Order Dispute SLA Worker (escalates disputes with expired timers to admins)
+	workers.StartOrderDisputeSLAWorker()
+
+	// Start Standing Order Worker (scheduled and recurring wallet transfers)
+	workers.StartStandingOrderWorker()
+
+	// Start LKM Reconciliation Worker (recovers lost top-up webhooks, daily report for finance)
+	workers.StartLKMReconciliationWorker()
+
+	// Start Email Outbox Worker (delivers admin broadcasts with retries)
+	workers.StartEmailOutboxWorker()
+
 	// Start Video Transcoding Worker (background job for video processing)
 	transcodingWorker := workers.StartWorkerInBackground(2) // 2 concurrent workers
 	defer transcodingWorker.Stop()
@@ -194,7 +213,19 @@ func main() {
 	bookingService := services.NewBookingService(walletService, serviceService, referralService)
 	charityService := services.NewCharityService(walletService)
 	hub := websocket.NewHub()
+	if config.WSClusterEnabled() {
+		redisService := services.NewRedisService()
+		if redisService.IsConnected() {
+			hub.EnableCluster(websocket.NewRedisClusterBroker(redisService.GetClient()))
+		} else {
+			log.Println("[Hub] WS_CLUSTER_ENABLED is on but Redis is unavailable; running single-instance hub")
+		}
+	}
 	go hub.Run()
+	websocket.GetCafeHub(hub)
+
+	// Start Scheduled Message Worker (delivers scheduled messages, expires TTL messages)
+	workers.StartScheduledMessageWorker(hub)
 
 	// Ensure all existing users have invite codes
 	go func() {
@@ -206,14 +237,19 @@ func main() {
 	// Handlers
 	authHandler := handlers.NewAuthHandler(walletService, referralService)
 	messageHandler := handlers.NewMessageHandler(aiChatService, hub, walletService, referralService)
+	hub.OnReceipt(messageHandler.HandleWSReceipt)
 	roomHandler := handlers.NewRoomHandler()
+	jointReadingHandler := handlers.NewJointReadingHandler(hub)
+	hub.OnReading(jointReadingHandler.HandleWSCommand)
+	roomHandler.OnReadingChanged(jointReadingHandler.BroadcastRoomState)
 	roomSFUHandler := handlers.NewRoomSFUHandler()
 	adminHandler := handlers.NewAdminHandler()
 	adminFinancialHandler := handlers.NewAdminFinancialHandler()
 	adminFinanceRBACHandler := handlers.NewAdminFinanceRBACHandler()
+	lkmAuditHandler := handlers.NewLKMAuditHandler()
 	aiHandler := handlers.NewAiHandler()
 	mediaHandler := handlers.NewMediaHandler(hub)
-	datingHandler := handlers.NewDatingHandler(aiChatService)
+	datingHandler := handlers.NewDatingHandler(aiChatService, hub)
 	typingHandler := handlers.NewTypingHandler(hub)
 	ragHandler := handlers.NewRAGHandler(services.NewRAGPipelineService(database.DB))
 	chatHandler := handlers.NewChatHandler()
@@ -225,6 +261,7 @@ func main() {
 	shopHandler := handlers.NewShopHandler()
 	productHandler := handlers.NewProductHandler()
 	orderHandler := handlers.NewOrderHandler()
+	orderDisputeHandler := handlers.NewOrderDisputeHandler()
 	educationHandler := handlers.NewEducationHandler(services.NewEducationService(database.DB))
 	educationTutorHandler := handlers.NewEducationTutorHandler(services.NewEducationTutorService(database.DB))
 	turnHandler := handlers.NewTurnHandler()
@@ -236,6 +273,7 @@ func main() {
 	yatraHandler := handlers.NewYatraHandler()
 	yatraAdminHandler := handlers.NewYatraAdminHandler()
 	walletHandler := handlers.NewWalletHandler(walletService)
+	walletControlHandler := handlers.NewWalletControlHandler(walletService)
 	referralHandler := handlers.NewReferralHandler(referralService)
 	serviceHandler := handlers.NewServiceHandler()
 	bookingHandler := handlers.NewBookingHandler(bookingService, calendarService)
@@ -244,6 +282,8 @@ func main() {
 	videoCircleHandler := handlers.NewVideoCircleHandler()
 	pathTrackerHandler := handlers.NewPathTrackerHandler()
 	channelHandler := handlers.NewChannelHandler()
+	libraryStudyHandler := handlers.NewLibraryStudyHandler()
+	libraryPlanHandler := handlers.NewLibraryPlanHandler()
 	supportHandler := handlers.NewSupportHandler()
 	lkmTopupHandler := handlers.NewLKMTopupHandler(lkmTopupService)
 	// bookHandler removed, using library functions directly
@@ -429,6 +469,9 @@ func main() {
 	admin.Post("/funds/expenses", adminFinancialHandler.CreateExpenseRequest)
 	admin.Post("/funds/expenses/:id/approve", adminFinancialHandler.ApproveExpenseRequest)
 	admin.Post("/funds/expenses/:id/reject", adminFinancialHandler.RejectExpenseRequest)
+	admin.Get("/funds/audit", lkmAuditHandler.ListReports)
+	admin.Post("/funds/audit/run", lkmAuditHandler.RunAudit)
+	admin.Get("/funds/audit/:id", lkmAuditHandler.GetReport)
 	admin.Get("/funds/permissions", adminFinanceRBACHandler.ListFinancePermissions)
 	admin.Get("/funds/permissions/me", adminFinanceRBACHandler.GetMyFinancePermissions)
 	admin.Post("/funds/permissions/grant", adminFinanceRBACHandler.GrantFinancePermission)
@@ -440,6 +483,10 @@ func main() {
 	admin.Post("/lkm/topups/:topupId/approve", lkmTopupHandler.ApproveTopup)
 	admin.Post("/lkm/topups/:topupId/reject", lkmTopupHandler.RejectTopup)
 	admin.Post("/lkm/topups/:topupId/mark-paid", lkmTopupHandler.MarkTopupPaid)
+	admin.Get("/lkm/reconciliation", lkmTopupHandler.ListReconciliationReports)
+	admin.Post("/lkm/reconciliation/run", lkmTopupHandler.RunReconciliation)
+	admin.Get("/lkm/reconciliation/:id", lkmTopupHandler.GetReconciliationReport)
+	admin.Post("/lkm/reconciliation/discrepancies/:id/resolve", lkmTopupHandler.ResolveDiscrepancy)
 
 	// RAG Management
 	admin.Get("/rag/corpora", adminHandler.ListGeminiCorpora)
@@ -523,17 +570,27 @@ func main() {
 	admin.Get("/referrals/stats", adminHandler.GetReferralGlobalStats)
 	admin.Get("/referrals/leaderboard", adminHandler.GetReferralLeaderboard)
 	admin.Get("/wallet/global-stats", adminHandler.GetGlobalWalletStats)
+	admin.Get("/wallet/limit-tiers", walletControlHandler.ListTiers)
+	admin.Put("/wallet/limit-tiers", walletControlHandler.UpsertTier)
+	admin.Get("/wallet/control-events", walletControlHandler.ListEvents)
 
 	admin.Get("/wallet/:userId", adminHandler.GetUserWallet)
 	admin.Get("/wallet/:userId/transactions", adminHandler.GetUserTransactions)
 	admin.Post("/wallet/charge", adminHandler.AdminChargeWallet)
 	admin.Post("/wallet/seize", adminHandler.AdminSeizeWallet)
 	admin.Post("/wallet/:userId/activate", adminHandler.ActivateUserPendingBalance)
+	admin.Get("/wallet/:userId/controls", walletControlHandler.GetControls)
+	admin.Post("/wallet/:userId/freeze", walletControlHandler.Freeze)
+	admin.Post("/wallet/:userId/unfreeze", walletControlHandler.Unfreeze)
+	admin.Put("/wallet/:userId/limits", walletControlHandler.SetLimits)
 
 	// Admin Shop Management Routes
 	admin.Get("/shops", shopHandler.AdminGetShops)
 	admin.Get("/shops/stats", shopHandler.AdminGetShopStats)
 	admin.Put("/shops/:id/moderate", shopHandler.AdminModerateShop)
+	admin.Get("/disputes", orderDisputeHandler.AdminListDisputes)
+	admin.Get("/disputes/:id", orderDisputeHandler.AdminGetDispute)
+	admin.Post("/disputes/:id/resolve", orderDisputeHandler.AdminResolveDispute)
 
 	// Admin Multimedia Hub Management Routes
 	admin.Get("/multimedia/stats", multimediaHandler.GetStats)
@@ -629,6 +686,8 @@ func main() {
 	admin.Put("/yatra/templates/:id", yatraAdminHandler.UpdateTemplate)
 	admin.Delete("/yatra/templates/:id", yatraAdminHandler.DeleteTemplate)
 	admin.Post("/yatra/broadcast", yatraAdminHandler.BroadcastEmail)
+	admin.Get("/yatra/broadcasts", yatraAdminHandler.GetBroadcasts)
+	admin.Get("/yatra/broadcasts/:id/messages", yatraAdminHandler.GetBroadcastMessages)
 
 	// Support Bot Management
 	admin.Get("/support/metrics", supportHandler.GetSupportMetrics)
@@ -647,7 +706,20 @@ func main() {
 	// Other Protected Routes
 	protected.Post("/messages", messageHandler.SendMessage)
 	protected.Get("/messages/history", messageHandler.GetMessagesHistory)
+	protected.Get("/messages/unread", messageHandler.GetUnreadCounts)
+	protected.Get("/messages/search", messageHandler.SearchMessages)
+	protected.Get("/messages/scheduled", messageHandler.ListScheduledMessages)
+	protected.Delete("/messages/scheduled/:id", messageHandler.CancelScheduledMessage)
+	protected.Get("/messages/receipts", messageHandler.GetReceipts)
+	protected.Post("/messages/read", messageHandler.MarkRead)
+	protected.Post("/messages/delivered", messageHandler.MarkDelivered)
+	protected.Get("/messages/:id/edits", messageHandler.GetMessageEdits) // before :userId/:recipientId
+	protected.Get("/messages/:id/thread", messageHandler.GetThread)
 	protected.Get("/messages/:userId/:recipientId", messageHandler.GetMessages)
+	protected.Patch("/messages/:id", messageHandler.EditMessage)
+	protected.Delete("/messages/:id", messageHandler.DeleteMessage)
+	protected.Post("/messages/:id/reactions", messageHandler.AddReaction)
+	protected.Delete("/messages/:id/reactions", messageHandler.RemoveReaction)
 
 	// Education Routes (Protected)
 	protected.Get("/education/modules/:moduleId/exams", educationHandler.GetModuleExams)
@@ -681,6 +753,36 @@ func main() {
 	// WebRTC Config
 	protected.Get("/turn-credentials", turnHandler.GetTurnCredentials)
 
+	// Library Study Routes (bookmarks, highlights, notes, reading progress)
+	protected.Get("/library/study/bookmarks", libraryStudyHandler.ListBookmarks)
+	protected.Put("/library/study/bookmarks/:verseId", libraryStudyHandler.SetBookmark)
+	protected.Delete("/library/study/bookmarks/:verseId", libraryStudyHandler.RemoveBookmark)
+	protected.Get("/library/study/highlights", libraryStudyHandler.ListHighlights)
+	protected.Post("/library/study/highlights", libraryStudyHandler.AddHighlight)
+	protected.Patch("/library/study/highlights/:id", libraryStudyHandler.UpdateHighlight)
+	protected.Delete("/library/study/highlights/:id", libraryStudyHandler.DeleteHighlight)
+	protected.Get("/library/study/notes", libraryStudyHandler.ListNotes)
+	protected.Post("/library/study/notes", libraryStudyHandler.AddNote)
+	protected.Patch("/library/study/notes/:id", libraryStudyHandler.UpdateNote)
+	protected.Delete("/library/study/notes/:id", libraryStudyHandler.DeleteNote)
+	protected.Get("/library/study/verses/:verseId/notes", libraryStudyHandler.GetVerseNotes)
+	protected.Get("/library/study/progress", libraryStudyHandler.ListProgress)
+	protected.Put("/library/study/progress", libraryStudyHandler.SaveProgress)
+	protected.Get("/library/study/continue", libraryStudyHandler.ContinueReading)
+	protected.Get("/library/study/sync", libraryStudyHandler.Sync)
+	protected.Get("/library/study/export", libraryStudyHandler.Export)
+	protected.Get("/library/plans/enrollments", libraryPlanHandler.ListEnrollments)
+	protected.Get("/library/plans/enrollments/:id/today", libraryPlanHandler.Today)
+	protected.Get("/library/plans/enrollments/:id/days/:day", libraryPlanHandler.GetDay)
+	protected.Post("/library/plans/enrollments/:id/days/:day/complete", libraryPlanHandler.CompleteDay)
+	protected.Put("/library/plans/enrollments/:id/reminder", libraryPlanHandler.UpdateReminder)
+	protected.Delete("/library/plans/enrollments/:id", libraryPlanHandler.Leave)
+	protected.Get("/library/plans", libraryPlanHandler.ListPlans)
+	protected.Post("/library/plans", libraryPlanHandler.CreatePlan)
+	protected.Get("/library/plans/:id", libraryPlanHandler.GetPlan)
+	protected.Delete("/library/plans/:id", libraryPlanHandler.DeletePlan)
+	protected.Post("/library/plans/:id/enroll", libraryPlanHandler.Enroll)
+
 	// User Portal Layout Routes
 	protected.Get("/user/portal-layout", userHandler.GetPortalLayout)
 	protected.Put("/user/portal-layout", userHandler.SavePortalLayout)
@@ -724,8 +826,13 @@ func main() {
 	protected.Post("/rooms/role", roomHandler.UpdateMemberRole)
 	protected.Get("/rooms/:id/members", roomHandler.GetRoomMembers)
 	protected.Get("/rooms/:id/summary", messageHandler.GetRoomSummary)
+	protected.Get("/rooms/:id/threads", messageHandler.GetRoomVerseThreads)
 	protected.Put("/rooms/:id", roomHandler.UpdateRoom)
 	protected.Put("/rooms/:id/settings", roomHandler.UpdateRoomSettings)
+	protected.Get("/rooms/:id/reading", jointReadingHandler.GetState)
+	protected.Get("/rooms/:id/reading/sessions", jointReadingHandler.ListSessions)
+	protected.Get("/rooms/:id/reading/log", jointReadingHandler.GetLog)
+	protected.Post("/rooms/:id/reading/:action", jointReadingHandler.Command)
 	protected.Post("/rooms/:id/image", roomHandler.UpdateRoomImage)
 	protected.Get("/rooms/:id/sfu/config", roomSFUHandler.GetRoomConfig)
 	protected.Post("/rooms/:id/sfu/token", roomSFUHandler.IssueRoomToken)
@@ -763,7 +870,12 @@ func main() {
 	protected.Get("/dating/stats", datingHandler.GetDatingStats)
 	protected.Get("/dating/cities", datingHandler.GetDatingCities)
 	protected.Get("/dating/candidates", datingHandler.GetCandidates)
+	protected.Get("/dating/feed", datingHandler.GetFeed)
+	protected.Post("/dating/feed/seen", datingHandler.MarkFeedSeen)
+	protected.Post("/dating/feed/:candidateId/pass", datingHandler.PassCandidate)
+	protected.Delete("/dating/feed/:candidateId/pass", datingHandler.PassCandidate)
 	protected.Post("/dating/compatibility/:userId/:candidateId", datingHandler.GetCompatibility)
+	protected.Get("/dating/ashtakoota/:candidateId", datingHandler.GetAshtakoota)
 	protected.Get("/dating/profile/:id", datingHandler.GetDatingProfile)
 	protected.Put("/dating/profile/:id", datingHandler.UpdateDatingProfile)
 	protected.Post("/dating/favorites", datingHandler.AddToFavorites)
@@ -773,6 +885,8 @@ func main() {
 	protected.Get("/dating/liked-me", datingHandler.GetWhoLikedMe)
 	protected.Get("/dating/notifications", datingHandler.GetNotifications)
 	protected.Delete("/dating/favorites/:id", datingHandler.RemoveFromFavorites)
+	protected.Get("/dating/matches", datingHandler.GetMatches)
+	protected.Delete("/dating/matches/:id", datingHandler.Unmatch)
 
 	// RAG Routes
 	protected.Get("/rag/domains", ragHandler.GetDomains)
@@ -849,6 +963,11 @@ func main() {
 	protected.Put("/orders/:id/status", orderHandler.UpdateOrderStatus)
 	protected.Get("/orders/:id/contact-buyer", orderHandler.ContactBuyer)
 
+	// Order Disputes (Sattva Market)
+	protected.Post("/orders/:id/dispute", orderDisputeHandler.OpenDispute)
+	protected.Get("/orders/:id/dispute", orderDisputeHandler.GetOrderDispute)
+	protected.Post("/orders/:id/dispute/statements", orderDisputeHandler.AddStatement)
+
 	// Cafe Routes (Sattva Cafe - Owner/Admin)
 	protected.Post("/cafes/upload", cafeHandler.UploadCafePhoto)
 	protected.Post("/cafes", cafeHandler.CreateCafe)
@@ -881,6 +1000,7 @@ func main() {
 	protected.Put("/cafe-orders/:id/status", cafeOrderHandler.UpdateOrderStatus)
 	protected.Post("/cafe-orders/:id/cancel", cafeOrderHandler.CancelOrder)
 	protected.Post("/cafe-orders/:id/pay", cafeOrderHandler.MarkAsPaid)
+	protected.Post("/cafe-orders/:id/payment-qr", cafeOrderHandler.CreatePaymentQR)
 	protected.Post("/cafe-orders/:id/repeat", cafeOrderHandler.RepeatOrder)
 	protected.Put("/cafe-orders/:id/items/:itemId/status", cafeOrderHandler.UpdateItemStatus)
 	// Cafe Orders for Staff
@@ -969,7 +1089,19 @@ func main() {
 	protected.Get("/wallet", walletHandler.GetBalance)
 	protected.Get("/wallet/transactions", walletHandler.GetTransactions)
 	protected.Get("/wallet/stats", walletHandler.GetStats)
+	protected.Get("/wallet/limits", walletHandler.GetLimits)
 	protected.Post("/wallet/transfer", walletHandler.Transfer)
+	protected.Get("/wallet/statements", walletHandler.GetStatements)
+	protected.Get("/wallet/statements/:month", walletHandler.GetStatement)
+	protected.Get("/wallet/standing-orders", walletHandler.GetStandingOrders)
+	protected.Post("/wallet/standing-orders", walletHandler.CreateStandingOrder)
+	protected.Post("/wallet/standing-orders/:id/cancel", walletHandler.CancelStandingOrder)
+	protected.Post("/wallet/standing-orders/:id/resume", walletHandler.ResumeStandingOrder)
+	protected.Get("/wallet/payment-requests", walletHandler.GetPaymentRequests)
+	protected.Post("/wallet/payment-requests", walletHandler.CreatePaymentRequest)
+	protected.Get("/wallet/payment-requests/:token", walletHandler.GetPaymentRequest)
+	protected.Post("/wallet/payment-requests/:token/pay", walletHandler.PayPaymentRequest)
+	protected.Post("/wallet/payment-requests/:token/cancel", walletHandler.CancelPaymentRequest)
 
 	// Referral System (Самбандха)
 	protected.Get("/referral/invite", referralHandler.GetMyInviteLink)
@@ -1021,6 +1153,7 @@ func main() {
 		}
 
 		c.Locals("userId", claims.UserID)
+		c.Locals("sessionID", claims.SessionID)
 		return c.Next()
 	})
 
@@ -1043,12 +1176,9 @@ func main() {
 			log.Printf("[WS-Security] Path ID %d DOES NOT MATCH Token ID %d. Using Token ID.", paramId, userId)
 		}
 
-		client := &websocket.Client{
-			Hub:    hub,
-			Conn:   c,
-			UserID: userId,
-			Send:   make(chan websocket.WSMessage, 256),
-		}
+		// Each device/session gets its own client so phone and Mini App stay connected together
+		sessionID, _ := c.Locals("sessionID").(uint)
+		client := websocket.NewClient(hub, c, userId, sessionID)
 
 		client.Hub.Register <- client
 		go client.WritePump()
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"rag-agent-server/internal/database"
+	"rag-agent-server/internal/models"
+	"rag-agent-server/internal/services"
+)
+
+// Checks that every LKM ledger group balances and that wallet balances match their
+// transaction history. Exits with status 1 while unrepaired findings remain.
+//
+//	go run ./cmd/lkm_audit            # report only
+//	go run ./cmd/lkm_audit -repair -actor 1
+func main() {
+	repair := flag.Bool("repair", false, "post correcting entries for every finding")
+	actor := flag.Uint("actor", 0, "admin user ID recorded on correcting entries")
+	flag.Parse()
+
+	database.Connect()
+
+	opts := services.LKMAuditOptions{Repair: *repair, Trigger: "cli"}
+	if *actor > 0 {
+		actorID := *actor
+		opts.ActorID = &actorID
+	}
+
+	report, err := services.NewLKMAuditService().Run(opts)
+	if err != nil {
+		log.Fatalf("Audit failed: %v", err)
+	}
+
+	fmt.Printf("REPORT=%d STATUS=%s GROUPS=%d WALLETS=%d FINDINGS=%d REPAIRED=%d\n",
+		report.ID, report.Status, report.GroupsTotal, report.WalletsTotal, report.FindingCount, report.RepairedCount)
+	for _, f := range report.Findings {
+		switch f.Kind {
+		case models.LKMAuditWalletDrift:
+			fmt.Printf("- %s wallet=%d field=%s stored=%d history=%d delta=%d repaired=%t\n",
+				f.Kind, *f.WalletID, f.Field, f.Actual, f.Expected, f.Delta, f.Repaired)
+		case models.LKMAuditUngroupedEntry:
+			fmt.Printf("- %s entry=%d amount=%d\n", f.Kind, *f.LedgerEntryID, f.Actual)
+		default:
+			fmt.Printf("- %s group=%s debits=%d credits=%d repaired=%t\n",
+				f.Kind, f.TxGroupID, f.Expected, f.Actual, f.Repaired)
+		}
+	}
+
+	if report.Status == models.LKMAuditDrift {
+		os.Exit(1)
+	}
+}
@@ -44,3 +44,7 @@ func RoomSFUEnabled() bool {
 func RoomSFURequireMembership() bool {
 	return FlagEnabled("ROOM_SFU_REQUIRE_MEMBERSHIP", true)
 }
+
+func WSClusterEnabled() bool {
+	return FlagEnabled("WS_CLUSTER_ENABLED", false)
+}
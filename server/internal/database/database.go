@@ -50,20 +50,27 @@ func Connect() {
 	err = DB.AutoMigrate(
 		// Core models
 		&models.User{}, &models.AuthSession{}, &models.Friend{}, &models.Message{}, &models.Block{},
+		&models.MessageEdit{}, &models.MessageHiddenForUser{}, &models.MessageReaction{},
+		&models.ConversationReadState{}, &models.ScheduledMessage{},
 		&models.AdminPermissionGrant{},
 		&models.Room{}, &models.RoomMember{}, &models.RoomInviteToken{}, &models.AiModel{}, &models.Media{},
+		&models.RoomReadingSession{}, &models.RoomReadingQueueEntry{}, &models.RoomReadingLogEntry{},
 		&models.Channel{}, &models.ChannelMember{}, &models.ChannelPost{}, &models.ChannelShowcase{},
 		&models.ChannelPostDelivery{},
 		&models.ChannelPromotedAdImpression{},
 		&models.UserDeviceToken{}, &models.PushDeliveryEvent{},
 		&models.SystemSetting{}, &models.MetricCounter{}, &models.UserDismissedPrompt{},
-		&models.DatingFavorite{}, &models.DatingCompatibility{},
+		&models.DatingFavorite{}, &models.DatingCompatibility{}, &models.DatingGunaMilan{}, &models.DatingMatch{}, &models.DatingFeedAction{},
 		&models.AIPrompt{}, &models.UserPortalLayout{},
 		// Ads models
 		&models.Ad{}, &models.AdPhoto{}, &models.AdFavorite{}, &models.AdReport{},
 		// Library models
 		&models.ScriptureCanto{}, &models.ScriptureChapter{},
 		&models.ScriptureVerse{}, // Dependent on ScriptureBook
+		&models.ScriptureBookmark{}, &models.ScriptureHighlight{},
+		&models.ScriptureNote{}, &models.ScriptureReadingProgress{},
+		&models.ScriptureReadingPlan{}, &models.ScriptureReadingPlanEnrollment{},
+		&models.ScriptureReadingPlanCompletion{},
 		// Tags
 		&models.Tag{}, &models.UserTag{},
 		// News models
@@ -74,6 +81,7 @@ func Connect() {
 		&models.Product{}, &models.ProductVariant{}, &models.ProductImage{},
 		&models.ProductReview{}, &models.ProductFavorite{},
 		&models.Order{}, &models.OrderItem{},
+		&models.OrderDispute{}, &models.OrderDisputeStatement{}, &models.OrderDisputeEvidence{},
 		// Education models
 		&models.EducationCourse{}, &models.EducationModule{},
 		&models.ExamQuestion{}, &models.AnswerOption{},
@@ -115,11 +123,14 @@ func Connect() {
 		// Yatra Admin models (moderation)
 		&models.YatraReport{}, &models.OrganizerBlock{},
 		&models.AdminNotification{}, &models.ModerationTemplate{},
+		&models.EmailBroadcast{}, &models.EmailOutboxMessage{},
 		// Services (universal service constructor)
 		&models.Service{}, &models.ServiceTariff{},
 		&models.ServiceSchedule{}, &models.ServiceBooking{},
 		// Wallet (Лакшми currency)
-		&models.Wallet{}, &models.WalletTransaction{},
+		&models.Wallet{}, &models.WalletTransaction{}, &models.WalletStandingOrder{}, &models.PaymentRequest{},
+		&models.WalletStatement{},
+		&models.WalletLimitTier{}, &models.WalletLimitOverride{}, &models.WalletControlEvent{},
 		// Charity (Seva module)
 		&models.CharityOrganization{}, &models.CharityProject{},
 		&models.CharityDonation{}, &models.CharityEvidence{},
@@ -132,6 +143,8 @@ func Connect() {
 		&models.LKMPackageConfig{}, &models.LKMPaymentProcessingCost{},
 		&models.LKMManualFXRate{}, &models.LKMTopupRiskTier{},
 		&models.LKMQuote{}, &models.LKMTopup{}, &models.LKMTopupWebhookEvent{},
+		&models.LKMReconciliationReport{}, &models.LKMReconciliationDiscrepancy{},
+		&models.LKMAuditReport{}, &models.LKMAuditFinding{},
 		// Path Tracker
 		&models.DailyCheckin{}, &models.DailyStep{},
 		&models.DailyStepEvent{}, &models.PathTrackerAlertEvent{}, &models.PathTrackerUnlock{}, &models.PathTrackerState{},
@@ -163,6 +176,44 @@ func Connect() {
 	DB.Exec(`CREATE INDEX IF NOT EXISTS idx_messages_recipient_sender_id_desc
 		ON messages (recipient_id, sender_id, id DESC)`)
 
+	// Message full-text search (ru + en stemming; media messages are indexed by file name)
+	DB.Exec(`ALTER TABLE messages
+		ADD COLUMN IF NOT EXISTS search_vector tsvector
+		GENERATED ALWAYS AS (
+			to_tsvector('russian', CASE WHEN coalesce(type, 'text') = 'text' THEN coalesce(content, '') ELSE coalesce(file_name, '') END) ||
+			to_tsvector('english', CASE WHEN coalesce(type, 'text') = 'text' THEN coalesce(content, '') ELSE coalesce(file_name, '') END)
+		) STORED`)
+	DB.Exec(`CREATE INDEX IF NOT EXISTS idx_messages_search_vector
+		ON messages USING GIN (search_vector)`)
+
+	// Scripture library search: stemmed full text per verse language plus trigram
+	// matching on diacritics-folded transliteration (see services.LibrarySearchService)
+	if err := DB.Exec(`CREATE EXTENSION IF NOT EXISTS pg_trgm`).Error; err != nil {
+		log.Printf("[Migration] pg_trgm unavailable, fuzzy library search disabled: %v", err)
+	}
+	DB.Exec(`CREATE OR REPLACE FUNCTION scripture_fold(input text) RETURNS text
+		LANGUAGE sql IMMUTABLE PARALLEL SAFE
+		AS $$ SELECT translate(lower(coalesce(input, '')), 'āīūṛṝḷḹṅñṭḍṇśṣṁṃḥĀĪŪṚṜḶḸṄÑṬḌṆŚṢṀṂḤ', 'aiurrllnntdnssmmhaiurrllnntdnssmmh') $$`)
+	DB.Exec(`ALTER TABLE scripture_verses
+		ADD COLUMN IF NOT EXISTS search_vector tsvector
+		GENERATED ALWAYS AS (
+			setweight(to_tsvector(CASE WHEN language = 'ru' THEN 'russian'::regconfig ELSE 'english'::regconfig END, coalesce(translation, '')), 'A') ||
+			setweight(to_tsvector(CASE WHEN language = 'ru' THEN 'russian'::regconfig ELSE 'english'::regconfig END, coalesce(synonyms, '')), 'B') ||
+			setweight(to_tsvector(CASE WHEN language = 'ru' THEN 'russian'::regconfig ELSE 'english'::regconfig END, coalesce(purport, '')), 'C')
+		) STORED`)
+	DB.Exec(`ALTER TABLE scripture_verses
+		ADD COLUMN IF NOT EXISTS search_fold text
+		GENERATED ALWAYS AS (scripture_fold(coalesce(transliteration, '') || ' ' || coalesce(synonyms, ''))) STORED`)
+	DB.Exec(`CREATE INDEX IF NOT EXISTS idx_scripture_verses_search_ru
+		ON scripture_verses USING GIN (search_vector) WHERE language = 'ru'`)
+	DB.Exec(`CREATE INDEX IF NOT EXISTS idx_scripture_verses_search_en
+		ON scripture_verses USING GIN (search_vector) WHERE language = 'en'`)
+	DB.Exec(`CREATE INDEX IF NOT EXISTS idx_scripture_verses_search_fold
+		ON scripture_verses USING GIN (search_fold gin_trgm_ops)`)
+	// One active enrollment per user and reading plan; finished or left ones stay as history
+	DB.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_reading_plan_enrollment_active
+		ON scripture_reading_plan_enrollments (user_id, plan_id) WHERE status = 'active'`)
+
 	backfillRoomOwnerMemberships()
 
 	// Backfill support conversation channel for legacy rows created before channel field existed.
@@ -192,6 +243,7 @@ func Connect() {
 	FixMultimediaLiveSources()
 	SeedTravel()
 	SeedWallets()
+	SeedWalletLimitTiers()
 	SeedCharity() // Initialize platform wallet and charity settings
 	SeedLKMAccounts()
 	SeedLKMTopup()
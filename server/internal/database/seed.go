@@ -155,6 +155,50 @@ func SeedSystemSettings() {
 			Key:   "CHANNELS_PROMOTED_INSERT_EVERY",
 			Value: "4",
 		},
+		{
+			Key:   "MARKET_ESCROW_FEE_PERCENT",
+			Value: "5",
+		},
+		{
+			Key:   "MARKET_ESCROW_AUTO_REFUND_DAYS",
+			Value: "7",
+		},
+		{
+			Key:   "MARKET_DISPUTE_SELLER_RESPONSE_HOURS",
+			Value: "48",
+		},
+		{
+			Key:   "MARKET_DISPUTE_RESOLUTION_HOURS",
+			Value: "120",
+		},
+		{
+			Key:   "LKM_TOPUP_RECONCILE_AFTER_MINUTES",
+			Value: "30",
+		},
+		{
+			Key:   "LKM_TOPUP_EXPIRE_AFTER_HOURS",
+			Value: "24",
+		},
+		{
+			Key:   "LKM_TOPUP_EXPIRE_WITHOUT_ADAPTER",
+			Value: "false",
+		},
+		{
+			Key:   "WALLET_NEW_ACCOUNT_DAYS",
+			Value: "7",
+		},
+		{
+			Key:   "WALLET_VELOCITY_MAX_RECIPIENTS_24H",
+			Value: "5",
+		},
+		{
+			Key:   "WALLET_VELOCITY_MAX_SENDERS_24H",
+			Value: "5",
+		},
+		{
+			Key:   "WALLET_VELOCITY_PASS_THROUGH_PERCENT",
+			Value: "80",
+		},
 		{
 			Key:   "FCM_SENDER_MODE",
 			Value: "auto",
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"log"
+
+	"rag-agent-server/internal/models"
+
+	"gorm.io/gorm"
+)
+
+func SeedWalletLimitTiers() {
+	if DB == nil {
+		return
+	}
+	if err := SeedWalletLimitTiersWithDB(DB); err != nil {
+		log.Printf("[Seed][WalletLimits] failed: %v", err)
+	}
+}
+
+// SeedWalletLimitTiersWithDB creates the default tiers; admin edits are kept on restart
+func SeedWalletLimitTiersWithDB(db *gorm.DB) error {
+	tiers := []models.WalletLimitTier{
+		{
+			Name:              "new_account",
+			MinAccountAgeDays: 0,
+			DailyLimit:        2000,
+			MonthlyLimit:      10000,
+			SortOrder:         1,
+			IsEnabled:         true,
+		},
+		{
+			Name:              "established",
+			MinAccountAgeDays: 30,
+			DailyLimit:        20000,
+			MonthlyLimit:      100000,
+			SortOrder:         2,
+			IsEnabled:         true,
+		},
+		{
+			Name:              "trusted",
+			MinAccountAgeDays: 180,
+			DailyLimit:        100000,
+			MonthlyLimit:      0,
+			SortOrder:         3,
+			IsEnabled:         true,
+		},
+	}
+	for _, tier := range tiers {
+		if err := db.Where("name = ?", tier.Name).
+			Attrs(models.WalletLimitTier{
+				MinAccountAgeDays: tier.MinAccountAgeDays,
+				DailyLimit:        tier.DailyLimit,
+				MonthlyLimit:      tier.MonthlyLimit,
+				SortOrder:         tier.SortOrder,
+				IsEnabled:         tier.IsEnabled,
+			}).
+			FirstOrCreate(&models.WalletLimitTier{}, models.WalletLimitTier{Name: tier.Name}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -979,6 +979,7 @@ func (h *AuthHandler) UpdateProfile(c *fiber.Ctx) error {
 
 	// Check if city changed
 	cityChanged := updateData.City != "" && updateData.City != user.City
+	birthBefore := services.PairBirthHash(user, models.User{})
 
 	// Update fields
 	user.KarmicName = updateData.KarmicName
@@ -1040,6 +1041,11 @@ func (h *AuthHandler) UpdateProfile(c *fiber.Ctx) error {
 			"error": "Could not update profile",
 		})
 	}
+	if services.PairBirthHash(user, models.User{}) != birthBefore {
+		if err := services.NewGunaMilanService().InvalidateUser(user.ID); err != nil {
+			log.Printf("[Profile] Failed to invalidate Ashtakoota cache for user %d: %v", user.ID, err)
+		}
+	}
 
 	user.Password = ""
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
@@ -14,8 +14,9 @@ import (
 
 // CafeOrderHandler handles cafe order-related HTTP requests
 type CafeOrderHandler struct {
-	orderService *services.CafeOrderService
-	cafeService  *services.CafeService
+	orderService    *services.CafeOrderService
+	cafeService     *services.CafeService
+	paymentRequests *services.PaymentRequestService
 }
 
 // NewCafeOrderHandler creates a new cafe order handler instance
@@ -26,8 +27,9 @@ func NewCafeOrderHandler() *CafeOrderHandler {
 	orderService := services.NewCafeOrderService(database.DB, dishService)
 
 	return &CafeOrderHandler{
-		orderService: orderService,
-		cafeService:  cafeService,
+		orderService:    orderService,
+		cafeService:     cafeService,
+		paymentRequests: services.NewPaymentRequestService(services.NewWalletService()),
 	}
 }
 
@@ -265,6 +267,32 @@ func (h *CafeOrderHandler) MarkAsPaid(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"message": "Order marked as paid"})
 }
 
+// CreatePaymentQR issues an LKM invoice for the order; the guest scans the QR to pay
+// POST /api/cafe-orders/:id/payment-qr
+func (h *CafeOrderHandler) CreatePaymentQR(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	orderID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid order ID"})
+	}
+
+	order, err := h.orderService.GetOrder(uint(orderID))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Order not found"})
+	}
+
+	if !h.hasStaffAccess(order.CafeID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Not authorized"})
+	}
+
+	request, err := h.paymentRequests.CreateForCafeOrder(uint(orderID))
+	if err != nil {
+		return paymentRequestError(c, err)
+	}
+
+	return c.JSON(services.NewPaymentRequestResponse(request))
+}
+
 // ===== Order Item Status =====
 
 // UpdateItemStatus updates the status of an order item
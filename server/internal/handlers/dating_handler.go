@@ -3,10 +3,12 @@ package handlers
 import (
 	"errors"
 	"fmt"
+	"log"
 	"rag-agent-server/internal/database"
 	"rag-agent-server/internal/middleware"
 	"rag-agent-server/internal/models"
 	"rag-agent-server/internal/services"
+	"rag-agent-server/internal/websocket"
 	"strconv"
 	"strings"
 	"time"
@@ -20,6 +22,10 @@ import (
 type DatingHandler struct {
 	aiService       *services.AiChatService
 	domainAssistant *services.DomainAssistantService
+	gunaMilan       *services.GunaMilanService
+	matches         *services.DatingMatchService
+	feed            *services.DatingFeedService
+	hub             *websocket.Hub
 }
 
 func parsePositiveUint(raw string) (uint, error) {
@@ -43,10 +49,14 @@ func requireDatingUserID(c *fiber.Ctx) (uint, error) {
 	return userID, nil
 }
 
-func NewDatingHandler(aiService *services.AiChatService) *DatingHandler {
+func NewDatingHandler(aiService *services.AiChatService, hub *websocket.Hub) *DatingHandler {
 	return &DatingHandler{
 		aiService:       aiService,
 		domainAssistant: services.GetDomainAssistantService(),
+		gunaMilan:       services.NewGunaMilanService(),
+		matches:         services.NewDatingMatchService(),
+		feed:            services.NewDatingFeedService(),
+		hub:             hub,
 	}
 }
 
@@ -98,128 +108,119 @@ func firstNonEmptyDating(values ...string) string {
 	return ""
 }
 
-func (h *DatingHandler) GetCandidates(c *fiber.Ctx) error {
-	var candidates []models.User
-	query := database.DB.Preload("Photos").Where("dating_enabled = ? AND is_profile_complete = ?", true, true)
-
-	// Simple filtering
-	// Parse query params
-	gender := c.Query("gender")
-	city := c.Query("city")
-	madh := c.Query("madh")
-	yogaStyle := c.Query("yogaStyle")
-	guna := c.Query("guna")
-	identity := c.Query("identity")
-	mode := c.Query("mode", "family") // family, business, friendship, seva
-	isNew := c.QueryBool("isNew", false)
-	skills := c.Query("skills")
-	industry := c.Query("industry")
-	minAge := c.QueryInt("minAge", 0)
-	maxAge := c.QueryInt("maxAge", 0)
-	userID := strings.TrimSpace(c.Query("userId"))
-
-	// Apply mode filter
-	if mode != "" {
-		query = query.Where("intentions ILIKE ?", "%"+mode+"%")
-	}
-
-	// Apply skills and industry for business mode
-	if skills != "" {
-		query = query.Where("skills ILIKE ?", "%"+skills+"%")
-	}
-	if industry != "" {
-		query = query.Where("industry ILIKE ?", "%"+industry+"%")
-	}
-
-	// Get current user to determine opposite gender default for family mode
-	var currentUser models.User
-	if userID != "" {
-		if parsedUserID, parseErr := parsePositiveUint(userID); parseErr == nil {
-			if err := database.DB.First(&currentUser, parsedUserID).Error; err == nil {
-				// If no gender specified, default to opposite ONLY in family mode
-				if gender == "" && mode == "family" {
-					switch currentUser.Gender {
-					case "Male":
-						gender = "Female"
-					case "Female":
-						gender = "Male"
-					}
-				}
-				// Exclude the user themselves
-				query = query.Where("id != ?", parsedUserID)
-			}
+// datingFeedQuery reads the feed filters shared by GetCandidates and GetFeed
+func datingFeedQuery(c *fiber.Ctx) services.DatingFeedQuery {
+	viewerID := middleware.GetUserID(c)
+	if viewerID == 0 {
+		if parsed, err := parsePositiveUint(c.Query("userId")); err == nil {
+			viewerID = parsed
 		}
 	}
-
-	// Apply Gender Filter - usually relevant for family, but might be for others too
-	if gender != "" {
-		query = query.Where("gender = ?", gender)
+	return services.DatingFeedQuery{
+		ViewerID:      viewerID,
+		Mode:          strings.TrimSpace(c.Query("mode", "family")), // family, business, friendship, seva
+		Gender:        strings.TrimSpace(c.Query("gender")),
+		City:          strings.TrimSpace(c.Query("city")),
+		Madh:          strings.TrimSpace(c.Query("madh")),
+		YogaStyle:     strings.TrimSpace(c.Query("yogaStyle")),
+		Guna:          strings.TrimSpace(c.Query("guna")),
+		Identity:      strings.TrimSpace(c.Query("identity")),
+		Skills:        strings.TrimSpace(c.Query("skills")),
+		Industry:      strings.TrimSpace(c.Query("industry")),
+		MinAge:        c.QueryInt("minAge", 0),
+		MaxAge:        c.QueryInt("maxAge", 0),
+		IsNew:         c.QueryBool("isNew", false),
+		MaxDistanceKm: c.QueryFloat("maxDistanceKm", 0),
+		Cursor:        c.Query("cursor"),
+		Limit:         c.QueryInt("limit", 0),
 	}
+}
 
-	// Apply City Filter
-	if city != "" {
-		// Case insensitive search might be better, but exact match for now as per previous code
-		query = query.Where("city = ?", city)
+// GetCandidates returns the first page of the ranked feed as a plain list of users
+func (h *DatingHandler) GetCandidates(c *fiber.Ctx) error {
+	query := datingFeedQuery(c)
+	if query.Limit <= 0 {
+		query.Limit = 100
 	}
-
-	// Apply Madh Filter
-	if madh != "" {
-		query = query.Where("madh = ?", madh)
+	page, err := h.feed.Feed(query)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidFeedCursor) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not fetch candidates",
+		})
 	}
 
-	if yogaStyle != "" {
-		query = query.Where("yoga_style = ?", yogaStyle)
+	candidates := make([]models.User, 0, len(page.Items))
+	for _, item := range page.Items {
+		candidates = append(candidates, item.User)
 	}
-
-	if guna != "" {
-		query = query.Where("guna = ?", guna)
+	if page.NextCursor != "" {
+		c.Set("X-Next-Cursor", page.NextCursor)
 	}
+	return c.JSON(candidates)
+}
 
-	if identity != "" {
-		query = query.Where("identity = ?", identity)
+// GetFeed returns a page of ranked candidates with scores and the cursor of the next page
+func (h *DatingHandler) GetFeed(c *fiber.Ctx) error {
+	if _, authErr := requireDatingUserID(c); authErr != nil {
+		return authErr
 	}
-
-	// Apply Age Filter (assuming Dob is YYYY-MM-DD or compatible string)
-	// We need to calculate date thresholds.
-	// Older people have smaller Dob strings.
-	// MinAge 20 => Born BEFORE (Today - 20 years) => Dob <= Date(Today - 20)
-	// MaxAge 30 => Born AFTER (Today - 30 years)  => Dob >= Date(Today - 30)
-
-	now := time.Now().UTC()
-	if isNew {
-		twentyFourHoursAgo := now.Add(-24 * time.Hour)
-		query = query.Where("created_at > ?", twentyFourHoursAgo)
+	page, err := h.feed.Feed(datingFeedQuery(c))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidFeedCursor) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch feed"})
 	}
+	return c.JSON(page)
+}
 
-	if minAge > 0 {
-		// Example: 2024 - 20 = 2004. Limit: 2004-12-25.
-		// User born 2004-12-24 (Age 20) => "2004-12-24" <= "2004-12-25" (True)
-		limitDate := now.AddDate(-minAge, 0, 0).Format("2006-01-02")
-		query = query.Where("dob <= ?", limitDate)
+// MarkFeedSeen records candidates the caller has already been shown
+func (h *DatingHandler) MarkFeedSeen(c *fiber.Ctx) error {
+	authUserID, authErr := requireDatingUserID(c)
+	if authErr != nil {
+		return authErr
 	}
-
-	if maxAge > 0 {
-		// Example: 2024 - 30 = 1994. Limit: 1994-12-25.
-		// User born 1995-01-01 (Age 29) => "1995-01-01" >= "1994-12-25" (True)
-		// User born 1990-01-01 (Age 34) => "1990-01-01" >= "1994-12-25" (False)
-		// We usually want inclusive for the whole year, but exact date is finer.
-		// Let's use exact date for simplicity.
-		// Actually standard age calculation:
-		// Age = 30 means born between [Now-31, Now-30).
-		// Let's stick to simple "At most X years old" => Born after Now - (X+1) years?
-		// If MaxAge is 30, we want everyone who hasn't turned 31 yet.
-		// So born AFTER (Now - 31 years).
-		limitDate := now.AddDate(-(maxAge + 1), 0, 0).Format("2006-01-02")
-		query = query.Where("dob > ?", limitDate)
+	var body struct {
+		CandidateIDs []uint `json:"candidateIds"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+	}
+	if len(body.CandidateIDs) > 200 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Too many candidates"})
+	}
+	if err := h.feed.MarkSeen(authUserID, body.CandidateIDs); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not save feed history"})
 	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
 
-	if err := query.Find(&candidates).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Could not fetch candidates",
-		})
+// PassCandidate hides a candidate from the caller's feed; DELETE brings them back
+func (h *DatingHandler) PassCandidate(c *fiber.Ctx) error {
+	authUserID, authErr := requireDatingUserID(c)
+	if authErr != nil {
+		return authErr
+	}
+	candidateID, err := parsePositiveUint(c.Params("candidateId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid candidate ID"})
 	}
 
-	return c.JSON(candidates)
+	if c.Method() == fiber.MethodDelete {
+		err = h.feed.Unpass(authUserID, candidateID)
+	} else {
+		err = h.feed.Pass(authUserID, candidateID)
+	}
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidFeedCandidate) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not save feed history"})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
 }
 
 func (h *DatingHandler) GetCompatibility(c *fiber.Ctx) error {
@@ -235,26 +236,40 @@ func (h *DatingHandler) GetCompatibility(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot calculate compatibility with yourself"})
 	}
 
-	// Check cache first
+	var user, candidate models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	if err := database.DB.First(&candidate, candidateID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Candidate not found"})
+	}
+
+	// The score itself is computed offline; the AI only explains it
+	ashtakoota, err := h.gunaMilan.Compute(user, candidate)
+	if err != nil && !errors.Is(err, services.ErrBirthDataMissing) {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not calculate Ashtakoota"})
+	}
+	birthHash := services.PairBirthHash(user, candidate)
+
+	// Check cache first; explanations written for older birth data are ignored
 	var cached models.DatingCompatibility
-	if err := database.DB.Where("user_id = ? AND candidate_id = ?", userID, candidateID).Order("created_at DESC").First(&cached).Error; err == nil {
+	if err := database.DB.Where("user_id = ? AND candidate_id = ? AND birth_hash = ?", userID, candidateID, birthHash).Order("created_at DESC").First(&cached).Error; err == nil {
 		lowerCached := strings.ToLower(strings.TrimSpace(cached.CompatibilityText))
 		if !strings.Contains(lowerCached, "не найдено достаточно данных") {
 			return c.JSON(fiber.Map{
 				"compatibility": cached.CompatibilityText,
+				"ashtakoota":    ashtakoota,
 			})
 		}
 	}
 
-	var user, candidate models.User
-	if err := database.DB.First(&user, userID).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
-	}
-	if err := database.DB.First(&candidate, candidateID).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Candidate not found"})
-	}
-
 	if h.aiService == nil {
+		if ashtakoota != nil {
+			return c.JSON(fiber.Map{
+				"compatibility": services.FormatGunaMilanSummary(ashtakoota),
+				"ashtakoota":    ashtakoota,
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "AI service not available"})
 	}
 
@@ -315,14 +330,22 @@ func (h *DatingHandler) GetCompatibility(c *fiber.Ctx) error {
 - О себе: %s
 ---
 
+%s
 %s`,
 		user.SpiritualName,
 		user.SpiritualName, user.Interests, user.Madh, user.Dob, user.BirthTime, user.BirthPlaceLink, user.Bio,
 		candidate.SpiritualName, candidate.Interests, candidate.Madh, candidate.Dob, candidate.BirthTime, candidate.BirthPlaceLink, candidate.Bio,
+		buildAshtakootaPromptBlock(ashtakoota),
 		ragBlock)
 
 	resp, err := h.aiService.GeneratePromptOnlyResponse(prompt)
 	if err != nil {
+		if ashtakoota != nil {
+			return c.JSON(fiber.Map{
+				"compatibility": services.FormatGunaMilanSummary(ashtakoota),
+				"ashtakoota":    ashtakoota,
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
@@ -342,14 +365,61 @@ func (h *DatingHandler) GetCompatibility(c *fiber.Ctx) error {
 		UserID:            userID,
 		CandidateID:       candidateID,
 		CompatibilityText: compatibility,
+		BirthHash:         birthHash,
 	}
 	database.DB.Create(&newCache)
 
 	return c.JSON(fiber.Map{
 		"compatibility": compatibility,
+		"ashtakoota":    ashtakoota,
 	})
 }
 
+// GetAshtakoota returns the deterministic Guna Milan breakdown between the caller and a candidate
+func (h *DatingHandler) GetAshtakoota(c *fiber.Ctx) error {
+	authUserID, authErr := requireDatingUserID(c)
+	if authErr != nil {
+		return authErr
+	}
+	candidateID, err := parsePositiveUint(c.Params("candidateId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid candidate ID"})
+	}
+	if authUserID == candidateID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot calculate compatibility with yourself"})
+	}
+
+	var user, candidate models.User
+	if err := database.DB.First(&user, authUserID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	if err := database.DB.First(&candidate, candidateID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Candidate not found"})
+	}
+
+	result, err := h.gunaMilan.Compute(user, candidate)
+	if err != nil {
+		if errors.Is(err, services.ErrBirthDataMissing) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not calculate Ashtakoota"})
+	}
+	return c.JSON(result)
+}
+
+// buildAshtakootaPromptBlock hands the computed breakdown to the model so that it explains
+// the numbers instead of inventing its own
+func buildAshtakootaPromptBlock(result *services.GunaMilanResult) string {
+	if result == nil {
+		return "Расчёт Ашта-куты недоступен: у одного из партнёров не указана дата рождения. Не придумывай баллы по кутам.\n"
+	}
+	var b strings.Builder
+	b.WriteString("РАССЧИТАННАЯ АШТА-КУТА (Гуна Милан). Эти значения точные: объясняй их, НЕ пересчитывай и не меняй баллы.\n")
+	b.WriteString(services.FormatGunaMilanSummary(result))
+	b.WriteString("\n")
+	return b.String()
+}
+
 func (h *DatingHandler) UpdateDatingProfile(c *fiber.Ctx) error {
 	authUserID, authErr := requireDatingUserID(c)
 	if authErr != nil {
@@ -370,14 +440,16 @@ func (h *DatingHandler) UpdateDatingProfile(c *fiber.Ctx) error {
 
 	// Use a struct with pointers for partial updates (handles zero values and correct mapping)
 	var updates struct {
-		Bio            *string `json:"bio"`
-		Interests      *string `json:"interests"`
-		LookingFor     *string `json:"lookingFor"`
-		MaritalStatus  *string `json:"maritalStatus"`
-		Dob            *string `json:"dob"`
-		BirthTime      *string `json:"birthTime"`
-		BirthPlaceLink *string `json:"birthPlaceLink"`
-		City           *string `json:"city"`
+		Bio            *string  `json:"bio"`
+		Interests      *string  `json:"interests"`
+		LookingFor     *string  `json:"lookingFor"`
+		MaritalStatus  *string  `json:"maritalStatus"`
+		Dob            *string  `json:"dob"`
+		BirthTime      *string  `json:"birthTime"`
+		BirthPlaceLink *string  `json:"birthPlaceLink"`
+		BirthLatitude  *float64 `json:"birthLatitude"`
+		BirthLongitude *float64 `json:"birthLongitude"`
+		City           *string  `json:"city"`
 
 		Madh               *string `json:"madh"`
 		YogaStyle          *string `json:"yogaStyle"`
@@ -418,6 +490,18 @@ func (h *DatingHandler) UpdateDatingProfile(c *fiber.Ctx) error {
 	if updates.BirthPlaceLink != nil {
 		updateMap["birth_place_link"] = *updates.BirthPlaceLink
 	}
+	if updates.BirthLatitude != nil {
+		if *updates.BirthLatitude < -90 || *updates.BirthLatitude > 90 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid birth latitude"})
+		}
+		updateMap["birth_latitude"] = *updates.BirthLatitude
+	}
+	if updates.BirthLongitude != nil {
+		if *updates.BirthLongitude < -180 || *updates.BirthLongitude > 180 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid birth longitude"})
+		}
+		updateMap["birth_longitude"] = *updates.BirthLongitude
+	}
 	if updates.City != nil {
 		updateMap["city"] = *updates.City
 	}
@@ -456,12 +540,18 @@ func (h *DatingHandler) UpdateDatingProfile(c *fiber.Ctx) error {
 		return c.JSON(user)
 	}
 
+	birthBefore := services.PairBirthHash(user, models.User{})
 	if err := database.DB.Model(&user).Updates(updateMap).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update profile"})
 	}
 	if err := database.DB.Preload("Photos").First(&user, user.ID).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not load updated profile"})
 	}
+	if services.PairBirthHash(user, models.User{}) != birthBefore {
+		if err := h.gunaMilan.InvalidateUser(user.ID); err != nil {
+			log.Printf("[Dating] Failed to invalidate Ashtakoota cache for user %d: %v", user.ID, err)
+		}
+	}
 
 	return c.JSON(user)
 }
@@ -497,9 +587,17 @@ func (h *DatingHandler) AddToFavorites(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not load candidate"})
 	}
 
+	blocked, err := h.matches.IsBlocked(body.UserID, body.CandidateID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not check block status"})
+	}
+	if blocked {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": services.ErrDatingUserBlocked.Error()})
+	}
+
 	var existing models.DatingFavorite
 	if err := database.DB.Where("user_id = ? AND candidate_id = ?", body.UserID, body.CandidateID).First(&existing).Error; err == nil {
-		return c.JSON(existing)
+		return c.JSON(favoriteResponse{DatingFavorite: existing, Match: h.detectMatch(body.UserID, body.CandidateID)})
 	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not check favorites"})
 	}
@@ -514,7 +612,84 @@ func (h *DatingHandler) AddToFavorites(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not add to favorites"})
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(favorite)
+	return c.Status(fiber.StatusCreated).JSON(favoriteResponse{DatingFavorite: favorite, Match: h.detectMatch(body.UserID, body.CandidateID)})
+}
+
+// favoriteResponse keeps the favorite fields flat and adds the match when the like was reciprocal
+type favoriteResponse struct {
+	models.DatingFavorite
+	Match *models.DatingMatch `json:"match,omitempty"`
+}
+
+// detectMatch turns a reciprocal like into a match and notifies both users. A failure
+// here must not fail the like itself, so it is only logged.
+func (h *DatingHandler) detectMatch(userID, candidateID uint) *models.DatingMatch {
+	match, created, err := h.matches.DetectMatch(userID, candidateID)
+	if err != nil {
+		log.Printf("[Dating] Match detection failed for %d -> %d: %v", userID, candidateID, err)
+		return nil
+	}
+	if match != nil && created {
+		h.broadcastMatchEvent(websocket.EventDatingMatch, userID, match)
+		go h.matches.NotifyMatch(match)
+	}
+	return match
+}
+
+func (h *DatingHandler) broadcastMatchEvent(eventType string, actorID uint, match *models.DatingMatch) {
+	if h.hub == nil {
+		return
+	}
+	roomID := uint(0)
+	if match.RoomID != nil {
+		roomID = *match.RoomID
+	}
+	h.hub.BroadcastEvent(websocket.MessageEvent{
+		Type:     eventType,
+		SenderID: actorID,
+		RoomID:   roomID,
+		Data:     match,
+	}, match.UserAID, match.UserBID)
+}
+
+// GetMatches lists the caller's active mutual matches
+func (h *DatingHandler) GetMatches(c *fiber.Ctx) error {
+	authUserID, authErr := requireDatingUserID(c)
+	if authErr != nil {
+		return authErr
+	}
+
+	matches, err := h.matches.ListMatches(authUserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch matches"})
+	}
+	return c.JSON(matches)
+}
+
+// Unmatch ends a match and blocks the partner
+func (h *DatingHandler) Unmatch(c *fiber.Ctx) error {
+	authUserID, authErr := requireDatingUserID(c)
+	if authErr != nil {
+		return authErr
+	}
+	matchID, err := parsePositiveUint(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid match ID"})
+	}
+
+	match, err := h.matches.Unmatch(authUserID, matchID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrDatingMatchNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, services.ErrDatingMatchForbidden):
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not unmatch"})
+	}
+
+	h.broadcastMatchEvent(websocket.EventDatingUnmatch, authUserID, match)
+	return c.JSON(match)
 }
 
 func (h *DatingHandler) GetFavorites(c *fiber.Ctx) error {
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"rag-agent-server/internal/middleware"
+	"rag-agent-server/internal/services"
+	"rag-agent-server/internal/websocket"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// JointReadingHandler runs the joint reading protocol of rooms. Commands arrive over the
+// hub ("reading_<action>") or REST; every change is pushed to all room members.
+type JointReadingHandler struct {
+	reading *services.JointReadingService
+	hub     *websocket.Hub
+}
+
+func NewJointReadingHandler(hub *websocket.Hub) *JointReadingHandler {
+	return &JointReadingHandler{reading: services.NewJointReadingService(), hub: hub}
+}
+
+type readingCommandRequest struct {
+	TargetUserID  uint  `json:"targetUserId"`
+	Canto         *int  `json:"canto"`
+	Chapter       *int  `json:"chapter"`
+	Verse         *int  `json:"verse"`
+	ExpectChapter *int  `json:"expectChapter"`
+	ExpectVerse   *int  `json:"expectVerse"`
+	ShowPurport   *bool `json:"showPurport"`
+}
+
+func (r readingCommandRequest) command(roomID, userID uint, action string) services.ReadingCommand {
+	return services.ReadingCommand{
+		RoomID:        roomID,
+		UserID:        userID,
+		Action:        strings.ToLower(strings.TrimSpace(action)),
+		TargetUserID:  r.TargetUserID,
+		Canto:         r.Canto,
+		Chapter:       r.Chapter,
+		Verse:         r.Verse,
+		ExpectChapter: r.ExpectChapter,
+		ExpectVerse:   r.ExpectVerse,
+		ShowPurport:   r.ShowPurport,
+	}
+}
+
+// HandleWSCommand applies {"type":"reading_<action>","roomId":N,"targetId":N,"payload":{...}}.
+// Late joiners send "reading_state" and get the current state back; failures are reported
+// to the sender only as reading_error.
+func (h *JointReadingHandler) HandleWSCommand(userID uint, action string, roomID, targetID uint, payload interface{}) {
+	var req readingCommandRequest
+	if payload != nil {
+		if raw, err := json.Marshal(payload); err == nil {
+			_ = json.Unmarshal(raw, &req)
+		}
+	}
+	if req.TargetUserID == 0 {
+		req.TargetUserID = targetID
+	}
+	if _, err := h.apply(req.command(roomID, userID, action)); err != nil {
+		if !isReadingClientError(err) {
+			log.Printf("[JointReading] ws command failed user=%d room=%d action=%s: %v", userID, roomID, action, err)
+			err = errors.New("Could not process reading command")
+		}
+		h.hub.BroadcastReading(websocket.ReadingEvent{
+			Type:     websocket.EventReadingError,
+			RoomID:   roomID,
+			SenderID: userID,
+			Reason:   action,
+			Data:     fiber.Map{"error": err.Error()},
+		}, userID)
+	}
+}
+
+// apply runs the command and pushes the resulting state: to every member after a change,
+// to the requesting user for a state request
+func (h *JointReadingHandler) apply(cmd services.ReadingCommand) (*services.ReadingUpdate, error) {
+	update, err := h.reading.Apply(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if h.hub == nil {
+		return update, nil
+	}
+	targets := update.MemberIDs
+	if len(targets) == 0 {
+		targets = []uint{cmd.UserID}
+	}
+	h.hub.BroadcastReading(websocket.ReadingEvent{
+		Type:     websocket.EventReadingState,
+		RoomID:   cmd.RoomID,
+		SenderID: cmd.UserID,
+		Reason:   cmd.Action,
+		Data:     update,
+	}, targets...)
+	return update, nil
+}
+
+// BroadcastRoomState pushes the current state to all members, e.g. after room settings changed
+func (h *JointReadingHandler) BroadcastRoomState(roomID, actorID uint) {
+	state, err := h.reading.State(roomID, actorID)
+	if err != nil {
+		log.Printf("[JointReading] state broadcast failed room=%d: %v", roomID, err)
+		return
+	}
+	memberIDs, err := getRoomMemberUserIDs(roomID)
+	if err != nil {
+		log.Printf("[JointReading] member lookup failed room=%d: %v", roomID, err)
+		return
+	}
+	h.hub.BroadcastReading(websocket.ReadingEvent{
+		Type:     websocket.EventReadingState,
+		RoomID:   roomID,
+		SenderID: actorID,
+		Reason:   "room_settings",
+		Data:     services.ReadingUpdate{State: *state},
+	}, memberIDs...)
+}
+
+func isReadingClientError(err error) bool {
+	for _, target := range []error{
+		services.ErrReadingRoomNotFound,
+		services.ErrReadingNotMember,
+		services.ErrReadingForbidden,
+		services.ErrReadingNoSession,
+		services.ErrReadingSessionActive,
+		services.ErrReadingStale,
+		services.ErrReadingEndOfBook,
+		services.ErrInvalidReadingCommand,
+		services.ErrReadingTargetNotInRoom,
+		services.ErrReadingSessionNotFound,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func respondReadingError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrReadingRoomNotFound), errors.Is(err, services.ErrReadingSessionNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrReadingNotMember), errors.Is(err, services.ErrReadingForbidden):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrReadingNoSession),
+		errors.Is(err, services.ErrReadingSessionActive),
+		errors.Is(err, services.ErrReadingStale),
+		errors.Is(err, services.ErrReadingEndOfBook):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidReadingCommand), errors.Is(err, services.ErrReadingTargetNotInRoom):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	default:
+		log.Printf("[JointReading] request failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not process reading request"})
+	}
+}
+
+// GetState handles GET /api/rooms/:id/reading
+func (h *JointReadingHandler) GetState(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	roomID, err := parseRoomIDParam(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid room id"})
+	}
+	state, err := h.reading.State(roomID, userID)
+	if err != nil {
+		return respondReadingError(c, err)
+	}
+	return c.JSON(state)
+}
+
+// Command handles POST /api/rooms/:id/reading/:action for clients without a socket
+func (h *JointReadingHandler) Command(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	roomID, err := parseRoomIDParam(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid room id"})
+	}
+	var req readingCommandRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+	update, err := h.apply(req.command(roomID, userID, c.Params("action")))
+	if err != nil {
+		return respondReadingError(c, err)
+	}
+	return c.JSON(update)
+}
+
+// ListSessions handles GET /api/rooms/:id/reading/sessions?limit=
+func (h *JointReadingHandler) ListSessions(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	roomID, err := parseRoomIDParam(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid room id"})
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	sessions, err := h.reading.ListSessions(roomID, userID, limit)
+	if err != nil {
+		return respondReadingError(c, err)
+	}
+	return c.JSON(fiber.Map{"sessions": sessions})
+}
+
+// GetLog handles GET /api/rooms/:id/reading/log?sessionId= (latest session by default)
+func (h *JointReadingHandler) GetLog(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	roomID, err := parseRoomIDParam(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid room id"})
+	}
+	sessionID, _, err := parseOptionalPositiveUint(c.Query("sessionId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid session id"})
+	}
+	sessionLog, err := h.reading.SessionLog(roomID, userID, sessionID)
+	if err != nil {
+		return respondReadingError(c, err)
+	}
+	return c.JSON(sessionLog)
+}
@@ -1,8 +1,14 @@
 package handlers
 
 import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
 	"rag-agent-server/internal/database"
 	"rag-agent-server/internal/models"
+	"rag-agent-server/internal/services"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -145,10 +151,15 @@ func GetLibraryVerses(c *fiber.Ctx) error {
 	return c.JSON(verses)
 }
 
-// ExportLibraryBook returns all verses for a book, optionally filtered by language
+// ExportLibraryBook returns all verses for a book, optionally filtered by language.
+// With ?format=epub|pdf|md|txt the book is rendered and streamed as a download instead,
+// see exportLibraryBookFile.
 func ExportLibraryBook(c *fiber.Ctx) error {
 	bookCode := c.Params("bookCode")
 	language := c.Query("language") // "ru", "en", or empty for both
+	if format := strings.ToLower(strings.TrimSpace(c.Query("format"))); format != "" && format != services.LibraryExportJSON {
+		return exportLibraryBookFile(c, bookCode)
+	}
 
 	query := database.DB.Model(&models.ScriptureVerse{}).Where("book_code = ?", bookCode)
 
@@ -164,22 +175,100 @@ func ExportLibraryBook(c *fiber.Ctx) error {
 	return c.JSON(verses)
 }
 
-// SearchLibrary searches through verses
+// exportLibraryBookFile streams a rendered book.
+// Query: format, language (ru|en, primary language), bilingual (adds the other language side by side), canto, chapter
+func exportLibraryBookFile(c *fiber.Ctx, bookCode string) error {
+	canto, _, err := parseOptionalPositiveUint(c.Query("canto"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid canto"})
+	}
+	chapter, _, err := parseOptionalPositiveUint(c.Query("chapter"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid chapter"})
+	}
+
+	exporter := services.NewLibraryExportService()
+	plan, err := exporter.Prepare(services.LibraryExportOptions{
+		BookCode:  bookCode,
+		Format:    c.Query("format"),
+		Canto:     int(canto),
+		Chapter:   int(chapter),
+		Language:  c.Query("language"),
+		Bilingual: c.QueryBool("bilingual", false),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrLibraryBookNotFound), errors.Is(err, services.ErrLibraryExportEmpty):
+			return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, services.ErrUnsupportedExportFormat), errors.Is(err, services.ErrUnsupportedExportLanguage):
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, services.ErrPDFExportUnavailable):
+			return c.Status(503).JSON(fiber.Map{"error": services.ErrPDFExportUnavailable.Error()})
+		default:
+			log.Printf("[Library] export prepare failed book=%s: %v", bookCode, err)
+			return c.Status(500).JSON(fiber.Map{"error": "Failed to export book data"})
+		}
+	}
+
+	c.Set(fiber.HeaderContentType, plan.ContentType())
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, plan.FileName()))
+	// Verses are loaded chapter by chapter while the body is being sent
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := exporter.Write(w, plan); err != nil {
+			log.Printf("[Library] export failed book=%s format=%s: %v", bookCode, plan.Options.Format, err)
+		}
+		if err := w.Flush(); err != nil {
+			log.Printf("[Library] export flush failed book=%s: %v", bookCode, err)
+		}
+	})
+	return nil
+}
+
+// legacyLibrarySearchLimit is the result count of unpaged searches, as before paging existed
+const legacyLibrarySearchLimit = 50
+
+// SearchLibrary handles GET /api/library/search
+// Query: q (required), bookCode, canto, chapter, language (ru|en), limit, offset
+// Without limit and offset the response is a bare array of hits for older clients;
+// with either of them it is a page {items, hasMore, nextOffset}.
 func SearchLibrary(c *fiber.Ctx) error {
-	q := c.Query("q")
-	if q == "" {
+	if strings.TrimSpace(c.Query("q")) == "" {
 		return c.Status(400).JSON(fiber.Map{"error": "Query parameter 'q' is required"})
 	}
 
-	var verses []models.ScriptureVerse
-	// Search in translation, synonym, purport, etc.
-	searchTerm := "%" + q + "%"
-	if err := database.DB.Where(
-		"translation ILIKE ? OR purport ILIKE ? OR synonyms ILIKE ?",
-		searchTerm, searchTerm, searchTerm,
-	).Limit(50).Find(&verses).Error; err != nil {
+	filters := services.LibrarySearchFilters{
+		Query:    c.Query("q"),
+		BookCode: c.Query("bookCode"),
+		Language: c.Query("language"),
+		Limit:    c.QueryInt("limit", 0),
+		Offset:   c.QueryInt("offset", 0),
+	}
+	canto, _, err := parseOptionalPositiveUint(c.Query("canto"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid canto"})
+	}
+	chapter, _, err := parseOptionalPositiveUint(c.Query("chapter"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid chapter"})
+	}
+	filters.Canto = int(canto)
+	filters.Chapter = int(chapter)
+	paged := c.Query("limit") != "" || c.Query("offset") != ""
+	if !paged {
+		filters.Limit = legacyLibrarySearchLimit
+	}
+
+	result, err := services.NewLibrarySearchService().Search(filters)
+	if err != nil {
+		if errors.Is(err, services.ErrSearchQueryTooShort) || errors.Is(err, services.ErrUnsupportedSearchLanguage) {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		log.Printf("[Library] search failed q=%q: %v", filters.Query, err)
 		return c.Status(500).JSON(fiber.Map{"error": "Search failed"})
 	}
 
-	return c.JSON(verses)
+	if !paged {
+		return c.JSON(result.Items)
+	}
+	return c.JSON(result)
 }
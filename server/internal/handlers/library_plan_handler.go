@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"rag-agent-server/internal/middleware"
+	"rag-agent-server/internal/models"
+	"rag-agent-server/internal/services"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// LibraryPlanHandler serves reading plans and per-user enrollments
+type LibraryPlanHandler struct {
+	plans *services.LibraryPlanService
+}
+
+func NewLibraryPlanHandler() *LibraryPlanHandler {
+	return &LibraryPlanHandler{plans: services.NewLibraryPlanService()}
+}
+
+type enrollPlanRequest struct {
+	StartDate    string `json:"start_date"` // YYYY-MM-DD in the user's timezone, defaults to today
+	ReminderHour *int   `json:"reminder_hour"`
+}
+
+type planReminderRequest struct {
+	ReminderHour *int `json:"reminder_hour"` // null disables reminders
+}
+
+func respondPlanError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrReadingPlanNotFound),
+		errors.Is(err, services.ErrPlanEnrollmentNotFound),
+		errors.Is(err, services.ErrLibraryBookNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrAlreadyEnrolled), errors.Is(err, services.ErrPlanEnrollmentNotActive):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidReadingPlan),
+		errors.Is(err, services.ErrReadingPlanEmpty),
+		errors.Is(err, services.ErrInvalidPlanDay),
+		errors.Is(err, services.ErrInvalidReminderHour):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	default:
+		log.Printf("[LibraryPlan] request failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not process reading plan request"})
+	}
+}
+
+// ListPlans handles GET /api/library/plans?bookCode=
+func (h *LibraryPlanHandler) ListPlans(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	plans, err := h.plans.ListPlans(userID, c.Query("bookCode"))
+	if err != nil {
+		return respondPlanError(c, err)
+	}
+	return c.JSON(fiber.Map{"plans": plans})
+}
+
+// CreatePlan handles POST /api/library/plans. Only admins may publish a plan to everyone.
+func (h *LibraryPlanHandler) CreatePlan(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var req services.ReadingPlanInput
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if req.IsPublic && !models.IsAdminRole(middleware.GetUserRole(c)) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Only admins can publish reading plans"})
+	}
+	plan, err := h.plans.CreatePlan(userID, req)
+	if err != nil {
+		return respondPlanError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(plan)
+}
+
+// GetPlan handles GET /api/library/plans/:id
+func (h *LibraryPlanHandler) GetPlan(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	planID, err := parseRequiredPositiveUint(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid plan ID"})
+	}
+	details, err := h.plans.GetPlan(userID, planID)
+	if err != nil {
+		return respondPlanError(c, err)
+	}
+	return c.JSON(details)
+}
+
+// DeletePlan handles DELETE /api/library/plans/:id
+func (h *LibraryPlanHandler) DeletePlan(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	planID, err := parseRequiredPositiveUint(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid plan ID"})
+	}
+	if err := h.plans.DeletePlan(userID, planID, models.IsAdminRole(middleware.GetUserRole(c))); err != nil {
+		return respondPlanError(c, err)
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
+
+// Enroll handles POST /api/library/plans/:id/enroll
+func (h *LibraryPlanHandler) Enroll(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	planID, err := parseRequiredPositiveUint(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid plan ID"})
+	}
+	var req enrollPlanRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+	enrollment, err := h.plans.Enroll(userID, planID, req.StartDate, req.ReminderHour)
+	if err != nil {
+		return respondPlanError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(enrollment)
+}
+
+// ListEnrollments handles GET /api/library/plans/enrollments?status=
+func (h *LibraryPlanHandler) ListEnrollments(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	enrollments, err := h.plans.ListEnrollments(userID, strings.ToLower(c.Query("status")))
+	if err != nil {
+		return respondPlanError(c, err)
+	}
+	return c.JSON(fiber.Map{"enrollments": enrollments})
+}
+
+// Today handles GET /api/library/plans/enrollments/:id/today
+func (h *LibraryPlanHandler) Today(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	enrollmentID, err := parseRequiredPositiveUint(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid enrollment ID"})
+	}
+	today, err := h.plans.Today(userID, enrollmentID)
+	if err != nil {
+		return respondPlanError(c, err)
+	}
+	return c.JSON(today)
+}
+
+// GetDay handles GET /api/library/plans/enrollments/:id/days/:day
+func (h *LibraryPlanHandler) GetDay(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	enrollmentID, dayNumber, err := parseEnrollmentDay(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	day, err := h.plans.Day(userID, enrollmentID, dayNumber)
+	if err != nil {
+		return respondPlanError(c, err)
+	}
+	return c.JSON(day)
+}
+
+// CompleteDay handles POST /api/library/plans/enrollments/:id/days/:day/complete
+func (h *LibraryPlanHandler) CompleteDay(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	enrollmentID, dayNumber, err := parseEnrollmentDay(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	enrollment, err := h.plans.CompleteDay(userID, enrollmentID, dayNumber)
+	if err != nil {
+		return respondPlanError(c, err)
+	}
+	return c.JSON(enrollment)
+}
+
+// UpdateReminder handles PUT /api/library/plans/enrollments/:id/reminder
+func (h *LibraryPlanHandler) UpdateReminder(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	enrollmentID, err := parseRequiredPositiveUint(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid enrollment ID"})
+	}
+	var req planReminderRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	enrollment, err := h.plans.UpdateReminder(userID, enrollmentID, req.ReminderHour)
+	if err != nil {
+		return respondPlanError(c, err)
+	}
+	return c.JSON(enrollment)
+}
+
+// Leave handles DELETE /api/library/plans/enrollments/:id
+func (h *LibraryPlanHandler) Leave(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	enrollmentID, err := parseRequiredPositiveUint(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid enrollment ID"})
+	}
+	if err := h.plans.Leave(userID, enrollmentID); err != nil {
+		return respondPlanError(c, err)
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
+
+func parseEnrollmentDay(c *fiber.Ctx) (uint, int, error) {
+	enrollmentID, err := parseRequiredPositiveUint(c.Params("id"))
+	if err != nil {
+		return 0, 0, errors.New("Invalid enrollment ID")
+	}
+	dayNumber, err := strconv.Atoi(strings.TrimSpace(c.Params("day")))
+	if err != nil || dayNumber < 1 {
+		return 0, 0, errors.New("Invalid day number")
+	}
+	return enrollmentID, dayNumber, nil
+}
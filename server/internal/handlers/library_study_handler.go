@@ -0,0 +1,401 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"rag-agent-server/internal/middleware"
+	"rag-agent-server/internal/services"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// LibraryStudyHandler serves the per-user study layer over the scripture library
+type LibraryStudyHandler struct {
+	study *services.LibraryStudyService
+}
+
+func NewLibraryStudyHandler() *LibraryStudyHandler {
+	return &LibraryStudyHandler{study: services.NewLibraryStudyService()}
+}
+
+type bookmarkRequest struct {
+	Label string `json:"label"`
+}
+
+type highlightColorRequest struct {
+	Color string `json:"color"`
+}
+
+type noteUpdateRequest struct {
+	Content    *string `json:"content"`
+	Visibility *string `json:"visibility"`
+}
+
+type progressRequest struct {
+	VerseID uint       `json:"verse_id"`
+	ReadAt  *time.Time `json:"read_at"`
+}
+
+func parseStudyFilter(c *fiber.Ctx) (services.StudyFilter, error) {
+	filter := services.StudyFilter{BookCode: strings.TrimSpace(c.Query("bookCode"))}
+	canto, _, err := parseOptionalPositiveUint(c.Query("canto"))
+	if err != nil {
+		return filter, err
+	}
+	chapter, _, err := parseOptionalPositiveUint(c.Query("chapter"))
+	if err != nil {
+		return filter, err
+	}
+	verseID, _, err := parseOptionalPositiveUint(c.Query("verseId"))
+	if err != nil {
+		return filter, err
+	}
+	filter.Canto = int(canto)
+	filter.Chapter = int(chapter)
+	filter.VerseID = verseID
+	return filter, nil
+}
+
+func respondStudyError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrVerseNotFound), errors.Is(err, services.ErrStudyItemNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidHighlight), errors.Is(err, services.ErrInvalidNote):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	default:
+		log.Printf("[LibraryStudy] request failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update study data"})
+	}
+}
+
+// ListBookmarks handles GET /api/library/study/bookmarks?bookCode=&canto=&chapter=
+func (h *LibraryStudyHandler) ListBookmarks(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	filter, err := parseStudyFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid filter"})
+	}
+	items, err := h.study.ListBookmarks(userID, filter)
+	if err != nil {
+		return respondStudyError(c, err)
+	}
+	return c.JSON(fiber.Map{"bookmarks": items})
+}
+
+// SetBookmark handles PUT /api/library/study/bookmarks/:verseId
+func (h *LibraryStudyHandler) SetBookmark(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	verseID, err := parseRequiredPositiveUint(c.Params("verseId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid verse ID"})
+	}
+	var req bookmarkRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+	bookmark, err := h.study.SetBookmark(userID, verseID, req.Label)
+	if err != nil {
+		return respondStudyError(c, err)
+	}
+	return c.JSON(bookmark)
+}
+
+// RemoveBookmark handles DELETE /api/library/study/bookmarks/:verseId
+func (h *LibraryStudyHandler) RemoveBookmark(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	verseID, err := parseRequiredPositiveUint(c.Params("verseId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid verse ID"})
+	}
+	if err := h.study.RemoveBookmark(userID, verseID); err != nil {
+		return respondStudyError(c, err)
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
+
+// ListHighlights handles GET /api/library/study/highlights?bookCode=&canto=&chapter=&verseId=
+func (h *LibraryStudyHandler) ListHighlights(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	filter, err := parseStudyFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid filter"})
+	}
+	items, err := h.study.ListHighlights(userID, filter)
+	if err != nil {
+		return respondStudyError(c, err)
+	}
+	return c.JSON(fiber.Map{"highlights": items, "colors": services.HighlightColors})
+}
+
+// AddHighlight handles POST /api/library/study/highlights
+func (h *LibraryStudyHandler) AddHighlight(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var req services.HighlightInput
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	highlight, err := h.study.AddHighlight(userID, req)
+	if err != nil {
+		return respondStudyError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(highlight)
+}
+
+// UpdateHighlight handles PATCH /api/library/study/highlights/:id
+func (h *LibraryStudyHandler) UpdateHighlight(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	id, err := parseRequiredPositiveUint(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid highlight ID"})
+	}
+	var req highlightColorRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	highlight, err := h.study.UpdateHighlightColor(userID, id, req.Color)
+	if err != nil {
+		return respondStudyError(c, err)
+	}
+	return c.JSON(highlight)
+}
+
+// DeleteHighlight handles DELETE /api/library/study/highlights/:id
+func (h *LibraryStudyHandler) DeleteHighlight(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	id, err := parseRequiredPositiveUint(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid highlight ID"})
+	}
+	if err := h.study.DeleteHighlight(userID, id); err != nil {
+		return respondStudyError(c, err)
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
+
+// ListNotes handles GET /api/library/study/notes?bookCode=&canto=&chapter=&verseId=
+func (h *LibraryStudyHandler) ListNotes(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	filter, err := parseStudyFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid filter"})
+	}
+	items, err := h.study.ListNotes(userID, filter)
+	if err != nil {
+		return respondStudyError(c, err)
+	}
+	return c.JSON(fiber.Map{"notes": items})
+}
+
+// GetVerseNotes handles GET /api/library/study/verses/:verseId/notes (own and shared notes)
+func (h *LibraryStudyHandler) GetVerseNotes(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	verseID, err := parseRequiredPositiveUint(c.Params("verseId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid verse ID"})
+	}
+	notes, err := h.study.VerseNotes(userID, verseID)
+	if err != nil {
+		return respondStudyError(c, err)
+	}
+	return c.JSON(fiber.Map{"notes": notes})
+}
+
+// AddNote handles POST /api/library/study/notes
+func (h *LibraryStudyHandler) AddNote(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var req services.NoteInput
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	note, err := h.study.AddNote(userID, req)
+	if err != nil {
+		return respondStudyError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(note)
+}
+
+// UpdateNote handles PATCH /api/library/study/notes/:id
+func (h *LibraryStudyHandler) UpdateNote(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	id, err := parseRequiredPositiveUint(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid note ID"})
+	}
+	var req noteUpdateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	note, err := h.study.UpdateNote(userID, id, req.Content, req.Visibility)
+	if err != nil {
+		return respondStudyError(c, err)
+	}
+	return c.JSON(note)
+}
+
+// DeleteNote handles DELETE /api/library/study/notes/:id
+func (h *LibraryStudyHandler) DeleteNote(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	id, err := parseRequiredPositiveUint(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid note ID"})
+	}
+	if err := h.study.DeleteNote(userID, id); err != nil {
+		return respondStudyError(c, err)
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
+
+// SaveProgress handles PUT /api/library/study/progress
+func (h *LibraryStudyHandler) SaveProgress(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var req progressRequest
+	if err := c.BodyParser(&req); err != nil || req.VerseID == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "verse_id is required"})
+	}
+	progress, err := h.study.SaveProgress(userID, req.VerseID, req.ReadAt)
+	if err != nil {
+		return respondStudyError(c, err)
+	}
+	return c.JSON(progress)
+}
+
+// ListProgress handles GET /api/library/study/progress
+func (h *LibraryStudyHandler) ListProgress(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	items, err := h.study.ListProgress(userID)
+	if err != nil {
+		return respondStudyError(c, err)
+	}
+	return c.JSON(fiber.Map{"progress": items})
+}
+
+// ContinueReading handles GET /api/library/study/continue?limit=
+func (h *LibraryStudyHandler) ContinueReading(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	items, err := h.study.ContinueReading(userID, c.QueryInt("limit", 0))
+	if err != nil {
+		return respondStudyError(c, err)
+	}
+	return c.JSON(fiber.Map{"items": items})
+}
+
+// Sync handles GET /api/library/study/sync?since=RFC3339.
+// Clients store server_time and pass it as since on the next pull.
+func (h *LibraryStudyHandler) Sync(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var since *time.Time
+	if raw := strings.TrimSpace(c.Query("since")); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid since, expected RFC3339"})
+		}
+		since = &parsed
+	}
+	payload, err := h.study.Changes(userID, since)
+	if err != nil {
+		return respondStudyError(c, err)
+	}
+	return c.JSON(payload)
+}
+
+// Export handles GET /api/library/study/export?format=json|md&bookCode=
+func (h *LibraryStudyHandler) Export(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	filter, err := parseStudyFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid filter"})
+	}
+
+	switch strings.ToLower(strings.TrimSpace(c.Query("format", "json"))) {
+	case "json":
+		bookmarks, err := h.study.ListBookmarks(userID, filter)
+		if err != nil {
+			return respondStudyError(c, err)
+		}
+		highlights, err := h.study.ListHighlights(userID, filter)
+		if err != nil {
+			return respondStudyError(c, err)
+		}
+		notes, err := h.study.ListNotes(userID, filter)
+		if err != nil {
+			return respondStudyError(c, err)
+		}
+		progress, err := h.study.ListProgress(userID)
+		if err != nil {
+			return respondStudyError(c, err)
+		}
+		c.Set(fiber.HeaderContentDisposition, `attachment; filename="library-study.json"`)
+		return c.JSON(fiber.Map{
+			"exported_at": time.Now().UTC(),
+			"bookmarks":   bookmarks,
+			"highlights":  highlights,
+			"notes":       notes,
+			"progress":    progress,
+		})
+	case "md", "markdown":
+		content, err := h.study.ExportMarkdown(userID, filter)
+		if err != nil {
+			return respondStudyError(c, err)
+		}
+		c.Set(fiber.HeaderContentType, "text/markdown; charset=utf-8")
+		c.Set(fiber.HeaderContentDisposition, `attachment; filename="library-study.md"`)
+		return c.SendString(content)
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "format must be json or md"})
+	}
+}
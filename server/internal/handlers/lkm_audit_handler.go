@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"errors"
+
+	"rag-agent-server/internal/models"
+	"rag-agent-server/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type LKMAuditHandler struct {
+	service *services.LKMAuditService
+}
+
+func NewLKMAuditHandler() *LKMAuditHandler {
+	return &LKMAuditHandler{service: services.NewLKMAuditService()}
+}
+
+func (h *LKMAuditHandler) ListReports(c *fiber.Ctx) error {
+	if _, err := requireFinanceAdmin(c); err != nil {
+		return err
+	}
+	reports, err := h.service.ListReports(parseAdminQueryInt(c.Query("limit"), 20, 1, 100))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"items": reports})
+}
+
+func (h *LKMAuditHandler) GetReport(c *fiber.Ctx) error {
+	if _, err := requireFinanceAdmin(c); err != nil {
+		return err
+	}
+	reportID, err := parsePositiveUint(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid report ID"})
+	}
+	report, err := h.service.GetReport(reportID)
+	if errors.Is(err, services.ErrLKMAuditReportNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(report)
+}
+
+// RunAudit checks the ledger and wallets; "repair": true (approvers only) posts correcting entries
+func (h *LKMAuditHandler) RunAudit(c *fiber.Ctx) error {
+	var req struct {
+		Repair bool `json:"repair"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+	}
+
+	var adminID uint
+	var err error
+	if req.Repair {
+		adminID, err = requireAdminPermission(c, string(models.AdminPermissionFinanceApprover))
+	} else {
+		adminID, err = requireFinanceAdmin(c)
+	}
+	if err != nil {
+		return err
+	}
+
+	report, err := h.service.Run(services.LKMAuditOptions{
+		Repair:  req.Repair,
+		ActorID: &adminID,
+		Trigger: "admin",
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(report)
+}
@@ -3,8 +3,10 @@ package handlers
 import (
 	"errors"
 	"strings"
+	"time"
 
 	"rag-agent-server/internal/middleware"
+	"rag-agent-server/internal/models"
 	"rag-agent-server/internal/services"
 
 	"github.com/gofiber/fiber/v2"
@@ -12,11 +14,15 @@ import (
 )
 
 type LKMTopupHandler struct {
-	service *services.LKMTopupService
+	service        *services.LKMTopupService
+	reconciliation *services.LKMReconciliationService
 }
 
 func NewLKMTopupHandler(service *services.LKMTopupService) *LKMTopupHandler {
-	return &LKMTopupHandler{service: service}
+	return &LKMTopupHandler{
+		service:        service,
+		reconciliation: services.NewLKMReconciliationService(service),
+	}
 }
 
 func inferTopupChannel(c *fiber.Ctx, explicit string) string {
@@ -87,6 +93,26 @@ func respondLKMError(c *fiber.Ctx, err error) error {
 			"error":     "Quote already used",
 			"errorCode": "QUOTE_ALREADY_USED",
 		})
+	case errors.Is(err, services.ErrPaymentWebhookSignature):
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":     "Invalid webhook signature",
+			"errorCode": "WEBHOOK_SIGNATURE_INVALID",
+		})
+	case errors.Is(err, services.ErrPaymentWebhookMismatch):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":     "Webhook does not match the payment",
+			"errorCode": "WEBHOOK_MISMATCH",
+		})
+	case errors.Is(err, services.ErrPaymentGatewayNotConfigured):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":     "Payment gateway is not configured",
+			"errorCode": "GATEWAY_NOT_CONFIGURED",
+		})
+	case errors.Is(err, services.ErrPaymentGatewayUnavailable):
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error":     "Payment gateway is unavailable, please try again later",
+			"errorCode": "GATEWAY_UNAVAILABLE",
+		})
 	case errors.Is(err, services.ErrLKMTopupNotFound):
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error":     "Top-up not found",
@@ -180,14 +206,17 @@ func (h *LKMTopupHandler) GetMyTopups(c *fiber.Ctx) error {
 	})
 }
 
+// Webhook accepts provider notifications. The raw body is handed to the gateway adapter,
+// which verifies the signature before anything is parsed or recorded.
 func (h *LKMTopupHandler) Webhook(c *fiber.Ctx) error {
-	var req services.LKMWebhookRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
-	}
-
 	gatewayCode := c.Params("gatewayCode")
-	result, err := h.service.HandleWebhook(gatewayCode, req)
+	result, err := h.service.HandleGatewayWebhook(gatewayCode, services.GatewayWebhook{
+		Body:   c.Body(),
+		Header: func(key string) string { return c.Get(key) },
+	})
+	if errors.Is(err, services.ErrPaymentWebhookIgnored) {
+		return c.JSON(fiber.Map{"ignored": true})
+	}
 	if err != nil {
 		return respondLKMError(c, err)
 	}
@@ -319,3 +348,98 @@ func (h *LKMTopupHandler) MarkTopupPaid(c *fiber.Ctx) error {
 		"status":  result.Status,
 	})
 }
+
+// requireFinanceAdmin admits finance managers and approvers
+func requireFinanceAdmin(c *fiber.Ctx) (uint, error) {
+	userID := middleware.GetUserID(c)
+	if hasAdminPermission(userID, middleware.GetUserRole(c), string(models.AdminPermissionFinanceApprover)) {
+		return userID, nil
+	}
+	return requireAdminPermission(c, string(models.AdminPermissionFinanceManager))
+}
+
+func (h *LKMTopupHandler) ListReconciliationReports(c *fiber.Ctx) error {
+	if _, err := requireFinanceAdmin(c); err != nil {
+		return err
+	}
+	reports, err := h.reconciliation.ListReports(parseAdminQueryInt(c.Query("limit"), 30, 1, 100))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"items": reports})
+}
+
+func (h *LKMTopupHandler) GetReconciliationReport(c *fiber.Ctx) error {
+	if _, err := requireFinanceAdmin(c); err != nil {
+		return err
+	}
+	reportID, err := parsePositiveUint(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid report ID"})
+	}
+	report, err := h.reconciliation.GetReport(reportID)
+	if errors.Is(err, services.ErrLKMReconciliationNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(report)
+}
+
+// RunReconciliation builds the report for a past day ("date": YYYY-MM-DD, default yesterday)
+func (h *LKMTopupHandler) RunReconciliation(c *fiber.Ctx) error {
+	if _, err := requireFinanceAdmin(c); err != nil {
+		return err
+	}
+	var req struct {
+		Date string `json:"date"`
+	}
+	_ = c.BodyParser(&req)
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	day := today.AddDate(0, 0, -1)
+	if raw := strings.TrimSpace(req.Date); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "date must be YYYY-MM-DD"})
+		}
+		day = parsed
+	}
+	if !day.Before(today) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "only finished days can be reconciled"})
+	}
+
+	report, err := h.reconciliation.GenerateDailyReport(day)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(report)
+}
+
+func (h *LKMTopupHandler) ResolveDiscrepancy(c *fiber.Ctx) error {
+	adminID, err := requireFinanceAdmin(c)
+	if err != nil {
+		return err
+	}
+	discrepancyID, err := parsePositiveUint(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid discrepancy ID"})
+	}
+	var req struct {
+		Note string `json:"note"`
+	}
+	_ = c.BodyParser(&req)
+	if strings.TrimSpace(req.Note) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Resolution note is required"})
+	}
+
+	discrepancy, err := h.reconciliation.ResolveDiscrepancy(discrepancyID, adminID, req.Note)
+	if errors.Is(err, services.ErrLKMDiscrepancyNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(discrepancy)
+}
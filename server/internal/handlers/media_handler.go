@@ -221,6 +221,11 @@ func (h *MediaHandler) UploadMessageMedia(c *fiber.Ctx) error {
 			"error": "recipientId and roomId are mutually exclusive",
 		})
 	}
+	if recipientID != 0 {
+		if err := services.EnsureDirectMessageAllowed(actorID, recipientID); err != nil {
+			return respondMessageActionError(c, err)
+		}
+	}
 
 	allowedTypes := map[string][]string{
 		"image":    {"image/jpeg", "image/png", "image/gif", "image/webp"},
@@ -341,6 +346,14 @@ func (h *MediaHandler) UploadMessageMedia(c *fiber.Ctx) error {
 
 	msg.RecipientID = recipientID
 	msg.RoomID = roomID
+	if replyToID, provided, parseErr := parseOptionalPositiveUint(c.FormValue("replyToId")); parseErr != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid replyToId"})
+	} else if provided {
+		msg.ReplyToID = &replyToID
+		if err := services.NewMessageThreadService().PrepareReply(&msg); err != nil {
+			return respondMessageActionError(c, err)
+		}
+	}
 
 	if err := database.DB.Create(&msg).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
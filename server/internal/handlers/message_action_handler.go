@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"rag-agent-server/internal/middleware"
+	"rag-agent-server/internal/services"
+	"rag-agent-server/internal/websocket"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type editMessageRequest struct {
+	Content string `json:"content"`
+}
+
+type reactionRequest struct {
+	Emoji string `json:"emoji"`
+}
+
+// EditMessage handles PATCH /api/messages/:id
+func (h *MessageHandler) EditMessage(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	messageID, err := parseRequiredPositiveUint(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid message ID"})
+	}
+
+	var req editMessageRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	audience, err := h.actions.EditMessage(messageID, userID, req.Content)
+	if err != nil {
+		return respondMessageActionError(c, err)
+	}
+
+	msg := audience.Message
+	h.broadcastMessageEvent(websocket.EventMessageEdited, userID, audience, fiber.Map{
+		"content":  msg.Content,
+		"editedAt": msg.EditedAt,
+	})
+	return c.JSON(msg)
+}
+
+// GetMessageEdits handles GET /api/messages/:id/edits
+func (h *MessageHandler) GetMessageEdits(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	messageID, err := parseRequiredPositiveUint(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid message ID"})
+	}
+
+	edits, err := h.actions.GetEditHistory(messageID, userID)
+	if err != nil {
+		return respondMessageActionError(c, err)
+	}
+	return c.JSON(fiber.Map{"edits": edits})
+}
+
+// DeleteMessage handles DELETE /api/messages/:id?scope=me|all
+func (h *MessageHandler) DeleteMessage(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	messageID, err := parseRequiredPositiveUint(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid message ID"})
+	}
+
+	scope := strings.ToLower(strings.TrimSpace(c.Query("scope", services.MessageDeleteScopeMe)))
+	audience, err := h.actions.DeleteMessage(messageID, userID, scope)
+	if err != nil {
+		return respondMessageActionError(c, err)
+	}
+
+	if audience != nil {
+		h.broadcastMessageEvent(websocket.EventMessageDeleted, userID, audience, fiber.Map{
+			"scope":     services.MessageDeleteScopeAll,
+			"deletedAt": audience.Message.DeletedForAllAt,
+		})
+	}
+	return c.JSON(fiber.Map{"success": true, "messageId": messageID, "scope": scope})
+}
+
+// AddReaction handles POST /api/messages/:id/reactions
+func (h *MessageHandler) AddReaction(c *fiber.Ctx) error {
+	var req reactionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	return h.setReaction(c, req.Emoji, true)
+}
+
+// RemoveReaction handles DELETE /api/messages/:id/reactions?emoji=
+func (h *MessageHandler) RemoveReaction(c *fiber.Ctx) error {
+	return h.setReaction(c, c.Query("emoji"), false)
+}
+
+func (h *MessageHandler) setReaction(c *fiber.Ctx, emoji string, add bool) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	messageID, err := parseRequiredPositiveUint(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid message ID"})
+	}
+
+	audience, err := h.actions.SetReaction(messageID, userID, emoji, add)
+	if err != nil {
+		return respondMessageActionError(c, err)
+	}
+
+	action := "removed"
+	if add {
+		action = "added"
+	}
+	h.broadcastMessageEvent(websocket.EventMessageReaction, userID, audience, fiber.Map{
+		"emoji":     strings.TrimSpace(emoji),
+		"action":    action,
+		"reactions": audience.Message.Reactions,
+	})
+
+	mine, err := h.actions.GetMyReactions(userID, []uint{messageID})
+	if err != nil {
+		log.Printf("[MessageHandler] Failed to load own reactions for message %d: %v", messageID, err)
+	}
+	return c.JSON(fiber.Map{
+		"messageId":   messageID,
+		"reactions":   audience.Message.Reactions,
+		"myReactions": mine[messageID],
+	})
+}
+
+func (h *MessageHandler) broadcastMessageEvent(eventType string, actorID uint, audience *services.MessageAudience, data fiber.Map) {
+	if h.hub == nil || audience == nil {
+		return
+	}
+	msg := audience.Message
+	h.hub.BroadcastEvent(websocket.MessageEvent{
+		Type:        eventType,
+		MessageID:   msg.ID,
+		SenderID:    actorID,
+		RecipientID: msg.RecipientID,
+		RoomID:      msg.RoomID,
+		Data:        data,
+	}, audience.TargetUserIDs...)
+}
+
+func respondMessageActionError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrMessageNotFound), errors.Is(err, services.ErrVerseNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrMessageForbidden), errors.Is(err, services.ErrMessageBlocked):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrMessageDeleted):
+		return c.Status(fiber.StatusGone).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrMessageNotEditable),
+		errors.Is(err, services.ErrInvalidReaction),
+		errors.Is(err, services.ErrInvalidReplyTarget),
+		errors.Is(err, services.ErrInvalidVerseAnchor),
+		errors.Is(err, services.ErrInvalidPayload):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	default:
+		log.Printf("[MessageHandler] message action failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update message"})
+	}
+}
@@ -22,6 +22,11 @@ type MessageHandler struct {
 	hub             *websocket.Hub
 	walletService   *services.WalletService
 	referralService *services.ReferralService
+	actions         *services.MessageActionService
+	receipts        *services.MessageReceiptService
+	threads         *services.MessageThreadService
+	search          *services.MessageSearchService
+	scheduled       *services.ScheduledMessageService
 }
 
 func NewMessageHandler(aiService *services.AiChatService, hub *websocket.Hub, walletService *services.WalletService, referralService *services.ReferralService) *MessageHandler {
@@ -30,6 +35,11 @@ func NewMessageHandler(aiService *services.AiChatService, hub *websocket.Hub, wa
 		hub:             hub,
 		walletService:   walletService,
 		referralService: referralService,
+		actions:         services.NewMessageActionService(),
+		receipts:        services.NewMessageReceiptService(),
+		threads:         services.NewMessageThreadService(),
+		search:          services.NewMessageSearchService(),
+		scheduled:       services.NewScheduledMessageService(),
 	}
 }
 
@@ -48,14 +58,36 @@ func (h *MessageHandler) SendMessage(c *fiber.Ctx) error {
 		})
 	}
 
+	var delivery sendMessageOptions
+	if err := c.BodyParser(&delivery); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Cannot parse JSON",
+		})
+	}
+	if err := services.ValidateMessageTTL(delivery.TTLSeconds); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
 	msg.SenderID = userID
 	msg.Content = strings.TrimSpace(msg.Content)
+	// Lifecycle fields are server-managed
+	msg.EditedAt = nil
+	msg.DeletedForAllAt = nil
+	msg.DeletedByID = 0
+	msg.Reactions = nil
+	msg.ExpiresAt = nil
 	if (msg.RecipientID == 0 && msg.RoomID == 0) || msg.Content == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Content and either RecipientID or RoomID are required",
 		})
 	}
 
+	if msg.RoomID == 0 {
+		if err := services.EnsureDirectMessageAllowed(userID, msg.RecipientID); err != nil {
+			return respondMessageActionError(c, err)
+		}
+	}
+
 	// LKM Billing: Check if this is an AI-enabled room
 	aiEnabled := false
 	roomName := ""
@@ -75,6 +107,11 @@ func (h *MessageHandler) SendMessage(c *fiber.Ctx) error {
 
 		aiEnabled = room.AiEnabled
 		roomName = room.Name
+		if aiEnabled && delivery.ScheduledAt != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Scheduled messages are not available in AI rooms",
+			})
+		}
 
 		memberIDs, err := getRoomMemberUserIDs(room.ID)
 		if err != nil {
@@ -85,6 +122,14 @@ func (h *MessageHandler) SendMessage(c *fiber.Ctx) error {
 		roomMemberIDs = memberIDs
 	}
 
+	if delivery.ScheduledAt != nil {
+		return h.scheduleMessage(c, userID, msg, delivery)
+	}
+
+	if err := h.threads.PrepareReply(&msg); err != nil {
+		return respondMessageActionError(c, err)
+	}
+
 	// If AI is enabled, charge 1 LKM per message
 	if aiEnabled && h.walletService != nil {
 		// Generate idempotent key: userID + timestamp + first 20 chars of content
@@ -116,11 +161,18 @@ func (h *MessageHandler) SendMessage(c *fiber.Ctx) error {
 		}
 	}
 
+	msg.ExpiresAt = services.MessageExpiry(time.Now(), delivery.TTLSeconds)
 	if err := database.DB.Create(&msg).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Could not save message",
 		})
 	}
+	if msg.ReplyToID != nil {
+		withPreview := []models.Message{msg}
+		if err := h.threads.AttachThreadInfo(withPreview); err == nil {
+			msg = withPreview[0]
+		}
+	}
 
 	services.GetMessagePushService().Dispatch(msg, services.MessagePushOptions{
 		RoomName:      roomName,
@@ -267,6 +319,7 @@ func (h *MessageHandler) GetMessages(c *fiber.Ctx) error {
 
 	roomId := c.Query("roomId")
 
+	var conversation services.ConversationRef
 	query := database.DB.Order("created_at asc")
 	if roomId != "" {
 		roomID, err := parseRequiredPositiveUint(roomId)
@@ -283,6 +336,7 @@ func (h *MessageHandler) GetMessages(c *fiber.Ctx) error {
 			return respondRoomAccessError(c, err)
 		}
 		query = query.Where("room_id = ?", roomID)
+		conversation.RoomID = roomID
 	} else {
 		recipientID, err := strconv.ParseUint(strings.TrimSpace(c.Params("recipientId")), 10, 64)
 		if err != nil || recipientID == 0 {
@@ -292,14 +346,21 @@ func (h *MessageHandler) GetMessages(c *fiber.Ctx) error {
 		}
 		query = query.Where("(sender_id = ? AND recipient_id = ?) OR (sender_id = ? AND recipient_id = ?)",
 			userID, recipientID, recipientID, userID)
+		conversation.PeerID = uint(recipientID)
 	}
 
+	query = query.Where("id NOT IN (?)", h.actions.HiddenMessagesSubquery(userID))
+
 	var messages []models.Message
 	if err := query.Find(&messages).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Could not fetch messages",
 		})
 	}
+	h.applyFetchedReceipts(userID, conversation, messages)
+	if err := h.threads.AttachThreadInfo(messages); err != nil {
+		log.Printf("[MessageHandler] thread info failed user=%d: %v", userID, err)
+	}
 
 	return c.Status(fiber.StatusOK).JSON(messages)
 }
@@ -365,6 +426,7 @@ func (h *MessageHandler) GetMessagesHistory(c *fiber.Ctx) error {
 		limit = 100
 	}
 
+	conversation := services.ConversationRef{RoomID: roomID, PeerID: peerUserID}
 	query := database.DB.Model(&models.Message{})
 	if roomProvided {
 		room, roomErr := loadRoomByID(roomID)
@@ -386,6 +448,7 @@ func (h *MessageHandler) GetMessagesHistory(c *fiber.Ctx) error {
 	if beforeProvided {
 		query = query.Where("id < ?", beforeID)
 	}
+	query = query.Where("id NOT IN (?)", h.actions.HiddenMessagesSubquery(userID))
 
 	var descItems []models.Message
 	if err := query.Order("id DESC").Limit(limit + 1).Find(&descItems).Error; err != nil {
@@ -401,6 +464,10 @@ func (h *MessageHandler) GetMessagesHistory(c *fiber.Ctx) error {
 	}
 
 	items := reverseMessages(descItems)
+	h.applyFetchedReceipts(userID, conversation, items)
+	if err := h.threads.AttachThreadInfo(items); err != nil {
+		log.Printf("[MessageHandler] thread info failed user=%d: %v", userID, err)
+	}
 	var nextBeforeID *uint
 	if hasMore && len(items) > 0 {
 		oldestID := items[0].ID
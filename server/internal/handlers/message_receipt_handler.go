@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"rag-agent-server/internal/middleware"
+	"rag-agent-server/internal/models"
+	"rag-agent-server/internal/services"
+	"rag-agent-server/internal/websocket"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type receiptRequest struct {
+	RoomID     uint `json:"roomId"`
+	PeerUserID uint `json:"peerUserId"`
+	MessageID  uint `json:"messageId"` // 0 = up to the latest message
+}
+
+// MarkRead handles POST /api/messages/read
+func (h *MessageHandler) MarkRead(c *fiber.Ctx) error {
+	return h.markReceipt(c, services.ReceiptRead)
+}
+
+// MarkDelivered handles POST /api/messages/delivered
+func (h *MessageHandler) MarkDelivered(c *fiber.Ctx) error {
+	return h.markReceipt(c, services.ReceiptDelivered)
+}
+
+func (h *MessageHandler) markReceipt(c *fiber.Ctx, kind string) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req receiptRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	receipt, err := h.applyReceipt(userID, services.ConversationRef{RoomID: req.RoomID, PeerID: req.PeerUserID}, kind, req.MessageID)
+	if err != nil {
+		return respondReceiptError(c, err)
+	}
+	return c.JSON(fiber.Map{
+		"kind":      receipt.Kind,
+		"messageId": receipt.MessageID,
+		"advanced":  receipt.Advanced,
+	})
+}
+
+// GetReceipts handles GET /api/messages/receipts?roomId=|peerUserId=
+func (h *MessageHandler) GetReceipts(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	roomID, _, err := parseOptionalPositiveUint(c.Query("roomId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid roomId"})
+	}
+	peerUserID, _, err := parseOptionalPositiveUint(c.Query("peerUserId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid peerUserId"})
+	}
+
+	states, err := h.receipts.GetReceipts(userID, services.ConversationRef{RoomID: roomID, PeerID: peerUserID})
+	if err != nil {
+		return respondReceiptError(c, err)
+	}
+	return c.JSON(fiber.Map{"receipts": states})
+}
+
+// GetUnreadCounts handles GET /api/messages/unread
+func (h *MessageHandler) GetUnreadCounts(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	direct, err := h.receipts.UnreadDirectCounts(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch unread counters"})
+	}
+	roomIDs, err := getUserRoomIDs(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch unread counters"})
+	}
+	rooms, err := h.receipts.UnreadRoomCounts(userID, roomIDs)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch unread counters"})
+	}
+
+	var total int64
+	for _, count := range direct {
+		total += count
+	}
+	for _, count := range rooms {
+		total += count
+	}
+	return c.JSON(fiber.Map{"direct": direct, "rooms": rooms, "total": total})
+}
+
+// HandleWSReceipt applies receipts acknowledged over the WebSocket (see websocket.ReceiptHandler)
+func (h *MessageHandler) HandleWSReceipt(userID uint, kind string, roomID, peerID, messageID uint) {
+	if _, err := h.applyReceipt(userID, services.ConversationRef{RoomID: roomID, PeerID: peerID}, kind, messageID); err != nil {
+		log.Printf("[MessageHandler] ws receipt rejected user=%d kind=%s room=%d peer=%d message=%d: %v",
+			userID, kind, roomID, peerID, messageID, err)
+	}
+}
+
+// applyReceipt moves the watermark and notifies participants when it advanced
+func (h *MessageHandler) applyReceipt(userID uint, ref services.ConversationRef, kind string, messageID uint) (*services.ReceiptUpdate, error) {
+	receipt, err := h.receipts.Mark(userID, ref, kind, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if receipt.Advanced && h.hub != nil {
+		eventType := websocket.EventMessageDelivered
+		if receipt.Kind == services.ReceiptRead {
+			eventType = websocket.EventMessageRead
+		}
+		h.hub.BroadcastEvent(websocket.MessageEvent{
+			Type:        eventType,
+			MessageID:   receipt.MessageID,
+			SenderID:    userID,
+			RecipientID: ref.PeerID,
+			RoomID:      ref.RoomID,
+			Data: fiber.Map{
+				"userId":    userID,
+				"messageId": receipt.MessageID,
+				"at":        receipt.At,
+			},
+		}, receipt.TargetUserIDs...)
+	}
+	if receipt.Advanced && receipt.Kind == services.ReceiptRead {
+		services.GetMessagePushService().DispatchRead(*receipt)
+	}
+	return receipt, nil
+}
+
+// applyFetchedReceipts annotates the viewer's own messages with receipt state and
+// marks the fetched messages from others as delivered. Failures are logged only.
+func (h *MessageHandler) applyFetchedReceipts(userID uint, ref services.ConversationRef, messages []models.Message) {
+	if ref.Validate(userID) != nil || len(messages) == 0 {
+		return
+	}
+	if err := h.receipts.AnnotateOwnMessages(userID, ref, messages); err != nil {
+		log.Printf("[MessageHandler] receipt annotation failed user=%d: %v", userID, err)
+	}
+
+	var latestIncoming uint
+	for _, msg := range messages {
+		if msg.SenderID != userID && msg.ID > latestIncoming {
+			latestIncoming = msg.ID
+		}
+	}
+	if latestIncoming == 0 {
+		return
+	}
+	if _, err := h.applyReceipt(userID, ref, services.ReceiptDelivered, latestIncoming); err != nil {
+		log.Printf("[MessageHandler] auto delivery receipt failed user=%d message=%d: %v", userID, latestIncoming, err)
+	}
+}
+
+func respondReceiptError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, services.ErrInvalidConversation) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return respondMessageActionError(c, err)
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"rag-agent-server/internal/middleware"
+	"rag-agent-server/internal/services"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SearchMessages handles GET /api/messages/search
+// Query: q (required), senderId, roomId, peerUserId, type, from, to (RFC3339 or YYYY-MM-DD), limit, offset
+func (h *MessageHandler) SearchMessages(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	filters := services.MessageSearchFilters{
+		Query:  c.Query("q"),
+		Type:   c.Query("type"),
+		Limit:  c.QueryInt("limit", 0),
+		Offset: c.QueryInt("offset", 0),
+	}
+	var err error
+	if filters.SenderID, _, err = parseOptionalPositiveUint(c.Query("senderId")); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid senderId"})
+	}
+	if filters.RoomID, _, err = parseOptionalPositiveUint(c.Query("roomId")); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid roomId"})
+	}
+	if filters.PeerUserID, _, err = parseOptionalPositiveUint(c.Query("peerUserId")); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid peerUserId"})
+	}
+	if filters.From, err = parseSearchDate(c.Query("from"), false); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid from date"})
+	}
+	if filters.To, err = parseSearchDate(c.Query("to"), true); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid to date"})
+	}
+
+	result, err := h.search.Search(userID, filters)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrSearchQueryTooShort), errors.Is(err, services.ErrInvalidConversation):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		default:
+			log.Printf("[MessageHandler] message search failed user=%d: %v", userID, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not search messages"})
+		}
+	}
+	return c.JSON(result)
+}
+
+// parseSearchDate accepts RFC3339 or a plain date. A plain "to" date covers the whole day.
+func parseSearchDate(raw string, endOfDay bool) (*time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	if parsed, err := time.Parse(time.RFC3339, raw); err == nil {
+		return &parsed, nil
+	}
+	parsed, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return nil, err
+	}
+	if endOfDay {
+		parsed = parsed.AddDate(0, 0, 1)
+	}
+	return &parsed, nil
+}
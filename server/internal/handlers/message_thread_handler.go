@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"rag-agent-server/internal/middleware"
+	"rag-agent-server/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetThread handles GET /api/messages/:id/thread?afterId=&limit=
+func (h *MessageHandler) GetThread(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	messageID, err := parseRequiredPositiveUint(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid message ID"})
+	}
+	afterID, _, err := parseOptionalPositiveUint(c.Query("afterId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid afterId"})
+	}
+
+	page, err := h.threads.ListThread(messageID, userID, afterID, c.QueryInt("limit", 0))
+	if err != nil {
+		return respondMessageActionError(c, err)
+	}
+	return c.JSON(page)
+}
+
+// GetRoomVerseThreads handles GET /api/rooms/:id/threads?verseId=&canto=&chapter=
+func (h *MessageHandler) GetRoomVerseThreads(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	roomID, err := parseRequiredPositiveUint(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid room id"})
+	}
+	verseID, _, err := parseOptionalPositiveUint(c.Query("verseId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid verseId"})
+	}
+
+	room, err := loadRoomByID(roomID)
+	if err != nil {
+		return respondRoomLoadError(c, err)
+	}
+	if _, err := ensureRoomAccess(room, userID, true); err != nil {
+		return respondRoomAccessError(c, err)
+	}
+
+	threads, err := h.threads.ListVerseThreads(room.ID, userID, services.VerseThreadFilter{
+		VerseID: verseID,
+		Canto:   c.QueryInt("canto", 0),
+		Chapter: c.QueryInt("chapter", 0),
+	})
+	if err != nil {
+		return respondMessageActionError(c, err)
+	}
+	return c.JSON(fiber.Map{"threads": threads})
+}
@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"rag-agent-server/internal/middleware"
+	"rag-agent-server/internal/models"
+	"rag-agent-server/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	maxDisputePhotos    = 5
+	maxDisputePhotoSize = 10 * 1024 * 1024
+)
+
+var errDisputeEvidenceStorage = errors.New("evidence storage is not configured")
+
+type OrderDisputeHandler struct {
+	service *services.OrderDisputeService
+}
+
+func NewOrderDisputeHandler() *OrderDisputeHandler {
+	return &OrderDisputeHandler{service: services.NewOrderDisputeService()}
+}
+
+// ==================== BUYER / SELLER ENDPOINTS ====================
+
+// OpenDispute opens a dispute on a shipped or delivered order (buyer).
+// Accepts JSON or multipart with up to 5 images in "photos".
+func (h *OrderDisputeHandler) OpenDispute(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	orderID, err := parsePositiveUint(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid order ID"})
+	}
+
+	var req models.OrderDisputeOpenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	evidence, err := h.uploadEvidence(c, orderID, userID)
+	if err != nil {
+		return h.evidenceError(c, err)
+	}
+
+	dispute, err := h.service.OpenDispute(orderID, userID, req, evidence)
+	if err != nil {
+		h.discardEvidence(evidence)
+		return h.disputeError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(dispute)
+}
+
+// GetOrderDispute returns the dispute with all statements (buyer or seller)
+func (h *OrderDisputeHandler) GetOrderDispute(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	orderID, err := parsePositiveUint(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid order ID"})
+	}
+
+	dispute, err := h.service.GetOrderDispute(orderID, userID)
+	if err != nil {
+		return h.disputeError(c, err)
+	}
+	return c.JSON(dispute)
+}
+
+// AddStatement adds a statement with optional photos (buyer or seller)
+func (h *OrderDisputeHandler) AddStatement(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	orderID, err := parsePositiveUint(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid order ID"})
+	}
+
+	var req models.OrderDisputeStatementRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	evidence, err := h.uploadEvidence(c, orderID, userID)
+	if err != nil {
+		return h.evidenceError(c, err)
+	}
+
+	statement, err := h.service.AddStatement(orderID, userID, req.Text, evidence)
+	if err != nil {
+		h.discardEvidence(evidence)
+		return h.disputeError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(statement)
+}
+
+// ==================== ADMIN ENDPOINTS ====================
+
+// AdminListDisputes returns the review queue, closest deadline first
+func (h *OrderDisputeHandler) AdminListDisputes(c *fiber.Ctx) error {
+	filters := models.OrderDisputeFilters{
+		Status:  models.OrderDisputeStatus(strings.TrimSpace(c.Query("status"))),
+		Overdue: parseYatraAdminBoolQuery(c.Query("overdue")),
+		Page:    parseBoundedQueryInt(c, "page", 1, 1, 100000),
+		Limit:   parseBoundedQueryInt(c, "limit", 20, 1, 50),
+	}
+
+	result, err := h.service.ListQueue(filters)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch disputes"})
+	}
+	return c.JSON(result)
+}
+
+// AdminGetDispute returns a dispute with the order, statements and evidence
+func (h *OrderDisputeHandler) AdminGetDispute(c *fiber.Ctx) error {
+	disputeID, err := parsePositiveUint(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid dispute ID"})
+	}
+
+	dispute, err := h.service.GetDispute(disputeID)
+	if err != nil {
+		return h.disputeError(c, err)
+	}
+	return c.JSON(dispute)
+}
+
+// AdminResolveDispute applies a full refund, partial refund or release to the seller
+func (h *OrderDisputeHandler) AdminResolveDispute(c *fiber.Ctx) error {
+	adminID := middleware.GetUserID(c)
+	disputeID, err := parsePositiveUint(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid dispute ID"})
+	}
+
+	var req models.OrderDisputeResolveRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	dispute, err := h.service.Resolve(disputeID, adminID, req)
+	if err != nil {
+		return h.disputeError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"dispute": dispute,
+	})
+}
+
+// uploadEvidence stores the "photos" of a multipart request in S3
+func (h *OrderDisputeHandler) uploadEvidence(c *fiber.Ctx, orderID, userID uint) ([]models.OrderDisputeEvidence, error) {
+	form, err := c.MultipartForm()
+	if err != nil || form == nil {
+		// Plain JSON statement without photos
+		return nil, nil
+	}
+	files := form.File["photos"]
+	if len(files) == 0 {
+		return nil, nil
+	}
+	if len(files) > maxDisputePhotos {
+		return nil, fmt.Errorf("at most %d photos are allowed", maxDisputePhotos)
+	}
+
+	s3Service := services.GetS3Service()
+	if s3Service == nil {
+		return nil, errDisputeEvidenceStorage
+	}
+
+	evidence := make([]models.OrderDisputeEvidence, 0, len(files))
+	for i, file := range files {
+		contentType := strings.ToLower(file.Header.Get("Content-Type"))
+		if !strings.HasPrefix(contentType, "image/") {
+			h.discardEvidence(evidence)
+			return nil, errors.New("only image files are allowed")
+		}
+		if file.Size > maxDisputePhotoSize {
+			h.discardEvidence(evidence)
+			return nil, errors.New("photo is larger than 10 MB")
+		}
+
+		content, err := file.Open()
+		if err != nil {
+			h.discardEvidence(evidence)
+			return nil, err
+		}
+		key := fmt.Sprintf("disputes/order_%d/u%d_%d_%d%s", orderID, userID, time.Now().UnixNano(), i, strings.ToLower(filepath.Ext(file.Filename)))
+		url, err := s3Service.UploadFile(c.UserContext(), content, key, contentType, file.Size)
+		content.Close()
+		if err != nil {
+			log.Printf("[Disputes] Evidence upload failed for order %d: %v", orderID, err)
+			h.discardEvidence(evidence)
+			return nil, errDisputeEvidenceStorage
+		}
+
+		evidence = append(evidence, models.OrderDisputeEvidence{
+			URL:         url,
+			S3Key:       key,
+			ContentType: contentType,
+			Size:        file.Size,
+		})
+	}
+	return evidence, nil
+}
+
+// discardEvidence removes uploaded photos when the statement was rejected
+func (h *OrderDisputeHandler) discardEvidence(evidence []models.OrderDisputeEvidence) {
+	s3Service := services.GetS3Service()
+	if s3Service == nil {
+		return
+	}
+	for _, item := range evidence {
+		if err := s3Service.DeleteFile(context.Background(), item.S3Key); err != nil {
+			log.Printf("[Disputes] Failed to delete evidence %s: %v", item.S3Key, err)
+		}
+	}
+}
+
+func (h *OrderDisputeHandler) evidenceError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, errDisputeEvidenceStorage) {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Photo evidence storage is unavailable"})
+	}
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+}
+
+func (h *OrderDisputeHandler) disputeError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrOrderNotFound), errors.Is(err, services.ErrDisputeNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrDisputeForbidden):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrDisputeExists), errors.Is(err, services.ErrDisputeResolved),
+		errors.Is(err, services.ErrDisputeSellerBalance):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrDisputeNotAllowed), errors.Is(err, services.ErrDisputeReasonRequired),
+		errors.Is(err, services.ErrDisputeEmptyStatement), errors.Is(err, services.ErrDisputeInvalidResolution),
+		errors.Is(err, services.ErrDisputeInvalidRefund):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	default:
+		log.Printf("[Disputes] %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not process dispute"})
+	}
+}
@@ -99,6 +99,16 @@ func getRoomMemberUserIDs(roomID uint) ([]uint, error) {
 	return deduped, nil
 }
 
+func getUserRoomIDs(userID uint) ([]uint, error) {
+	var roomIDs []uint
+	if err := database.DB.Model(&models.RoomMember{}).
+		Where("user_id = ?", userID).
+		Pluck("room_id", &roomIDs).Error; err != nil {
+		return nil, err
+	}
+	return roomIDs, nil
+}
+
 func hardDeleteRoomMember(roomID uint, userID uint) (int64, error) {
 	result := database.DB.Unscoped().
 		Where("room_id = ? AND user_id = ?", roomID, userID).
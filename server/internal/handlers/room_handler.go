@@ -23,7 +23,9 @@ import (
 	"gorm.io/gorm/clause"
 )
 
-type RoomHandler struct{}
+type RoomHandler struct {
+	onReadingChanged func(roomID, actorID uint)
+}
 
 type roomListItem struct {
 	models.Room
@@ -31,6 +33,7 @@ type roomListItem struct {
 	MyRole       string `json:"myRole,omitempty"`
 	IsMember     bool   `json:"isMember"`
 	CanJoin      bool   `json:"canJoin"`
+	UnreadCount  int64  `json:"unreadCount"`
 }
 
 type roomMemberUserSummary struct {
@@ -57,6 +60,11 @@ func NewRoomHandler() *RoomHandler {
 	return &RoomHandler{}
 }
 
+// OnReadingChanged sets a callback for settings updates that move the joint reading position
+func (h *RoomHandler) OnReadingChanged(fn func(roomID, actorID uint)) {
+	h.onReadingChanged = fn
+}
+
 func (h *RoomHandler) CreateRoom(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 	if userID == 0 {
@@ -166,8 +174,15 @@ func (h *RoomHandler) GetRooms(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch room memberships"})
 	}
 	myRoleByRoom := make(map[uint]string, len(myMemberships))
+	memberRoomIDs := make([]uint, 0, len(myMemberships))
 	for _, m := range myMemberships {
 		myRoleByRoom[m.RoomID] = models.NormalizeRoomRole(m.Role)
+		memberRoomIDs = append(memberRoomIDs, m.RoomID)
+	}
+
+	unreadByRoom, err := services.NewMessageReceiptService().UnreadRoomCounts(userID, memberRoomIDs)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch unread counters"})
 	}
 
 	response := make([]roomListItem, 0, len(rooms))
@@ -180,6 +195,7 @@ func (h *RoomHandler) GetRooms(c *fiber.Ctx) error {
 			MyRole:       myRole,
 			IsMember:     isMember,
 			CanJoin:      room.IsPublic && !isMember,
+			UnreadCount:  unreadByRoom[room.ID],
 		})
 	}
 
@@ -963,6 +979,7 @@ func (h *RoomHandler) UpdateRoomSettings(c *fiber.Ctx) error {
 		IsPublic       *bool   `json:"isPublic"`
 		AiEnabled      *bool   `json:"aiEnabled"`
 		BookCode       *string `json:"bookCode"`
+		CurrentCanto   *int    `json:"currentCanto"`
 		CurrentChapter *int    `json:"currentChapter"`
 		CurrentVerse   *int    `json:"currentVerse"`
 		Language       *string `json:"language"`
@@ -995,6 +1012,9 @@ func (h *RoomHandler) UpdateRoomSettings(c *fiber.Ctx) error {
 	if body.BookCode != nil {
 		updates["book_code"] = strings.TrimSpace(*body.BookCode)
 	}
+	if body.CurrentCanto != nil {
+		updates["current_canto"] = *body.CurrentCanto
+	}
 	if body.CurrentChapter != nil {
 		updates["current_chapter"] = *body.CurrentChapter
 	}
@@ -1022,6 +1042,11 @@ func (h *RoomHandler) UpdateRoomSettings(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update room settings"})
 	}
 
+	if h.onReadingChanged != nil && (body.BookCode != nil || body.CurrentCanto != nil || body.CurrentChapter != nil ||
+		body.CurrentVerse != nil || body.ShowPurport != nil) {
+		h.onReadingChanged(roomID, actorID)
+	}
+
 	return c.SendStatus(fiber.StatusOK)
 }
 
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"errors"
+	"rag-agent-server/internal/middleware"
+	"rag-agent-server/internal/models"
+	"rag-agent-server/internal/services"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// sendMessageOptions are the delivery options accepted by POST /api/messages
+// next to the message fields
+type sendMessageOptions struct {
+	ScheduledAt *time.Time `json:"scheduledAt"`
+	TTLSeconds  int        `json:"ttlSeconds"`
+}
+
+// scheduleMessage queues a validated text message instead of sending it now
+func (h *MessageHandler) scheduleMessage(c *fiber.Ctx, userID uint, msg models.Message, delivery sendMessageOptions) error {
+	if msg.Type != "" && msg.Type != "text" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Only text messages can be scheduled"})
+	}
+	if msg.VerseID != nil && *msg.VerseID != 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Verse threads cannot be scheduled"})
+	}
+
+	scheduled, err := h.scheduled.Schedule(userID, services.ScheduleMessageInput{
+		RecipientID: msg.RecipientID,
+		RoomID:      msg.RoomID,
+		Content:     msg.Content,
+		ReplyToID:   msg.ReplyToID,
+		QuoteText:   msg.QuoteText,
+		ScheduledAt: *delivery.ScheduledAt,
+		TTLSeconds:  delivery.TTLSeconds,
+	})
+	if err != nil {
+		return respondScheduledMessageError(c, err)
+	}
+	return c.Status(fiber.StatusAccepted).JSON(scheduled)
+}
+
+// ListScheduledMessages handles GET /api/messages/scheduled
+func (h *MessageHandler) ListScheduledMessages(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	items, err := h.scheduled.ListPending(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch scheduled messages"})
+	}
+	return c.JSON(fiber.Map{"items": items})
+}
+
+// CancelScheduledMessage handles DELETE /api/messages/scheduled/:id
+func (h *MessageHandler) CancelScheduledMessage(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	id, err := parseRequiredPositiveUint(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid scheduled message ID"})
+	}
+
+	if err := h.scheduled.Cancel(id, userID); err != nil {
+		return respondScheduledMessageError(c, err)
+	}
+	return c.JSON(fiber.Map{"success": true, "id": id})
+}
+
+func respondScheduledMessageError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrScheduledMessageNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrScheduledMessageNotPending):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidSchedule),
+		errors.Is(err, services.ErrInvalidMessageTTL),
+		errors.Is(err, services.ErrInvalidConversation):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return respondMessageActionError(c, err)
+	}
+}
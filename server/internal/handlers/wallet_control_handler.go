@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"errors"
+
+	"rag-agent-server/internal/models"
+	"rag-agent-server/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type WalletControlHandler struct {
+	service *services.WalletControlService
+}
+
+func NewWalletControlHandler(walletService *services.WalletService) *WalletControlHandler {
+	return &WalletControlHandler{service: services.NewWalletControlService(walletService)}
+}
+
+// GetControls returns freeze state, limits and recent control events of a user
+// GET /api/admin/wallet/:userId/controls
+func (h *WalletControlHandler) GetControls(c *fiber.Ctx) error {
+	if _, err := requireFinanceAdmin(c); err != nil {
+		return err
+	}
+	userID, err := parsePositiveUint(c.Params("userId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+	controls, err := h.service.GetControls(userID)
+	if err != nil {
+		return walletControlError(c, err)
+	}
+	return c.JSON(controls)
+}
+
+// Freeze blocks outgoing payments of a wallet
+// POST /api/admin/wallet/:userId/freeze
+func (h *WalletControlHandler) Freeze(c *fiber.Ctx) error {
+	return h.setFrozen(c, true)
+}
+
+// Unfreeze lifts a wallet freeze
+// POST /api/admin/wallet/:userId/unfreeze
+func (h *WalletControlHandler) Unfreeze(c *fiber.Ctx) error {
+	return h.setFrozen(c, false)
+}
+
+func (h *WalletControlHandler) setFrozen(c *fiber.Ctx, frozen bool) error {
+	adminID, err := requireFinanceAdmin(c)
+	if err != nil {
+		return err
+	}
+	userID, err := parsePositiveUint(c.Params("userId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+	}
+
+	var controls *services.WalletControlsResponse
+	if frozen {
+		controls, err = h.service.Freeze(adminID, userID, body.Reason)
+	} else {
+		controls, err = h.service.Unfreeze(adminID, userID, body.Reason)
+	}
+	if err != nil {
+		return walletControlError(c, err)
+	}
+	return c.JSON(controls)
+}
+
+// SetLimits replaces the user's limit override
+// PUT /api/admin/wallet/:userId/limits
+func (h *WalletControlHandler) SetLimits(c *fiber.Ctx) error {
+	adminID, err := requireFinanceAdmin(c)
+	if err != nil {
+		return err
+	}
+	userID, err := parsePositiveUint(c.Params("userId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+	var req services.WalletLimitOverrideRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+	}
+	controls, err := h.service.SetLimitOverride(adminID, userID, req)
+	if err != nil {
+		return walletControlError(c, err)
+	}
+	return c.JSON(controls)
+}
+
+// ListEvents returns the audit trail of wallet controls, optionally for one user
+// GET /api/admin/wallet/control-events?userId=&limit=
+func (h *WalletControlHandler) ListEvents(c *fiber.Ctx) error {
+	if _, err := requireFinanceAdmin(c); err != nil {
+		return err
+	}
+	var userID uint
+	if raw := c.Query("userId"); raw != "" {
+		parsed, err := parsePositiveUint(raw)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+		}
+		userID = parsed
+	}
+	events, err := h.service.ListEvents(userID, parseAdminQueryInt(c.Query("limit"), 50, 1, 200))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"items": events})
+}
+
+// GET /api/admin/wallet/limit-tiers
+func (h *WalletControlHandler) ListTiers(c *fiber.Ctx) error {
+	if _, err := requireFinanceAdmin(c); err != nil {
+		return err
+	}
+	tiers, err := h.service.ListTiers()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"items": tiers})
+}
+
+// UpsertTier changes platform-wide limits, so it is reserved for finance approvers
+// PUT /api/admin/wallet/limit-tiers
+func (h *WalletControlHandler) UpsertTier(c *fiber.Ctx) error {
+	if _, err := requireAdminPermission(c, string(models.AdminPermissionFinanceApprover)); err != nil {
+		return err
+	}
+	var input services.WalletLimitTierInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+	}
+	tier, err := h.service.UpsertTier(input)
+	if err != nil {
+		return walletControlError(c, err)
+	}
+	return c.JSON(tier)
+}
+
+func walletControlError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrWalletLimitTierNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrWalletAlreadyFrozen), errors.Is(err, services.ErrWalletNotFrozen):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrWalletControlReasonRequired), errors.Is(err, services.ErrWalletLimitInvalid),
+		errors.Is(err, services.ErrWalletLimitTierNameRequired):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"log"
 	"rag-agent-server/internal/middleware"
 	"rag-agent-server/internal/models"
 	"rag-agent-server/internal/services"
@@ -11,13 +14,19 @@ import (
 
 // WalletHandler handles wallet-related HTTP requests
 type WalletHandler struct {
-	walletService *services.WalletService
+	walletService   *services.WalletService
+	standingOrders  *services.WalletStandingOrderService
+	paymentRequests *services.PaymentRequestService
+	statements      *services.WalletStatementService
 }
 
 // NewWalletHandler creates a new wallet handler
 func NewWalletHandler(walletService *services.WalletService) *WalletHandler {
 	return &WalletHandler{
-		walletService: walletService,
+		walletService:   walletService,
+		standingOrders:  services.NewWalletStandingOrderService(walletService),
+		paymentRequests: services.NewPaymentRequestService(walletService),
+		statements:      services.NewWalletStatementService(),
 	}
 }
 
@@ -94,6 +103,9 @@ func (h *WalletHandler) Transfer(c *fiber.Ctx) error {
 	}
 
 	err := h.walletService.Transfer(userID, req.ToUserID, req.Amount, req.Description, req.BookingID)
+	if errors.Is(err, services.ErrWalletFrozen) || errors.Is(err, services.ErrTransferVelocityBlocked) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": err.Error(),
@@ -108,6 +120,21 @@ func (h *WalletHandler) Transfer(c *fiber.Ctx) error {
 	})
 }
 
+// GetLimits returns the user's outgoing limits and how much of them is used
+// GET /api/wallet/limits
+func (h *WalletHandler) GetLimits(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	limits, err := h.walletService.GetSpendingLimits(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(limits)
+}
+
 // GetStats returns wallet statistics
 // GET /api/wallet/stats
 func (h *WalletHandler) GetStats(c *fiber.Ctx) error {
@@ -125,3 +152,254 @@ func (h *WalletHandler) GetStats(c *fiber.Ctx) error {
 
 	return c.JSON(stats)
 }
+
+// GetStandingOrders returns the user's scheduled and recurring transfers
+// GET /api/wallet/standing-orders
+func (h *WalletHandler) GetStandingOrders(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	orders, err := h.standingOrders.List(userID, models.StandingOrderStatus(c.Query("status")))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{"standingOrders": orders})
+}
+
+// CreateStandingOrder schedules a one-off, weekly or monthly transfer
+// POST /api/wallet/standing-orders
+func (h *WalletHandler) CreateStandingOrder(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req models.StandingOrderCreateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	order, err := h.standingOrders.Create(userID, req)
+	if err != nil {
+		return h.standingOrderError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(order)
+}
+
+// CancelStandingOrder stops a standing order
+// POST /api/wallet/standing-orders/:id/cancel
+func (h *WalletHandler) CancelStandingOrder(c *fiber.Ctx) error {
+	return h.changeStandingOrder(c, h.standingOrders.Cancel)
+}
+
+// ResumeStandingOrder reactivates a standing order paused for insufficient balance
+// POST /api/wallet/standing-orders/:id/resume
+func (h *WalletHandler) ResumeStandingOrder(c *fiber.Ctx) error {
+	return h.changeStandingOrder(c, h.standingOrders.Resume)
+}
+
+func (h *WalletHandler) changeStandingOrder(c *fiber.Ctx, change func(userID, orderID uint) (*models.WalletStandingOrder, error)) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	orderID, err := parsePositiveUint(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid standing order ID"})
+	}
+
+	order, err := change(userID, orderID)
+	if err != nil {
+		return h.standingOrderError(c, err)
+	}
+	return c.JSON(order)
+}
+
+func (h *WalletHandler) standingOrderError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrStandingOrderNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrStandingOrderInvalid), errors.Is(err, services.ErrStandingOrderLimit):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrStandingOrderNotPaused), errors.Is(err, services.ErrStandingOrderFinished):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
+
+// GetPaymentRequests returns the payment requests the user issued
+// GET /api/wallet/payment-requests
+func (h *WalletHandler) GetPaymentRequests(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	requests, err := h.paymentRequests.ListMine(userID, c.QueryInt("limit", 50))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	response := make([]models.PaymentRequestResponse, 0, len(requests))
+	for i := range requests {
+		response = append(response, services.NewPaymentRequestResponse(&requests[i]))
+	}
+	return c.JSON(fiber.Map{"paymentRequests": response})
+}
+
+// CreatePaymentRequest issues an LKM invoice with a shareable link and QR payload
+// POST /api/wallet/payment-requests
+func (h *WalletHandler) CreatePaymentRequest(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req models.PaymentRequestCreateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	request, err := h.paymentRequests.Create(userID, req)
+	if err != nil {
+		return paymentRequestError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(services.NewPaymentRequestResponse(request))
+}
+
+// GetPaymentRequest returns a request by token for the payer's confirmation screen
+// GET /api/wallet/payment-requests/:token
+func (h *WalletHandler) GetPaymentRequest(c *fiber.Ctx) error {
+	request, err := h.paymentRequests.GetByToken(c.Params("token"))
+	if err != nil {
+		return paymentRequestError(c, err)
+	}
+	return c.JSON(services.NewPaymentRequestResponse(request))
+}
+
+// PayPaymentRequest confirms a payment request; repeated confirmations never charge twice
+// POST /api/wallet/payment-requests/:token/pay
+func (h *WalletHandler) PayPaymentRequest(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	request, err := h.paymentRequests.Pay(userID, c.Params("token"))
+	if err != nil {
+		return paymentRequestError(c, err)
+	}
+
+	go func(payeeID uint, amount int, description string) {
+		if err := services.GetPushService().SendPaymentRequestPaid(payeeID, amount, description); err != nil {
+			log.Printf("[Wallet] Payment request push failed: %v", err)
+		}
+	}(request.PayeeID, request.Amount, request.Description)
+
+	wallet, _ := h.walletService.GetBalance(userID)
+	return c.JSON(fiber.Map{
+		"success":        true,
+		"paymentRequest": services.NewPaymentRequestResponse(request),
+		"wallet":         wallet,
+	})
+}
+
+// CancelPaymentRequest withdraws a pending payment request
+// POST /api/wallet/payment-requests/:token/cancel
+func (h *WalletHandler) CancelPaymentRequest(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	request, err := h.paymentRequests.Cancel(userID, c.Params("token"))
+	if err != nil {
+		return paymentRequestError(c, err)
+	}
+	return c.JSON(services.NewPaymentRequestResponse(request))
+}
+
+func paymentRequestError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrPaymentRequestNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrPaymentRequestForbidden), errors.Is(err, services.ErrWalletFrozen),
+		errors.Is(err, services.ErrTransferVelocityBlocked):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrPaymentRequestInvalid), errors.Is(err, services.ErrPaymentRequestOwn),
+		errors.Is(err, services.ErrInsufficientBalance), errors.Is(err, services.ErrSpendingLimitExceeded):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrPaymentRequestClosed), errors.Is(err, services.ErrCafeOrderAlreadyPaid):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
+
+// GetStatement generates the monthly statement and archives it
+// GET /api/wallet/statements/:month?format=csv|pdf&lang=ru|en
+func (h *WalletHandler) GetStatement(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	format, err := services.NormalizeStatementFormat(c.Query("format"))
+	if err != nil {
+		if errors.Is(err, services.ErrPDFExportUnavailable) {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": services.ErrPDFExportUnavailable.Error()})
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	report, err := h.statements.Build(userID, c.Params("month"), c.Query("lang"))
+	if errors.Is(err, services.ErrInvalidStatementMonth) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		log.Printf("[Wallet] Statement for user %d failed: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to build statement"})
+	}
+	data, err := h.statements.Render(report, format)
+	if err != nil {
+		log.Printf("[Wallet] Statement render for user %d failed: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to render statement"})
+	}
+	if _, err := h.statements.Archive(report, format, data); err != nil {
+		// The user still gets the file; the archive is retried on the next request
+		log.Printf("[Wallet] Statement archive for user %d %s failed: %v", userID, report.Month, err)
+	}
+
+	c.Set(fiber.HeaderContentType, services.WalletStatementContentType(format))
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, report.FileName(format)))
+	return c.Send(data)
+}
+
+// GetStatements lists archived statements with short-lived download links
+// GET /api/wallet/statements
+func (h *WalletHandler) GetStatements(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	statements, err := h.statements.ListArchived(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"items": statements})
+}
@@ -87,6 +87,7 @@ type YatraAdminHandler struct {
 	analyticsService      *services.YatraAnalyticsService
 	notificationService   *services.AdminNotificationService
 	templateService       *services.ModerationTemplateService
+	emailOutboxService    *services.EmailOutboxService
 }
 
 // NewYatraAdminHandler creates a new admin handler
@@ -101,7 +102,8 @@ func NewYatraAdminHandler() *YatraAdminHandler {
 	yatraAdminService := services.NewYatraAdminService(database.DB, yatraService, notificationService)
 
 	analyticsService := services.NewYatraAnalyticsService(database.DB)
-	templateService := services.NewModerationTemplateService(database.DB)
+	emailOutboxService := services.NewEmailOutboxService(database.DB, services.GetMailer())
+	templateService := services.NewModerationTemplateService(database.DB, emailOutboxService)
 
 	return &YatraAdminHandler{
 		yatraAdminService:     yatraAdminService,
@@ -110,6 +112,7 @@ func NewYatraAdminHandler() *YatraAdminHandler {
 		analyticsService:      analyticsService,
 		notificationService:   notificationService,
 		templateService:       templateService,
+		emailOutboxService:    emailOutboxService,
 	}
 }
 
@@ -186,31 +189,74 @@ func (h *YatraAdminHandler) DeleteTemplate(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"message": "Template deleted"})
 }
 
-// BroadcastEmail sends mass emails
+// BroadcastEmail queues mass emails for the selected organizers
 // POST /api/admin/yatra/broadcast
 func (h *YatraAdminHandler) BroadcastEmail(c *fiber.Ctx) error {
-	if _, err := requireYatraAdminUserID(c); err != nil {
+	adminID, err := requireYatraAdminUserID(c)
+	if err != nil {
 		return err
 	}
 	var req struct {
-		TemplateID      uint   `json:"template_id"`
-		RecipientFilter string `json:"recipient_filter"` // all_organizers, active_organizers
+		TemplateID      uint              `json:"template_id"`
+		RecipientFilter string            `json:"recipient_filter"` // all_organizers, active_organizers, blocked_organizers
+		Variables       map[string]string `json:"variables"`
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
 	}
+	if req.TemplateID == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "template_id is required"})
+	}
 
-	count, err := h.templateService.BroadcastEmail(req.TemplateID, req.RecipientFilter)
+	broadcast, err := h.templateService.BroadcastEmail(req.TemplateID, req.RecipientFilter, adminID, req.Variables)
 	if err != nil {
+		if errors.Is(err, services.ErrUnknownRecipientFilter) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Template not found"})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	return c.JSON(fiber.Map{
-		"message": "Broadcast initiated",
-		"count":   count,
+		"message":     "Broadcast initiated",
+		"count":       broadcast.TotalCount,
+		"broadcastId": broadcast.ID,
+		"status":      broadcast.Status,
 	})
 }
 
+// GetBroadcasts returns recent email broadcasts with delivery counters
+// GET /api/admin/yatra/broadcasts
+func (h *YatraAdminHandler) GetBroadcasts(c *fiber.Ctx) error {
+	if _, err := requireYatraAdminUserID(c); err != nil {
+		return err
+	}
+	broadcasts, err := h.emailOutboxService.ListBroadcasts(parseBoundedQueryInt(c, "limit", 20, 1, 100))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get broadcasts"})
+	}
+	return c.JSON(broadcasts)
+}
+
+// GetBroadcastMessages returns per-recipient delivery status of a broadcast
+// GET /api/admin/yatra/broadcasts/:id/messages
+func (h *YatraAdminHandler) GetBroadcastMessages(c *fiber.Ctx) error {
+	if _, err := requireYatraAdminUserID(c); err != nil {
+		return err
+	}
+	id, err := parsePositiveYatraAdminParamUint(c, "id", "Invalid broadcast ID")
+	if err != nil {
+		return err
+	}
+	messages, err := h.emailOutboxService.GetBroadcastMessages(id, c.Query("status"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get broadcast messages"})
+	}
+	return c.JSON(messages)
+}
+
 // ==================== YATRA MANAGEMENT ====================
 
 // GetAllYatras returns all yatras (admin view with drafts, cancelled, etc.)
@@ -15,6 +15,10 @@ const (
 	NotificationOrganizerReport   AdminNotificationType = "organizer_report"
 	NotificationYatraCancelled    AdminNotificationType = "yatra_cancelled_soon"
 	NotificationHighPriorityIssue AdminNotificationType = "high_priority_issue"
+	NotificationOrderDispute      AdminNotificationType = "order_dispute"
+	NotificationOrderDisputeSLA   AdminNotificationType = "order_dispute_sla"
+	NotificationLKMReconciliation AdminNotificationType = "lkm_reconciliation"
+	NotificationWalletVelocity    AdminNotificationType = "wallet_velocity"
 )
 
 // AdminNotification represents a notification for admin users
@@ -7,4 +7,5 @@ type DatingCompatibility struct {
 	UserID            uint   `json:"userId" gorm:"index:idx_user_candidate"`
 	CandidateID       uint   `json:"candidateId" gorm:"index:idx_user_candidate"`
 	CompatibilityText string `json:"compatibilityText"`
+	BirthHash         string `json:"birthHash" gorm:"type:varchar(64)"` // Birth data the explanation was written for
 }
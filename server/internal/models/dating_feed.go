@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+const (
+	DatingFeedSeen = "seen"
+	DatingFeedPass = "pass"
+)
+
+// DatingFeedAction remembers what a user did with a candidate shown in the feed. Seen
+// candidates sink in the ranking; passed ones are no longer shown.
+type DatingFeedAction struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	UserID      uint      `gorm:"not null;uniqueIndex:idx_dating_feed_action_pair,priority:1" json:"userId"`
+	CandidateID uint      `gorm:"not null;uniqueIndex:idx_dating_feed_action_pair,priority:2" json:"candidateId"`
+	Action      string    `gorm:"type:varchar(10);not null" json:"action"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// DatingGunaMilan caches the deterministic Ashtakoota result for a user/candidate pair.
+// BirthHash covers the birth data of both users, so a changed profile misses the cache.
+type DatingGunaMilan struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	UserID      uint      `gorm:"not null;uniqueIndex:idx_dating_guna_milan_pair,priority:1" json:"userId"`
+	CandidateID uint      `gorm:"not null;uniqueIndex:idx_dating_guna_milan_pair,priority:2;index" json:"candidateId"`
+	BirthHash   string    `gorm:"type:varchar(64);not null" json:"birthHash"`
+	Total       float64   `json:"total"`
+	Result      string    `gorm:"type:text" json:"result"` // GunaMilanResult JSON
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
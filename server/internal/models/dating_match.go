@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DatingMatchActive    = "active"
+	DatingMatchUnmatched = "unmatched"
+)
+
+// DatingMatch is a reciprocal like between two users. UserAID is always the lower user ID,
+// so a pair has exactly one row.
+type DatingMatch struct {
+	gorm.Model
+	UserAID       uint       `json:"userAId" gorm:"not null;uniqueIndex:idx_dating_match_pair,priority:1"`
+	UserBID       uint       `json:"userBId" gorm:"not null;uniqueIndex:idx_dating_match_pair,priority:2;index"`
+	RoomID        *uint      `json:"roomId"` // Private room created for the pair
+	Status        string     `json:"status" gorm:"type:varchar(20);default:'active';index"`
+	MatchedAt     time.Time  `json:"matchedAt"`
+	UnmatchedByID *uint      `json:"unmatchedById,omitempty"`
+	UnmatchedAt   *time.Time `json:"unmatchedAt,omitempty"`
+}
+
+// PartnerID returns the other side of the match
+func (m DatingMatch) PartnerID(userID uint) uint {
+	if m.UserAID == userID {
+		return m.UserBID
+	}
+	return m.UserAID
+}
+
+func (m DatingMatch) HasUser(userID uint) bool {
+	return m.UserAID == userID || m.UserBID == userID
+}
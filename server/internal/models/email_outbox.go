@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// EmailBroadcastStatus represents the lifecycle of a mass email broadcast
+type EmailBroadcastStatus string
+
+const (
+	EmailBroadcastQueued    EmailBroadcastStatus = "queued"    // Recipients resolved, messages in outbox
+	EmailBroadcastSending   EmailBroadcastStatus = "sending"   // At least one message delivered
+	EmailBroadcastCompleted EmailBroadcastStatus = "completed" // No pending messages left
+)
+
+// EmailOutboxStatus represents the delivery status of a single outbound email
+type EmailOutboxStatus string
+
+const (
+	EmailOutboxPending EmailOutboxStatus = "pending" // Waiting for (re)delivery
+	EmailOutboxSent    EmailOutboxStatus = "sent"    // Accepted by the mailer
+	EmailOutboxFailed  EmailOutboxStatus = "failed"  // Gave up after max attempts
+)
+
+// EmailBroadcast groups outbox messages created by one admin broadcast
+type EmailBroadcast struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	TemplateID      uint                 `json:"templateId" gorm:"index"`
+	RecipientFilter string               `json:"recipientFilter" gorm:"type:varchar(50)"`
+	CreatedBy       uint                 `json:"createdBy" gorm:"index"`
+	Status          EmailBroadcastStatus `json:"status" gorm:"type:varchar(20);default:'queued';index"`
+
+	// Denormalized counters, refreshed by the outbox worker
+	TotalCount  int `json:"totalCount"`
+	SentCount   int `json:"sentCount"`
+	FailedCount int `json:"failedCount"`
+
+	CompletedAt *time.Time `json:"completedAt"`
+}
+
+// EmailOutboxMessage is a persisted outbound email with retry state
+type EmailOutboxMessage struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+
+	BroadcastID *uint `json:"broadcastId" gorm:"index"`
+	UserID      *uint `json:"userId" gorm:"index"`
+
+	ToEmail string `json:"toEmail" gorm:"type:varchar(255);not null"`
+	Subject string `json:"subject" gorm:"type:varchar(255)"`
+	Body    string `json:"body" gorm:"type:text"`
+
+	Status        EmailOutboxStatus `json:"status" gorm:"type:varchar(20);default:'pending';index:idx_email_outbox_due,priority:1"`
+	Attempts      int               `json:"attempts" gorm:"default:0"`
+	NextAttemptAt time.Time         `json:"nextAttemptAt" gorm:"index:idx_email_outbox_due,priority:2"`
+	LastError     string            `json:"lastError" gorm:"type:text"`
+	SentAt        *time.Time        `json:"sentAt"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Joint reading keeps the live position on Room (BookCode, CurrentCanto, CurrentChapter,
+// CurrentVerse, ActiveReaderID); these tables hold the session, hand-off queue and log.
+
+type RoomReadingSession struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	RoomID      uint       `gorm:"not null;index" json:"roomId"`
+	StartedByID uint       `json:"startedById"`
+	BookCode    string     `gorm:"type:varchar(20)" json:"bookCode"`
+	StartedAt   time.Time  `json:"startedAt"`
+	EndedAt     *time.Time `gorm:"index" json:"endedAt,omitempty"`
+}
+
+// RoomReadingQueueEntry is a member waiting for their turn to read aloud
+type RoomReadingQueueEntry struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	RoomID    uint      `gorm:"not null;uniqueIndex:idx_room_reading_queue_user,priority:1" json:"roomId"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_room_reading_queue_user,priority:2" json:"userId"`
+	Position  int       `gorm:"not null" json:"position"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// RoomReadingLogEntry records a verse read during a session and who read it
+type RoomReadingLogEntry struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	SessionID uint      `gorm:"not null;index" json:"sessionId"`
+	RoomID    uint      `gorm:"not null;index" json:"roomId"`
+	ReaderID  uint      `gorm:"index" json:"readerId"`
+	BookCode  string    `gorm:"type:varchar(20)" json:"bookCode"`
+	Canto     int       `json:"canto"`
+	Chapter   int       `json:"chapter"`
+	Verse     int       `json:"verse"`
+	ReadAt    time.Time `json:"readAt"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Reading plans split a chapter range of a ScriptureBook into a fixed number of daily
+// portions. Calendar dates on enrollments are "YYYY-MM-DD" in the user's timezone.
+
+const (
+	ReadingPlanEnrollmentActive    = "active"
+	ReadingPlanEnrollmentCompleted = "completed"
+	ReadingPlanEnrollmentLeft      = "left"
+)
+
+type ScriptureReadingPlan struct {
+	ID            uint           `gorm:"primaryKey" json:"id"`
+	BookCode      string         `gorm:"type:varchar(20);not null;index" json:"book_code"`
+	TitleEn       string         `gorm:"type:varchar(255)" json:"title_en"`
+	TitleRu       string         `gorm:"type:varchar(255)" json:"title_ru"`
+	DescriptionEn string         `gorm:"type:text" json:"description_en"`
+	DescriptionRu string         `gorm:"type:text" json:"description_ru"`
+	FromCanto     int            `json:"from_canto"` // 0 for books without cantos
+	FromChapter   int            `json:"from_chapter"`
+	ToCanto       int            `json:"to_canto"`
+	ToChapter     int            `json:"to_chapter"`
+	DurationDays  int            `gorm:"not null" json:"duration_days"`
+	IsPublic      bool           `gorm:"default:false;index" json:"is_public"`
+	CreatedByID   uint           `gorm:"index" json:"created_by_id"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+type ScriptureReadingPlanEnrollment struct {
+	ID               uint                 `gorm:"primaryKey" json:"id"`
+	UserID           uint                 `gorm:"not null;index:idx_reading_plan_enrollment_user_plan,priority:1" json:"user_id"`
+	PlanID           uint                 `gorm:"not null;index:idx_reading_plan_enrollment_user_plan,priority:2" json:"plan_id"`
+	Plan             ScriptureReadingPlan `gorm:"foreignKey:PlanID" json:"plan,omitempty"`
+	Status           string               `gorm:"type:varchar(20);default:'active';index" json:"status"`
+	StartDate        string               `gorm:"type:varchar(10);not null" json:"start_date"`
+	ReminderHour     *int                 `json:"reminder_hour"` // 0-23 in the user's timezone, nil disables reminders
+	CompletedDays    int                  `gorm:"default:0" json:"completed_days"`
+	CurrentStreak    int                  `gorm:"default:0" json:"current_streak"`
+	LongestStreak    int                  `gorm:"default:0" json:"longest_streak"`
+	LastReadDate     string               `gorm:"type:varchar(10)" json:"last_read_date"` // Local date of the latest completion
+	LastReminderDate string               `gorm:"type:varchar(10)" json:"-"`
+	CompletedAt      *time.Time           `json:"completed_at,omitempty"`
+	CreatedAt        time.Time            `json:"created_at"`
+	UpdatedAt        time.Time            `json:"updated_at"`
+}
+
+// ScriptureReadingPlanCompletion records that one plan day was read
+type ScriptureReadingPlanCompletion struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	EnrollmentID uint      `gorm:"not null;uniqueIndex:idx_reading_plan_completion_day,priority:1" json:"enrollment_id"`
+	DayNumber    int       `gorm:"not null;uniqueIndex:idx_reading_plan_completion_day,priority:2" json:"day_number"`
+	LocalDate    string    `gorm:"type:varchar(10)" json:"local_date"`
+	CompletedAt  time.Time `json:"completed_at"`
+}
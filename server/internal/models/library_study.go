@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Study layer over ScriptureVerse. Rows are soft-deleted so that other devices
+// pick up deletions through the sync endpoint (updated_at + deleted_at).
+
+const (
+	ScriptureNotePrivate = "private"
+	ScriptureNoteShared  = "shared"
+)
+
+type ScriptureBookmark struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	UserID    uint           `gorm:"not null;uniqueIndex:idx_scripture_bookmark_user_verse,priority:1" json:"user_id"`
+	VerseID   uint           `gorm:"not null;uniqueIndex:idx_scripture_bookmark_user_verse,priority:2" json:"verse_id"`
+	BookCode  string         `gorm:"type:varchar(20);index" json:"book_code"`
+	Label     string         `gorm:"type:varchar(255)" json:"label"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `gorm:"index" json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+}
+
+// ScriptureHighlight marks a rune range [StartOffset, EndOffset) of one verse field
+type ScriptureHighlight struct {
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	UserID      uint           `gorm:"not null;index:idx_scripture_highlight_user_verse,priority:1" json:"user_id"`
+	VerseID     uint           `gorm:"not null;index:idx_scripture_highlight_user_verse,priority:2" json:"verse_id"`
+	BookCode    string         `gorm:"type:varchar(20);index" json:"book_code"`
+	Field       string         `gorm:"type:varchar(20);not null" json:"field"` // translation, purport, synonyms, transliteration
+	StartOffset int            `json:"start_offset"`
+	EndOffset   int            `json:"end_offset"`
+	Color       string         `gorm:"type:varchar(20);not null" json:"color"`
+	Text        string         `gorm:"type:text" json:"text"` // Highlighted text at creation time
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `gorm:"index" json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+}
+
+type ScriptureNote struct {
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	UserID      uint           `gorm:"not null;index:idx_scripture_note_user_verse,priority:1" json:"user_id"`
+	VerseID     uint           `gorm:"not null;index:idx_scripture_note_user_verse,priority:2;index" json:"verse_id"`
+	BookCode    string         `gorm:"type:varchar(20);index" json:"book_code"`
+	HighlightID *uint          `json:"highlight_id,omitempty"`
+	Content     string         `gorm:"type:text;not null" json:"content"`
+	Visibility  string         `gorm:"type:varchar(20);default:'private';index" json:"visibility"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `gorm:"index" json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+}
+
+// ScriptureReadingProgress is the last-read position of a user in a book
+type ScriptureReadingProgress struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_scripture_progress_user_book,priority:1" json:"user_id"`
+	BookCode  string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_scripture_progress_user_book,priority:2" json:"book_code"`
+	VerseID   uint      `gorm:"not null" json:"verse_id"`
+	Canto     int       `json:"canto"`
+	Chapter   int       `json:"chapter"`
+	Verse     string    `gorm:"type:varchar(20)" json:"verse"`
+	Language  string    `gorm:"type:varchar(10)" json:"language"`
+	ReadAt    time.Time `json:"read_at"` // Client time of the read; older updates from other devices are ignored
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `gorm:"index" json:"updated_at"`
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type LKMAuditStatus string
+
+const (
+	LKMAuditConsistent LKMAuditStatus = "consistent"
+	LKMAuditDrift      LKMAuditStatus = "drift"
+	LKMAuditRepaired   LKMAuditStatus = "repaired"
+)
+
+type LKMAuditFindingKind string
+
+const (
+	LKMAuditUnbalancedGroup LKMAuditFindingKind = "unbalanced_group" // Debits and credits of a TxGroupID differ
+	LKMAuditUngroupedEntry  LKMAuditFindingKind = "ungrouped_entry"  // Posted ledger entry without a TxGroupID
+	LKMAuditWalletDrift     LKMAuditFindingKind = "wallet_drift"     // Stored wallet balance differs from its history
+)
+
+// Wallet balance columns recomputed by the audit
+const (
+	WalletFieldBalance            = "balance"
+	WalletFieldBonusBalance       = "bonus_balance"
+	WalletFieldPendingBalance     = "pending_balance"
+	WalletFieldFrozenBalance      = "frozen_balance"
+	WalletFieldFrozenBonusBalance = "frozen_bonus_balance"
+)
+
+// LKMAuditReport is one run of the double-entry consistency check over the LKM ledger
+// and all wallets
+type LKMAuditReport struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	Trigger      string `gorm:"type:varchar(20);not null" json:"trigger"` // admin, cli
+	ActorID      *uint  `gorm:"index" json:"actorId,omitempty"`
+	RepairMode   bool   `gorm:"default:false" json:"repairMode"`
+	GroupsTotal  int    `gorm:"not null;default:0" json:"groupsTotal"`
+	WalletsTotal int    `gorm:"not null;default:0" json:"walletsTotal"`
+
+	FindingCount  int            `gorm:"not null;default:0" json:"findingCount"`
+	RepairedCount int            `gorm:"not null;default:0" json:"repairedCount"`
+	Status        LKMAuditStatus `gorm:"type:varchar(20);not null;index" json:"status"`
+	FinishedAt    time.Time      `json:"finishedAt"`
+
+	Findings []LKMAuditFinding `gorm:"foreignKey:ReportID" json:"findings,omitempty"`
+}
+
+type LKMAuditFinding struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+
+	ReportID uint                `gorm:"not null;index" json:"reportId"`
+	Kind     LKMAuditFindingKind `gorm:"type:varchar(30);not null;index" json:"kind"`
+
+	// Ledger findings
+	TxGroupID     string `gorm:"size:80;index" json:"txGroupId,omitempty"`
+	LedgerEntryID *uint  `json:"ledgerEntryId,omitempty"`
+
+	// Wallet findings
+	WalletID *uint  `gorm:"index" json:"walletId,omitempty"`
+	Field    string `gorm:"type:varchar(30)" json:"field,omitempty"`
+	// FirstDivergentTxID is the first transaction whose BalanceAfter disagrees with the replay
+	FirstDivergentTxID *uint `json:"firstDivergentTxId,omitempty"`
+
+	Expected int `json:"expected"` // Debits for groups, recomputed value for wallets
+	Actual   int `json:"actual"`   // Credits for groups, stored value for wallets
+	Delta    int `json:"delta"`    // Actual - Expected
+
+	Repaired  bool   `gorm:"default:false" json:"repaired"`
+	RepairRef string `gorm:"size:100" json:"repairRef,omitempty"` // Correcting ledger entry or wallet transaction
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type LKMReconciliationStatus string
+
+const (
+	LKMReconciliationClean         LKMReconciliationStatus = "clean"
+	LKMReconciliationDiscrepancies LKMReconciliationStatus = "discrepancies"
+)
+
+type LKMDiscrepancyKind string
+
+const (
+	LKMDiscrepancyGatewayPaidNotCredited LKMDiscrepancyKind = "gateway_paid_not_credited" // Settled by the provider, no LKM issued
+	LKMDiscrepancyCreditedNotSettled     LKMDiscrepancyKind = "credited_not_settled"      // LKM issued, provider does not report a payment
+	LKMDiscrepancyAmountMismatch         LKMDiscrepancyKind = "amount_mismatch"           // Settled amount differs from the quote
+	LKMDiscrepancyCreditedWithoutEvent   LKMDiscrepancyKind = "credited_without_event"    // Credited without a paid webhook event
+	LKMDiscrepancyPaidEventNotApplied    LKMDiscrepancyKind = "paid_event_not_applied"    // Paid event recorded, top-up still pending
+	LKMDiscrepancyWalletCreditMissing    LKMDiscrepancyKind = "wallet_credit_missing"     // Credited top-up without a wallet transaction
+	LKMDiscrepancyWalletCreditMismatch   LKMDiscrepancyKind = "wallet_credit_mismatch"    // Wallet credit differs from ReceiveLKM
+	LKMDiscrepancyUnexpectedWalletCredit LKMDiscrepancyKind = "unexpected_wallet_credit"  // Wallet credit for a top-up that is not credited
+)
+
+// LKMReconciliationReport is the daily comparison of gateway settlements, webhook events
+// and wallet credits for the top-ups created or credited on ReportDate (UTC)
+type LKMReconciliationReport struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	ReportDate  string    `gorm:"type:varchar(10);not null;uniqueIndex" json:"reportDate"`
+	PeriodStart time.Time `gorm:"not null" json:"periodStart"`
+	PeriodEnd   time.Time `gorm:"not null" json:"periodEnd"`
+
+	TopupsChecked    int                     `gorm:"not null;default:0" json:"topupsChecked"`
+	GatewayChecked   int                     `gorm:"not null;default:0" json:"gatewayChecked"` // Top-ups confirmed against the provider API
+	CreditedCount    int                     `gorm:"not null;default:0" json:"creditedCount"`
+	CreditedLKM      int                     `gorm:"not null;default:0" json:"creditedLkm"`
+	DiscrepancyCount int                     `gorm:"not null;default:0" json:"discrepancyCount"`
+	Status           LKMReconciliationStatus `gorm:"type:varchar(24);not null;index" json:"status"`
+	NotifiedAt       *time.Time              `json:"notifiedAt,omitempty"`
+	FinalizedAt      *time.Time              `json:"finalizedAt,omitempty"` // Nil while top-up recovery may still change the outcome
+
+	Discrepancies []LKMReconciliationDiscrepancy `gorm:"foreignKey:ReportID" json:"discrepancies,omitempty"`
+}
+
+type LKMReconciliationDiscrepancy struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	ReportID    uint               `gorm:"not null;index" json:"reportId"`
+	TopupID     string             `gorm:"type:varchar(80);not null;index" json:"topupId"`
+	GatewayCode string             `gorm:"type:varchar(64);not null" json:"gatewayCode"`
+	Kind        LKMDiscrepancyKind `gorm:"type:varchar(48);not null;index" json:"kind"`
+	Details     string             `gorm:"type:varchar(500)" json:"details"`
+
+	Resolved       bool       `gorm:"default:false;index" json:"resolved"`
+	ResolvedByID   *uint      `gorm:"index" json:"resolvedById,omitempty"`
+	ResolvedAt     *time.Time `json:"resolvedAt,omitempty"`
+	ResolutionNote string     `gorm:"type:varchar(500)" json:"resolutionNote"`
+}
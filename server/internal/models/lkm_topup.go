@@ -29,6 +29,7 @@ const (
 	LKMTopupStatusManualReview   LKMTopupStatus = "manual_review"
 	LKMTopupStatusCredited       LKMTopupStatus = "credited"
 	LKMTopupStatusRejected       LKMTopupStatus = "rejected"
+	LKMTopupStatusExpired        LKMTopupStatus = "expired"
 )
 
 type LKMTopupGlobalConfig struct {
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
@@ -18,6 +20,67 @@ type Message struct {
 	Duration  int                    `json:"duration,omitempty"`                       // Audio duration in seconds
 	Thumbnail string                 `json:"thumbnail,omitempty"`                      // Thumbnail URL for images
 	MapData   map[string]interface{} `json:"mapData,omitempty" gorm:"serializer:json"` // Geo-intent data
+
+	// Lifecycle: edit / delete-for-all tombstone / reaction aggregate
+	EditedAt        *time.Time     `json:"editedAt,omitempty"`
+	DeletedForAllAt *time.Time     `json:"deletedForAllAt,omitempty"`
+	DeletedByID     uint           `json:"deletedById,omitempty"`
+	ExpiresAt       *time.Time     `json:"expiresAt,omitempty" gorm:"index"`           // Self-destruct time (deleted for all by a worker)
+	Reactions       map[string]int `json:"reactions,omitempty" gorm:"serializer:json"` // emoji -> count
+
+	// Replies and threads. ThreadRootID is derived from ReplyToID on send;
+	// VerseID anchors a room thread root to a ScriptureVerse.
+	ReplyToID    *uint  `json:"replyToId,omitempty" gorm:"index"`
+	ThreadRootID *uint  `json:"threadRootId,omitempty" gorm:"index"`
+	QuoteText    string `json:"quoteText,omitempty" gorm:"type:text"` // Quoted excerpt of the parent
+	VerseID      *uint  `json:"verseId,omitempty" gorm:"index"`
+
+	// Computed per request (not stored)
+	ThreadReplyCount int                  `json:"threadReplyCount,omitempty" gorm:"-"`
+	ReplyPreview     *MessageReplyPreview `json:"replyTo,omitempty" gorm:"-"`
+
+	// Receipt state of the viewer's own messages, computed per request (not stored)
+	DeliveryStatus string `json:"deliveryStatus,omitempty" gorm:"-"` // sent, delivered, read
+	ReadCount      int    `json:"readCount,omitempty" gorm:"-"`      // Room messages: members who read it
+}
+
+// MessageReplyPreview is a short excerpt of the parent message shown above a reply
+type MessageReplyPreview struct {
+	ID       uint   `json:"id"`
+	SenderID uint   `json:"senderId"`
+	Type     string `json:"type"`
+	Content  string `json:"content"`
+	Deleted  bool   `json:"deleted,omitempty"`
+}
+
+// MessageEdit keeps the previous content of an edited message
+type MessageEdit struct {
+	gorm.Model
+	MessageID       uint   `json:"messageId" gorm:"index"`
+	EditorID        uint   `json:"editorId"`
+	PreviousContent string `json:"previousContent" gorm:"type:text"`
+}
+
+// MessageHiddenForUser marks a message as deleted for one user only ("delete for me")
+type MessageHiddenForUser struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	MessageID uint      `json:"messageId" gorm:"uniqueIndex:idx_message_hidden_user"`
+	UserID    uint      `json:"userId" gorm:"uniqueIndex:idx_message_hidden_user;index"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// MessageReaction is a single user's emoji reaction on a message
+type MessageReaction struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	MessageID uint      `json:"messageId" gorm:"uniqueIndex:idx_message_reaction_unique"`
+	UserID    uint      `json:"userId" gorm:"uniqueIndex:idx_message_reaction_unique"`
+	Emoji     string    `json:"emoji" gorm:"type:varchar(32);uniqueIndex:idx_message_reaction_unique"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// IsDeletedForAll reports whether the message was removed for every participant
+func (m *Message) IsDeletedForAll() bool {
+	return m.DeletedForAllAt != nil
 }
 
 func (m *Message) GetID() uint {
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Message delivery states reported to the sender
+const (
+	MessageStatusSent      = "sent"
+	MessageStatusDelivered = "delivered"
+	MessageStatusRead      = "read"
+)
+
+// ConversationReadState stores per-user delivery/read watermarks for a conversation.
+// A room conversation has PeerID = 0, a 1:1 conversation has RoomID = 0 and PeerID set
+// to the other participant. Every message with ID <= LastReadMessageID counts as read.
+type ConversationReadState struct {
+	ID                     uint       `json:"id" gorm:"primaryKey"`
+	UserID                 uint       `json:"userId" gorm:"not null;uniqueIndex:idx_conversation_read_state"`
+	RoomID                 uint       `json:"roomId" gorm:"not null;default:0;uniqueIndex:idx_conversation_read_state;index"`
+	PeerID                 uint       `json:"peerId" gorm:"not null;default:0;uniqueIndex:idx_conversation_read_state"`
+	LastDeliveredMessageID uint       `json:"lastDeliveredMessageId" gorm:"not null;default:0"`
+	LastReadMessageID      uint       `json:"lastReadMessageId" gorm:"not null;default:0"`
+	DeliveredAt            *time.Time `json:"deliveredAt,omitempty"`
+	ReadAt                 *time.Time `json:"readAt,omitempty"`
+	CreatedAt              time.Time  `json:"createdAt"`
+	UpdatedAt              time.Time  `json:"updatedAt"`
+}
@@ -29,6 +29,16 @@ const (
 	DeliveryTypeDigital  DeliveryType = "digital"  // Digital download
 )
 
+// OrderEscrowStatus tracks LKM held for an order
+type OrderEscrowStatus string
+
+const (
+	OrderEscrowNone     OrderEscrowStatus = ""         // Paid directly or externally
+	OrderEscrowHeld     OrderEscrowStatus = "held"     // Frozen on the buyer's wallet
+	OrderEscrowReleased OrderEscrowStatus = "released" // Paid out to the seller
+	OrderEscrowRefunded OrderEscrowStatus = "refunded" // Returned to the buyer
+)
+
 // Order represents a customer order
 type Order struct {
 	gorm.Model
@@ -59,6 +69,12 @@ type Order struct {
 	RegularLkmPaid int        `json:"regularLkmPaid" gorm:"default:0"`
 	BonusLkmPaid   int        `json:"bonusLkmPaid" gorm:"default:0"`
 
+	// Escrow: LKM is frozen on the buyer's wallet until delivery (see Shop.EscrowEnabled)
+	EscrowStatus     OrderEscrowStatus `json:"escrowStatus,omitempty" gorm:"type:varchar(20);index"`
+	EscrowFeeLkm     int               `json:"escrowFeeLkm" gorm:"default:0"`
+	EscrowReleasedAt *time.Time        `json:"escrowReleasedAt,omitempty"`
+	EscrowRefundedAt *time.Time        `json:"escrowRefundedAt,omitempty"`
+
 	// Delivery
 	DeliveryType    DeliveryType `json:"deliveryType" gorm:"type:varchar(20);not null"`
 	DeliveryAddress string       `json:"deliveryAddress" gorm:"type:text"`
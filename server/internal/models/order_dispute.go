@@ -0,0 +1,126 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OrderDisputeStatus represents where a dispute is in the review workflow
+type OrderDisputeStatus string
+
+const (
+	OrderDisputeOpen     OrderDisputeStatus = "open"     // Waiting for the seller's answer
+	OrderDisputeReview   OrderDisputeStatus = "review"   // In the admin queue
+	OrderDisputeResolved OrderDisputeStatus = "resolved" // Outcome applied to the wallets
+)
+
+// OrderDisputeResolution is the outcome chosen by the admin
+type OrderDisputeResolution string
+
+const (
+	OrderDisputeFullRefund      OrderDisputeResolution = "full_refund"
+	OrderDisputePartialRefund   OrderDisputeResolution = "partial_refund"
+	OrderDisputeReleaseToSeller OrderDisputeResolution = "release_to_seller"
+)
+
+// OrderDisputeParty marks who wrote a statement
+type OrderDisputeParty string
+
+const (
+	OrderDisputeBuyer  OrderDisputeParty = "buyer"
+	OrderDisputeSeller OrderDisputeParty = "seller"
+	OrderDisputeAdmin  OrderDisputeParty = "admin"
+)
+
+// OrderDispute is an issue raised by the buyer about a shipped or delivered order.
+// An order has at most one dispute.
+type OrderDispute struct {
+	gorm.Model
+
+	OrderID  uint   `json:"orderId" gorm:"not null;uniqueIndex"`
+	Order    *Order `json:"order,omitempty" gorm:"foreignKey:OrderID"`
+	BuyerID  uint   `json:"buyerId" gorm:"not null;index"`
+	SellerID uint   `json:"sellerId" gorm:"not null;index"`
+
+	Status OrderDisputeStatus `json:"status" gorm:"type:varchar(20);default:'open';index"`
+	Reason string             `json:"reason" gorm:"type:varchar(200);not null"`
+
+	// Order status at the moment the dispute was opened
+	PreviousOrderStatus OrderStatus `json:"previousOrderStatus" gorm:"type:varchar(20)"`
+
+	// SLA timers
+	SellerDueAt              time.Time  `json:"sellerDueAt"`  // Seller must answer by this time
+	ResolveDueAt             time.Time  `json:"resolveDueAt"` // Admin must resolve by this time
+	SellerRespondedAt        *time.Time `json:"sellerRespondedAt,omitempty"`
+	SellerOverdueAt          *time.Time `json:"sellerOverdueAt,omitempty"`
+	ResolveOverdueNotifiedAt *time.Time `json:"resolveOverdueNotifiedAt,omitempty"`
+
+	// Outcome
+	Resolution     OrderDisputeResolution `json:"resolution,omitempty" gorm:"type:varchar(30)"`
+	RefundLkm      int                    `json:"refundLkm" gorm:"default:0"`
+	ResolutionNote string                 `json:"resolutionNote" gorm:"type:text"`
+	ResolvedByID   *uint                  `json:"resolvedById,omitempty"`
+	ResolvedAt     *time.Time             `json:"resolvedAt,omitempty"`
+
+	Statements []OrderDisputeStatement `json:"statements,omitempty" gorm:"foreignKey:DisputeID"`
+}
+
+// OrderDisputeStatement is one party's account of the problem, with optional photos
+type OrderDisputeStatement struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"createdAt"`
+
+	DisputeID uint              `json:"disputeId" gorm:"not null;index"`
+	AuthorID  uint              `json:"authorId" gorm:"not null"`
+	Party     OrderDisputeParty `json:"party" gorm:"type:varchar(10);not null"`
+	Text      string            `json:"text" gorm:"type:text"`
+
+	Evidence []OrderDisputeEvidence `json:"evidence,omitempty" gorm:"foreignKey:StatementID"`
+}
+
+// OrderDisputeEvidence is a photo stored in S3 and attached to a statement
+type OrderDisputeEvidence struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"createdAt"`
+
+	StatementID uint   `json:"statementId" gorm:"not null;index"`
+	URL         string `json:"url" gorm:"type:varchar(1000);not null"`
+	S3Key       string `json:"-" gorm:"type:varchar(500)"`
+	ContentType string `json:"contentType" gorm:"type:varchar(100)"`
+	Size        int64  `json:"size"`
+}
+
+// OrderDisputeOpenRequest DTO for opening a dispute
+type OrderDisputeOpenRequest struct {
+	Reason string `json:"reason" form:"reason"`
+	Text   string `json:"text" form:"text"`
+}
+
+// OrderDisputeStatementRequest DTO for adding a statement
+type OrderDisputeStatementRequest struct {
+	Text string `json:"text" form:"text"`
+}
+
+// OrderDisputeResolveRequest DTO for the admin decision
+type OrderDisputeResolveRequest struct {
+	Resolution OrderDisputeResolution `json:"resolution"`
+	RefundLkm  int                    `json:"refundLkm"` // Only for partial_refund
+	Note       string                 `json:"note"`
+}
+
+// OrderDisputeFilters for the admin queue
+type OrderDisputeFilters struct {
+	Status  OrderDisputeStatus
+	Overdue bool
+	Page    int
+	Limit   int
+}
+
+// OrderDisputeListResponse for the admin queue
+type OrderDisputeListResponse struct {
+	Disputes   []OrderDispute `json:"disputes"`
+	Total      int64          `json:"total"`
+	Page       int            `json:"page"`
+	TotalPages int            `json:"totalPages"`
+}
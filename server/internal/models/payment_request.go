@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PaymentRequestStatus represents the lifecycle of a payment request
+type PaymentRequestStatus string
+
+const (
+	PaymentRequestPending   PaymentRequestStatus = "pending"
+	PaymentRequestPaid      PaymentRequestStatus = "paid"
+	PaymentRequestCancelled PaymentRequestStatus = "cancelled"
+	PaymentRequestExpired   PaymentRequestStatus = "expired"
+	PaymentRequestRefunded  PaymentRequestStatus = "refunded" // Paid, then returned to the payer
+)
+
+// PaymentRequest is an LKM invoice created by the payee. It is shared as a link or QR
+// code and paid by whoever confirms it, unless PayerID restricts it to one user.
+type PaymentRequest struct {
+	gorm.Model
+
+	Token   string `json:"token" gorm:"type:varchar(64);uniqueIndex;not null"`
+	PayeeID uint   `json:"payeeId" gorm:"not null;index"`
+	Payee   *User  `json:"payee,omitempty" gorm:"foreignKey:PayeeID"`
+	PayerID *uint  `json:"payerId,omitempty" gorm:"index"` // Set on payment, or upfront to restrict the payer
+
+	Amount      int                  `json:"amount" gorm:"not null"`
+	Description string               `json:"description" gorm:"type:varchar(500)"`
+	Status      PaymentRequestStatus `json:"status" gorm:"type:varchar(20);default:'pending';index"`
+	ExpiresAt   time.Time            `json:"expiresAt" gorm:"index"`
+
+	// Invoice for a cafe order paid by scanning the QR at the table or counter
+	CafeOrderID *uint `json:"cafeOrderId,omitempty" gorm:"index"`
+
+	PaidAt      *time.Time `json:"paidAt,omitempty"`
+	CancelledAt *time.Time `json:"cancelledAt,omitempty"`
+	RefundedAt  *time.Time `json:"refundedAt,omitempty"`
+}
+
+// PaymentRequestCreateRequest DTO for creating a payment request
+type PaymentRequestCreateRequest struct {
+	Amount           int    `json:"amount"`
+	Description      string `json:"description"`
+	ExpiresInMinutes int    `json:"expiresInMinutes"` // Defaults to 24 hours
+	PayerID          *uint  `json:"payerId"`
+}
+
+// PaymentRequestResponse is a payment request with its shareable links
+type PaymentRequestResponse struct {
+	PaymentRequest
+	PayeeName string `json:"payeeName"`
+	DeepLink  string `json:"deepLink"`
+	WebLink   string `json:"webLink"`
+	QRPayload string `json:"qrPayload"` // Content to encode in the QR code
+}
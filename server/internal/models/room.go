@@ -47,7 +47,8 @@ type Room struct {
 	StartTime        string `json:"startTime"` // Stored as ISO string or you can use time.Time
 	Location         string `json:"location"`  // City, Country, Yatra
 	Language         string `json:"language"`
-	BookCode         string `json:"bookCode"` // e.g. "bg", "sb"
+	BookCode         string `json:"bookCode"`                      // e.g. "bg", "sb"
+	CurrentCanto     int    `json:"currentCanto" gorm:"default:0"` // 0 for books without cantos
 	CurrentChapter   int    `json:"currentChapter" gorm:"default:1"`
 	CurrentVerse     int    `json:"currentVerse" gorm:"default:1"`
 	ActiveReaderID   uint   `json:"activeReaderId" gorm:"default:0"`
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ScheduledMessageStatus string
+
+const (
+	ScheduledMessagePending   ScheduledMessageStatus = "pending"
+	ScheduledMessageSent      ScheduledMessageStatus = "sent"
+	ScheduledMessageCancelled ScheduledMessageStatus = "cancelled"
+	ScheduledMessageFailed    ScheduledMessageStatus = "failed"
+)
+
+// ScheduledMessage is a chat message queued for delivery at ScheduledAt.
+// On delivery a regular Message is created and linked via MessageID.
+type ScheduledMessage struct {
+	gorm.Model
+	SenderID    uint                   `json:"senderId" gorm:"index;not null"`
+	RecipientID uint                   `json:"recipientId"`
+	RoomID      uint                   `json:"roomId"`
+	Content     string                 `json:"content" gorm:"type:text"`
+	Type        string                 `json:"type" gorm:"default:'text'"`
+	ReplyToID   *uint                  `json:"replyToId,omitempty"`
+	QuoteText   string                 `json:"quoteText,omitempty" gorm:"type:text"`
+	ScheduledAt time.Time              `json:"scheduledAt" gorm:"not null;index:idx_scheduled_messages_due,priority:2"`
+	TTLSeconds  int                    `json:"ttlSeconds,omitempty"` // Self-destruct delay after delivery, 0 = keep
+	Status      ScheduledMessageStatus `json:"status" gorm:"type:varchar(20);default:'pending';index:idx_scheduled_messages_due,priority:1"`
+	MessageID   *uint                  `json:"messageId,omitempty"`
+	SentAt      *time.Time             `json:"sentAt,omitempty"`
+	LastError   string                 `json:"lastError,omitempty" gorm:"type:text"`
+}
@@ -75,6 +75,9 @@ type Shop struct {
 	ModeratedBy       *uint      `json:"moderatedBy"`
 	ModeratedAt       *time.Time `json:"moderatedAt"`
 
+	// Payments: LKM orders are held in escrow until delivery instead of being paid at once
+	EscrowEnabled bool `json:"escrowEnabled" gorm:"default:false"`
+
 	// Technical Room for notifications
 	TechRoomID *uint `json:"techRoomId" gorm:"index"`
 
@@ -123,22 +126,23 @@ type ShopCreateRequest struct {
 
 // ShopUpdateRequest for updating a shop
 type ShopUpdateRequest struct {
-	Name         *string       `json:"name"`
-	Description  *string       `json:"description"`
-	Category     *ShopCategory `json:"category"`
-	City         *string       `json:"city"`
-	Address      *string       `json:"address"`
-	Latitude     *float64      `json:"latitude"`
-	Longitude    *float64      `json:"longitude"`
-	Phone        *string       `json:"phone"`
-	Email        *string       `json:"email"`
-	Website      *string       `json:"website"`
-	Telegram     *string       `json:"telegram"`
-	Instagram    *string       `json:"instagram"`
-	VK           *string       `json:"vk"`
-	WorkingHours *string       `json:"workingHours"`
-	LogoURL      *string       `json:"logoUrl"`
-	CoverURL     *string       `json:"coverUrl"`
+	Name          *string       `json:"name"`
+	Description   *string       `json:"description"`
+	Category      *ShopCategory `json:"category"`
+	City          *string       `json:"city"`
+	Address       *string       `json:"address"`
+	Latitude      *float64      `json:"latitude"`
+	Longitude     *float64      `json:"longitude"`
+	Phone         *string       `json:"phone"`
+	Email         *string       `json:"email"`
+	Website       *string       `json:"website"`
+	Telegram      *string       `json:"telegram"`
+	Instagram     *string       `json:"instagram"`
+	VK            *string       `json:"vk"`
+	WorkingHours  *string       `json:"workingHours"`
+	LogoURL       *string       `json:"logoUrl"`
+	CoverURL      *string       `json:"coverUrl"`
+	EscrowEnabled *bool         `json:"escrowEnabled"`
 }
 
 // ShopFilters for querying shops
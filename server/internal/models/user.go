@@ -43,6 +43,8 @@ type User struct {
 	MaritalStatus      string     `json:"maritalStatus"`
 	BirthTime          string     `json:"birthTime" gorm:"column:birth_time"`
 	BirthPlaceLink     string     `json:"birthPlaceLink" gorm:"column:birth_place_link"`
+	BirthLatitude      *float64   `json:"birthLatitude" gorm:"column:birth_latitude"`
+	BirthLongitude     *float64   `json:"birthLongitude" gorm:"column:birth_longitude"`
 	DatingEnabled      bool       `json:"datingEnabled" gorm:"default:false"`
 	IsProfileComplete  bool       `json:"isProfileComplete" gorm:"default:false"`
 	CurrentPlan        string     `json:"currentPlan" gorm:"default:'trial'"`
@@ -41,6 +41,11 @@ type Wallet struct {
 	FrozenBalance      int `json:"frozenBalance" gorm:"default:0"`      // Frozen regular (held for bookings/charity)
 	FrozenBonusBalance int `json:"frozenBonusBalance" gorm:"default:0"` // Frozen bonus (held for bookings)
 
+	// Admin freeze: blocks outgoing payments, incoming credits still arrive
+	SpendingFrozen       bool       `json:"spendingFrozen" gorm:"default:false;index"`
+	SpendingFrozenAt     *time.Time `json:"spendingFrozenAt,omitempty"`
+	SpendingFrozenReason string     `json:"spendingFrozenReason,omitempty" gorm:"type:varchar(500)"`
+
 	// Statistics
 	TotalEarned int `json:"totalEarned" gorm:"default:0"` // Total credits received
 	TotalSpent  int `json:"totalSpent" gorm:"default:0"`  // Total debits made
@@ -74,6 +79,9 @@ const (
 	TransactionTypeRelease     TransactionType = "release"      // Разморозка
 	TransactionTypeAdminCharge TransactionType = "admin_charge" // Админ: начисление
 	TransactionTypeAdminSeize  TransactionType = "admin_seize"  // Админ: списание
+
+	TransactionTypeAdjustmentCredit TransactionType = "adjustment_credit" // Аудит: корректирующая запись (+)
+	TransactionTypeAdjustmentDebit  TransactionType = "adjustment_debit"  // Аудит: корректирующая запись (-)
 )
 
 // WalletTransaction represents a single transaction in a wallet
@@ -96,6 +104,9 @@ type WalletTransaction struct {
 	// Related booking (if applicable)
 	BookingID *uint `json:"bookingId" gorm:"index"`
 
+	// Related marketplace order (escrow holds)
+	OrderID *uint `json:"orderId,omitempty" gorm:"index"`
+
 	// Related wallet (for transfers between users)
 	RelatedWalletID *uint   `json:"relatedWalletId" gorm:"index"`
 	RelatedWallet   *Wallet `json:"relatedWallet,omitempty" gorm:"foreignKey:RelatedWalletID"`
@@ -109,6 +120,9 @@ type WalletTransaction struct {
 	// Admin audit trail
 	AdminID *uint  `json:"adminId" gorm:"index"`            // Who performed admin action
 	Reason  string `json:"reason" gorm:"type:varchar(500)"` // Reason for admin action
+
+	// Balance column explained by an audit adjustment (see WalletField* constants)
+	AdjustedField string `json:"adjustedField,omitempty" gorm:"type:varchar(30)"`
 }
 
 // ==================== DTOs ====================
@@ -122,6 +136,7 @@ type WalletResponse struct {
 	PendingBalance     int    `json:"pendingBalance"`
 	FrozenBalance      int    `json:"frozenBalance"`
 	FrozenBonusBalance int    `json:"frozenBonusBalance"`
+	SpendingFrozen     bool   `json:"spendingFrozen"`
 	Currency           string `json:"currency"`
 	CurrencyName       string `json:"currencyName"`
 	TotalEarned        int    `json:"totalEarned"`
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WalletLimitTier caps regular LKM leaving a personal wallet by account age. A user gets
+// the enabled tier with the highest MinAccountAgeDays they have reached.
+type WalletLimitTier struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	Name              string `gorm:"type:varchar(64);not null;uniqueIndex" json:"name"`
+	MinAccountAgeDays int    `gorm:"not null;default:0" json:"minAccountAgeDays"`
+	DailyLimit        int    `gorm:"not null;default:0" json:"dailyLimit"`   // 0 = unlimited
+	MonthlyLimit      int    `gorm:"not null;default:0" json:"monthlyLimit"` // 0 = unlimited
+	SortOrder         int    `gorm:"not null;default:0" json:"sortOrder"`
+	IsEnabled         bool   `gorm:"default:true;index" json:"isEnabled"`
+}
+
+// WalletLimitOverride pins a user to a tier and/or replaces single limits of it
+type WalletLimitOverride struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	UserID       uint   `gorm:"not null;uniqueIndex" json:"userId"`
+	TierName     string `gorm:"type:varchar(64)" json:"tierName,omitempty"`
+	DailyLimit   *int   `json:"dailyLimit,omitempty"`   // 0 = unlimited
+	MonthlyLimit *int   `json:"monthlyLimit,omitempty"` // 0 = unlimited
+	Note         string `gorm:"type:varchar(500)" json:"note,omitempty"`
+	UpdatedByID  *uint  `gorm:"index" json:"updatedById,omitempty"`
+}
+
+type WalletControlAction string
+
+const (
+	WalletControlFreeze          WalletControlAction = "freeze"
+	WalletControlUnfreeze        WalletControlAction = "unfreeze"
+	WalletControlLimitsUpdated   WalletControlAction = "limits_updated"
+	WalletControlVelocityBlocked WalletControlAction = "velocity_blocked"
+)
+
+// WalletControlEvent is the append-only audit trail of freezes, limit changes and
+// blocked transfers. AdminID is empty for automatic blocks.
+type WalletControlEvent struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"createdAt"`
+
+	UserID   uint                `gorm:"not null;index" json:"userId"`
+	WalletID *uint               `gorm:"index" json:"walletId,omitempty"`
+	AdminID  *uint               `gorm:"index" json:"adminId,omitempty"`
+	Action   WalletControlAction `gorm:"type:varchar(32);not null;index" json:"action"`
+	Reason   string              `gorm:"type:varchar(500)" json:"reason"`
+	Details  string              `gorm:"type:text" json:"details,omitempty"`
+}
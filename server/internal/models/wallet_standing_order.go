@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// StandingOrderFrequency controls how often a standing order repeats
+type StandingOrderFrequency string
+
+const (
+	StandingOrderOnce    StandingOrderFrequency = "once"    // Single scheduled transfer
+	StandingOrderWeekly  StandingOrderFrequency = "weekly"  // Every 7 days
+	StandingOrderMonthly StandingOrderFrequency = "monthly" // Same day every month
+)
+
+// StandingOrderStatus represents the lifecycle of a standing order
+type StandingOrderStatus string
+
+const (
+	StandingOrderActive    StandingOrderStatus = "active"
+	StandingOrderPaused    StandingOrderStatus = "paused"    // Insufficient balance, resumed by the payer
+	StandingOrderCancelled StandingOrderStatus = "cancelled" // Cancelled by the payer
+	StandingOrderCompleted StandingOrderStatus = "completed" // All runs done
+)
+
+// WalletStandingOrder is a scheduled or recurring LKM transfer, e.g. a monthly donation
+// to a teacher or temple
+type WalletStandingOrder struct {
+	gorm.Model
+
+	FromUserID uint  `json:"fromUserId" gorm:"not null;index"`
+	ToUserID   uint  `json:"toUserId" gorm:"not null;index"`
+	ToUser     *User `json:"toUser,omitempty" gorm:"foreignKey:ToUserID"`
+
+	Amount      int                    `json:"amount" gorm:"not null"`
+	Description string                 `json:"description" gorm:"type:varchar(500)"`
+	Frequency   StandingOrderFrequency `json:"frequency" gorm:"type:varchar(20);not null"`
+	Status      StandingOrderStatus    `json:"status" gorm:"type:varchar(20);default:'active';index"`
+
+	// Schedule
+	NextRunAt time.Time  `json:"nextRunAt" gorm:"index"`
+	AnchorDay int        `json:"anchorDay"` // Day of month for monthly orders, clamped to short months
+	EndsAt    *time.Time `json:"endsAt,omitempty"`
+	MaxRuns   int        `json:"maxRuns" gorm:"default:0"` // 0 means until cancelled
+
+	// Execution state
+	RunCount     int        `json:"runCount" gorm:"default:0"`
+	LastRunAt    *time.Time `json:"lastRunAt,omitempty"`
+	LastError    string     `json:"lastError,omitempty" gorm:"type:varchar(500)"`
+	FailureCount int        `json:"failureCount" gorm:"default:0"` // Consecutive runs that failed unexpectedly
+	PausedAt     *time.Time `json:"pausedAt,omitempty"`
+	CancelledAt  *time.Time `json:"cancelledAt,omitempty"`
+}
+
+// StandingOrderCreateRequest DTO for creating a standing order
+type StandingOrderCreateRequest struct {
+	ToUserID    uint                   `json:"toUserId"`
+	Amount      int                    `json:"amount"`
+	Description string                 `json:"description"`
+	Frequency   StandingOrderFrequency `json:"frequency"`
+	StartAt     *time.Time             `json:"startAt"` // Defaults to now
+	EndsAt      *time.Time             `json:"endsAt"`
+	MaxRuns     int                    `json:"maxRuns"`
+}
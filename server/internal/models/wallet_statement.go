@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WalletStatement is an archived monthly statement of a user's wallet. Regenerating the
+// same month and format replaces the archived file.
+type WalletStatement struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	UserID   uint   `gorm:"not null;uniqueIndex:ux_wallet_statement_period" json:"userId"`
+	WalletID uint   `gorm:"not null;index" json:"walletId"`
+	Month    string `gorm:"type:varchar(7);not null;uniqueIndex:ux_wallet_statement_period" json:"month"` // YYYY-MM
+	Format   string `gorm:"type:varchar(8);not null;uniqueIndex:ux_wallet_statement_period" json:"format"`
+
+	PeriodStart time.Time `gorm:"not null" json:"periodStart"`
+	PeriodEnd   time.Time `gorm:"not null" json:"periodEnd"` // Exclusive; "now" for the running month
+
+	OpeningBalance      int `json:"openingBalance"`
+	OpeningBonusBalance int `json:"openingBonusBalance"`
+	ClosingBalance      int `json:"closingBalance"`
+	ClosingBonusBalance int `json:"closingBonusBalance"`
+	BalanceDrift        int `gorm:"not null;default:0" json:"balanceDrift"` // Stored minus replayed balance; non-zero flags the statement
+	BonusBalanceDrift   int `gorm:"not null;default:0" json:"bonusBalanceDrift"`
+	TotalIn             int `json:"totalIn"`
+	TotalOut            int `json:"totalOut"`
+	TransactionCount    int `json:"transactionCount"`
+
+	StorageKey  string    `gorm:"type:varchar(255);not null" json:"-"`
+	SizeBytes   int64     `json:"sizeBytes"`
+	GeneratedAt time.Time `gorm:"not null" json:"generatedAt"`
+
+	DownloadURL string `gorm:"-" json:"downloadUrl,omitempty"`
+}
@@ -760,7 +760,7 @@ func (s *CafeOrderService) CancelOrder(orderID uint, userID uint, reason string)
 
 	err := s.db.Transaction(func(tx *gorm.DB) error {
 		var order models.CafeOrder
-		if err := tx.First(&order, orderID).Error; err != nil {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, orderID).Error; err != nil {
 			return err
 		}
 
@@ -776,7 +776,22 @@ func (s *CafeOrderService) CancelOrder(orderID uint, userID uint, reason string)
 			"cancel_reason": reason,
 		}
 
-		if order.PaymentMethod == "lkm" && order.IsPaid && order.CustomerID != nil && (order.RegularLkmPaid+order.BonusLkmPaid) > 0 {
+		// Orders paid by QR went to the cafe owner, so the refund comes from there too
+		refundedByOwner := false
+		if order.PaymentMethod == "lkm" && order.IsPaid {
+			refunded, err := refundCafeOrderPaymentTx(tx, s.walletService, &order)
+			if err != nil {
+				return err
+			}
+			refundedByOwner = refunded
+		}
+
+		if refundedByOwner {
+			orderUpdates["is_paid"] = false
+			orderUpdates["paid_at"] = nil
+			orderUpdates["regular_lkm_paid"] = 0
+			orderUpdates["bonus_lkm_paid"] = 0
+		} else if order.PaymentMethod == "lkm" && order.IsPaid && order.CustomerID != nil && (order.RegularLkmPaid+order.BonusLkmPaid) > 0 {
 			if err := s.walletService.refundTxWithSplit(
 				tx,
 				*order.CustomerID,
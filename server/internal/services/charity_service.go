@@ -320,6 +320,9 @@ func (s *CharityService) Donate(donorUserID uint, req models.DonateRequest) (*mo
 			First(userWallet, userWallet.ID).Error; err != nil {
 			return err
 		}
+		if err := s.WalletService.enforceOutgoingTx(tx, userWallet, totalAmount); err != nil {
+			return err
+		}
 
 		if userWallet.Balance < totalAmount {
 			return errors.New("insufficient balance")
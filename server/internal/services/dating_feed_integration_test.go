@@ -0,0 +1,69 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"rag-agent-server/internal/models"
+)
+
+func TestFeedPagingWhileMarkingSeen_Integration(t *testing.T) {
+	db := setupYatraServiceIntegrationDB(t)
+	if err := db.AutoMigrate(&models.Media{}, &models.Block{}, &models.DatingFeedAction{}, &models.DatingFavorite{}); err != nil {
+		t.Fatalf("dating feed automigrate failed: %v", err)
+	}
+
+	city := fmt.Sprintf("feed-it-%d", time.Now().UnixNano())
+	viewer := createYatraIntegrationUser(t, db, "feed-viewer")
+	const candidates = 7
+	for i := 0; i < candidates; i++ {
+		user := createYatraIntegrationUser(t, db, fmt.Sprintf("feed-candidate-%d", i))
+		if err := db.Model(&user).Updates(map[string]interface{}{"dating_enabled": true, "city": city}).Error; err != nil {
+			t.Fatalf("enable dating for candidate %d: %v", i, err)
+		}
+	}
+
+	service := NewDatingFeedService()
+	seen := map[uint]bool{}
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > candidates {
+			t.Fatalf("feed did not end after %d pages", pages)
+		}
+		page, err := service.Feed(DatingFeedQuery{ViewerID: viewer.ID, City: city, Cursor: cursor, Limit: 2})
+		if err != nil {
+			t.Fatalf("Feed() page %d error = %v", pages, err)
+		}
+		ids := make([]uint, 0, len(page.Items))
+		for _, item := range page.Items {
+			if seen[item.User.ID] {
+				t.Fatalf("candidate %d returned twice (page %d)", item.User.ID, pages)
+			}
+			seen[item.User.ID] = true
+			ids = append(ids, item.User.ID)
+		}
+		// The client reports shown cards before asking for the next page
+		if err := service.MarkSeen(viewer.ID, ids); err != nil {
+			t.Fatalf("MarkSeen() error = %v", err)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	if len(seen) != candidates {
+		t.Fatalf("paged through %d candidates, want %d", len(seen), candidates)
+	}
+
+	// A new scroll ranks them as seen
+	page, err := service.Feed(DatingFeedQuery{ViewerID: viewer.ID, City: city, Limit: candidates})
+	if err != nil {
+		t.Fatalf("Feed() error = %v", err)
+	}
+	for _, item := range page.Items {
+		if !item.Seen {
+			t.Fatalf("candidate %d not marked seen in a new scroll", item.User.ID)
+		}
+	}
+}
@@ -0,0 +1,449 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"math"
+	"rag-agent-server/internal/database"
+	"rag-agent-server/internal/models"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidFeedCursor    = errors.New("invalid feed cursor")
+	ErrInvalidFeedCandidate = errors.New("invalid candidate")
+)
+
+const (
+	datingFeedDefaultLimit = 20
+	datingFeedMaxLimit     = 100
+	// Ranking happens in memory over the most recently updated eligible profiles
+	datingFeedPoolSize = 1000
+)
+
+// Score weights of the ranking signals
+const (
+	feedWeightDistance  = 30.0
+	feedWeightInterests = 25.0
+	feedWeightActivity  = 20.0
+	feedWeightMadh      = 10.0
+	feedWeightYogaStyle = 5.0
+	feedWeightAge       = 10.0
+	feedWeightBusiness  = 10.0
+	feedPenaltySeen     = 15.0
+
+	feedDistanceScaleKm = 25.0
+	feedActivityDays    = 7.0
+	feedAgeSpanYears    = 15.0
+)
+
+type DatingFeedService struct {
+	db *gorm.DB
+}
+
+func NewDatingFeedService() *DatingFeedService {
+	return &DatingFeedService{db: database.DB}
+}
+
+// DatingFeedQuery holds the hard filters of the feed; everything else is ranking
+type DatingFeedQuery struct {
+	ViewerID      uint
+	Mode          string // family, business, friendship, seva
+	Gender        string
+	City          string
+	Madh          string
+	YogaStyle     string
+	Guna          string
+	Identity      string
+	Skills        string
+	Industry      string
+	MinAge        int
+	MaxAge        int
+	IsNew         bool
+	MaxDistanceKm float64
+	Cursor        string
+	Limit         int
+}
+
+type DatingFeedItem struct {
+	User       models.User `json:"user"`
+	Score      float64     `json:"score"`
+	DistanceKm *float64    `json:"distanceKm,omitempty"`
+	Seen       bool        `json:"seen"`
+}
+
+type DatingFeedPage struct {
+	Items      []DatingFeedItem `json:"items"`
+	NextCursor string           `json:"nextCursor,omitempty"`
+}
+
+// feedCandidate is the slice of a profile the ranking needs
+type feedCandidate struct {
+	ID         uint
+	Latitude   *float64
+	Longitude  *float64
+	Interests  string
+	Madh       string
+	YogaStyle  string
+	Dob        string
+	LastSeen   string
+	Intentions string
+	Skills     string
+	Industry   string
+}
+
+type scoredCandidate struct {
+	id         uint
+	score      float64
+	distanceKm *float64
+	seen       bool
+}
+
+// Feed returns one page of ranked candidates for the viewer. Blocked users in either
+// direction, passed and already liked candidates are excluded.
+func (s *DatingFeedService) Feed(q DatingFeedQuery) (*DatingFeedPage, error) {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = datingFeedDefaultLimit
+	}
+	if limit > datingFeedMaxLimit {
+		limit = datingFeedMaxLimit
+	}
+	cursor, hasCursor, err := decodeFeedCursor(q.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	// Every page of one scroll is ranked as of the first page, so that activity decay
+	// and candidates marked seen meanwhile do not reshuffle the remaining pages
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	if hasCursor {
+		now = cursor.snapshot
+	}
+
+	var viewer models.User
+	if q.ViewerID != 0 {
+		if err := s.db.First(&viewer, q.ViewerID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+	// Family mode shows the opposite gender unless asked otherwise
+	if q.Gender == "" && q.Mode == "family" {
+		switch viewer.Gender {
+		case "Male":
+			q.Gender = "Female"
+		case "Female":
+			q.Gender = "Male"
+		}
+	}
+
+	var pool []feedCandidate
+	if err := s.filteredQuery(q, now).
+		Select("id", "latitude", "longitude", "interests", "madh", "yoga_style", "dob", "last_seen", "intentions", "skills", "industry").
+		Order("updated_at DESC").Limit(datingFeedPoolSize).
+		Find(&pool).Error; err != nil {
+		return nil, err
+	}
+
+	seen := map[uint]bool{}
+	if q.ViewerID != 0 {
+		var seenIDs []uint
+		if err := s.db.Model(&models.DatingFeedAction{}).
+			Where("user_id = ? AND action = ? AND created_at < ?", q.ViewerID, models.DatingFeedSeen, now).
+			Pluck("candidate_id", &seenIDs).Error; err != nil {
+			return nil, err
+		}
+		for _, id := range seenIDs {
+			seen[id] = true
+		}
+	}
+
+	scored := make([]scoredCandidate, 0, len(pool))
+	for _, c := range pool {
+		if q.Mode != "" && !containsToken(c.Intentions, q.Mode) {
+			continue
+		}
+		item := scoreFeedCandidate(viewer, c, q, seen[c.ID], now)
+		if q.MaxDistanceKm > 0 && (item.distanceKm == nil || *item.distanceKm > q.MaxDistanceKm) {
+			continue
+		}
+		scored = append(scored, item)
+	}
+	sort.Slice(scored, func(i, j int) bool { return feedLess(scored[i], scored[j]) })
+
+	start := 0
+	if hasCursor {
+		cursorItem := scoredCandidate{id: cursor.id, score: cursor.score}
+		start = sort.Search(len(scored), func(i int) bool { return feedLess(cursorItem, scored[i]) })
+	}
+	end := start + limit
+	if end > len(scored) {
+		end = len(scored)
+	}
+	page := scored[start:end]
+
+	result := &DatingFeedPage{Items: make([]DatingFeedItem, 0, len(page))}
+	if len(page) == 0 {
+		return result, nil
+	}
+	if end < len(scored) {
+		last := page[len(page)-1]
+		result.NextCursor = encodeFeedCursor(feedCursor{score: last.score, id: last.id, snapshot: now})
+	}
+
+	ids := make([]uint, 0, len(page))
+	for _, item := range page {
+		ids = append(ids, item.id)
+	}
+	var users []models.User
+	if err := s.db.Preload("Photos").Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.User, len(users))
+	for _, u := range users {
+		u.Password = ""
+		byID[u.ID] = u
+	}
+	for _, item := range page {
+		u, ok := byID[item.id]
+		if !ok {
+			continue
+		}
+		result.Items = append(result.Items, DatingFeedItem{User: u, Score: item.score, DistanceKm: item.distanceKm, Seen: item.seen})
+	}
+	return result, nil
+}
+
+func (s *DatingFeedService) filteredQuery(q DatingFeedQuery, now time.Time) *gorm.DB {
+	query := s.db.Model(&models.User{}).Where("dating_enabled = ? AND is_profile_complete = ?", true, true)
+
+	if q.ViewerID != 0 {
+		query = query.Where("id <> ?", q.ViewerID).
+			Where("id NOT IN (?)", s.db.Model(&models.Block{}).Select("blocked_id").Where("user_id = ?", q.ViewerID)).
+			Where("id NOT IN (?)", s.db.Model(&models.Block{}).Select("user_id").Where("blocked_id = ?", q.ViewerID)).
+			Where("id NOT IN (?)", s.db.Model(&models.DatingFeedAction{}).Select("candidate_id").Where("user_id = ? AND action = ?", q.ViewerID, models.DatingFeedPass)).
+			Where("id NOT IN (?)", s.db.Model(&models.DatingFavorite{}).Select("candidate_id").Where("user_id = ?", q.ViewerID))
+	}
+	// Narrows the pool; Feed then requires the mode as a whole token of the comma list
+	if q.Mode != "" {
+		query = query.Where("intentions ILIKE ?", "%"+q.Mode+"%")
+	}
+	if q.Gender != "" {
+		query = query.Where("gender = ?", q.Gender)
+	}
+	if q.City != "" {
+		query = query.Where("city = ?", q.City)
+	}
+	if q.Madh != "" {
+		query = query.Where("madh = ?", q.Madh)
+	}
+	if q.YogaStyle != "" {
+		query = query.Where("yoga_style = ?", q.YogaStyle)
+	}
+	if q.Guna != "" {
+		query = query.Where("guna = ?", q.Guna)
+	}
+	if q.Identity != "" {
+		query = query.Where("identity = ?", q.Identity)
+	}
+	if q.IsNew {
+		query = query.Where("created_at > ?", now.Add(-24*time.Hour))
+	}
+	// Dob is stored as YYYY-MM-DD, so string comparison orders by date
+	if q.MinAge > 0 {
+		query = query.Where("dob <= ?", now.AddDate(-q.MinAge, 0, 0).Format("2006-01-02"))
+	}
+	if q.MaxAge > 0 {
+		query = query.Where("dob > ?", now.AddDate(-(q.MaxAge+1), 0, 0).Format("2006-01-02"))
+	}
+	return query
+}
+
+func scoreFeedCandidate(viewer models.User, c feedCandidate, q DatingFeedQuery, seen bool, now time.Time) scoredCandidate {
+	item := scoredCandidate{id: c.ID, seen: seen}
+	score := 0.0
+
+	if viewer.Latitude != nil && viewer.Longitude != nil && c.Latitude != nil && c.Longitude != nil {
+		d := haversineDistance(*viewer.Latitude, *viewer.Longitude, *c.Latitude, *c.Longitude)
+		item.distanceKm = &d
+		score += feedWeightDistance / (1 + d/feedDistanceScaleKm)
+	}
+
+	score += feedWeightInterests * tokenOverlap(viewer.Interests, c.Interests)
+	if viewer.Madh != "" && strings.EqualFold(strings.TrimSpace(viewer.Madh), strings.TrimSpace(c.Madh)) {
+		score += feedWeightMadh
+	}
+	if viewer.YogaStyle != "" && strings.EqualFold(strings.TrimSpace(viewer.YogaStyle), strings.TrimSpace(c.YogaStyle)) {
+		score += feedWeightYogaStyle
+	}
+
+	if viewerAge, ok := ageOn(viewer.Dob, now); ok {
+		if candidateAge, ok := ageOn(c.Dob, now); ok {
+			score += feedWeightAge * math.Max(0, 1-math.Abs(float64(viewerAge-candidateAge))/feedAgeSpanYears)
+		}
+	}
+
+	if lastSeen, err := time.Parse(time.RFC3339, strings.TrimSpace(c.LastSeen)); err == nil {
+		days := math.Max(0, now.Sub(lastSeen).Hours()/24)
+		score += feedWeightActivity * math.Exp(-days/feedActivityDays)
+	}
+
+	if q.Skills != "" && containsToken(c.Skills, q.Skills) {
+		score += feedWeightBusiness
+	}
+	if q.Industry != "" && containsToken(c.Industry, q.Industry) {
+		score += feedWeightBusiness
+	}
+
+	if seen {
+		score -= feedPenaltySeen
+	}
+	// Rounded so that the cursor reproduces the exact ordering key
+	item.score = math.Round(score*1e4) / 1e4
+	return item
+}
+
+// feedLess orders by score descending, then by ID descending for a stable order
+func feedLess(a, b scoredCandidate) bool {
+	if a.score != b.score {
+		return a.score > b.score
+	}
+	return a.id > b.id
+}
+
+// feedCursor points after the last item of a page; snapshot is the ranking time of
+// the first page
+type feedCursor struct {
+	score    float64
+	id       uint
+	snapshot time.Time
+}
+
+func encodeFeedCursor(c feedCursor) string {
+	raw := strconv.FormatFloat(c.score, 'f', 4, 64) + "|" + strconv.FormatUint(uint64(c.id), 10) +
+		"|" + strconv.FormatInt(c.snapshot.UnixMicro(), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeFeedCursor(cursor string) (feedCursor, bool, error) {
+	cursor = strings.TrimSpace(cursor)
+	if cursor == "" {
+		return feedCursor{}, false, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return feedCursor{}, false, ErrInvalidFeedCursor
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 {
+		return feedCursor{}, false, ErrInvalidFeedCursor
+	}
+	score, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return feedCursor{}, false, ErrInvalidFeedCursor
+	}
+	id, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil || id == 0 {
+		return feedCursor{}, false, ErrInvalidFeedCursor
+	}
+	micros, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || micros <= 0 {
+		return feedCursor{}, false, ErrInvalidFeedCursor
+	}
+	return feedCursor{score: score, id: uint(id), snapshot: time.UnixMicro(micros).UTC()}, true, nil
+}
+
+// splitTokens splits a free-text list such as "kirtan, yoga; cooking" into lowercase tokens
+func splitTokens(raw string) map[string]struct{} {
+	tokens := map[string]struct{}{}
+	for _, part := range strings.FieldsFunc(strings.ToLower(raw), func(r rune) bool {
+		return r == ',' || r == ';' || r == '|' || r == '/' || r == '\n'
+	}) {
+		if part = strings.TrimSpace(part); part != "" {
+			tokens[part] = struct{}{}
+		}
+	}
+	return tokens
+}
+
+// tokenOverlap is the Jaccard similarity of two token lists
+func tokenOverlap(a, b string) float64 {
+	left, right := splitTokens(a), splitTokens(b)
+	if len(left) == 0 || len(right) == 0 {
+		return 0
+	}
+	shared := 0
+	for token := range left {
+		if _, ok := right[token]; ok {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(left)+len(right)-shared)
+}
+
+func containsToken(list, token string) bool {
+	_, ok := splitTokens(list)[strings.ToLower(strings.TrimSpace(token))]
+	return ok
+}
+
+// ageOn returns full years since a YYYY-MM-DD (or ISO timestamp) date of birth
+func ageOn(dob string, now time.Time) (int, bool) {
+	dob = strings.TrimSpace(dob)
+	if len(dob) < 10 {
+		return 0, false
+	}
+	born, err := time.Parse("2006-01-02", dob[:10])
+	if err != nil {
+		return 0, false
+	}
+	age := now.Year() - born.Year()
+	if now.YearDay() < born.YearDay() {
+		age--
+	}
+	return age, age >= 0
+}
+
+// MarkSeen records candidates the viewer has scrolled past; passes are kept as they are
+func (s *DatingFeedService) MarkSeen(viewerID uint, candidateIDs []uint) error {
+	ids := uniqueIDs(candidateIDs)
+	if len(ids) == 0 {
+		return nil
+	}
+	rows := make([]models.DatingFeedAction, 0, len(ids))
+	for _, id := range ids {
+		if id == 0 || id == viewerID {
+			continue
+		}
+		rows = append(rows, models.DatingFeedAction{UserID: viewerID, CandidateID: id, Action: models.DatingFeedSeen})
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "candidate_id"}},
+		DoNothing: true,
+	}).Create(&rows).Error
+}
+
+// Pass hides a candidate from the viewer's feed
+func (s *DatingFeedService) Pass(viewerID, candidateID uint) error {
+	if candidateID == 0 || candidateID == viewerID {
+		return ErrInvalidFeedCandidate
+	}
+	row := models.DatingFeedAction{UserID: viewerID, CandidateID: candidateID, Action: models.DatingFeedPass}
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "candidate_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"action", "updated_at"}),
+	}).Create(&row).Error
+}
+
+// Unpass brings a passed candidate back into the feed
+func (s *DatingFeedService) Unpass(viewerID, candidateID uint) error {
+	return s.db.Where("user_id = ? AND candidate_id = ? AND action = ?", viewerID, candidateID, models.DatingFeedPass).
+		Delete(&models.DatingFeedAction{}).Error
+}
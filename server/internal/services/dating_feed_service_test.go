@@ -0,0 +1,107 @@
+package services
+
+import (
+	"encoding/base64"
+	"rag-agent-server/internal/models"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestFeedCursorRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	snapshot := time.Date(2026, 6, 1, 12, 0, 0, 123456000, time.UTC)
+	got, ok, err := decodeFeedCursor(encodeFeedCursor(feedCursor{score: 42.1234, id: 17, snapshot: snapshot}))
+	if err != nil || !ok || got.score != 42.1234 || got.id != 17 || !got.snapshot.Equal(snapshot) {
+		t.Fatalf("round trip = %+v, %v, %v", got, ok, err)
+	}
+	if _, ok, err := decodeFeedCursor(""); ok || err != nil {
+		t.Fatalf("empty cursor must mean the first page")
+	}
+	for _, bad := range []string{
+		"###",
+		"bm90LWEtY3Vyc29y",
+		encodeFeedCursor(feedCursor{score: 1, snapshot: snapshot}),
+		base64.RawURLEncoding.EncodeToString([]byte("1.0000|5")),
+	} {
+		if _, _, err := decodeFeedCursor(bad); err != ErrInvalidFeedCursor {
+			t.Fatalf("decodeFeedCursor(%q) error = %v, want ErrInvalidFeedCursor", bad, err)
+		}
+	}
+}
+
+func TestFeedPagingOrder(t *testing.T) {
+	t.Parallel()
+
+	scored := []scoredCandidate{{id: 1, score: 10}, {id: 2, score: 30}, {id: 3, score: 10}, {id: 4, score: 20}}
+	sort.Slice(scored, func(i, j int) bool { return feedLess(scored[i], scored[j]) })
+
+	want := []uint{2, 4, 3, 1}
+	for i, item := range scored {
+		if item.id != want[i] {
+			t.Fatalf("position %d = %d, want %d", i, item.id, want[i])
+		}
+	}
+
+	cursor := scoredCandidate{id: 3, score: 10}
+	start := sort.Search(len(scored), func(i int) bool { return feedLess(cursor, scored[i]) })
+	if start != 3 || scored[start].id != 1 {
+		t.Fatalf("page after cursor starts at %d", start)
+	}
+}
+
+func TestTokenOverlap(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{a: "Kirtan, Yoga", b: "yoga; kirtan", want: 1},
+		{a: "kirtan, yoga", b: "yoga, cooking", want: 1.0 / 3.0},
+		{a: "", b: "yoga", want: 0},
+	}
+	for _, tc := range tests {
+		if got := tokenOverlap(tc.a, tc.b); got != tc.want {
+			t.Fatalf("tokenOverlap(%q, %q) = %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+	if !containsToken("family,business", "business") || containsToken("family,business", "bus") {
+		t.Fatalf("containsToken must match whole tokens only")
+	}
+}
+
+func TestScoreFeedCandidate(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	lat, lon := 55.75, 37.62
+	nearLat, nearLon := 55.76, 37.63
+	farLat, farLon := 59.93, 30.33
+	viewer := models.User{Latitude: &lat, Longitude: &lon, Interests: "kirtan, yoga", Madh: "Gaudiya", Dob: "1995-03-10"}
+
+	near := feedCandidate{ID: 1, Latitude: &nearLat, Longitude: &nearLon, Interests: "yoga", Madh: "Gaudiya", Dob: "1996-01-01", LastSeen: now.Add(-time.Hour).Format(time.RFC3339)}
+	far := near
+	far.ID, far.Latitude, far.Longitude = 2, &farLat, &farLon
+
+	nearScore := scoreFeedCandidate(viewer, near, DatingFeedQuery{}, false, now)
+	farScore := scoreFeedCandidate(viewer, far, DatingFeedQuery{}, false, now)
+	if nearScore.score <= farScore.score {
+		t.Fatalf("closer candidate must rank higher: %v <= %v", nearScore.score, farScore.score)
+	}
+	if farScore.distanceKm == nil || *farScore.distanceKm < 600 {
+		t.Fatalf("unexpected distance %v", farScore.distanceKm)
+	}
+
+	seenScore := scoreFeedCandidate(viewer, near, DatingFeedQuery{}, true, now)
+	if seenScore.score >= nearScore.score {
+		t.Fatalf("seen candidates must sink in the ranking")
+	}
+
+	stale := near
+	stale.LastSeen = now.AddDate(0, -2, 0).Format(time.RFC3339)
+	if scoreFeedCandidate(viewer, stale, DatingFeedQuery{}, false, now).score >= nearScore.score {
+		t.Fatalf("recently active candidates must rank higher")
+	}
+}
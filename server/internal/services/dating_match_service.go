@@ -0,0 +1,279 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"rag-agent-server/internal/database"
+	"rag-agent-server/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrDatingMatchNotFound  = errors.New("match not found")
+	ErrDatingMatchForbidden = errors.New("not a participant of this match")
+	ErrDatingUserBlocked    = errors.New("contact with this user is blocked")
+)
+
+type DatingMatchService struct {
+	db *gorm.DB
+}
+
+func NewDatingMatchService() *DatingMatchService {
+	return &DatingMatchService{db: database.DB}
+}
+
+// DatingMatchView is a match as seen by one of its participants
+type DatingMatchView struct {
+	models.DatingMatch
+	Partner models.User `json:"partner"`
+}
+
+// datingMatchPair orders two user IDs the way DatingMatch stores them
+func datingMatchPair(a, b uint) (uint, uint) {
+	if a > b {
+		return b, a
+	}
+	return a, b
+}
+
+// IsBlocked reports whether either user has blocked the other
+func (s *DatingMatchService) IsBlocked(a, b uint) (bool, error) {
+	return isBlockedBetween(s.db, a, b)
+}
+
+func isBlockedBetween(db *gorm.DB, a, b uint) (bool, error) {
+	var count int64
+	err := db.Model(&models.Block{}).
+		Where("(user_id = ? AND blocked_id = ?) OR (user_id = ? AND blocked_id = ?)", a, b, b, a).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// EnsureDirectMessageAllowed rejects a direct message when either user blocked the
+// other, which includes pairs that unmatched
+func EnsureDirectMessageAllowed(senderID, recipientID uint) error {
+	blocked, err := isBlockedBetween(database.DB, senderID, recipientID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrMessageBlocked
+	}
+	return nil
+}
+
+// DetectMatch is called after userID likes candidateID. When the like is reciprocal it
+// creates the match together with a private room for the pair. created is false when
+// there is no reciprocal like or the pair is already matched.
+func (s *DatingMatchService) DetectMatch(userID, candidateID uint) (*models.DatingMatch, bool, error) {
+	var match models.DatingMatch
+	created := false
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Both likes may land at the same time; locking the pair serializes detection
+		var locked []models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+			Where("id IN ?", []uint{userID, candidateID}).Order("id").Find(&locked).Error; err != nil {
+			return err
+		}
+
+		a, b := datingMatchPair(userID, candidateID)
+		err := tx.Where("user_a_id = ? AND user_b_id = ?", a, b).First(&match).Error
+		if err == nil && match.Status == models.DatingMatchActive {
+			return nil
+		}
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		var reciprocal int64
+		if err := tx.Model(&models.DatingFavorite{}).
+			Where("user_id = ? AND candidate_id = ?", candidateID, userID).
+			Count(&reciprocal).Error; err != nil {
+			return err
+		}
+		if reciprocal == 0 {
+			return nil
+		}
+		blocked, err := isBlockedBetween(tx, userID, candidateID)
+		if err != nil {
+			return err
+		}
+		if blocked {
+			return nil
+		}
+
+		var users []models.User
+		if err := tx.Select("id", "spiritual_name", "karmic_name").Where("id IN ?", []uint{a, b}).Order("id").Find(&users).Error; err != nil {
+			return err
+		}
+		if len(users) != 2 {
+			return gorm.ErrRecordNotFound
+		}
+
+		// The candidate liked first, so they own the room
+		room := models.Room{
+			Name:        fmt.Sprintf("%s & %s", datingDisplayName(users[0]), datingDisplayName(users[1])),
+			Description: "Совпадение в знакомствах",
+			OwnerID:     candidateID,
+			IsPublic:    false,
+		}
+		if err := tx.Create(&room).Error; err != nil {
+			return err
+		}
+		members := []models.RoomMember{
+			{RoomID: room.ID, UserID: candidateID, Role: models.RoomRoleOwner},
+			{RoomID: room.ID, UserID: userID, Role: models.RoomRoleAdmin},
+		}
+		if err := tx.Create(&members).Error; err != nil {
+			return err
+		}
+
+		// A pair that unmatched earlier and lifted the block reuses its row
+		match.UserAID, match.UserBID = a, b
+		match.RoomID = &room.ID
+		match.Status = models.DatingMatchActive
+		match.MatchedAt = time.Now().UTC()
+		match.UnmatchedByID, match.UnmatchedAt = nil, nil
+		if err := tx.Save(&match).Error; err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if match.ID == 0 || match.Status != models.DatingMatchActive {
+		return nil, false, nil
+	}
+	return &match, created, nil
+}
+
+// ListMatches returns the user's active matches, newest first
+func (s *DatingMatchService) ListMatches(userID uint) ([]DatingMatchView, error) {
+	var matches []models.DatingMatch
+	if err := s.db.Where("(user_a_id = ? OR user_b_id = ?) AND status = ?", userID, userID, models.DatingMatchActive).
+		Order("matched_at DESC").Find(&matches).Error; err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return []DatingMatchView{}, nil
+	}
+
+	partnerIDs := make([]uint, 0, len(matches))
+	for _, m := range matches {
+		partnerIDs = append(partnerIDs, m.PartnerID(userID))
+	}
+	var partners []models.User
+	if err := s.db.Preload("Photos").Where("id IN ?", partnerIDs).Find(&partners).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.User, len(partners))
+	for _, p := range partners {
+		p.Password = ""
+		byID[p.ID] = p
+	}
+
+	views := make([]DatingMatchView, 0, len(matches))
+	for _, m := range matches {
+		partner, ok := byID[m.PartnerID(userID)]
+		if !ok {
+			continue
+		}
+		views = append(views, DatingMatchView{DatingMatch: m, Partner: partner})
+	}
+	return views, nil
+}
+
+// Unmatch ends the match, closes its room and blocks the partner, so the pair cannot
+// like, match or message each other again until the block is lifted
+func (s *DatingMatchService) Unmatch(userID, matchID uint) (*models.DatingMatch, error) {
+	var match models.DatingMatch
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&match, matchID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrDatingMatchNotFound
+			}
+			return err
+		}
+		if !match.HasUser(userID) {
+			return ErrDatingMatchForbidden
+		}
+		if match.Status != models.DatingMatchActive {
+			return ErrDatingMatchNotFound
+		}
+		partnerID := match.PartnerID(userID)
+
+		now := time.Now().UTC()
+		match.Status = models.DatingMatchUnmatched
+		match.UnmatchedByID = &userID
+		match.UnmatchedAt = &now
+		if err := tx.Model(&match).Select("status", "unmatched_by_id", "unmatched_at").Updates(&match).Error; err != nil {
+			return err
+		}
+
+		var existing int64
+		if err := tx.Model(&models.Block{}).Where("user_id = ? AND blocked_id = ?", userID, partnerID).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing == 0 {
+			if err := tx.Create(&models.Block{UserID: userID, BlockedID: partnerID}).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("(user_id = ? AND candidate_id = ?) OR (user_id = ? AND candidate_id = ?)",
+			userID, partnerID, partnerID, userID).Delete(&models.DatingFavorite{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("(user_id = ? AND friend_id = ?) OR (user_id = ? AND friend_id = ?)",
+			userID, partnerID, partnerID, userID).Delete(&models.Friend{}).Error; err != nil {
+			return err
+		}
+
+		if match.RoomID != nil {
+			if err := tx.Where("room_id = ?", *match.RoomID).Delete(&models.RoomMember{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&models.Room{}, *match.RoomID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &match, nil
+}
+
+// NotifyMatch sends the match push to both users
+func (s *DatingMatchService) NotifyMatch(match *models.DatingMatch) {
+	var users []models.User
+	if err := s.db.Select("id", "spiritual_name", "karmic_name").Where("id IN ?", []uint{match.UserAID, match.UserBID}).Find(&users).Error; err != nil {
+		log.Printf("[Dating] Failed to load users for match %d: %v", match.ID, err)
+		return
+	}
+	names := make(map[uint]string, len(users))
+	for _, u := range users {
+		names[u.ID] = datingDisplayName(u)
+	}
+	roomID := uint(0)
+	if match.RoomID != nil {
+		roomID = *match.RoomID
+	}
+	for _, userID := range []uint{match.UserAID, match.UserBID} {
+		if err := GetPushService().SendDatingMatch(userID, match.ID, roomID, names[match.PartnerID(userID)]); err != nil {
+			log.Printf("[Dating] Failed to push match %d to user %d: %v", match.ID, userID, err)
+		}
+	}
+}
+
+func datingDisplayName(u models.User) string {
+	return firstNonEmpty(u.SpiritualName, u.KarmicName, fmt.Sprintf("User %d", u.ID))
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"rag-agent-server/internal/models"
+	"testing"
+)
+
+func TestDatingMatchPair(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		a, b         uint
+		wantA, wantB uint
+	}{
+		{a: 3, b: 7, wantA: 3, wantB: 7},
+		{a: 7, b: 3, wantA: 3, wantB: 7},
+		{a: 5, b: 5, wantA: 5, wantB: 5},
+	}
+	for _, tc := range tests {
+		gotA, gotB := datingMatchPair(tc.a, tc.b)
+		if gotA != tc.wantA || gotB != tc.wantB {
+			t.Fatalf("datingMatchPair(%d, %d) = %d, %d; want %d, %d", tc.a, tc.b, gotA, gotB, tc.wantA, tc.wantB)
+		}
+	}
+}
+
+func TestDatingMatchPartner(t *testing.T) {
+	t.Parallel()
+
+	match := models.DatingMatch{UserAID: 3, UserBID: 7}
+	if got := match.PartnerID(3); got != 7 {
+		t.Fatalf("PartnerID(3) = %d, want 7", got)
+	}
+	if got := match.PartnerID(7); got != 3 {
+		t.Fatalf("PartnerID(7) = %d, want 3", got)
+	}
+	if match.HasUser(5) || !match.HasUser(7) {
+		t.Fatalf("HasUser must only accept the two participants")
+	}
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"rag-agent-server/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	emailOutboxMaxAttempts  = 5
+	emailOutboxBaseBackoff  = time.Minute
+	emailOutboxMaxBackoff   = 6 * time.Hour
+	emailOutboxClaimLease   = 5 * time.Minute
+	emailOutboxSendTimeout  = 30 * time.Second
+	emailOutboxDefaultBatch = 100
+)
+
+// EmailOutboxService persists outbound emails and delivers them with retries
+type EmailOutboxService struct {
+	db     *gorm.DB
+	mailer Mailer
+}
+
+// NewEmailOutboxService creates a new outbox service
+func NewEmailOutboxService(db *gorm.DB, mailer Mailer) *EmailOutboxService {
+	return &EmailOutboxService{db: db, mailer: mailer}
+}
+
+// emailOutboxBackoff returns the delay before the next attempt (exponential, capped)
+func emailOutboxBackoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := emailOutboxBaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= emailOutboxMaxBackoff {
+			return emailOutboxMaxBackoff
+		}
+	}
+	return delay
+}
+
+// Enqueue stores messages in the outbox inside the given transaction
+func (s *EmailOutboxService) Enqueue(tx *gorm.DB, messages []models.EmailOutboxMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	now := time.Now()
+	for i := range messages {
+		messages[i].Status = models.EmailOutboxPending
+		messages[i].Attempts = 0
+		if messages[i].NextAttemptAt.IsZero() {
+			messages[i].NextAttemptAt = now
+		}
+	}
+	return tx.CreateInBatches(messages, 200).Error
+}
+
+// ProcessDue delivers pending messages whose next attempt is due.
+// Returns the number of messages successfully sent.
+func (s *EmailOutboxService) ProcessDue(ctx context.Context, batchSize int) (int, error) {
+	if s.mailer == nil {
+		return 0, errors.New("mailer is not configured")
+	}
+	if batchSize <= 0 {
+		batchSize = emailOutboxDefaultBatch
+	}
+
+	var due []models.EmailOutboxMessage
+	if err := s.db.Where("status = ? AND next_attempt_at <= ?", models.EmailOutboxPending, time.Now()).
+		Order("next_attempt_at ASC, id ASC").
+		Limit(batchSize).
+		Find(&due).Error; err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	touchedBroadcasts := make(map[uint]struct{})
+	for i := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		msg := &due[i]
+		if !s.claim(msg) {
+			continue
+		}
+		if s.deliver(ctx, msg) {
+			sent++
+		}
+		if msg.BroadcastID != nil {
+			touchedBroadcasts[*msg.BroadcastID] = struct{}{}
+		}
+	}
+
+	for broadcastID := range touchedBroadcasts {
+		if err := s.RefreshBroadcastStats(broadcastID); err != nil {
+			log.Printf("[EmailOutbox] Failed to refresh broadcast %d stats: %v", broadcastID, err)
+		}
+	}
+	return sent, nil
+}
+
+// claim pushes next_attempt_at forward so another instance does not pick the same row
+func (s *EmailOutboxService) claim(msg *models.EmailOutboxMessage) bool {
+	result := s.db.Model(&models.EmailOutboxMessage{}).
+		Where("id = ? AND status = ? AND next_attempt_at = ?", msg.ID, models.EmailOutboxPending, msg.NextAttemptAt).
+		Update("next_attempt_at", time.Now().Add(emailOutboxClaimLease))
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+func (s *EmailOutboxService) deliver(ctx context.Context, msg *models.EmailOutboxMessage) bool {
+	sendCtx, cancel := context.WithTimeout(ctx, emailOutboxSendTimeout)
+	err := s.mailer.Send(sendCtx, outboxToEmailMessage(msg))
+	cancel()
+
+	attempts := msg.Attempts + 1
+	now := time.Now()
+	if err == nil {
+		s.db.Model(&models.EmailOutboxMessage{}).Where("id = ?", msg.ID).Updates(map[string]interface{}{
+			"status":     models.EmailOutboxSent,
+			"attempts":   attempts,
+			"sent_at":    now,
+			"last_error": "",
+		})
+		return true
+	}
+
+	updates := map[string]interface{}{
+		"attempts":   attempts,
+		"last_error": err.Error(),
+	}
+	if attempts >= emailOutboxMaxAttempts {
+		updates["status"] = models.EmailOutboxFailed
+		log.Printf("[EmailOutbox] Giving up on message %d to %s after %d attempts: %v", msg.ID, msg.ToEmail, attempts, err)
+	} else {
+		updates["next_attempt_at"] = now.Add(emailOutboxBackoff(attempts))
+		log.Printf("[EmailOutbox] Attempt %d for message %d failed: %v", attempts, msg.ID, err)
+	}
+	s.db.Model(&models.EmailOutboxMessage{}).Where("id = ?", msg.ID).Updates(updates)
+	return false
+}
+
+func outboxToEmailMessage(msg *models.EmailOutboxMessage) EmailMessage {
+	return EmailMessage{To: msg.ToEmail, Subject: msg.Subject, Body: msg.Body}
+}
+
+// RefreshBroadcastStats recalculates per-status counters for a broadcast
+func (s *EmailOutboxService) RefreshBroadcastStats(broadcastID uint) error {
+	type statusCount struct {
+		Status models.EmailOutboxStatus
+		Count  int
+	}
+	var rows []statusCount
+	if err := s.db.Model(&models.EmailOutboxMessage{}).
+		Select("status, COUNT(*) as count").
+		Where("broadcast_id = ?", broadcastID).
+		Group("status").
+		Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	total, sent, failed := 0, 0, 0
+	for _, row := range rows {
+		total += row.Count
+		switch row.Status {
+		case models.EmailOutboxSent:
+			sent = row.Count
+		case models.EmailOutboxFailed:
+			failed = row.Count
+		}
+	}
+
+	updates := map[string]interface{}{
+		"total_count":  total,
+		"sent_count":   sent,
+		"failed_count": failed,
+	}
+	switch {
+	case sent+failed >= total:
+		updates["status"] = models.EmailBroadcastCompleted
+		updates["completed_at"] = time.Now()
+	case sent+failed > 0:
+		updates["status"] = models.EmailBroadcastSending
+	}
+	return s.db.Model(&models.EmailBroadcast{}).Where("id = ?", broadcastID).Updates(updates).Error
+}
+
+// ListBroadcasts returns recent broadcasts, newest first
+func (s *EmailOutboxService) ListBroadcasts(limit int) ([]models.EmailBroadcast, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	var broadcasts []models.EmailBroadcast
+	err := s.db.Order("id DESC").Limit(limit).Find(&broadcasts).Error
+	return broadcasts, err
+}
+
+// GetBroadcastMessages returns per-recipient delivery status for a broadcast
+func (s *EmailOutboxService) GetBroadcastMessages(broadcastID uint, status string) ([]models.EmailOutboxMessage, error) {
+	query := s.db.Where("broadcast_id = ?", broadcastID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var messages []models.EmailOutboxMessage
+	err := query.Order("id ASC").Find(&messages).Error
+	return messages, err
+}
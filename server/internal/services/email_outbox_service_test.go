@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	t.Parallel()
+
+	vars := map[string]string{
+		"organizerName": "Govinda das",
+		"date":          "01.02.2026",
+	}
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "simple substitution", in: "Hare Krishna, {{organizerName}}!", want: "Hare Krishna, Govinda das!"},
+		{name: "whitespace inside braces", in: "{{ organizerName }} / {{date}}", want: "Govinda das / 01.02.2026"},
+		{name: "unknown variable kept", in: "Reason: {{reason}}", want: "Reason: {{reason}}"},
+		{name: "no placeholders", in: "plain text", want: "plain text"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := RenderTemplate(tt.in, vars); got != tt.want {
+				t.Fatalf("RenderTemplate(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmailOutboxBackoff(t *testing.T) {
+	t.Parallel()
+
+	if got := emailOutboxBackoff(1); got != time.Minute {
+		t.Fatalf("attempt 1 backoff = %v, want 1m", got)
+	}
+	if got := emailOutboxBackoff(3); got != 4*time.Minute {
+		t.Fatalf("attempt 3 backoff = %v, want 4m", got)
+	}
+	if got := emailOutboxBackoff(50); got != emailOutboxMaxBackoff {
+		t.Fatalf("attempt 50 backoff = %v, want cap %v", got, emailOutboxMaxBackoff)
+	}
+}
+
+func TestFileMailerWritesEML(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	mailer := &FileMailer{Dir: dir, From: "VedaMatch <no-reply@vedamatch.ru>"}
+
+	err := mailer.Send(context.Background(), EmailMessage{
+		To:      "organizer@example.com\r\nBcc: evil@example.com",
+		Subject: "Новости ятр",
+		Body:    "Hare Krishna",
+	})
+	if err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected exactly one .eml file, got %v (err=%v)", files, err)
+	}
+	raw, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("read eml: %v", err)
+	}
+	content := string(raw)
+	if strings.Contains(content, "\r\nBcc:") {
+		t.Fatalf("header injection was not sanitized:\n%s", content)
+	}
+	if !strings.Contains(content, "Subject: =?utf-8?q?") {
+		t.Fatalf("non-ASCII subject should be Q-encoded:\n%s", content)
+	}
+}
+
+func TestNewMailerFromEnv(t *testing.T) {
+	tests := []struct {
+		name   string
+		driver string
+		host   string
+		want   string
+	}{
+		{name: "smtp host without driver", host: "smtp.example.com", want: "smtp"},
+		{name: "explicit file driver", driver: "file", want: "file"},
+		{name: "nothing configured", want: "unconfigured"},
+		{name: "smtp driver without host", driver: "smtp", want: "unconfigured"},
+		{name: "unknown driver", driver: "sendmail", want: "unconfigured"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("MAIL_DRIVER", tt.driver)
+			t.Setenv("SMTP_HOST", tt.host)
+			t.Setenv("MAIL_FILE_DIR", t.TempDir())
+
+			mailer := NewMailerFromEnv()
+			var got string
+			switch mailer.(type) {
+			case *SMTPMailer:
+				got = "smtp"
+			case *FileMailer:
+				got = "file"
+			case unconfiguredMailer:
+				got = "unconfigured"
+			}
+			if got != tt.want {
+				t.Fatalf("NewMailerFromEnv() = %T, want %s", mailer, tt.want)
+			}
+			if got == "unconfigured" {
+				if err := mailer.Send(context.Background(), EmailMessage{To: "a@example.com"}); !errors.Is(err, ErrMailerNotConfigured) {
+					t.Fatalf("Send() error = %v, want ErrMailerNotConfigured", err)
+				}
+			}
+		})
+	}
+}
+
+func TestEnvelopeAddress(t *testing.T) {
+	t.Parallel()
+
+	if got := envelopeAddress("VedaMatch <no-reply@vedamatch.ru>"); got != "no-reply@vedamatch.ru" {
+		t.Fatalf("envelopeAddress = %q", got)
+	}
+	if got := envelopeAddress(" plain@vedamatch.ru "); got != "plain@vedamatch.ru" {
+		t.Fatalf("envelopeAddress = %q", got)
+	}
+}
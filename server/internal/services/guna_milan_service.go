@@ -0,0 +1,604 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"rag-agent-server/internal/database"
+	"rag-agent-server/internal/models"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrBirthDataMissing = errors.New("date of birth is required for a compatibility chart")
+
+// gunaMilanVersion is part of the cache key; bump it when the calculation changes
+const gunaMilanVersion = "1"
+
+var (
+	nakshatraNames = []string{
+		"Ashwini", "Bharani", "Krittika", "Rohini", "Mrigashira", "Ardra", "Punarvasu", "Pushya", "Ashlesha",
+		"Magha", "Purva Phalguni", "Uttara Phalguni", "Hasta", "Chitra", "Swati", "Vishakha", "Anuradha", "Jyeshtha",
+		"Mula", "Purva Ashadha", "Uttara Ashadha", "Shravana", "Dhanishta", "Shatabhisha", "Purva Bhadrapada", "Uttara Bhadrapada", "Revati",
+	}
+	rashiNames = []string{
+		"Mesha", "Vrishabha", "Mithuna", "Karka", "Simha", "Kanya",
+		"Tula", "Vrishchika", "Dhanu", "Makara", "Kumbha", "Meena",
+	}
+)
+
+// Grahas used as rashi lords
+const (
+	grahaSun = iota
+	grahaMoon
+	grahaMars
+	grahaMercury
+	grahaJupiter
+	grahaVenus
+	grahaSaturn
+)
+
+var grahaNames = []string{"Sun", "Moon", "Mars", "Mercury", "Jupiter", "Venus", "Saturn"}
+
+var rashiLords = []int{grahaMars, grahaVenus, grahaMercury, grahaMoon, grahaSun, grahaMercury, grahaVenus, grahaMars, grahaJupiter, grahaSaturn, grahaSaturn, grahaJupiter}
+
+// Natural relationship of graha (row) towards graha (column): 1 friend, 0 neutral, -1 enemy
+var grahaRelations = [7][7]int{
+	grahaSun:     {0, 1, 1, 0, 1, -1, -1},
+	grahaMoon:    {1, 0, 0, 1, 0, 0, 0},
+	grahaMars:    {1, 1, 0, -1, 1, 0, 0},
+	grahaMercury: {1, -1, 0, 0, 0, 1, 0},
+	grahaJupiter: {1, 1, 1, -1, 0, -1, 0},
+	grahaVenus:   {-1, -1, 0, 1, 0, 0, 1},
+	grahaSaturn:  {-1, -1, -1, 1, 0, 1, 0},
+}
+
+var varnaNames = []string{"Shudra", "Vaishya", "Kshatriya", "Brahmin"}
+
+// Varna rank of each Moon rashi, by element: fire Kshatriya, earth Vaishya, air Shudra, water Brahmin
+var rashiVarna = []int{2, 1, 0, 3, 2, 1, 0, 3, 2, 1, 0, 3}
+
+const (
+	vashyaChatushpada = iota
+	vashyaManava
+	vashyaJalachara
+	vashyaVanachara
+	vashyaKeeta
+)
+
+var vashyaNames = []string{"Chatushpada", "Manava", "Jalachara", "Vanachara", "Keeta"}
+
+// Vashya points, groom group (row) against bride group (column)
+var vashyaPoints = [5][5]float64{
+	{2, 1, 1, 0.5, 1},
+	{1, 2, 0.5, 0, 1},
+	{1, 0.5, 2, 1, 1},
+	{0, 0, 0, 2, 0},
+	{1, 1, 1, 0, 2},
+}
+
+var yoniNames = []string{"Horse", "Elephant", "Sheep", "Serpent", "Dog", "Cat", "Rat", "Cow", "Buffalo", "Tiger", "Deer", "Monkey", "Mongoose", "Lion"}
+
+// Yoni animal of each nakshatra (index into yoniNames)
+var nakshatraYoni = []int{0, 1, 2, 3, 3, 4, 5, 2, 5, 6, 6, 7, 8, 9, 8, 9, 10, 10, 4, 11, 12, 11, 13, 0, 13, 7, 1}
+
+// Yoni points; sworn enemies (Horse-Buffalo, Elephant-Lion, Sheep-Monkey, Serpent-Mongoose,
+// Dog-Deer, Cat-Rat, Cow-Tiger) score 0
+var yoniPoints = [14][14]float64{
+	{4, 2, 2, 3, 2, 2, 2, 1, 0, 1, 3, 3, 2, 1},
+	{2, 4, 3, 3, 2, 2, 2, 2, 3, 1, 2, 3, 2, 0},
+	{2, 3, 4, 2, 1, 2, 1, 3, 3, 1, 2, 0, 3, 1},
+	{3, 3, 2, 4, 2, 1, 1, 1, 1, 2, 2, 2, 0, 2},
+	{2, 2, 1, 2, 4, 2, 1, 2, 2, 1, 0, 2, 1, 1},
+	{2, 2, 2, 1, 2, 4, 0, 2, 2, 1, 3, 3, 2, 1},
+	{2, 2, 1, 1, 1, 0, 4, 2, 2, 2, 2, 2, 1, 2},
+	{1, 2, 3, 1, 2, 2, 2, 4, 3, 0, 3, 2, 2, 1},
+	{0, 3, 3, 1, 2, 2, 2, 3, 4, 1, 2, 2, 2, 1},
+	{1, 1, 1, 2, 1, 1, 2, 0, 1, 4, 1, 1, 2, 1},
+	{3, 2, 2, 2, 0, 3, 2, 3, 2, 1, 4, 2, 2, 1},
+	{3, 3, 0, 2, 2, 3, 2, 2, 2, 1, 2, 4, 3, 2},
+	{2, 2, 3, 0, 1, 2, 1, 2, 2, 2, 2, 3, 4, 2},
+	{1, 0, 1, 2, 1, 1, 2, 1, 1, 1, 1, 2, 2, 4},
+}
+
+var ganaNames = []string{"Deva", "Manushya", "Rakshasa"}
+
+var nakshatraGana = []int{0, 1, 2, 1, 0, 1, 0, 0, 2, 2, 1, 1, 0, 2, 0, 2, 0, 2, 2, 1, 1, 0, 2, 2, 1, 1, 0}
+
+// Gana points, groom gana (row) against bride gana (column)
+var ganaPoints = [3][3]float64{
+	{6, 5, 1},
+	{6, 6, 0},
+	{0, 0, 6},
+}
+
+var nadiNames = []string{"Adi", "Madhya", "Antya"}
+
+// Mars in these houses (whole-sign, counted from the Lagna or the Moon) gives Mangal dosha
+var manglikHouses = map[int]bool{1: true, 2: true, 4: true, 7: true, 8: true, 12: true}
+
+type GunaMilanService struct {
+	db *gorm.DB
+}
+
+func NewGunaMilanService() *GunaMilanService {
+	return &GunaMilanService{db: database.DB}
+}
+
+// BirthProfile is the normalized birth data of one person
+type BirthProfile struct {
+	Birth      time.Time // Instant of birth
+	TimeKnown  bool      // false: local noon was assumed
+	Latitude   float64
+	Longitude  float64
+	PlaceKnown bool
+	Female     bool
+}
+
+type BirthChart struct {
+	MoonLongitude      float64 `json:"moonLongitude"` // Sidereal, Lahiri
+	Nakshatra          string  `json:"nakshatra"`
+	NakshatraIndex     int     `json:"nakshatraIndex"`
+	Pada               int     `json:"pada"`
+	Rashi              string  `json:"rashi"`
+	RashiIndex         int     `json:"rashiIndex"`
+	RashiLord          string  `json:"rashiLord"`
+	Lagna              string  `json:"lagna,omitempty"` // Empty when birth time or place is unknown
+	MarsRashi          string  `json:"marsRashi"`
+	MarsHouseFromLagna int     `json:"marsHouseFromLagna,omitempty"`
+	MarsHouseFromMoon  int     `json:"marsHouseFromMoon"`
+	Manglik            bool    `json:"manglik"`
+	TimeKnown          bool    `json:"timeKnown"`
+	PlaceKnown         bool    `json:"placeKnown"`
+	MoonBoundary       bool    `json:"moonBoundary"` // Moon near a nakshatra edge while the birth time is unknown
+}
+
+type AshtakootaScore struct {
+	Koota          string  `json:"koota"`
+	Score          float64 `json:"score"`
+	Max            float64 `json:"max"`
+	UserValue      string  `json:"userValue"`
+	CandidateValue string  `json:"candidateValue"`
+}
+
+type ManglikMatch struct {
+	User       bool `json:"user"`
+	Candidate  bool `json:"candidate"`
+	Compatible bool `json:"compatible"` // Neither or both partners are manglik
+}
+
+type GunaMilanResult struct {
+	User        BirthChart        `json:"user"`
+	Candidate   BirthChart        `json:"candidate"`
+	BrideIsUser bool              `json:"brideIsUser"`
+	Kootas      []AshtakootaScore `json:"kootas"`
+	Total       float64           `json:"total"`
+	Max         float64           `json:"max"`
+	Verdict     string            `json:"verdict"` // low, average, good, excellent
+	Manglik     ManglikMatch      `json:"manglik"`
+	ComputedAt  time.Time         `json:"computedAt"`
+}
+
+// ==================== Birth data ====================
+
+var coordinatePattern = regexp.MustCompile(`(-?\d{1,2}\.\d+)\s*,\s*(-?\d{1,3}\.\d+)`)
+
+// BirthProfileFromUser reads Dob, BirthTime and birth coordinates. Coordinates fall back to
+// ones embedded in BirthPlaceLink, then to the current location. The birth time is local to
+// the birthplace: User.Timezone is used when it fits the birth longitude, otherwise the
+// offset is the mean solar time of the longitude rounded to half an hour.
+func BirthProfileFromUser(u models.User) (BirthProfile, error) {
+	profile := BirthProfile{Female: isFemaleGender(u.Gender)}
+
+	dob := strings.TrimSpace(u.Dob)
+	if len(dob) < 10 {
+		return profile, ErrBirthDataMissing
+	}
+	date, err := time.Parse("2006-01-02", dob[:10])
+	if err != nil {
+		return profile, ErrBirthDataMissing
+	}
+
+	hour, minute := 12, 0
+	if h, m, ok := parseBirthTime(u.BirthTime); ok {
+		hour, minute = h, m
+		profile.TimeKnown = true
+	}
+
+	switch {
+	case u.BirthLatitude != nil && u.BirthLongitude != nil:
+		profile.Latitude, profile.Longitude, profile.PlaceKnown = *u.BirthLatitude, *u.BirthLongitude, true
+	default:
+		if match := coordinatePattern.FindStringSubmatch(u.BirthPlaceLink); match != nil {
+			lat, _ := strconv.ParseFloat(match[1], 64)
+			lon, _ := strconv.ParseFloat(match[2], 64)
+			if math.Abs(lat) <= 90 && math.Abs(lon) <= 180 {
+				profile.Latitude, profile.Longitude, profile.PlaceKnown = lat, lon, true
+			}
+		}
+		if !profile.PlaceKnown && u.Latitude != nil && u.Longitude != nil {
+			profile.Latitude, profile.Longitude = *u.Latitude, *u.Longitude
+		}
+	}
+
+	loc := birthLocation(u.Timezone, profile.Longitude, profile.PlaceKnown || u.Longitude != nil, date)
+	profile.Birth = time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, loc)
+	return profile, nil
+}
+
+func parseBirthTime(raw string) (int, int, bool) {
+	parts := strings.Split(strings.TrimSpace(raw), ":")
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+	h, err1 := strconv.Atoi(strings.TrimSpace(parts[0]))
+	m, err2 := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err1 != nil || err2 != nil || h < 0 || h > 23 || m < 0 || m > 59 {
+		return 0, 0, false
+	}
+	return h, m, true
+}
+
+func birthLocation(timezone string, longitude float64, longitudeKnown bool, date time.Time) *time.Location {
+	solarOffset := math.Round(longitude/15*2) / 2
+	if tz := strings.TrimSpace(timezone); tz != "" {
+		if loc, err := time.LoadLocation(tz); err == nil {
+			_, offset := time.Date(date.Year(), date.Month(), date.Day(), 12, 0, 0, 0, loc).Zone()
+			if !longitudeKnown || math.Abs(float64(offset)/3600-solarOffset) <= 1.5 {
+				return loc
+			}
+		}
+	}
+	if longitudeKnown {
+		return time.FixedZone("LMT", int(solarOffset*3600))
+	}
+	return userLocation("")
+}
+
+func isFemaleGender(gender string) bool {
+	switch strings.ToLower(strings.TrimSpace(gender)) {
+	case "female", "f", "woman", "женский", "женщина", "ж":
+		return true
+	default:
+		return false
+	}
+}
+
+// birthFingerprint changes whenever any input of the chart changes
+func birthFingerprint(u models.User) string {
+	coord := func(v *float64) string {
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*v, 'f', 4, 64)
+	}
+	return strings.Join([]string{
+		strings.TrimSpace(u.Dob), strings.TrimSpace(u.BirthTime), strings.TrimSpace(u.BirthPlaceLink),
+		coord(u.BirthLatitude), coord(u.BirthLongitude), coord(u.Latitude), coord(u.Longitude),
+		strings.TrimSpace(u.Timezone), strings.ToLower(strings.TrimSpace(u.Gender)),
+	}, "|")
+}
+
+// PairBirthHash identifies the birth data of both users; cached results with another hash are stale
+func PairBirthHash(user, candidate models.User) string {
+	sum := sha256.Sum256([]byte(gunaMilanVersion + "\n" + birthFingerprint(user) + "\n" + birthFingerprint(candidate)))
+	return hex.EncodeToString(sum[:])
+}
+
+// ==================== Chart and kootas ====================
+
+func buildBirthChart(p BirthProfile) BirthChart {
+	jd := julianDay(p.Birth)
+	moon := siderealLongitude(moonTropicalLongitude(jd), jd)
+	mars := siderealLongitude(marsTropicalLongitude(jd), jd)
+
+	nak := int(moon / nakshatraSpan)
+	rashi := int(moon / 30)
+	marsRashi := int(mars / 30)
+	chart := BirthChart{
+		MoonLongitude:     math.Round(moon*1e4) / 1e4,
+		Nakshatra:         nakshatraNames[nak],
+		NakshatraIndex:    nak,
+		Pada:              int(math.Mod(moon, nakshatraSpan)/padaSpan) + 1,
+		Rashi:             rashiNames[rashi],
+		RashiIndex:        rashi,
+		RashiLord:         grahaNames[rashiLords[rashi]],
+		MarsRashi:         rashiNames[marsRashi],
+		MarsHouseFromMoon: houseFrom(rashi, marsRashi),
+		TimeKnown:         p.TimeKnown,
+		PlaceKnown:        p.PlaceKnown,
+	}
+	chart.Manglik = manglikHouses[chart.MarsHouseFromMoon]
+
+	if p.TimeKnown && p.PlaceKnown {
+		lagna := int(siderealLongitude(ascendantTropicalLongitude(jd, p.Latitude, p.Longitude), jd) / 30)
+		chart.Lagna = rashiNames[lagna]
+		chart.MarsHouseFromLagna = houseFrom(lagna, marsRashi)
+		chart.Manglik = manglikHouses[chart.MarsHouseFromLagna]
+	}
+	if !p.TimeKnown {
+		// The Moon moves up to ~7.5° in half a day
+		offset := math.Mod(moon, nakshatraSpan)
+		chart.MoonBoundary = offset < 7.5 || nakshatraSpan-offset < 7.5
+	}
+	return chart
+}
+
+// houseFrom counts whole-sign houses from one rashi to another (same rashi is the 1st house)
+func houseFrom(from, to int) int {
+	return (to-from+12)%12 + 1
+}
+
+func nakshatraNadi(nak int) int {
+	return []int{0, 1, 2, 2, 1, 0}[nak%6]
+}
+
+func rashiVashya(chart BirthChart) int {
+	degree := math.Mod(chart.MoonLongitude, 30)
+	switch chart.RashiIndex {
+	case 0, 1:
+		return vashyaChatushpada
+	case 3, 11:
+		return vashyaJalachara
+	case 4:
+		return vashyaVanachara
+	case 7:
+		return vashyaKeeta
+	case 8:
+		if degree < 15 {
+			return vashyaManava
+		}
+		return vashyaChatushpada
+	case 9:
+		if degree < 15 {
+			return vashyaChatushpada
+		}
+		return vashyaJalachara
+	default:
+		return vashyaManava
+	}
+}
+
+// taraPoints gives 1.5 for each direction in which the count of nakshatras lands on an
+// auspicious tara (anything but Vipat 3, Pratyak 5 and Naidhana 7)
+func taraPoints(groomNak, brideNak int) float64 {
+	good := func(from, to int) bool {
+		tara := ((to-from+27)%27)%9 + 1
+		return tara != 3 && tara != 5 && tara != 7
+	}
+	score := 0.0
+	if good(brideNak, groomNak) {
+		score += 1.5
+	}
+	if good(groomNak, brideNak) {
+		score += 1.5
+	}
+	return score
+}
+
+func grahaMaitriPoints(groomLord, brideLord int) float64 {
+	if groomLord == brideLord {
+		return 5
+	}
+	a, b := grahaRelations[groomLord][brideLord], grahaRelations[brideLord][groomLord]
+	switch {
+	case a == 1 && b == 1:
+		return 5
+	case a+b == 1: // friend and neutral
+		return 4
+	case a == 0 && b == 0:
+		return 3
+	case a+b == 0: // friend and enemy
+		return 1
+	case a+b == -1: // neutral and enemy
+		return 0.5
+	default:
+		return 0
+	}
+}
+
+// bhakootPoints rejects the 2/12, 5/9 and 6/8 placements of the Moon signs
+func bhakootPoints(groomRashi, brideRashi int) float64 {
+	switch houseFrom(brideRashi, groomRashi) {
+	case 2, 12, 5, 9, 6, 8:
+		return 0
+	default:
+		return 7
+	}
+}
+
+func gunaMilanVerdict(total float64) string {
+	switch {
+	case total >= 33:
+		return "excellent"
+	case total >= 25:
+		return "good"
+	case total >= 18:
+		return "average"
+	default:
+		return "low"
+	}
+}
+
+// ComputeGunaMilan scores the 36-point Ashtakoota match. The groom/bride roles the kootas
+// need come from the genders; for other combinations the user takes the groom's side.
+func ComputeGunaMilan(user, candidate BirthProfile) *GunaMilanResult {
+	uc, cc := buildBirthChart(user), buildBirthChart(candidate)
+	brideIsUser := user.Female && !candidate.Female
+	groom, bride := uc, cc
+	if brideIsUser {
+		groom, bride = cc, uc
+	}
+	// Values are reported from the user's and candidate's side regardless of the roles
+	pick := func(names []string, userIdx, candidateIdx int) (string, string) {
+		return names[userIdx], names[candidateIdx]
+	}
+	add := func(result *GunaMilanResult, koota string, score, max float64, userValue, candidateValue string) {
+		result.Kootas = append(result.Kootas, AshtakootaScore{Koota: koota, Score: score, Max: max, UserValue: userValue, CandidateValue: candidateValue})
+		result.Total += score
+		result.Max += max
+	}
+
+	result := &GunaMilanResult{User: uc, Candidate: cc, BrideIsUser: brideIsUser, ComputedAt: time.Now().UTC()}
+
+	varna := 0.0
+	if rashiVarna[groom.RashiIndex] >= rashiVarna[bride.RashiIndex] {
+		varna = 1
+	}
+	uv, cv := pick(varnaNames, rashiVarna[uc.RashiIndex], rashiVarna[cc.RashiIndex])
+	add(result, "varna", varna, 1, uv, cv)
+
+	uv, cv = pick(vashyaNames, rashiVashya(uc), rashiVashya(cc))
+	add(result, "vashya", vashyaPoints[rashiVashya(groom)][rashiVashya(bride)], 2, uv, cv)
+
+	add(result, "tara", taraPoints(groom.NakshatraIndex, bride.NakshatraIndex), 3, uc.Nakshatra, cc.Nakshatra)
+
+	uv, cv = pick(yoniNames, nakshatraYoni[uc.NakshatraIndex], nakshatraYoni[cc.NakshatraIndex])
+	add(result, "yoni", yoniPoints[nakshatraYoni[groom.NakshatraIndex]][nakshatraYoni[bride.NakshatraIndex]], 4, uv, cv)
+
+	add(result, "graha_maitri", grahaMaitriPoints(rashiLords[groom.RashiIndex], rashiLords[bride.RashiIndex]), 5, uc.RashiLord, cc.RashiLord)
+
+	uv, cv = pick(ganaNames, nakshatraGana[uc.NakshatraIndex], nakshatraGana[cc.NakshatraIndex])
+	add(result, "gana", ganaPoints[nakshatraGana[groom.NakshatraIndex]][nakshatraGana[bride.NakshatraIndex]], 6, uv, cv)
+
+	add(result, "bhakoot", bhakootPoints(groom.RashiIndex, bride.RashiIndex), 7, uc.Rashi, cc.Rashi)
+
+	nadi := 8.0
+	if nakshatraNadi(uc.NakshatraIndex) == nakshatraNadi(cc.NakshatraIndex) {
+		nadi = 0
+	}
+	uv, cv = pick(nadiNames, nakshatraNadi(uc.NakshatraIndex), nakshatraNadi(cc.NakshatraIndex))
+	add(result, "nadi", nadi, 8, uv, cv)
+
+	result.Verdict = gunaMilanVerdict(result.Total)
+	result.Manglik = ManglikMatch{User: uc.Manglik, Candidate: cc.Manglik, Compatible: uc.Manglik == cc.Manglik}
+	return result
+}
+
+// ==================== Cache ====================
+
+// Compute returns the Ashtakoota result for the pair, reusing the cached one while the
+// birth data of both users is unchanged
+func (s *GunaMilanService) Compute(user, candidate models.User) (*GunaMilanResult, error) {
+	hash := PairBirthHash(user, candidate)
+
+	var cached models.DatingGunaMilan
+	err := s.db.Where("user_id = ? AND candidate_id = ? AND birth_hash = ?", user.ID, candidate.ID, hash).First(&cached).Error
+	if err == nil {
+		var result GunaMilanResult
+		if jsonErr := json.Unmarshal([]byte(cached.Result), &result); jsonErr == nil {
+			return &result, nil
+		}
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	userProfile, err := BirthProfileFromUser(user)
+	if err != nil {
+		return nil, fmt.Errorf("user: %w", err)
+	}
+	candidateProfile, err := BirthProfileFromUser(candidate)
+	if err != nil {
+		return nil, fmt.Errorf("candidate: %w", err)
+	}
+	result := ComputeGunaMilan(userProfile, candidateProfile)
+
+	payload, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	row := models.DatingGunaMilan{
+		UserID:      user.ID,
+		CandidateID: candidate.ID,
+		BirthHash:   hash,
+		Total:       result.Total,
+		Result:      string(payload),
+	}
+	if err := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "candidate_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"birth_hash", "total", "result", "updated_at"}),
+	}).Create(&row).Error; err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// InvalidateUser drops cached charts and AI explanations involving the user after their birth data changed
+func (s *GunaMilanService) InvalidateUser(userID uint) error {
+	if err := s.db.Where("user_id = ? OR candidate_id = ?", userID, userID).Delete(&models.DatingGunaMilan{}).Error; err != nil {
+		return err
+	}
+	return s.db.Where("user_id = ? OR candidate_id = ?", userID, userID).Delete(&models.DatingCompatibility{}).Error
+}
+
+// FormatGunaMilanSummary renders the result as plain Russian text, used when the AI
+// explanation is unavailable
+func FormatGunaMilanSummary(r *GunaMilanResult) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "Ашта-кута: %s из %s баллов (%s).\n\n", formatPoints(r.Total), formatPoints(r.Max), gunaVerdictRu(r.Verdict))
+	fmt.Fprintf(&out, "Ваша луна: %s, накшатра %s (пада %d). Луна партнёра: %s, накшатра %s (пада %d).\n\n",
+		r.User.Rashi, r.User.Nakshatra, r.User.Pada, r.Candidate.Rashi, r.Candidate.Nakshatra, r.Candidate.Pada)
+	for _, k := range r.Kootas {
+		fmt.Fprintf(&out, "• %s: %s/%s (%s — %s)\n", gunaKootaRu(k.Koota), formatPoints(k.Score), formatPoints(k.Max), k.UserValue, k.CandidateValue)
+	}
+	switch {
+	case r.Manglik.User && r.Manglik.Candidate:
+		out.WriteString("\nМангал-доша есть у обоих партнёров и взаимно нейтрализуется.")
+	case r.Manglik.User || r.Manglik.Candidate:
+		out.WriteString("\nМангал-доша есть только у одного из партнёров — стоит обсудить это с астрологом.")
+	default:
+		out.WriteString("\nМангал-доши нет ни у одного из партнёров.")
+	}
+	if !r.User.TimeKnown || !r.Candidate.TimeKnown {
+		out.WriteString("\nВремя рождения указано не у обоих партнёров, поэтому расчёт приблизительный.")
+	}
+	return out.String()
+}
+
+func formatPoints(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func gunaVerdictRu(verdict string) string {
+	switch verdict {
+	case "excellent":
+		return "превосходная совместимость"
+	case "good":
+		return "хорошая совместимость"
+	case "average":
+		return "средняя совместимость"
+	default:
+		return "низкая совместимость"
+	}
+}
+
+func gunaKootaRu(koota string) string {
+	switch koota {
+	case "varna":
+		return "Варна"
+	case "vashya":
+		return "Вашья"
+	case "tara":
+		return "Тара"
+	case "yoni":
+		return "Йони"
+	case "graha_maitri":
+		return "Граха-майтри"
+	case "gana":
+		return "Гана"
+	case "bhakoot":
+		return "Бхакут"
+	case "nadi":
+		return "Нади"
+	default:
+		return koota
+	}
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"math"
+	"rag-agent-server/internal/models"
+	"testing"
+	"time"
+)
+
+func TestMoonTropicalLongitudeMeeusExample(t *testing.T) {
+	t.Parallel()
+
+	// Meeus, example 47.a: 1992 April 12, 0h TD; λ = 133.162655° including 0.004610° of nutation
+	got := moonTropicalLongitude(2448724.5)
+	if math.Abs(got-133.158045) > 0.01 {
+		t.Fatalf("moonTropicalLongitude = %.6f, want ~133.158045", got)
+	}
+}
+
+func TestAshtakootaTables(t *testing.T) {
+	t.Parallel()
+
+	for a := range yoniPoints {
+		for b := range yoniPoints {
+			if yoniPoints[a][b] != yoniPoints[b][a] {
+				t.Fatalf("yoni table is not symmetric at %s/%s", yoniNames[a], yoniNames[b])
+			}
+		}
+	}
+	enemies := [][2]string{{"Horse", "Buffalo"}, {"Elephant", "Lion"}, {"Sheep", "Monkey"}, {"Serpent", "Mongoose"}, {"Dog", "Deer"}, {"Cat", "Rat"}, {"Cow", "Tiger"}}
+	index := func(name string) int {
+		for i, n := range yoniNames {
+			if n == name {
+				return i
+			}
+		}
+		t.Fatalf("unknown yoni %s", name)
+		return -1
+	}
+	for _, pair := range enemies {
+		if got := yoniPoints[index(pair[0])][index(pair[1])]; got != 0 {
+			t.Fatalf("yoni %s/%s = %v, want 0", pair[0], pair[1], got)
+		}
+	}
+
+	if len(nakshatraYoni) != 27 || len(nakshatraGana) != 27 {
+		t.Fatalf("nakshatra tables must cover 27 nakshatras")
+	}
+	// Ashwini Adi, Bharani Madhya, Krittika Antya, Rohini Antya, Mrigashira Madhya, Ardra Adi
+	for nak, want := range []int{0, 1, 2, 2, 1, 0, 0, 1, 2} {
+		if got := nakshatraNadi(nak); got != want {
+			t.Fatalf("nakshatraNadi(%s) = %d, want %d", nakshatraNames[nak], got, want)
+		}
+	}
+}
+
+func TestKootaPoints(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{name: "bhakoot same rashi", got: bhakootPoints(0, 0), want: 7},
+		{name: "bhakoot 2/12", got: bhakootPoints(1, 0), want: 0},
+		{name: "bhakoot 6/8", got: bhakootPoints(5, 0), want: 0},
+		{name: "bhakoot 3/11", got: bhakootPoints(2, 0), want: 7},
+		{name: "bhakoot 7/7", got: bhakootPoints(6, 0), want: 7},
+		{name: "maitri same lord", got: grahaMaitriPoints(grahaMars, grahaMars), want: 5},
+		{name: "maitri mutual friends", got: grahaMaitriPoints(grahaSun, grahaMoon), want: 5},
+		{name: "maitri mutual enemies", got: grahaMaitriPoints(grahaSun, grahaVenus), want: 0},
+		{name: "maitri friend and enemy", got: grahaMaitriPoints(grahaMoon, grahaMercury), want: 1},
+		{name: "tara same nakshatra", got: taraPoints(4, 4), want: 3},
+		{name: "tara vipat both ways", got: taraPoints(0, 2), want: 1.5},
+	}
+	for _, tc := range tests {
+		if tc.got != tc.want {
+			t.Fatalf("%s = %v, want %v", tc.name, tc.got, tc.want)
+		}
+	}
+}
+
+func TestComputeGunaMilan(t *testing.T) {
+	t.Parallel()
+
+	lat, lon := 55.7558, 37.6173
+	groom, err := BirthProfileFromUser(models.User{Dob: "1990-05-17", BirthTime: "08:30", Gender: "Male", Timezone: "Europe/Moscow", BirthLatitude: &lat, BirthLongitude: &lon})
+	if err != nil {
+		t.Fatalf("groom profile: %v", err)
+	}
+	bride, err := BirthProfileFromUser(models.User{Dob: "1992-11-03T00:00:00Z", Gender: "Female"})
+	if err != nil {
+		t.Fatalf("bride profile: %v", err)
+	}
+	if bride.TimeKnown || bride.PlaceKnown {
+		t.Fatalf("bride without birth time or place must be marked as estimated")
+	}
+
+	result := ComputeGunaMilan(bride, groom)
+	if !result.BrideIsUser {
+		t.Fatalf("female user must take the bride's side")
+	}
+	if result.Max != 36 || len(result.Kootas) != 8 {
+		t.Fatalf("unexpected koota set: max %v, %d kootas", result.Max, len(result.Kootas))
+	}
+	sum := 0.0
+	for _, k := range result.Kootas {
+		if k.Score < 0 || k.Score > k.Max {
+			t.Fatalf("koota %s out of range: %v/%v", k.Koota, k.Score, k.Max)
+		}
+		sum += k.Score
+	}
+	if sum != result.Total || result.Verdict != gunaMilanVerdict(sum) {
+		t.Fatalf("total %v / verdict %s do not match kootas (%v)", result.Total, result.Verdict, sum)
+	}
+	if result.Candidate.Lagna == "" || result.User.Lagna != "" {
+		t.Fatalf("lagna must be computed only with a known birth time and place")
+	}
+
+	again := ComputeGunaMilan(bride, groom)
+	if again.Total != result.Total || again.User.Nakshatra != result.User.Nakshatra {
+		t.Fatalf("result must be deterministic")
+	}
+}
+
+func TestBirthProfileFromUser(t *testing.T) {
+	t.Parallel()
+
+	if _, err := BirthProfileFromUser(models.User{Dob: ""}); err != ErrBirthDataMissing {
+		t.Fatalf("empty dob: got %v, want ErrBirthDataMissing", err)
+	}
+
+	profile, err := BirthProfileFromUser(models.User{Dob: "1985-01-20", BirthTime: "23:15", BirthPlaceLink: "https://maps.example/?q=28.6139,77.2090", Timezone: "Europe/Moscow"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !profile.PlaceKnown || math.Abs(profile.Longitude-77.2090) > 1e-9 {
+		t.Fatalf("coordinates must be read from the birth place link, got %+v", profile)
+	}
+	// Moscow time does not fit a birth in Delhi, so the longitude offset (+5:00) is used
+	if _, offset := profile.Birth.Zone(); offset != 5*3600 {
+		t.Fatalf("birth offset = %d, want %d", offset, 5*3600)
+	}
+	if want := time.Date(1985, 1, 20, 18, 15, 0, 0, time.UTC); !profile.Birth.Equal(want) {
+		t.Fatalf("birth instant = %s, want %s", profile.Birth.UTC(), want)
+	}
+
+	a := models.User{Dob: "1990-01-01", BirthTime: "10:00"}
+	b := a
+	b.BirthTime = "10:05"
+	if PairBirthHash(a, models.User{}) == PairBirthHash(b, models.User{}) {
+		t.Fatalf("birth hash must change with the birth time")
+	}
+}
@@ -0,0 +1,569 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"rag-agent-server/internal/database"
+	"rag-agent-server/internal/models"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrReadingRoomNotFound    = errors.New("room not found")
+	ErrReadingNotMember       = errors.New("only room members can take part in joint reading")
+	ErrReadingForbidden       = errors.New("not allowed to perform this reading action")
+	ErrReadingNoSession       = errors.New("no active reading session in this room")
+	ErrReadingSessionActive   = errors.New("a reading session is already active")
+	ErrReadingStale           = errors.New("reading position has already changed")
+	ErrReadingEndOfBook       = errors.New("end of the book reached")
+	ErrInvalidReadingCommand  = errors.New("invalid reading command")
+	ErrReadingTargetNotInRoom = errors.New("target user is not a member of this room")
+	ErrReadingSessionNotFound = errors.New("reading session not found")
+)
+
+// Joint reading actions; clients send them over the hub as "reading_<action>"
+const (
+	ReadingActionState      = "state"       // Late joiners ask for the current state
+	ReadingActionStart      = "start"       // owner/admin
+	ReadingActionStop       = "stop"        // owner/admin
+	ReadingActionAdvance    = "advance"     // active reader or owner/admin
+	ReadingActionGoto       = "goto"        // owner/admin
+	ReadingActionPass       = "pass"        // active reader or owner/admin: hand off to the next in queue
+	ReadingActionSetReader  = "set_reader"  // owner/admin
+	ReadingActionQueueJoin  = "queue_join"  // any member
+	ReadingActionQueueLeave = "queue_leave" // any member for themselves, owner/admin for anyone
+	ReadingActionSettings   = "settings"    // owner/admin
+)
+
+type JointReadingService struct {
+	db *gorm.DB
+}
+
+func NewJointReadingService() *JointReadingService {
+	return &JointReadingService{db: database.DB}
+}
+
+type ReadingCommand struct {
+	RoomID        uint
+	UserID        uint
+	Action        string
+	TargetUserID  uint
+	Canto         *int
+	Chapter       *int
+	Verse         *int
+	ExpectChapter *int // Advance is rejected as stale when the position moved meanwhile
+	ExpectVerse   *int
+	ShowPurport   *bool
+}
+
+type JointReadingState struct {
+	RoomID         uint       `json:"roomId"`
+	SessionID      uint       `json:"sessionId"`
+	Active         bool       `json:"active"`
+	StartedAt      *time.Time `json:"startedAt,omitempty"`
+	BookCode       string     `json:"bookCode"`
+	Canto          int        `json:"canto"`
+	Chapter        int        `json:"chapter"`
+	Verse          int        `json:"verse"`
+	ActiveReaderID uint       `json:"activeReaderId"`
+	ShowPurport    bool       `json:"showPurport"`
+	Queue          []uint     `json:"queue"`
+}
+
+// ReadingUpdate is the outcome of a command. MemberIDs is set when the change must be
+// broadcast to the room; read-only commands leave it empty.
+type ReadingUpdate struct {
+	State     JointReadingState           `json:"state"`
+	Read      *models.RoomReadingLogEntry `json:"read,omitempty"`
+	MemberIDs []uint                      `json:"-"`
+}
+
+type ReadingLogReader struct {
+	UserID uint `json:"userId"`
+	Verses int  `json:"verses"`
+}
+
+type ReadingSessionLog struct {
+	Session models.RoomReadingSession    `json:"session"`
+	Entries []models.RoomReadingLogEntry `json:"entries"`
+	Readers []ReadingLogReader           `json:"readers"`
+}
+
+// readingAllowed applies the RoomMember.Role rules of the protocol
+func readingAllowed(action, role string, isReader bool) bool {
+	manager := models.CanManageRoomMembers(role)
+	switch action {
+	case ReadingActionState, ReadingActionQueueJoin, ReadingActionQueueLeave:
+		return role != ""
+	case ReadingActionAdvance, ReadingActionPass:
+		return manager || isReader
+	case ReadingActionStart, ReadingActionStop, ReadingActionGoto, ReadingActionSetReader, ReadingActionSettings:
+		return manager
+	default:
+		return false
+	}
+}
+
+func isReadingAction(action string) bool {
+	switch action {
+	case ReadingActionState, ReadingActionStart, ReadingActionStop, ReadingActionAdvance, ReadingActionGoto,
+		ReadingActionPass, ReadingActionSetReader, ReadingActionQueueJoin, ReadingActionQueueLeave, ReadingActionSettings:
+		return true
+	default:
+		return false
+	}
+}
+
+func readingNeedsSession(action string) bool {
+	switch action {
+	case ReadingActionAdvance, ReadingActionGoto, ReadingActionPass, ReadingActionSetReader, ReadingActionStop:
+		return true
+	default:
+		return false
+	}
+}
+
+// Apply runs one command under a lock on the room row, so concurrent commands from
+// several members and instances see a consistent position and queue.
+func (s *JointReadingService) Apply(cmd ReadingCommand) (*ReadingUpdate, error) {
+	if cmd.RoomID == 0 || cmd.UserID == 0 || !isReadingAction(cmd.Action) {
+		return nil, ErrInvalidReadingCommand
+	}
+
+	update := &ReadingUpdate{}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var room models.Room
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&room, cmd.RoomID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrReadingRoomNotFound
+			}
+			return err
+		}
+		role, err := roomMemberRole(tx, room.ID, cmd.UserID)
+		if err != nil {
+			return err
+		}
+		if role == "" {
+			return ErrReadingNotMember
+		}
+		if !readingAllowed(cmd.Action, role, room.ActiveReaderID == cmd.UserID) {
+			return ErrReadingForbidden
+		}
+
+		session, err := activeReadingSession(tx, room.ID)
+		if err != nil {
+			return err
+		}
+		if session == nil && readingNeedsSession(cmd.Action) {
+			return ErrReadingNoSession
+		}
+
+		changes := map[string]interface{}{}
+		switch cmd.Action {
+		case ReadingActionState:
+		case ReadingActionStart:
+			if session != nil {
+				return ErrReadingSessionActive
+			}
+			session = &models.RoomReadingSession{RoomID: room.ID, StartedByID: cmd.UserID, BookCode: room.BookCode, StartedAt: time.Now()}
+			if err := tx.Create(session).Error; err != nil {
+				return err
+			}
+			reader := cmd.TargetUserID
+			if reader == 0 {
+				if reader, err = popReadingQueue(tx, room.ID); err != nil {
+					return err
+				}
+			}
+			if reader == 0 {
+				reader = cmd.UserID
+			}
+			if err := s.setReader(tx, &room, reader, changes); err != nil {
+				return err
+			}
+		case ReadingActionStop:
+			now := time.Now()
+			if err := tx.Model(session).Update("ended_at", now).Error; err != nil {
+				return err
+			}
+			session = nil
+			changes["active_reader_id"] = uint(0)
+			room.ActiveReaderID = 0
+			if err := tx.Where("room_id = ?", room.ID).Delete(&models.RoomReadingQueueEntry{}).Error; err != nil {
+				return err
+			}
+		case ReadingActionAdvance:
+			if (cmd.ExpectChapter != nil && *cmd.ExpectChapter != room.CurrentChapter) ||
+				(cmd.ExpectVerse != nil && *cmd.ExpectVerse != room.CurrentVerse) {
+				return ErrReadingStale
+			}
+			canto, chapter, verse, err := nextReadingPosition(tx, room.BookCode, room.CurrentCanto, room.CurrentChapter, room.CurrentVerse)
+			if err != nil {
+				return err
+			}
+			reader := room.ActiveReaderID
+			if reader == 0 {
+				reader = cmd.UserID
+			}
+			entry := models.RoomReadingLogEntry{
+				SessionID: session.ID,
+				RoomID:    room.ID,
+				ReaderID:  reader,
+				BookCode:  room.BookCode,
+				Canto:     room.CurrentCanto,
+				Chapter:   room.CurrentChapter,
+				Verse:     room.CurrentVerse,
+				ReadAt:    time.Now(),
+			}
+			if err := tx.Create(&entry).Error; err != nil {
+				return err
+			}
+			update.Read = &entry
+			setReadingPosition(&room, canto, chapter, verse, changes)
+		case ReadingActionGoto:
+			if cmd.Chapter == nil || cmd.Verse == nil || *cmd.Chapter < 1 || *cmd.Verse < 1 {
+				return fmt.Errorf("%w: chapter and verse are required", ErrInvalidReadingCommand)
+			}
+			canto := room.CurrentCanto
+			if cmd.Canto != nil && *cmd.Canto >= 0 {
+				canto = *cmd.Canto
+			}
+			setReadingPosition(&room, canto, *cmd.Chapter, *cmd.Verse, changes)
+		case ReadingActionPass:
+			next, err := popReadingQueue(tx, room.ID)
+			if err != nil {
+				return err
+			}
+			changes["active_reader_id"] = next
+			room.ActiveReaderID = next
+		case ReadingActionSetReader:
+			if err := s.setReader(tx, &room, cmd.TargetUserID, changes); err != nil {
+				return err
+			}
+		case ReadingActionQueueJoin:
+			if room.ActiveReaderID == cmd.UserID && session != nil {
+				break
+			}
+			if err := joinReadingQueue(tx, room.ID, cmd.UserID); err != nil {
+				return err
+			}
+		case ReadingActionQueueLeave:
+			target := cmd.UserID
+			if cmd.TargetUserID != 0 && cmd.TargetUserID != cmd.UserID {
+				if !models.CanManageRoomMembers(role) {
+					return ErrReadingForbidden
+				}
+				target = cmd.TargetUserID
+			}
+			if err := tx.Where("room_id = ? AND user_id = ?", room.ID, target).Delete(&models.RoomReadingQueueEntry{}).Error; err != nil {
+				return err
+			}
+		case ReadingActionSettings:
+			if cmd.ShowPurport == nil {
+				return ErrInvalidReadingCommand
+			}
+			changes["show_purport"] = *cmd.ShowPurport
+			room.ShowPurport = *cmd.ShowPurport
+		}
+
+		if len(changes) > 0 {
+			if err := tx.Model(&models.Room{}).Where("id = ?", room.ID).Updates(changes).Error; err != nil {
+				return err
+			}
+		}
+		state, err := buildReadingState(tx, &room, session)
+		if err != nil {
+			return err
+		}
+		update.State = *state
+		if cmd.Action != ReadingActionState {
+			memberIDs, err := roomMemberIDs(tx, room.ID)
+			if err != nil {
+				return err
+			}
+			update.MemberIDs = memberIDs
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return update, nil
+}
+
+// setReader makes a room member the active reader and takes them out of the queue
+func (s *JointReadingService) setReader(tx *gorm.DB, room *models.Room, userID uint, changes map[string]interface{}) error {
+	if userID == 0 {
+		return ErrInvalidReadingCommand
+	}
+	role, err := roomMemberRole(tx, room.ID, userID)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return ErrReadingTargetNotInRoom
+	}
+	if err := tx.Where("room_id = ? AND user_id = ?", room.ID, userID).Delete(&models.RoomReadingQueueEntry{}).Error; err != nil {
+		return err
+	}
+	changes["active_reader_id"] = userID
+	room.ActiveReaderID = userID
+	return nil
+}
+
+func setReadingPosition(room *models.Room, canto, chapter, verse int, changes map[string]interface{}) {
+	room.CurrentCanto, room.CurrentChapter, room.CurrentVerse = canto, chapter, verse
+	changes["current_canto"] = canto
+	changes["current_chapter"] = chapter
+	changes["current_verse"] = verse
+}
+
+// State returns the current joint reading state for a member, e.g. a late joiner
+func (s *JointReadingService) State(roomID, userID uint) (*JointReadingState, error) {
+	update, err := s.Apply(ReadingCommand{RoomID: roomID, UserID: userID, Action: ReadingActionState})
+	if err != nil {
+		return nil, err
+	}
+	return &update.State, nil
+}
+
+// SessionLog returns who read which verses in a session; sessionID 0 selects the latest one
+func (s *JointReadingService) SessionLog(roomID, userID, sessionID uint) (*ReadingSessionLog, error) {
+	role, err := roomMemberRole(s.db, roomID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if role == "" {
+		return nil, ErrReadingNotMember
+	}
+
+	var session models.RoomReadingSession
+	query := s.db.Where("room_id = ?", roomID)
+	if sessionID != 0 {
+		query = query.Where("id = ?", sessionID)
+	}
+	if err := query.Order("started_at DESC, id DESC").First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReadingSessionNotFound
+		}
+		return nil, err
+	}
+
+	var entries []models.RoomReadingLogEntry
+	if err := s.db.Where("session_id = ?", session.ID).Order("read_at ASC, id ASC").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return &ReadingSessionLog{Session: session, Entries: entries, Readers: summarizeReaders(entries)}, nil
+}
+
+// ListSessions returns the room's reading sessions, newest first
+func (s *JointReadingService) ListSessions(roomID, userID uint, limit int) ([]models.RoomReadingSession, error) {
+	role, err := roomMemberRole(s.db, roomID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if role == "" {
+		return nil, ErrReadingNotMember
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	var sessions []models.RoomReadingSession
+	err = s.db.Where("room_id = ?", roomID).Order("started_at DESC, id DESC").Limit(limit).Find(&sessions).Error
+	return sessions, err
+}
+
+func summarizeReaders(entries []models.RoomReadingLogEntry) []ReadingLogReader {
+	counts := make(map[uint]int)
+	order := make([]uint, 0)
+	for _, e := range entries {
+		if _, ok := counts[e.ReaderID]; !ok {
+			order = append(order, e.ReaderID)
+		}
+		counts[e.ReaderID]++
+	}
+	readers := make([]ReadingLogReader, 0, len(order))
+	for _, id := range order {
+		readers = append(readers, ReadingLogReader{UserID: id, Verses: counts[id]})
+	}
+	return readers
+}
+
+func roomMemberRole(db *gorm.DB, roomID, userID uint) (string, error) {
+	var member models.RoomMember
+	if err := db.Where("room_id = ? AND user_id = ?", roomID, userID).First(&member).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	return models.NormalizeRoomRole(member.Role), nil
+}
+
+func roomMemberIDs(db *gorm.DB, roomID uint) ([]uint, error) {
+	var ids []uint
+	if err := db.Model(&models.RoomMember{}).Where("room_id = ?", roomID).Pluck("user_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return uniqueIDs(ids), nil
+}
+
+func activeReadingSession(db *gorm.DB, roomID uint) (*models.RoomReadingSession, error) {
+	var session models.RoomReadingSession
+	err := db.Where("room_id = ? AND ended_at IS NULL", roomID).Order("id DESC").First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// readingQueue lists waiting members in order; users who left the room are skipped
+func readingQueue(db *gorm.DB, roomID uint) ([]uint, error) {
+	var ids []uint
+	err := db.Model(&models.RoomReadingQueueEntry{}).
+		Joins("JOIN room_members rm ON rm.room_id = room_reading_queue_entries.room_id AND rm.user_id = room_reading_queue_entries.user_id AND rm.deleted_at IS NULL").
+		Where("room_reading_queue_entries.room_id = ?", roomID).
+		Order("room_reading_queue_entries.position ASC, room_reading_queue_entries.id ASC").
+		Pluck("room_reading_queue_entries.user_id", &ids).Error
+	return ids, err
+}
+
+func popReadingQueue(tx *gorm.DB, roomID uint) (uint, error) {
+	queue, err := readingQueue(tx, roomID)
+	if err != nil || len(queue) == 0 {
+		return 0, err
+	}
+	if err := tx.Where("room_id = ? AND user_id = ?", roomID, queue[0]).Delete(&models.RoomReadingQueueEntry{}).Error; err != nil {
+		return 0, err
+	}
+	return queue[0], nil
+}
+
+func joinReadingQueue(tx *gorm.DB, roomID, userID uint) error {
+	var maxPosition int
+	if err := tx.Model(&models.RoomReadingQueueEntry{}).
+		Where("room_id = ?", roomID).
+		Select("COALESCE(MAX(position), 0)").
+		Scan(&maxPosition).Error; err != nil {
+		return err
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.RoomReadingQueueEntry{
+		RoomID:   roomID,
+		UserID:   userID,
+		Position: maxPosition + 1,
+	}).Error
+}
+
+func buildReadingState(db *gorm.DB, room *models.Room, session *models.RoomReadingSession) (*JointReadingState, error) {
+	queue, err := readingQueue(db, room.ID)
+	if err != nil {
+		return nil, err
+	}
+	state := &JointReadingState{
+		RoomID:         room.ID,
+		BookCode:       room.BookCode,
+		Canto:          room.CurrentCanto,
+		Chapter:        room.CurrentChapter,
+		Verse:          room.CurrentVerse,
+		ActiveReaderID: room.ActiveReaderID,
+		ShowPurport:    room.ShowPurport,
+		Queue:          queue,
+	}
+	if state.Queue == nil {
+		state.Queue = []uint{}
+	}
+	if session != nil {
+		state.SessionID = session.ID
+		state.Active = true
+		startedAt := session.StartedAt
+		state.StartedAt = &startedAt
+	}
+	return state, nil
+}
+
+// nextReadingPosition moves to the next verse of the chapter, or to the first verse of the
+// next chapter. Chapters whose verses are not imported advance one verse at a time.
+func nextReadingPosition(db *gorm.DB, bookCode string, canto, chapter, verse int) (int, int, int, error) {
+	if strings.TrimSpace(bookCode) == "" {
+		return canto, chapter, verse + 1, nil
+	}
+	verses, err := chapterVerseRefs(db, bookCode, canto, chapter)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if len(verses) == 0 {
+		return canto, chapter, verse + 1, nil
+	}
+	if next, ok := nextVerseNumber(verse, verses); ok {
+		return canto, chapter, next, nil
+	}
+
+	var following models.ScriptureChapter
+	err = db.Where("book_code = ? AND (canto, chapter) > (?, ?)", bookCode, canto, chapter).
+		Order("canto ASC, chapter ASC").
+		First(&following).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, 0, 0, ErrReadingEndOfBook
+	}
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	nextVerses, err := chapterVerseRefs(db, bookCode, following.Canto, following.Chapter)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	first, ok := nextVerseNumber(0, nextVerses)
+	if !ok {
+		first = 1
+	}
+	return following.Canto, following.Chapter, first, nil
+}
+
+func chapterVerseRefs(db *gorm.DB, bookCode string, canto, chapter int) ([]string, error) {
+	var verses []string
+	err := db.Model(&models.ScriptureVerse{}).
+		Where("book_code = ? AND canto = ? AND chapter = ?", bookCode, canto, chapter).
+		Distinct("verse").
+		Pluck("verse", &verses).Error
+	return verses, err
+}
+
+// nextVerseNumber returns the smallest verse start after current. Grouped verses such as
+// "16-18" start at 16, so advancing from 16 lands on 19.
+func nextVerseNumber(current int, verses []string) (int, bool) {
+	starts := make([]int, 0, len(verses))
+	for _, v := range verses {
+		if n, ok := leadingVerseNumber(v); ok {
+			starts = append(starts, n)
+		}
+	}
+	sort.Ints(starts)
+	for _, n := range starts {
+		if n > current {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+func leadingVerseNumber(verse string) (int, bool) {
+	verse = strings.TrimSpace(verse)
+	end := 0
+	for end < len(verse) && verse[end] >= '0' && verse[end] <= '9' {
+		end++
+	}
+	if end == 0 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(verse[:end])
+	return n, err == nil
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"rag-agent-server/internal/models"
+	"testing"
+)
+
+func TestReadingAllowed(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		action   string
+		role     string
+		isReader bool
+		want     bool
+	}{
+		{action: ReadingActionState, role: models.RoomRoleMember, want: true},
+		{action: ReadingActionState, role: "", want: false},
+		{action: ReadingActionQueueJoin, role: models.RoomRoleMember, want: true},
+		{action: ReadingActionAdvance, role: models.RoomRoleMember, want: false},
+		{action: ReadingActionAdvance, role: models.RoomRoleMember, isReader: true, want: true},
+		{action: ReadingActionAdvance, role: models.RoomRoleAdmin, want: true},
+		{action: ReadingActionPass, role: models.RoomRoleMember, isReader: true, want: true},
+		{action: ReadingActionStart, role: models.RoomRoleMember, isReader: true, want: false},
+		{action: ReadingActionStart, role: models.RoomRoleOwner, want: true},
+		{action: ReadingActionSetReader, role: models.RoomRoleAdmin, want: true},
+		{action: ReadingActionGoto, role: models.RoomRoleMember, isReader: true, want: false},
+		{action: ReadingActionSettings, role: models.RoomRoleOwner, want: true},
+		{action: "unknown", role: models.RoomRoleOwner, want: false},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.action+"/"+tc.role, func(t *testing.T) {
+			t.Parallel()
+			if got := readingAllowed(tc.action, tc.role, tc.isReader); got != tc.want {
+				t.Fatalf("readingAllowed(%q, %q, %v) = %v, want %v", tc.action, tc.role, tc.isReader, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNextVerseNumber(t *testing.T) {
+	t.Parallel()
+
+	verses := []string{"1", "2", "16-18", "3", "19", "20 "}
+	tests := []struct {
+		current int
+		want    int
+		ok      bool
+	}{
+		{current: 0, want: 1, ok: true},
+		{current: 3, want: 16, ok: true},
+		{current: 16, want: 19, ok: true},
+		{current: 17, want: 19, ok: true},
+		{current: 20, ok: false},
+	}
+
+	for _, tc := range tests {
+		got, ok := nextVerseNumber(tc.current, verses)
+		if ok != tc.ok || got != tc.want {
+			t.Fatalf("nextVerseNumber(%d) = %d, %v; want %d, %v", tc.current, got, ok, tc.want, tc.ok)
+		}
+	}
+	if _, ok := leadingVerseNumber("intro"); ok {
+		t.Fatalf("non-numeric verse must be skipped")
+	}
+}
+
+func TestSummarizeReaders(t *testing.T) {
+	t.Parallel()
+
+	readers := summarizeReaders([]models.RoomReadingLogEntry{
+		{ReaderID: 7, Verse: 1},
+		{ReaderID: 3, Verse: 2},
+		{ReaderID: 7, Verse: 3},
+	})
+	if len(readers) != 2 || readers[0].UserID != 7 || readers[0].Verses != 2 || readers[1].Verses != 1 {
+		t.Fatalf("unexpected reader summary %+v", readers)
+	}
+}
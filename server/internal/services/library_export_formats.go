@@ -0,0 +1,355 @@
+package services
+
+import (
+	"archive/zip"
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+)
+
+// markdownExportWriter renders Markdown: # book, ## canto, ### chapter, #### verse
+type markdownExportWriter struct {
+	w      *bufio.Writer
+	labels exportLabels
+}
+
+func newMarkdownExportWriter(w io.Writer) *markdownExportWriter {
+	return &markdownExportWriter{w: bufio.NewWriter(w)}
+}
+
+func (m *markdownExportWriter) Begin(plan *LibraryExportPlan) error {
+	m.labels = labelsFor(plan.Languages[0])
+	fmt.Fprintf(m.w, "# %s\n\n", plan.BookTitle())
+	if desc := pickTitle(plan.Languages[0] == "ru", plan.Book.DescriptionRu, plan.Book.DescriptionEn); desc != "" {
+		fmt.Fprintf(m.w, "> %s\n\n", desc)
+	}
+	return nil
+}
+
+func (m *markdownExportWriter) Chapter(chapter exportChapter, verses []exportVerse) error {
+	level := "##"
+	if chapter.Canto > 0 {
+		if chapter.NewCanto {
+			fmt.Fprintf(m.w, "## %s\n\n", cantoHeading(m.labels, chapter))
+		}
+		level = "###"
+	}
+	fmt.Fprintf(m.w, "%s %s\n\n", level, chapterHeading(m.labels, chapter))
+	for _, verse := range verses {
+		fmt.Fprintf(m.w, "%s# %s %s\n\n", level, m.labels.Text, verseLabel(chapter.Canto, chapter.Chapter, verse.Reference))
+		if verse.Devanagari != "" {
+			fmt.Fprintf(m.w, "%s\n\n", markdownLines(verse.Devanagari))
+		}
+		if verse.Transliteration != "" {
+			fmt.Fprintf(m.w, "*%s*\n\n", markdownLines(verse.Transliteration))
+		}
+		m.text(verse.Primary, verse.Secondary != nil)
+		if verse.Secondary != nil {
+			m.text(*verse.Secondary, true)
+		}
+	}
+	return m.w.Flush()
+}
+
+func (m *markdownExportWriter) text(t exportVerseText, tagged bool) {
+	labels := labelsFor(t.Language)
+	if tagged && (t.Synonyms != "" || t.Translation != "" || t.Purport != "") {
+		fmt.Fprintf(m.w, "**[%s]**\n\n", strings.ToUpper(t.Language))
+	}
+	if t.Synonyms != "" {
+		fmt.Fprintf(m.w, "**%s:** %s\n\n", labels.Synonyms, t.Synonyms)
+	}
+	if t.Translation != "" {
+		fmt.Fprintf(m.w, "**%s:** %s\n\n", labels.Translation, t.Translation)
+	}
+	if t.Purport != "" {
+		fmt.Fprintf(m.w, "**%s**\n\n%s\n\n", labels.Purport, t.Purport)
+	}
+}
+
+func (m *markdownExportWriter) End() error {
+	return m.w.Flush()
+}
+
+// markdownLines keeps verse line breaks (two trailing spaces force a <br>)
+func markdownLines(s string) string {
+	return strings.ReplaceAll(strings.TrimSpace(s), "\n", "  \n")
+}
+
+// textExportWriter renders plain text with underlined headings
+type textExportWriter struct {
+	w      *bufio.Writer
+	labels exportLabels
+}
+
+func newTextExportWriter(w io.Writer) *textExportWriter {
+	return &textExportWriter{w: bufio.NewWriter(w)}
+}
+
+func (t *textExportWriter) Begin(plan *LibraryExportPlan) error {
+	t.labels = labelsFor(plan.Languages[0])
+	t.heading(plan.BookTitle(), "=")
+	if desc := pickTitle(plan.Languages[0] == "ru", plan.Book.DescriptionRu, plan.Book.DescriptionEn); desc != "" {
+		fmt.Fprintf(t.w, "%s\n\n", desc)
+	}
+	return nil
+}
+
+func (t *textExportWriter) heading(title, underline string) {
+	fmt.Fprintf(t.w, "%s\n%s\n\n", title, strings.Repeat(underline, len([]rune(title))))
+}
+
+func (t *textExportWriter) Chapter(chapter exportChapter, verses []exportVerse) error {
+	if chapter.NewCanto && chapter.Canto > 0 {
+		t.heading(cantoHeading(t.labels, chapter), "=")
+	}
+	t.heading(chapterHeading(t.labels, chapter), "-")
+	for _, verse := range verses {
+		fmt.Fprintf(t.w, "%s %s\n\n", t.labels.Text, verseLabel(chapter.Canto, chapter.Chapter, verse.Reference))
+		if verse.Devanagari != "" {
+			fmt.Fprintf(t.w, "%s\n\n", strings.TrimSpace(verse.Devanagari))
+		}
+		if verse.Transliteration != "" {
+			fmt.Fprintf(t.w, "%s\n\n", strings.TrimSpace(verse.Transliteration))
+		}
+		t.text(verse.Primary, verse.Secondary != nil)
+		if verse.Secondary != nil {
+			t.text(*verse.Secondary, true)
+		}
+	}
+	return t.w.Flush()
+}
+
+func (t *textExportWriter) text(v exportVerseText, tagged bool) {
+	labels := labelsFor(v.Language)
+	if tagged && (v.Synonyms != "" || v.Translation != "" || v.Purport != "") {
+		fmt.Fprintf(t.w, "[%s]\n", strings.ToUpper(v.Language))
+	}
+	if v.Synonyms != "" {
+		fmt.Fprintf(t.w, "%s: %s\n\n", labels.Synonyms, v.Synonyms)
+	}
+	if v.Translation != "" {
+		fmt.Fprintf(t.w, "%s: %s\n\n", labels.Translation, v.Translation)
+	}
+	if v.Purport != "" {
+		fmt.Fprintf(t.w, "%s:\n%s\n\n", labels.Purport, v.Purport)
+	}
+}
+
+func (t *textExportWriter) End() error {
+	return t.w.Flush()
+}
+
+// epubExportWriter streams an EPUB 3 container: one XHTML document per chapter,
+// with the package document and navigation written last once all chapters are known
+type epubExportWriter struct {
+	zip      *zip.Writer
+	plan     *LibraryExportPlan
+	labels   exportLabels
+	chapters []epubChapterRef
+}
+
+type epubChapterRef struct {
+	ID       string
+	File     string
+	Title    string
+	CantoNav string // Set on the first chapter of a canto
+}
+
+func newEPUBExportWriter(w io.Writer) *epubExportWriter {
+	return &epubExportWriter{zip: zip.NewWriter(w)}
+}
+
+func (e *epubExportWriter) Begin(plan *LibraryExportPlan) error {
+	e.plan = plan
+	e.labels = labelsFor(plan.Languages[0])
+
+	// The mimetype entry must come first and be stored uncompressed
+	mimetype, err := e.zip.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(mimetype, "application/epub+zip"); err != nil {
+		return err
+	}
+	if err := e.file("META-INF/container.xml", `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`); err != nil {
+		return err
+	}
+	if err := e.file("OEBPS/style.css", epubStylesheet); err != nil {
+		return err
+	}
+
+	var title strings.Builder
+	fmt.Fprintf(&title, "<h1>%s</h1>\n", html.EscapeString(plan.BookTitle()))
+	if desc := pickTitle(plan.Languages[0] == "ru", plan.Book.DescriptionRu, plan.Book.DescriptionEn); desc != "" {
+		fmt.Fprintf(&title, "<p class=\"description\">%s</p>\n", html.EscapeString(desc))
+	}
+	return e.file("OEBPS/title.xhtml", e.page(plan.BookTitle(), title.String()))
+}
+
+func (e *epubExportWriter) Chapter(chapter exportChapter, verses []exportVerse) error {
+	ref := epubChapterRef{
+		ID:    fmt.Sprintf("ch-%d-%d", chapter.Canto, chapter.Chapter),
+		Title: chapterHeading(e.labels, chapter),
+	}
+	ref.File = ref.ID + ".xhtml"
+
+	var body strings.Builder
+	if chapter.NewCanto && chapter.Canto > 0 {
+		ref.CantoNav = cantoHeading(e.labels, chapter)
+		fmt.Fprintf(&body, "<h1>%s</h1>\n", html.EscapeString(ref.CantoNav))
+	}
+	fmt.Fprintf(&body, "<h2>%s</h2>\n", html.EscapeString(ref.Title))
+	for _, verse := range verses {
+		body.WriteString("<section class=\"verse\">\n")
+		fmt.Fprintf(&body, "<h3>%s %s</h3>\n", e.labels.Text, html.EscapeString(verseLabel(chapter.Canto, chapter.Chapter, verse.Reference)))
+		if verse.Devanagari != "" {
+			fmt.Fprintf(&body, "<p class=\"devanagari\">%s</p>\n", epubLines(verse.Devanagari))
+		}
+		if verse.Transliteration != "" {
+			fmt.Fprintf(&body, "<p class=\"translit\">%s</p>\n", epubLines(verse.Transliteration))
+		}
+		if verse.Secondary == nil {
+			body.WriteString(epubVerseText(verse.Primary))
+		} else {
+			// Side-by-side columns for bilingual output
+			fmt.Fprintf(&body, "<table class=\"bilingual\"><tr><td lang=\"%s\">%s</td><td lang=\"%s\">%s</td></tr></table>\n",
+				verse.Primary.Language, epubVerseText(verse.Primary),
+				verse.Secondary.Language, epubVerseText(*verse.Secondary))
+		}
+		body.WriteString("</section>\n")
+	}
+
+	if err := e.file("OEBPS/"+ref.File, e.page(ref.Title, body.String())); err != nil {
+		return err
+	}
+	e.chapters = append(e.chapters, ref)
+	return nil
+}
+
+func (e *epubExportWriter) End() error {
+	if err := e.file("OEBPS/nav.xhtml", e.nav()); err != nil {
+		return err
+	}
+	if err := e.file("OEBPS/content.opf", e.opf()); err != nil {
+		return err
+	}
+	return e.zip.Close()
+}
+
+func (e *epubExportWriter) file(name, content string) error {
+	f, err := e.zip.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(f, content)
+	return err
+}
+
+func (e *epubExportWriter) page(title, body string) string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" lang="%[1]s" xml:lang="%[1]s">
+<head><meta charset="UTF-8"/><title>%[2]s</title><link rel="stylesheet" type="text/css" href="style.css"/></head>
+<body>
+%[3]s</body>
+</html>
+`, e.plan.Languages[0], html.EscapeString(title), body)
+}
+
+func (e *epubExportWriter) nav() string {
+	var items strings.Builder
+	open := false
+	for _, ch := range e.chapters {
+		link := fmt.Sprintf("<li><a href=\"%s\">%s</a></li>\n", ch.File, html.EscapeString(ch.Title))
+		if ch.CantoNav != "" {
+			if open {
+				items.WriteString("</ol></li>\n")
+			}
+			fmt.Fprintf(&items, "<li><a href=\"%s\">%s</a><ol>\n", ch.File, html.EscapeString(ch.CantoNav))
+			open = true
+		}
+		items.WriteString(link)
+	}
+	if open {
+		items.WriteString("</ol></li>\n")
+	}
+	body := fmt.Sprintf("<nav epub:type=\"toc\" id=\"toc\"><h1>%s</h1><ol>\n<li><a href=\"title.xhtml\">%s</a></li>\n%s</ol></nav>\n",
+		html.EscapeString(e.plan.BookTitle()), html.EscapeString(e.plan.BookTitle()), items.String())
+	return e.page(e.plan.BookTitle(), body)
+}
+
+func (e *epubExportWriter) opf() string {
+	var manifest, spine strings.Builder
+	for _, ch := range e.chapters {
+		fmt.Fprintf(&manifest, "    <item id=\"%s\" href=\"%s\" media-type=\"application/xhtml+xml\"/>\n", ch.ID, ch.File)
+		fmt.Fprintf(&spine, "    <itemref idref=\"%s\"/>\n", ch.ID)
+	}
+	var languages strings.Builder
+	for _, lang := range e.plan.Languages {
+		fmt.Fprintf(&languages, "    <dc:language>%s</dc:language>\n", lang)
+	}
+	identifier := "urn:vedamatch:library:" + strings.TrimSuffix(e.plan.FileName(), "."+LibraryExportEPUB)
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="book-id">%s</dc:identifier>
+    <dc:title>%s</dc:title>
+%s    <meta property="dcterms:modified">%s</meta>
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="css" href="style.css" media-type="text/css"/>
+    <item id="title" href="title.xhtml" media-type="application/xhtml+xml"/>
+%s  </manifest>
+  <spine>
+    <itemref idref="title"/>
+%s  </spine>
+</package>
+`, html.EscapeString(identifier), html.EscapeString(e.plan.BookTitle()), languages.String(),
+		time.Now().UTC().Format("2006-01-02T15:04:05Z"), manifest.String(), spine.String())
+}
+
+func epubVerseText(t exportVerseText) string {
+	labels := labelsFor(t.Language)
+	var b strings.Builder
+	if t.Synonyms != "" {
+		fmt.Fprintf(&b, "<p class=\"synonyms\"><b>%s:</b> %s</p>\n", labels.Synonyms, html.EscapeString(t.Synonyms))
+	}
+	if t.Translation != "" {
+		fmt.Fprintf(&b, "<p class=\"translation\"><b>%s:</b> %s</p>\n", labels.Translation, html.EscapeString(t.Translation))
+	}
+	if t.Purport != "" {
+		fmt.Fprintf(&b, "<h4>%s</h4>\n", labels.Purport)
+		for _, para := range strings.Split(t.Purport, "\n") {
+			if para = strings.TrimSpace(para); para != "" {
+				fmt.Fprintf(&b, "<p>%s</p>\n", html.EscapeString(para))
+			}
+		}
+	}
+	return b.String()
+}
+
+func epubLines(s string) string {
+	return strings.ReplaceAll(html.EscapeString(strings.TrimSpace(s)), "\n", "<br/>")
+}
+
+const epubStylesheet = `body { font-family: serif; line-height: 1.5; }
+h1, h2 { text-align: center; }
+.description { font-style: italic; text-align: center; }
+.verse { margin-bottom: 1.5em; }
+.devanagari, .translit { text-align: center; }
+.translit { font-style: italic; }
+.translation { font-weight: bold; }
+table.bilingual { width: 100%; border-collapse: collapse; }
+table.bilingual td { width: 50%; vertical-align: top; padding: 0 0.5em; }
+`
@@ -0,0 +1,226 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/unidoc/unipdf/v3/common/license"
+	"github.com/unidoc/unipdf/v3/creator"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+var ErrPDFExportUnavailable = errors.New("PDF export is not configured on this server")
+
+// Fonts need Cyrillic and IAST glyphs. LIBRARY_PDF_FONT / LIBRARY_PDF_FONT_BOLD override the
+// DejaVu defaults (package font-dejavu on Alpine, fonts-dejavu-core on Debian).
+var pdfFontCandidates = []string{
+	"/usr/share/fonts/dejavu/DejaVuSans.ttf",
+	"/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf",
+}
+
+var pdfBoldFontCandidates = []string{
+	"/usr/share/fonts/dejavu/DejaVuSans-Bold.ttf",
+	"/usr/share/fonts/truetype/dejavu/DejaVuSans-Bold.ttf",
+}
+
+var (
+	pdfExportOnce     sync.Once
+	pdfExportErr      error
+	pdfRegularFontTTF string
+	pdfBoldFontTTF    string
+)
+
+// ensurePDFExportReady applies the unipdf license (UNIDOC_LICENSE_API_KEY) and locates fonts once
+func ensurePDFExportReady() error {
+	pdfExportOnce.Do(func() {
+		key := strings.TrimSpace(os.Getenv("UNIDOC_LICENSE_API_KEY"))
+		if key == "" {
+			pdfExportErr = ErrPDFExportUnavailable
+			log.Println("[LibraryExport] UNIDOC_LICENSE_API_KEY is not set; PDF export disabled")
+			return
+		}
+		if err := license.SetMeteredKey(key); err != nil {
+			pdfExportErr = fmt.Errorf("%w: %v", ErrPDFExportUnavailable, err)
+			log.Printf("[LibraryExport] unipdf license rejected: %v", err)
+			return
+		}
+		pdfRegularFontTTF = firstExistingFile(os.Getenv("LIBRARY_PDF_FONT"), pdfFontCandidates...)
+		pdfBoldFontTTF = firstExistingFile(os.Getenv("LIBRARY_PDF_FONT_BOLD"), pdfBoldFontCandidates...)
+		if pdfRegularFontTTF == "" {
+			pdfExportErr = fmt.Errorf("%w: no unicode TTF font found", ErrPDFExportUnavailable)
+			log.Println("[LibraryExport] no unicode TTF font found (set LIBRARY_PDF_FONT); PDF export disabled")
+		}
+	})
+	return pdfExportErr
+}
+
+func firstExistingFile(preferred string, candidates ...string) string {
+	for _, path := range append([]string{strings.TrimSpace(preferred)}, candidates...) {
+		if path == "" {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// pdfExportWriter lays out the book with unipdf. The PDF cross-reference table needs the
+// whole document, so pages are built in memory and written to w at the end.
+type pdfExportWriter struct {
+	w       io.Writer
+	c       *creator.Creator
+	regular *model.PdfFont
+	bold    *model.PdfFont
+	labels  exportLabels
+}
+
+func newPDFExportWriter(w io.Writer) (*pdfExportWriter, error) {
+	if err := ensurePDFExportReady(); err != nil {
+		return nil, err
+	}
+	regular, err := model.NewCompositePdfFontFromTTFFile(pdfRegularFontTTF)
+	if err != nil {
+		return nil, fmt.Errorf("load pdf font: %w", err)
+	}
+	bold := regular
+	if pdfBoldFontTTF != "" {
+		if bold, err = model.NewCompositePdfFontFromTTFFile(pdfBoldFontTTF); err != nil {
+			return nil, fmt.Errorf("load pdf bold font: %w", err)
+		}
+	}
+
+	c := creator.New()
+	c.SetPageSize(creator.PageSizeA4)
+	c.SetPageMargins(50, 50, 60, 60)
+	c.EnableFontSubsetting(regular)
+	if bold != regular {
+		c.EnableFontSubsetting(bold)
+	}
+	return &pdfExportWriter{w: w, c: c, regular: regular, bold: bold}, nil
+}
+
+func (p *pdfExportWriter) Begin(plan *LibraryExportPlan) error {
+	p.labels = labelsFor(plan.Languages[0])
+	p.c.SetLanguage(plan.Languages[0])
+	p.c.AddTOC = true
+
+	title := p.c.NewStyledParagraph()
+	title.SetTextAlignment(creator.TextAlignmentCenter)
+	title.SetMargins(0, 0, 200, 20)
+	p.chunk(title, plan.BookTitle(), p.bold, 24)
+	if err := p.c.Draw(title); err != nil {
+		return err
+	}
+	if desc := pickTitle(plan.Languages[0] == "ru", plan.Book.DescriptionRu, plan.Book.DescriptionEn); desc != "" {
+		para := p.c.NewStyledParagraph()
+		para.SetTextAlignment(creator.TextAlignmentCenter)
+		p.chunk(para, desc, p.regular, 12)
+		return p.c.Draw(para)
+	}
+	return nil
+}
+
+func (p *pdfExportWriter) Chapter(chapter exportChapter, verses []exportVerse) error {
+	heading := chapterHeading(p.labels, chapter)
+	if chapter.Canto > 0 {
+		heading = fmt.Sprintf("%d.%d %s", chapter.Canto, chapter.Chapter, heading)
+	}
+	ch := p.c.NewChapter(heading)
+	ch.SetShowNumbering(false)
+	ch.GetHeading().SetFont(p.bold)
+	ch.GetHeading().SetFontSize(16)
+	if chapter.NewCanto && chapter.Canto > 0 {
+		canto := p.c.NewStyledParagraph()
+		canto.SetMargins(0, 0, 0, 10)
+		p.chunk(canto, cantoHeading(p.labels, chapter), p.regular, 12)
+		if err := ch.Add(canto); err != nil {
+			return err
+		}
+	}
+
+	for _, verse := range verses {
+		ref := p.c.NewStyledParagraph()
+		ref.SetMargins(0, 0, 14, 4)
+		p.chunk(ref, p.labels.Text+" "+verseLabel(chapter.Canto, chapter.Chapter, verse.Reference), p.bold, 12)
+		if err := ch.Add(ref); err != nil {
+			return err
+		}
+		// Devanagari needs complex shaping that unipdf fonts do not provide; the transliteration carries the verse
+		if verse.Transliteration != "" {
+			translit := p.c.NewStyledParagraph()
+			translit.SetTextAlignment(creator.TextAlignmentCenter)
+			translit.SetMargins(0, 0, 4, 4)
+			p.chunk(translit, strings.TrimSpace(verse.Transliteration), p.regular, 10)
+			if err := ch.Add(translit); err != nil {
+				return err
+			}
+		}
+
+		if verse.Secondary == nil {
+			for _, block := range p.textBlocks(verse.Primary) {
+				if err := ch.Add(block); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		// Side-by-side columns for bilingual output
+		table := p.c.NewTable(2)
+		table.EnableRowWrap(true)
+		table.SetMargins(0, 0, 4, 4)
+		for _, text := range []exportVerseText{verse.Primary, *verse.Secondary} {
+			div := p.c.NewDivision()
+			for _, block := range p.textBlocks(text) {
+				if err := div.Add(block); err != nil {
+					return err
+				}
+			}
+			cell := table.NewCell()
+			cell.SetIndent(4)
+			if err := cell.SetContent(div); err != nil {
+				return err
+			}
+		}
+		if err := ch.Add(table); err != nil {
+			return err
+		}
+	}
+	return p.c.Draw(ch)
+}
+
+func (p *pdfExportWriter) textBlocks(t exportVerseText) []*creator.StyledParagraph {
+	labels := labelsFor(t.Language)
+	blocks := make([]*creator.StyledParagraph, 0, 3)
+	add := func(label, text string) {
+		if text == "" {
+			return
+		}
+		para := p.c.NewStyledParagraph()
+		para.SetMargins(0, 0, 4, 4)
+		p.chunk(para, label+": ", p.bold, 10)
+		p.chunk(para, text, p.regular, 10)
+		blocks = append(blocks, para)
+	}
+	add(labels.Synonyms, t.Synonyms)
+	add(labels.Translation, t.Translation)
+	add(labels.Purport, t.Purport)
+	return blocks
+}
+
+func (p *pdfExportWriter) chunk(para *creator.StyledParagraph, text string, font *model.PdfFont, size float64) {
+	chunk := para.Append(text)
+	chunk.Style.Font = font
+	chunk.Style.FontSize = size
+}
+
+func (p *pdfExportWriter) End() error {
+	return p.c.Write(p.w)
+}
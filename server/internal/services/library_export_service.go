@@ -0,0 +1,375 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"rag-agent-server/internal/database"
+	"rag-agent-server/internal/models"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrLibraryBookNotFound       = errors.New("book not found")
+	ErrUnsupportedExportFormat   = errors.New("format must be one of: json, epub, pdf, md, txt")
+	ErrUnsupportedExportLanguage = errors.New("language must be one of: ru, en")
+	ErrLibraryExportEmpty        = errors.New("nothing to export for the selected scope")
+)
+
+// Library export formats
+const (
+	LibraryExportJSON     = "json"
+	LibraryExportEPUB     = "epub"
+	LibraryExportPDF      = "pdf"
+	LibraryExportMarkdown = "md"
+	LibraryExportText     = "txt"
+)
+
+var libraryExportContentTypes = map[string]string{
+	LibraryExportEPUB:     "application/epub+zip",
+	LibraryExportPDF:      "application/pdf",
+	LibraryExportMarkdown: "text/markdown; charset=utf-8",
+	LibraryExportText:     "text/plain; charset=utf-8",
+}
+
+// LibraryExportOptions selects what to export. Canto/Chapter 0 mean the whole book.
+// Bilingual puts the other language next to Language ("ru" by default) for each verse.
+type LibraryExportOptions struct {
+	BookCode  string
+	Format    string
+	Canto     int
+	Chapter   int
+	Language  string
+	Bilingual bool
+}
+
+// LibraryExportPlan is a validated export with the book structure resolved.
+// It is built before the response starts streaming so errors can still be reported.
+type LibraryExportPlan struct {
+	Options   LibraryExportOptions
+	Book      models.ScriptureBook
+	Cantos    map[int]models.ScriptureCanto
+	Chapters  []models.ScriptureChapter
+	Languages []string // Primary first
+}
+
+// ContentType returns the MIME type of the export
+func (p *LibraryExportPlan) ContentType() string {
+	return libraryExportContentTypes[p.Options.Format]
+}
+
+// FileName returns a download file name such as "sb-1-2-ru-en.epub"
+func (p *LibraryExportPlan) FileName() string {
+	parts := []string{p.Book.Code}
+	if p.Options.Canto > 0 {
+		parts = append(parts, fmt.Sprint(p.Options.Canto))
+	}
+	if p.Options.Chapter > 0 {
+		parts = append(parts, fmt.Sprint(p.Options.Chapter))
+	}
+	parts = append(parts, p.Languages...)
+	return strings.Join(parts, "-") + "." + p.Options.Format
+}
+
+// exportVerseText is one language version of a verse
+type exportVerseText struct {
+	Language    string
+	Synonyms    string
+	Translation string
+	Purport     string
+}
+
+// exportVerse is a verse with its primary and, for bilingual exports, secondary text
+type exportVerse struct {
+	Reference       string
+	Devanagari      string
+	Transliteration string
+	Primary         exportVerseText
+	Secondary       *exportVerseText
+}
+
+// exportChapter is a chapter with resolved titles in the primary language
+type exportChapter struct {
+	Canto      int
+	Chapter    int
+	CantoTitle string
+	Title      string
+	NewCanto   bool // First chapter of its canto within the export
+}
+
+// libraryExportWriter renders an export incrementally, one chapter at a time
+type libraryExportWriter interface {
+	Begin(plan *LibraryExportPlan) error
+	Chapter(chapter exportChapter, verses []exportVerse) error
+	End() error
+}
+
+// LibraryExportService renders scripture books into downloadable formats
+type LibraryExportService struct {
+	db *gorm.DB
+}
+
+func NewLibraryExportService() *LibraryExportService {
+	return &LibraryExportService{db: database.DB}
+}
+
+// Prepare validates options and loads the book structure
+func (s *LibraryExportService) Prepare(opts LibraryExportOptions) (*LibraryExportPlan, error) {
+	opts.Format = strings.ToLower(strings.TrimSpace(opts.Format))
+	if opts.Format == "markdown" {
+		opts.Format = LibraryExportMarkdown
+	}
+	if _, ok := libraryExportContentTypes[opts.Format]; !ok {
+		return nil, ErrUnsupportedExportFormat
+	}
+	languages, err := libraryExportLanguages(opts.Language, opts.Bilingual)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Format == LibraryExportPDF {
+		if err := ensurePDFExportReady(); err != nil {
+			return nil, err
+		}
+	}
+
+	plan := &LibraryExportPlan{Options: opts, Languages: languages, Cantos: make(map[int]models.ScriptureCanto)}
+	if err := s.db.Where("code = ?", opts.BookCode).First(&plan.Book).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLibraryBookNotFound
+		}
+		return nil, err
+	}
+
+	var cantos []models.ScriptureCanto
+	if err := s.db.Where("book_code = ?", plan.Book.Code).Find(&cantos).Error; err != nil {
+		return nil, err
+	}
+	for _, canto := range cantos {
+		plan.Cantos[canto.Canto] = canto
+	}
+
+	chapters := s.db.Where("book_code = ?", plan.Book.Code)
+	if opts.Canto > 0 {
+		chapters = chapters.Where("canto = ?", opts.Canto)
+	}
+	if opts.Chapter > 0 {
+		chapters = chapters.Where("chapter = ?", opts.Chapter)
+	}
+	if err := chapters.Order("canto ASC, chapter ASC").Find(&plan.Chapters).Error; err != nil {
+		return nil, err
+	}
+	// Books still being parsed have verses but no structure rows
+	if len(plan.Chapters) == 0 {
+		query := s.db.Model(&models.ScriptureVerse{}).
+			Select("DISTINCT canto, chapter").
+			Where("book_code = ? AND language IN ?", plan.Book.Code, languages)
+		if opts.Canto > 0 {
+			query = query.Where("canto = ?", opts.Canto)
+		}
+		if opts.Chapter > 0 {
+			query = query.Where("chapter = ?", opts.Chapter)
+		}
+		if err := query.Order("canto ASC, chapter ASC").Scan(&plan.Chapters).Error; err != nil {
+			return nil, err
+		}
+	}
+	if len(plan.Chapters) == 0 {
+		return nil, ErrLibraryExportEmpty
+	}
+	return plan, nil
+}
+
+// libraryExportLanguages returns the export languages, primary first
+func libraryExportLanguages(language string, bilingual bool) ([]string, error) {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if language == "" {
+		language = "ru"
+	}
+	var other string
+	switch language {
+	case "ru":
+		other = "en"
+	case "en":
+		other = "ru"
+	default:
+		return nil, ErrUnsupportedExportLanguage
+	}
+	if bilingual {
+		return []string{language, other}, nil
+	}
+	return []string{language}, nil
+}
+
+// Write streams the export to w, loading verses one chapter at a time
+func (s *LibraryExportService) Write(w io.Writer, plan *LibraryExportPlan) error {
+	out, err := newLibraryExportWriter(w, plan.Options.Format)
+	if err != nil {
+		return err
+	}
+	if err := out.Begin(plan); err != nil {
+		return err
+	}
+	lastCanto := -1
+	for _, chapter := range plan.Chapters {
+		verses, err := s.chapterVerses(plan, chapter.Canto, chapter.Chapter)
+		if err != nil {
+			return err
+		}
+		if len(verses) == 0 {
+			continue
+		}
+		info := plan.chapterInfo(chapter)
+		info.NewCanto = chapter.Canto != lastCanto
+		lastCanto = chapter.Canto
+		if err := out.Chapter(info, verses); err != nil {
+			return err
+		}
+	}
+	return out.End()
+}
+
+func newLibraryExportWriter(w io.Writer, format string) (libraryExportWriter, error) {
+	switch format {
+	case LibraryExportMarkdown:
+		return newMarkdownExportWriter(w), nil
+	case LibraryExportText:
+		return newTextExportWriter(w), nil
+	case LibraryExportEPUB:
+		return newEPUBExportWriter(w), nil
+	case LibraryExportPDF:
+		return newPDFExportWriter(w)
+	default:
+		return nil, ErrUnsupportedExportFormat
+	}
+}
+
+func (p *LibraryExportPlan) chapterInfo(chapter models.ScriptureChapter) exportChapter {
+	primaryRu := p.Languages[0] == "ru"
+	info := exportChapter{
+		Canto:   chapter.Canto,
+		Chapter: chapter.Chapter,
+		Title:   pickTitle(primaryRu, chapter.TitleRu, chapter.TitleEn),
+	}
+	if canto, ok := p.Cantos[chapter.Canto]; ok {
+		info.CantoTitle = pickTitle(primaryRu, canto.TitleRu, canto.TitleEn)
+	}
+	return info
+}
+
+// BookTitle returns the book name in the primary language
+func (p *LibraryExportPlan) BookTitle() string {
+	return pickTitle(p.Languages[0] == "ru", p.Book.NameRu, p.Book.NameEn)
+}
+
+func pickTitle(preferRu bool, ru, en string) string {
+	if preferRu && strings.TrimSpace(ru) != "" {
+		return ru
+	}
+	if strings.TrimSpace(en) != "" {
+		return en
+	}
+	return ru
+}
+
+// chapterVerses loads the verses of one chapter and pairs secondary-language texts by verse number
+func (s *LibraryExportService) chapterVerses(plan *LibraryExportPlan, canto, chapter int) ([]exportVerse, error) {
+	var rows []models.ScriptureVerse
+	if err := s.db.Where("book_code = ? AND canto = ? AND chapter = ? AND language IN ?",
+		plan.Book.Code, canto, chapter, plan.Languages).
+		Order("id ASC").
+		Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	return pairExportVerses(rows, plan.Languages), nil
+}
+
+// pairExportVerses orders verses as in the primary language; verses that exist only
+// in the secondary language are appended so nothing is lost
+func pairExportVerses(rows []models.ScriptureVerse, languages []string) []exportVerse {
+	primary := languages[0]
+	verses := make([]exportVerse, 0, len(rows))
+	index := make(map[string]int, len(rows))
+	for _, row := range rows {
+		if row.Language != primary {
+			continue
+		}
+		index[row.Verse] = len(verses)
+		verses = append(verses, exportVerse{
+			Reference:       row.Verse,
+			Devanagari:      row.Devanagari,
+			Transliteration: row.Transliteration,
+			Primary:         verseText(row),
+		})
+	}
+	if len(languages) < 2 {
+		return verses
+	}
+	for _, row := range rows {
+		if row.Language != languages[1] {
+			continue
+		}
+		text := verseText(row)
+		if i, ok := index[row.Verse]; ok {
+			if verses[i].Secondary == nil {
+				verses[i].Secondary = &text
+			}
+			continue
+		}
+		index[row.Verse] = len(verses)
+		verses = append(verses, exportVerse{
+			Reference:       row.Verse,
+			Devanagari:      row.Devanagari,
+			Transliteration: row.Transliteration,
+			Primary:         exportVerseText{Language: primary},
+			Secondary:       &text,
+		})
+	}
+	return verses
+}
+
+func verseText(row models.ScriptureVerse) exportVerseText {
+	return exportVerseText{
+		Language:    row.Language,
+		Synonyms:    strings.TrimSpace(row.Synonyms),
+		Translation: strings.TrimSpace(row.Translation),
+		Purport:     strings.TrimSpace(row.Purport),
+	}
+}
+
+// verseLabel formats a verse reference such as "1.2.3" or "2.13"
+func verseLabel(canto, chapter int, verse string) string {
+	if canto > 0 {
+		return fmt.Sprintf("%d.%d.%s", canto, chapter, verse)
+	}
+	return fmt.Sprintf("%d.%s", chapter, verse)
+}
+
+// exportLabels are the section headings in the primary language
+type exportLabels struct {
+	Canto, Chapter, Text, Synonyms, Translation, Purport string
+}
+
+func labelsFor(language string) exportLabels {
+	if language == "ru" {
+		return exportLabels{Canto: "Песнь", Chapter: "Глава", Text: "Текст", Synonyms: "Пословный перевод", Translation: "Перевод", Purport: "Комментарий"}
+	}
+	return exportLabels{Canto: "Canto", Chapter: "Chapter", Text: "Text", Synonyms: "Synonyms", Translation: "Translation", Purport: "Purport"}
+}
+
+func chapterHeading(labels exportLabels, chapter exportChapter) string {
+	heading := fmt.Sprintf("%s %d", labels.Chapter, chapter.Chapter)
+	if chapter.Title != "" {
+		heading += ". " + chapter.Title
+	}
+	return heading
+}
+
+func cantoHeading(labels exportLabels, chapter exportChapter) string {
+	heading := fmt.Sprintf("%s %d", labels.Canto, chapter.Canto)
+	if chapter.CantoTitle != "" {
+		heading += ". " + chapter.CantoTitle
+	}
+	return heading
+}
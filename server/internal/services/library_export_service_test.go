@@ -0,0 +1,172 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"rag-agent-server/internal/models"
+	"strings"
+	"testing"
+)
+
+func TestLibraryExportLanguages(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		language  string
+		bilingual bool
+		want      []string
+		wantErr   error
+	}{
+		{name: "default", want: []string{"ru"}},
+		{name: "english", language: "EN", want: []string{"en"}},
+		{name: "bilingual ru first", language: "ru", bilingual: true, want: []string{"ru", "en"}},
+		{name: "bilingual en first", language: "en", bilingual: true, want: []string{"en", "ru"}},
+		{name: "unsupported", language: "de", wantErr: ErrUnsupportedExportLanguage},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := libraryExportLanguages(tc.language, tc.bilingual)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("expected %v, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil || strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Fatalf("got %v (%v), want %v", got, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestPairExportVerses(t *testing.T) {
+	t.Parallel()
+
+	rows := []models.ScriptureVerse{
+		{Verse: "1", Language: "ru", Translation: "Дхритараштра спросил"},
+		{Verse: "2", Language: "ru", Translation: "Санджая сказал"},
+		{Verse: "1", Language: "en", Translation: "Dhṛtarāṣṭra said"},
+		{Verse: "3", Language: "en", Translation: "Behold the army"},
+	}
+
+	verses := pairExportVerses(rows, []string{"ru", "en"})
+	if len(verses) != 3 {
+		t.Fatalf("expected 3 verses, got %d", len(verses))
+	}
+	if verses[0].Secondary == nil || verses[0].Secondary.Translation != "Dhṛtarāṣṭra said" {
+		t.Fatalf("expected verse 1 paired with english text, got %+v", verses[0].Secondary)
+	}
+	if verses[1].Secondary != nil {
+		t.Fatalf("expected verse 2 without english text, got %+v", verses[1].Secondary)
+	}
+	if verses[2].Reference != "3" || verses[2].Primary.Translation != "" || verses[2].Secondary == nil {
+		t.Fatalf("expected english-only verse 3 appended, got %+v", verses[2])
+	}
+
+	single := pairExportVerses(rows, []string{"en"})
+	if len(single) != 2 || single[0].Secondary != nil {
+		t.Fatalf("expected 2 english verses without pairing, got %+v", single)
+	}
+}
+
+func testExportPlan(format string, languages ...string) *LibraryExportPlan {
+	return &LibraryExportPlan{
+		Options:   LibraryExportOptions{BookCode: "bg", Format: format},
+		Book:      models.ScriptureBook{Code: "bg", NameEn: "Bhagavad Gita As It Is", NameRu: "Бхагавад-гита"},
+		Cantos:    map[int]models.ScriptureCanto{},
+		Chapters:  []models.ScriptureChapter{{BookCode: "bg", Chapter: 1, TitleRu: "Наблюдение за армиями"}},
+		Languages: languages,
+	}
+}
+
+func renderTestExport(t *testing.T, plan *LibraryExportPlan, verses []exportVerse) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	out, err := newLibraryExportWriter(&buf, plan.Options.Format)
+	if err != nil {
+		t.Fatalf("writer: %v", err)
+	}
+	if err := out.Begin(plan); err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	chapter := plan.chapterInfo(plan.Chapters[0])
+	chapter.NewCanto = true
+	if err := out.Chapter(chapter, verses); err != nil {
+		t.Fatalf("chapter: %v", err)
+	}
+	if err := out.End(); err != nil {
+		t.Fatalf("end: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestMarkdownExportBilingual(t *testing.T) {
+	t.Parallel()
+
+	plan := testExportPlan(LibraryExportMarkdown, "ru", "en")
+	verses := []exportVerse{{
+		Reference:       "1",
+		Transliteration: "dhṛtarāṣṭra uvāca",
+		Primary:         exportVerseText{Language: "ru", Translation: "Дхритараштра спросил"},
+		Secondary:       &exportVerseText{Language: "en", Translation: "Dhṛtarāṣṭra said"},
+	}}
+
+	out := string(renderTestExport(t, plan, verses))
+	for _, want := range []string{
+		"# Бхагавад-гита",
+		"## Глава 1. Наблюдение за армиями",
+		"### Текст 1.1",
+		"*dhṛtarāṣṭra uvāca*",
+		"**Перевод:** Дхритараштра спросил",
+		"**[EN]**",
+		"**Translation:** Dhṛtarāṣṭra said",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("markdown export missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestEPUBExportLayout(t *testing.T) {
+	t.Parallel()
+
+	plan := testExportPlan(LibraryExportEPUB, "en")
+	verses := []exportVerse{{Reference: "1", Primary: exportVerseText{Language: "en", Translation: "Dhṛtarāṣṭra said <&>"}}}
+
+	data := renderTestExport(t, plan, verses)
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("invalid zip: %v", err)
+	}
+	if len(archive.File) == 0 || archive.File[0].Name != "mimetype" || archive.File[0].Method != zip.Store {
+		t.Fatalf("mimetype must be the first stored entry")
+	}
+
+	names := make(map[string]*zip.File)
+	for _, f := range archive.File {
+		names[f.Name] = f
+	}
+	for _, want := range []string{"META-INF/container.xml", "OEBPS/content.opf", "OEBPS/nav.xhtml", "OEBPS/ch-0-1.xhtml"} {
+		if names[want] == nil {
+			t.Fatalf("missing %s in epub", want)
+		}
+	}
+
+	rc, err := names["OEBPS/ch-0-1.xhtml"].Open()
+	if err != nil {
+		t.Fatalf("open chapter: %v", err)
+	}
+	defer rc.Close()
+	var chapter bytes.Buffer
+	if _, err := chapter.ReadFrom(rc); err != nil {
+		t.Fatalf("read chapter: %v", err)
+	}
+	if !strings.Contains(chapter.String(), "Dhṛtarāṣṭra said &lt;&amp;&gt;") {
+		t.Fatalf("chapter text not escaped:\n%s", chapter.String())
+	}
+}
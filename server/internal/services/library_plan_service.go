@@ -0,0 +1,773 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"rag-agent-server/internal/database"
+	"rag-agent-server/internal/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrReadingPlanNotFound     = errors.New("reading plan not found")
+	ErrInvalidReadingPlan      = errors.New("invalid reading plan")
+	ErrReadingPlanEmpty        = errors.New("no verses in the selected range")
+	ErrPlanEnrollmentNotFound  = errors.New("reading plan enrollment not found")
+	ErrAlreadyEnrolled         = errors.New("already enrolled in this reading plan")
+	ErrInvalidPlanDay          = errors.New("invalid reading plan day")
+	ErrInvalidReminderHour     = errors.New("reminder hour must be between 0 and 23")
+	ErrPlanEnrollmentNotActive = errors.New("reading plan enrollment is not active")
+)
+
+const (
+	defaultReadingPlanTimezone = "Europe/Moscow"
+	readingPlanDateLayout      = "2006-01-02"
+	maxReadingPlanDurationDays = 3650
+)
+
+type LibraryPlanService struct {
+	db *gorm.DB
+}
+
+func NewLibraryPlanService() *LibraryPlanService {
+	return &LibraryPlanService{db: database.DB}
+}
+
+type ReadingPlanInput struct {
+	BookCode      string `json:"book_code"`
+	TitleEn       string `json:"title_en"`
+	TitleRu       string `json:"title_ru"`
+	DescriptionEn string `json:"description_en"`
+	DescriptionRu string `json:"description_ru"`
+	FromCanto     int    `json:"from_canto"`
+	FromChapter   int    `json:"from_chapter"`
+	ToCanto       int    `json:"to_canto"`
+	ToChapter     int    `json:"to_chapter"`
+	DurationDays  int    `json:"duration_days"`
+	IsPublic      bool   `json:"is_public"`
+}
+
+// ReadingPlanSegment is a contiguous verse range of one chapter. FromVerse/ToVerse are
+// empty when the library has no verses for the chapter and the whole chapter is assigned.
+type ReadingPlanSegment struct {
+	Canto      int    `json:"canto"`
+	Chapter    int    `json:"chapter"`
+	FromVerse  string `json:"from_verse,omitempty"`
+	ToVerse    string `json:"to_verse,omitempty"`
+	VerseCount int    `json:"verse_count"`
+}
+
+type ReadingPlanDay struct {
+	DayNumber int                  `json:"day_number"`
+	Date      string               `json:"date,omitempty"`
+	Segments  []ReadingPlanSegment `json:"segments"`
+	Completed bool                 `json:"completed"`
+}
+
+type ReadingPlanDetails struct {
+	Plan models.ScriptureReadingPlan `json:"plan"`
+	Days []ReadingPlanDay            `json:"days"`
+}
+
+// ReadingPlanToday is the assignment for the user's current local date
+type ReadingPlanToday struct {
+	Enrollment  models.ScriptureReadingPlanEnrollment `json:"enrollment"`
+	Date        string                                `json:"date"`
+	DayNumber   int                                   `json:"day_number"` // 0 before the start date
+	Day         *ReadingPlanDay                       `json:"day,omitempty"`
+	NextUnread  int                                   `json:"next_unread_day"` // 0 when every day is read
+	MissedDays  int                                   `json:"missed_days"`
+	ReadToday   bool                                  `json:"read_today"`
+	Finished    bool                                  `json:"finished"` // Calendar end of the plan has passed
+	CompletedAt *time.Time                            `json:"completed_at,omitempty"`
+}
+
+// ReadingPlanReminder is a claimed reminder ready to be pushed
+type ReadingPlanReminder struct {
+	UserID       uint
+	EnrollmentID uint
+	PlanTitle    string
+	DayNumber    int
+}
+
+type planUnit struct {
+	Canto   int
+	Chapter int
+	Verse   string
+}
+
+func (s *LibraryPlanService) CreatePlan(userID uint, input ReadingPlanInput) (*models.ScriptureReadingPlan, error) {
+	plan := models.ScriptureReadingPlan{
+		BookCode:      strings.ToLower(strings.TrimSpace(input.BookCode)),
+		TitleEn:       truncateText(strings.TrimSpace(input.TitleEn), 255),
+		TitleRu:       truncateText(strings.TrimSpace(input.TitleRu), 255),
+		DescriptionEn: strings.TrimSpace(input.DescriptionEn),
+		DescriptionRu: strings.TrimSpace(input.DescriptionRu),
+		FromCanto:     input.FromCanto,
+		FromChapter:   input.FromChapter,
+		ToCanto:       input.ToCanto,
+		ToChapter:     input.ToChapter,
+		DurationDays:  input.DurationDays,
+		IsPublic:      input.IsPublic,
+		CreatedByID:   userID,
+	}
+	if plan.BookCode == "" || (plan.TitleEn == "" && plan.TitleRu == "") {
+		return nil, fmt.Errorf("%w: book_code and a title are required", ErrInvalidReadingPlan)
+	}
+	if plan.DurationDays < 1 || plan.DurationDays > maxReadingPlanDurationDays {
+		return nil, fmt.Errorf("%w: duration_days must be between 1 and %d", ErrInvalidReadingPlan, maxReadingPlanDurationDays)
+	}
+	if plan.FromCanto < 0 || plan.ToCanto < 0 || plan.FromChapter < 0 || plan.ToChapter < 0 {
+		return nil, fmt.Errorf("%w: negative canto or chapter", ErrInvalidReadingPlan)
+	}
+
+	var book models.ScriptureBook
+	if err := s.db.Where("code = ?", plan.BookCode).First(&book).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLibraryBookNotFound
+		}
+		return nil, err
+	}
+	if err := s.fillPlanRange(&plan); err != nil {
+		return nil, err
+	}
+
+	units, err := s.planUnits(&plan)
+	if err != nil {
+		return nil, err
+	}
+	if len(units) == 0 {
+		return nil, ErrReadingPlanEmpty
+	}
+	if plan.DurationDays > len(units) {
+		return nil, fmt.Errorf("%w: the range has only %d readings, shorten duration_days", ErrInvalidReadingPlan, len(units))
+	}
+
+	if err := s.db.Create(&plan).Error; err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// fillPlanRange defaults an open range to the first/last chapter of the book and checks its order
+func (s *LibraryPlanService) fillPlanRange(plan *models.ScriptureReadingPlan) error {
+	if plan.FromChapter == 0 || plan.ToChapter == 0 {
+		var chapters []models.ScriptureChapter
+		if err := s.db.Where("book_code = ?", plan.BookCode).Order("canto ASC, chapter ASC").Find(&chapters).Error; err != nil {
+			return err
+		}
+		if len(chapters) == 0 {
+			return ErrReadingPlanEmpty
+		}
+		if plan.FromChapter == 0 {
+			plan.FromCanto, plan.FromChapter = chapters[0].Canto, chapters[0].Chapter
+		}
+		if plan.ToChapter == 0 {
+			last := chapters[len(chapters)-1]
+			plan.ToCanto, plan.ToChapter = last.Canto, last.Chapter
+		}
+	}
+	if plan.FromCanto > plan.ToCanto || (plan.FromCanto == plan.ToCanto && plan.FromChapter > plan.ToChapter) {
+		return fmt.Errorf("%w: range start is after its end", ErrInvalidReadingPlan)
+	}
+	return nil
+}
+
+// ListPlans returns public plans and the caller's own plans
+func (s *LibraryPlanService) ListPlans(userID uint, bookCode string) ([]models.ScriptureReadingPlan, error) {
+	query := s.db.Where("is_public = ? OR created_by_id = ?", true, userID)
+	if code := strings.ToLower(strings.TrimSpace(bookCode)); code != "" {
+		query = query.Where("book_code = ?", code)
+	}
+	var plans []models.ScriptureReadingPlan
+	err := query.Order("is_public DESC, duration_days ASC, id ASC").Find(&plans).Error
+	return plans, err
+}
+
+func (s *LibraryPlanService) visiblePlan(userID, planID uint) (*models.ScriptureReadingPlan, error) {
+	var plan models.ScriptureReadingPlan
+	err := s.db.Where("id = ? AND (is_public = ? OR created_by_id = ?)", planID, true, userID).First(&plan).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReadingPlanNotFound
+		}
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// GetPlan returns the plan with its full day-by-day schedule
+func (s *LibraryPlanService) GetPlan(userID, planID uint) (*ReadingPlanDetails, error) {
+	plan, err := s.visiblePlan(userID, planID)
+	if err != nil {
+		return nil, err
+	}
+	days, err := s.planDays(plan)
+	if err != nil {
+		return nil, err
+	}
+	return &ReadingPlanDetails{Plan: *plan, Days: days}, nil
+}
+
+// DeletePlan removes a plan owned by the caller; admins may delete any plan
+func (s *LibraryPlanService) DeletePlan(userID, planID uint, isAdmin bool) error {
+	query := s.db.Where("id = ?", planID)
+	if !isAdmin {
+		query = query.Where("created_by_id = ?", userID)
+	}
+	result := query.Delete(&models.ScriptureReadingPlan{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrReadingPlanNotFound
+	}
+	return nil
+}
+
+// planUnits lists the readable verses of the plan range in book order. Verses are taken
+// across languages so that a plan covers the same references for every reader; chapters
+// without imported verses count as a single unit.
+func (s *LibraryPlanService) planUnits(plan *models.ScriptureReadingPlan) ([]planUnit, error) {
+	var chapters []models.ScriptureChapter
+	if err := s.db.
+		Where("book_code = ? AND (canto, chapter) >= (?, ?) AND (canto, chapter) <= (?, ?)",
+			plan.BookCode, plan.FromCanto, plan.FromChapter, plan.ToCanto, plan.ToChapter).
+		Order("canto ASC, chapter ASC").
+		Find(&chapters).Error; err != nil {
+		return nil, err
+	}
+
+	var verses []planUnit
+	if err := s.db.Model(&models.ScriptureVerse{}).
+		Select("canto, chapter, verse").
+		Where("book_code = ? AND (canto, chapter) >= (?, ?) AND (canto, chapter) <= (?, ?)",
+			plan.BookCode, plan.FromCanto, plan.FromChapter, plan.ToCanto, plan.ToChapter).
+		Group("canto, chapter, verse").
+		Order("canto ASC, chapter ASC, MIN(id) ASC").
+		Scan(&verses).Error; err != nil {
+		return nil, err
+	}
+	return mergePlanUnits(chapters, verses), nil
+}
+
+// mergePlanUnits inserts whole-chapter units for chapters that have no verses yet
+func mergePlanUnits(chapters []models.ScriptureChapter, verses []planUnit) []planUnit {
+	withVerses := make(map[[2]int]bool, len(chapters))
+	for _, v := range verses {
+		withVerses[[2]int{v.Canto, v.Chapter}] = true
+	}
+	units := make([]planUnit, 0, len(verses)+len(chapters))
+	i := 0
+	for _, ch := range chapters {
+		for i < len(verses) && planUnitBefore(verses[i], ch.Canto, ch.Chapter) {
+			units = append(units, verses[i])
+			i++
+		}
+		if !withVerses[[2]int{ch.Canto, ch.Chapter}] {
+			units = append(units, planUnit{Canto: ch.Canto, Chapter: ch.Chapter})
+		}
+	}
+	return append(units, verses[i:]...)
+}
+
+func planUnitBefore(u planUnit, canto, chapter int) bool {
+	return u.Canto < canto || (u.Canto == canto && u.Chapter < chapter)
+}
+
+// splitPlanDays spreads units evenly over the plan; earlier days never get more than one extra unit
+func splitPlanDays(units []planUnit, days int) [][]planUnit {
+	if days < 1 {
+		return nil
+	}
+	out := make([][]planUnit, days)
+	for d := 0; d < days; d++ {
+		out[d] = units[d*len(units)/days : (d+1)*len(units)/days]
+	}
+	return out
+}
+
+func planSegments(units []planUnit) []ReadingPlanSegment {
+	segments := make([]ReadingPlanSegment, 0, 2)
+	for _, u := range units {
+		if n := len(segments); n > 0 && u.Verse != "" && segments[n-1].FromVerse != "" &&
+			segments[n-1].Canto == u.Canto && segments[n-1].Chapter == u.Chapter {
+			segments[n-1].ToVerse = u.Verse
+			segments[n-1].VerseCount++
+			continue
+		}
+		segments = append(segments, ReadingPlanSegment{
+			Canto:      u.Canto,
+			Chapter:    u.Chapter,
+			FromVerse:  u.Verse,
+			ToVerse:    u.Verse,
+			VerseCount: boolToInt(u.Verse != ""),
+		})
+	}
+	return segments
+}
+
+func boolToInt(v bool) int {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+func (s *LibraryPlanService) planDays(plan *models.ScriptureReadingPlan) ([]ReadingPlanDay, error) {
+	units, err := s.planUnits(plan)
+	if err != nil {
+		return nil, err
+	}
+	split := splitPlanDays(units, plan.DurationDays)
+	days := make([]ReadingPlanDay, len(split))
+	for i, chunk := range split {
+		days[i] = ReadingPlanDay{DayNumber: i + 1, Segments: planSegments(chunk)}
+	}
+	return days, nil
+}
+
+// ==================== Enrollment ====================
+
+func validReminderHour(hour *int) error {
+	if hour != nil && (*hour < 0 || *hour > 23) {
+		return ErrInvalidReminderHour
+	}
+	return nil
+}
+
+// userLocation resolves User.Timezone, falling back to the platform default zone
+func userLocation(timezone string) *time.Location {
+	if tz := strings.TrimSpace(timezone); tz != "" {
+		if loc, err := time.LoadLocation(tz); err == nil {
+			return loc
+		}
+	}
+	loc, err := time.LoadLocation(defaultReadingPlanTimezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+func (s *LibraryPlanService) userLocation(userID uint) (*time.Location, error) {
+	var timezone string
+	if err := s.db.Model(&models.User{}).Where("id = ?", userID).Pluck("timezone", &timezone).Error; err != nil {
+		return nil, err
+	}
+	return userLocation(timezone), nil
+}
+
+// planDayNumber is the 1-based plan day of a local date; 0 or less before the start date
+func planDayNumber(startDate, today string) int {
+	start, err := time.Parse(readingPlanDateLayout, startDate)
+	if err != nil {
+		return 0
+	}
+	day, err := time.Parse(readingPlanDateLayout, today)
+	if err != nil {
+		return 0
+	}
+	return int(day.Sub(start).Hours()/24) + 1
+}
+
+func shiftLocalDate(date string, days int) string {
+	t, err := time.Parse(readingPlanDateLayout, date)
+	if err != nil {
+		return ""
+	}
+	return t.AddDate(0, 0, days).Format(readingPlanDateLayout)
+}
+
+// applyReadingStreak counts consecutive local dates with at least one completed day
+func applyReadingStreak(e *models.ScriptureReadingPlanEnrollment, today string) {
+	switch e.LastReadDate {
+	case today:
+		return
+	case shiftLocalDate(today, -1):
+		e.CurrentStreak++
+	default:
+		e.CurrentStreak = 1
+	}
+	e.LastReadDate = today
+	if e.CurrentStreak > e.LongestStreak {
+		e.LongestStreak = e.CurrentStreak
+	}
+}
+
+// visibleStreak drops a streak that was broken by a missed day but not yet reset by a new completion
+func visibleStreak(e *models.ScriptureReadingPlanEnrollment, today string) {
+	if e.LastReadDate != today && e.LastReadDate != shiftLocalDate(today, -1) {
+		e.CurrentStreak = 0
+	}
+}
+
+func (s *LibraryPlanService) Enroll(userID, planID uint, startDate string, reminderHour *int) (*models.ScriptureReadingPlanEnrollment, error) {
+	if err := validReminderHour(reminderHour); err != nil {
+		return nil, err
+	}
+	plan, err := s.visiblePlan(userID, planID)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := s.userLocation(userID)
+	if err != nil {
+		return nil, err
+	}
+	today := time.Now().In(loc).Format(readingPlanDateLayout)
+	if startDate = strings.TrimSpace(startDate); startDate == "" {
+		startDate = today
+	} else if _, err := time.Parse(readingPlanDateLayout, startDate); err != nil {
+		return nil, fmt.Errorf("%w: start_date must be YYYY-MM-DD", ErrInvalidPlanDay)
+	}
+
+	enrollment := models.ScriptureReadingPlanEnrollment{
+		UserID:       userID,
+		PlanID:       plan.ID,
+		Status:       models.ReadingPlanEnrollmentActive,
+		StartDate:    startDate,
+		ReminderHour: reminderHour,
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.ScriptureReadingPlanEnrollment{}).
+			Where("user_id = ? AND plan_id = ? AND status = ?", userID, plan.ID, models.ReadingPlanEnrollmentActive).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrAlreadyEnrolled
+		}
+		return tx.Create(&enrollment).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) || strings.Contains(err.Error(), "idx_reading_plan_enrollment_active") {
+			return nil, ErrAlreadyEnrolled
+		}
+		return nil, err
+	}
+	enrollment.Plan = *plan
+	return &enrollment, nil
+}
+
+func (s *LibraryPlanService) enrollment(userID, enrollmentID uint) (*models.ScriptureReadingPlanEnrollment, error) {
+	var enrollment models.ScriptureReadingPlanEnrollment
+	err := s.db.Preload("Plan", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Where("id = ? AND user_id = ?", enrollmentID, userID).
+		First(&enrollment).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPlanEnrollmentNotFound
+		}
+		return nil, err
+	}
+	return &enrollment, nil
+}
+
+// ListEnrollments returns the user's enrollments, newest first; status filters when set
+func (s *LibraryPlanService) ListEnrollments(userID uint, status string) ([]models.ScriptureReadingPlanEnrollment, error) {
+	query := s.db.Preload("Plan", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).Where("user_id = ?", userID)
+	if status = strings.TrimSpace(status); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var enrollments []models.ScriptureReadingPlanEnrollment
+	if err := query.Order("created_at DESC").Find(&enrollments).Error; err != nil {
+		return nil, err
+	}
+	loc, err := s.userLocation(userID)
+	if err != nil {
+		return nil, err
+	}
+	today := time.Now().In(loc).Format(readingPlanDateLayout)
+	for i := range enrollments {
+		visibleStreak(&enrollments[i], today)
+	}
+	return enrollments, nil
+}
+
+func (s *LibraryPlanService) completedDays(enrollmentID uint) (map[int]bool, error) {
+	var numbers []int
+	if err := s.db.Model(&models.ScriptureReadingPlanCompletion{}).
+		Where("enrollment_id = ?", enrollmentID).
+		Pluck("day_number", &numbers).Error; err != nil {
+		return nil, err
+	}
+	done := make(map[int]bool, len(numbers))
+	for _, n := range numbers {
+		done[n] = true
+	}
+	return done, nil
+}
+
+// Today returns the assignment for the user's local date along with catch-up information
+func (s *LibraryPlanService) Today(userID, enrollmentID uint) (*ReadingPlanToday, error) {
+	enrollment, err := s.enrollment(userID, enrollmentID)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := s.userLocation(userID)
+	if err != nil {
+		return nil, err
+	}
+	days, err := s.planDays(&enrollment.Plan)
+	if err != nil {
+		return nil, err
+	}
+	done, err := s.completedDays(enrollment.ID)
+	if err != nil {
+		return nil, err
+	}
+	return buildReadingPlanToday(enrollment, days, done, time.Now().In(loc).Format(readingPlanDateLayout)), nil
+}
+
+func buildReadingPlanToday(enrollment *models.ScriptureReadingPlanEnrollment, days []ReadingPlanDay, done map[int]bool, today string) *ReadingPlanToday {
+	visibleStreak(enrollment, today)
+	out := &ReadingPlanToday{
+		Enrollment:  *enrollment,
+		Date:        today,
+		ReadToday:   enrollment.LastReadDate == today,
+		CompletedAt: enrollment.CompletedAt,
+	}
+
+	dayNumber := planDayNumber(enrollment.StartDate, today)
+	if dayNumber > len(days) {
+		out.Finished = true
+	}
+	due := dayNumber
+	if due > len(days) {
+		due = len(days)
+	}
+	for n := 1; n <= len(days); n++ {
+		if done[n] {
+			continue
+		}
+		if out.NextUnread == 0 {
+			out.NextUnread = n
+		}
+		if n < due || (n == due && out.Finished) {
+			out.MissedDays++
+		}
+	}
+
+	if dayNumber >= 1 && dayNumber <= len(days) {
+		day := days[dayNumber-1]
+		day.Date = today
+		day.Completed = done[dayNumber]
+		out.DayNumber = dayNumber
+		out.Day = &day
+	}
+	return out
+}
+
+// Day returns one plan day with its calendar date for this enrollment
+func (s *LibraryPlanService) Day(userID, enrollmentID uint, dayNumber int) (*ReadingPlanDay, error) {
+	enrollment, err := s.enrollment(userID, enrollmentID)
+	if err != nil {
+		return nil, err
+	}
+	if dayNumber < 1 || dayNumber > enrollment.Plan.DurationDays {
+		return nil, ErrInvalidPlanDay
+	}
+	days, err := s.planDays(&enrollment.Plan)
+	if err != nil {
+		return nil, err
+	}
+	if dayNumber > len(days) {
+		return nil, ErrInvalidPlanDay
+	}
+	done, err := s.completedDays(enrollment.ID)
+	if err != nil {
+		return nil, err
+	}
+	day := days[dayNumber-1]
+	day.Date = shiftLocalDate(enrollment.StartDate, dayNumber-1)
+	day.Completed = done[dayNumber]
+	return &day, nil
+}
+
+// CompleteDay marks a plan day as read. Repeating the call for the same day is a no-op;
+// days may be read ahead of or behind the calendar, but the streak follows local dates.
+func (s *LibraryPlanService) CompleteDay(userID, enrollmentID uint, dayNumber int) (*models.ScriptureReadingPlanEnrollment, error) {
+	loc, err := s.userLocation(userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	today := now.In(loc).Format(readingPlanDateLayout)
+
+	var enrollment models.ScriptureReadingPlanEnrollment
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND user_id = ?", enrollmentID, userID).
+			First(&enrollment).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrPlanEnrollmentNotFound
+			}
+			return err
+		}
+		if enrollment.Status == models.ReadingPlanEnrollmentLeft {
+			return ErrPlanEnrollmentNotActive
+		}
+		var plan models.ScriptureReadingPlan
+		if err := tx.Unscoped().First(&plan, enrollment.PlanID).Error; err != nil {
+			return err
+		}
+		if dayNumber < 1 || dayNumber > plan.DurationDays {
+			return ErrInvalidPlanDay
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.ScriptureReadingPlanCompletion{
+			EnrollmentID: enrollment.ID,
+			DayNumber:    dayNumber,
+			LocalDate:    today,
+			CompletedAt:  now,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			enrollment.Plan = plan
+			return nil
+		}
+
+		enrollment.CompletedDays++
+		applyReadingStreak(&enrollment, today)
+		if enrollment.CompletedDays >= plan.DurationDays && enrollment.Status == models.ReadingPlanEnrollmentActive {
+			enrollment.Status = models.ReadingPlanEnrollmentCompleted
+			enrollment.CompletedAt = &now
+		}
+		if err := tx.Model(&enrollment).Select("completed_days", "current_streak", "longest_streak", "last_read_date", "status", "completed_at").
+			Updates(&enrollment).Error; err != nil {
+			return err
+		}
+		enrollment.Plan = plan
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	visibleStreak(&enrollment, today)
+	return &enrollment, nil
+}
+
+// UpdateReminder changes the reminder hour; nil turns reminders off
+func (s *LibraryPlanService) UpdateReminder(userID, enrollmentID uint, hour *int) (*models.ScriptureReadingPlanEnrollment, error) {
+	if err := validReminderHour(hour); err != nil {
+		return nil, err
+	}
+	enrollment, err := s.enrollment(userID, enrollmentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(enrollment).Update("reminder_hour", hour).Error; err != nil {
+		return nil, err
+	}
+	enrollment.ReminderHour = hour
+	return enrollment, nil
+}
+
+// Leave stops an active enrollment; progress is kept for history
+func (s *LibraryPlanService) Leave(userID, enrollmentID uint) error {
+	result := s.db.Model(&models.ScriptureReadingPlanEnrollment{}).
+		Where("id = ? AND user_id = ? AND status = ?", enrollmentID, userID, models.ReadingPlanEnrollmentActive).
+		Update("status", models.ReadingPlanEnrollmentLeft)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if _, err := s.enrollment(userID, enrollmentID); err != nil {
+			return err
+		}
+		return ErrPlanEnrollmentNotActive
+	}
+	return nil
+}
+
+// ==================== Reminders ====================
+
+type reminderCandidate struct {
+	ID               uint
+	UserID           uint
+	PlanID           uint
+	StartDate        string
+	ReminderHour     *int
+	LastReadDate     string
+	LastReminderDate string
+	Timezone         string
+	Language         string
+}
+
+// ClaimDueReminders picks active enrollments whose reminder hour has passed in the user's
+// timezone and that have nothing read today. Each enrollment is claimed at most once per
+// local date, so a worker tick that runs late still sends the reminder.
+func (s *LibraryPlanService) ClaimDueReminders(now time.Time) ([]ReadingPlanReminder, error) {
+	var candidates []reminderCandidate
+	if err := s.db.Table("scripture_reading_plan_enrollments AS e").
+		Select("e.id, e.user_id, e.plan_id, e.start_date, e.reminder_hour, e.last_read_date, e.last_reminder_date, u.timezone, u.language").
+		Joins("JOIN users u ON u.id = e.user_id AND u.deleted_at IS NULL").
+		Where("e.status = ? AND e.reminder_hour IS NOT NULL", models.ReadingPlanEnrollmentActive).
+		Order("e.id ASC").
+		Scan(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	planIDs := make([]uint, 0, len(candidates))
+	for _, c := range candidates {
+		planIDs = append(planIDs, c.PlanID)
+	}
+	plans := make(map[uint]models.ScriptureReadingPlan)
+	if len(planIDs) > 0 {
+		var rows []models.ScriptureReadingPlan
+		if err := s.db.Unscoped().Where("id IN ?", uniqueIDs(planIDs)).Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, p := range rows {
+			plans[p.ID] = p
+		}
+	}
+
+	reminders := make([]ReadingPlanReminder, 0)
+	for _, c := range candidates {
+		local := now.In(userLocation(c.Timezone))
+		today := local.Format(readingPlanDateLayout)
+		if !reminderDue(c, local.Hour(), today) {
+			continue
+		}
+		plan, ok := plans[c.PlanID]
+		if !ok {
+			continue
+		}
+		dayNumber := planDayNumber(c.StartDate, today)
+		if dayNumber < 1 || dayNumber > plan.DurationDays {
+			continue
+		}
+
+		claim := s.db.Model(&models.ScriptureReadingPlanEnrollment{}).
+			Where("id = ? AND (last_reminder_date IS NULL OR last_reminder_date <> ?)", c.ID, today).
+			Update("last_reminder_date", today)
+		if claim.Error != nil {
+			return reminders, claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			continue
+		}
+		reminders = append(reminders, ReadingPlanReminder{
+			UserID:       c.UserID,
+			EnrollmentID: c.ID,
+			PlanTitle:    pickTitle(strings.EqualFold(c.Language, "ru"), plan.TitleRu, plan.TitleEn),
+			DayNumber:    dayNumber,
+		})
+	}
+	return reminders, nil
+}
+
+func reminderDue(c reminderCandidate, localHour int, today string) bool {
+	if c.ReminderHour == nil || localHour < *c.ReminderHour {
+		return false
+	}
+	return c.LastReminderDate != today && c.LastReadDate != today
+}
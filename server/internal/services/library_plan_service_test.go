@@ -0,0 +1,160 @@
+package services
+
+import (
+	"rag-agent-server/internal/models"
+	"testing"
+	"time"
+)
+
+func TestSplitPlanDays(t *testing.T) {
+	t.Parallel()
+
+	units := make([]planUnit, 10)
+	for i := range units {
+		units[i] = planUnit{Chapter: 1, Verse: string(rune('a' + i))}
+	}
+
+	days := splitPlanDays(units, 3)
+	if len(days) != 3 {
+		t.Fatalf("expected 3 days, got %d", len(days))
+	}
+	total := 0
+	for i, day := range days {
+		if len(day) < 3 || len(day) > 4 {
+			t.Fatalf("day %d has %d units, want 3-4", i+1, len(day))
+		}
+		total += len(day)
+	}
+	if total != len(units) {
+		t.Fatalf("expected all %d units assigned, got %d", len(units), total)
+	}
+	if days[2][len(days[2])-1].Verse != "j" {
+		t.Fatalf("last day must end with the last unit")
+	}
+}
+
+func TestPlanSegments(t *testing.T) {
+	t.Parallel()
+
+	segments := planSegments([]planUnit{
+		{Canto: 1, Chapter: 1, Verse: "22"},
+		{Canto: 1, Chapter: 1, Verse: "23"},
+		{Canto: 1, Chapter: 2},
+		{Canto: 1, Chapter: 3, Verse: "1"},
+	})
+	if len(segments) != 3 {
+		t.Fatalf("expected 3 segments, got %+v", segments)
+	}
+	if segments[0].FromVerse != "22" || segments[0].ToVerse != "23" || segments[0].VerseCount != 2 {
+		t.Fatalf("unexpected first segment %+v", segments[0])
+	}
+	if segments[1].Chapter != 2 || segments[1].FromVerse != "" || segments[1].VerseCount != 0 {
+		t.Fatalf("expected whole-chapter segment, got %+v", segments[1])
+	}
+}
+
+func TestMergePlanUnits(t *testing.T) {
+	t.Parallel()
+
+	chapters := []models.ScriptureChapter{{Chapter: 1}, {Chapter: 2}, {Chapter: 3}}
+	verses := []planUnit{{Chapter: 1, Verse: "1"}, {Chapter: 3, Verse: "1"}, {Chapter: 3, Verse: "2"}}
+
+	units := mergePlanUnits(chapters, verses)
+	want := []planUnit{{Chapter: 1, Verse: "1"}, {Chapter: 2}, {Chapter: 3, Verse: "1"}, {Chapter: 3, Verse: "2"}}
+	if len(units) != len(want) {
+		t.Fatalf("got %+v, want %+v", units, want)
+	}
+	for i := range want {
+		if units[i] != want[i] {
+			t.Fatalf("unit %d: got %+v, want %+v", i, units[i], want[i])
+		}
+	}
+}
+
+func TestApplyReadingStreak(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		lastRead    string
+		streak      int
+		longest     int
+		wantStreak  int
+		wantLongest int
+	}{
+		{name: "first read", wantStreak: 1, wantLongest: 1},
+		{name: "consecutive day", lastRead: "2026-03-09", streak: 4, longest: 4, wantStreak: 5, wantLongest: 5},
+		{name: "same day", lastRead: "2026-03-10", streak: 4, longest: 6, wantStreak: 4, wantLongest: 6},
+		{name: "gap resets", lastRead: "2026-03-07", streak: 4, longest: 6, wantStreak: 1, wantLongest: 6},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			e := models.ScriptureReadingPlanEnrollment{LastReadDate: tc.lastRead, CurrentStreak: tc.streak, LongestStreak: tc.longest}
+			applyReadingStreak(&e, "2026-03-10")
+			if e.CurrentStreak != tc.wantStreak || e.LongestStreak != tc.wantLongest || e.LastReadDate != "2026-03-10" {
+				t.Fatalf("got streak %d longest %d last %s", e.CurrentStreak, e.LongestStreak, e.LastReadDate)
+			}
+		})
+	}
+}
+
+func TestBuildReadingPlanToday(t *testing.T) {
+	t.Parallel()
+
+	days := []ReadingPlanDay{{DayNumber: 1}, {DayNumber: 2}, {DayNumber: 3}, {DayNumber: 4}}
+	enrollment := &models.ScriptureReadingPlanEnrollment{StartDate: "2026-03-01", LastReadDate: "2026-03-01", CurrentStreak: 1}
+
+	today := buildReadingPlanToday(enrollment, days, map[int]bool{1: true}, "2026-03-03")
+	if today.DayNumber != 3 || today.Day == nil || today.Day.Date != "2026-03-03" {
+		t.Fatalf("expected day 3 for 2026-03-03, got %+v", today)
+	}
+	if today.NextUnread != 2 || today.MissedDays != 1 || today.ReadToday {
+		t.Fatalf("expected day 2 missed, got next %d missed %d", today.NextUnread, today.MissedDays)
+	}
+	if today.Enrollment.CurrentStreak != 0 {
+		t.Fatalf("expected broken streak to show as 0, got %d", today.Enrollment.CurrentStreak)
+	}
+
+	before := buildReadingPlanToday(enrollment, days, nil, "2026-02-27")
+	if before.DayNumber != 0 || before.Day != nil || before.MissedDays != 0 {
+		t.Fatalf("expected no assignment before start, got %+v", before)
+	}
+
+	after := buildReadingPlanToday(enrollment, days, map[int]bool{1: true, 2: true}, "2026-03-10")
+	if !after.Finished || after.Day != nil || after.MissedDays != 2 {
+		t.Fatalf("expected finished plan with 2 missed days, got %+v", after)
+	}
+}
+
+func TestReminderDueUsesLocalDate(t *testing.T) {
+	t.Parallel()
+
+	hour := 7
+	// 2026-03-10 02:30 UTC is 09:30 in Vladivostok and 21:30 of the previous day in New York
+	now := time.Date(2026, 3, 10, 2, 30, 0, 0, time.UTC)
+
+	vlad := now.In(userLocation("Asia/Vladivostok"))
+	if !reminderDue(reminderCandidate{ReminderHour: &hour}, vlad.Hour(), vlad.Format(readingPlanDateLayout)) {
+		t.Fatalf("expected reminder due after 07:00 local time")
+	}
+	if reminderDue(reminderCandidate{ReminderHour: &hour, LastReadDate: "2026-03-10"}, vlad.Hour(), "2026-03-10") {
+		t.Fatalf("no reminder once today's reading is done")
+	}
+	if reminderDue(reminderCandidate{ReminderHour: &hour, LastReminderDate: "2026-03-10"}, vlad.Hour(), "2026-03-10") {
+		t.Fatalf("reminder must be sent once per local date")
+	}
+
+	ny := now.In(userLocation("America/New_York"))
+	if ny.Format(readingPlanDateLayout) != "2026-03-09" {
+		t.Fatalf("expected previous local date in New York, got %s", ny.Format(readingPlanDateLayout))
+	}
+	if reminderDue(reminderCandidate{ReminderHour: &hour}, 5, "2026-03-10") {
+		t.Fatalf("reminder must wait for the chosen hour")
+	}
+	if userLocation("Not/AZone").String() != defaultReadingPlanTimezone {
+		t.Fatalf("invalid timezone should fall back to %s", defaultReadingPlanTimezone)
+	}
+}
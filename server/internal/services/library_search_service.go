@@ -0,0 +1,224 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"rag-agent-server/internal/database"
+	"rag-agent-server/internal/models"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+var ErrUnsupportedSearchLanguage = errors.New("language must be one of: ru, en")
+
+const (
+	defaultLibrarySearchLimit = 20
+	maxLibrarySearchLimit     = 100
+
+	// Fuzzy (trigram) matches weigh less than stemmed full-text matches
+	libraryFuzzyRankWeight   = 0.5
+	libraryFuzzySnippetRunes = 200
+
+	libraryHeadlineOptions = `StartSel="[[hl]]", StopSel="[[/hl]]", MaxWords=35, MinWords=10, MaxFragments=2, FragmentDelimiter=" … "`
+)
+
+// librarySearchConfigs maps verse languages to the text search configuration used by
+// scripture_verses.search_vector (see database.Connect). Each has its own partial GIN index.
+var librarySearchConfigs = map[string]string{
+	"ru": "russian",
+	"en": "english",
+}
+
+// transliterationFold strips IAST diacritics; keep in sync with scripture_fold() in database.Connect
+var transliterationFold = strings.NewReplacer(
+	"ā", "a", "ī", "i", "ū", "u", "ṛ", "r", "ṝ", "r", "ḷ", "l", "ḹ", "l",
+	"ṅ", "n", "ñ", "n", "ṭ", "t", "ḍ", "d", "ṇ", "n", "ś", "s", "ṣ", "s",
+	"ṁ", "m", "ṃ", "m", "ḥ", "h",
+)
+
+// FoldTransliteration lowercases s and removes IAST diacritics, so "Kṛṣṇa" and "krsna" compare equal
+func FoldTransliteration(s string) string {
+	return transliterationFold.Replace(strings.ToLower(s))
+}
+
+// LibrarySearchFilters narrows a library search. Zero values mean "any".
+type LibrarySearchFilters struct {
+	Query    string
+	BookCode string
+	Canto    int
+	Chapter  int
+	Language string
+	Limit    int
+	Offset   int
+}
+
+// LibrarySearchHit is a matching verse with its rank and highlighted snippet
+type LibrarySearchHit struct {
+	models.ScriptureVerse
+	Rank      float64 `json:"rank"`
+	Snippet   string  `json:"snippet"`    // HTML-escaped, matches wrapped in <mark>
+	MatchType string  `json:"match_type"` // "fulltext" or "fuzzy"
+}
+
+// LibrarySearchResult is a page of verses ordered by relevance
+type LibrarySearchResult struct {
+	Items      []LibrarySearchHit `json:"items"`
+	HasMore    bool               `json:"hasMore"`
+	NextOffset *int               `json:"nextOffset"`
+}
+
+// LibrarySearchService runs ranked full-text and transliteration-tolerant search over scripture verses
+type LibrarySearchService struct {
+	db *gorm.DB
+}
+
+func NewLibrarySearchService() *LibrarySearchService {
+	return &LibrarySearchService{db: database.DB}
+}
+
+var (
+	trigramOnce      sync.Once
+	trigramAvailable bool
+)
+
+// fuzzyEnabled reports whether pg_trgm is installed; without it only full-text matching is used
+func (s *LibrarySearchService) fuzzyEnabled() bool {
+	trigramOnce.Do(func() {
+		var count int64
+		if err := s.db.Raw("SELECT COUNT(*) FROM pg_extension WHERE extname = 'pg_trgm'").Scan(&count).Error; err != nil {
+			log.Printf("[LibrarySearch] could not check pg_trgm: %v", err)
+			return
+		}
+		trigramAvailable = count > 0
+		if !trigramAvailable {
+			log.Println("[LibrarySearch] pg_trgm is not installed; fuzzy transliteration search disabled")
+		}
+	})
+	return trigramAvailable
+}
+
+// normalizeLibrarySearchLanguages returns the languages to search, all supported ones for ""
+func normalizeLibrarySearchLanguages(language string) ([]string, error) {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if language == "" {
+		return []string{"ru", "en"}, nil
+	}
+	if _, ok := librarySearchConfigs[language]; !ok {
+		return nil, ErrUnsupportedSearchLanguage
+	}
+	return []string{language}, nil
+}
+
+// Search finds verses matching filters.Query by stemmed full text (translation, synonyms,
+// purport) or by fuzzy match against the diacritics-folded transliteration
+func (s *LibrarySearchService) Search(filters LibrarySearchFilters) (*LibrarySearchResult, error) {
+	q := strings.TrimSpace(filters.Query)
+	if utf8.RuneCountInString(q) < minMessageSearchRunes {
+		return nil, ErrSearchQueryTooShort
+	}
+	languages, err := normalizeLibrarySearchLanguages(filters.Language)
+	if err != nil {
+		return nil, err
+	}
+	limit := filters.Limit
+	if limit <= 0 {
+		limit = defaultLibrarySearchLimit
+	}
+	if limit > maxLibrarySearchLimit {
+		limit = maxLibrarySearchLimit
+	}
+	offset := filters.Offset
+	if offset < 0 {
+		offset = 0
+	}
+	folded := FoldTransliteration(q)
+	fuzzy := s.fuzzyEnabled()
+
+	// One predicate per language so each can use its partial index
+	langQuery := "CASE WHEN scripture_verses.language = 'ru' THEN websearch_to_tsquery('russian', ?) ELSE websearch_to_tsquery('english', ?) END"
+	langConfig := "CASE WHEN scripture_verses.language = 'ru' THEN 'russian'::regconfig ELSE 'english'::regconfig END"
+	ftsParts := make([]string, 0, len(languages))
+	ftsArgs := make([]interface{}, 0, len(languages)*2)
+	for _, lang := range languages {
+		ftsParts = append(ftsParts, "(scripture_verses.language = ? AND scripture_verses.search_vector @@ websearch_to_tsquery('"+librarySearchConfigs[lang]+"', ?))")
+		ftsArgs = append(ftsArgs, lang, q)
+	}
+	ftsMatch := "(" + strings.Join(ftsParts, " OR ") + ")"
+
+	rankExpr := "ts_rank_cd(scripture_verses.search_vector, " + langQuery + ")"
+	rankArgs := []interface{}{q, q}
+	matchExpr := ftsMatch
+	matchArgs := ftsArgs
+	if fuzzy {
+		rankExpr += " + word_similarity(?, scripture_verses.search_fold) * ?"
+		rankArgs = append(rankArgs, folded, libraryFuzzyRankWeight)
+		matchExpr = "(" + ftsMatch + " OR ? <% scripture_verses.search_fold)"
+		matchArgs = append(append([]interface{}{}, ftsArgs...), folded)
+	}
+
+	selectArgs := append([]interface{}{}, rankArgs...)
+	selectArgs = append(selectArgs, ftsArgs...)
+	selectArgs = append(selectArgs, q, q)
+	query := s.db.Table("scripture_verses").
+		Select(
+			"scripture_verses.*, "+
+				"("+rankExpr+") AS rank, "+
+				ftsMatch+" AS full_text_match, "+
+				"ts_headline("+langConfig+", coalesce(scripture_verses.translation, '') || ' … ' || coalesce(scripture_verses.purport, ''), "+
+				langQuery+", '"+libraryHeadlineOptions+"') AS snippet",
+			selectArgs...,
+		).
+		Where(matchExpr, matchArgs...)
+
+	if bookCode := strings.TrimSpace(filters.BookCode); bookCode != "" {
+		query = query.Where("scripture_verses.book_code = ?", bookCode)
+	}
+	if filters.Canto > 0 {
+		query = query.Where("scripture_verses.canto = ?", filters.Canto)
+	}
+	if filters.Chapter > 0 {
+		query = query.Where("scripture_verses.chapter = ?", filters.Chapter)
+	}
+	if len(languages) == 1 {
+		query = query.Where("scripture_verses.language = ?", languages[0])
+	}
+
+	type searchRow struct {
+		models.ScriptureVerse
+		Rank          float64
+		FullTextMatch bool
+		Snippet       string
+	}
+	var rows []searchRow
+	if err := query.Order("rank DESC, scripture_verses.id ASC").
+		Offset(offset).
+		Limit(limit + 1).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	result := &LibrarySearchResult{Items: make([]LibrarySearchHit, 0, len(rows))}
+	if len(rows) > limit {
+		rows = rows[:limit]
+		result.HasMore = true
+		next := offset + limit
+		result.NextOffset = &next
+	}
+	for _, row := range rows {
+		hit := LibrarySearchHit{
+			ScriptureVerse: row.ScriptureVerse,
+			Rank:           row.Rank,
+			Snippet:        renderSearchSnippet(row.Snippet),
+			MatchType:      "fulltext",
+		}
+		if !row.FullTextMatch {
+			hit.MatchType = "fuzzy"
+			hit.Snippet = renderSearchSnippet(truncateText(row.Transliteration, libraryFuzzySnippetRunes))
+		}
+		result.Items = append(result.Items, hit)
+	}
+	return result, nil
+}
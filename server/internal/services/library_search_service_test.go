@@ -0,0 +1,48 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFoldTransliteration(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "Kṛṣṇa", want: "krsna"},
+		{in: "śrī bhagavān uvāca", want: "sri bhagavan uvaca"},
+		{in: "dharma-kṣetre kuru-kṣetre", want: "dharma-ksetre kuru-ksetre"},
+		{in: "oṁ namo bhagavate", want: "om namo bhagavate"},
+		{in: "ŚRĪMAD", want: "srimad"},
+		{in: "plain ascii", want: "plain ascii"},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.in, func(t *testing.T) {
+			t.Parallel()
+			if got := FoldTransliteration(tc.in); got != tc.want {
+				t.Fatalf("FoldTransliteration(%q) = %q, want %q", tc.in, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeLibrarySearchLanguages(t *testing.T) {
+	t.Parallel()
+
+	all, err := normalizeLibrarySearchLanguages("")
+	if err != nil || len(all) != len(librarySearchConfigs) {
+		t.Fatalf("expected all languages, got %v (%v)", all, err)
+	}
+	ru, err := normalizeLibrarySearchLanguages(" RU ")
+	if err != nil || len(ru) != 1 || ru[0] != "ru" {
+		t.Fatalf("expected [ru], got %v (%v)", ru, err)
+	}
+	if _, err := normalizeLibrarySearchLanguages("hi"); !errors.Is(err, ErrUnsupportedSearchLanguage) {
+		t.Fatalf("expected ErrUnsupportedSearchLanguage, got %v", err)
+	}
+}
@@ -0,0 +1,670 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"rag-agent-server/internal/database"
+	"rag-agent-server/internal/models"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrStudyItemNotFound = errors.New("study item not found")
+	ErrInvalidHighlight  = errors.New("highlight needs a known field, color and a valid text range")
+	ErrInvalidNote       = errors.New("note content must be 1-10000 characters and visibility private or shared")
+)
+
+const (
+	maxStudyNoteRunes      = 10000
+	maxStudyLabelRunes     = 255
+	defaultContinueReading = 5
+	maxContinueReading     = 20
+)
+
+// HighlightColors is the palette accepted for highlights
+var HighlightColors = []string{"yellow", "green", "blue", "pink", "purple", "orange"}
+
+// highlightFields are the verse fields a highlight can cover
+var highlightFields = map[string]func(v *models.ScriptureVerse) string{
+	"translation":     func(v *models.ScriptureVerse) string { return v.Translation },
+	"purport":         func(v *models.ScriptureVerse) string { return v.Purport },
+	"synonyms":        func(v *models.ScriptureVerse) string { return v.Synonyms },
+	"transliteration": func(v *models.ScriptureVerse) string { return v.Transliteration },
+}
+
+// StudyFilter narrows study items; zero values mean "any"
+type StudyFilter struct {
+	BookCode string
+	Canto    int
+	Chapter  int
+	VerseID  uint
+}
+
+// HighlightInput describes a new highlight over a verse field
+type HighlightInput struct {
+	VerseID     uint   `json:"verse_id"`
+	Field       string `json:"field"`
+	StartOffset int    `json:"start_offset"`
+	EndOffset   int    `json:"end_offset"`
+	Color       string `json:"color"`
+}
+
+// NoteInput describes a new note; HighlightID optionally attaches it to a highlight
+type NoteInput struct {
+	VerseID     uint   `json:"verse_id"`
+	HighlightID *uint  `json:"highlight_id"`
+	Content     string `json:"content"`
+	Visibility  string `json:"visibility"`
+}
+
+// VerseNote is a note on a verse as seen by a reader: their own or shared by someone else
+type VerseNote struct {
+	models.ScriptureNote
+	AuthorName string `json:"author_name"`
+	Own        bool   `json:"own"`
+}
+
+// ContinueReadingItem is a book the user is reading with the verse to resume from
+type ContinueReadingItem struct {
+	Progress models.ScriptureReadingProgress `json:"progress"`
+	Book     models.ScriptureBook            `json:"book"`
+	Verse    *models.ScriptureVerse          `json:"verse,omitempty"`
+}
+
+// StudySyncPayload holds study rows changed after Since, deleted ones with deleted_at set
+type StudySyncPayload struct {
+	Since      *time.Time                        `json:"since,omitempty"`
+	ServerTime time.Time                         `json:"server_time"`
+	Bookmarks  []models.ScriptureBookmark        `json:"bookmarks"`
+	Highlights []models.ScriptureHighlight       `json:"highlights"`
+	Notes      []models.ScriptureNote            `json:"notes"`
+	Progress   []models.ScriptureReadingProgress `json:"progress"`
+}
+
+// LibraryStudyService manages per-user bookmarks, highlights, notes and reading progress
+type LibraryStudyService struct {
+	db *gorm.DB
+}
+
+func NewLibraryStudyService() *LibraryStudyService {
+	return &LibraryStudyService{db: database.DB}
+}
+
+func (s *LibraryStudyService) loadVerse(verseID uint) (*models.ScriptureVerse, error) {
+	var verse models.ScriptureVerse
+	if err := s.db.First(&verse, verseID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrVerseNotFound
+		}
+		return nil, err
+	}
+	return &verse, nil
+}
+
+// applyStudyFilter scopes a query on a study table by book and verse location
+func (s *LibraryStudyService) applyStudyFilter(query *gorm.DB, filter StudyFilter) *gorm.DB {
+	if filter.VerseID != 0 {
+		query = query.Where("verse_id = ?", filter.VerseID)
+	}
+	if bookCode := strings.TrimSpace(filter.BookCode); bookCode != "" {
+		query = query.Where("book_code = ?", bookCode)
+	}
+	if filter.Canto > 0 || filter.Chapter > 0 {
+		verses := s.db.Model(&models.ScriptureVerse{}).Select("id")
+		if filter.Canto > 0 {
+			verses = verses.Where("canto = ?", filter.Canto)
+		}
+		if filter.Chapter > 0 {
+			verses = verses.Where("chapter = ?", filter.Chapter)
+		}
+		query = query.Where("verse_id IN (?)", verses)
+	}
+	return query
+}
+
+// SetBookmark bookmarks a verse (or updates the label), restoring a previously removed bookmark
+func (s *LibraryStudyService) SetBookmark(userID, verseID uint, label string) (*models.ScriptureBookmark, error) {
+	verse, err := s.loadVerse(verseID)
+	if err != nil {
+		return nil, err
+	}
+	label = truncateText(strings.TrimSpace(label), maxStudyLabelRunes)
+
+	var bookmark models.ScriptureBookmark
+	err = s.db.Unscoped().Where("user_id = ? AND verse_id = ?", userID, verseID).First(&bookmark).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		bookmark = models.ScriptureBookmark{UserID: userID, VerseID: verseID, BookCode: verse.BookCode, Label: label}
+		// A concurrent request from another device may have created it already
+		if err := s.db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "verse_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"label": label, "deleted_at": nil, "updated_at": time.Now()}),
+		}).Create(&bookmark).Error; err != nil {
+			return nil, err
+		}
+		return &bookmark, nil
+	case err != nil:
+		return nil, err
+	}
+
+	if err := s.db.Unscoped().Model(&bookmark).Updates(map[string]interface{}{
+		"label":      label,
+		"deleted_at": nil,
+	}).Error; err != nil {
+		return nil, err
+	}
+	bookmark.DeletedAt = gorm.DeletedAt{}
+	return &bookmark, nil
+}
+
+// RemoveBookmark removes the user's bookmark on a verse
+func (s *LibraryStudyService) RemoveBookmark(userID, verseID uint) error {
+	result := s.db.Where("user_id = ? AND verse_id = ?", userID, verseID).Delete(&models.ScriptureBookmark{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrStudyItemNotFound
+	}
+	return nil
+}
+
+// ListBookmarks returns the user's bookmarks, newest first
+func (s *LibraryStudyService) ListBookmarks(userID uint, filter StudyFilter) ([]models.ScriptureBookmark, error) {
+	var items []models.ScriptureBookmark
+	query := s.applyStudyFilter(s.db.Where("user_id = ?", userID), filter)
+	err := query.Order("created_at DESC").Find(&items).Error
+	return items, err
+}
+
+// AddHighlight highlights a rune range of a verse field
+func (s *LibraryStudyService) AddHighlight(userID uint, in HighlightInput) (*models.ScriptureHighlight, error) {
+	verse, err := s.loadVerse(in.VerseID)
+	if err != nil {
+		return nil, err
+	}
+	field := strings.ToLower(strings.TrimSpace(in.Field))
+	color := strings.ToLower(strings.TrimSpace(in.Color))
+	text, err := highlightText(verse, field, in.StartOffset, in.EndOffset)
+	if err != nil || !validHighlightColor(color) {
+		return nil, ErrInvalidHighlight
+	}
+
+	highlight := models.ScriptureHighlight{
+		UserID:      userID,
+		VerseID:     verse.ID,
+		BookCode:    verse.BookCode,
+		Field:       field,
+		StartOffset: in.StartOffset,
+		EndOffset:   in.EndOffset,
+		Color:       color,
+		Text:        text,
+	}
+	if err := s.db.Create(&highlight).Error; err != nil {
+		return nil, err
+	}
+	return &highlight, nil
+}
+
+// highlightText returns the highlighted part of a verse field; offsets are in runes
+func highlightText(verse *models.ScriptureVerse, field string, start, end int) (string, error) {
+	get, ok := highlightFields[field]
+	if !ok {
+		return "", ErrInvalidHighlight
+	}
+	runes := []rune(get(verse))
+	if start < 0 || end <= start || end > len(runes) {
+		return "", ErrInvalidHighlight
+	}
+	return string(runes[start:end]), nil
+}
+
+func validHighlightColor(color string) bool {
+	for _, c := range HighlightColors {
+		if c == color {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateHighlightColor recolors one of the user's highlights
+func (s *LibraryStudyService) UpdateHighlightColor(userID, id uint, color string) (*models.ScriptureHighlight, error) {
+	color = strings.ToLower(strings.TrimSpace(color))
+	if !validHighlightColor(color) {
+		return nil, ErrInvalidHighlight
+	}
+	var highlight models.ScriptureHighlight
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&highlight).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrStudyItemNotFound
+		}
+		return nil, err
+	}
+	if err := s.db.Model(&highlight).Update("color", color).Error; err != nil {
+		return nil, err
+	}
+	return &highlight, nil
+}
+
+// DeleteHighlight removes one of the user's highlights; notes attached to it stay on the verse
+func (s *LibraryStudyService) DeleteHighlight(userID, id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", id, userID).Delete(&models.ScriptureHighlight{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrStudyItemNotFound
+		}
+		return tx.Model(&models.ScriptureNote{}).
+			Where("user_id = ? AND highlight_id = ?", userID, id).
+			Update("highlight_id", nil).Error
+	})
+}
+
+// ListHighlights returns the user's highlights in reading order
+func (s *LibraryStudyService) ListHighlights(userID uint, filter StudyFilter) ([]models.ScriptureHighlight, error) {
+	var items []models.ScriptureHighlight
+	query := s.applyStudyFilter(s.db.Where("user_id = ?", userID), filter)
+	err := query.Order("verse_id ASC, field ASC, start_offset ASC").Find(&items).Error
+	return items, err
+}
+
+func normalizeNote(content, visibility string) (string, string, error) {
+	content = strings.TrimSpace(content)
+	if content == "" || utf8.RuneCountInString(content) > maxStudyNoteRunes {
+		return "", "", ErrInvalidNote
+	}
+	visibility = strings.ToLower(strings.TrimSpace(visibility))
+	if visibility == "" {
+		visibility = models.ScriptureNotePrivate
+	}
+	if visibility != models.ScriptureNotePrivate && visibility != models.ScriptureNoteShared {
+		return "", "", ErrInvalidNote
+	}
+	return content, visibility, nil
+}
+
+// AddNote adds a note on a verse, optionally attached to one of the user's highlights on it
+func (s *LibraryStudyService) AddNote(userID uint, in NoteInput) (*models.ScriptureNote, error) {
+	content, visibility, err := normalizeNote(in.Content, in.Visibility)
+	if err != nil {
+		return nil, err
+	}
+	verse, err := s.loadVerse(in.VerseID)
+	if err != nil {
+		return nil, err
+	}
+	if in.HighlightID != nil && *in.HighlightID == 0 {
+		in.HighlightID = nil
+	}
+	if in.HighlightID != nil {
+		var count int64
+		if err := s.db.Model(&models.ScriptureHighlight{}).
+			Where("id = ? AND user_id = ? AND verse_id = ?", *in.HighlightID, userID, verse.ID).
+			Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return nil, ErrStudyItemNotFound
+		}
+	}
+
+	note := models.ScriptureNote{
+		UserID:      userID,
+		VerseID:     verse.ID,
+		BookCode:    verse.BookCode,
+		HighlightID: in.HighlightID,
+		Content:     content,
+		Visibility:  visibility,
+	}
+	if err := s.db.Create(&note).Error; err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// UpdateNote changes the content and/or visibility of one of the user's notes
+func (s *LibraryStudyService) UpdateNote(userID, id uint, content, visibility *string) (*models.ScriptureNote, error) {
+	var note models.ScriptureNote
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&note).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrStudyItemNotFound
+		}
+		return nil, err
+	}
+	newContent, newVisibility := note.Content, note.Visibility
+	if content != nil {
+		newContent = *content
+	}
+	if visibility != nil {
+		newVisibility = *visibility
+	}
+	newContent, newVisibility, err := normalizeNote(newContent, newVisibility)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(&note).Updates(map[string]interface{}{
+		"content":    newContent,
+		"visibility": newVisibility,
+	}).Error; err != nil {
+		return nil, err
+	}
+	note.Content, note.Visibility = newContent, newVisibility
+	return &note, nil
+}
+
+// DeleteNote removes one of the user's notes
+func (s *LibraryStudyService) DeleteNote(userID, id uint) error {
+	result := s.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.ScriptureNote{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrStudyItemNotFound
+	}
+	return nil
+}
+
+// ListNotes returns the user's own notes, newest first
+func (s *LibraryStudyService) ListNotes(userID uint, filter StudyFilter) ([]models.ScriptureNote, error) {
+	var items []models.ScriptureNote
+	query := s.applyStudyFilter(s.db.Where("user_id = ?", userID), filter)
+	err := query.Order("created_at DESC").Find(&items).Error
+	return items, err
+}
+
+// VerseNotes returns the viewer's notes on a verse plus notes others shared on it
+func (s *LibraryStudyService) VerseNotes(viewerID, verseID uint) ([]VerseNote, error) {
+	if _, err := s.loadVerse(verseID); err != nil {
+		return nil, err
+	}
+	type noteRow struct {
+		models.ScriptureNote
+		SpiritualName string
+		KarmicName    string
+	}
+	var rows []noteRow
+	if err := s.db.Table("scripture_notes").
+		Select("scripture_notes.*, users.spiritual_name, users.karmic_name").
+		Joins("LEFT JOIN users ON users.id = scripture_notes.user_id").
+		Where("scripture_notes.deleted_at IS NULL AND scripture_notes.verse_id = ?", verseID).
+		Where("scripture_notes.user_id = ? OR scripture_notes.visibility = ?", viewerID, models.ScriptureNoteShared).
+		Order("scripture_notes.created_at ASC").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	notes := make([]VerseNote, 0, len(rows))
+	for _, row := range rows {
+		author := strings.TrimSpace(row.SpiritualName)
+		if author == "" {
+			author = strings.TrimSpace(row.KarmicName)
+		}
+		notes = append(notes, VerseNote{ScriptureNote: row.ScriptureNote, AuthorName: author, Own: row.UserID == viewerID})
+	}
+	return notes, nil
+}
+
+// SaveProgress records the verse the user is reading. readAt is the client time of the read;
+// a position older than the stored one (stale device) is ignored and the stored one returned.
+func (s *LibraryStudyService) SaveProgress(userID, verseID uint, readAt *time.Time) (*models.ScriptureReadingProgress, error) {
+	verse, err := s.loadVerse(verseID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	at := now
+	if readAt != nil && !readAt.IsZero() && readAt.Before(now) {
+		at = *readAt
+	}
+
+	progress := models.ScriptureReadingProgress{
+		UserID:   userID,
+		BookCode: verse.BookCode,
+		VerseID:  verse.ID,
+		Canto:    verse.Canto,
+		Chapter:  verse.Chapter,
+		Verse:    verse.Verse,
+		Language: verse.Language,
+		ReadAt:   at,
+	}
+	if err := s.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "book_code"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"verse_id", "canto", "chapter", "verse", "language", "read_at", "updated_at",
+		}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "scripture_reading_progresses.read_at <= EXCLUDED.read_at"},
+		}},
+	}).Create(&progress).Error; err != nil {
+		return nil, err
+	}
+
+	var stored models.ScriptureReadingProgress
+	if err := s.db.Where("user_id = ? AND book_code = ?", userID, verse.BookCode).First(&stored).Error; err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+// ListProgress returns the user's position in every book, most recent first
+func (s *LibraryStudyService) ListProgress(userID uint) ([]models.ScriptureReadingProgress, error) {
+	var items []models.ScriptureReadingProgress
+	err := s.db.Where("user_id = ?", userID).Order("read_at DESC").Find(&items).Error
+	return items, err
+}
+
+// ContinueReading returns the most recently read books with the verse to resume from
+func (s *LibraryStudyService) ContinueReading(userID uint, limit int) ([]ContinueReadingItem, error) {
+	if limit <= 0 {
+		limit = defaultContinueReading
+	}
+	if limit > maxContinueReading {
+		limit = maxContinueReading
+	}
+	var progress []models.ScriptureReadingProgress
+	if err := s.db.Where("user_id = ?", userID).Order("read_at DESC").Limit(limit).Find(&progress).Error; err != nil {
+		return nil, err
+	}
+	if len(progress) == 0 {
+		return []ContinueReadingItem{}, nil
+	}
+
+	codes := make([]string, 0, len(progress))
+	verseIDs := make([]uint, 0, len(progress))
+	for _, p := range progress {
+		codes = append(codes, p.BookCode)
+		verseIDs = append(verseIDs, p.VerseID)
+	}
+	var books []models.ScriptureBook
+	if err := s.db.Where("code IN ?", codes).Find(&books).Error; err != nil {
+		return nil, err
+	}
+	var verses []models.ScriptureVerse
+	if err := s.db.Where("id IN ?", verseIDs).Find(&verses).Error; err != nil {
+		return nil, err
+	}
+	bookByCode := make(map[string]models.ScriptureBook, len(books))
+	for _, b := range books {
+		bookByCode[b.Code] = b
+	}
+	verseByID := make(map[uint]models.ScriptureVerse, len(verses))
+	for _, v := range verses {
+		verseByID[v.ID] = v
+	}
+
+	items := make([]ContinueReadingItem, 0, len(progress))
+	for _, p := range progress {
+		book, ok := bookByCode[p.BookCode]
+		if !ok {
+			continue // Book was removed from the library
+		}
+		item := ContinueReadingItem{Progress: p, Book: book}
+		if verse, ok := verseByID[p.VerseID]; ok {
+			verse.Purport = "" // Keep the payload small; the reader loads the full chapter
+			item.Verse = &verse
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// Changes returns study rows changed after since (everything when nil), including deletions
+func (s *LibraryStudyService) Changes(userID uint, since *time.Time) (*StudySyncPayload, error) {
+	payload := &StudySyncPayload{Since: since, ServerTime: time.Now().UTC()}
+	changed := func() *gorm.DB {
+		query := s.db.Unscoped().Where("user_id = ?", userID)
+		if since != nil {
+			return query.Where("(updated_at > ? OR deleted_at > ?)", *since, *since)
+		}
+		return query.Where("deleted_at IS NULL")
+	}
+	if err := changed().Order("id ASC").Find(&payload.Bookmarks).Error; err != nil {
+		return nil, err
+	}
+	if err := changed().Order("id ASC").Find(&payload.Highlights).Error; err != nil {
+		return nil, err
+	}
+	if err := changed().Order("id ASC").Find(&payload.Notes).Error; err != nil {
+		return nil, err
+	}
+	progress := s.db.Where("user_id = ?", userID)
+	if since != nil {
+		progress = progress.Where("updated_at > ?", *since)
+	}
+	if err := progress.Order("id ASC").Find(&payload.Progress).Error; err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// studyExportVerse groups the user's study items on one verse
+type studyExportVerse struct {
+	Verse      models.ScriptureVerse
+	Bookmark   *models.ScriptureBookmark
+	Highlights []models.ScriptureHighlight
+	Notes      []models.ScriptureNote
+}
+
+// ExportMarkdown renders all of the user's bookmarks, highlights and notes grouped by book and verse
+func (s *LibraryStudyService) ExportMarkdown(userID uint, filter StudyFilter) (string, error) {
+	bookmarks, err := s.ListBookmarks(userID, filter)
+	if err != nil {
+		return "", err
+	}
+	highlights, err := s.ListHighlights(userID, filter)
+	if err != nil {
+		return "", err
+	}
+	notes, err := s.ListNotes(userID, filter)
+	if err != nil {
+		return "", err
+	}
+
+	grouped := make(map[uint]*studyExportVerse)
+	entry := func(verseID uint) *studyExportVerse {
+		if grouped[verseID] == nil {
+			grouped[verseID] = &studyExportVerse{}
+		}
+		return grouped[verseID]
+	}
+	for i := range bookmarks {
+		entry(bookmarks[i].VerseID).Bookmark = &bookmarks[i]
+	}
+	for _, h := range highlights {
+		e := entry(h.VerseID)
+		e.Highlights = append(e.Highlights, h)
+	}
+	for _, n := range notes {
+		e := entry(n.VerseID)
+		e.Notes = append(e.Notes, n)
+	}
+	if len(grouped) == 0 {
+		return "", nil
+	}
+
+	verseIDs := make([]uint, 0, len(grouped))
+	for id := range grouped {
+		verseIDs = append(verseIDs, id)
+	}
+	var verses []models.ScriptureVerse
+	if err := s.db.Select("id", "book_code", "canto", "chapter", "verse", "language", "translation").
+		Where("id IN ?", verseIDs).Find(&verses).Error; err != nil {
+		return "", err
+	}
+	var books []models.ScriptureBook
+	if err := s.db.Find(&books).Error; err != nil {
+		return "", err
+	}
+	bookNames := make(map[string]string, len(books))
+	for _, b := range books {
+		bookNames[b.Code] = pickTitle(true, b.NameRu, b.NameEn)
+	}
+
+	entries := make([]*studyExportVerse, 0, len(verses))
+	for _, v := range verses {
+		e := grouped[v.ID]
+		e.Verse = v
+		entries = append(entries, e)
+	}
+	return renderStudyMarkdown(entries, bookNames), nil
+}
+
+func renderStudyMarkdown(entries []*studyExportVerse, bookNames map[string]string) string {
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i].Verse, entries[j].Verse
+		if a.BookCode != b.BookCode {
+			return a.BookCode < b.BookCode
+		}
+		if a.Canto != b.Canto {
+			return a.Canto < b.Canto
+		}
+		if a.Chapter != b.Chapter {
+			return a.Chapter < b.Chapter
+		}
+		return a.ID < b.ID
+	})
+
+	var out strings.Builder
+	currentBook := ""
+	for _, e := range entries {
+		v := e.Verse
+		if v.BookCode != currentBook {
+			currentBook = v.BookCode
+			name := bookNames[v.BookCode]
+			if name == "" {
+				name = strings.ToUpper(v.BookCode)
+			}
+			fmt.Fprintf(&out, "# %s\n\n", name)
+		}
+		fmt.Fprintf(&out, "## %s %s\n\n", strings.ToUpper(v.BookCode), verseLabel(v.Canto, v.Chapter, v.Verse))
+		if e.Bookmark != nil {
+			if e.Bookmark.Label != "" {
+				fmt.Fprintf(&out, "🔖 %s\n\n", e.Bookmark.Label)
+			} else {
+				out.WriteString("🔖\n\n")
+			}
+		}
+		if v.Translation != "" {
+			fmt.Fprintf(&out, "> %s\n\n", strings.TrimSpace(v.Translation))
+		}
+		for _, h := range e.Highlights {
+			fmt.Fprintf(&out, "- ==%s== (%s, %s)\n", strings.TrimSpace(h.Text), h.Field, h.Color)
+		}
+		if len(e.Highlights) > 0 {
+			out.WriteString("\n")
+		}
+		for _, n := range e.Notes {
+			fmt.Fprintf(&out, "%s\n\n", n.Content)
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"errors"
+	"rag-agent-server/internal/models"
+	"strings"
+	"testing"
+)
+
+func TestHighlightText(t *testing.T) {
+	t.Parallel()
+
+	verse := &models.ScriptureVerse{Translation: "Дхритараштра спросил: О Санджая"}
+	tests := []struct {
+		name       string
+		field      string
+		start, end int
+		want       string
+		wantErr    bool
+	}{
+		{name: "cyrillic runes", field: "translation", start: 0, end: 12, want: "Дхритараштра"},
+		{name: "whole field", field: "translation", start: 0, end: 31, want: "Дхритараштра спросил: О Санджая"},
+		{name: "past end", field: "translation", start: 5, end: 32, wantErr: true},
+		{name: "empty range", field: "translation", start: 3, end: 3, wantErr: true},
+		{name: "negative start", field: "translation", start: -1, end: 2, wantErr: true},
+		{name: "unknown field", field: "devanagari", start: 0, end: 1, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := highlightText(verse, tc.field, tc.start, tc.end)
+			if tc.wantErr {
+				if !errors.Is(err, ErrInvalidHighlight) {
+					t.Fatalf("expected ErrInvalidHighlight, got %q, %v", got, err)
+				}
+				return
+			}
+			if err != nil || got != tc.want {
+				t.Fatalf("got %q (%v), want %q", got, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeNote(t *testing.T) {
+	t.Parallel()
+
+	content, visibility, err := normalizeNote("  Важная мысль  ", "")
+	if err != nil || content != "Важная мысль" || visibility != models.ScriptureNotePrivate {
+		t.Fatalf("unexpected normalization: %q %q %v", content, visibility, err)
+	}
+	if _, visibility, err := normalizeNote("x", "Shared"); err != nil || visibility != models.ScriptureNoteShared {
+		t.Fatalf("expected shared visibility, got %q %v", visibility, err)
+	}
+	if _, _, err := normalizeNote("   ", "private"); !errors.Is(err, ErrInvalidNote) {
+		t.Fatalf("expected ErrInvalidNote for empty content, got %v", err)
+	}
+	if _, _, err := normalizeNote("x", "public"); !errors.Is(err, ErrInvalidNote) {
+		t.Fatalf("expected ErrInvalidNote for unknown visibility, got %v", err)
+	}
+	if _, _, err := normalizeNote(strings.Repeat("a", maxStudyNoteRunes+1), ""); !errors.Is(err, ErrInvalidNote) {
+		t.Fatalf("expected ErrInvalidNote for long content, got %v", err)
+	}
+}
+
+func TestRenderStudyMarkdown(t *testing.T) {
+	t.Parallel()
+
+	entries := []*studyExportVerse{
+		{
+			Verse: models.ScriptureVerse{ID: 20, BookCode: "sb", Canto: 1, Chapter: 1, Verse: "1"},
+			Notes: []models.ScriptureNote{{Content: "Начало Бхагаватам"}},
+		},
+		{
+			Verse:      models.ScriptureVerse{ID: 10, BookCode: "bg", Chapter: 2, Verse: "13", Translation: "Душа переходит в новое тело"},
+			Bookmark:   &models.ScriptureBookmark{Label: "Душа"},
+			Highlights: []models.ScriptureHighlight{{Text: "новое тело", Field: "translation", Color: "yellow"}},
+		},
+	}
+
+	out := renderStudyMarkdown(entries, map[string]string{"bg": "Бхагавад-гита"})
+	for _, want := range []string{"# Бхагавад-гита", "## BG 2.13", "🔖 Душа", "- ==новое тело== (translation, yellow)", "# SB", "## SB 1.1.1", "Начало Бхагаватам"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("markdown missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Index(out, "BG 2.13") > strings.Index(out, "SB 1.1.1") {
+		t.Fatalf("expected books in code order:\n%s", out)
+	}
+}
@@ -0,0 +1,419 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"rag-agent-server/internal/database"
+	"rag-agent-server/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrLKMAuditReportNotFound = errors.New("audit report not found")
+
+// lkmSuspenseAccountCode absorbs the difference of unbalanced groups until finance reclassifies it
+const lkmSuspenseAccountCode = "ledger_suspense"
+
+// LKMAuditOptions controls one consistency check run
+type LKMAuditOptions struct {
+	Repair  bool
+	ActorID *uint
+	Trigger string // admin, cli
+}
+
+// LKMAuditService verifies that every ledger TxGroupID balances and that the denormalized
+// wallet balances match their transaction history.
+//
+// Repair mode never moves money: the stored balances are what users can spend, so
+// correcting entries are appended to the history (and a suspense posting to the ledger)
+// to explain the difference. Finance can then charge or seize if the balance was wrong.
+type LKMAuditService struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+func NewLKMAuditService() *LKMAuditService {
+	return &LKMAuditService{db: database.DB, now: time.Now}
+}
+
+// walletBalances is the state of a wallet recomputed from its transactions
+type walletBalances struct {
+	Balance            int
+	BonusBalance       int
+	PendingBalance     int
+	FrozenBalance      int
+	FrozenBonusBalance int
+	// FirstDivergentTxID is the first transaction whose BalanceAfter disagrees with the replay
+	FirstDivergentTxID *uint
+}
+
+// replayWalletTransactions recomputes a wallet from its transactions in insertion order
+func replayWalletTransactions(txs []models.WalletTransaction) walletBalances {
+	var b walletBalances
+	for i := range txs {
+		t := &txs[i]
+		b.apply(t)
+		if b.FirstDivergentTxID == nil && t.BalanceAfter != b.Balance {
+			id := t.ID
+			b.FirstDivergentTxID = &id
+		}
+	}
+	return b
+}
+
+// apply moves one transaction between the balance columns the way WalletService does
+func (b *walletBalances) apply(t *models.WalletTransaction) {
+	bonus := t.BonusAmount
+	if bonus < 0 {
+		bonus = 0
+	}
+	if bonus > t.Amount {
+		bonus = t.Amount
+	}
+	regular := t.Amount - bonus
+
+	switch t.Type {
+	case models.TransactionTypeCredit, models.TransactionTypeAdminCharge:
+		b.Balance += t.Amount
+	case models.TransactionTypeDebit:
+		b.Balance -= regular
+		b.BonusBalance -= bonus
+	case models.TransactionTypeAdminSeize:
+		b.Balance -= t.Amount
+	case models.TransactionTypeBonus:
+		switch t.Description {
+		case walletWelcomePendingDescription:
+			b.PendingBalance += t.Amount
+		case walletWelcomeActivatedDescription:
+			b.PendingBalance -= t.Amount
+			b.BonusBalance += t.Amount
+		default:
+			b.BonusBalance += t.Amount
+		}
+	case models.TransactionTypeHold:
+		b.Balance -= regular
+		b.BonusBalance -= bonus
+		b.FrozenBalance += regular
+		b.FrozenBonusBalance += bonus
+	case models.TransactionTypeRelease:
+		b.FrozenBalance -= regular
+		b.FrozenBonusBalance -= bonus
+	case models.TransactionTypeRefund:
+		b.Balance += regular
+		b.BonusBalance += bonus
+		// Refunds of a hold carry its booking or order; plain refunds do not
+		if t.BookingID != nil || t.OrderID != nil {
+			b.FrozenBalance -= regular
+			b.FrozenBonusBalance -= bonus
+		}
+	case models.TransactionTypeAdjustmentCredit, models.TransactionTypeAdjustmentDebit:
+		amount := t.Amount
+		if t.Type == models.TransactionTypeAdjustmentDebit {
+			amount = -amount
+		}
+		switch t.AdjustedField {
+		case models.WalletFieldBalance:
+			b.Balance += amount
+		case models.WalletFieldBonusBalance:
+			b.BonusBalance += amount
+		case models.WalletFieldPendingBalance:
+			b.PendingBalance += amount
+		case models.WalletFieldFrozenBalance:
+			b.FrozenBalance += amount
+		case models.WalletFieldFrozenBonusBalance:
+			b.FrozenBonusBalance += amount
+		}
+	}
+}
+
+// walletDrift lists the balance columns whose stored value differs from the replay,
+// "balance" first so its correction restores the BalanceAfter chain before the others
+func walletDrift(wallet *models.Wallet, replayed walletBalances) []models.LKMAuditFinding {
+	columns := []struct {
+		field    string
+		expected int
+		actual   int
+	}{
+		{models.WalletFieldBalance, replayed.Balance, wallet.Balance},
+		{models.WalletFieldBonusBalance, replayed.BonusBalance, wallet.BonusBalance},
+		{models.WalletFieldPendingBalance, replayed.PendingBalance, wallet.PendingBalance},
+		{models.WalletFieldFrozenBalance, replayed.FrozenBalance, wallet.FrozenBalance},
+		{models.WalletFieldFrozenBonusBalance, replayed.FrozenBonusBalance, wallet.FrozenBonusBalance},
+	}
+
+	var found []models.LKMAuditFinding
+	for _, col := range columns {
+		if col.expected == col.actual {
+			continue
+		}
+		walletID := wallet.ID
+		found = append(found, models.LKMAuditFinding{
+			Kind:               models.LKMAuditWalletDrift,
+			WalletID:           &walletID,
+			Field:              col.field,
+			FirstDivergentTxID: replayed.FirstDivergentTxID,
+			Expected:           col.expected,
+			Actual:             col.actual,
+			Delta:              col.actual - col.expected,
+		})
+	}
+	return found
+}
+
+// Run checks the ledger and every wallet and stores the report with its findings
+func (s *LKMAuditService) Run(opts LKMAuditOptions) (*models.LKMAuditReport, error) {
+	if opts.Trigger == "" {
+		opts.Trigger = "admin"
+	}
+	report := models.LKMAuditReport{
+		Trigger:    opts.Trigger,
+		ActorID:    opts.ActorID,
+		RepairMode: opts.Repair,
+		Status:     models.LKMAuditConsistent,
+	}
+	// The report is stored first so correcting entries can reference it
+	if err := s.db.Create(&report).Error; err != nil {
+		return nil, err
+	}
+
+	if err := s.auditLedger(&report, opts); err != nil {
+		return nil, err
+	}
+	if err := s.auditWallets(&report, opts); err != nil {
+		return nil, err
+	}
+
+	report.FindingCount = len(report.Findings)
+	for _, finding := range report.Findings {
+		if finding.Repaired {
+			report.RepairedCount++
+		}
+	}
+	switch {
+	case report.FindingCount == 0:
+		report.Status = models.LKMAuditConsistent
+	case report.RepairedCount == report.FindingCount:
+		report.Status = models.LKMAuditRepaired
+	default:
+		report.Status = models.LKMAuditDrift
+	}
+	report.FinishedAt = s.now().UTC()
+
+	if err := s.db.Model(&report).Select("groups_total", "wallets_total", "finding_count", "repaired_count", "status", "finished_at").
+		Updates(&report).Error; err != nil {
+		return nil, err
+	}
+	log.Printf("[LKMAudit] Report %d: %d groups, %d wallets, %d findings (%d repaired)",
+		report.ID, report.GroupsTotal, report.WalletsTotal, report.FindingCount, report.RepairedCount)
+	return &report, nil
+}
+
+type ledgerGroupTotals struct {
+	TxGroupID string
+	Debits    int
+	Credits   int
+}
+
+func (s *LKMAuditService) auditLedger(report *models.LKMAuditReport, opts LKMAuditOptions) error {
+	var groupsTotal int64
+	if err := s.db.Model(&models.LKMLedgerEntry{}).
+		Where("status = ? AND tx_group_id <> ''", "posted").
+		Distinct("tx_group_id").Count(&groupsTotal).Error; err != nil {
+		return err
+	}
+	report.GroupsTotal = int(groupsTotal)
+
+	var unbalanced []ledgerGroupTotals
+	if err := s.db.Model(&models.LKMLedgerEntry{}).
+		Select("tx_group_id, "+
+			"COALESCE(SUM(CASE WHEN entry_type = ? THEN amount ELSE 0 END), 0) AS debits, "+
+			"COALESCE(SUM(CASE WHEN entry_type = ? THEN amount ELSE 0 END), 0) AS credits",
+			models.LKMLedgerEntryTypeDebit, models.LKMLedgerEntryTypeCredit).
+		Where("status = ? AND tx_group_id <> ''", "posted").
+		Group("tx_group_id").
+		Having("SUM(CASE WHEN entry_type = ? THEN amount ELSE 0 END) <> SUM(CASE WHEN entry_type = ? THEN amount ELSE 0 END)",
+			models.LKMLedgerEntryTypeDebit, models.LKMLedgerEntryTypeCredit).
+		Order("tx_group_id").
+		Scan(&unbalanced).Error; err != nil {
+		return err
+	}
+
+	for _, group := range unbalanced {
+		finding := models.LKMAuditFinding{
+			ReportID:  report.ID,
+			Kind:      models.LKMAuditUnbalancedGroup,
+			TxGroupID: group.TxGroupID,
+			Expected:  group.Debits,
+			Actual:    group.Credits,
+			Delta:     group.Credits - group.Debits,
+		}
+		if opts.Repair {
+			if err := s.postSuspenseEntry(&finding, report.ID, opts.ActorID); err != nil {
+				log.Printf("[LKMAudit] Could not repair group %s: %v", group.TxGroupID, err)
+			}
+		}
+		if err := s.db.Create(&finding).Error; err != nil {
+			return err
+		}
+		report.Findings = append(report.Findings, finding)
+	}
+
+	// One-sided postings have nothing to balance against; they are reported, not repaired
+	var ungrouped []models.LKMLedgerEntry
+	if err := s.db.Where("status = ? AND (tx_group_id IS NULL OR tx_group_id = '')", "posted").
+		Order("id").Find(&ungrouped).Error; err != nil {
+		return err
+	}
+	for _, entry := range ungrouped {
+		entryID := entry.ID
+		finding := models.LKMAuditFinding{
+			ReportID:      report.ID,
+			Kind:          models.LKMAuditUngroupedEntry,
+			LedgerEntryID: &entryID,
+			Actual:        entry.Amount,
+			Delta:         entry.Amount,
+		}
+		if err := s.db.Create(&finding).Error; err != nil {
+			return err
+		}
+		report.Findings = append(report.Findings, finding)
+	}
+	return nil
+}
+
+// postSuspenseEntry balances a group with a posting to the suspense account
+func (s *LKMAuditService) postSuspenseEntry(finding *models.LKMAuditFinding, reportID uint, actorID *uint) error {
+	entry := models.LKMLedgerEntry{
+		TxGroupID:         finding.TxGroupID,
+		EntryType:         models.LKMLedgerEntryTypeDebit,
+		Amount:            finding.Delta,
+		AccountCode:       lkmSuspenseAccountCode,
+		Status:            "posted",
+		SourceService:     "audit",
+		SourceTrigger:     "audit_repair",
+		SourceContextJSON: fmt.Sprintf(`{"auditReportId":%d}`, reportID),
+		ActorAdminID:      actorID,
+		Note:              fmt.Sprintf("Audit correction for unbalanced group (report %d)", reportID),
+	}
+	if finding.Delta < 0 {
+		entry.EntryType = models.LKMLedgerEntryTypeCredit
+		entry.Amount = -finding.Delta
+	}
+	if err := s.db.Create(&entry).Error; err != nil {
+		return err
+	}
+	finding.Repaired = true
+	finding.RepairRef = fmt.Sprintf("ledger_entry:%d", entry.ID)
+	return nil
+}
+
+func (s *LKMAuditService) auditWallets(report *models.LKMAuditReport, opts LKMAuditOptions) error {
+	var walletIDs []uint
+	if err := s.db.Model(&models.Wallet{}).Order("id").Pluck("id", &walletIDs).Error; err != nil {
+		return err
+	}
+	report.WalletsTotal = len(walletIDs)
+
+	for _, walletID := range walletIDs {
+		findings, err := s.auditWallet(walletID, report.ID, opts)
+		if err != nil {
+			return fmt.Errorf("wallet %d: %w", walletID, err)
+		}
+		report.Findings = append(report.Findings, findings...)
+	}
+	return nil
+}
+
+// auditWallet replays one wallet under a row lock, so the history and the stored balances
+// are read at the same point between two wallet operations
+func (s *LKMAuditService) auditWallet(walletID, reportID uint, opts LKMAuditOptions) ([]models.LKMAuditFinding, error) {
+	var findings []models.LKMAuditFinding
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		strength := "SHARE"
+		if opts.Repair {
+			strength = "UPDATE"
+		}
+		var wallet models.Wallet
+		if err := tx.Clauses(clause.Locking{Strength: strength}).First(&wallet, walletID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		var txs []models.WalletTransaction
+		if err := tx.Where("wallet_id = ?", wallet.ID).Order("id ASC").Find(&txs).Error; err != nil {
+			return err
+		}
+
+		findings = walletDrift(&wallet, replayWalletTransactions(txs))
+		for i := range findings {
+			findings[i].ReportID = reportID
+			if opts.Repair {
+				if err := s.postWalletAdjustmentTx(tx, &wallet, &findings[i], reportID, opts.ActorID); err != nil {
+					return err
+				}
+			}
+			if err := tx.Create(&findings[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return findings, err
+}
+
+// postWalletAdjustmentTx appends a correcting transaction that explains the drift of one
+// balance column; the stored balances are left untouched
+func (s *LKMAuditService) postWalletAdjustmentTx(tx *gorm.DB, wallet *models.Wallet, finding *models.LKMAuditFinding, reportID uint, actorID *uint) error {
+	adjustment := models.WalletTransaction{
+		WalletID:      wallet.ID,
+		Type:          models.TransactionTypeAdjustmentCredit,
+		Amount:        finding.Delta,
+		Description:   "Audit correction: " + finding.Field,
+		BalanceAfter:  wallet.Balance,
+		DedupKey:      fmt.Sprintf("lkm_audit:%d:%d:%s", reportID, wallet.ID, finding.Field),
+		AdminID:       actorID,
+		Reason:        fmt.Sprintf("LKM audit report %d", reportID),
+		AdjustedField: finding.Field,
+	}
+	if finding.Delta < 0 {
+		adjustment.Type = models.TransactionTypeAdjustmentDebit
+		adjustment.Amount = -finding.Delta
+	}
+	if err := tx.Create(&adjustment).Error; err != nil {
+		return err
+	}
+	finding.Repaired = true
+	finding.RepairRef = fmt.Sprintf("wallet_transaction:%d", adjustment.ID)
+	return nil
+}
+
+// ListReports returns the latest audit runs without their findings
+func (s *LKMAuditService) ListReports(limit int) ([]models.LKMAuditReport, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	reports := []models.LKMAuditReport{}
+	if err := s.db.Order("id DESC").Limit(limit).Find(&reports).Error; err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+func (s *LKMAuditService) GetReport(reportID uint) (*models.LKMAuditReport, error) {
+	var report models.LKMAuditReport
+	if err := s.db.Preload("Findings", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	}).First(&report, reportID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLKMAuditReportNotFound
+		}
+		return nil, err
+	}
+	return &report, nil
+}
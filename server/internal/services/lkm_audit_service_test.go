@@ -0,0 +1,72 @@
+package services
+
+import (
+	"rag-agent-server/internal/models"
+	"testing"
+)
+
+func TestReplayWalletTransactions(t *testing.T) {
+	t.Parallel()
+
+	bookingID := uint(7)
+	orderID := uint(9)
+	txs := []models.WalletTransaction{
+		{ID: 1, Type: models.TransactionTypeBonus, Amount: 50, BonusAmount: 50, Description: walletWelcomePendingDescription, BalanceAfter: 0},
+		{ID: 2, Type: models.TransactionTypeCredit, Amount: 1000, BalanceAfter: 1000},
+		{ID: 3, Type: models.TransactionTypeBonus, Amount: 50, BonusAmount: 50, Description: walletWelcomeActivatedDescription, BalanceAfter: 1000},
+		{ID: 4, Type: models.TransactionTypeDebit, Amount: 100, BonusAmount: 20, BalanceAfter: 920},
+		{ID: 5, Type: models.TransactionTypeHold, Amount: 300, BonusAmount: 10, BookingID: &bookingID, BalanceAfter: 630},
+		{ID: 6, Type: models.TransactionTypeRelease, Amount: 300, BonusAmount: 10, BookingID: &bookingID, BalanceAfter: 630},
+		{ID: 7, Type: models.TransactionTypeHold, Amount: 200, OrderID: &orderID, BalanceAfter: 430},
+		{ID: 8, Type: models.TransactionTypeRefund, Amount: 200, OrderID: &orderID, BalanceAfter: 630},
+		{ID: 9, Type: models.TransactionTypeRefund, Amount: 40, BonusAmount: 5, BalanceAfter: 665},
+		{ID: 10, Type: models.TransactionTypeAdminSeize, Amount: 65, BalanceAfter: 600},
+		{ID: 11, Type: models.TransactionTypeAdjustmentDebit, Amount: 15, AdjustedField: models.WalletFieldBonusBalance, BalanceAfter: 600},
+	}
+
+	got := replayWalletTransactions(txs)
+	want := walletBalances{Balance: 600, BonusBalance: 10, PendingBalance: 0, FrozenBalance: 0, FrozenBonusBalance: 0}
+	if got.Balance != want.Balance || got.BonusBalance != want.BonusBalance || got.PendingBalance != want.PendingBalance ||
+		got.FrozenBalance != want.FrozenBalance || got.FrozenBonusBalance != want.FrozenBonusBalance {
+		t.Fatalf("replayWalletTransactions() = %+v, want %+v", got, want)
+	}
+	if got.FirstDivergentTxID != nil {
+		t.Fatalf("FirstDivergentTxID = %d, want nil", *got.FirstDivergentTxID)
+	}
+}
+
+func TestReplayWalletTransactionsFindsDivergence(t *testing.T) {
+	t.Parallel()
+
+	txs := []models.WalletTransaction{
+		{ID: 1, Type: models.TransactionTypeCredit, Amount: 100, BalanceAfter: 100},
+		// Balance was changed without a transaction between these two rows
+		{ID: 2, Type: models.TransactionTypeCredit, Amount: 100, BalanceAfter: 250},
+		{ID: 3, Type: models.TransactionTypeDebit, Amount: 50, BalanceAfter: 200},
+	}
+	got := replayWalletTransactions(txs)
+	if got.FirstDivergentTxID == nil || *got.FirstDivergentTxID != 2 {
+		t.Fatalf("FirstDivergentTxID = %v, want 2", got.FirstDivergentTxID)
+	}
+}
+
+func TestWalletDrift(t *testing.T) {
+	t.Parallel()
+
+	wallet := &models.Wallet{ID: 3, Balance: 250, BonusBalance: 10, FrozenBalance: 40}
+	replayed := walletBalances{Balance: 150, BonusBalance: 10, FrozenBalance: 60}
+
+	got := walletDrift(wallet, replayed)
+	if len(got) != 2 {
+		t.Fatalf("walletDrift() returned %d findings (%+v), want 2", len(got), got)
+	}
+	if got[0].Field != models.WalletFieldBalance || got[0].Delta != 100 || got[0].Expected != 150 || got[0].Actual != 250 {
+		t.Fatalf("balance finding = %+v", got[0])
+	}
+	if got[1].Field != models.WalletFieldFrozenBalance || got[1].Delta != -20 {
+		t.Fatalf("frozen finding = %+v", got[1])
+	}
+	if got[0].WalletID == nil || *got[0].WalletID != 3 {
+		t.Fatalf("finding wallet = %v, want 3", got[0].WalletID)
+	}
+}
@@ -0,0 +1,538 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"rag-agent-server/internal/database"
+	"rag-agent-server/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrLKMReconciliationNotFound = errors.New("reconciliation report not found")
+	ErrLKMDiscrepancyNotFound    = errors.New("discrepancy not found")
+)
+
+const (
+	defaultLKMReconcileAfterMinutes = 30
+	defaultLKMExpireAfterHours      = 24
+	lkmRecoveryBatchSize            = 100
+	// Off by default: without an adapter a manually confirmed payment may still arrive
+	lkmExpireWithoutAdapterSetting = "LKM_TOPUP_EXPIRE_WITHOUT_ADAPTER"
+)
+
+// LKMReconciliationService recovers top-ups whose webhook was lost and builds the daily
+// reconciliation report for finance admins
+type LKMReconciliationService struct {
+	db            *gorm.DB
+	topups        *LKMTopupService
+	notifications *AdminNotificationService
+	now           func() time.Time
+}
+
+// LKMRecoveryResult summarizes one recovery pass
+type LKMRecoveryResult struct {
+	Credited int
+	Review   int
+	Expired  int
+	Failed   int
+}
+
+func NewLKMReconciliationService(topups *LKMTopupService) *LKMReconciliationService {
+	db := topups.db
+	if db == nil {
+		db = database.DB
+	}
+	return &LKMReconciliationService{
+		db:            db,
+		topups:        topups,
+		notifications: NewAdminNotificationService(db),
+		now:           time.Now,
+	}
+}
+
+// RecoverStale polls the gateway for top-ups that have been waiting longer than
+// LKM_TOPUP_RECONCILE_AFTER_MINUTES. Settled payments are credited through the regular
+// webhook path under a reconciliation event ID; abandoned ones are expired. Top-ups of
+// gateways without an adapter are only expired when LKM_TOPUP_EXPIRE_WITHOUT_ADAPTER is on.
+func (s *LKMReconciliationService) RecoverStale(now time.Time) (LKMRecoveryResult, error) {
+	var result LKMRecoveryResult
+	after := time.Duration(marketIntSetting(s.db, "LKM_TOPUP_RECONCILE_AFTER_MINUTES", defaultLKMReconcileAfterMinutes)) * time.Minute
+	expireAfter := time.Duration(marketIntSetting(s.db, "LKM_TOPUP_EXPIRE_AFTER_HOURS", defaultLKMExpireAfterHours)) * time.Hour
+	expireWithoutAdapter := parseBoolSetting(marketSetting(s.db, lkmExpireWithoutAdapterSetting, ""), false)
+
+	var stale []models.LKMTopup
+	if err := s.db.Where("status IN ? AND created_at <= ?",
+		[]models.LKMTopupStatus{models.LKMTopupStatusPendingPayment, models.LKMTopupStatusPaid}, now.Add(-after)).
+		Order("created_at ASC").Limit(lkmRecoveryBatchSize).
+		Find(&stale).Error; err != nil {
+		return result, err
+	}
+
+	for i := range stale {
+		topup := &stale[i]
+		outcome, err := s.recoverOne(topup, now, expireAfter, expireWithoutAdapter)
+		if err != nil {
+			result.Failed++
+			log.Printf("[LKMReconcile] Recovery of top-up %s failed: %v", topup.TopupID, err)
+			continue
+		}
+		switch outcome {
+		case models.LKMTopupStatusCredited:
+			result.Credited++
+		case models.LKMTopupStatusManualReview:
+			result.Review++
+		case models.LKMTopupStatusExpired:
+			result.Expired++
+		}
+	}
+	return result, nil
+}
+
+func (s *LKMReconciliationService) recoverOne(topup *models.LKMTopup, now time.Time, expireAfter time.Duration, expireWithoutAdapter bool) (models.LKMTopupStatus, error) {
+	// Paid but never credited: replay the paid event, the wallet dedup key prevents a double credit
+	if topup.Status == models.LKMTopupStatusPaid {
+		return s.applyPaid(topup, nil)
+	}
+
+	gateway, ok := s.topups.gateways.Get(topup.GatewayCode)
+	if !ok && !expireWithoutAdapter {
+		// Nothing to poll; the top-up waits for its webhook or an admin
+		return topup.Status, nil
+	}
+	if !ok || topup.ExternalPaymentID == "" {
+		if now.Sub(topup.CreatedAt) >= expireAfter {
+			return s.expire(topup, "not_paid")
+		}
+		return topup.Status, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), lkmGatewayCallTimeout)
+	defer cancel()
+	status, err := gateway.FetchStatus(ctx, topup.ExternalPaymentID)
+	if err != nil {
+		// Never expire a top-up we could not check: the payer may have paid
+		return topup.Status, err
+	}
+
+	switch status.Status {
+	case GatewayStatusPaid:
+		return s.applyPaid(topup, status)
+	case GatewayStatusCancelled, GatewayStatusFailed, GatewayStatusExpired:
+		return s.expire(topup, "gateway_"+status.Status)
+	default:
+		if now.Sub(topup.CreatedAt) >= expireAfter {
+			return s.expire(topup, "not_paid")
+		}
+		return topup.Status, nil
+	}
+}
+
+func (s *LKMReconciliationService) applyPaid(topup *models.LKMTopup, status *GatewayPaymentStatus) (models.LKMTopupStatus, error) {
+	request := LKMWebhookRequest{
+		EventID:           "reconcile:" + topup.TopupID + ":paid",
+		TopupID:           topup.TopupID,
+		Status:            GatewayStatusPaid,
+		ExternalPaymentID: topup.ExternalPaymentID,
+	}
+	if status != nil {
+		event := &GatewayWebhookEvent{
+			ExternalPaymentID: status.ExternalPaymentID,
+			Status:            status.Status,
+			Amount:            status.Amount,
+			Currency:          status.Currency,
+		}
+		if err := checkWebhookMatchesTopup(event, topup); err != nil {
+			// Left pending; the daily report flags it for finance
+			return topup.Status, err
+		}
+		request.Payload, _ = json.Marshal(status)
+	}
+
+	updated, err := s.topups.HandleWebhook(topup.GatewayCode, request)
+	if err != nil {
+		return topup.Status, err
+	}
+	return updated.Status, nil
+}
+
+func (s *LKMReconciliationService) expire(topup *models.LKMTopup, reason string) (models.LKMTopupStatus, error) {
+	res := s.db.Model(&models.LKMTopup{}).
+		Where("id = ? AND status = ?", topup.ID, models.LKMTopupStatusPendingPayment).
+		Updates(map[string]interface{}{
+			"status":      models.LKMTopupStatusExpired,
+			"risk_reason": reason,
+		})
+	if res.Error != nil {
+		return topup.Status, res.Error
+	}
+	if res.RowsAffected == 0 {
+		// A webhook got there first
+		return topup.Status, nil
+	}
+	return models.LKMTopupStatusExpired, nil
+}
+
+// topupEvidence is everything known about a top-up from our own records and the provider
+type topupEvidence struct {
+	hasPaidEvent    bool
+	walletCredit    int
+	hasWalletCredit bool
+	gateway         *GatewayPaymentStatus
+}
+
+// GenerateDailyReport reconciles the top-ups created or credited on the given UTC day.
+// Stale top-up recovery may credit or expire them until LKM_TOPUP_EXPIRE_AFTER_HOURS after
+// the day ends: until then every run rebuilds the report and leaves out what recovery is
+// still expected to settle. After that the report is finalized and re-running returns it.
+func (s *LKMReconciliationService) GenerateDailyReport(day time.Time) (*models.LKMReconciliationReport, error) {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+	reportDate := start.Format("2006-01-02")
+	now := s.now().UTC()
+
+	var existing models.LKMReconciliationReport
+	if err := s.db.Where("report_date = ?", reportDate).First(&existing).Error; err == nil {
+		if existing.FinalizedAt != nil {
+			return &existing, nil
+		}
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	expireAfter := time.Duration(marketIntSetting(s.db, "LKM_TOPUP_EXPIRE_AFTER_HOURS", defaultLKMExpireAfterHours)) * time.Hour
+	final := !now.Before(end.Add(expireAfter))
+
+	var topups []models.LKMTopup
+	if err := s.db.Where("(created_at >= ? AND created_at < ?) OR (credited_at >= ? AND credited_at < ?)", start, end, start, end).
+		Order("created_at ASC").Find(&topups).Error; err != nil {
+		return nil, err
+	}
+
+	evidence, gatewayChecked, err := s.collectEvidence(topups)
+	if err != nil {
+		return nil, err
+	}
+
+	built := models.LKMReconciliationReport{
+		TopupsChecked:  len(topups),
+		GatewayChecked: gatewayChecked,
+	}
+	for i := range topups {
+		topup := &topups[i]
+		if topup.Status == models.LKMTopupStatusCredited {
+			built.CreditedCount++
+			built.CreditedLKM += topup.ReceiveLKM
+		}
+		recoveryPending := !final && isRecoverableTopupStatus(topup.Status)
+		built.Discrepancies = append(built.Discrepancies, reconcileTopup(topup, evidence[topup.TopupID], recoveryPending)...)
+	}
+
+	var report models.LKMReconciliationReport
+	notify := false
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Instances racing on the same day: one insert wins, the rest wait for its row lock
+		placeholder := models.LKMReconciliationReport{
+			ReportDate:  reportDate,
+			PeriodStart: start,
+			PeriodEnd:   end,
+			Status:      models.LKMReconciliationClean,
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&placeholder).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("report_date = ?", reportDate).First(&report).Error; err != nil {
+			return err
+		}
+		if report.FinalizedAt != nil {
+			return nil
+		}
+
+		// Open findings are rebuilt; resolved ones keep their resolution
+		var resolved []models.LKMReconciliationDiscrepancy
+		if err := tx.Where("report_id = ? AND resolved = ?", report.ID, true).Order("id ASC").Find(&resolved).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("report_id = ? AND resolved = ?", report.ID, false).
+			Delete(&models.LKMReconciliationDiscrepancy{}).Error; err != nil {
+			return err
+		}
+		settled := make(map[string]bool, len(resolved))
+		for _, d := range resolved {
+			settled[d.TopupID+"|"+string(d.Kind)] = true
+		}
+		open := make([]models.LKMReconciliationDiscrepancy, 0, len(built.Discrepancies))
+		for _, d := range built.Discrepancies {
+			if settled[d.TopupID+"|"+string(d.Kind)] {
+				continue
+			}
+			d.ReportID = report.ID
+			open = append(open, d)
+		}
+		if len(open) > 0 {
+			if err := tx.Create(&open).Error; err != nil {
+				return err
+			}
+		}
+
+		previousCount := report.DiscrepancyCount
+		report.TopupsChecked = built.TopupsChecked
+		report.GatewayChecked = built.GatewayChecked
+		report.CreditedCount = built.CreditedCount
+		report.CreditedLKM = built.CreditedLKM
+		report.DiscrepancyCount = len(resolved) + len(open)
+		report.Status = models.LKMReconciliationClean
+		if report.DiscrepancyCount > 0 {
+			report.Status = models.LKMReconciliationDiscrepancies
+		}
+		if final {
+			report.FinalizedAt = &now
+		}
+		if err := tx.Model(&report).Select("topups_checked", "gateway_checked", "credited_count", "credited_lkm",
+			"discrepancy_count", "status", "finalized_at").Updates(&report).Error; err != nil {
+			return err
+		}
+		report.Discrepancies = append(resolved, open...)
+		notify = len(open) > 0 && (report.NotifiedAt == nil || report.DiscrepancyCount > previousCount)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if notify {
+		s.flagToFinance(&report)
+	}
+	return &report, nil
+}
+
+// RefreshReports builds the report for the day before now and rebuilds the earlier ones
+// that are not final yet
+func (s *LKMReconciliationService) RefreshReports(now time.Time) error {
+	yesterday := now.UTC().AddDate(0, 0, -1)
+	days := []time.Time{yesterday}
+	var open []models.LKMReconciliationReport
+	if err := s.db.Select("id", "report_date", "period_start").
+		Where("finalized_at IS NULL AND report_date <> ?", yesterday.Format("2006-01-02")).
+		Order("report_date ASC").Find(&open).Error; err != nil {
+		return err
+	}
+	for _, report := range open {
+		days = append(days, report.PeriodStart)
+	}
+
+	var firstErr error
+	for _, day := range days {
+		if _, err := s.GenerateDailyReport(day); err != nil {
+			log.Printf("[LKMReconcile] Report for %s failed: %v", day.Format("2006-01-02"), err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// isRecoverableTopupStatus reports top-ups that RecoverStale still polls and may credit
+func isRecoverableTopupStatus(status models.LKMTopupStatus) bool {
+	return status == models.LKMTopupStatusPendingPayment || status == models.LKMTopupStatusPaid
+}
+
+func (s *LKMReconciliationService) collectEvidence(topups []models.LKMTopup) (map[string]topupEvidence, int, error) {
+	evidence := make(map[string]topupEvidence, len(topups))
+	if len(topups) == 0 {
+		return evidence, 0, nil
+	}
+
+	topupIDs := make([]string, 0, len(topups))
+	dedupKeys := make([]string, 0, len(topups))
+	for _, topup := range topups {
+		topupIDs = append(topupIDs, topup.TopupID)
+		dedupKeys = append(dedupKeys, fmt.Sprintf("lkm_topup:%s", topup.TopupID))
+	}
+
+	var events []models.LKMTopupWebhookEvent
+	if err := s.db.Where("topup_id IN ?", topupIDs).Find(&events).Error; err != nil {
+		return nil, 0, err
+	}
+	for _, event := range events {
+		if isPaidWebhookStatus(event.Status) {
+			item := evidence[event.TopupID]
+			item.hasPaidEvent = true
+			evidence[event.TopupID] = item
+		}
+	}
+
+	var credits []models.WalletTransaction
+	if err := s.db.Where("dedup_key IN ? AND type = ?", dedupKeys, models.TransactionTypeCredit).Find(&credits).Error; err != nil {
+		return nil, 0, err
+	}
+	for _, credit := range credits {
+		topupID := strings.TrimPrefix(credit.DedupKey, "lkm_topup:")
+		item := evidence[topupID]
+		item.hasWalletCredit = true
+		item.walletCredit += credit.Amount
+		evidence[topupID] = item
+	}
+
+	gatewayChecked := 0
+	for _, topup := range topups {
+		gateway, ok := s.topups.gateways.Get(topup.GatewayCode)
+		if !ok || topup.ExternalPaymentID == "" {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), lkmGatewayCallTimeout)
+		status, err := gateway.FetchStatus(ctx, topup.ExternalPaymentID)
+		cancel()
+		if err != nil {
+			log.Printf("[LKMReconcile] Could not fetch %s status for top-up %s: %v", topup.GatewayCode, topup.TopupID, err)
+			continue
+		}
+		gatewayChecked++
+		item := evidence[topup.TopupID]
+		item.gateway = status
+		evidence[topup.TopupID] = item
+	}
+	return evidence, gatewayChecked, nil
+}
+
+// reconcileTopup compares a top-up with its webhook events, wallet credit and provider state.
+// With recoveryPending a settled but uncredited payment is not flagged yet: RecoverStale
+// credits it on its next pass.
+func reconcileTopup(topup *models.LKMTopup, evidence topupEvidence, recoveryPending bool) []models.LKMReconciliationDiscrepancy {
+	var found []models.LKMReconciliationDiscrepancy
+	flag := func(kind models.LKMDiscrepancyKind, format string, args ...interface{}) {
+		found = append(found, models.LKMReconciliationDiscrepancy{
+			TopupID:     topup.TopupID,
+			GatewayCode: topup.GatewayCode,
+			Kind:        kind,
+			Details:     truncateText(fmt.Sprintf(format, args...), 490),
+		})
+	}
+
+	gatewayPaid := evidence.gateway != nil && evidence.gateway.Status == GatewayStatusPaid
+
+	if topup.Status == models.LKMTopupStatusCredited {
+		switch {
+		case !evidence.hasWalletCredit:
+			flag(models.LKMDiscrepancyWalletCreditMissing, "top-up is credited but no wallet credit exists")
+		case evidence.walletCredit != topup.ReceiveLKM:
+			flag(models.LKMDiscrepancyWalletCreditMismatch, "wallet credit %d LKM, top-up %d LKM", evidence.walletCredit, topup.ReceiveLKM)
+		}
+		if !evidence.hasPaidEvent {
+			flag(models.LKMDiscrepancyCreditedWithoutEvent, "credited without a paid webhook event")
+		}
+		if evidence.gateway != nil && !gatewayPaid {
+			flag(models.LKMDiscrepancyCreditedNotSettled, "gateway reports %q for payment %s", evidence.gateway.ProviderStatus, topup.ExternalPaymentID)
+		}
+	} else {
+		if evidence.hasWalletCredit {
+			flag(models.LKMDiscrepancyUnexpectedWalletCredit, "wallet credit %d LKM while top-up is %s", evidence.walletCredit, topup.Status)
+		}
+		if gatewayPaid && topup.Status != models.LKMTopupStatusManualReview && !recoveryPending {
+			flag(models.LKMDiscrepancyGatewayPaidNotCredited, "gateway settled payment %s, top-up is %s", topup.ExternalPaymentID, topup.Status)
+		}
+		if evidence.hasPaidEvent && !recoveryPending &&
+			(topup.Status == models.LKMTopupStatusPendingPayment || topup.Status == models.LKMTopupStatusExpired) {
+			flag(models.LKMDiscrepancyPaidEventNotApplied, "paid webhook event recorded, top-up is %s", topup.Status)
+		}
+	}
+
+	if gatewayPaid {
+		if math.Abs(evidence.gateway.Amount-topup.TotalPayAmount) > 0.01 ||
+			(evidence.gateway.Currency != "" && !strings.EqualFold(evidence.gateway.Currency, topup.PayCurrency)) {
+			flag(models.LKMDiscrepancyAmountMismatch, "gateway settled %.2f %s, quoted %.2f %s",
+				evidence.gateway.Amount, evidence.gateway.Currency, topup.TotalPayAmount, topup.PayCurrency)
+		}
+	}
+	return found
+}
+
+// flagToFinance raises an admin notification and pushes finance admins
+func (s *LKMReconciliationService) flagToFinance(report *models.LKMReconciliationReport) {
+	message := fmt.Sprintf("Сверка пополнений LKM за %s: найдено расхождений — %d", report.ReportDate, report.DiscrepancyCount)
+	if err := s.notifications.CreateNotification(models.AdminNotificationCreateRequest{
+		Type:    models.NotificationLKMReconciliation,
+		Message: message,
+		LinkTo:  fmt.Sprintf("/admin/lkm/reconciliation/%d", report.ID),
+	}); err != nil {
+		log.Printf("[LKMReconcile] Failed to create admin notification for %s: %v", report.ReportDate, err)
+	}
+
+	var financeAdminIDs []uint
+	if err := s.db.Model(&models.User{}).
+		Where("role = ? OR id IN (?)", models.RoleSuperadmin,
+			s.db.Model(&models.AdminPermissionGrant{}).Select("user_id").
+				Where("permission IN ?", []models.AdminPermission{models.AdminPermissionFinanceManager, models.AdminPermissionFinanceApprover})).
+		Pluck("id", &financeAdminIDs).Error; err != nil {
+		log.Printf("[LKMReconcile] Failed to load finance admins: %v", err)
+		return
+	}
+	push := GetPushService()
+	for _, adminID := range financeAdminIDs {
+		if err := push.SendLKMReconciliationAlert(adminID, report.ID, report.ReportDate, report.DiscrepancyCount); err != nil {
+			log.Printf("[LKMReconcile] Push to admin %d failed: %v", adminID, err)
+		}
+	}
+
+	now := s.now().UTC()
+	report.NotifiedAt = &now
+	if err := s.db.Model(report).Update("notified_at", now).Error; err != nil {
+		log.Printf("[LKMReconcile] Failed to mark report %s notified: %v", report.ReportDate, err)
+	}
+}
+
+// ListReports returns the latest reports without their discrepancies
+func (s *LKMReconciliationService) ListReports(limit int) ([]models.LKMReconciliationReport, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 30
+	}
+	reports := []models.LKMReconciliationReport{}
+	if err := s.db.Order("report_date DESC").Limit(limit).Find(&reports).Error; err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+func (s *LKMReconciliationService) GetReport(reportID uint) (*models.LKMReconciliationReport, error) {
+	var report models.LKMReconciliationReport
+	if err := s.db.Preload("Discrepancies", func(db *gorm.DB) *gorm.DB {
+		return db.Order("resolved ASC, id ASC")
+	}).First(&report, reportID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLKMReconciliationNotFound
+		}
+		return nil, err
+	}
+	return &report, nil
+}
+
+// ResolveDiscrepancy records how finance settled a discrepancy
+func (s *LKMReconciliationService) ResolveDiscrepancy(discrepancyID, adminID uint, note string) (*models.LKMReconciliationDiscrepancy, error) {
+	var discrepancy models.LKMReconciliationDiscrepancy
+	if err := s.db.First(&discrepancy, discrepancyID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLKMDiscrepancyNotFound
+		}
+		return nil, err
+	}
+
+	now := s.now().UTC()
+	discrepancy.Resolved = true
+	discrepancy.ResolvedByID = &adminID
+	discrepancy.ResolvedAt = &now
+	discrepancy.ResolutionNote = truncateText(strings.TrimSpace(note), 490)
+	if err := s.db.Model(&discrepancy).Select("resolved", "resolved_by_id", "resolved_at", "resolution_note").Updates(&discrepancy).Error; err != nil {
+		return nil, err
+	}
+	return &discrepancy, nil
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"rag-agent-server/internal/models"
+	"testing"
+)
+
+func TestReconcileTopup(t *testing.T) {
+	t.Parallel()
+
+	credited := models.LKMTopup{
+		TopupID:           "tp_1",
+		GatewayCode:       "stripe",
+		ExternalPaymentID: "pi_1",
+		Status:            models.LKMTopupStatusCredited,
+		ReceiveLKM:        500,
+		TotalPayAmount:    10,
+		PayCurrency:       "USD",
+	}
+	pending := credited
+	pending.Status = models.LKMTopupStatusPendingPayment
+
+	paid := &GatewayPaymentStatus{Status: GatewayStatusPaid, ProviderStatus: "succeeded", Amount: 10, Currency: "usd"}
+
+	tests := []struct {
+		name            string
+		topup           models.LKMTopup
+		evidence        topupEvidence
+		recoveryPending bool
+		want            []models.LKMDiscrepancyKind
+	}{
+		{
+			name:     "consistent credit",
+			topup:    credited,
+			evidence: topupEvidence{hasPaidEvent: true, hasWalletCredit: true, walletCredit: 500, gateway: paid},
+		},
+		{
+			name:     "credited without wallet transaction or event",
+			topup:    credited,
+			evidence: topupEvidence{},
+			want:     []models.LKMDiscrepancyKind{models.LKMDiscrepancyWalletCreditMissing, models.LKMDiscrepancyCreditedWithoutEvent},
+		},
+		{
+			name:     "wallet credit differs",
+			topup:    credited,
+			evidence: topupEvidence{hasPaidEvent: true, hasWalletCredit: true, walletCredit: 400},
+			want:     []models.LKMDiscrepancyKind{models.LKMDiscrepancyWalletCreditMismatch},
+		},
+		{
+			name:  "credited but not settled",
+			topup: credited,
+			evidence: topupEvidence{hasPaidEvent: true, hasWalletCredit: true, walletCredit: 500,
+				gateway: &GatewayPaymentStatus{Status: GatewayStatusPending, ProviderStatus: "processing"}},
+			want: []models.LKMDiscrepancyKind{models.LKMDiscrepancyCreditedNotSettled},
+		},
+		{
+			name:     "settled but still pending",
+			topup:    pending,
+			evidence: topupEvidence{hasPaidEvent: true, gateway: paid},
+			want:     []models.LKMDiscrepancyKind{models.LKMDiscrepancyGatewayPaidNotCredited, models.LKMDiscrepancyPaidEventNotApplied},
+		},
+		{
+			name:            "settled and left to recovery",
+			topup:           pending,
+			evidence:        topupEvidence{hasPaidEvent: true, gateway: paid},
+			recoveryPending: true,
+		},
+		{
+			name:     "wallet credit for pending top-up",
+			topup:    pending,
+			evidence: topupEvidence{hasWalletCredit: true, walletCredit: 500},
+			want:     []models.LKMDiscrepancyKind{models.LKMDiscrepancyUnexpectedWalletCredit},
+		},
+		{
+			name:  "settled amount differs",
+			topup: credited,
+			evidence: topupEvidence{hasPaidEvent: true, hasWalletCredit: true, walletCredit: 500,
+				gateway: &GatewayPaymentStatus{Status: GatewayStatusPaid, Amount: 9.5, Currency: "USD"}},
+			want: []models.LKMDiscrepancyKind{models.LKMDiscrepancyAmountMismatch},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := reconcileTopup(&tt.topup, tt.evidence, tt.recoveryPending)
+			if len(got) != len(tt.want) {
+				t.Fatalf("reconcileTopup() returned %d discrepancies (%v), want %v", len(got), got, tt.want)
+			}
+			for i, kind := range tt.want {
+				if got[i].Kind != kind {
+					t.Fatalf("discrepancy %d = %s, want %s", i, got[i].Kind, kind)
+				}
+				if got[i].TopupID != tt.topup.TopupID {
+					t.Fatalf("discrepancy %d topup = %q, want %q", i, got[i].TopupID, tt.topup.TopupID)
+				}
+			}
+		})
+	}
+}
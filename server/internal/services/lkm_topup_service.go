@@ -1,10 +1,14 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"math"
+	"net/url"
+	"os"
 	"strings"
 	"time"
 
@@ -28,13 +32,16 @@ var (
 )
 
 const (
-	defaultLKMQuoteTTL = 10 * time.Minute
-	currencyRUB        = "RUB"
+	defaultLKMQuoteTTL      = 10 * time.Minute
+	currencyRUB             = "RUB"
+	lkmGatewayCallTimeout   = 20 * time.Second
+	defaultLKMTopupReturnTo = "https://lkm.vedamatch.ru/topup/result"
 )
 
 type LKMTopupService struct {
 	db            *gorm.DB
 	walletService *WalletService
+	gateways      *PaymentGatewayRegistry
 	now           func() time.Time
 	quoteTTL      time.Duration
 }
@@ -107,6 +114,7 @@ type LKMTopupResponse struct {
 	PayCurrency    string    `json:"payCurrency"`
 	GatewayCode    string    `json:"gatewayCode"`
 	PaymentMethod  string    `json:"paymentMethod"`
+	PaymentURL     string    `json:"paymentUrl,omitempty"`
 	CreatedAt      time.Time `json:"createdAt"`
 }
 
@@ -157,11 +165,17 @@ func NewLKMTopupServiceWithDB(db *gorm.DB, walletService *WalletService) *LKMTop
 	return &LKMTopupService{
 		db:            db,
 		walletService: walletService,
+		gateways:      NewPaymentGatewayRegistryFromEnv(),
 		now:           time.Now,
 		quoteTTL:      defaultLKMQuoteTTL,
 	}
 }
 
+// SetPaymentGateways replaces the provider adapters, e.g. with the mock gateway in tests
+func (s *LKMTopupService) SetPaymentGateways(registry *PaymentGatewayRegistry) {
+	s.gateways = registry
+}
+
 func (s *LKMTopupService) ensureDB() error {
 	if s.db == nil {
 		return errors.New("database is not initialized")
@@ -626,6 +640,7 @@ func (s *LKMTopupService) CreateTopupFromQuote(userID uint, req LKMCreateTopupRe
 	}
 
 	var response *LKMTopupResponse
+	var created models.LKMTopup
 	err := s.db.Transaction(func(tx *gorm.DB) error {
 		var quote models.LKMQuote
 		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
@@ -684,6 +699,7 @@ func (s *LKMTopupService) CreateTopupFromQuote(userID uint, req LKMCreateTopupRe
 			return err
 		}
 
+		created = record
 		response = &LKMTopupResponse{
 			TopupID:        record.TopupID,
 			QuoteID:        record.QuoteID,
@@ -701,9 +717,63 @@ func (s *LKMTopupService) CreateTopupFromQuote(userID uint, req LKMCreateTopupRe
 	if err != nil {
 		return nil, err
 	}
+
+	if gateway, ok := s.gateways.Get(created.GatewayCode); ok {
+		paymentURL, err := s.startGatewayPayment(gateway, &created)
+		if err != nil {
+			return nil, err
+		}
+		response.PaymentURL = paymentURL
+	}
 	return response, nil
 }
 
+// startGatewayPayment registers the top-up with the provider. A top-up the provider
+// refused is rejected, so it never lingers as payable.
+func (s *LKMTopupService) startGatewayPayment(gateway PaymentGateway, topup *models.LKMTopup) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), lkmGatewayCallTimeout)
+	defer cancel()
+
+	payment, err := gateway.CreatePayment(ctx, GatewayPaymentRequest{
+		TopupID:     topup.TopupID,
+		UserID:      topup.UserID,
+		Amount:      topup.TotalPayAmount,
+		Currency:    topup.PayCurrency,
+		Description: fmt.Sprintf("Пополнение %d LKM", topup.ReceiveLKM),
+		ReturnURL:   lkmTopupReturnURL(topup.TopupID),
+	})
+	if err != nil {
+		log.Printf("[LKM] %s payment creation failed for top-up %s: %v", gateway.Code(), topup.TopupID, err)
+		if updateErr := s.db.Model(topup).Updates(map[string]interface{}{
+			"status":      models.LKMTopupStatusRejected,
+			"risk_reason": "gateway_error",
+		}).Error; updateErr != nil {
+			log.Printf("[LKM] Failed to reject top-up %s: %v", topup.TopupID, updateErr)
+		}
+		if errors.Is(err, ErrPaymentGatewayUnavailable) {
+			return "", err
+		}
+		return "", fmt.Errorf("%w: %v", ErrPaymentGatewayUnavailable, err)
+	}
+
+	if err := s.db.Model(topup).Update("external_payment_id", payment.ExternalPaymentID).Error; err != nil {
+		return "", err
+	}
+	return payment.RedirectURL, nil
+}
+
+func lkmTopupReturnURL(topupID string) string {
+	base := strings.TrimSpace(os.Getenv("LKM_TOPUP_RETURN_URL"))
+	if base == "" {
+		base = defaultLKMTopupReturnTo
+	}
+	separator := "?"
+	if strings.Contains(base, "?") {
+		separator = "&"
+	}
+	return base + separator + "topupId=" + url.QueryEscape(topupID)
+}
+
 func isPaidWebhookStatus(status string) bool {
 	value := strings.ToLower(strings.TrimSpace(status))
 	return value == "paid" || value == "succeeded" || value == "success" || value == "captured"
@@ -871,6 +941,75 @@ func (s *LKMTopupService) HandleWebhook(gatewayCode string, req LKMWebhookReques
 	return &result, nil
 }
 
+// HandleGatewayWebhook verifies a provider notification with the gateway adapter before
+// it reaches HandleWebhook. Unsigned, forged and mismatched notifications are logged and
+// rejected; nothing is recorded for them.
+func (s *LKMTopupService) HandleGatewayWebhook(gatewayCode string, webhook GatewayWebhook) (*models.LKMTopup, error) {
+	if err := s.ensureDB(); err != nil {
+		return nil, err
+	}
+	code := strings.ToLower(strings.TrimSpace(gatewayCode))
+	gateway, ok := s.gateways.Get(code)
+	if !ok {
+		log.Printf("[LKM] Rejected webhook for unconfigured gateway %q", code)
+		return nil, ErrPaymentGatewayNotConfigured
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), lkmGatewayCallTimeout)
+	defer cancel()
+	event, err := gateway.VerifyWebhook(ctx, webhook)
+	if err != nil {
+		if !errors.Is(err, ErrPaymentWebhookIgnored) {
+			log.Printf("[LKM] Rejected %s webhook (%d bytes): %v", code, len(webhook.Body), err)
+		}
+		return nil, err
+	}
+
+	var topup models.LKMTopup
+	query := s.db.Where("gateway_code = ?", code)
+	if event.TopupID != "" {
+		query = query.Where("topup_id = ?", event.TopupID)
+	} else {
+		query = query.Where("external_payment_id = ? AND external_payment_id <> ''", event.ExternalPaymentID)
+	}
+	if err := query.First(&topup).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("[LKM] Rejected %s webhook %s: unknown top-up %q", code, event.EventID, event.TopupID)
+			return nil, ErrLKMTopupNotFound
+		}
+		return nil, err
+	}
+	if err := checkWebhookMatchesTopup(event, &topup); err != nil {
+		log.Printf("[LKM] Rejected %s webhook %s for top-up %s: %v", code, event.EventID, topup.TopupID, err)
+		return nil, err
+	}
+
+	return s.HandleWebhook(code, LKMWebhookRequest{
+		EventID:           event.EventID,
+		TopupID:           topup.TopupID,
+		Status:            event.Status,
+		ExternalPaymentID: event.ExternalPaymentID,
+		Payload:           json.RawMessage(webhook.Body),
+	})
+}
+
+// checkWebhookMatchesTopup refuses to credit a paid event whose payment differs from the top-up
+func checkWebhookMatchesTopup(event *GatewayWebhookEvent, topup *models.LKMTopup) error {
+	if topup.ExternalPaymentID != "" && event.ExternalPaymentID != "" && topup.ExternalPaymentID != event.ExternalPaymentID {
+		return fmt.Errorf("%w: payment %s belongs to another top-up", ErrPaymentWebhookMismatch, event.ExternalPaymentID)
+	}
+	if event.Status != GatewayStatusPaid {
+		return nil
+	}
+	if event.Currency != "" && !strings.EqualFold(event.Currency, topup.PayCurrency) {
+		return fmt.Errorf("%w: currency %s, expected %s", ErrPaymentWebhookMismatch, event.Currency, topup.PayCurrency)
+	}
+	if math.Abs(event.Amount-topup.TotalPayAmount) > 0.01 {
+		return fmt.Errorf("%w: amount %.2f, expected %.2f", ErrPaymentWebhookMismatch, event.Amount, topup.TotalPayAmount)
+	}
+	return nil
+}
+
 func (s *LKMTopupService) GetAdminConfig() (*LKMAdminConfig, error) {
 	if err := s.ensureDB(); err != nil {
 		return nil, err
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
@@ -380,3 +381,145 @@ func TestLKMTopup_RiskRoutingAndWalletCredit_Integration(t *testing.T) {
 		t.Fatalf("manual tier expected manual_review, got %s", manualProcessed.Status)
 	}
 }
+
+func TestLKMRecoverStale_GatewayWithoutAdapter_Integration(t *testing.T) {
+	db := setupLKMTopupIntegrationDB(t)
+	if err := db.AutoMigrate(&models.SystemSetting{}, &models.AdminNotification{}); err != nil {
+		t.Fatalf("auto-migrate failed: %v", err)
+	}
+	svc := NewLKMTopupServiceWithDB(db, NewWalletService())
+	svc.gateways = NewPaymentGatewayRegistry()
+	upsertSystemSetting(t, db, lkmExpireWithoutAdapterSetting, "false")
+	user := createLKMTopupTestUser(t, db, "recover-no-adapter")
+
+	quote, err := svc.CreateQuote(user.ID, LKMQuoteRequest{
+		LKMAmount:     500,
+		Region:        "cis",
+		Currency:      "RUB",
+		GatewayCode:   "yookassa",
+		PaymentMethod: "default",
+	}, "lkm.vedamatch.ru")
+	if err != nil {
+		t.Fatalf("quote failed: %v", err)
+	}
+	topup, err := svc.CreateTopupFromQuote(user.ID, LKMCreateTopupRequest{QuoteID: quote.QuoteID, Channel: "web"})
+	if err != nil {
+		t.Fatalf("topup failed: %v", err)
+	}
+	if err := db.Model(&models.LKMTopup{}).Where("topup_id = ?", topup.TopupID).
+		Update("created_at", time.Now().Add(-72*time.Hour)).Error; err != nil {
+		t.Fatalf("backdate topup: %v", err)
+	}
+
+	reconciler := NewLKMReconciliationService(svc)
+	result, err := reconciler.RecoverStale(time.Now())
+	if err != nil {
+		t.Fatalf("RecoverStale() error = %v", err)
+	}
+	var reloaded models.LKMTopup
+	if err := db.Where("topup_id = ?", topup.TopupID).First(&reloaded).Error; err != nil {
+		t.Fatalf("reload topup: %v", err)
+	}
+	if result.Expired != 0 || reloaded.Status != models.LKMTopupStatusPendingPayment {
+		t.Fatalf("top-up without adapter expired by default: status=%s result=%+v", reloaded.Status, result)
+	}
+
+	upsertSystemSetting(t, db, lkmExpireWithoutAdapterSetting, "true")
+	if _, err := reconciler.RecoverStale(time.Now()); err != nil {
+		t.Fatalf("RecoverStale() error = %v", err)
+	}
+	if err := db.Where("topup_id = ?", topup.TopupID).First(&reloaded).Error; err != nil {
+		t.Fatalf("reload topup: %v", err)
+	}
+	if reloaded.Status != models.LKMTopupStatusExpired {
+		t.Fatalf("status = %s, want %s once opted in", reloaded.Status, models.LKMTopupStatusExpired)
+	}
+}
+
+// settledGateway reports every payment as paid
+type settledGateway struct{ code string }
+
+func (g settledGateway) Code() string { return g.code }
+func (g settledGateway) CreatePayment(context.Context, GatewayPaymentRequest) (*GatewayPayment, error) {
+	return nil, errors.New("not supported")
+}
+func (g settledGateway) VerifyWebhook(context.Context, GatewayWebhook) (*GatewayWebhookEvent, error) {
+	return nil, ErrPaymentWebhookSignature
+}
+func (g settledGateway) MapStatus(string) string { return GatewayStatusPaid }
+func (g settledGateway) FetchStatus(_ context.Context, externalPaymentID string) (*GatewayPaymentStatus, error) {
+	return &GatewayPaymentStatus{ExternalPaymentID: externalPaymentID, Status: GatewayStatusPaid, ProviderStatus: "succeeded"}, nil
+}
+
+func TestLKMDailyReport_RefreshedUntilRecoveryWindowPasses_Integration(t *testing.T) {
+	db := setupLKMTopupIntegrationDB(t)
+	if err := db.AutoMigrate(&models.SystemSetting{}, &models.AdminNotification{}, &models.AdminPermissionGrant{},
+		&models.LKMReconciliationReport{}, &models.LKMReconciliationDiscrepancy{}); err != nil {
+		t.Fatalf("auto-migrate failed: %v", err)
+	}
+	svc := NewLKMTopupServiceWithDB(db, NewWalletService())
+	svc.gateways = NewPaymentGatewayRegistry(settledGateway{code: "yookassa"})
+	upsertSystemSetting(t, db, "LKM_TOPUP_EXPIRE_AFTER_HOURS", "24")
+	user := createLKMTopupTestUser(t, db, "report-refresh")
+
+	quote, err := svc.CreateQuote(user.ID, LKMQuoteRequest{
+		LKMAmount:     500,
+		Region:        "cis",
+		Currency:      "RUB",
+		GatewayCode:   "yookassa",
+		PaymentMethod: "default",
+	}, "lkm.vedamatch.ru")
+	if err != nil {
+		t.Fatalf("quote failed: %v", err)
+	}
+	topup, err := svc.CreateTopupFromQuote(user.ID, LKMCreateTopupRequest{QuoteID: quote.QuoteID, Channel: "web"})
+	if err != nil {
+		t.Fatalf("topup failed: %v", err)
+	}
+
+	// A far-away day so other rows in the shared database do not show up in the report
+	day := time.Date(2001, 2, 3, 0, 0, 0, 0, time.UTC)
+	if err := db.Model(&models.LKMTopup{}).Where("topup_id = ?", topup.TopupID).Updates(map[string]interface{}{
+		"created_at":          day.Add(23 * time.Hour),
+		"external_payment_id": "pay-" + topup.TopupID,
+	}).Error; err != nil {
+		t.Fatalf("backdate topup: %v", err)
+	}
+
+	reconciler := NewLKMReconciliationService(svc)
+	reconciler.now = func() time.Time { return day.Add(30 * time.Hour) }
+	report, err := reconciler.GenerateDailyReport(day)
+	if err != nil {
+		t.Fatalf("GenerateDailyReport() error = %v", err)
+	}
+	if report.FinalizedAt != nil || report.DiscrepancyCount != 0 {
+		t.Fatalf("provisional report: finalized=%v discrepancies=%d, want open and clean while recovery owns the top-up",
+			report.FinalizedAt, report.DiscrepancyCount)
+	}
+
+	// Recovery never credited it: once the window passed the report flags it and is final
+	reconciler.now = func() time.Time { return day.Add(49 * time.Hour) }
+	final, err := reconciler.GenerateDailyReport(day)
+	if err != nil {
+		t.Fatalf("GenerateDailyReport() error = %v", err)
+	}
+	if final.ID != report.ID || final.FinalizedAt == nil {
+		t.Fatalf("report %d finalized=%v, want report %d finalized", final.ID, final.FinalizedAt, report.ID)
+	}
+	if final.DiscrepancyCount != 1 || final.Discrepancies[0].Kind != models.LKMDiscrepancyGatewayPaidNotCredited {
+		t.Fatalf("final discrepancies = %+v, want %s", final.Discrepancies, models.LKMDiscrepancyGatewayPaidNotCredited)
+	}
+
+	// A final report is not rebuilt
+	if err := db.Model(&models.LKMTopup{}).Where("topup_id = ?", topup.TopupID).
+		Update("status", models.LKMTopupStatusExpired).Error; err != nil {
+		t.Fatalf("expire topup: %v", err)
+	}
+	again, err := reconciler.GenerateDailyReport(day)
+	if err != nil {
+		t.Fatalf("GenerateDailyReport() error = %v", err)
+	}
+	if again.ID != final.ID || again.DiscrepancyCount != 1 {
+		t.Fatalf("final report changed: %+v", again)
+	}
+}
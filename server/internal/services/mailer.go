@@ -0,0 +1,234 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EmailMessage is a single rendered outbound email
+type EmailMessage struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers rendered emails. Implementations must be safe for concurrent use.
+type Mailer interface {
+	Send(ctx context.Context, msg EmailMessage) error
+}
+
+var (
+	mailerInstance Mailer
+	mailerOnce     sync.Once
+)
+
+// ErrMailerNotConfigured is returned by every send when no mail driver is configured
+var ErrMailerNotConfigured = errors.New("mailer is not configured: set SMTP_HOST or MAIL_DRIVER")
+
+// GetMailer returns the process-wide mailer configured from the environment
+func GetMailer() Mailer {
+	mailerOnce.Do(func() {
+		mailerInstance = NewMailerFromEnv()
+	})
+	return mailerInstance
+}
+
+// NewMailerFromEnv picks a mailer based on MAIL_DRIVER (smtp|file).
+// Without MAIL_DRIVER it uses SMTP when SMTP_HOST is set. The file sink is only used
+// when asked for explicitly; with no usable config every send fails, so outbox
+// messages end up failed instead of silently written to disk.
+func NewMailerFromEnv() Mailer {
+	from := strings.TrimSpace(os.Getenv("MAIL_FROM"))
+	if from == "" {
+		from = "VedaMatch <no-reply@vedamatch.ru>"
+	}
+
+	driver := strings.ToLower(strings.TrimSpace(os.Getenv("MAIL_DRIVER")))
+	host := strings.TrimSpace(os.Getenv("SMTP_HOST"))
+	if driver == "" && host != "" {
+		driver = "smtp"
+	}
+
+	switch driver {
+	case "smtp":
+		if host == "" {
+			log.Printf("[Mailer] ERROR: MAIL_DRIVER=smtp but SMTP_HOST is not set, emails will not be sent")
+			return unconfiguredMailer{}
+		}
+		port, _ := strconv.Atoi(strings.TrimSpace(os.Getenv("SMTP_PORT")))
+		if port <= 0 {
+			port = 587
+		}
+		log.Printf("[Mailer] Using SMTP mailer at %s:%d", host, port)
+		return &SMTPMailer{
+			Host:     host,
+			Port:     port,
+			Username: strings.TrimSpace(os.Getenv("SMTP_USERNAME")),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+			Timeout:  15 * time.Second,
+		}
+	case "file":
+		dir := strings.TrimSpace(os.Getenv("MAIL_FILE_DIR"))
+		if dir == "" {
+			dir = "./mail_outbox"
+		}
+		log.Printf("[Mailer] Using file mailer sink at %s", dir)
+		return &FileMailer{Dir: dir, From: from}
+	case "":
+		log.Printf("[Mailer] ERROR: neither SMTP_HOST nor MAIL_DRIVER is set, emails will not be sent")
+		return unconfiguredMailer{}
+	default:
+		log.Printf("[Mailer] ERROR: unknown MAIL_DRIVER %q, emails will not be sent", driver)
+		return unconfiguredMailer{}
+	}
+}
+
+// unconfiguredMailer fails every send so that a missing mail config shows up as failed
+// outbox messages
+type unconfiguredMailer struct{}
+
+func (unconfiguredMailer) Send(context.Context, EmailMessage) error {
+	return ErrMailerNotConfigured
+}
+
+// SMTPMailer sends email through an SMTP relay, upgrading to TLS when offered
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	Timeout  time.Duration
+}
+
+// Send delivers a message via SMTP
+func (m *SMTPMailer) Send(ctx context.Context, msg EmailMessage) error {
+	if m == nil || m.Host == "" {
+		return errors.New("smtp mailer is not configured")
+	}
+	to := strings.TrimSpace(msg.To)
+	if to == "" {
+		return errors.New("recipient is required")
+	}
+
+	addr := net.JoinHostPort(m.Host, strconv.Itoa(m.Port))
+	dialer := &net.Dialer{Timeout: m.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("smtp dial: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	} else if m.Timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(m.Timeout))
+	}
+
+	client, err := smtp.NewClient(conn, m.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp handshake: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.Host}); err != nil {
+			return fmt.Errorf("smtp starttls: %w", err)
+		}
+	}
+	if m.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.Username, m.Password, m.Host)); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+
+	if err := client.Mail(envelopeAddress(m.From)); err != nil {
+		return fmt.Errorf("smtp mail from: %w", err)
+	}
+	if err := client.Rcpt(to); err != nil {
+		return fmt.Errorf("smtp rcpt: %w", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	if _, err := w.Write(buildMIMEMessage(m.From, msg)); err != nil {
+		w.Close()
+		return fmt.Errorf("smtp write: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp data close: %w", err)
+	}
+	return client.Quit()
+}
+
+// FileMailer writes each message as an .eml file into Dir.
+// Used as a local SMTP stand-in for development and tests.
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+var fileMailerUnsafeChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// Send writes the message to disk
+func (m *FileMailer) Send(ctx context.Context, msg EmailMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if strings.TrimSpace(msg.To) == "" {
+		return errors.New("recipient is required")
+	}
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return fmt.Errorf("create mail dir: %w", err)
+	}
+
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), fileMailerUnsafeChars.ReplaceAllString(msg.To, "_"))
+	return os.WriteFile(filepath.Join(m.Dir, name), buildMIMEMessage(m.From, msg), 0o644)
+}
+
+var mailHeaderSanitizer = strings.NewReplacer("\r", "", "\n", "")
+
+func buildMIMEMessage(from string, msg EmailMessage) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("From: " + mailHeaderSanitizer.Replace(from) + "\r\n")
+	buf.WriteString("To: " + mailHeaderSanitizer.Replace(msg.To) + "\r\n")
+	buf.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	buf.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	buf.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+
+	encoded := base64.StdEncoding.EncodeToString([]byte(msg.Body))
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded + "\r\n")
+	return buf.Bytes()
+}
+
+// envelopeAddress extracts "addr" from "Name <addr>"
+func envelopeAddress(from string) string {
+	if start := strings.LastIndex(from, "<"); start >= 0 {
+		if end := strings.LastIndex(from, ">"); end > start {
+			return from[start+1 : end]
+		}
+	}
+	return strings.TrimSpace(from)
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"rag-agent-server/internal/models"
+)
+
+func TestDeleteForAllDropsEditHistory_Integration(t *testing.T) {
+	db := setupYatraServiceIntegrationDB(t)
+	if err := db.AutoMigrate(&models.Message{}, &models.MessageEdit{}, &models.MessageReaction{}, &models.MessageHiddenForUser{}); err != nil {
+		t.Fatalf("message automigrate failed: %v", err)
+	}
+
+	sender := createYatraIntegrationUser(t, db, "edit-sender")
+	recipient := createYatraIntegrationUser(t, db, "edit-recipient")
+	msg := models.Message{SenderID: sender.ID, RecipientID: recipient.ID, Content: "first draft", Type: "text"}
+	if err := db.Create(&msg).Error; err != nil {
+		t.Fatalf("create message: %v", err)
+	}
+
+	service := NewMessageActionService()
+	if _, err := service.EditMessage(msg.ID, sender.ID, "second draft"); err != nil {
+		t.Fatalf("EditMessage() error = %v", err)
+	}
+	if _, err := service.SetReaction(msg.ID, recipient.ID, "🙏", true); err != nil {
+		t.Fatalf("SetReaction() error = %v", err)
+	}
+	if _, err := service.DeleteMessage(msg.ID, sender.ID, MessageDeleteScopeAll); err != nil {
+		t.Fatalf("DeleteMessage() error = %v", err)
+	}
+
+	var edits int64
+	if err := db.Unscoped().Model(&models.MessageEdit{}).Where("message_id = ?", msg.ID).Count(&edits).Error; err != nil {
+		t.Fatalf("count edits: %v", err)
+	}
+	if edits != 0 {
+		t.Fatalf("edit rows after delete for all = %d, want 0", edits)
+	}
+	var reactions int64
+	if err := db.Model(&models.MessageReaction{}).Where("message_id = ?", msg.ID).Count(&reactions).Error; err != nil {
+		t.Fatalf("count reactions: %v", err)
+	}
+	if reactions != 0 {
+		t.Fatalf("reaction rows after delete for all = %d, want 0", reactions)
+	}
+
+	if _, err := service.SetReaction(msg.ID, recipient.ID, "🙏", true); !errors.Is(err, ErrMessageDeleted) {
+		t.Fatalf("SetReaction() on deleted message error = %v, want %v", err, ErrMessageDeleted)
+	}
+}
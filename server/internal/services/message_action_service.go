@@ -0,0 +1,424 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"rag-agent-server/internal/database"
+	"rag-agent-server/internal/models"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrMessageNotFound    = errors.New("message not found")
+	ErrMessageForbidden   = errors.New("forbidden")
+	ErrMessageDeleted     = errors.New("message was deleted")
+	ErrMessageNotEditable = errors.New("only text messages can be edited")
+	ErrInvalidReaction    = errors.New("invalid reaction")
+	ErrMessageBlocked     = errors.New("messaging this user is blocked")
+)
+
+const (
+	MessageDeleteScopeMe  = "me"
+	MessageDeleteScopeAll = "all"
+
+	maxReactionRunes = 8
+)
+
+// MessageActionService implements edit, delete and reactions on existing messages
+type MessageActionService struct {
+	db *gorm.DB
+}
+
+func NewMessageActionService() *MessageActionService {
+	return &MessageActionService{db: database.DB}
+}
+
+// MessageAudience describes who must receive real-time updates for a message
+type MessageAudience struct {
+	Message       models.Message
+	TargetUserIDs []uint
+}
+
+// loadForParticipant loads a message and checks the actor can see it.
+// Returns the actor's room role ("" for direct chats).
+func (s *MessageActionService) loadForParticipant(messageID, actorID uint) (*models.Message, string, error) {
+	return loadMessageForParticipant(s.db, messageID, actorID)
+}
+
+func loadMessageForParticipant(db *gorm.DB, messageID, actorID uint) (*models.Message, string, error) {
+	var msg models.Message
+	if err := db.First(&msg, messageID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", ErrMessageNotFound
+		}
+		return nil, "", err
+	}
+
+	if msg.RoomID != 0 {
+		var member models.RoomMember
+		if err := db.Where("room_id = ? AND user_id = ?", msg.RoomID, actorID).First(&member).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, "", ErrMessageForbidden
+			}
+			return nil, "", err
+		}
+		return &msg, models.NormalizeRoomRole(member.Role), nil
+	}
+
+	if msg.SenderID != actorID && msg.RecipientID != actorID {
+		return nil, "", ErrMessageForbidden
+	}
+	return &msg, "", nil
+}
+
+// audienceFor returns all users that should see updates of the message
+func (s *MessageActionService) audienceFor(msg *models.Message) ([]uint, error) {
+	if msg.RoomID == 0 {
+		return uniqueIDs([]uint{msg.SenderID, msg.RecipientID}), nil
+	}
+	var userIDs []uint
+	if err := s.db.Model(&models.RoomMember{}).
+		Where("room_id = ?", msg.RoomID).
+		Pluck("user_id", &userIDs).Error; err != nil {
+		return nil, err
+	}
+	return uniqueIDs(userIDs), nil
+}
+
+// EditMessage replaces the content of a text message and records the previous version
+func (s *MessageActionService) EditMessage(messageID, actorID uint, content string) (*MessageAudience, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, ErrInvalidPayload
+	}
+
+	msg, _, err := s.loadForParticipant(messageID, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if msg.SenderID != actorID {
+		return nil, ErrMessageForbidden
+	}
+	if msg.IsDeletedForAll() {
+		return nil, ErrMessageDeleted
+	}
+	if msg.Type != "" && msg.Type != "text" {
+		return nil, ErrMessageNotEditable
+	}
+	if msg.Content == content {
+		return s.withAudience(msg)
+	}
+
+	now := time.Now()
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&models.MessageEdit{
+			MessageID:       msg.ID,
+			EditorID:        actorID,
+			PreviousContent: msg.Content,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Message{}).Where("id = ?", msg.ID).Updates(map[string]interface{}{
+			"content":   content,
+			"edited_at": now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	msg.Content = content
+	msg.EditedAt = &now
+	return s.withAudience(msg)
+}
+
+// GetEditHistory returns previous versions of a message, oldest first
+func (s *MessageActionService) GetEditHistory(messageID, actorID uint) ([]models.MessageEdit, error) {
+	msg, _, err := s.loadForParticipant(messageID, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if msg.IsDeletedForAll() {
+		return []models.MessageEdit{}, nil
+	}
+	var edits []models.MessageEdit
+	if err := s.db.Where("message_id = ?", msg.ID).Order("id ASC").Find(&edits).Error; err != nil {
+		return nil, err
+	}
+	return edits, nil
+}
+
+// canDeleteForAll: senders can delete their own messages; room owners/admins can
+// remove other members' messages, but only the owner can remove an admin's or owner's.
+func (s *MessageActionService) canDeleteForAll(msg *models.Message, actorID uint, actorRole string) (bool, error) {
+	if msg.SenderID == actorID {
+		return true, nil
+	}
+	if msg.RoomID == 0 || !models.CanManageRoomMembers(actorRole) {
+		return false, nil
+	}
+	if actorRole == models.RoomRoleOwner {
+		return true, nil
+	}
+	authorRole := ""
+	var author models.RoomMember
+	if err := s.db.Where("room_id = ? AND user_id = ?", msg.RoomID, msg.SenderID).First(&author).Error; err == nil {
+		authorRole = models.NormalizeRoomRole(author.Role)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+	return !models.CanManageRoomMembers(authorRole), nil
+}
+
+// DeleteMessage hides the message for the actor (scope "me") or turns it
+// into a tombstone for every participant (scope "all").
+// The returned audience is nil for scope "me" since nobody else is affected.
+func (s *MessageActionService) DeleteMessage(messageID, actorID uint, scope string) (*MessageAudience, error) {
+	msg, role, err := s.loadForParticipant(messageID, actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch scope {
+	case "", MessageDeleteScopeMe:
+		hidden := models.MessageHiddenForUser{MessageID: msg.ID, UserID: actorID}
+		if err := s.db.Where(hidden).FirstOrCreate(&hidden).Error; err != nil {
+			return nil, err
+		}
+		return nil, nil
+	case MessageDeleteScopeAll:
+	default:
+		return nil, ErrInvalidPayload
+	}
+
+	if msg.IsDeletedForAll() {
+		return s.withAudience(msg)
+	}
+	allowed, err := s.canDeleteForAll(msg, actorID, role)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, ErrMessageForbidden
+	}
+
+	if err := s.tombstone(msg, actorID); err != nil {
+		return nil, err
+	}
+	return s.withAudience(msg)
+}
+
+// tombstone clears the message payload for every participant and drops its reactions
+// and edit history, so no earlier version of the text survives
+func (s *MessageActionService) tombstone(msg *models.Message, actorID uint) error {
+	now := time.Now()
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("message_id = ?", msg.ID).Delete(&models.MessageReaction{}).Error; err != nil {
+			return err
+		}
+		// Unscoped: a soft delete would keep PreviousContent in the table
+		if err := tx.Unscoped().Where("message_id = ?", msg.ID).Delete(&models.MessageEdit{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Message{}).Where("id = ?", msg.ID).Updates(map[string]interface{}{
+			"content":            "",
+			"file_name":          "",
+			"file_size":          0,
+			"mime_type":          "",
+			"duration":           0,
+			"thumbnail":          "",
+			"map_data":           nil,
+			"reactions":          nil,
+			"quote_text":         "",
+			"deleted_for_all_at": now,
+			"deleted_by_id":      actorID,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	msg.Content = ""
+	msg.FileName = ""
+	msg.FileSize = 0
+	msg.MimeType = ""
+	msg.Duration = 0
+	msg.Thumbnail = ""
+	msg.MapData = nil
+	msg.Reactions = nil
+	msg.QuoteText = ""
+	msg.DeletedForAllAt = &now
+	msg.DeletedByID = actorID
+	return nil
+}
+
+// ExpireDueMessages tombstones self-destructing messages whose ExpiresAt has passed
+func (s *MessageActionService) ExpireDueMessages(limit int) ([]MessageAudience, error) {
+	if limit <= 0 {
+		limit = 200
+	}
+	var due []models.Message
+	if err := s.db.Where("expires_at IS NOT NULL AND expires_at <= ? AND deleted_for_all_at IS NULL", time.Now()).
+		Order("expires_at ASC").
+		Limit(limit).
+		Find(&due).Error; err != nil {
+		return nil, err
+	}
+
+	expired := make([]MessageAudience, 0, len(due))
+	for i := range due {
+		msg := &due[i]
+		if err := s.tombstone(msg, 0); err != nil {
+			return expired, err
+		}
+		audience, err := s.withAudience(msg)
+		if err != nil {
+			return expired, err
+		}
+		expired = append(expired, *audience)
+	}
+	return expired, nil
+}
+
+// NormalizeReaction validates a reaction emoji
+func NormalizeReaction(emoji string) (string, error) {
+	emoji = strings.TrimSpace(emoji)
+	if emoji == "" || utf8.RuneCountInString(emoji) > maxReactionRunes {
+		return "", ErrInvalidReaction
+	}
+	for _, r := range emoji {
+		if unicode.IsSpace(r) || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsControl(r) {
+			return "", ErrInvalidReaction
+		}
+	}
+	return emoji, nil
+}
+
+// SetReaction adds (add=true) or removes the actor's reaction and refreshes the aggregate
+func (s *MessageActionService) SetReaction(messageID, actorID uint, emoji string, add bool) (*MessageAudience, error) {
+	emoji, err := NormalizeReaction(emoji)
+	if err != nil {
+		return nil, err
+	}
+	msg, _, err := s.loadForParticipant(messageID, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if msg.IsDeletedForAll() {
+		return nil, ErrMessageDeleted
+	}
+
+	var counts map[string]int
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Serializes concurrent reactions on the message, so the recount below is never
+		// overwritten by a stale one, and re-checks a concurrent delete for everyone
+		var locked models.Message
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "deleted_for_all_at").
+			First(&locked, msg.ID).Error; err != nil {
+			return err
+		}
+		if locked.IsDeletedForAll() {
+			return ErrMessageDeleted
+		}
+
+		if add {
+			reaction := models.MessageReaction{MessageID: msg.ID, UserID: actorID, Emoji: emoji}
+			if err := tx.Where(reaction).FirstOrCreate(&reaction).Error; err != nil {
+				return err
+			}
+		} else {
+			if err := tx.Where("message_id = ? AND user_id = ? AND emoji = ?", msg.ID, actorID, emoji).
+				Delete(&models.MessageReaction{}).Error; err != nil {
+				return err
+			}
+		}
+
+		type emojiCount struct {
+			Emoji string
+			Count int
+		}
+		var rows []emojiCount
+		if err := tx.Model(&models.MessageReaction{}).
+			Select("emoji, COUNT(*) AS count").
+			Where("message_id = ?", msg.ID).
+			Group("emoji").
+			Scan(&rows).Error; err != nil {
+			return err
+		}
+		counts = make(map[string]int, len(rows))
+		for _, row := range rows {
+			counts[row.Emoji] = row.Count
+		}
+		encoded, err := json.Marshal(counts)
+		if err != nil {
+			return err
+		}
+		// Map updates bypass the field serializer, so store the JSON text directly
+		return tx.Model(&models.Message{}).Where("id = ?", msg.ID).
+			Update("reactions", string(encoded)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	msg.Reactions = counts
+	return s.withAudience(msg)
+}
+
+// GetMyReactions returns the emojis the user put on the given messages
+func (s *MessageActionService) GetMyReactions(userID uint, messageIDs []uint) (map[uint][]string, error) {
+	result := make(map[uint][]string)
+	if len(messageIDs) == 0 {
+		return result, nil
+	}
+	var reactions []models.MessageReaction
+	if err := s.db.Where("user_id = ? AND message_id IN ?", userID, messageIDs).
+		Order("id ASC").Find(&reactions).Error; err != nil {
+		return nil, err
+	}
+	for _, reaction := range reactions {
+		result[reaction.MessageID] = append(result[reaction.MessageID], reaction.Emoji)
+	}
+	return result, nil
+}
+
+// HiddenMessagesSubquery selects IDs of messages the user deleted for themselves
+func (s *MessageActionService) HiddenMessagesSubquery(userID uint) *gorm.DB {
+	return hiddenMessagesSubquery(s.db, userID)
+}
+
+func hiddenMessagesSubquery(db *gorm.DB, userID uint) *gorm.DB {
+	return db.Model(&models.MessageHiddenForUser{}).Select("message_id").Where("user_id = ?", userID)
+}
+
+func (s *MessageActionService) withAudience(msg *models.Message) (*MessageAudience, error) {
+	targets, err := s.audienceFor(msg)
+	if err != nil {
+		return nil, err
+	}
+	return &MessageAudience{Message: *msg, TargetUserIDs: targets}, nil
+}
+
+// uniqueIDs drops zero and duplicate IDs, keeping the original order
+func uniqueIDs(input []uint) []uint {
+	seen := make(map[uint]struct{}, len(input))
+	result := make([]uint, 0, len(input))
+	for _, userID := range input {
+		if userID == 0 {
+			continue
+		}
+		if _, ok := seen[userID]; ok {
+			continue
+		}
+		seen[userID] = struct{}{}
+		result = append(result, userID)
+	}
+	return result
+}
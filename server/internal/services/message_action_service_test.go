@@ -0,0 +1,74 @@
+package services
+
+import (
+	"errors"
+	"rag-agent-server/internal/models"
+	"testing"
+	"time"
+)
+
+func TestNormalizeReaction(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{name: "single emoji", in: "👍", want: "👍"},
+		{name: "trimmed", in: "  🙏 ", want: "🙏"},
+		{name: "skin tone modifier", in: "👍🏽", want: "👍🏽"},
+		{name: "zwj sequence", in: "👨‍👩‍👧", want: "👨‍👩‍👧"},
+		{name: "empty", in: "  ", wantErr: true},
+		{name: "letters", in: "ok", wantErr: true},
+		{name: "digits", in: "100", wantErr: true},
+		{name: "inner space", in: "👍 👍", wantErr: true},
+		{name: "too long", in: "🙏🙏🙏🙏🙏🙏🙏🙏🙏", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := NormalizeReaction(tt.in)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidReaction) {
+					t.Fatalf("NormalizeReaction(%q) error = %v, want ErrInvalidReaction", tt.in, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("NormalizeReaction(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanDeleteForAllSenderAndOwner(t *testing.T) {
+	t.Parallel()
+
+	s := &MessageActionService{}
+	msg := &models.Message{SenderID: 1, RoomID: 10}
+
+	if ok, err := s.canDeleteForAll(msg, 1, models.RoomRoleMember); err != nil || !ok {
+		t.Fatalf("sender should be able to delete own message, got %v %v", ok, err)
+	}
+	if ok, err := s.canDeleteForAll(msg, 2, models.RoomRoleMember); err != nil || ok {
+		t.Fatalf("plain member must not delete others' messages, got %v %v", ok, err)
+	}
+	if ok, err := s.canDeleteForAll(msg, 2, models.RoomRoleOwner); err != nil || !ok {
+		t.Fatalf("room owner should delete any message, got %v %v", ok, err)
+	}
+
+	direct := &models.Message{SenderID: 1, RecipientID: 2}
+	if ok, err := s.canDeleteForAll(direct, 2, ""); err != nil || ok {
+		t.Fatalf("direct chat recipient must not delete sender's message, got %v %v", ok, err)
+	}
+
+	now := time.Now()
+	deleted := models.Message{DeletedForAllAt: &now}
+	if !deleted.IsDeletedForAll() {
+		t.Fatalf("expected tombstone to report deleted")
+	}
+}
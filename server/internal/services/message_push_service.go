@@ -16,11 +16,15 @@ type MessagePushOptions struct {
 }
 
 type MessagePushService struct {
-	push *PushNotificationService
+	push     *PushNotificationService
+	receipts *MessageReceiptService
 }
 
 func NewMessagePushService() *MessagePushService {
-	return &MessagePushService{push: GetPushService()}
+	return &MessagePushService{
+		push:     GetPushService(),
+		receipts: NewMessageReceiptService(),
+	}
 }
 
 func (s *MessagePushService) Dispatch(message models.Message, opts MessagePushOptions) {
@@ -49,6 +53,9 @@ func (s *MessagePushService) sendP2PPush(message models.Message) {
 	if message.RecipientID == 0 || message.RecipientID == message.SenderID {
 		return
 	}
+	if len(s.unreadRecipients(message, []uint{message.RecipientID})) == 0 {
+		return
+	}
 
 	body := strings.TrimSpace(message.Content)
 	if strings.ToLower(strings.TrimSpace(message.Type)) != "text" {
@@ -86,7 +93,7 @@ func (s *MessagePushService) sendRoomPush(message models.Message, opts MessagePu
 		return
 	}
 
-	recipients := uniqueUsers(opts.RoomMemberIDs)
+	recipients := s.unreadRecipients(message, uniqueUsers(opts.RoomMemberIDs))
 	if len(recipients) == 0 {
 		return
 	}
@@ -132,6 +139,60 @@ func (s *MessagePushService) sendRoomPush(message models.Message, opts MessagePu
 	}
 }
 
+// DispatchRead tells the reader's other devices that the conversation was read
+// up to receipt.MessageID, so they can dismiss the notifications shown for it.
+// Pushes are sent without waiting for a read; this silent push retracts them.
+func (s *MessagePushService) DispatchRead(receipt ReceiptUpdate) {
+	if s == nil || s.push == nil || !config.PushP2PEnabled() {
+		return
+	}
+	if receipt.Kind != ReceiptRead || !receipt.Advanced || receipt.MessageID == 0 {
+		return
+	}
+
+	go func() {
+		if err := s.push.SendToUser(receipt.UserID, buildMessagesReadPush(receipt)); err != nil {
+			_ = GetMetricsService().Increment(MetricPushSendFail, 1)
+			log.Printf("[MessagePush] read_sync_push_failed user_id=%d room_id=%d peer_id=%d message_id=%d error=%v",
+				receipt.UserID, receipt.Conversation.RoomID, receipt.Conversation.PeerID, receipt.MessageID, err)
+		}
+	}()
+}
+
+// buildMessagesReadPush builds the data-only push for DispatchRead
+func buildMessagesReadPush(receipt ReceiptUpdate) PushMessage {
+	ref := receipt.Conversation
+	data := map[string]string{
+		"type":          "messages_read",
+		"upToMessageId": fmt.Sprintf("%d", receipt.MessageID),
+	}
+	if ref.RoomID != 0 {
+		data["chatType"] = "room"
+		data["roomId"] = fmt.Sprintf("%d", ref.RoomID)
+	} else {
+		data["chatType"] = "p2p"
+		data["peerId"] = fmt.Sprintf("%d", ref.PeerID)
+	}
+	return PushMessage{
+		Priority: "normal",
+		EventKey: fmt.Sprintf("read:%d:%d:%d:%d", receipt.UserID, ref.RoomID, ref.PeerID, receipt.MessageID),
+		Data:     data,
+	}
+}
+
+// unreadRecipients drops users who already read the message on another device
+func (s *MessagePushService) unreadRecipients(message models.Message, recipients []uint) []uint {
+	if s.receipts == nil || s.receipts.db == nil || len(recipients) == 0 {
+		return recipients
+	}
+	unread, err := s.receipts.FilterUnread(message, recipients)
+	if err != nil {
+		log.Printf("[MessagePush] read_state_lookup_failed message_id=%d error=%v", message.ID, err)
+		return recipients
+	}
+	return unread
+}
+
 func uniqueUsers(input []uint) []uint {
 	if len(input) == 0 {
 		return nil
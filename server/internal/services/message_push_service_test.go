@@ -0,0 +1,53 @@
+package services
+
+import "testing"
+
+func TestBuildMessagesReadPush(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		receipt  ReceiptUpdate
+		wantData map[string]string
+		wantKey  string
+	}{
+		{
+			name:    "room",
+			receipt: ReceiptUpdate{UserID: 7, Conversation: ConversationRef{RoomID: 3}, Kind: ReceiptRead, MessageID: 40, Advanced: true},
+			wantData: map[string]string{
+				"type": "messages_read", "chatType": "room", "roomId": "3", "upToMessageId": "40",
+			},
+			wantKey: "read:7:3:0:40",
+		},
+		{
+			name:    "peer",
+			receipt: ReceiptUpdate{UserID: 7, Conversation: ConversationRef{PeerID: 9}, Kind: ReceiptRead, MessageID: 41, Advanced: true},
+			wantData: map[string]string{
+				"type": "messages_read", "chatType": "p2p", "peerId": "9", "upToMessageId": "41",
+			},
+			wantKey: "read:7:0:9:41",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			msg := buildMessagesReadPush(tt.receipt)
+			if !msg.isDataOnly() {
+				t.Fatalf("read sync push must be data-only, got title=%q body=%q", msg.Title, msg.Body)
+			}
+			if msg.EventKey != tt.wantKey {
+				t.Fatalf("EventKey = %q, want %q", msg.EventKey, tt.wantKey)
+			}
+			if len(msg.Data) != len(tt.wantData) {
+				t.Fatalf("Data = %v, want %v", msg.Data, tt.wantData)
+			}
+			for key, want := range tt.wantData {
+				if got := msg.Data[key]; got != want {
+					t.Fatalf("Data[%q] = %q, want %q", key, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package services
+
+import (
+	"errors"
+	"rag-agent-server/internal/database"
+	"rag-agent-server/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInvalidConversation = errors.New("exactly one of roomId or peerUserId is required")
+
+// Receipt kinds
+const (
+	ReceiptDelivered = "delivered"
+	ReceiptRead      = "read"
+)
+
+// ConversationRef identifies a room chat (RoomID) or a 1:1 chat with PeerID
+type ConversationRef struct {
+	RoomID uint
+	PeerID uint
+}
+
+// Validate checks that exactly one side is set and the peer is not the user
+func (r ConversationRef) Validate(userID uint) error {
+	if (r.RoomID == 0) == (r.PeerID == 0) || (r.PeerID != 0 && r.PeerID == userID) {
+		return ErrInvalidConversation
+	}
+	return nil
+}
+
+// ReceiptUpdate is the result of moving a delivery/read watermark
+type ReceiptUpdate struct {
+	UserID        uint
+	Conversation  ConversationRef
+	Kind          string
+	MessageID     uint
+	At            time.Time
+	Advanced      bool   // false when the watermark was already at or past MessageID
+	TargetUserIDs []uint // participants to notify
+}
+
+// MessageReceiptService tracks delivery and read watermarks per conversation
+type MessageReceiptService struct {
+	db *gorm.DB
+}
+
+func NewMessageReceiptService() *MessageReceiptService {
+	return &MessageReceiptService{db: database.DB}
+}
+
+// conversationMessages scopes a messages query to the conversation as seen by userID
+func conversationMessages(db *gorm.DB, userID uint, ref ConversationRef) *gorm.DB {
+	query := db.Model(&models.Message{})
+	if ref.RoomID != 0 {
+		return query.Where("room_id = ?", ref.RoomID)
+	}
+	return query.Where("room_id = 0 AND ((sender_id = ? AND recipient_id = ?) OR (sender_id = ? AND recipient_id = ?))",
+		userID, ref.PeerID, ref.PeerID, userID)
+}
+
+func (s *MessageReceiptService) ensureAccess(userID uint, ref ConversationRef) error {
+	if err := ref.Validate(userID); err != nil {
+		return err
+	}
+	if ref.RoomID == 0 {
+		return nil
+	}
+	var count int64
+	if err := s.db.Model(&models.RoomMember{}).
+		Where("room_id = ? AND user_id = ?", ref.RoomID, userID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrMessageForbidden
+	}
+	return nil
+}
+
+func (s *MessageReceiptService) participants(userID uint, ref ConversationRef) ([]uint, error) {
+	if ref.RoomID == 0 {
+		return uniqueIDs([]uint{ref.PeerID, userID}), nil
+	}
+	var userIDs []uint
+	if err := s.db.Model(&models.RoomMember{}).
+		Where("room_id = ?", ref.RoomID).
+		Pluck("user_id", &userIDs).Error; err != nil {
+		return nil, err
+	}
+	return uniqueIDs(userIDs), nil
+}
+
+// Mark moves the user's delivered or read watermark up to messageID.
+// messageID = 0 means "the latest message in the conversation".
+// Watermarks never move backwards; marking read also marks delivered.
+func (s *MessageReceiptService) Mark(userID uint, ref ConversationRef, kind string, messageID uint) (*ReceiptUpdate, error) {
+	if kind != ReceiptDelivered && kind != ReceiptRead {
+		return nil, ErrInvalidPayload
+	}
+	if err := s.ensureAccess(userID, ref); err != nil {
+		return nil, err
+	}
+
+	scope := conversationMessages(s.db, userID, ref)
+	if messageID == 0 {
+		var latest *uint
+		if err := scope.Select("MAX(id)").Scan(&latest).Error; err != nil {
+			return nil, err
+		}
+		if latest == nil {
+			return &ReceiptUpdate{UserID: userID, Conversation: ref, Kind: kind}, nil
+		}
+		messageID = *latest
+	} else {
+		var count int64
+		if err := scope.Where("id = ?", messageID).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return nil, ErrMessageNotFound
+		}
+	}
+
+	now := time.Now()
+	state := models.ConversationReadState{UserID: userID, RoomID: ref.RoomID, PeerID: ref.PeerID}
+	if err := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&state).Error; err != nil {
+		return nil, err
+	}
+
+	update := s.db.Model(&models.ConversationReadState{}).
+		Where("user_id = ? AND room_id = ? AND peer_id = ?", userID, ref.RoomID, ref.PeerID)
+	var result *gorm.DB
+	if kind == ReceiptRead {
+		result = update.Where("last_read_message_id < ?", messageID).Updates(map[string]interface{}{
+			"last_read_message_id":      messageID,
+			"read_at":                   now,
+			"last_delivered_message_id": gorm.Expr("GREATEST(last_delivered_message_id, ?)", messageID),
+			"delivered_at":              now,
+		})
+	} else {
+		result = update.Where("last_delivered_message_id < ?", messageID).Updates(map[string]interface{}{
+			"last_delivered_message_id": messageID,
+			"delivered_at":              now,
+		})
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	receipt := &ReceiptUpdate{
+		UserID:       userID,
+		Conversation: ref,
+		Kind:         kind,
+		MessageID:    messageID,
+		At:           now,
+		Advanced:     result.RowsAffected > 0,
+	}
+	if receipt.Advanced {
+		targets, err := s.participants(userID, ref)
+		if err != nil {
+			return nil, err
+		}
+		receipt.TargetUserIDs = targets
+	}
+	return receipt, nil
+}
+
+// othersStates returns watermarks of the other participants of the conversation
+func (s *MessageReceiptService) othersStates(userID uint, ref ConversationRef) ([]models.ConversationReadState, error) {
+	var states []models.ConversationReadState
+	query := s.db.Model(&models.ConversationReadState{})
+	if ref.RoomID != 0 {
+		members := s.db.Model(&models.RoomMember{}).Select("user_id").Where("room_id = ?", ref.RoomID)
+		query = query.Where("room_id = ? AND peer_id = 0 AND user_id <> ? AND user_id IN (?)", ref.RoomID, userID, members)
+	} else {
+		query = query.Where("room_id = 0 AND user_id = ? AND peer_id = ?", ref.PeerID, userID)
+	}
+	if err := query.Order("user_id ASC").Find(&states).Error; err != nil {
+		return nil, err
+	}
+	return states, nil
+}
+
+// GetReceipts returns delivery/read watermarks of the other participants
+func (s *MessageReceiptService) GetReceipts(userID uint, ref ConversationRef) ([]models.ConversationReadState, error) {
+	if err := s.ensureAccess(userID, ref); err != nil {
+		return nil, err
+	}
+	return s.othersStates(userID, ref)
+}
+
+// AnnotateOwnMessages fills DeliveryStatus/ReadCount on messages sent by userID.
+// In rooms a message is "read" once at least one other member read it.
+func (s *MessageReceiptService) AnnotateOwnMessages(userID uint, ref ConversationRef, messages []models.Message) error {
+	hasOwn := false
+	for i := range messages {
+		if messages[i].SenderID == userID {
+			hasOwn = true
+			break
+		}
+	}
+	if !hasOwn {
+		return nil
+	}
+
+	states, err := s.othersStates(userID, ref)
+	if err != nil {
+		return err
+	}
+	for i := range messages {
+		if messages[i].SenderID == userID {
+			applyReceiptStatus(&messages[i], states)
+		}
+	}
+	return nil
+}
+
+func applyReceiptStatus(msg *models.Message, states []models.ConversationReadState) {
+	read, delivered := 0, 0
+	for _, state := range states {
+		if state.LastReadMessageID >= msg.ID {
+			read++
+		}
+		if state.LastDeliveredMessageID >= msg.ID {
+			delivered++
+		}
+	}
+	switch {
+	case read > 0:
+		msg.DeliveryStatus = models.MessageStatusRead
+	case delivered > 0:
+		msg.DeliveryStatus = models.MessageStatusDelivered
+	default:
+		msg.DeliveryStatus = models.MessageStatusSent
+	}
+	if msg.RoomID != 0 {
+		msg.ReadCount = read
+	}
+}
+
+// unreadBase selects visible messages from other senders that are past the user's read watermark
+func (s *MessageReceiptService) unreadBase(userID uint) *gorm.DB {
+	return s.db.Table("messages AS m").
+		Where("m.deleted_at IS NULL AND m.deleted_for_all_at IS NULL").
+		Where("m.sender_id <> ?", userID).
+		Where("m.id > COALESCE(r.last_read_message_id, 0)").
+		Where("m.id NOT IN (?)", hiddenMessagesSubquery(s.db, userID))
+}
+
+// UnreadRoomCounts returns unread message counts for the given rooms
+func (s *MessageReceiptService) UnreadRoomCounts(userID uint, roomIDs []uint) (map[uint]int64, error) {
+	counts := make(map[uint]int64, len(roomIDs))
+	if len(roomIDs) == 0 {
+		return counts, nil
+	}
+	type row struct {
+		RoomID uint
+		Unread int64
+	}
+	var rows []row
+	if err := s.unreadBase(userID).
+		Select("m.room_id AS room_id, COUNT(*) AS unread").
+		Joins("LEFT JOIN conversation_read_states r ON r.user_id = ? AND r.room_id = m.room_id AND r.peer_id = 0", userID).
+		Where("m.room_id IN ?", roomIDs).
+		Group("m.room_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		counts[r.RoomID] = r.Unread
+	}
+	return counts, nil
+}
+
+// UnreadDirectCounts returns unread 1:1 message counts keyed by peer user ID
+func (s *MessageReceiptService) UnreadDirectCounts(userID uint) (map[uint]int64, error) {
+	type row struct {
+		PeerID uint
+		Unread int64
+	}
+	var rows []row
+	if err := s.unreadBase(userID).
+		Select("m.sender_id AS peer_id, COUNT(*) AS unread").
+		Joins("LEFT JOIN conversation_read_states r ON r.user_id = ? AND r.room_id = 0 AND r.peer_id = m.sender_id", userID).
+		Where("m.room_id = 0 AND m.recipient_id = ?", userID).
+		Group("m.sender_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	counts := make(map[uint]int64, len(rows))
+	for _, r := range rows {
+		counts[r.PeerID] = r.Unread
+	}
+	return counts, nil
+}
+
+// FilterUnread returns the recipients that have not yet read the message
+func (s *MessageReceiptService) FilterUnread(message models.Message, recipientIDs []uint) ([]uint, error) {
+	if len(recipientIDs) == 0 || message.ID == 0 {
+		return recipientIDs, nil
+	}
+	query := s.db.Model(&models.ConversationReadState{}).
+		Where("user_id IN ? AND last_read_message_id >= ?", recipientIDs, message.ID)
+	if message.RoomID != 0 {
+		query = query.Where("room_id = ? AND peer_id = 0", message.RoomID)
+	} else {
+		query = query.Where("room_id = 0 AND peer_id = ?", message.SenderID)
+	}
+	var readers []uint
+	if err := query.Pluck("user_id", &readers).Error; err != nil {
+		return nil, err
+	}
+	if len(readers) == 0 {
+		return recipientIDs, nil
+	}
+	read := make(map[uint]struct{}, len(readers))
+	for _, id := range readers {
+		read[id] = struct{}{}
+	}
+	result := make([]uint, 0, len(recipientIDs))
+	for _, id := range recipientIDs {
+		if _, ok := read[id]; !ok {
+			result = append(result, id)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"errors"
+	"rag-agent-server/internal/models"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestConversationRefValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		ref     ConversationRef
+		wantErr bool
+	}{
+		{name: "room", ref: ConversationRef{RoomID: 3}},
+		{name: "peer", ref: ConversationRef{PeerID: 2}},
+		{name: "none", ref: ConversationRef{}, wantErr: true},
+		{name: "both", ref: ConversationRef{RoomID: 3, PeerID: 2}, wantErr: true},
+		{name: "self", ref: ConversationRef{PeerID: 1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := tt.ref.Validate(1)
+			if tt.wantErr != errors.Is(err, ErrInvalidConversation) {
+				t.Fatalf("Validate(%+v) error = %v, wantErr %v", tt.ref, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApplyReceiptStatus(t *testing.T) {
+	t.Parallel()
+
+	states := []models.ConversationReadState{
+		{UserID: 2, LastDeliveredMessageID: 20, LastReadMessageID: 10},
+		{UserID: 3, LastDeliveredMessageID: 15, LastReadMessageID: 15},
+	}
+
+	tests := []struct {
+		name      string
+		msg       models.Message
+		want      string
+		wantCount int
+	}{
+		{name: "read by everyone", msg: models.Message{Model: gorm.Model{ID: 10}, RoomID: 1}, want: models.MessageStatusRead, wantCount: 2},
+		{name: "read by one member", msg: models.Message{Model: gorm.Model{ID: 12}, RoomID: 1}, want: models.MessageStatusRead, wantCount: 1},
+		{name: "delivered only", msg: models.Message{Model: gorm.Model{ID: 18}, RoomID: 1}, want: models.MessageStatusDelivered},
+		{name: "sent", msg: models.Message{Model: gorm.Model{ID: 21}, RoomID: 1}, want: models.MessageStatusSent},
+		{name: "direct message has no read count", msg: models.Message{Model: gorm.Model{ID: 5}, RecipientID: 2}, want: models.MessageStatusRead},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			msg := tt.msg
+			applyReceiptStatus(&msg, states)
+			if msg.DeliveryStatus != tt.want || msg.ReadCount != tt.wantCount {
+				t.Fatalf("got status=%q readCount=%d, want %q/%d", msg.DeliveryStatus, msg.ReadCount, tt.want, tt.wantCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"errors"
+	"html"
+	"rag-agent-server/internal/database"
+	"rag-agent-server/internal/models"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+var ErrSearchQueryTooShort = errors.New("search query must be at least 2 characters")
+
+const (
+	minMessageSearchRunes     = 2
+	defaultMessageSearchLimit = 20
+	maxMessageSearchLimit     = 50
+
+	// Combined ru/en query, must match the messages.search_vector expression (see database.Connect)
+	messageSearchTSQuery = "(websearch_to_tsquery('russian', ?) || websearch_to_tsquery('english', ?))"
+
+	// Highlight markers are swapped for <mark> after HTML-escaping the snippet
+	searchHighlightStart         = "[[hl]]"
+	searchHighlightStop          = "[[/hl]]"
+	messageSearchHeadlineOptions = `StartSel="[[hl]]", StopSel="[[/hl]]", MaxWords=30, MinWords=8, MaxFragments=2, FragmentDelimiter=" … "`
+)
+
+// MessageSearchFilters narrows a message search. Zero values mean "any".
+type MessageSearchFilters struct {
+	Query      string
+	SenderID   uint
+	RoomID     uint
+	PeerUserID uint
+	Type       string
+	From       *time.Time
+	To         *time.Time
+	Limit      int
+	Offset     int
+}
+
+// MessageSearchHit is a matching message with its rank and highlighted snippet
+type MessageSearchHit struct {
+	Message  models.Message `json:"message"`
+	Rank     float64        `json:"rank"`
+	Snippet  string         `json:"snippet"` // HTML-escaped, matches wrapped in <mark>
+	RoomName string         `json:"roomName,omitempty"`
+	PeerID   uint           `json:"peerUserId,omitempty"`
+}
+
+// MessageSearchResult is a page of search hits ordered by relevance
+type MessageSearchResult struct {
+	Items      []MessageSearchHit `json:"items"`
+	HasMore    bool               `json:"hasMore"`
+	NextOffset *int               `json:"nextOffset"`
+}
+
+// MessageSearchService runs full-text search over the user's rooms and 1:1 chats
+type MessageSearchService struct {
+	db *gorm.DB
+}
+
+func NewMessageSearchService() *MessageSearchService {
+	return &MessageSearchService{db: database.DB}
+}
+
+// Search finds messages visible to userID that match filters.Query
+func (s *MessageSearchService) Search(userID uint, filters MessageSearchFilters) (*MessageSearchResult, error) {
+	q := strings.TrimSpace(filters.Query)
+	if utf8.RuneCountInString(q) < minMessageSearchRunes {
+		return nil, ErrSearchQueryTooShort
+	}
+	if filters.RoomID != 0 && filters.PeerUserID != 0 {
+		return nil, ErrInvalidConversation
+	}
+	limit := filters.Limit
+	if limit <= 0 {
+		limit = defaultMessageSearchLimit
+	}
+	if limit > maxMessageSearchLimit {
+		limit = maxMessageSearchLimit
+	}
+	offset := filters.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	myRooms := s.db.Model(&models.RoomMember{}).Select("room_id").Where("user_id = ?", userID)
+	query := s.db.Table("messages").
+		Select(
+			"messages.*, rooms.name AS room_name, "+
+				"ts_rank_cd(messages.search_vector, "+messageSearchTSQuery+") AS rank, "+
+				"ts_headline('russian', CASE WHEN coalesce(messages.type, 'text') = 'text' THEN messages.content ELSE messages.file_name END, "+
+				messageSearchTSQuery+", '"+messageSearchHeadlineOptions+"') AS snippet",
+			q, q, q, q,
+		).
+		Joins("LEFT JOIN rooms ON rooms.id = messages.room_id AND messages.room_id <> 0").
+		Where("messages.deleted_at IS NULL AND messages.deleted_for_all_at IS NULL").
+		Where("messages.search_vector @@ "+messageSearchTSQuery, q, q).
+		Where("(messages.room_id IN (?) OR (messages.room_id = 0 AND (messages.sender_id = ? OR messages.recipient_id = ?)))",
+			myRooms, userID, userID).
+		Where("messages.id NOT IN (?)", hiddenMessagesSubquery(s.db, userID))
+
+	if filters.RoomID != 0 {
+		query = query.Where("messages.room_id = ?", filters.RoomID)
+	}
+	if filters.PeerUserID != 0 {
+		query = query.Where("messages.room_id = 0 AND ((messages.sender_id = ? AND messages.recipient_id = ?) OR (messages.sender_id = ? AND messages.recipient_id = ?))",
+			userID, filters.PeerUserID, filters.PeerUserID, userID)
+	}
+	if filters.SenderID != 0 {
+		query = query.Where("messages.sender_id = ?", filters.SenderID)
+	}
+	if t := strings.ToLower(strings.TrimSpace(filters.Type)); t != "" {
+		query = query.Where("coalesce(messages.type, 'text') = ?", t)
+	}
+	if filters.From != nil {
+		query = query.Where("messages.created_at >= ?", *filters.From)
+	}
+	if filters.To != nil {
+		query = query.Where("messages.created_at < ?", *filters.To)
+	}
+
+	type searchRow struct {
+		models.Message
+		RoomName string
+		Rank     float64
+		Snippet  string
+	}
+	var rows []searchRow
+	if err := query.Order("rank DESC, messages.id DESC").
+		Offset(offset).
+		Limit(limit + 1).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	result := &MessageSearchResult{Items: make([]MessageSearchHit, 0, len(rows))}
+	if len(rows) > limit {
+		rows = rows[:limit]
+		result.HasMore = true
+		next := offset + limit
+		result.NextOffset = &next
+	}
+	for _, row := range rows {
+		hit := MessageSearchHit{
+			Message:  row.Message,
+			Rank:     row.Rank,
+			Snippet:  renderSearchSnippet(row.Snippet),
+			RoomName: row.RoomName,
+		}
+		if row.Message.RoomID == 0 {
+			hit.PeerID = row.Message.SenderID
+			if hit.PeerID == userID {
+				hit.PeerID = row.Message.RecipientID
+			}
+		}
+		result.Items = append(result.Items, hit)
+	}
+	return result, nil
+}
+
+// renderSearchSnippet HTML-escapes a ts_headline fragment and turns the markers into <mark> tags
+func renderSearchSnippet(raw string) string {
+	escaped := html.EscapeString(raw)
+	escaped = strings.ReplaceAll(escaped, searchHighlightStart, "<mark>")
+	return strings.ReplaceAll(escaped, searchHighlightStop, "</mark>")
+}
@@ -0,0 +1,28 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRenderSearchSnippet(t *testing.T) {
+	t.Parallel()
+
+	raw := `Read [[hl]]Gita[[/hl]] <script>alert(1)</script> & chant … [[hl]]Кришна[[/hl]]`
+	want := `Read <mark>Gita</mark> &lt;script&gt;alert(1)&lt;/script&gt; &amp; chant … <mark>Кришна</mark>`
+	if got := renderSearchSnippet(raw); got != want {
+		t.Fatalf("renderSearchSnippet() = %q, want %q", got, want)
+	}
+}
+
+func TestMessageSearchValidatesInput(t *testing.T) {
+	t.Parallel()
+
+	s := &MessageSearchService{}
+	if _, err := s.Search(1, MessageSearchFilters{Query: " я "}); !errors.Is(err, ErrSearchQueryTooShort) {
+		t.Fatalf("expected ErrSearchQueryTooShort, got %v", err)
+	}
+	if _, err := s.Search(1, MessageSearchFilters{Query: "japa", RoomID: 2, PeerUserID: 3}); !errors.Is(err, ErrInvalidConversation) {
+		t.Fatalf("expected ErrInvalidConversation, got %v", err)
+	}
+}
@@ -0,0 +1,362 @@
+package services
+
+import (
+	"errors"
+	"rag-agent-server/internal/database"
+	"rag-agent-server/internal/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidReplyTarget = errors.New("reply target is not part of this conversation")
+	ErrVerseNotFound      = errors.New("scripture verse not found")
+	ErrInvalidVerseAnchor = errors.New("verse can only anchor a room thread of the same book")
+)
+
+const (
+	maxQuoteRunes        = 500
+	replyPreviewRunes    = 200
+	defaultThreadLimit   = 50
+	maxThreadLimit       = 200
+	maxVerseThreadsLimit = 100
+)
+
+// MessageThreadService handles replies, quotes and threads anchored to scripture verses
+type MessageThreadService struct {
+	db *gorm.DB
+}
+
+func NewMessageThreadService() *MessageThreadService {
+	return &MessageThreadService{db: database.DB}
+}
+
+// MessageThreadPage is a page of thread replies, oldest first
+type MessageThreadPage struct {
+	Root        models.Message   `json:"root"`
+	Items       []models.Message `json:"items"`
+	ReplyCount  int64            `json:"replyCount"`
+	HasMore     bool             `json:"hasMore"`
+	NextAfterID *uint            `json:"nextAfterId"`
+}
+
+// VerseAnchor is a compact reference to the ScriptureVerse a thread is attached to
+type VerseAnchor struct {
+	ID             uint   `json:"id"`
+	BookCode       string `json:"bookCode"`
+	Canto          int    `json:"canto"`
+	Chapter        int    `json:"chapter"`
+	Verse          string `json:"verse"`
+	VerseReference string `json:"verseReference,omitempty"`
+}
+
+// VerseThread is a room thread root anchored to a verse
+type VerseThread struct {
+	Root        models.Message `json:"root"`
+	Verse       *VerseAnchor   `json:"verse,omitempty"`
+	ReplyCount  int64          `json:"replyCount"`
+	LastReplyAt *time.Time     `json:"lastReplyAt,omitempty"`
+}
+
+// VerseThreadFilter narrows verse threads; zero values mean "any"
+type VerseThreadFilter struct {
+	VerseID uint
+	Canto   int
+	Chapter int
+}
+
+// PrepareReply validates ReplyToID/VerseID of a new message and derives ThreadRootID.
+// Replies to a reply join the thread of the original root.
+func (s *MessageThreadService) PrepareReply(msg *models.Message) error {
+	msg.ThreadRootID = nil
+	if msg.ReplyToID != nil && *msg.ReplyToID == 0 {
+		msg.ReplyToID = nil
+	}
+	if msg.VerseID != nil && *msg.VerseID == 0 {
+		msg.VerseID = nil
+	}
+	msg.QuoteText = strings.TrimSpace(msg.QuoteText)
+	if msg.ReplyToID == nil {
+		msg.QuoteText = ""
+	} else {
+		msg.QuoteText = truncateText(msg.QuoteText, maxQuoteRunes)
+	}
+
+	if msg.ReplyToID != nil {
+		var parent models.Message
+		if err := s.db.First(&parent, *msg.ReplyToID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrMessageNotFound
+			}
+			return err
+		}
+		if !sameConversation(&parent, msg) {
+			return ErrInvalidReplyTarget
+		}
+		if parent.IsDeletedForAll() {
+			return ErrMessageDeleted
+		}
+		rootID := parent.ID
+		if parent.ThreadRootID != nil {
+			rootID = *parent.ThreadRootID
+		}
+		msg.ThreadRootID = &rootID
+	}
+
+	if msg.VerseID == nil {
+		return nil
+	}
+	// Only room thread roots carry a verse; replies inherit it through the root
+	if msg.RoomID == 0 || msg.ReplyToID != nil {
+		return ErrInvalidVerseAnchor
+	}
+	var verse models.ScriptureVerse
+	if err := s.db.Select("id", "book_code").First(&verse, *msg.VerseID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrVerseNotFound
+		}
+		return err
+	}
+	var room models.Room
+	if err := s.db.Select("id", "book_code").First(&room, msg.RoomID).Error; err != nil {
+		return err
+	}
+	if room.BookCode != "" && !strings.EqualFold(room.BookCode, verse.BookCode) {
+		return ErrInvalidVerseAnchor
+	}
+	return nil
+}
+
+func sameConversation(parent, msg *models.Message) bool {
+	if parent.RoomID != msg.RoomID {
+		return false
+	}
+	if msg.RoomID != 0 {
+		return true
+	}
+	return (parent.SenderID == msg.SenderID && parent.RecipientID == msg.RecipientID) ||
+		(parent.SenderID == msg.RecipientID && parent.RecipientID == msg.SenderID)
+}
+
+// AttachThreadInfo fills ThreadReplyCount and ReplyPreview on the given messages
+func (s *MessageThreadService) AttachThreadInfo(messages []models.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(messages))
+	parentIDs := make([]uint, 0)
+	for _, msg := range messages {
+		ids = append(ids, msg.ID)
+		if msg.ReplyToID != nil {
+			parentIDs = append(parentIDs, *msg.ReplyToID)
+		}
+	}
+
+	counts, err := s.replyStats(ids)
+	if err != nil {
+		return err
+	}
+
+	parents := make(map[uint]models.Message)
+	if len(parentIDs) > 0 {
+		var rows []models.Message
+		if err := s.db.Unscoped().Where("id IN ?", uniqueIDs(parentIDs)).Find(&rows).Error; err != nil {
+			return err
+		}
+		for _, row := range rows {
+			parents[row.ID] = row
+		}
+	}
+
+	for i := range messages {
+		messages[i].ThreadReplyCount = int(counts[messages[i].ID].Count)
+		if messages[i].ReplyToID == nil {
+			continue
+		}
+		parent, ok := parents[*messages[i].ReplyToID]
+		if !ok {
+			messages[i].ReplyPreview = &models.MessageReplyPreview{ID: *messages[i].ReplyToID, Deleted: true}
+			continue
+		}
+		messages[i].ReplyPreview = buildReplyPreview(parent)
+	}
+	return nil
+}
+
+func buildReplyPreview(parent models.Message) *models.MessageReplyPreview {
+	preview := &models.MessageReplyPreview{
+		ID:       parent.ID,
+		SenderID: parent.SenderID,
+		Type:     parent.Type,
+	}
+	if parent.IsDeletedForAll() || parent.DeletedAt.Valid {
+		preview.Deleted = true
+		return preview
+	}
+	if parent.Type == "" || parent.Type == "text" {
+		preview.Content = truncateText(parent.Content, replyPreviewRunes)
+	} else {
+		preview.Content = parent.FileName
+	}
+	return preview
+}
+
+type threadStat struct {
+	ThreadRootID uint
+	Count        int64
+	LastReplyAt  *time.Time
+}
+
+func (s *MessageThreadService) replyStats(rootIDs []uint) (map[uint]threadStat, error) {
+	stats := make(map[uint]threadStat, len(rootIDs))
+	if len(rootIDs) == 0 {
+		return stats, nil
+	}
+	var rows []threadStat
+	if err := s.db.Model(&models.Message{}).
+		Select("thread_root_id, COUNT(*) AS count, MAX(created_at) AS last_reply_at").
+		Where("thread_root_id IN ?", rootIDs).
+		Group("thread_root_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		stats[row.ThreadRootID] = row
+	}
+	return stats, nil
+}
+
+// ListThread returns replies of the thread that contains messageID
+func (s *MessageThreadService) ListThread(messageID, viewerID, afterID uint, limit int) (*MessageThreadPage, error) {
+	if limit <= 0 {
+		limit = defaultThreadLimit
+	}
+	if limit > maxThreadLimit {
+		limit = maxThreadLimit
+	}
+
+	root, _, err := loadMessageForParticipant(s.db, messageID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if root.ThreadRootID != nil {
+		if root, _, err = loadMessageForParticipant(s.db, *root.ThreadRootID, viewerID); err != nil {
+			return nil, err
+		}
+	}
+
+	base := func() *gorm.DB {
+		return s.db.Model(&models.Message{}).
+			Where("thread_root_id = ?", root.ID).
+			Where("id NOT IN (?)", hiddenMessagesSubquery(s.db, viewerID))
+	}
+
+	page := &MessageThreadPage{Root: *root}
+	if err := base().Count(&page.ReplyCount).Error; err != nil {
+		return nil, err
+	}
+
+	query := base()
+	if afterID > 0 {
+		query = query.Where("id > ?", afterID)
+	}
+	var items []models.Message
+	if err := query.Order("id ASC").Limit(limit + 1).Find(&items).Error; err != nil {
+		return nil, err
+	}
+	if len(items) > limit {
+		items = items[:limit]
+		page.HasMore = true
+		lastID := items[len(items)-1].ID
+		page.NextAfterID = &lastID
+	}
+
+	if err := s.AttachThreadInfo(items); err != nil {
+		return nil, err
+	}
+	rootSlice := []models.Message{page.Root}
+	if err := s.AttachThreadInfo(rootSlice); err != nil {
+		return nil, err
+	}
+	page.Root = rootSlice[0]
+	page.Items = items
+	return page, nil
+}
+
+// ListVerseThreads returns verse-anchored thread roots of a room, newest first
+func (s *MessageThreadService) ListVerseThreads(roomID, viewerID uint, filter VerseThreadFilter) ([]VerseThread, error) {
+	var memberCount int64
+	if err := s.db.Model(&models.RoomMember{}).
+		Where("room_id = ? AND user_id = ?", roomID, viewerID).
+		Count(&memberCount).Error; err != nil {
+		return nil, err
+	}
+	if memberCount == 0 {
+		return nil, ErrMessageForbidden
+	}
+
+	query := s.db.Model(&models.Message{}).
+		Where("room_id = ? AND verse_id IS NOT NULL AND thread_root_id IS NULL", roomID).
+		Where("id NOT IN (?)", hiddenMessagesSubquery(s.db, viewerID))
+	if filter.VerseID != 0 {
+		query = query.Where("verse_id = ?", filter.VerseID)
+	} else if filter.Chapter > 0 {
+		verses := s.db.Model(&models.ScriptureVerse{}).Select("id").Where("chapter = ?", filter.Chapter)
+		if filter.Canto > 0 {
+			verses = verses.Where("canto = ?", filter.Canto)
+		}
+		query = query.Where("verse_id IN (?)", verses)
+	}
+
+	var roots []models.Message
+	if err := query.Order("id DESC").Limit(maxVerseThreadsLimit).Find(&roots).Error; err != nil {
+		return nil, err
+	}
+	if len(roots) == 0 {
+		return []VerseThread{}, nil
+	}
+
+	rootIDs := make([]uint, 0, len(roots))
+	verseIDs := make([]uint, 0, len(roots))
+	for _, root := range roots {
+		rootIDs = append(rootIDs, root.ID)
+		verseIDs = append(verseIDs, *root.VerseID)
+	}
+	stats, err := s.replyStats(rootIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var verseRows []models.ScriptureVerse
+	if err := s.db.Select("id", "book_code", "canto", "chapter", "verse", "verse_reference").
+		Where("id IN ?", uniqueIDs(verseIDs)).
+		Find(&verseRows).Error; err != nil {
+		return nil, err
+	}
+	verses := make(map[uint]*VerseAnchor, len(verseRows))
+	for _, v := range verseRows {
+		verses[v.ID] = &VerseAnchor{
+			ID:             v.ID,
+			BookCode:       v.BookCode,
+			Canto:          v.Canto,
+			Chapter:        v.Chapter,
+			Verse:          v.Verse,
+			VerseReference: v.VerseReference,
+		}
+	}
+
+	result := make([]VerseThread, 0, len(roots))
+	for _, root := range roots {
+		stat := stats[root.ID]
+		root.ThreadReplyCount = int(stat.Count)
+		result = append(result, VerseThread{
+			Root:        root,
+			Verse:       verses[*root.VerseID],
+			ReplyCount:  stat.Count,
+			LastReplyAt: stat.LastReplyAt,
+		})
+	}
+	return result, nil
+}
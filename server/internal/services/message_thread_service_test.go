@@ -0,0 +1,71 @@
+package services
+
+import (
+	"rag-agent-server/internal/models"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestSameConversation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		parent models.Message
+		reply  models.Message
+		want   bool
+	}{
+		{name: "same room", parent: models.Message{RoomID: 5, SenderID: 1}, reply: models.Message{RoomID: 5, SenderID: 2}, want: true},
+		{name: "other room", parent: models.Message{RoomID: 5}, reply: models.Message{RoomID: 6}, want: false},
+		{name: "direct reply to peer", parent: models.Message{SenderID: 1, RecipientID: 2}, reply: models.Message{SenderID: 2, RecipientID: 1}, want: true},
+		{name: "direct reply to self", parent: models.Message{SenderID: 1, RecipientID: 2}, reply: models.Message{SenderID: 1, RecipientID: 2}, want: true},
+		{name: "foreign direct chat", parent: models.Message{SenderID: 1, RecipientID: 3}, reply: models.Message{SenderID: 1, RecipientID: 2}, want: false},
+		{name: "room message into direct chat", parent: models.Message{RoomID: 5, SenderID: 2}, reply: models.Message{SenderID: 1, RecipientID: 2}, want: false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := sameConversation(&tt.parent, &tt.reply); got != tt.want {
+				t.Fatalf("sameConversation() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildReplyPreview(t *testing.T) {
+	t.Parallel()
+
+	text := models.Message{Model: gorm.Model{ID: 7}, SenderID: 3, Type: "text", Content: "Hare Krishna"}
+	if preview := buildReplyPreview(text); preview.Content != "Hare Krishna" || preview.Deleted {
+		t.Fatalf("unexpected text preview: %+v", preview)
+	}
+
+	media := models.Message{Model: gorm.Model{ID: 8}, Type: "image", Content: "https://cdn/x.jpg", FileName: "x.jpg"}
+	if preview := buildReplyPreview(media); preview.Content != "x.jpg" {
+		t.Fatalf("media preview should show file name, got %+v", preview)
+	}
+
+	now := time.Now()
+	deleted := models.Message{Model: gorm.Model{ID: 9}, Type: "text", Content: "secret", DeletedForAllAt: &now}
+	if preview := buildReplyPreview(deleted); !preview.Deleted || preview.Content != "" {
+		t.Fatalf("deleted parent must not leak content, got %+v", preview)
+	}
+}
+
+func TestPrepareReplyWithoutParentDropsQuote(t *testing.T) {
+	t.Parallel()
+
+	zero := uint(0)
+	root := uint(42)
+	msg := models.Message{RoomID: 1, QuoteText: "quoted", ReplyToID: &zero, ThreadRootID: &root}
+	if err := (&MessageThreadService{}).PrepareReply(&msg); err != nil {
+		t.Fatalf("PrepareReply() error = %v", err)
+	}
+	if msg.ReplyToID != nil || msg.ThreadRootID != nil || msg.QuoteText != "" {
+		t.Fatalf("expected plain message, got replyTo=%v root=%v quote=%q", msg.ReplyToID, msg.ThreadRootID, msg.QuoteText)
+	}
+}
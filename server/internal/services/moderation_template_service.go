@@ -2,17 +2,23 @@ package services
 
 import (
 	"errors"
+	"fmt"
+	"log"
 	"rag-agent-server/internal/models"
+	"regexp"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 )
 
 type ModerationTemplateService struct {
-	db *gorm.DB
+	db     *gorm.DB
+	outbox *EmailOutboxService
 }
 
-func NewModerationTemplateService(db *gorm.DB) *ModerationTemplateService {
-	return &ModerationTemplateService{db: db}
+func NewModerationTemplateService(db *gorm.DB, outbox *EmailOutboxService) *ModerationTemplateService {
+	return &ModerationTemplateService{db: db, outbox: outbox}
 }
 
 // CreateTemplate creates a new email/notification template
@@ -82,47 +88,149 @@ func (s *ModerationTemplateService) DeleteTemplate(id uint) error {
 	return s.db.Delete(&models.ModerationTemplate{}, id).Error
 }
 
-// BroadcastEmail mock function - in real world this would send emails
-// This is a placeholder for the broadcast functionality
-func (s *ModerationTemplateService) BroadcastEmail(templateID uint, recipientFilter string) (int, error) {
-	template, err := s.GetTemplateByID(templateID)
-	if err != nil {
-		return 0, err
+// Broadcast recipient filters
+const (
+	BroadcastAllOrganizers     = "all_organizers"
+	BroadcastActiveOrganizers  = "active_organizers"
+	BroadcastBlockedOrganizers = "blocked_organizers"
+)
+
+// ErrUnknownRecipientFilter is returned for unsupported broadcast filters
+var ErrUnknownRecipientFilter = errors.New("unknown recipient filter")
+
+var templateVariablePattern = regexp.MustCompile(`\{\{\s*([a-zA-Z0-9_]+)\s*\}\}`)
+
+// RenderTemplate substitutes {{variable}} placeholders. Unknown variables are left as-is.
+func RenderTemplate(text string, vars map[string]string) string {
+	return templateVariablePattern.ReplaceAllStringFunc(text, func(match string) string {
+		key := templateVariablePattern.FindStringSubmatch(match)[1]
+		if value, ok := vars[key]; ok {
+			return value
+		}
+		return match
+	})
+}
+
+// broadcastRecipient is a resolved user with fields needed for rendering
+type broadcastRecipient struct {
+	ID            uint
+	Email         string
+	KarmicName    string
+	SpiritualName string
+}
+
+func (r broadcastRecipient) displayName() string {
+	if name := strings.TrimSpace(r.SpiritualName); name != "" {
+		return name
+	}
+	if name := strings.TrimSpace(r.KarmicName); name != "" {
+		return name
 	}
+	return r.Email
+}
 
-	// 1. Fetch Recipients based on filter
-	// var users []models.User
-	// query := s.db.Model(&models.User{})
+// resolveBroadcastRecipients returns users matching the recipient filter
+func (s *ModerationTemplateService) resolveBroadcastRecipients(recipientFilter string) ([]broadcastRecipient, error) {
+	now := time.Now()
+	query := s.db.Model(&models.User{}).
+		Select("users.id, users.email, users.karmic_name, users.spiritual_name").
+		Where("users.email <> ''")
 
 	switch recipientFilter {
-	case "all_organizers":
-		// Example logic: Users who have created at least one yatra
-		// For simplicity, let's assume all users for this MVP mock
-		// In production: join with yatras table or check role
-	case "active_organizers":
-		// Users with active yatras
-	case "blocked_organizers":
-		// Users in blocked table
-	}
-
-	// Mocking the fetch
-	// if err := query.Find(&users).Error; err != nil { return 0, err }
-
-	// 2. Mock Send Loop
-	// count := 0
-	// for _, user := range users {
-	//    sendEmail(user.Email, template.Subject, template.Body)
-	//    count++
-	// }
-
-	// For MVP, we just return a success count without actually spamming real emails
-	// We'll trust the AdminNotificationService is working, but for mass email
-	// we usually use an external provider (SendGrid, AWS SES).
-	// Here we return a dummy count.
+	case BroadcastAllOrganizers:
+		query = query.Where("users.id IN (?)",
+			s.db.Model(&models.Yatra{}).Select("DISTINCT organizer_id"))
+	case BroadcastActiveOrganizers:
+		query = query.Where("users.id IN (?)",
+			s.db.Model(&models.Yatra{}).Select("DISTINCT organizer_id").
+				Where("status IN ?", []models.YatraStatus{models.YatraStatusOpen, models.YatraStatusFull, models.YatraStatusActive}))
+	case BroadcastBlockedOrganizers:
+		query = query.Where("users.id IN (?)",
+			s.db.Model(&models.OrganizerBlock{}).Select("user_id").
+				Where("is_active = ? AND (expires_at IS NULL OR expires_at > ?)", true, now))
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownRecipientFilter, recipientFilter)
+	}
+
+	var recipients []broadcastRecipient
+	if err := query.Order("users.id ASC").Scan(&recipients).Error; err != nil {
+		return nil, err
+	}
+	return recipients, nil
+}
 
+// BroadcastEmail resolves recipients for the filter, renders the template per
+// recipient and queues the emails in the outbox. Delivery happens in the
+// email outbox worker. Returns the created broadcast with its recipient count.
+func (s *ModerationTemplateService) BroadcastEmail(templateID uint, recipientFilter string, adminID uint, vars map[string]string) (*models.EmailBroadcast, error) {
+	template, err := s.GetTemplateByID(templateID)
+	if err != nil {
+		return nil, err
+	}
 	if !template.IsActive {
-		return 0, errors.New("template is not active")
+		return nil, errors.New("template is not active")
+	}
+	if s.outbox == nil {
+		return nil, errors.New("email outbox is not configured")
+	}
+
+	recipients, err := s.resolveBroadcastRecipients(recipientFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	subject := template.Subject
+	if strings.TrimSpace(subject) == "" {
+		subject = template.Name
+	}
+	date := time.Now().Format("02.01.2006")
+
+	broadcast := &models.EmailBroadcast{
+		TemplateID:      template.ID,
+		RecipientFilter: recipientFilter,
+		CreatedBy:       adminID,
+		Status:          models.EmailBroadcastQueued,
+		TotalCount:      len(recipients),
+	}
+	if len(recipients) == 0 {
+		// Nothing to deliver, so the outbox worker will never complete it
+		now := time.Now()
+		broadcast.Status = models.EmailBroadcastCompleted
+		broadcast.CompletedAt = &now
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(broadcast).Error; err != nil {
+			return err
+		}
+
+		messages := make([]models.EmailOutboxMessage, 0, len(recipients))
+		for _, recipient := range recipients {
+			recipientVars := map[string]string{
+				"organizerName": recipient.displayName(),
+				"date":          date,
+			}
+			for key, value := range vars {
+				if _, reserved := recipientVars[key]; !reserved {
+					recipientVars[key] = value
+				}
+			}
+			broadcastID := broadcast.ID
+			userID := recipient.ID
+			messages = append(messages, models.EmailOutboxMessage{
+				BroadcastID: &broadcastID,
+				UserID:      &userID,
+				ToEmail:     recipient.Email,
+				Subject:     RenderTemplate(subject, recipientVars),
+				Body:        RenderTemplate(template.Body, recipientVars),
+			})
+		}
+		return s.outbox.Enqueue(tx, messages)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return 42, nil // Mock: "Sent to 42 users"
+	log.Printf("[Broadcast] Admin %d queued template %d for %d recipients (%s)", adminID, template.ID, len(recipients), recipientFilter)
+	return broadcast, nil
 }
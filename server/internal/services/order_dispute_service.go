@@ -0,0 +1,544 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"rag-agent-server/internal/database"
+	"rag-agent-server/internal/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrDisputeNotFound          = errors.New("dispute not found")
+	ErrDisputeExists            = errors.New("a dispute is already opened for this order")
+	ErrDisputeNotAllowed        = errors.New("dispute cannot be opened at this stage")
+	ErrDisputeForbidden         = errors.New("not a party of this dispute")
+	ErrDisputeResolved          = errors.New("dispute is already resolved")
+	ErrDisputeReasonRequired    = errors.New("dispute reason is required")
+	ErrDisputeEmptyStatement    = errors.New("statement text or photos are required")
+	ErrDisputeInvalidResolution = errors.New("invalid dispute resolution")
+	ErrDisputeInvalidRefund     = errors.New("partial refund must be between 1 and the paid amount minus 1")
+	ErrDisputeSellerBalance     = errors.New("seller balance is too low to return the payout")
+)
+
+// Dispute SLA settings (SystemSetting keys) and their defaults
+const (
+	marketDisputeSellerHoursSetting  = "MARKET_DISPUTE_SELLER_RESPONSE_HOURS"
+	marketDisputeResolveHoursSetting = "MARKET_DISPUTE_RESOLUTION_HOURS"
+
+	defaultMarketDisputeSellerHours  = 48
+	defaultMarketDisputeResolveHours = 120
+)
+
+// OrderDisputeService runs the buyer/seller dispute workflow for marketplace orders
+type OrderDisputeService struct {
+	db            *gorm.DB
+	walletService *WalletService
+	orderService  *OrderService
+	notifications *AdminNotificationService
+}
+
+func NewOrderDisputeService() *OrderDisputeService {
+	return &OrderDisputeService{
+		db:            database.DB,
+		walletService: NewWalletService(),
+		orderService:  NewOrderService(),
+		notifications: NewAdminNotificationService(database.DB),
+	}
+}
+
+// OpenDispute moves a shipped or delivered order into dispute with the buyer's statement
+func (s *OrderDisputeService) OpenDispute(orderID, buyerID uint, req models.OrderDisputeOpenRequest, evidence []models.OrderDisputeEvidence) (*models.OrderDispute, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, ErrDisputeReasonRequired
+	}
+	reason = truncateText(reason, 197)
+
+	var (
+		dispute models.OrderDispute
+		order   models.Order
+	)
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, orderID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrOrderNotFound
+			}
+			return err
+		}
+		if order.BuyerID != buyerID {
+			return ErrDisputeForbidden
+		}
+		if !s.orderService.isValidStatusTransition(order.Status, models.OrderStatusDispute) {
+			return ErrDisputeNotAllowed
+		}
+
+		var existing int64
+		if err := tx.Unscoped().Model(&models.OrderDispute{}).Where("order_id = ?", orderID).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return ErrDisputeExists
+		}
+
+		now := time.Now().UTC()
+		dispute = models.OrderDispute{
+			OrderID:             order.ID,
+			BuyerID:             order.BuyerID,
+			SellerID:            order.SellerID,
+			Status:              models.OrderDisputeOpen,
+			Reason:              reason,
+			PreviousOrderStatus: order.Status,
+			SellerDueAt:         now.Add(time.Duration(marketIntSetting(tx, marketDisputeSellerHoursSetting, defaultMarketDisputeSellerHours)) * time.Hour),
+			ResolveDueAt:        now.Add(time.Duration(marketIntSetting(tx, marketDisputeResolveHoursSetting, defaultMarketDisputeResolveHours)) * time.Hour),
+		}
+		if err := tx.Create(&dispute).Error; err != nil {
+			return err
+		}
+		if _, err := s.createStatementTx(tx, &dispute, buyerID, models.OrderDisputeBuyer, req.Text, evidence); err != nil {
+			return err
+		}
+
+		return tx.Model(&order).Update("status", models.OrderStatusDispute).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyAdmins(&dispute, models.NotificationOrderDispute,
+		fmt.Sprintf("Открыт спор по заказу %s: %s", order.OrderNumber, reason))
+	if err := GetPushService().SendOrderDisputeOpened(order.SellerID, order.ID, order.OrderNumber, dispute.SellerDueAt); err != nil {
+		log.Printf("[Disputes] Failed to push dispute %d to seller %d: %v", dispute.ID, order.SellerID, err)
+	}
+	return &dispute, nil
+}
+
+// GetOrderDispute returns the dispute of an order to its buyer or seller
+func (s *OrderDisputeService) GetOrderDispute(orderID, userID uint) (*models.OrderDispute, error) {
+	var dispute models.OrderDispute
+	if err := s.withStatements(s.db).Where("order_id = ?", orderID).First(&dispute).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDisputeNotFound
+		}
+		return nil, err
+	}
+	if dispute.BuyerID != userID && dispute.SellerID != userID {
+		return nil, ErrDisputeForbidden
+	}
+	return &dispute, nil
+}
+
+// AddStatement attaches a buyer or seller statement to an unresolved dispute. The
+// seller's first answer moves the dispute into the admin queue.
+func (s *OrderDisputeService) AddStatement(orderID, userID uint, text string, evidence []models.OrderDisputeEvidence) (*models.OrderDisputeStatement, error) {
+	var (
+		dispute   models.OrderDispute
+		statement *models.OrderDisputeStatement
+		toReview  bool
+	)
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("order_id = ?", orderID).First(&dispute).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrDisputeNotFound
+			}
+			return err
+		}
+		if dispute.Status == models.OrderDisputeResolved {
+			return ErrDisputeResolved
+		}
+
+		var party models.OrderDisputeParty
+		switch userID {
+		case dispute.BuyerID:
+			party = models.OrderDisputeBuyer
+		case dispute.SellerID:
+			party = models.OrderDisputeSeller
+		default:
+			return ErrDisputeForbidden
+		}
+
+		var err error
+		if statement, err = s.createStatementTx(tx, &dispute, userID, party, text, evidence); err != nil {
+			return err
+		}
+
+		if party == models.OrderDisputeSeller && dispute.SellerRespondedAt == nil {
+			now := time.Now().UTC()
+			updates := map[string]interface{}{"seller_responded_at": now}
+			if dispute.Status == models.OrderDisputeOpen {
+				updates["status"] = models.OrderDisputeReview
+				toReview = true
+			}
+			return tx.Model(&dispute).Updates(updates).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if toReview {
+		s.notifyAdmins(&dispute, models.NotificationOrderDispute,
+			fmt.Sprintf("Продавец ответил по спору #%d, спор ждёт решения", dispute.ID))
+	}
+	return statement, nil
+}
+
+func (s *OrderDisputeService) createStatementTx(tx *gorm.DB, dispute *models.OrderDispute, authorID uint, party models.OrderDisputeParty, text string, evidence []models.OrderDisputeEvidence) (*models.OrderDisputeStatement, error) {
+	text = strings.TrimSpace(text)
+	if text == "" && len(evidence) == 0 {
+		return nil, ErrDisputeEmptyStatement
+	}
+	statement := models.OrderDisputeStatement{
+		DisputeID: dispute.ID,
+		AuthorID:  authorID,
+		Party:     party,
+		Text:      text,
+		Evidence:  evidence,
+	}
+	if err := tx.Create(&statement).Error; err != nil {
+		return nil, err
+	}
+	return &statement, nil
+}
+
+// ListQueue returns disputes for admin review, the closest resolution deadline first.
+// Without a status filter only unresolved disputes are returned.
+func (s *OrderDisputeService) ListQueue(filters models.OrderDisputeFilters) (*models.OrderDisputeListResponse, error) {
+	query := s.db.Model(&models.OrderDispute{})
+	if filters.Status != "" {
+		query = query.Where("status = ?", filters.Status)
+	} else {
+		query = query.Where("status <> ?", models.OrderDisputeResolved)
+	}
+	if filters.Overdue {
+		query = query.Where("status <> ? AND (resolve_due_at < ? OR seller_overdue_at IS NOT NULL)", models.OrderDisputeResolved, time.Now().UTC())
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	page, limit := filters.Page, filters.Limit
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 50 {
+		limit = 20
+	}
+
+	var disputes []models.OrderDispute
+	if err := query.Preload("Order").
+		Order("resolve_due_at ASC").
+		Offset((page - 1) * limit).Limit(limit).
+		Find(&disputes).Error; err != nil {
+		return nil, err
+	}
+
+	return &models.OrderDisputeListResponse{
+		Disputes:   disputes,
+		Total:      total,
+		Page:       page,
+		TotalPages: calculateOrderTotalPages(total, limit),
+	}, nil
+}
+
+// GetDispute returns a dispute with the order and all statements for admin review
+func (s *OrderDisputeService) GetDispute(disputeID uint) (*models.OrderDispute, error) {
+	var dispute models.OrderDispute
+	if err := s.withStatements(s.db).Preload("Order.Items").First(&dispute, disputeID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDisputeNotFound
+		}
+		return nil, err
+	}
+	return &dispute, nil
+}
+
+func (s *OrderDisputeService) withStatements(db *gorm.DB) *gorm.DB {
+	return db.Preload("Statements", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at ASC")
+	}).Preload("Statements.Evidence")
+}
+
+// Resolve applies the admin decision: the buyer gets all, part or none of the paid LKM
+// back and the rest goes to the seller
+func (s *OrderDisputeService) Resolve(disputeID, adminID uint, req models.OrderDisputeResolveRequest) (*models.OrderDispute, error) {
+	var (
+		dispute models.OrderDispute
+		order   models.Order
+	)
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&dispute, disputeID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrDisputeNotFound
+			}
+			return err
+		}
+		if dispute.Status == models.OrderDisputeResolved {
+			return ErrDisputeResolved
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, dispute.OrderID).Error; err != nil {
+			return err
+		}
+
+		refund, err := disputeRefundAmount(req.Resolution, req.RefundLkm, order.RegularLkmPaid+order.BonusLkmPaid)
+		if err != nil {
+			return err
+		}
+		if err := s.settleTx(tx, &order, refund); err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		orderUpdates := map[string]interface{}{
+			"status":       models.OrderStatusCompleted,
+			"completed_at": now,
+		}
+		if req.Resolution == models.OrderDisputeFullRefund {
+			orderUpdates = map[string]interface{}{
+				"status":        models.OrderStatusCancelled,
+				"cancelled_at":  now,
+				"cancelled_by":  adminID,
+				"cancel_reason": "Возврат по решению спора",
+				"is_paid":       false,
+			}
+		}
+		if err := tx.Model(&order).Updates(orderUpdates).Error; err != nil {
+			return err
+		}
+
+		note := strings.TrimSpace(req.Note)
+		if note != "" {
+			if _, err := s.createStatementTx(tx, &dispute, adminID, models.OrderDisputeAdmin, note, nil); err != nil {
+				return err
+			}
+		}
+
+		dispute.Status = models.OrderDisputeResolved
+		dispute.Resolution = req.Resolution
+		dispute.RefundLkm = refund
+		dispute.ResolutionNote = note
+		dispute.ResolvedByID = &adminID
+		dispute.ResolvedAt = &now
+		return tx.Model(&dispute).
+			Select("status", "resolution", "refund_lkm", "resolution_note", "resolved_by_id", "resolved_at").
+			Updates(&dispute).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	push := GetPushService()
+	for _, userID := range []uint{dispute.BuyerID, dispute.SellerID} {
+		if err := push.SendOrderDisputeResolved(userID, order.ID, order.OrderNumber, dispute.Resolution, dispute.RefundLkm); err != nil {
+			log.Printf("[Disputes] Failed to push resolution of dispute %d to user %d: %v", dispute.ID, userID, err)
+		}
+	}
+	return &dispute, nil
+}
+
+// disputeRefundAmount validates the decision against the LKM paid for the order
+func disputeRefundAmount(resolution models.OrderDisputeResolution, requested, paid int) (int, error) {
+	switch resolution {
+	case models.OrderDisputeFullRefund:
+		return paid, nil
+	case models.OrderDisputeReleaseToSeller:
+		return 0, nil
+	case models.OrderDisputePartialRefund:
+		if requested <= 0 || requested >= paid {
+			return 0, ErrDisputeInvalidRefund
+		}
+		return requested, nil
+	default:
+		return 0, ErrDisputeInvalidResolution
+	}
+}
+
+// splitDisputeRefund returns regular and bonus LKM in the proportion they were paid
+func splitDisputeRefund(refund, regularPaid, bonusPaid int) (int, int) {
+	paid := regularPaid + bonusPaid
+	if refund <= 0 || paid <= 0 {
+		return 0, 0
+	}
+	if refund >= paid {
+		return regularPaid, bonusPaid
+	}
+	bonus := int(math.Round(float64(refund) * float64(bonusPaid) / float64(paid)))
+	if bonus > bonusPaid {
+		bonus = bonusPaid
+	}
+	regular := refund - bonus
+	if regular > regularPaid {
+		regular = regularPaid
+		bonus = refund - regular
+	}
+	return regular, bonus
+}
+
+// settleTx moves the paid LKM according to the decision. Held escrow is split between
+// buyer and seller; a payout the seller already received is charged back together with
+// the matching share of the platform fee.
+func (s *OrderDisputeService) settleTx(tx *gorm.DB, order *models.Order, refund int) error {
+	paid := order.RegularLkmPaid + order.BonusLkmPaid
+	if paid <= 0 {
+		return nil
+	}
+	regularRefund, bonusRefund := splitDisputeRefund(refund, order.RegularLkmPaid, order.BonusLkmPaid)
+	ref := orderHoldRef(order.ID)
+	refundDescription := "Возврат по спору, заказ " + order.OrderNumber
+	now := time.Now().UTC()
+
+	switch order.EscrowStatus {
+	case models.OrderEscrowHeld:
+		if refund > 0 {
+			if err := s.walletService.refundHoldTx(tx, order.BuyerID, regularRefund, bonusRefund, ref, refundDescription); err != nil {
+				return err
+			}
+		}
+		if refund == paid {
+			return tx.Model(order).Updates(map[string]interface{}{
+				"escrow_status":      models.OrderEscrowRefunded,
+				"escrow_refunded_at": now,
+			}).Error
+		}
+
+		rest := paid - refund
+		fee := marketEscrowFee(rest, s.orderService.escrowFeePercent(tx))
+		if err := s.walletService.releaseFundsTx(
+			tx,
+			order.BuyerID,
+			order.RegularLkmPaid-regularRefund,
+			order.BonusLkmPaid-bonusRefund,
+			ref,
+			order.SellerID,
+			fee,
+			"Оплата заказа "+order.OrderNumber+" по решению спора",
+		); err != nil {
+			return err
+		}
+		return tx.Model(order).Updates(map[string]interface{}{
+			"escrow_status":      models.OrderEscrowReleased,
+			"escrow_fee_lkm":     fee,
+			"escrow_released_at": now,
+		}).Error
+
+	case models.OrderEscrowReleased:
+		if refund == 0 {
+			return nil
+		}
+		feeShare := int(math.Round(float64(order.EscrowFeeLkm) * float64(refund) / float64(paid)))
+		if feeShare > order.EscrowFeeLkm {
+			feeShare = order.EscrowFeeLkm
+		}
+		sellerShare := refund - feeShare
+
+		buyerWallet, err := s.walletService.getOrCreateWalletTx(tx, order.BuyerID)
+		if err != nil {
+			return err
+		}
+		if sellerShare > 0 {
+			sellerWallet, err := s.walletService.getOrCreateLockedWalletTx(tx, order.SellerID)
+			if err != nil {
+				return err
+			}
+			if sellerWallet.Balance < sellerShare {
+				return ErrDisputeSellerBalance
+			}
+			if err := s.walletService.chargeBackTx(tx, sellerWallet, sellerShare, ref, buyerWallet.ID, refundDescription); err != nil {
+				return err
+			}
+		}
+		if feeShare > 0 {
+			platform, err := s.walletService.lockPlatformWalletTx(tx)
+			if err != nil {
+				return err
+			}
+			if err := s.walletService.chargeBackTx(tx, platform, feeShare, ref, buyerWallet.ID, "Возврат комиссии: "+refundDescription); err != nil {
+				return err
+			}
+		}
+		if err := s.walletService.refundTxWithSplit(tx, order.BuyerID, regularRefund, bonusRefund, refundDescription, nil); err != nil {
+			return err
+		}
+		updates := map[string]interface{}{"escrow_fee_lkm": order.EscrowFeeLkm - feeShare}
+		if refund == paid {
+			updates["escrow_status"] = models.OrderEscrowRefunded
+			updates["escrow_refunded_at"] = now
+		}
+		return tx.Model(order).Updates(updates).Error
+
+	default:
+		// Paid directly: the platform refunds the buyer as it does on cancellation
+		if refund == 0 || !order.IsPaid || order.PaymentMethod != "lkm" {
+			return nil
+		}
+		return s.walletService.refundTxWithSplit(tx, order.BuyerID, regularRefund, bonusRefund, refundDescription, nil)
+	}
+}
+
+// SweepSLA escalates disputes whose timers ran out: a seller who did not answer in time
+// sends the dispute to the admin queue, and an overdue resolution alerts the admins once
+func (s *OrderDisputeService) SweepSLA(now time.Time) (int, error) {
+	escalated := 0
+
+	var silent []models.OrderDispute
+	if err := s.db.Where("status = ? AND seller_responded_at IS NULL AND seller_due_at < ?", models.OrderDisputeOpen, now).
+		Limit(200).Find(&silent).Error; err != nil {
+		return escalated, err
+	}
+	for i := range silent {
+		res := s.db.Model(&models.OrderDispute{}).
+			Where("id = ? AND status = ?", silent[i].ID, models.OrderDisputeOpen).
+			Updates(map[string]interface{}{"status": models.OrderDisputeReview, "seller_overdue_at": now})
+		if res.Error != nil {
+			log.Printf("[Disputes] Failed to escalate dispute %d: %v", silent[i].ID, res.Error)
+			continue
+		}
+		if res.RowsAffected == 1 {
+			escalated++
+			s.notifyAdmins(&silent[i], models.NotificationOrderDisputeSLA,
+				fmt.Sprintf("Продавец не ответил по спору #%d вовремя, спор передан на рассмотрение", silent[i].ID))
+		}
+	}
+
+	var overdue []models.OrderDispute
+	if err := s.db.Where("status <> ? AND resolve_overdue_notified_at IS NULL AND resolve_due_at < ?", models.OrderDisputeResolved, now).
+		Limit(200).Find(&overdue).Error; err != nil {
+		return escalated, err
+	}
+	for i := range overdue {
+		res := s.db.Model(&models.OrderDispute{}).
+			Where("id = ? AND resolve_overdue_notified_at IS NULL", overdue[i].ID).
+			Update("resolve_overdue_notified_at", now)
+		if res.Error != nil {
+			log.Printf("[Disputes] Failed to flag overdue dispute %d: %v", overdue[i].ID, res.Error)
+			continue
+		}
+		if res.RowsAffected == 1 {
+			escalated++
+			s.notifyAdmins(&overdue[i], models.NotificationOrderDisputeSLA,
+				fmt.Sprintf("Спор #%d не решён в срок (до %s)", overdue[i].ID, overdue[i].ResolveDueAt.Format("02.01.2006 15:04")))
+		}
+	}
+	return escalated, nil
+}
+
+func (s *OrderDisputeService) notifyAdmins(dispute *models.OrderDispute, kind models.AdminNotificationType, message string) {
+	buyerID := dispute.BuyerID
+	if err := s.notifications.CreateNotification(models.AdminNotificationCreateRequest{
+		Type:    kind,
+		Message: message,
+		LinkTo:  fmt.Sprintf("/admin/disputes/%d", dispute.ID),
+		UserID:  &buyerID,
+	}); err != nil {
+		log.Printf("[Disputes] Failed to notify admins about dispute %d: %v", dispute.ID, err)
+	}
+}
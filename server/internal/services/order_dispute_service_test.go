@@ -0,0 +1,58 @@
+package services
+
+import (
+	"rag-agent-server/internal/models"
+	"testing"
+)
+
+func TestDisputeRefundAmount(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		resolution models.OrderDisputeResolution
+		requested  int
+		paid       int
+		want       int
+		wantErr    error
+	}{
+		{name: "full refund ignores requested", resolution: models.OrderDisputeFullRefund, requested: 10, paid: 300, want: 300},
+		{name: "release to seller", resolution: models.OrderDisputeReleaseToSeller, paid: 300, want: 0},
+		{name: "partial refund", resolution: models.OrderDisputePartialRefund, requested: 120, paid: 300, want: 120},
+		{name: "partial refund of everything", resolution: models.OrderDisputePartialRefund, requested: 300, paid: 300, wantErr: ErrDisputeInvalidRefund},
+		{name: "partial refund without amount", resolution: models.OrderDisputePartialRefund, paid: 300, wantErr: ErrDisputeInvalidRefund},
+		{name: "partial refund of external order", resolution: models.OrderDisputePartialRefund, requested: 1, paid: 0, wantErr: ErrDisputeInvalidRefund},
+		{name: "unknown resolution", resolution: "split", paid: 300, wantErr: ErrDisputeInvalidResolution},
+	}
+	for _, tc := range tests {
+		got, err := disputeRefundAmount(tc.resolution, tc.requested, tc.paid)
+		if err != tc.wantErr || got != tc.want {
+			t.Fatalf("%s: got %d, %v; want %d, %v", tc.name, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestSplitDisputeRefund(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		refund, regularPaid, bonusPaid int
+		wantRegular, wantBonus         int
+	}{
+		{refund: 100, regularPaid: 300, bonusPaid: 100, wantRegular: 75, wantBonus: 25},
+		{refund: 400, regularPaid: 300, bonusPaid: 100, wantRegular: 300, wantBonus: 100},
+		{refund: 50, regularPaid: 0, bonusPaid: 80, wantRegular: 0, wantBonus: 50},
+		{refund: 1, regularPaid: 1, bonusPaid: 1, wantRegular: 0, wantBonus: 1},
+		{refund: 0, regularPaid: 300, bonusPaid: 100},
+	}
+	for _, tc := range tests {
+		regular, bonus := splitDisputeRefund(tc.refund, tc.regularPaid, tc.bonusPaid)
+		if regular != tc.wantRegular || bonus != tc.wantBonus {
+			t.Fatalf("splitDisputeRefund(%d, %d, %d) = %d, %d; want %d, %d",
+				tc.refund, tc.regularPaid, tc.bonusPaid, regular, bonus, tc.wantRegular, tc.wantBonus)
+		}
+		if regular+bonus != min(tc.refund, tc.regularPaid+tc.bonusPaid) {
+			t.Fatalf("split of %d lost LKM: %d + %d", tc.refund, regular, bonus)
+		}
+	}
+}
@@ -3,7 +3,9 @@ package services
 import (
 	"errors"
 	"fmt"
+	"log"
 	"math"
+	"strconv"
 	"strings"
 	"time"
 
@@ -28,6 +30,16 @@ func NewOrderService() *OrderService {
 	}
 }
 
+// Escrow settings (SystemSetting keys) and their defaults
+const (
+	marketEscrowFeeSetting        = "MARKET_ESCROW_FEE_PERCENT"
+	marketEscrowAutoRefundSetting = "MARKET_ESCROW_AUTO_REFUND_DAYS"
+
+	defaultMarketEscrowFeePercent     = 5.0
+	maxMarketEscrowFeePercent         = 50.0
+	defaultMarketEscrowAutoRefundDays = 7
+)
+
 // Errors
 var (
 	ErrOrderNotFound      = errors.New("order not found")
@@ -291,19 +303,37 @@ func (s *OrderService) CreateOrder(buyerID uint, req models.OrderCreateRequest)
 		}
 
 		paymentAllocation := SpendAllocation{}
+		escrow := shop.EscrowEnabled && totalLKM > 0
 		if totalLKM > 0 {
-			allocation, _, err := s.walletService.spendTxWithOptions(
-				tx,
-				buyerID,
-				totalLKM,
-				fmt.Sprintf("market_order_%d", order.ID),
-				"Оплата заказа в магазине "+shop.Name,
-				SpendOptions{
-					AllowBonus:      shop.IsVedaMatch && bonusCapLKM > 0,
-					MaxBonusPercent: 100,
-					MaxBonusAmount:  bonusCapLKM,
-				},
+			spendOpts := SpendOptions{
+				AllowBonus:      shop.IsVedaMatch && bonusCapLKM > 0,
+				MaxBonusPercent: 100,
+				MaxBonusAmount:  bonusCapLKM,
+			}
+			var (
+				allocation SpendAllocation
+				err        error
 			)
+			if escrow {
+				// Frozen on the buyer's wallet until the order is delivered or refunded
+				allocation, err = s.walletService.holdFundsTx(
+					tx,
+					buyerID,
+					totalLKM,
+					orderHoldRef(order.ID),
+					"Оплата заказа в магазине "+shop.Name+" (удержание до доставки)",
+					spendOpts,
+				)
+			} else {
+				allocation, _, err = s.walletService.spendTxWithOptions(
+					tx,
+					buyerID,
+					totalLKM,
+					fmt.Sprintf("market_order_%d", order.ID),
+					"Оплата заказа в магазине "+shop.Name,
+					spendOpts,
+				)
+			}
 			if err != nil {
 				tx.Rollback()
 				return nil, fmt.Errorf("payment failed: %w", err)
@@ -319,6 +349,10 @@ func (s *OrderService) CreateOrder(buyerID uint, req models.OrderCreateRequest)
 			"regular_lkm_paid": paymentAllocation.RegularAmount,
 			"bonus_lkm_paid":   paymentAllocation.BonusAmount,
 		}
+		if escrow {
+			paymentUpdates["escrow_status"] = models.OrderEscrowHeld
+			order.EscrowStatus = models.OrderEscrowHeld
+		}
 		if err := tx.Model(&order).Updates(paymentUpdates).Error; err != nil {
 			tx.Rollback()
 			return nil, err
@@ -515,51 +549,173 @@ func (s *OrderService) getOrders(filters models.OrderFilters) (*models.OrderList
 	}, nil
 }
 
-// UpdateOrderStatus updates order status (seller only)
+// UpdateOrderStatus updates order status (seller only). The transition is validated
+// against the locked row, so a dispute, cancellation or escrow refund that lands first
+// is never overwritten.
 func (s *OrderService) UpdateOrderStatus(orderID uint, sellerID uint, status models.OrderStatus) (*models.Order, error) {
-	order, err := s.GetOrder(orderID)
-	if err != nil {
-		return nil, err
+	// Disputes carry the buyer's statement and are opened through OrderDisputeService
+	if status == models.OrderStatusDispute {
+		return nil, ErrInvalidOrderStatus
 	}
 
-	// Verify seller owns the shop
-	if order.SellerID != sellerID {
-		return nil, ErrUnauthorizedOrder
+	if status == models.OrderStatusCancelled {
+		order, err := s.GetOrder(orderID)
+		if err != nil {
+			return nil, err
+		}
+		if order.SellerID != sellerID {
+			return nil, ErrUnauthorizedOrder
+		}
+		if !s.isValidStatusTransition(order.Status, status) {
+			return nil, ErrInvalidOrderStatus
+		}
+		// Cancellation must return the buyer's LKM and reserved stock; CancelOrder
+		// re-checks the status under its own lock
+		return s.CancelOrder(orderID, sellerID, "")
 	}
 
-	// Validate status transition
-	if !s.isValidStatusTransition(order.Status, status) {
-		return nil, ErrInvalidOrderStatus
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("Items").
+			First(&order, orderID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrOrderNotFound
+			}
+			return err
+		}
+
+		// Verify seller owns the shop
+		if order.SellerID != sellerID {
+			return ErrUnauthorizedOrder
+		}
+
+		// Validate status transition
+		if !s.isValidStatusTransition(order.Status, status) {
+			return ErrInvalidOrderStatus
+		}
+
+		now := time.Now().UTC()
+		updates := map[string]interface{}{
+			"status": status,
+		}
+
+		switch status {
+		case models.OrderStatusConfirmed:
+			updates["confirmed_at"] = now
+		case models.OrderStatusShipped:
+			updates["shipped_at"] = now
+			// Deduct stock on shipment
+			for _, item := range order.Items {
+				if err := s.productService.DeductStockTx(tx, item.ProductID, item.VariantID, item.Quantity); err != nil {
+					return err
+				}
+			}
+		case models.OrderStatusDelivered:
+			updates["delivered_at"] = now
+		case models.OrderStatusCompleted:
+			updates["completed_at"] = now
+		}
+
+		if err := tx.Model(&order).Updates(updates).Error; err != nil {
+			return err
+		}
+		if status == models.OrderStatusDelivered || status == models.OrderStatusCompleted {
+			return s.releaseEscrowTx(tx, &order)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
 	}
 
+	return s.GetOrder(orderID)
+}
+
+// releaseEscrowTx pays a held order out to the seller minus the platform fee. The
+// conditional update makes a second delivered/completed transition a no-op.
+func (s *OrderService) releaseEscrowTx(tx *gorm.DB, order *models.Order) error {
+	regular, bonus := order.RegularLkmPaid, order.BonusLkmPaid
+	if regular+bonus <= 0 {
+		return nil
+	}
+	fee := marketEscrowFee(regular+bonus, s.escrowFeePercent(tx))
+
 	now := time.Now().UTC()
-	updates := map[string]interface{}{
-		"status": status,
+	res := tx.Model(&models.Order{}).
+		Where("id = ? AND escrow_status = ?", order.ID, models.OrderEscrowHeld).
+		Updates(map[string]interface{}{
+			"escrow_status":      models.OrderEscrowReleased,
+			"escrow_fee_lkm":     fee,
+			"escrow_released_at": now,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil
 	}
 
-	switch status {
-	case models.OrderStatusConfirmed:
-		updates["confirmed_at"] = now
-	case models.OrderStatusShipped:
-		updates["shipped_at"] = now
-		// Deduct stock on shipment
-		for _, item := range order.Items {
-			if err := s.productService.DeductStock(item.ProductID, item.VariantID, item.Quantity); err != nil {
-				return nil, err
-			}
-		}
-	case models.OrderStatusDelivered:
-		updates["delivered_at"] = now
-	case models.OrderStatusCompleted:
-		updates["completed_at"] = now
+	if err := s.walletService.releaseFundsTx(
+		tx,
+		order.BuyerID,
+		regular,
+		bonus,
+		orderHoldRef(order.ID),
+		order.SellerID,
+		fee,
+		"Оплата заказа "+order.OrderNumber,
+	); err != nil {
+		return fmt.Errorf("escrow release failed: %w", err)
 	}
 
-	if err := database.DB.Model(&order).Updates(updates).Error; err != nil {
-		return nil, err
+	order.EscrowStatus = models.OrderEscrowReleased
+	order.EscrowFeeLkm = fee
+	order.EscrowReleasedAt = &now
+	return nil
+}
+
+// marketEscrowFee rounds the platform fee to whole LKM
+func marketEscrowFee(total int, percent float64) int {
+	if total <= 0 || percent <= 0 {
+		return 0
 	}
+	fee := int(math.Round(float64(total) * percent / 100))
+	if fee > total {
+		return total
+	}
+	return fee
+}
+
+func (s *OrderService) escrowFeePercent(tx *gorm.DB) float64 {
+	percent, err := strconv.ParseFloat(marketSetting(tx, marketEscrowFeeSetting, ""), 64)
+	if err != nil || percent < 0 {
+		return defaultMarketEscrowFeePercent
+	}
+	if percent > maxMarketEscrowFeePercent {
+		return maxMarketEscrowFeePercent
+	}
+	return percent
+}
 
-	order.Status = status
-	return order, nil
+func (s *OrderService) escrowAutoRefundDays(tx *gorm.DB) int {
+	return marketIntSetting(tx, marketEscrowAutoRefundSetting, defaultMarketEscrowAutoRefundDays)
+}
+
+// marketIntSetting reads a positive integer setting
+func marketIntSetting(tx *gorm.DB, key string, fallback int) int {
+	value, err := strconv.Atoi(marketSetting(tx, key, ""))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+func marketSetting(tx *gorm.DB, key, fallback string) string {
+	var setting models.SystemSetting
+	if err := tx.Where("key = ?", key).First(&setting).Error; err == nil {
+		return strings.TrimSpace(setting.Value)
+	}
+	return fallback
 }
 
 // CancelOrder cancels an order
@@ -585,53 +741,127 @@ func (s *OrderService) CancelOrder(orderID uint, userID uint, reason string) (*m
 			return errors.New("order cannot be cancelled at this stage")
 		}
 
-		now := time.Now().UTC()
-		updates := map[string]interface{}{
-			"status":        models.OrderStatusCancelled,
-			"cancelled_at":  now,
-			"cancelled_by":  userID,
-			"cancel_reason": reason,
-		}
+		return s.cancelLockedOrderTx(tx, &cancelledOrder, &userID, reason)
+	}); err != nil {
+		return nil, err
+	}
 
-		// Restore reserved stock if order was confirmed
-		if cancelledOrder.Status == models.OrderStatusConfirmed {
-			for _, item := range cancelledOrder.Items {
-				if item.VariantID == nil {
-					continue
-				}
-				if err := tx.Model(&models.ProductVariant{}).
-					Where("id = ?", *item.VariantID).
-					Update("reserved", gorm.Expr("reserved - ?", item.Quantity)).Error; err != nil {
-					return err
-				}
-			}
-		}
+	return &cancelledOrder, nil
+}
+
+// cancelLockedOrderTx cancels an order whose row is locked by tx: it restores reserved
+// stock and returns the buyer's LKM, from escrow or as a refund of a direct payment
+func (s *OrderService) cancelLockedOrderTx(tx *gorm.DB, order *models.Order, cancelledBy *uint, reason string) error {
+	now := time.Now().UTC()
+	updates := map[string]interface{}{
+		"status":        models.OrderStatusCancelled,
+		"cancelled_at":  now,
+		"cancelled_by":  cancelledBy,
+		"cancel_reason": reason,
+	}
 
-		if cancelledOrder.PaymentMethod == "lkm" && cancelledOrder.IsPaid && (cancelledOrder.RegularLkmPaid+cancelledOrder.BonusLkmPaid) > 0 {
-			if err := s.walletService.refundTxWithSplit(
-				tx,
-				cancelledOrder.BuyerID,
-				cancelledOrder.RegularLkmPaid,
-				cancelledOrder.BonusLkmPaid,
-				"Возврат за отмену заказа "+cancelledOrder.OrderNumber,
-				nil,
-			); err != nil {
+	// Restore reserved stock if order was confirmed
+	if order.Status == models.OrderStatusConfirmed {
+		for _, item := range order.Items {
+			if item.VariantID == nil {
+				continue
+			}
+			if err := tx.Model(&models.ProductVariant{}).
+				Where("id = ?", *item.VariantID).
+				Update("reserved", gorm.Expr("reserved - ?", item.Quantity)).Error; err != nil {
 				return err
 			}
-			updates["is_paid"] = false
 		}
+	}
 
-		if err := tx.Model(&cancelledOrder).Updates(updates).Error; err != nil {
+	paidLKM := order.RegularLkmPaid + order.BonusLkmPaid
+	switch {
+	case order.EscrowStatus == models.OrderEscrowHeld && paidLKM > 0:
+		if err := s.walletService.refundHoldTx(
+			tx,
+			order.BuyerID,
+			order.RegularLkmPaid,
+			order.BonusLkmPaid,
+			orderHoldRef(order.ID),
+			"Возврат за отмену заказа "+order.OrderNumber,
+		); err != nil {
+			return err
+		}
+		updates["is_paid"] = false
+		updates["escrow_status"] = models.OrderEscrowRefunded
+		updates["escrow_refunded_at"] = now
+		order.EscrowStatus = models.OrderEscrowRefunded
+	case order.PaymentMethod == "lkm" && order.IsPaid && paidLKM > 0:
+		if err := s.walletService.refundTxWithSplit(
+			tx,
+			order.BuyerID,
+			order.RegularLkmPaid,
+			order.BonusLkmPaid,
+			"Возврат за отмену заказа "+order.OrderNumber,
+			nil,
+		); err != nil {
 			return err
 		}
+		updates["is_paid"] = false
+	}
 
-		cancelledOrder.Status = models.OrderStatusCancelled
-		return nil
-	}); err != nil {
+	if err := tx.Model(order).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	order.Status = models.OrderStatusCancelled
+	return nil
+}
+
+// RefundStaleEscrows cancels escrow orders the seller has not shipped within the
+// configured number of days and returns the held LKM to the buyers
+func (s *OrderService) RefundStaleEscrows(now time.Time) ([]models.Order, error) {
+	days := s.escrowAutoRefundDays(database.DB)
+	cutoff := now.AddDate(0, 0, -days)
+
+	var candidateIDs []uint
+	if err := database.DB.Model(&models.Order{}).
+		Where("escrow_status = ? AND status IN ? AND paid_at < ?", models.OrderEscrowHeld, escrowUnshippedStatuses, cutoff).
+		Order("id").Limit(200).
+		Pluck("id", &candidateIDs).Error; err != nil {
 		return nil, err
 	}
 
-	return &cancelledOrder, nil
+	refunded := make([]models.Order, 0, len(candidateIDs))
+	reason := fmt.Sprintf("Автоотмена: заказ не отправлен в течение %d дн.", days)
+	for _, id := range candidateIDs {
+		var order models.Order
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Items").First(&order, id).Error; err != nil {
+				return err
+			}
+			// Re-checked under the lock: the seller may have shipped meanwhile
+			if order.EscrowStatus != models.OrderEscrowHeld || !isEscrowUnshipped(order.Status) {
+				order.ID = 0
+				return nil
+			}
+			return s.cancelLockedOrderTx(tx, &order, nil, reason)
+		})
+		if err != nil {
+			log.Printf("[Orders] Escrow auto-refund failed for order %d: %v", id, err)
+			continue
+		}
+		if order.ID != 0 {
+			refunded = append(refunded, order)
+		}
+	}
+	return refunded, nil
+}
+
+var escrowUnshippedStatuses = []models.OrderStatus{models.OrderStatusNew, models.OrderStatusConfirmed, models.OrderStatusPaid}
+
+func isEscrowUnshipped(status models.OrderStatus) bool {
+	for _, s := range escrowUnshippedStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
 }
 
 // MarkNotificationSent marks that seller was notified
@@ -656,7 +886,7 @@ func (s *OrderService) isValidStatusTransition(from, to models.OrderStatus) bool
 		models.OrderStatusNew:       {models.OrderStatusConfirmed, models.OrderStatusCancelled},
 		models.OrderStatusConfirmed: {models.OrderStatusPaid, models.OrderStatusShipped, models.OrderStatusCancelled},
 		models.OrderStatusPaid:      {models.OrderStatusShipped},
-		models.OrderStatusShipped:   {models.OrderStatusDelivered},
+		models.OrderStatusShipped:   {models.OrderStatusDelivered, models.OrderStatusDispute},
 		models.OrderStatusDelivered: {models.OrderStatusCompleted, models.OrderStatusDispute},
 	}
 
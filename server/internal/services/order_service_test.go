@@ -1,6 +1,9 @@
 package services
 
-import "testing"
+import (
+	"rag-agent-server/internal/models"
+	"testing"
+)
 
 func TestCalculateOrderTotalPages(t *testing.T) {
 	t.Parallel()
@@ -36,3 +39,39 @@ func TestNormalizeMarketPaymentMethod(t *testing.T) {
 		t.Fatalf("card should be invalid")
 	}
 }
+
+func TestMarketEscrowFee(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		total   int
+		percent float64
+		want    int
+	}{
+		{total: 1000, percent: 5, want: 50},
+		{total: 99, percent: 5, want: 5},
+		{total: 10, percent: 0, want: 0},
+		{total: 0, percent: 5, want: 0},
+		{total: 10, percent: 150, want: 10},
+	}
+	for _, tc := range tests {
+		if got := marketEscrowFee(tc.total, tc.percent); got != tc.want {
+			t.Fatalf("marketEscrowFee(%d, %v) = %d, want %d", tc.total, tc.percent, got, tc.want)
+		}
+	}
+}
+
+func TestIsEscrowUnshipped(t *testing.T) {
+	t.Parallel()
+
+	for _, status := range []models.OrderStatus{models.OrderStatusNew, models.OrderStatusConfirmed, models.OrderStatusPaid} {
+		if !isEscrowUnshipped(status) {
+			t.Fatalf("%s must be eligible for the auto-refund", status)
+		}
+	}
+	for _, status := range []models.OrderStatus{models.OrderStatusShipped, models.OrderStatusDelivered, models.OrderStatusCancelled} {
+		if isEscrowUnshipped(status) {
+			t.Fatalf("%s must not be auto-refunded", status)
+		}
+	}
+}
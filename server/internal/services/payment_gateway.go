@@ -0,0 +1,258 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrPaymentGatewayNotConfigured = errors.New("payment gateway is not configured")
+	ErrPaymentGatewayUnavailable   = errors.New("payment gateway is unavailable")
+	ErrPaymentWebhookSignature     = errors.New("invalid webhook signature")
+	ErrPaymentWebhookMismatch      = errors.New("webhook does not match the payment")
+	ErrPaymentWebhookIgnored       = errors.New("webhook event is not relevant for top-ups")
+)
+
+// Gateway-independent payment statuses. Adapters map provider statuses onto these, so
+// HandleWebhook and reconciliation never see provider-specific values.
+const (
+	GatewayStatusPending   = "pending"
+	GatewayStatusPaid      = "paid"
+	GatewayStatusFailed    = "failed"
+	GatewayStatusCancelled = "cancelled"
+	GatewayStatusExpired   = "expired"
+	GatewayStatusRefunded  = "refunded"
+)
+
+// PaymentGateway is a payment provider adapter for LKM top-ups
+type PaymentGateway interface {
+	// Code matches LKMPaymentGateway.Code
+	Code() string
+	// CreatePayment registers the payment with the provider and returns where to send the payer
+	CreatePayment(ctx context.Context, req GatewayPaymentRequest) (*GatewayPayment, error)
+	// VerifyWebhook authenticates a provider notification and maps it to a gateway-independent event.
+	// It returns ErrPaymentWebhookSignature for unsigned or forged notifications.
+	VerifyWebhook(ctx context.Context, webhook GatewayWebhook) (*GatewayWebhookEvent, error)
+	// MapStatus maps a provider payment status to one of the GatewayStatus* values
+	MapStatus(providerStatus string) string
+	// FetchStatus reads the current payment state from the provider, for reconciliation
+	FetchStatus(ctx context.Context, externalPaymentID string) (*GatewayPaymentStatus, error)
+}
+
+type GatewayPaymentRequest struct {
+	TopupID     string
+	UserID      uint
+	Amount      float64
+	Currency    string
+	Description string
+	ReturnURL   string
+}
+
+type GatewayPayment struct {
+	ExternalPaymentID string
+	RedirectURL       string
+	Status            string
+}
+
+// GatewayWebhook is the raw notification as received over HTTP
+type GatewayWebhook struct {
+	Body   []byte
+	Header func(key string) string
+}
+
+func (w GatewayWebhook) header(key string) string {
+	if w.Header == nil {
+		return ""
+	}
+	return strings.TrimSpace(w.Header(key))
+}
+
+type GatewayWebhookEvent struct {
+	EventID           string
+	TopupID           string
+	ExternalPaymentID string
+	Status            string
+	ProviderStatus    string
+	Amount            float64
+	Currency          string
+}
+
+type GatewayPaymentStatus struct {
+	ExternalPaymentID string
+	TopupID           string
+	Status            string
+	ProviderStatus    string
+	Amount            float64
+	Currency          string
+}
+
+// PaymentGatewayRegistry holds the adapters of the configured providers
+type PaymentGatewayRegistry struct {
+	gateways map[string]PaymentGateway
+}
+
+func NewPaymentGatewayRegistry(gateways ...PaymentGateway) *PaymentGatewayRegistry {
+	registry := &PaymentGatewayRegistry{gateways: make(map[string]PaymentGateway, len(gateways))}
+	for _, gateway := range gateways {
+		registry.gateways[strings.ToLower(gateway.Code())] = gateway
+	}
+	return registry
+}
+
+// NewPaymentGatewayRegistryFromEnv registers every provider whose credentials are set
+func NewPaymentGatewayRegistryFromEnv() *PaymentGatewayRegistry {
+	var gateways []PaymentGateway
+	if shopID, secretKey := os.Getenv("YOOKASSA_SHOP_ID"), os.Getenv("YOOKASSA_SECRET_KEY"); shopID != "" && secretKey != "" {
+		gateways = append(gateways, NewYooKassaGateway(shopID, secretKey))
+	}
+	if secretKey := os.Getenv("STRIPE_SECRET_KEY"); secretKey != "" {
+		gateways = append(gateways, NewStripeGateway(secretKey, os.Getenv("STRIPE_WEBHOOK_SECRET")))
+	}
+	if secret := os.Getenv("LKM_MOCK_GATEWAY_SECRET"); secret != "" {
+		gateways = append(gateways, NewMockPaymentGateway(secret))
+	}
+	return NewPaymentGatewayRegistry(gateways...)
+}
+
+func (r *PaymentGatewayRegistry) Get(code string) (PaymentGateway, bool) {
+	if r == nil {
+		return nil, false
+	}
+	gateway, ok := r.gateways[strings.ToLower(strings.TrimSpace(code))]
+	return gateway, ok
+}
+
+func hmacSHA256Hex(secret, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func equalSignatures(received, expected string) bool {
+	received = strings.ToLower(strings.TrimSpace(received))
+	if received == "" || len(received) != len(expected) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(received), []byte(expected)) == 1
+}
+
+// ==================== MOCK GATEWAY ====================
+
+const mockGatewaySignatureHeader = "X-Mock-Signature"
+
+// MockPaymentGateway is an in-memory provider for tests and local development.
+// Webhooks are signed with a hex HMAC-SHA256 of the body in X-Mock-Signature.
+type MockPaymentGateway struct {
+	secret string
+
+	mu       sync.Mutex
+	payments map[string]GatewayPaymentStatus
+}
+
+type mockGatewayWebhookPayload struct {
+	EventID   string  `json:"eventId"`
+	PaymentID string  `json:"paymentId"`
+	TopupID   string  `json:"topupId"`
+	Status    string  `json:"status"`
+	Amount    float64 `json:"amount"`
+	Currency  string  `json:"currency"`
+}
+
+func NewMockPaymentGateway(secret string) *MockPaymentGateway {
+	return &MockPaymentGateway{secret: secret, payments: map[string]GatewayPaymentStatus{}}
+}
+
+func (g *MockPaymentGateway) Code() string { return "mock" }
+
+func (g *MockPaymentGateway) CreatePayment(_ context.Context, req GatewayPaymentRequest) (*GatewayPayment, error) {
+	paymentID := "mock_" + uuid.NewString()
+	g.mu.Lock()
+	g.payments[paymentID] = GatewayPaymentStatus{
+		ExternalPaymentID: paymentID,
+		TopupID:           req.TopupID,
+		Status:            GatewayStatusPending,
+		ProviderStatus:    GatewayStatusPending,
+		Amount:            req.Amount,
+		Currency:          strings.ToUpper(req.Currency),
+	}
+	g.mu.Unlock()
+	return &GatewayPayment{
+		ExternalPaymentID: paymentID,
+		RedirectURL:       "https://mock-gateway.local/pay/" + paymentID,
+		Status:            GatewayStatusPending,
+	}, nil
+}
+
+// SetStatus changes a payment as if the payer acted on the provider side
+func (g *MockPaymentGateway) SetStatus(paymentID, status string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if payment, ok := g.payments[paymentID]; ok {
+		payment.ProviderStatus = status
+		payment.Status = g.MapStatus(status)
+		g.payments[paymentID] = payment
+	}
+}
+
+// Sign returns the signature header value for a webhook body
+func (g *MockPaymentGateway) Sign(body []byte) string {
+	return hmacSHA256Hex([]byte(g.secret), body)
+}
+
+func (g *MockPaymentGateway) VerifyWebhook(_ context.Context, webhook GatewayWebhook) (*GatewayWebhookEvent, error) {
+	if g.secret == "" || !equalSignatures(webhook.header(mockGatewaySignatureHeader), g.Sign(webhook.Body)) {
+		return nil, ErrPaymentWebhookSignature
+	}
+
+	var payload mockGatewayWebhookPayload
+	if err := json.Unmarshal(webhook.Body, &payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPaymentWebhookMismatch, err)
+	}
+	return &GatewayWebhookEvent{
+		EventID:           payload.EventID,
+		TopupID:           payload.TopupID,
+		ExternalPaymentID: payload.PaymentID,
+		Status:            g.MapStatus(payload.Status),
+		ProviderStatus:    payload.Status,
+		Amount:            payload.Amount,
+		Currency:          strings.ToUpper(payload.Currency),
+	}, nil
+}
+
+func (g *MockPaymentGateway) MapStatus(providerStatus string) string {
+	switch strings.ToLower(strings.TrimSpace(providerStatus)) {
+	case "paid", "succeeded":
+		return GatewayStatusPaid
+	case "failed":
+		return GatewayStatusFailed
+	case "cancelled", "canceled":
+		return GatewayStatusCancelled
+	case "expired":
+		return GatewayStatusExpired
+	case "refunded":
+		return GatewayStatusRefunded
+	default:
+		return GatewayStatusPending
+	}
+}
+
+func (g *MockPaymentGateway) FetchStatus(_ context.Context, externalPaymentID string) (*GatewayPaymentStatus, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	payment, ok := g.payments[externalPaymentID]
+	if !ok {
+		return nil, fmt.Errorf("mock payment %s not found", externalPaymentID)
+	}
+	return &payment, nil
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	stripeAPIBaseURL             = "https://api.stripe.com/v1"
+	stripeSignatureHeader        = "Stripe-Signature"
+	stripeWebhookTimestampWindow = 5 * time.Minute
+)
+
+// StripeGateway is the adapter for international card payments through Checkout Sessions.
+// Webhooks are verified against the Stripe-Signature header and the endpoint secret.
+type StripeGateway struct {
+	secretKey     string
+	webhookSecret string
+	baseURL       string
+	httpClient    *http.Client
+	now           func() time.Time
+}
+
+type stripeCheckoutSession struct {
+	ID                string            `json:"id"`
+	URL               string            `json:"url"`
+	Status            string            `json:"status"`
+	PaymentStatus     string            `json:"payment_status"`
+	AmountTotal       int64             `json:"amount_total"`
+	Currency          string            `json:"currency"`
+	ClientReferenceID string            `json:"client_reference_id"`
+	Metadata          map[string]string `json:"metadata"`
+}
+
+type stripeEvent struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		Object stripeCheckoutSession `json:"object"`
+	} `json:"data"`
+}
+
+func NewStripeGateway(secretKey, webhookSecret string) *StripeGateway {
+	return &StripeGateway{
+		secretKey:     secretKey,
+		webhookSecret: webhookSecret,
+		baseURL:       stripeAPIBaseURL,
+		httpClient:    &http.Client{Timeout: 15 * time.Second},
+		now:           time.Now,
+	}
+}
+
+func (g *StripeGateway) Code() string { return "stripe" }
+
+func (g *StripeGateway) CreatePayment(ctx context.Context, req GatewayPaymentRequest) (*GatewayPayment, error) {
+	form := url.Values{}
+	form.Set("mode", "payment")
+	form.Set("success_url", req.ReturnURL)
+	form.Set("cancel_url", req.ReturnURL)
+	form.Set("client_reference_id", req.TopupID)
+	form.Set("metadata[topupId]", req.TopupID)
+	form.Set("payment_intent_data[metadata][topupId]", req.TopupID)
+	form.Set("line_items[0][quantity]", "1")
+	form.Set("line_items[0][price_data][currency]", strings.ToLower(req.Currency))
+	form.Set("line_items[0][price_data][unit_amount]", strconv.FormatInt(stripeMinorUnits(req.Amount), 10))
+	form.Set("line_items[0][price_data][product_data][name]", req.Description)
+
+	var session stripeCheckoutSession
+	if err := g.do(ctx, http.MethodPost, "/checkout/sessions", req.TopupID, form, &session); err != nil {
+		return nil, err
+	}
+	return &GatewayPayment{
+		ExternalPaymentID: session.ID,
+		RedirectURL:       session.URL,
+		Status:            g.sessionStatus(session),
+	}, nil
+}
+
+// stripeMinorUnits converts to cents; top-ups are only quoted in two-decimal currencies
+func stripeMinorUnits(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+func (g *StripeGateway) VerifyWebhook(_ context.Context, webhook GatewayWebhook) (*GatewayWebhookEvent, error) {
+	if err := verifyStripeSignature(webhook.header(stripeSignatureHeader), webhook.Body, g.webhookSecret, g.now()); err != nil {
+		return nil, err
+	}
+
+	var event stripeEvent
+	if err := json.Unmarshal(webhook.Body, &event); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPaymentWebhookMismatch, err)
+	}
+
+	session := event.Data.Object
+	var status string
+	switch event.Type {
+	case "checkout.session.completed", "checkout.session.async_payment_succeeded":
+		status = g.MapStatus(session.PaymentStatus)
+	case "checkout.session.async_payment_failed":
+		status = GatewayStatusFailed
+	case "checkout.session.expired":
+		status = GatewayStatusExpired
+	default:
+		return nil, ErrPaymentWebhookIgnored
+	}
+
+	topupID := session.Metadata["topupId"]
+	if topupID == "" {
+		topupID = session.ClientReferenceID
+	}
+	return &GatewayWebhookEvent{
+		EventID:           event.ID,
+		TopupID:           topupID,
+		ExternalPaymentID: session.ID,
+		Status:            status,
+		ProviderStatus:    session.PaymentStatus,
+		Amount:            float64(session.AmountTotal) / 100,
+		Currency:          strings.ToUpper(session.Currency),
+	}, nil
+}
+
+// verifyStripeSignature checks a "t=<unix>,v1=<hex>" header: v1 is the HMAC-SHA256 of
+// "<t>.<body>" with the endpoint secret. Old timestamps are refused to stop replays.
+func verifyStripeSignature(header string, body []byte, secret string, now time.Time) error {
+	if secret == "" || header == "" {
+		return ErrPaymentWebhookSignature
+	}
+
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrPaymentWebhookSignature
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > stripeWebhookTimestampWindow || age < -stripeWebhookTimestampWindow {
+		return fmt.Errorf("%w: timestamp outside of tolerance", ErrPaymentWebhookSignature)
+	}
+
+	expected := hmacSHA256Hex([]byte(secret), append([]byte(timestamp+"."), body...))
+	for _, signature := range signatures {
+		if equalSignatures(signature, expected) {
+			return nil
+		}
+	}
+	return ErrPaymentWebhookSignature
+}
+
+func (g *StripeGateway) MapStatus(providerStatus string) string {
+	switch strings.ToLower(strings.TrimSpace(providerStatus)) {
+	case "paid", "no_payment_required":
+		return GatewayStatusPaid
+	case "expired":
+		return GatewayStatusExpired
+	default:
+		// unpaid checkout or async payment still in flight
+		return GatewayStatusPending
+	}
+}
+
+func (g *StripeGateway) sessionStatus(session stripeCheckoutSession) string {
+	if session.Status == "expired" {
+		return GatewayStatusExpired
+	}
+	return g.MapStatus(session.PaymentStatus)
+}
+
+func (g *StripeGateway) FetchStatus(ctx context.Context, externalPaymentID string) (*GatewayPaymentStatus, error) {
+	var session stripeCheckoutSession
+	if err := g.do(ctx, http.MethodGet, "/checkout/sessions/"+url.PathEscape(externalPaymentID), "", nil, &session); err != nil {
+		return nil, err
+	}
+	topupID := session.Metadata["topupId"]
+	if topupID == "" {
+		topupID = session.ClientReferenceID
+	}
+	return &GatewayPaymentStatus{
+		ExternalPaymentID: session.ID,
+		TopupID:           topupID,
+		Status:            g.sessionStatus(session),
+		ProviderStatus:    session.PaymentStatus,
+		Amount:            float64(session.AmountTotal) / 100,
+		Currency:          strings.ToUpper(session.Currency),
+	}, nil
+}
+
+func (g *StripeGateway) do(ctx context.Context, method, path, idempotencyKey string, form url.Values, out interface{}) error {
+	var reader io.Reader
+	if form != nil {
+		reader = strings.NewReader(form.Encode())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, g.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(g.secretKey, "")
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPaymentGatewayUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPaymentGatewayUnavailable, err)
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%w: stripe returned %d: %s", ErrPaymentGatewayUnavailable, resp.StatusCode, truncateText(string(respBody), 300))
+	}
+	return json.Unmarshal(respBody, out)
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"rag-agent-server/internal/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func headerGetter(values map[string]string) func(string) string {
+	return func(key string) string { return values[key] }
+}
+
+func TestVerifyStripeSignature(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	body := []byte(`{"id":"evt_1"}`)
+	sign := func(ts time.Time, secret string) string {
+		timestamp := fmt.Sprintf("%d", ts.Unix())
+		return fmt.Sprintf("t=%s,v1=%s", timestamp, hmacSHA256Hex([]byte(secret), append([]byte(timestamp+"."), body...)))
+	}
+
+	tests := []struct {
+		name    string
+		header  string
+		secret  string
+		wantErr bool
+	}{
+		{name: "valid", header: sign(now, "whsec"), secret: "whsec"},
+		{name: "valid among rotated secrets", header: sign(now, "whsec") + ",v1=deadbeef", secret: "whsec"},
+		{name: "unsigned", header: "", secret: "whsec", wantErr: true},
+		{name: "wrong secret", header: sign(now, "other"), secret: "whsec", wantErr: true},
+		{name: "replayed", header: sign(now.Add(-10*time.Minute), "whsec"), secret: "whsec", wantErr: true},
+		{name: "endpoint secret not configured", header: sign(now, ""), secret: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := verifyStripeSignature(tt.header, body, tt.secret, now)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("verifyStripeSignature() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrPaymentWebhookSignature) {
+				t.Fatalf("error %v is not ErrPaymentWebhookSignature", err)
+			}
+		})
+	}
+}
+
+func TestStripeGatewayVerifyWebhookMapsEvents(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	gateway := NewStripeGateway("sk_test", "whsec")
+	gateway.now = func() time.Time { return now }
+
+	signed := func(body string) GatewayWebhook {
+		timestamp := fmt.Sprintf("%d", now.Unix())
+		signature := hmacSHA256Hex([]byte("whsec"), []byte(timestamp+"."+body))
+		return GatewayWebhook{
+			Body:   []byte(body),
+			Header: headerGetter(map[string]string{"Stripe-Signature": "t=" + timestamp + ",v1=" + signature}),
+		}
+	}
+
+	event, err := gateway.VerifyWebhook(context.Background(), signed(`{"id":"evt_1","type":"checkout.session.completed","data":{"object":{"id":"cs_1","payment_status":"paid","amount_total":1999,"currency":"usd","metadata":{"topupId":"t-1"}}}}`))
+	if err != nil {
+		t.Fatalf("VerifyWebhook() error = %v", err)
+	}
+	if event.Status != GatewayStatusPaid || event.TopupID != "t-1" || event.Amount != 19.99 || event.Currency != "USD" {
+		t.Fatalf("unexpected event %+v", event)
+	}
+
+	if _, err := gateway.VerifyWebhook(context.Background(), signed(`{"id":"evt_2","type":"customer.created","data":{"object":{}}}`)); !errors.Is(err, ErrPaymentWebhookIgnored) {
+		t.Fatalf("unrelated event error = %v, want ErrPaymentWebhookIgnored", err)
+	}
+}
+
+func TestMockGatewayRejectsUnsignedWebhook(t *testing.T) {
+	t.Parallel()
+
+	gateway := NewMockPaymentGateway("secret")
+	body := []byte(`{"eventId":"e1","paymentId":"p1","topupId":"t1","status":"paid","amount":199,"currency":"RUB"}`)
+
+	if _, err := gateway.VerifyWebhook(context.Background(), GatewayWebhook{Body: body}); !errors.Is(err, ErrPaymentWebhookSignature) {
+		t.Fatalf("unsigned webhook error = %v, want ErrPaymentWebhookSignature", err)
+	}
+
+	tampered := GatewayWebhook{
+		Body:   []byte(strings.Replace(string(body), "199", "1", 1)),
+		Header: headerGetter(map[string]string{mockGatewaySignatureHeader: gateway.Sign(body)}),
+	}
+	if _, err := gateway.VerifyWebhook(context.Background(), tampered); !errors.Is(err, ErrPaymentWebhookSignature) {
+		t.Fatalf("tampered webhook error = %v, want ErrPaymentWebhookSignature", err)
+	}
+
+	event, err := gateway.VerifyWebhook(context.Background(), GatewayWebhook{
+		Body:   body,
+		Header: headerGetter(map[string]string{mockGatewaySignatureHeader: gateway.Sign(body)}),
+	})
+	if err != nil {
+		t.Fatalf("signed webhook error = %v", err)
+	}
+	if event.Status != GatewayStatusPaid || event.TopupID != "t1" {
+		t.Fatalf("unexpected event %+v", event)
+	}
+}
+
+func TestYooKassaGatewayConfirmsNotificationWithAPI(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "shop" || pass != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/payments/pay-paid":
+			fmt.Fprint(w, `{"id":"pay-paid","status":"succeeded","amount":{"value":"199.00","currency":"RUB"},"metadata":{"topupId":"t-1"}}`)
+		case "/payments/pay-pending":
+			fmt.Fprint(w, `{"id":"pay-pending","status":"pending","amount":{"value":"199.00","currency":"RUB"},"metadata":{"topupId":"t-2"}}`)
+		case "/refunds/ref-done":
+			fmt.Fprint(w, `{"id":"ref-done","status":"succeeded","payment_id":"pay-paid","amount":{"value":"199.00","currency":"RUB"}}`)
+		case "/refunds/ref-pending":
+			fmt.Fprint(w, `{"id":"ref-pending","status":"pending","payment_id":"pay-paid","amount":{"value":"199.00","currency":"RUB"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	gateway := NewYooKassaGateway("shop", "key")
+	gateway.baseURL = server.URL
+
+	notification := func(paymentID, status string) GatewayWebhook {
+		return GatewayWebhook{Body: []byte(fmt.Sprintf(`{"type":"notification","event":"payment.%s","object":{"id":"%s","status":"%s"}}`, status, paymentID, status))}
+	}
+
+	event, err := gateway.VerifyWebhook(context.Background(), notification("pay-paid", "succeeded"))
+	if err != nil {
+		t.Fatalf("VerifyWebhook() error = %v", err)
+	}
+	if event.Status != GatewayStatusPaid || event.TopupID != "t-1" || event.Amount != 199 {
+		t.Fatalf("unexpected event %+v", event)
+	}
+
+	if _, err := gateway.VerifyWebhook(context.Background(), notification("pay-pending", "succeeded")); !errors.Is(err, ErrPaymentWebhookMismatch) {
+		t.Fatalf("forged success error = %v, want ErrPaymentWebhookMismatch", err)
+	}
+	if _, err := gateway.VerifyWebhook(context.Background(), GatewayWebhook{Body: []byte(`{"status":"paid"}`)}); !errors.Is(err, ErrPaymentWebhookSignature) {
+		t.Fatalf("malformed notification error = %v, want ErrPaymentWebhookSignature", err)
+	}
+
+	refund := func(refundID, paymentID string) GatewayWebhook {
+		return GatewayWebhook{Body: []byte(fmt.Sprintf(`{"type":"notification","event":"refund.succeeded","object":{"id":"%s","status":"succeeded","payment_id":"%s"}}`, refundID, paymentID))}
+	}
+	event, err = gateway.VerifyWebhook(context.Background(), refund("ref-done", "pay-paid"))
+	if err != nil {
+		t.Fatalf("VerifyWebhook(refund) error = %v", err)
+	}
+	if event.Status != GatewayStatusRefunded || event.ExternalPaymentID != "pay-paid" || event.TopupID != "t-1" {
+		t.Fatalf("unexpected refund event %+v", event)
+	}
+	if _, err := gateway.VerifyWebhook(context.Background(), refund("ref-pending", "pay-paid")); !errors.Is(err, ErrPaymentWebhookMismatch) {
+		t.Fatalf("unsettled refund error = %v, want ErrPaymentWebhookMismatch", err)
+	}
+	if _, err := gateway.VerifyWebhook(context.Background(), refund("ref-done", "pay-pending")); !errors.Is(err, ErrPaymentWebhookMismatch) {
+		t.Fatalf("refund for another payment error = %v, want ErrPaymentWebhookMismatch", err)
+	}
+	if _, err := gateway.VerifyWebhook(context.Background(), refund("ref-forged", "pay-paid")); !errors.Is(err, ErrPaymentGatewayUnavailable) {
+		t.Fatalf("unknown refund error = %v, want ErrPaymentGatewayUnavailable", err)
+	}
+}
+
+func TestCheckWebhookMatchesTopup(t *testing.T) {
+	t.Parallel()
+
+	topup := models.LKMTopup{TopupID: "t-1", ExternalPaymentID: "pay-1", TotalPayAmount: 199, PayCurrency: "RUB"}
+
+	tests := []struct {
+		name    string
+		event   GatewayWebhookEvent
+		wantErr bool
+	}{
+		{name: "matching payment", event: GatewayWebhookEvent{ExternalPaymentID: "pay-1", Status: GatewayStatusPaid, Amount: 199, Currency: "RUB"}},
+		{name: "underpaid", event: GatewayWebhookEvent{ExternalPaymentID: "pay-1", Status: GatewayStatusPaid, Amount: 19.9, Currency: "RUB"}, wantErr: true},
+		{name: "other currency", event: GatewayWebhookEvent{ExternalPaymentID: "pay-1", Status: GatewayStatusPaid, Amount: 199, Currency: "USD"}, wantErr: true},
+		{name: "other payment", event: GatewayWebhookEvent{ExternalPaymentID: "pay-2", Status: GatewayStatusPending}, wantErr: true},
+		{name: "amount not checked before payment", event: GatewayWebhookEvent{ExternalPaymentID: "pay-1", Status: GatewayStatusCancelled}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := checkWebhookMatchesTopup(&tt.event, &topup)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("checkWebhookMatchesTopup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const yooKassaAPIBaseURL = "https://api.yookassa.ru/v3"
+
+// YooKassaGateway is the adapter for RU/CIS card and SBP payments.
+//
+// YooKassa does not sign HTTP notifications. A notification is therefore never trusted
+// on its own: the payment or refund it names is re-read from the API with the shop
+// credentials and the notification is rejected unless status and top-up match.
+type YooKassaGateway struct {
+	shopID     string
+	secretKey  string
+	baseURL    string
+	httpClient *http.Client
+}
+
+type yooKassaAmount struct {
+	Value    string `json:"value"`
+	Currency string `json:"currency"`
+}
+
+type yooKassaPayment struct {
+	ID           string            `json:"id"`
+	Status       string            `json:"status"`
+	Amount       yooKassaAmount    `json:"amount"`
+	PaymentID    string            `json:"payment_id"` // Set on refund objects
+	Metadata     map[string]string `json:"metadata"`
+	Confirmation struct {
+		Type            string `json:"type"`
+		ConfirmationURL string `json:"confirmation_url"`
+	} `json:"confirmation"`
+}
+
+type yooKassaNotification struct {
+	Type   string          `json:"type"`
+	Event  string          `json:"event"`
+	Object yooKassaPayment `json:"object"`
+}
+
+func NewYooKassaGateway(shopID, secretKey string) *YooKassaGateway {
+	return &YooKassaGateway{
+		shopID:     shopID,
+		secretKey:  secretKey,
+		baseURL:    yooKassaAPIBaseURL,
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (g *YooKassaGateway) Code() string { return "yookassa" }
+
+func (g *YooKassaGateway) CreatePayment(ctx context.Context, req GatewayPaymentRequest) (*GatewayPayment, error) {
+	body := map[string]interface{}{
+		"amount": yooKassaAmount{
+			Value:    strconv.FormatFloat(round2(req.Amount), 'f', 2, 64),
+			Currency: strings.ToUpper(req.Currency),
+		},
+		"capture": true,
+		"confirmation": map[string]string{
+			"type":       "redirect",
+			"return_url": req.ReturnURL,
+		},
+		"description": truncateText(req.Description, 125), // YooKassa limit is 128 characters
+		"metadata": map[string]string{
+			"topupId": req.TopupID,
+			"userId":  strconv.FormatUint(uint64(req.UserID), 10),
+		},
+	}
+
+	var payment yooKassaPayment
+	// The top-up ID doubles as the idempotence key, so a retried request never creates a second payment
+	if err := g.do(ctx, http.MethodPost, "/payments", req.TopupID, body, &payment); err != nil {
+		return nil, err
+	}
+	return &GatewayPayment{
+		ExternalPaymentID: payment.ID,
+		RedirectURL:       payment.Confirmation.ConfirmationURL,
+		Status:            g.MapStatus(payment.Status),
+	}, nil
+}
+
+func (g *YooKassaGateway) VerifyWebhook(ctx context.Context, webhook GatewayWebhook) (*GatewayWebhookEvent, error) {
+	var notification yooKassaNotification
+	if err := json.Unmarshal(webhook.Body, &notification); err != nil || notification.Type != "notification" || notification.Object.ID == "" {
+		return nil, ErrPaymentWebhookSignature
+	}
+
+	isRefund := strings.HasPrefix(notification.Event, "refund.")
+	paymentID := notification.Object.ID
+	if isRefund {
+		refund, err := g.fetchRefund(ctx, notification.Object.ID)
+		if err != nil {
+			return nil, err
+		}
+		if refund.Status != "succeeded" {
+			return nil, fmt.Errorf("%w: notification says refunded, refund is %s", ErrPaymentWebhookMismatch, refund.Status)
+		}
+		if claimedPayment := notification.Object.PaymentID; claimedPayment != "" && claimedPayment != refund.PaymentID {
+			return nil, fmt.Errorf("%w: refund belongs to payment %s", ErrPaymentWebhookMismatch, refund.PaymentID)
+		}
+		paymentID = refund.PaymentID
+	}
+	if paymentID == "" {
+		return nil, ErrPaymentWebhookSignature
+	}
+
+	current, err := g.FetchStatus(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	claimed := g.MapStatus(notification.Object.Status)
+	if isRefund {
+		claimed = GatewayStatusRefunded
+	} else if claimed != current.Status {
+		return nil, fmt.Errorf("%w: notification says %s, payment is %s", ErrPaymentWebhookMismatch, notification.Object.Status, current.ProviderStatus)
+	}
+	if topupID := notification.Object.Metadata["topupId"]; topupID != "" && topupID != current.TopupID {
+		return nil, fmt.Errorf("%w: top-up %s does not match the payment", ErrPaymentWebhookMismatch, topupID)
+	}
+
+	return &GatewayWebhookEvent{
+		EventID:           notification.Object.ID + ":" + notification.Event,
+		TopupID:           current.TopupID,
+		ExternalPaymentID: paymentID,
+		Status:            claimed,
+		ProviderStatus:    notification.Object.Status,
+		Amount:            current.Amount,
+		Currency:          current.Currency,
+	}, nil
+}
+
+func (g *YooKassaGateway) MapStatus(providerStatus string) string {
+	switch strings.ToLower(strings.TrimSpace(providerStatus)) {
+	case "succeeded":
+		return GatewayStatusPaid
+	case "canceled":
+		return GatewayStatusCancelled
+	default:
+		// pending and waiting_for_capture
+		return GatewayStatusPending
+	}
+}
+
+func (g *YooKassaGateway) FetchStatus(ctx context.Context, externalPaymentID string) (*GatewayPaymentStatus, error) {
+	var payment yooKassaPayment
+	if err := g.do(ctx, http.MethodGet, "/payments/"+url.PathEscape(externalPaymentID), "", nil, &payment); err != nil {
+		return nil, err
+	}
+	amount, _ := strconv.ParseFloat(payment.Amount.Value, 64)
+	return &GatewayPaymentStatus{
+		ExternalPaymentID: payment.ID,
+		TopupID:           payment.Metadata["topupId"],
+		Status:            g.MapStatus(payment.Status),
+		ProviderStatus:    payment.Status,
+		Amount:            amount,
+		Currency:          strings.ToUpper(payment.Amount.Currency),
+	}, nil
+}
+
+func (g *YooKassaGateway) fetchRefund(ctx context.Context, refundID string) (*yooKassaPayment, error) {
+	var refund yooKassaPayment
+	if err := g.do(ctx, http.MethodGet, "/refunds/"+url.PathEscape(refundID), "", nil, &refund); err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+func (g *YooKassaGateway) do(ctx context.Context, method, path, idempotenceKey string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, g.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(g.shopID, g.secretKey)
+	req.Header.Set("Content-Type", "application/json")
+	if idempotenceKey != "" {
+		req.Header.Set("Idempotence-Key", idempotenceKey)
+	}
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPaymentGatewayUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPaymentGatewayUnavailable, err)
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%w: yookassa returned %d: %s", ErrPaymentGatewayUnavailable, resp.StatusCode, truncateText(string(respBody), 300))
+	}
+	return json.Unmarshal(respBody, out)
+}
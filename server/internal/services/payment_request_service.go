@@ -0,0 +1,334 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"rag-agent-server/internal/database"
+	"rag-agent-server/internal/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrPaymentRequestNotFound  = errors.New("payment request not found")
+	ErrPaymentRequestInvalid   = errors.New("invalid payment request")
+	ErrPaymentRequestClosed    = errors.New("payment request is no longer payable")
+	ErrPaymentRequestForbidden = errors.New("payment request is addressed to another user")
+	ErrPaymentRequestOwn       = errors.New("cannot pay your own payment request")
+	ErrCafeOrderAlreadyPaid    = errors.New("order is already paid")
+)
+
+const (
+	defaultPaymentRequestTTL = 24 * time.Hour
+	maxPaymentRequestTTL     = 30 * 24 * time.Hour
+	cafePaymentRequestTTL    = 30 * time.Minute
+)
+
+// PaymentRequestService lets a payee invoice LKM through a shareable link or QR code
+type PaymentRequestService struct {
+	db            *gorm.DB
+	walletService *WalletService
+}
+
+func NewPaymentRequestService(walletService *WalletService) *PaymentRequestService {
+	return &PaymentRequestService{db: database.DB, walletService: walletService}
+}
+
+// Create issues a payment request owned by the payee
+func (s *PaymentRequestService) Create(payeeID uint, req models.PaymentRequestCreateRequest) (*models.PaymentRequest, error) {
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("%w: amount must be positive", ErrPaymentRequestInvalid)
+	}
+	if req.PayerID != nil && *req.PayerID == payeeID {
+		return nil, ErrPaymentRequestOwn
+	}
+	return s.create(s.db, payeeID, req.Amount, strings.TrimSpace(req.Description), paymentRequestTTL(req.ExpiresInMinutes), req.PayerID, nil)
+}
+
+// CreateForCafeOrder issues a QR invoice for an unpaid cafe order, payable to the cafe
+// owner. A still valid invoice for the same order is returned instead of a new one.
+func (s *PaymentRequestService) CreateForCafeOrder(orderID uint) (*models.PaymentRequest, error) {
+	var order models.CafeOrder
+	if err := s.db.Preload("Cafe").First(&order, orderID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPaymentRequestNotFound
+		}
+		return nil, err
+	}
+	if order.IsPaid {
+		return nil, ErrCafeOrderAlreadyPaid
+	}
+	if order.Status == models.CafeOrderStatusCancelled || order.Cafe == nil {
+		return nil, fmt.Errorf("%w: order cannot be paid", ErrPaymentRequestInvalid)
+	}
+	amount := moneyToLKM(order.Total)
+	if amount <= 0 {
+		return nil, fmt.Errorf("%w: order total is zero", ErrPaymentRequestInvalid)
+	}
+
+	var existing models.PaymentRequest
+	err := s.db.Where("cafe_order_id = ? AND status = ? AND expires_at > ? AND amount = ?",
+		orderID, models.PaymentRequestPending, time.Now().UTC(), amount).
+		Order("id DESC").First(&existing).Error
+	if err == nil {
+		return &existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	description := fmt.Sprintf("%s, заказ %s", order.Cafe.Name, order.OrderNumber)
+	return s.create(s.db, order.Cafe.OwnerID, amount, description, cafePaymentRequestTTL, nil, &order.ID)
+}
+
+func (s *PaymentRequestService) create(db *gorm.DB, payeeID uint, amount int, description string, ttl time.Duration, payerID, cafeOrderID *uint) (*models.PaymentRequest, error) {
+	token, err := generatePaymentRequestToken()
+	if err != nil {
+		return nil, err
+	}
+	request := models.PaymentRequest{
+		Token:       token,
+		PayeeID:     payeeID,
+		PayerID:     payerID,
+		Amount:      amount,
+		Description: description,
+		Status:      models.PaymentRequestPending,
+		ExpiresAt:   time.Now().UTC().Add(ttl),
+		CafeOrderID: cafeOrderID,
+	}
+	if err := db.Create(&request).Error; err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func paymentRequestTTL(minutes int) time.Duration {
+	if minutes <= 0 {
+		return defaultPaymentRequestTTL
+	}
+	ttl := time.Duration(minutes) * time.Minute
+	if ttl > maxPaymentRequestTTL {
+		return maxPaymentRequestTTL
+	}
+	return ttl
+}
+
+func generatePaymentRequestToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// GetByToken returns a request for the payer's confirmation screen
+func (s *PaymentRequestService) GetByToken(token string) (*models.PaymentRequest, error) {
+	var request models.PaymentRequest
+	if err := s.db.Preload("Payee", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "spiritual_name", "karmic_name", "avatar_url")
+	}).Where("token = ?", strings.TrimSpace(token)).First(&request).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPaymentRequestNotFound
+		}
+		return nil, err
+	}
+	request.Status = effectivePaymentRequestStatus(&request, time.Now().UTC())
+	return &request, nil
+}
+
+// effectivePaymentRequestStatus reports expiry without waiting for a status write
+func effectivePaymentRequestStatus(request *models.PaymentRequest, now time.Time) models.PaymentRequestStatus {
+	if request.Status == models.PaymentRequestPending && !request.ExpiresAt.After(now) {
+		return models.PaymentRequestExpired
+	}
+	return request.Status
+}
+
+// ListMine returns the payment requests the user issued, newest first
+func (s *PaymentRequestService) ListMine(payeeID uint, limit int) ([]models.PaymentRequest, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	requests := []models.PaymentRequest{}
+	if err := s.db.Where("payee_id = ?", payeeID).Order("id DESC").Limit(limit).Find(&requests).Error; err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	for i := range requests {
+		requests[i].Status = effectivePaymentRequestStatus(&requests[i], now)
+	}
+	return requests, nil
+}
+
+// Pay confirms a request: the payer's LKM goes to the payee and a linked cafe order is
+// marked as paid. The transfer is deduplicated by request, so a retried confirmation
+// cannot charge twice.
+func (s *PaymentRequestService) Pay(payerID uint, token string) (*models.PaymentRequest, error) {
+	var request models.PaymentRequest
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token = ?", strings.TrimSpace(token)).First(&request).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrPaymentRequestNotFound
+			}
+			return err
+		}
+
+		now := time.Now().UTC()
+		if err := checkPaymentRequestPayable(&request, payerID, now); err != nil {
+			return err
+		}
+
+		var cafeOrder *models.CafeOrder
+		if request.CafeOrderID != nil {
+			var order models.CafeOrder
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, *request.CafeOrderID).Error; err != nil {
+				return err
+			}
+			if order.IsPaid {
+				return ErrCafeOrderAlreadyPaid
+			}
+			if order.Status == models.CafeOrderStatusCancelled {
+				return ErrPaymentRequestClosed
+			}
+			cafeOrder = &order
+		}
+
+		description := request.Description
+		if description == "" {
+			description = "Оплата по запросу"
+		}
+		if _, err := s.walletService.transferTx(tx, payerID, request.PayeeID, request.Amount,
+			paymentRequestDedupKey(request.ID), description, nil); err != nil {
+			return err
+		}
+
+		if cafeOrder != nil {
+			updates := map[string]interface{}{
+				"is_paid":          true,
+				"paid_at":          now,
+				"payment_method":   "lkm",
+				"regular_lkm_paid": request.Amount,
+				"bonus_lkm_paid":   0,
+			}
+			if cafeOrder.CustomerID == nil {
+				// Walk-in guest: the order shows up in the payer's history from now on
+				updates["customer_id"] = payerID
+			}
+			if err := tx.Model(cafeOrder).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+
+		request.Status = models.PaymentRequestPaid
+		request.PayerID = &payerID
+		request.PaidAt = &now
+		return tx.Model(&request).Select("status", "payer_id", "paid_at").Updates(&request).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func checkPaymentRequestPayable(request *models.PaymentRequest, payerID uint, now time.Time) error {
+	if effectivePaymentRequestStatus(request, now) != models.PaymentRequestPending {
+		return ErrPaymentRequestClosed
+	}
+	if request.PayeeID == payerID {
+		return ErrPaymentRequestOwn
+	}
+	if request.PayerID != nil && *request.PayerID != payerID {
+		return ErrPaymentRequestForbidden
+	}
+	return nil
+}
+
+func paymentRequestDedupKey(requestID uint) string {
+	return fmt.Sprintf("payment_request:%d", requestID)
+}
+
+// Cancel withdraws a pending request
+func (s *PaymentRequestService) Cancel(payeeID uint, token string) (*models.PaymentRequest, error) {
+	var request models.PaymentRequest
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token = ? AND payee_id = ?", strings.TrimSpace(token), payeeID).First(&request).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrPaymentRequestNotFound
+			}
+			return err
+		}
+		if request.Status != models.PaymentRequestPending {
+			return ErrPaymentRequestClosed
+		}
+		now := time.Now().UTC()
+		request.Status = models.PaymentRequestCancelled
+		request.CancelledAt = &now
+		return tx.Model(&request).Select("status", "cancelled_at").Updates(&request).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// refundCafeOrderPaymentTx returns a QR payment for a cancelled cafe order from the cafe
+// owner back to the payer. It reports false when the order was not paid through a request.
+func refundCafeOrderPaymentTx(tx *gorm.DB, walletService *WalletService, order *models.CafeOrder) (bool, error) {
+	var request models.PaymentRequest
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("cafe_order_id = ? AND status = ?", order.ID, models.PaymentRequestPaid).
+		First(&request).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if request.PayerID == nil {
+		return false, nil
+	}
+
+	if _, err := walletService.returnTransferTx(tx, request.PayeeID, *request.PayerID, request.Amount,
+		fmt.Sprintf("payment_request_refund:%d", request.ID),
+		"Возврат за отмену заказа "+order.OrderNumber); err != nil {
+		return false, err
+	}
+	now := time.Now().UTC()
+	request.Status = models.PaymentRequestRefunded
+	request.RefundedAt = &now
+	return true, tx.Model(&request).Select("status", "refunded_at").Updates(&request).Error
+}
+
+// NewPaymentRequestResponse attaches the shareable links; the QR code encodes the deep link
+func NewPaymentRequestResponse(request *models.PaymentRequest) models.PaymentRequestResponse {
+	deepLink, webLink := paymentRequestLinks(request.Token, os.Getenv("PAYMENT_LINK_WEB_BASE"))
+	response := models.PaymentRequestResponse{
+		PaymentRequest: *request,
+		DeepLink:       deepLink,
+		WebLink:        webLink,
+		QRPayload:      deepLink,
+	}
+	if request.Payee != nil {
+		response.PayeeName = request.Payee.SpiritualName
+		if response.PayeeName == "" {
+			response.PayeeName = request.Payee.KarmicName
+		}
+	}
+	return response
+}
+
+func paymentRequestLinks(token, webBase string) (deepLink, webLink string) {
+	webBase = strings.TrimRight(strings.TrimSpace(webBase), "/")
+	if webBase == "" {
+		webBase = "https://vedamatch.ru/pay"
+	}
+	return "vedamatch://pay/" + token, webBase + "/" + token
+}
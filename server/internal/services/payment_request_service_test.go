@@ -0,0 +1,129 @@
+package services
+
+import (
+	"errors"
+	"rag-agent-server/internal/models"
+	"testing"
+	"time"
+)
+
+func TestPaymentRequestTTL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		minutes int
+		want    time.Duration
+	}{
+		{name: "default", minutes: 0, want: 24 * time.Hour},
+		{name: "negative falls back to default", minutes: -5, want: 24 * time.Hour},
+		{name: "custom", minutes: 15, want: 15 * time.Minute},
+		{name: "capped", minutes: 60 * 24 * 90, want: 30 * 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := paymentRequestTTL(tt.minutes); got != tt.want {
+				t.Fatalf("paymentRequestTTL(%d) = %v, want %v", tt.minutes, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckPaymentRequestPayable(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	otherPayer := uint(9)
+
+	tests := []struct {
+		name    string
+		request models.PaymentRequest
+		payerID uint
+		wantErr error
+	}{
+		{
+			name:    "open request",
+			request: models.PaymentRequest{PayeeID: 1, Status: models.PaymentRequestPending, ExpiresAt: now.Add(time.Hour)},
+			payerID: 2,
+		},
+		{
+			name:    "expired",
+			request: models.PaymentRequest{PayeeID: 1, Status: models.PaymentRequestPending, ExpiresAt: now},
+			payerID: 2,
+			wantErr: ErrPaymentRequestClosed,
+		},
+		{
+			name:    "already paid",
+			request: models.PaymentRequest{PayeeID: 1, Status: models.PaymentRequestPaid, ExpiresAt: now.Add(time.Hour)},
+			payerID: 2,
+			wantErr: ErrPaymentRequestClosed,
+		},
+		{
+			name:    "refunded",
+			request: models.PaymentRequest{PayeeID: 1, Status: models.PaymentRequestRefunded, ExpiresAt: now.Add(time.Hour)},
+			payerID: 2,
+			wantErr: ErrPaymentRequestClosed,
+		},
+		{
+			name:    "own request",
+			request: models.PaymentRequest{PayeeID: 1, Status: models.PaymentRequestPending, ExpiresAt: now.Add(time.Hour)},
+			payerID: 1,
+			wantErr: ErrPaymentRequestOwn,
+		},
+		{
+			name:    "addressed to another payer",
+			request: models.PaymentRequest{PayeeID: 1, PayerID: &otherPayer, Status: models.PaymentRequestPending, ExpiresAt: now.Add(time.Hour)},
+			payerID: 2,
+			wantErr: ErrPaymentRequestForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := checkPaymentRequestPayable(&tt.request, tt.payerID, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("checkPaymentRequestPayable() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPaymentRequestLinks(t *testing.T) {
+	t.Parallel()
+
+	deepLink, webLink := paymentRequestLinks("abc", "")
+	if deepLink != "vedamatch://pay/abc" || webLink != "https://vedamatch.ru/pay/abc" {
+		t.Fatalf("default links = %q, %q", deepLink, webLink)
+	}
+
+	_, webLink = paymentRequestLinks("abc", " https://example.com/p/ ")
+	if webLink != "https://example.com/p/abc" {
+		t.Fatalf("custom web link = %q", webLink)
+	}
+}
+
+func TestGeneratePaymentRequestTokenIsURLSafe(t *testing.T) {
+	t.Parallel()
+
+	first, err := generatePaymentRequestToken()
+	if err != nil {
+		t.Fatalf("generatePaymentRequestToken() error = %v", err)
+	}
+	second, _ := generatePaymentRequestToken()
+	if first == second {
+		t.Fatal("tokens must be unique")
+	}
+	if len(first) != 32 {
+		t.Fatalf("token length = %d, want 32", len(first))
+	}
+	for _, r := range first {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			t.Fatalf("token %q contains non URL-safe rune %q", first, r)
+		}
+	}
+}
@@ -729,11 +729,16 @@ func (s *ProductService) ReserveStock(productID uint, variantID *uint, quantity
 
 // DeductStock deducts stock after order confirmation
 func (s *ProductService) DeductStock(productID uint, variantID *uint, quantity int) error {
+	return s.DeductStockTx(database.DB, productID, variantID, quantity)
+}
+
+// DeductStockTx is DeductStock inside the caller's transaction
+func (s *ProductService) DeductStockTx(tx *gorm.DB, productID uint, variantID *uint, quantity int) error {
 	if quantity <= 0 {
 		return errors.New("quantity must be greater than zero")
 	}
 	if variantID != nil {
-		result := database.DB.Model(&models.ProductVariant{}).
+		result := tx.Model(&models.ProductVariant{}).
 			Where("id = ? AND product_id = ? AND stock >= ? AND reserved >= ?", *variantID, productID, quantity, quantity).
 			Updates(map[string]interface{}{
 				"stock":    gorm.Expr("stock - ?", quantity),
@@ -744,7 +749,7 @@ func (s *ProductService) DeductStock(productID uint, variantID *uint, quantity i
 		}
 		if result.RowsAffected == 0 {
 			var exists int64
-			if err := database.DB.Model(&models.ProductVariant{}).
+			if err := tx.Model(&models.ProductVariant{}).
 				Where("id = ? AND product_id = ?", *variantID, productID).
 				Count(&exists).Error; err != nil {
 				return err
@@ -756,7 +761,7 @@ func (s *ProductService) DeductStock(productID uint, variantID *uint, quantity i
 		}
 		return nil
 	}
-	result := database.DB.Model(&models.Product{}).
+	result := tx.Model(&models.Product{}).
 		Where("id = ? AND (track_stock = false OR stock >= ?)", productID, quantity).
 		Update("stock", gorm.Expr("CASE WHEN track_stock THEN stock - ? ELSE stock END", quantity))
 	if result.Error != nil {
@@ -764,7 +769,7 @@ func (s *ProductService) DeductStock(productID uint, variantID *uint, quantity i
 	}
 	if result.RowsAffected == 0 {
 		var product models.Product
-		if err := database.DB.Select("id", "track_stock", "stock").First(&product, productID).Error; err != nil {
+		if err := tx.Select("id", "track_stock", "stock").First(&product, productID).Error; err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
 				return ErrProductNotFound
 			}
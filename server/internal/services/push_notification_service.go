@@ -89,6 +89,11 @@ type PushMessage struct {
 	EventKey string            `json:"-"`
 }
 
+// isDataOnly reports a silent message: no title or body, only Data for the app
+func (m PushMessage) isDataOnly() bool {
+	return strings.TrimSpace(m.Title) == "" && strings.TrimSpace(m.Body) == ""
+}
+
 // UserDeviceTokenInput is an API-friendly payload for token registration.
 type UserDeviceTokenInput struct {
 	Token      string
@@ -114,15 +119,17 @@ type PushHealthSummary struct {
 
 // FCMMessage represents the FCM message format
 type FCMMessage struct {
-	To              string   `json:"to,omitempty"`
-	RegistrationIDs []string `json:"registration_ids,omitempty"`
-	Notification    struct {
-		Title    string `json:"title"`
-		Body     string `json:"body"`
-		ImageURL string `json:"image,omitempty"`
-	} `json:"notification"`
-	Data     map[string]string `json:"data,omitempty"`
-	Priority string            `json:"priority,omitempty"`
+	To              string            `json:"to,omitempty"`
+	RegistrationIDs []string          `json:"registration_ids,omitempty"`
+	Notification    *FCMNotification  `json:"notification,omitempty"` // nil sends a data-only message
+	Data            map[string]string `json:"data,omitempty"`
+	Priority        string            `json:"priority,omitempty"`
+}
+
+type FCMNotification struct {
+	Title    string `json:"title"`
+	Body     string `json:"body"`
+	ImageURL string `json:"image,omitempty"`
 }
 
 type fcmBatchResponse struct {
@@ -183,13 +190,14 @@ type fcmV1ResolvedConfig struct {
 }
 
 type expoPushMessage struct {
-	To       string            `json:"to"`
-	Title    string            `json:"title"`
-	Body     string            `json:"body"`
-	Data     map[string]string `json:"data,omitempty"`
-	Sound    string            `json:"sound,omitempty"`
-	Badge    int               `json:"badge,omitempty"`
-	Priority string            `json:"priority,omitempty"`
+	To               string            `json:"to"`
+	Title            string            `json:"title,omitempty"`
+	Body             string            `json:"body,omitempty"`
+	Data             map[string]string `json:"data,omitempty"`
+	Sound            string            `json:"sound,omitempty"`
+	Badge            int               `json:"badge,omitempty"`
+	Priority         string            `json:"priority,omitempty"`
+	ContentAvailable bool              `json:"_contentAvailable,omitempty"` // wakes the iOS app for data-only messages
 }
 
 type expoPushResponse struct {
@@ -823,6 +831,54 @@ func (s *PushNotificationService) SendWalletBalanceActivated(userID uint, amount
 	return s.SendToUser(userID, message)
 }
 
+// SendStandingOrderPaused tells the payer that a standing order stopped for lack of LKM
+func (s *PushNotificationService) SendStandingOrderPaused(userID uint, standingOrderID uint, amount int, recipientName string) error {
+	message := PushMessage{
+		Title:    "⏸ Регулярный перевод приостановлен",
+		Body:     fmt.Sprintf("Не хватило LKM для перевода %d LKM получателю %s. Пополните баланс и возобновите перевод", amount, recipientName),
+		Priority: "high",
+		Data: map[string]string{
+			"type":            "standing_order_paused",
+			"standingOrderId": fmt.Sprintf("%d", standingOrderID),
+			"screen":          "Wallet",
+		},
+	}
+	return s.SendToUser(userID, message)
+}
+
+// SendPaymentRequestPaid tells the payee that an LKM payment request was paid
+func (s *PushNotificationService) SendPaymentRequestPaid(payeeID uint, amount int, description string) error {
+	body := fmt.Sprintf("Получено %d LKM по запросу на оплату", amount)
+	if description != "" {
+		body = fmt.Sprintf("Получено %d LKM: %s", amount, description)
+	}
+	message := PushMessage{
+		Title:    "✅ Запрос оплачен",
+		Body:     body,
+		Priority: "high",
+		Data: map[string]string{
+			"type":   "payment_request_paid",
+			"amount": fmt.Sprintf("%d", amount),
+			"screen": "Wallet",
+		},
+	}
+	return s.SendToUser(payeeID, message)
+}
+
+// SendLKMReconciliationAlert tells a finance admin that the daily top-up reconciliation found discrepancies
+func (s *PushNotificationService) SendLKMReconciliationAlert(adminID uint, reportID uint, reportDate string, discrepancies int) error {
+	message := PushMessage{
+		Title:    "⚠️ Сверка пополнений LKM",
+		Body:     fmt.Sprintf("За %s найдено расхождений: %d. Проверьте отчет сверки", reportDate, discrepancies),
+		Priority: "high",
+		Data: map[string]string{
+			"type":     "lkm_reconciliation",
+			"reportId": fmt.Sprintf("%d", reportID),
+		},
+	}
+	return s.SendToUser(adminID, message)
+}
+
 // SendCharityReportWarning notifies organization owner that a report is due soon or overdue
 func (s *PushNotificationService) SendCharityReportWarning(ownerID uint, projectName string, daysRemaining int) error {
 	title := "⚠️ Отчет по проекту"
@@ -889,6 +945,99 @@ func (s *PushNotificationService) SendVideoCirclePublishFailed(userID uint, reas
 	return s.SendToUser(userID, buildVideoCirclePublishResultMessage("failed", 0, reason))
 }
 
+// ==================== LIBRARY NOTIFICATIONS ====================
+
+// SendReadingPlanReminder reminds a user about today's portion of a reading plan
+func (s *PushNotificationService) SendReadingPlanReminder(userID uint, enrollmentID uint, planTitle string, dayNumber int) error {
+	message := PushMessage{
+		Title:    "📖 Время чтения",
+		Body:     fmt.Sprintf("%s — день %d ждёт вас", planTitle, dayNumber),
+		Priority: "default",
+		Data: map[string]string{
+			"type":         "reading_plan_reminder",
+			"enrollmentId": fmt.Sprintf("%d", enrollmentID),
+			"day":          fmt.Sprintf("%d", dayNumber),
+			"screen":       "ReadingPlan",
+		},
+	}
+	return s.SendToUser(userID, message)
+}
+
+// ==================== DATING NOTIFICATIONS ====================
+
+// SendDatingMatch tells a user about a mutual like and the room opened for the pair
+func (s *PushNotificationService) SendDatingMatch(userID uint, matchID uint, roomID uint, partnerName string) error {
+	message := PushMessage{
+		Title:    "💞 Взаимная симпатия!",
+		Body:     fmt.Sprintf("Вы и %s понравились друг другу. Начните общение!", partnerName),
+		Priority: "high",
+		Data: map[string]string{
+			"type":    "dating_match",
+			"matchId": fmt.Sprintf("%d", matchID),
+			"roomId":  fmt.Sprintf("%d", roomID),
+			"screen":  "RoomChat",
+		},
+	}
+	return s.SendToUser(userID, message)
+}
+
+// ==================== MARKET NOTIFICATIONS ====================
+
+// SendOrderEscrowRefunded tells the buyer that an unshipped order was cancelled and the hold returned
+func (s *PushNotificationService) SendOrderEscrowRefunded(buyerID uint, orderID uint, orderNumber string, amount int) error {
+	message := PushMessage{
+		Title:    "↩️ Заказ отменён",
+		Body:     fmt.Sprintf("Продавец не отправил заказ %s вовремя. %d LKM вернулись на ваш баланс", orderNumber, amount),
+		Priority: "high",
+		Data: map[string]string{
+			"type":    "order_escrow_refunded",
+			"orderId": fmt.Sprintf("%d", orderID),
+			"screen":  "MyOrders",
+		},
+	}
+	return s.SendToUser(buyerID, message)
+}
+
+// SendOrderDisputeOpened asks the seller to answer a dispute before the SLA runs out
+func (s *PushNotificationService) SendOrderDisputeOpened(sellerID uint, orderID uint, orderNumber string, dueAt time.Time) error {
+	message := PushMessage{
+		Title:    "⚠️ Спор по заказу",
+		Body:     fmt.Sprintf("Покупатель открыл спор по заказу %s. Ответьте до %s", orderNumber, formatTime(dueAt)),
+		Priority: "high",
+		Data: map[string]string{
+			"type":    "order_dispute_opened",
+			"orderId": fmt.Sprintf("%d", orderID),
+			"screen":  "MyOrders",
+		},
+	}
+	return s.SendToUser(sellerID, message)
+}
+
+// SendOrderDisputeResolved tells a party of the dispute about the admin decision
+func (s *PushNotificationService) SendOrderDisputeResolved(userID uint, orderID uint, orderNumber string, resolution models.OrderDisputeResolution, refundLkm int) error {
+	var body string
+	switch resolution {
+	case models.OrderDisputeFullRefund:
+		body = fmt.Sprintf("Спор по заказу %s решён: покупателю возвращено %d LKM", orderNumber, refundLkm)
+	case models.OrderDisputePartialRefund:
+		body = fmt.Sprintf("Спор по заказу %s решён: частичный возврат %d LKM", orderNumber, refundLkm)
+	default:
+		body = fmt.Sprintf("Спор по заказу %s решён в пользу продавца", orderNumber)
+	}
+	message := PushMessage{
+		Title:    "⚖️ Спор решён",
+		Body:     body,
+		Priority: "high",
+		Data: map[string]string{
+			"type":       "order_dispute_resolved",
+			"orderId":    fmt.Sprintf("%d", orderID),
+			"resolution": string(resolution),
+			"screen":     "MyOrders",
+		},
+	}
+	return s.SendToUser(userID, message)
+}
+
 // formatTime helper for readable time format in Russian
 func formatTime(t time.Time) string {
 	months := []string{"", "янв", "фев", "мар", "апр", "май", "июн", "июл", "авг", "сен", "окт", "ноя", "дек"}
@@ -1059,9 +1208,13 @@ func (s *PushNotificationService) sendFCMLegacyAttempt(targets []pushTokenTarget
 			Data:            message.Data,
 			Priority:        message.Priority,
 		}
-		fcmMsg.Notification.Title = message.Title
-		fcmMsg.Notification.Body = message.Body
-		fcmMsg.Notification.ImageURL = message.ImageURL
+		if !message.isDataOnly() {
+			fcmMsg.Notification = &FCMNotification{
+				Title:    message.Title,
+				Body:     message.Body,
+				ImageURL: message.ImageURL,
+			}
+		}
 		for _, target := range batch {
 			fcmMsg.RegistrationIDs = append(fcmMsg.RegistrationIDs, target.Token)
 		}
@@ -1416,14 +1569,19 @@ func (s *PushNotificationService) sendExpoAttempt(targets []pushTokenTarget, mes
 
 		payload := make([]expoPushMessage, 0, len(batch))
 		for _, target := range batch {
-			payload = append(payload, expoPushMessage{
+			item := expoPushMessage{
 				To:       target.Token,
 				Title:    message.Title,
 				Body:     message.Body,
 				Data:     message.Data,
 				Sound:    "default",
 				Priority: message.Priority,
-			})
+			}
+			if message.isDataOnly() {
+				item.Sound = ""
+				item.ContentAvailable = true
+			}
+			payload = append(payload, item)
 		}
 
 		jsonData, err := json.Marshal(payload)
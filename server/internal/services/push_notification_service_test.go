@@ -1,6 +1,7 @@
 package services
 
 import (
+	"encoding/json"
 	"math/rand"
 	"net/http"
 	"net/http/httptest"
@@ -191,3 +192,27 @@ func TestSendFCMLegacyAttemptMissingResultsAreRetried(t *testing.T) {
 	require.Len(t, retryTargets, 1)
 	require.Equal(t, "token-b", retryTargets[0].Token)
 }
+
+func TestSendFCMLegacyAttemptDataOnlyOmitsNotification(t *testing.T) {
+	var captured map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&captured)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"success":1,"failure":0,"results":[{"message_id":"m1"}]}`))
+	}))
+	defer server.Close()
+
+	svc := &PushNotificationService{
+		fcmURL:     server.URL,
+		httpClient: server.Client(),
+		fcmEnvKey:  "test-key",
+	}
+
+	_, err := svc.sendFCMLegacyAttempt([]pushTokenTarget{
+		{Token: "token-a", Provider: providerFCM},
+	}, PushMessage{Data: map[string]string{"type": "messages_read"}}, 1, false)
+	require.NoError(t, err)
+	require.NotContains(t, captured, "notification")
+	require.Equal(t, map[string]interface{}{"type": "messages_read"}, captured["data"])
+}
@@ -225,7 +225,7 @@ func (s *ReferralService) ProcessActivation(userID uint) error {
 				Type:         models.TransactionTypeBonus,
 				Amount:       activated,
 				BonusAmount:  activated,
-				Description:  "Welcome Bonus Activated",
+				Description:  walletWelcomeActivatedDescription,
 				BalanceAfter: userWallet.Balance,
 			}
 			if err := tx.Create(&activationTx).Error; err != nil {
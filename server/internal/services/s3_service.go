@@ -105,6 +105,31 @@ func (s *S3Service) UploadFile(ctx context.Context, file io.Reader, fileName str
 	return fileURL, nil
 }
 
+// UploadPrivateFile uploads a file without a public ACL; hand out GeneratePresignedURL links instead
+func (s *S3Service) UploadPrivateFile(ctx context.Context, file io.Reader, key string, contentType string, contentSize int64) error {
+	if s == nil || s.client == nil {
+		return fmt.Errorf("S3 service not initialized")
+	}
+
+	putInput := &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucketName),
+		Key:         aws.String(key),
+		Body:        file,
+		ContentType: aws.String(contentType),
+		ACL:         types.ObjectCannedACLPrivate,
+	}
+	if contentSize > 0 {
+		putInput.ContentLength = aws.Int64(contentSize)
+	}
+
+	if _, err := s.client.PutObject(ctx, putInput, s3.WithAPIOptions(
+		v4.SwapComputePayloadSHA256ForUnsignedPayloadMiddleware,
+	)); err != nil {
+		return fmt.Errorf("failed to upload file to S3: %w", err)
+	}
+	return nil
+}
+
 // DeleteFile deletes a file from S3
 func (s *S3Service) DeleteFile(ctx context.Context, fileName string) error {
 	if s == nil || s.client == nil {
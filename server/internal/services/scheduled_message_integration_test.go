@@ -0,0 +1,76 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"rag-agent-server/internal/models"
+)
+
+func TestScheduledMessagesRespectBlocks_Integration(t *testing.T) {
+	db := setupYatraServiceIntegrationDB(t)
+	if err := db.AutoMigrate(&models.Block{}, &models.Message{}, &models.ScheduledMessage{}); err != nil {
+		t.Fatalf("scheduled message automigrate failed: %v", err)
+	}
+
+	sender := createYatraIntegrationUser(t, db, "scheduled-sender")
+	recipient := createYatraIntegrationUser(t, db, "scheduled-recipient")
+	service := NewScheduledMessageService()
+
+	// Scheduled before the block, due now
+	pending := models.ScheduledMessage{
+		SenderID:    sender.ID,
+		RecipientID: recipient.ID,
+		Content:     "see you tomorrow",
+		Type:        "text",
+		ScheduledAt: time.Now().Add(-time.Minute),
+		Status:      models.ScheduledMessagePending,
+	}
+	if err := db.Create(&pending).Error; err != nil {
+		t.Fatalf("create scheduled message: %v", err)
+	}
+
+	// Unmatch blocks from the recipient's side
+	if err := db.Create(&models.Block{UserID: recipient.ID, BlockedID: sender.ID}).Error; err != nil {
+		t.Fatalf("create block: %v", err)
+	}
+
+	if err := EnsureDirectMessageAllowed(sender.ID, recipient.ID); !errors.Is(err, ErrMessageBlocked) {
+		t.Fatalf("EnsureDirectMessageAllowed() error = %v, want %v", err, ErrMessageBlocked)
+	}
+
+	_, err := service.Schedule(sender.ID, ScheduleMessageInput{
+		RecipientID: recipient.ID,
+		Content:     "hello again",
+		ScheduledAt: time.Now().Add(time.Hour),
+	})
+	if !errors.Is(err, ErrMessageBlocked) {
+		t.Fatalf("Schedule() error = %v, want %v", err, ErrMessageBlocked)
+	}
+
+	delivered, err := service.DeliverDue(10)
+	if err != nil {
+		t.Fatalf("DeliverDue() error = %v", err)
+	}
+	if len(delivered) != 0 {
+		t.Fatalf("delivered %d messages to a blocked user", len(delivered))
+	}
+
+	var reloaded models.ScheduledMessage
+	if err := db.First(&reloaded, pending.ID).Error; err != nil {
+		t.Fatalf("reload scheduled message: %v", err)
+	}
+	if reloaded.Status != models.ScheduledMessageFailed {
+		t.Fatalf("status = %s, want %s", reloaded.Status, models.ScheduledMessageFailed)
+	}
+	var messages int64
+	if err := db.Model(&models.Message{}).
+		Where("sender_id = ? AND recipient_id = ?", sender.ID, recipient.ID).
+		Count(&messages).Error; err != nil {
+		t.Fatalf("count messages: %v", err)
+	}
+	if messages != 0 {
+		t.Fatalf("messages = %d, want 0", messages)
+	}
+}
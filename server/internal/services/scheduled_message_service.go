@@ -0,0 +1,310 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"rag-agent-server/internal/database"
+	"rag-agent-server/internal/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrScheduledMessageNotFound   = errors.New("scheduled message not found")
+	ErrScheduledMessageNotPending = errors.New("scheduled message is no longer pending")
+	ErrInvalidSchedule            = errors.New("scheduledAt must be in the future and within one year")
+	ErrInvalidMessageTTL          = fmt.Errorf("ttlSeconds must be between %d and %d", MinMessageTTLSeconds, MaxMessageTTLSeconds)
+)
+
+// The expiry worker runs once a minute, so shorter TTLs would not be honoured
+const (
+	MinMessageTTLSeconds = 60
+	MaxMessageTTLSeconds = 30 * 24 * 60 * 60
+
+	maxScheduleAhead           = 365 * 24 * time.Hour
+	defaultScheduledBatchLimit = 200
+)
+
+// ValidateMessageTTL accepts 0 (no self-destruct) or a TTL within bounds
+func ValidateMessageTTL(ttlSeconds int) error {
+	if ttlSeconds == 0 {
+		return nil
+	}
+	if ttlSeconds < MinMessageTTLSeconds || ttlSeconds > MaxMessageTTLSeconds {
+		return ErrInvalidMessageTTL
+	}
+	return nil
+}
+
+// MessageExpiry returns when a message sent at sentAt self-destructs, nil for no TTL
+func MessageExpiry(sentAt time.Time, ttlSeconds int) *time.Time {
+	if ttlSeconds <= 0 {
+		return nil
+	}
+	expiresAt := sentAt.Add(time.Duration(ttlSeconds) * time.Second)
+	return &expiresAt
+}
+
+// ScheduleMessageInput describes a text message to deliver later
+type ScheduleMessageInput struct {
+	RecipientID uint
+	RoomID      uint
+	Content     string
+	ReplyToID   *uint
+	QuoteText   string
+	ScheduledAt time.Time
+	TTLSeconds  int
+}
+
+// DeliveredMessage is a scheduled message that was turned into a regular Message,
+// with what callers need to fan it out through the hub and push
+type DeliveredMessage struct {
+	Message       models.Message
+	RoomName      string
+	RoomMemberIDs []uint
+}
+
+// ScheduledMessageService queues chat messages for later delivery
+type ScheduledMessageService struct {
+	db      *gorm.DB
+	threads *MessageThreadService
+}
+
+func NewScheduledMessageService() *ScheduledMessageService {
+	return &ScheduledMessageService{
+		db:      database.DB,
+		threads: NewMessageThreadService(),
+	}
+}
+
+// Schedule validates and stores a message for delivery at in.ScheduledAt
+func (s *ScheduledMessageService) Schedule(senderID uint, in ScheduleMessageInput) (*models.ScheduledMessage, error) {
+	content := strings.TrimSpace(in.Content)
+	if content == "" {
+		return nil, ErrInvalidPayload
+	}
+	if err := (ConversationRef{RoomID: in.RoomID, PeerID: in.RecipientID}).Validate(senderID); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if !in.ScheduledAt.After(now) || in.ScheduledAt.After(now.Add(maxScheduleAhead)) {
+		return nil, ErrInvalidSchedule
+	}
+	if err := ValidateMessageTTL(in.TTLSeconds); err != nil {
+		return nil, err
+	}
+	if in.RoomID != 0 {
+		if err := s.ensureRoomMember(in.RoomID, senderID); err != nil {
+			return nil, err
+		}
+	}
+	if in.RecipientID != 0 {
+		if err := s.ensureNotBlocked(senderID, in.RecipientID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Validate the reply target up front; it is checked again on delivery
+	draft := models.Message{
+		SenderID:    senderID,
+		RecipientID: in.RecipientID,
+		RoomID:      in.RoomID,
+		ReplyToID:   in.ReplyToID,
+		QuoteText:   in.QuoteText,
+	}
+	if err := s.threads.PrepareReply(&draft); err != nil {
+		return nil, err
+	}
+
+	scheduled := models.ScheduledMessage{
+		SenderID:    senderID,
+		RecipientID: in.RecipientID,
+		RoomID:      in.RoomID,
+		Content:     content,
+		Type:        "text",
+		ReplyToID:   draft.ReplyToID,
+		QuoteText:   draft.QuoteText,
+		ScheduledAt: in.ScheduledAt.UTC(),
+		TTLSeconds:  in.TTLSeconds,
+		Status:      models.ScheduledMessagePending,
+	}
+	if err := s.db.Create(&scheduled).Error; err != nil {
+		return nil, err
+	}
+	return &scheduled, nil
+}
+
+func (s *ScheduledMessageService) ensureRoomMember(roomID, userID uint) error {
+	var count int64
+	if err := s.db.Model(&models.RoomMember{}).
+		Where("room_id = ? AND user_id = ?", roomID, userID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrMessageForbidden
+	}
+	return nil
+}
+
+func (s *ScheduledMessageService) ensureNotBlocked(senderID, recipientID uint) error {
+	blocked, err := isBlockedBetween(s.db, senderID, recipientID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrMessageBlocked
+	}
+	return nil
+}
+
+// ListPending returns the sender's pending scheduled messages, soonest first
+func (s *ScheduledMessageService) ListPending(senderID uint) ([]models.ScheduledMessage, error) {
+	var items []models.ScheduledMessage
+	err := s.db.Where("sender_id = ? AND status = ?", senderID, models.ScheduledMessagePending).
+		Order("scheduled_at ASC").
+		Find(&items).Error
+	return items, err
+}
+
+// Cancel cancels a pending scheduled message owned by senderID
+func (s *ScheduledMessageService) Cancel(id, senderID uint) error {
+	var scheduled models.ScheduledMessage
+	if err := s.db.Where("id = ? AND sender_id = ?", id, senderID).First(&scheduled).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrScheduledMessageNotFound
+		}
+		return err
+	}
+	result := s.db.Model(&models.ScheduledMessage{}).
+		Where("id = ? AND status = ?", id, models.ScheduledMessagePending).
+		Update("status", models.ScheduledMessageCancelled)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrScheduledMessageNotPending
+	}
+	return nil
+}
+
+// DeliverDue turns due scheduled messages into regular messages.
+// Items that can no longer be delivered (sender left the room, room removed, the
+// pair blocked each other) are marked failed; other errors leave them pending for the next run.
+func (s *ScheduledMessageService) DeliverDue(limit int) ([]DeliveredMessage, error) {
+	if limit <= 0 {
+		limit = defaultScheduledBatchLimit
+	}
+	var due []models.ScheduledMessage
+	if err := s.db.Where("status = ? AND scheduled_at <= ?", models.ScheduledMessagePending, time.Now().UTC()).
+		Order("scheduled_at ASC, id ASC").
+		Limit(limit).
+		Find(&due).Error; err != nil {
+		return nil, err
+	}
+
+	delivered := make([]DeliveredMessage, 0, len(due))
+	for i := range due {
+		item, err := s.deliver(&due[i])
+		if err != nil {
+			log.Printf("[ScheduledMessages] delivery failed id=%d sender=%d: %v", due[i].ID, due[i].SenderID, err)
+			if errors.Is(err, ErrMessageForbidden) || errors.Is(err, ErrMessageBlocked) || errors.Is(err, gorm.ErrRecordNotFound) {
+				s.markFailed(due[i].ID, err)
+			}
+			continue
+		}
+		if item != nil {
+			delivered = append(delivered, *item)
+		}
+	}
+	return delivered, nil
+}
+
+func (s *ScheduledMessageService) deliver(scheduled *models.ScheduledMessage) (*DeliveredMessage, error) {
+	result := &DeliveredMessage{}
+	if scheduled.RoomID != 0 {
+		if err := s.ensureRoomMember(scheduled.RoomID, scheduled.SenderID); err != nil {
+			return nil, err
+		}
+		var room models.Room
+		if err := s.db.Select("id", "name").First(&room, scheduled.RoomID).Error; err != nil {
+			return nil, err
+		}
+		result.RoomName = room.Name
+		if err := s.db.Model(&models.RoomMember{}).
+			Where("room_id = ?", scheduled.RoomID).
+			Pluck("user_id", &result.RoomMemberIDs).Error; err != nil {
+			return nil, err
+		}
+		result.RoomMemberIDs = uniqueIDs(result.RoomMemberIDs)
+	}
+	if scheduled.RecipientID != 0 {
+		// The pair may have blocked each other or unmatched since scheduling
+		if err := s.ensureNotBlocked(scheduled.SenderID, scheduled.RecipientID); err != nil {
+			return nil, err
+		}
+	}
+
+	msg := models.Message{
+		SenderID:    scheduled.SenderID,
+		RecipientID: scheduled.RecipientID,
+		RoomID:      scheduled.RoomID,
+		Content:     scheduled.Content,
+		Type:        scheduled.Type,
+		ReplyToID:   scheduled.ReplyToID,
+		QuoteText:   scheduled.QuoteText,
+	}
+	if err := s.threads.PrepareReply(&msg); err != nil {
+		// The parent went away in the meantime; send as a plain message
+		msg.ReplyToID = nil
+		msg.QuoteText = ""
+		msg.ThreadRootID = nil
+	}
+
+	now := time.Now()
+	msg.ExpiresAt = MessageExpiry(now, scheduled.TTLSeconds)
+
+	claimed := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		claim := tx.Model(&models.ScheduledMessage{}).
+			Where("id = ? AND status = ?", scheduled.ID, models.ScheduledMessagePending).
+			Updates(map[string]interface{}{
+				"status":  models.ScheduledMessageSent,
+				"sent_at": now,
+			})
+		if claim.Error != nil {
+			return claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			return nil // Cancelled or taken by another instance
+		}
+		claimed = true
+		if err := tx.Create(&msg).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.ScheduledMessage{}).Where("id = ?", scheduled.ID).Update("message_id", msg.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, nil
+	}
+
+	result.Message = msg
+	return result, nil
+}
+
+func (s *ScheduledMessageService) markFailed(id uint, cause error) {
+	if err := s.db.Model(&models.ScheduledMessage{}).
+		Where("id = ? AND status = ?", id, models.ScheduledMessagePending).
+		Updates(map[string]interface{}{
+			"status":     models.ScheduledMessageFailed,
+			"last_error": cause.Error(),
+		}).Error; err != nil {
+		log.Printf("[ScheduledMessages] failed to mark id=%d as failed: %v", id, err)
+	}
+}
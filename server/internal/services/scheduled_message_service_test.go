@@ -0,0 +1,51 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValidateMessageTTL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		ttl     int
+		wantErr bool
+	}{
+		{name: "no ttl", ttl: 0},
+		{name: "minimum", ttl: MinMessageTTLSeconds},
+		{name: "maximum", ttl: MaxMessageTTLSeconds},
+		{name: "too short", ttl: MinMessageTTLSeconds - 1, wantErr: true},
+		{name: "too long", ttl: MaxMessageTTLSeconds + 1, wantErr: true},
+		{name: "negative", ttl: -5, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			err := ValidateMessageTTL(tc.ttl)
+			if tc.wantErr && !errors.Is(err, ErrInvalidMessageTTL) {
+				t.Fatalf("expected ErrInvalidMessageTTL, got %v", err)
+			}
+			if !tc.wantErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestMessageExpiry(t *testing.T) {
+	t.Parallel()
+
+	sentAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	if got := MessageExpiry(sentAt, 0); got != nil {
+		t.Fatalf("expected no expiry without ttl, got %v", got)
+	}
+	got := MessageExpiry(sentAt, 3600)
+	if got == nil || !got.Equal(sentAt.Add(time.Hour)) {
+		t.Fatalf("expected expiry one hour after send, got %v", got)
+	}
+}
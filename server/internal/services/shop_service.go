@@ -194,6 +194,9 @@ func (s *ShopService) UpdateShop(shopID uint, userID uint, userRole string, req
 	if req.CoverURL != nil {
 		shop.CoverURL = *req.CoverURL
 	}
+	if req.EscrowEnabled != nil {
+		shop.EscrowEnabled = *req.EscrowEnabled
+	}
 
 	if err := database.DB.Save(&shop).Error; err != nil {
 		return nil, err
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"math"
+	"time"
+)
+
+// Offline sidereal positions for compatibility charts. The Moon uses the main periodic
+// terms of Meeus' lunar theory (Astronomical Algorithms, ch. 47; error well under 0.05°),
+// Mars the JPL approximate Keplerian elements (1800-2050), both converted to sidereal
+// longitude with the Lahiri ayanamsa. That is far finer than a nakshatra pada (3°20').
+
+const (
+	nakshatraSpan = 360.0 / 27.0
+	padaSpan      = nakshatraSpan / 4.0
+	j2000         = 2451545.0
+	degToRad      = math.Pi / 180
+)
+
+func julianDay(t time.Time) float64 {
+	return float64(t.UTC().UnixNano())/86400e9 + 2440587.5
+}
+
+func julianCenturies(jd float64) float64 {
+	return (jd - j2000) / 36525.0
+}
+
+func normalizeDegrees(deg float64) float64 {
+	deg = math.Mod(deg, 360)
+	if deg < 0 {
+		deg += 360
+	}
+	return deg
+}
+
+func sinDeg(deg float64) float64 { return math.Sin(deg * degToRad) }
+func cosDeg(deg float64) float64 { return math.Cos(deg * degToRad) }
+
+// lahiriAyanamsa is the Chitrapaksha ayanamsa in degrees (23°51'11" at J2000, 50.26"/year)
+func lahiriAyanamsa(jd float64) float64 {
+	return 23.8531 + 1.3960*julianCenturies(jd)
+}
+
+// Periodic terms of the Moon's longitude: multiples of D, M, M', F and the coefficient in 1e-6 degrees
+var moonLongitudeTerms = [][5]float64{
+	{0, 0, 1, 0, 6288774}, {2, 0, -1, 0, 1274027}, {2, 0, 0, 0, 658314}, {0, 0, 2, 0, 213618},
+	{0, 1, 0, 0, -185116}, {0, 0, 0, 2, -114332}, {2, 0, -2, 0, 58793}, {2, -1, -1, 0, 57066},
+	{2, 0, 1, 0, 53322}, {2, -1, 0, 0, 45758}, {0, 1, -1, 0, -40923}, {1, 0, 0, 0, -34720},
+	{0, 1, 1, 0, -30383}, {2, 0, 0, -2, 15327}, {0, 0, 1, 2, -12528}, {0, 0, 1, -2, 10980},
+	{4, 0, -1, 0, 10675}, {0, 0, 3, 0, 10034}, {4, 0, -2, 0, 8548}, {2, 1, -1, 0, -7888},
+	{2, 1, 0, 0, -6766}, {1, 0, -1, 0, -5163}, {1, 1, 0, 0, 4987}, {2, -1, 1, 0, 4036},
+	{2, 0, 2, 0, 3994}, {4, 0, 0, 0, 3861}, {2, 0, -3, 0, 3665}, {0, 1, -2, 0, -2689},
+	{2, 0, -1, 2, -2602}, {2, -1, -2, 0, 2390}, {1, 0, 1, 0, -2348}, {2, -2, 0, 0, 2236},
+	{0, 1, 2, 0, -2120}, {0, 2, 0, 0, -2069}, {2, -2, -1, 0, 2048},
+}
+
+// moonTropicalLongitude returns the Moon's geocentric ecliptic longitude of date
+func moonTropicalLongitude(jd float64) float64 {
+	t := julianCenturies(jd)
+	t2, t3, t4 := t*t, t*t*t, t*t*t*t
+
+	lp := 218.3164477 + 481267.88123421*t - 0.0015786*t2 + t3/538841 - t4/65194000
+	d := 297.8501921 + 445267.1114034*t - 0.0018819*t2 + t3/545868 - t4/113065000
+	m := 357.5291092 + 35999.0502909*t - 0.0001536*t2 + t3/24490000
+	mp := 134.9633964 + 477198.8675055*t + 0.0087414*t2 + t3/69699 - t4/14712000
+	f := 93.2720950 + 483202.0175233*t - 0.0036539*t2 - t3/3526000 + t4/863310000
+	a1 := 119.75 + 131.849*t
+	a2 := 53.09 + 479264.290*t
+	e := 1 - 0.002516*t - 0.0000074*t2
+
+	sum := 0.0
+	for _, term := range moonLongitudeTerms {
+		coeff := term[4]
+		switch math.Abs(term[1]) {
+		case 1:
+			coeff *= e
+		case 2:
+			coeff *= e * e
+		}
+		sum += coeff * sinDeg(term[0]*d+term[1]*m+term[2]*mp+term[3]*f)
+	}
+	sum += 3958*sinDeg(a1) + 1962*sinDeg(lp-f) + 318*sinDeg(a2)
+
+	return normalizeDegrees(lp + sum/1e6)
+}
+
+type keplerElements struct {
+	a, e, i, l, peri, node    float64 // J2000 values: AU, -, deg, deg, deg, deg
+	da, de, di, dl, dp, dnode float64 // rates per Julian century
+}
+
+var (
+	earthElements = keplerElements{
+		a: 1.00000261, e: 0.01671123, i: -0.00001531, l: 100.46457166, peri: 102.93768193, node: 0,
+		da: 0.00000562, de: -0.00004392, di: -0.01294668, dl: 35999.37244981, dp: 0.32327364, dnode: 0,
+	}
+	marsElements = keplerElements{
+		a: 1.52371034, e: 0.09339410, i: 1.84969142, l: -4.55343205, peri: -23.94362959, node: 49.55953891,
+		da: 0.00001847, de: 0.00007882, di: -0.00813131, dl: 19140.30268499, dp: 0.44441088, dnode: -0.29257343,
+	}
+)
+
+// heliocentric returns J2000 ecliptic coordinates of a planet in AU
+func (k keplerElements) heliocentric(t float64) (float64, float64, float64) {
+	a := k.a + k.da*t
+	e := k.e + k.de*t
+	inc := (k.i + k.di*t) * degToRad
+	l := k.l + k.dl*t
+	peri := k.peri + k.dp*t
+	node := (k.node + k.dnode*t) * degToRad
+	omega := peri*degToRad - node
+	mean := normalizeDegrees(l-peri) * degToRad
+
+	ecc := mean
+	for n := 0; n < 10; n++ {
+		delta := (ecc - e*math.Sin(ecc) - mean) / (1 - e*math.Cos(ecc))
+		ecc -= delta
+		if math.Abs(delta) < 1e-12 {
+			break
+		}
+	}
+	xp := a * (math.Cos(ecc) - e)
+	yp := a * math.Sqrt(1-e*e) * math.Sin(ecc)
+
+	cw, sw := math.Cos(omega), math.Sin(omega)
+	cn, sn := math.Cos(node), math.Sin(node)
+	ci, si := math.Cos(inc), math.Sin(inc)
+	x := (cw*cn-sw*sn*ci)*xp + (-sw*cn-cw*sn*ci)*yp
+	y := (cw*sn+sw*cn*ci)*xp + (-sw*sn+cw*cn*ci)*yp
+	z := (sw*si)*xp + (cw*si)*yp
+	return x, y, z
+}
+
+// marsTropicalLongitude returns the geocentric longitude of Mars referred to the equinox of date
+func marsTropicalLongitude(jd float64) float64 {
+	t := julianCenturies(jd)
+	mx, my, _ := marsElements.heliocentric(t)
+	ex, ey, _ := earthElements.heliocentric(t)
+	lon := math.Atan2(my-ey, mx-ex) / degToRad
+	// General precession carries J2000 longitudes to the equinox of date
+	return normalizeDegrees(lon + 1.396971*t)
+}
+
+// ascendantTropicalLongitude returns the rising degree of the ecliptic for a place
+func ascendantTropicalLongitude(jd, latitude, longitude float64) float64 {
+	t := julianCenturies(jd)
+	gmst := 280.46061837 + 360.98564736629*(jd-j2000) + 0.000387933*t*t - t*t*t/38710000
+	lst := normalizeDegrees(gmst + longitude)
+	eps := 23.4392911 - 0.0130042*t
+	y := cosDeg(lst)
+	x := -(sinDeg(lst)*cosDeg(eps) + math.Tan(latitude*degToRad)*sinDeg(eps))
+	return normalizeDegrees(math.Atan2(y, x) / degToRad)
+}
+
+func siderealLongitude(tropical, jd float64) float64 {
+	return normalizeDegrees(tropical - lahiriAyanamsa(jd))
+}
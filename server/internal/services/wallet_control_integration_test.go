@@ -0,0 +1,97 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"rag-agent-server/internal/models"
+
+	"gorm.io/gorm"
+)
+
+func setupWalletControlIntegrationDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db := setupYatraServiceIntegrationDB(t)
+	if err := db.AutoMigrate(&models.WalletControlEvent{}, &models.AdminNotification{}); err != nil {
+		t.Fatalf("wallet control automigrate failed: %v", err)
+	}
+	return db
+}
+
+func TestTransferTxVelocityBlocksFanOut_Integration(t *testing.T) {
+	db := setupWalletControlIntegrationDB(t)
+	upsertSystemSetting(t, db, walletVelocityMaxRecipientsKey, "2")
+
+	walletService := NewWalletService()
+	sender := createYatraIntegrationUser(t, db, "velocity-sender")
+	setWalletBalance(t, db, sender.ID, 1000)
+
+	for i := 0; i < 2; i++ {
+		recipient := createYatraIntegrationUser(t, db, fmt.Sprintf("velocity-recipient-%d", i))
+		if _, err := walletService.transferTx(db, sender.ID, recipient.ID, 10, "", "payment link", nil); err != nil {
+			t.Fatalf("transfer %d: %v", i, err)
+		}
+	}
+
+	blocked := createYatraIntegrationUser(t, db, "velocity-recipient-blocked")
+	_, err := walletService.transferTx(db, sender.ID, blocked.ID, 10, "", "payment link", nil)
+	if !errors.Is(err, ErrTransferVelocityBlocked) {
+		t.Fatalf("third recipient error = %v, want %v", err, ErrTransferVelocityBlocked)
+	}
+
+	var senderWallet models.Wallet
+	if err := db.Where("user_id = ?", sender.ID).First(&senderWallet).Error; err != nil {
+		t.Fatalf("load sender wallet: %v", err)
+	}
+	if senderWallet.Balance != 980 {
+		t.Fatalf("blocked transfer moved money: balance = %d, want 980", senderWallet.Balance)
+	}
+	var events int64
+	if err := db.Model(&models.WalletControlEvent{}).
+		Where("user_id = ? AND action = ? AND reason = ?", sender.ID, models.WalletControlVelocityBlocked, transferVelocityFanOut).
+		Count(&events).Error; err != nil {
+		t.Fatalf("count events: %v", err)
+	}
+	if events != 1 {
+		t.Fatalf("velocity events = %d, want 1", events)
+	}
+
+	// Giving money back is exempt from the sender's controls
+	if _, err := walletService.returnTransferTx(db, sender.ID, blocked.ID, 10, "", "refund"); err != nil {
+		t.Fatalf("returnTransferTx() error = %v", err)
+	}
+}
+
+func TestWalletLimitsIgnoreChargebacks_Integration(t *testing.T) {
+	db := setupWalletControlIntegrationDB(t)
+
+	walletService := NewWalletService()
+	seller := createYatraIntegrationUser(t, db, "limits-seller")
+	buyer := createYatraIntegrationUser(t, db, "limits-buyer")
+	setWalletBalance(t, db, seller.ID, 1000)
+	setWalletBalance(t, db, buyer.ID, 0)
+
+	if _, _, err := walletService.spendTxWithOptions(db, seller.ID, 100, "", "Spend", SpendOptions{}); err != nil {
+		t.Fatalf("spend: %v", err)
+	}
+	var sellerWallet, buyerWallet models.Wallet
+	if err := db.Where("user_id = ?", seller.ID).First(&sellerWallet).Error; err != nil {
+		t.Fatalf("load seller wallet: %v", err)
+	}
+	if err := db.Where("user_id = ?", buyer.ID).First(&buyerWallet).Error; err != nil {
+		t.Fatalf("load buyer wallet: %v", err)
+	}
+	if err := walletService.chargeBackTx(db, &sellerWallet, 300, orderHoldRef(42), buyerWallet.ID, "Dispute refund"); err != nil {
+		t.Fatalf("chargeBackTx() error = %v", err)
+	}
+
+	limits, err := walletService.walletLimitsTx(db, seller.ID, sellerWallet.ID, time.Now())
+	if err != nil {
+		t.Fatalf("walletLimitsTx() error = %v", err)
+	}
+	if limits.DailyUsed != 100 || limits.MonthlyUsed != 100 {
+		t.Fatalf("usage = %d/%d, want chargeback excluded (100/100)", limits.DailyUsed, limits.MonthlyUsed)
+	}
+}
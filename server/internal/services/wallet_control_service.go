@@ -0,0 +1,609 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"rag-agent-server/internal/database"
+	"rag-agent-server/internal/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrWalletFrozen            = errors.New("wallet is frozen: outgoing payments are disabled")
+	ErrSpendingLimitExceeded   = errors.New("spending limit exceeded")
+	ErrTransferVelocityBlocked = errors.New("transfer blocked by anti-fraud checks, contact support")
+
+	ErrWalletControlReasonRequired = errors.New("reason is required")
+	ErrWalletAlreadyFrozen         = errors.New("wallet is already frozen")
+	ErrWalletNotFrozen             = errors.New("wallet is not frozen")
+	ErrWalletLimitTierNotFound     = errors.New("limit tier not found")
+	ErrWalletLimitInvalid          = errors.New("limits must not be negative")
+	ErrWalletLimitTierNameRequired = errors.New("tier name is required")
+)
+
+const (
+	walletNewAccountDaysKey              = "WALLET_NEW_ACCOUNT_DAYS"
+	walletVelocityMaxRecipientsKey       = "WALLET_VELOCITY_MAX_RECIPIENTS_24H"
+	walletVelocityMaxSendersKey          = "WALLET_VELOCITY_MAX_SENDERS_24H"
+	walletVelocityPassThroughPercentKey  = "WALLET_VELOCITY_PASS_THROUGH_PERCENT"
+	walletVelocityWindow                 = 24 * time.Hour
+	walletControlEventsDefaultLimit      = 50
+	walletControlEventsRecentForResponse = 20
+)
+
+// Velocity rules reported in WalletControlEvent.Reason
+const (
+	transferVelocityFanOut      = "fan_out"
+	transferVelocityFanIn       = "fan_in"
+	transferVelocityPassThrough = "pass_through"
+)
+
+// WalletLimits are the outgoing limits in force for a user and what is already used.
+// Usage is gross regular LKM debited or held in the UTC day/month; bonus LKM is not
+// counted because it cannot leave the platform, nor are dispute chargebacks.
+type WalletLimits struct {
+	TierName     string `json:"tierName,omitempty"`
+	DailyLimit   int    `json:"dailyLimit"`   // 0 = unlimited
+	MonthlyLimit int    `json:"monthlyLimit"` // 0 = unlimited
+	DailyUsed    int    `json:"dailyUsed"`
+	MonthlyUsed  int    `json:"monthlyUsed"`
+	Overridden   bool   `json:"overridden"`
+}
+
+// resolveWalletLimits picks the tier for the account age, then applies the user's override
+func resolveWalletLimits(tiers []models.WalletLimitTier, override *models.WalletLimitOverride, accountAge time.Duration) WalletLimits {
+	var tier *models.WalletLimitTier
+	ageDays := int(accountAge / (24 * time.Hour))
+	for i := range tiers {
+		t := &tiers[i]
+		if !t.IsEnabled {
+			continue
+		}
+		if override != nil && override.TierName != "" {
+			if t.Name == override.TierName {
+				tier = t
+				break
+			}
+			continue
+		}
+		if t.MinAccountAgeDays <= ageDays && (tier == nil || t.MinAccountAgeDays > tier.MinAccountAgeDays) {
+			tier = t
+		}
+	}
+
+	var limits WalletLimits
+	if tier != nil {
+		limits.TierName = tier.Name
+		limits.DailyLimit = tier.DailyLimit
+		limits.MonthlyLimit = tier.MonthlyLimit
+	}
+	if override != nil {
+		limits.Overridden = true
+		if override.DailyLimit != nil {
+			limits.DailyLimit = *override.DailyLimit
+		}
+		if override.MonthlyLimit != nil {
+			limits.MonthlyLimit = *override.MonthlyLimit
+		}
+	}
+	return limits
+}
+
+// checkSpendingLimit reports whether amount more regular LKM fits into the limits
+func checkSpendingLimit(limits WalletLimits, amount int) error {
+	if limits.DailyLimit > 0 && limits.DailyUsed+amount > limits.DailyLimit {
+		return fmt.Errorf("%w: daily limit %d LKM, %d LKM left", ErrSpendingLimitExceeded,
+			limits.DailyLimit, maxInt(limits.DailyLimit-limits.DailyUsed, 0))
+	}
+	if limits.MonthlyLimit > 0 && limits.MonthlyUsed+amount > limits.MonthlyLimit {
+		return fmt.Errorf("%w: monthly limit %d LKM, %d LKM left", ErrSpendingLimitExceeded,
+			limits.MonthlyLimit, maxInt(limits.MonthlyLimit-limits.MonthlyUsed, 0))
+	}
+	return nil
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// walletLimitsTx resolves the limits of a user and their usage of walletID so far
+func (s *WalletService) walletLimitsTx(tx *gorm.DB, userID, walletID uint, now time.Time) (WalletLimits, error) {
+	var user models.User
+	if err := tx.Select("id", "created_at").First(&user, userID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return WalletLimits{}, err
+	}
+	var tiers []models.WalletLimitTier
+	if err := tx.Where("is_enabled = true").Order("sort_order ASC, min_account_age_days ASC").Find(&tiers).Error; err != nil {
+		return WalletLimits{}, err
+	}
+	var override *models.WalletLimitOverride
+	var found models.WalletLimitOverride
+	if err := tx.Where("user_id = ?", userID).First(&found).Error; err == nil {
+		override = &found
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return WalletLimits{}, err
+	}
+
+	// A missing user gets the strictest tier
+	accountAge := time.Duration(0)
+	if user.ID != 0 {
+		accountAge = now.Sub(user.CreatedAt)
+	}
+	limits := resolveWalletLimits(tiers, override, accountAge)
+	if walletID == 0 {
+		return limits, nil
+	}
+
+	now = now.UTC()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	var usage struct {
+		Daily   int
+		Monthly int
+	}
+	if err := tx.Model(&models.WalletTransaction{}).
+		Select("COALESCE(SUM(CASE WHEN created_at >= ? THEN amount - bonus_amount ELSE 0 END), 0) AS daily, "+
+			"COALESCE(SUM(amount - bonus_amount), 0) AS monthly", dayStart).
+		Where("wallet_id = ? AND type IN ? AND created_at >= ?", walletID,
+			[]models.TransactionType{models.TransactionTypeDebit, models.TransactionTypeHold}, monthStart).
+		Where("dedup_key IS NULL OR dedup_key NOT LIKE ?", walletChargebackDedupPrefix+"%").
+		Scan(&usage).Error; err != nil {
+		return WalletLimits{}, err
+	}
+	limits.DailyUsed = usage.Daily
+	limits.MonthlyUsed = usage.Monthly
+	return limits, nil
+}
+
+// enforceOutgoingTx rejects payments from a frozen wallet and regular LKM over the user's
+// limits. wallet must be locked by the caller. Platform and charity wallets are exempt.
+func (s *WalletService) enforceOutgoingTx(tx *gorm.DB, wallet *models.Wallet, regularAmount int) error {
+	if wallet.Type != models.WalletTypePersonal || wallet.UserID == nil {
+		return nil
+	}
+	if wallet.SpendingFrozen {
+		return ErrWalletFrozen
+	}
+	if regularAmount <= 0 {
+		return nil
+	}
+	limits, err := s.walletLimitsTx(tx, *wallet.UserID, wallet.ID, time.Now())
+	if err != nil {
+		return err
+	}
+	return checkSpendingLimit(limits, regularAmount)
+}
+
+// GetSpendingLimits returns the user's limits and what is left of them
+func (s *WalletService) GetSpendingLimits(userID uint) (*WalletLimits, error) {
+	wallet, err := s.GetOrCreateWallet(userID)
+	if err != nil {
+		return nil, err
+	}
+	limits, err := s.walletLimitsTx(database.DB, userID, wallet.ID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &limits, nil
+}
+
+// transferVelocityRules are the thresholds for transfers that involve new accounts
+type transferVelocityRules struct {
+	MaxRecipients      int // distinct recipients of a new sender in the window
+	MaxSenders         int // distinct senders into a new receiver in the window
+	PassThroughPercent int // how closely a new sender's outflow may mirror its inflow
+}
+
+// transferVelocityInput describes the window including the transfer being checked
+type transferVelocityInput struct {
+	SenderNew   bool
+	ReceiverNew bool
+	Recipients  int // distinct wallets the sender paid
+	Senders     int // distinct wallets that paid the receiver
+	Received    int // LKM the sender got from other users
+	Sent        int // LKM the sender paid to other users
+}
+
+// evaluateTransferVelocity returns the mule pattern the transfer matches, or "".
+//   - fan_out: a new account spreads money over many recipients
+//   - fan_in: a new account collects money from many senders
+//   - pass_through: a new account forwards to another new account about as much as it
+//     just received, i.e. sent and received are within PassThroughPercent of each other
+func evaluateTransferVelocity(in transferVelocityInput, rules transferVelocityRules) string {
+	if in.SenderNew && rules.MaxRecipients > 0 && in.Recipients > rules.MaxRecipients {
+		return transferVelocityFanOut
+	}
+	if in.ReceiverNew && rules.MaxSenders > 0 && in.Senders > rules.MaxSenders {
+		return transferVelocityFanIn
+	}
+	if in.SenderNew && in.ReceiverNew && rules.PassThroughPercent > 0 && in.Received > 0 &&
+		in.Sent*100 >= in.Received*rules.PassThroughPercent &&
+		in.Received*100 >= in.Sent*rules.PassThroughPercent {
+		return transferVelocityPassThrough
+	}
+	return ""
+}
+
+// transferVelocityBlock is what gets recorded when a transfer is rejected
+type transferVelocityBlock struct {
+	FromUserID   uint
+	ToUserID     uint
+	FromWalletID uint
+	Amount       int
+	Rule         string
+	Input        transferVelocityInput
+}
+
+// enforceTransferVelocityTx rejects a transfer that matches a mule pattern. It runs
+// before any balance is moved, so a caller that keeps its transaction (a standing order
+// being paused) commits nothing of the transfer. The block is recorded outside the
+// caller's transaction so it survives a rollback.
+func (s *WalletService) enforceTransferVelocityTx(tx *gorm.DB, fromWallet, toWallet *models.Wallet, amount int) error {
+	if fromWallet.UserID == nil || toWallet.UserID == nil {
+		return nil
+	}
+	block, err := s.checkTransferVelocityTx(tx, fromWallet, toWallet, amount, time.Now())
+	if err != nil {
+		return err
+	}
+	if block == nil {
+		return nil
+	}
+	s.recordVelocityBlock(*block)
+	return ErrTransferVelocityBlocked
+}
+
+// checkTransferVelocityTx evaluates the window as if the transfer had been made
+func (s *WalletService) checkTransferVelocityTx(tx *gorm.DB, fromWallet, toWallet *models.Wallet, amount int, now time.Time) (*transferVelocityBlock, error) {
+	fromUserID, toUserID := *fromWallet.UserID, *toWallet.UserID
+	newAccountDays := marketIntSetting(tx, walletNewAccountDaysKey, 7)
+	var users []models.User
+	if err := tx.Select("id", "created_at").Where("id IN ?", []uint{fromUserID, toUserID}).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	isNew := map[uint]bool{fromUserID: true, toUserID: true}
+	for _, user := range users {
+		isNew[user.ID] = now.Sub(user.CreatedAt) < time.Duration(newAccountDays)*24*time.Hour
+	}
+	in := transferVelocityInput{SenderNew: isNew[fromUserID], ReceiverNew: isNew[toUserID]}
+	if !in.SenderNew && !in.ReceiverNew {
+		return nil, nil
+	}
+
+	since := now.Add(-walletVelocityWindow)
+	transfers := func(walletID uint, txType models.TransactionType) *gorm.DB {
+		return tx.Model(&models.WalletTransaction{}).
+			Where("wallet_id = ? AND type = ? AND related_wallet_id IS NOT NULL AND created_at >= ?", walletID, txType, since)
+	}
+	var recipients, senders int64
+	if err := transfers(fromWallet.ID, models.TransactionTypeDebit).
+		Where("related_wallet_id <> ?", toWallet.ID).
+		Distinct("related_wallet_id").Count(&recipients).Error; err != nil {
+		return nil, err
+	}
+	if err := transfers(toWallet.ID, models.TransactionTypeCredit).
+		Where("related_wallet_id <> ?", fromWallet.ID).
+		Distinct("related_wallet_id").Count(&senders).Error; err != nil {
+		return nil, err
+	}
+	if err := transfers(fromWallet.ID, models.TransactionTypeCredit).
+		Select("COALESCE(SUM(amount), 0)").Scan(&in.Received).Error; err != nil {
+		return nil, err
+	}
+	if err := transfers(fromWallet.ID, models.TransactionTypeDebit).
+		Select("COALESCE(SUM(amount), 0)").Scan(&in.Sent).Error; err != nil {
+		return nil, err
+	}
+	// Count the transfer being checked
+	in.Recipients = int(recipients) + 1
+	in.Senders = int(senders) + 1
+	in.Sent += amount
+
+	rule := evaluateTransferVelocity(in, transferVelocityRules{
+		MaxRecipients:      marketIntSetting(tx, walletVelocityMaxRecipientsKey, 5),
+		MaxSenders:         marketIntSetting(tx, walletVelocityMaxSendersKey, 5),
+		PassThroughPercent: marketIntSetting(tx, walletVelocityPassThroughPercentKey, 80),
+	})
+	if rule == "" {
+		return nil, nil
+	}
+	return &transferVelocityBlock{
+		FromUserID:   fromUserID,
+		ToUserID:     toUserID,
+		FromWalletID: fromWallet.ID,
+		Amount:       amount,
+		Rule:         rule,
+		Input:        in,
+	}, nil
+}
+
+// recordVelocityBlock writes the audit event and alerts admins through database.DB, not
+// the transfer's transaction
+func (s *WalletService) recordVelocityBlock(block transferVelocityBlock) {
+	in := block.Input
+	event := models.WalletControlEvent{
+		UserID: block.FromUserID,
+		Action: models.WalletControlVelocityBlocked,
+		Reason: block.Rule,
+		Details: fmt.Sprintf("transfer of %d LKM to user %d; 24h: recipients=%d senders_to_receiver=%d received=%d sent=%d new_sender=%t new_receiver=%t",
+			block.Amount, block.ToUserID, in.Recipients, in.Senders, in.Received, in.Sent, in.SenderNew, in.ReceiverNew),
+	}
+	if block.FromWalletID != 0 {
+		event.WalletID = &block.FromWalletID
+	}
+	if err := database.DB.Create(&event).Error; err != nil {
+		log.Printf("[Wallet] Failed to record velocity block for user %d: %v", block.FromUserID, err)
+	}
+	log.Printf("[Wallet] Transfer blocked (%s): %d LKM from user %d to user %d", block.Rule, block.Amount, block.FromUserID, block.ToUserID)
+
+	if err := NewAdminNotificationService(database.DB).CreateNotification(models.AdminNotificationCreateRequest{
+		Type: models.NotificationWalletVelocity,
+		Message: fmt.Sprintf("Перевод %d LKM от пользователя %d пользователю %d заблокирован (%s)",
+			block.Amount, block.FromUserID, block.ToUserID, block.Rule),
+		LinkTo: fmt.Sprintf("/admin/wallet/%d/controls", block.FromUserID),
+	}); err != nil {
+		log.Printf("[Wallet] Failed to notify admins about velocity block for user %d: %v", block.FromUserID, err)
+	}
+}
+
+// ==================== ADMIN CONTROLS ====================
+
+// WalletControlService lets finance admins freeze wallets and tune outgoing limits.
+// Every change is written to WalletControlEvent in the same transaction.
+type WalletControlService struct {
+	db     *gorm.DB
+	wallet *WalletService
+	now    func() time.Time
+}
+
+func NewWalletControlService(walletService *WalletService) *WalletControlService {
+	if walletService == nil {
+		walletService = NewWalletService()
+	}
+	return &WalletControlService{db: database.DB, wallet: walletService, now: time.Now}
+}
+
+// WalletControlsResponse is the admin view of one user's wallet controls
+type WalletControlsResponse struct {
+	UserID               uint                        `json:"userId"`
+	WalletID             uint                        `json:"walletId"`
+	SpendingFrozen       bool                        `json:"spendingFrozen"`
+	SpendingFrozenAt     *time.Time                  `json:"spendingFrozenAt,omitempty"`
+	SpendingFrozenReason string                      `json:"spendingFrozenReason,omitempty"`
+	Limits               WalletLimits                `json:"limits"`
+	Override             *models.WalletLimitOverride `json:"override,omitempty"`
+	Events               []models.WalletControlEvent `json:"events"`
+}
+
+// WalletLimitOverrideRequest replaces the user's override; with no tier and no limits
+// the override is removed and the age-based tier applies again
+type WalletLimitOverrideRequest struct {
+	TierName     string `json:"tierName"`
+	DailyLimit   *int   `json:"dailyLimit"`
+	MonthlyLimit *int   `json:"monthlyLimit"`
+	Reason       string `json:"reason"`
+}
+
+// WalletLimitTierInput creates or updates a tier by name
+type WalletLimitTierInput struct {
+	Name              string `json:"name"`
+	MinAccountAgeDays int    `json:"minAccountAgeDays"`
+	DailyLimit        int    `json:"dailyLimit"`
+	MonthlyLimit      int    `json:"monthlyLimit"`
+	SortOrder         int    `json:"sortOrder"`
+	IsEnabled         *bool  `json:"isEnabled"`
+}
+
+func (s *WalletControlService) GetControls(userID uint) (*WalletControlsResponse, error) {
+	wallet, err := s.wallet.GetOrCreateWallet(userID)
+	if err != nil {
+		return nil, err
+	}
+	limits, err := s.wallet.walletLimitsTx(s.db, userID, wallet.ID, s.now())
+	if err != nil {
+		return nil, err
+	}
+	resp := &WalletControlsResponse{
+		UserID:               userID,
+		WalletID:             wallet.ID,
+		SpendingFrozen:       wallet.SpendingFrozen,
+		SpendingFrozenAt:     wallet.SpendingFrozenAt,
+		SpendingFrozenReason: wallet.SpendingFrozenReason,
+		Limits:               limits,
+	}
+	var override models.WalletLimitOverride
+	if err := s.db.Where("user_id = ?", userID).First(&override).Error; err == nil {
+		resp.Override = &override
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if resp.Events, err = s.ListEvents(userID, walletControlEventsRecentForResponse); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Freeze blocks every outgoing payment of the wallet; credits, refunds and releases of
+// funds held before the freeze still go through
+func (s *WalletControlService) Freeze(adminID, userID uint, reason string) (*WalletControlsResponse, error) {
+	return s.setFrozen(adminID, userID, reason, true)
+}
+
+func (s *WalletControlService) Unfreeze(adminID, userID uint, reason string) (*WalletControlsResponse, error) {
+	return s.setFrozen(adminID, userID, reason, false)
+}
+
+func (s *WalletControlService) setFrozen(adminID, userID uint, reason string, frozen bool) (*WalletControlsResponse, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrWalletControlReasonRequired
+	}
+	now := s.now()
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		wallet, err := s.wallet.getOrCreateLockedWalletTx(tx, userID)
+		if err != nil {
+			return err
+		}
+		if wallet.SpendingFrozen == frozen {
+			if frozen {
+				return ErrWalletAlreadyFrozen
+			}
+			return ErrWalletNotFrozen
+		}
+
+		updates := map[string]interface{}{
+			"spending_frozen":        frozen,
+			"spending_frozen_at":     nil,
+			"spending_frozen_reason": "",
+		}
+		action := models.WalletControlUnfreeze
+		if frozen {
+			updates["spending_frozen_at"] = now
+			updates["spending_frozen_reason"] = reason
+			action = models.WalletControlFreeze
+		}
+		if err := tx.Model(wallet).Updates(updates).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.WalletControlEvent{
+			UserID:   userID,
+			WalletID: &wallet.ID,
+			AdminID:  &adminID,
+			Action:   action,
+			Reason:   reason,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[Wallet] Wallet of user %d frozen=%t by admin %d (reason: %s)", userID, frozen, adminID, reason)
+	return s.GetControls(userID)
+}
+
+func (s *WalletControlService) SetLimitOverride(adminID, userID uint, req WalletLimitOverrideRequest) (*WalletControlsResponse, error) {
+	req.TierName = strings.TrimSpace(req.TierName)
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		return nil, ErrWalletControlReasonRequired
+	}
+	if (req.DailyLimit != nil && *req.DailyLimit < 0) || (req.MonthlyLimit != nil && *req.MonthlyLimit < 0) {
+		return nil, ErrWalletLimitInvalid
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if req.TierName != "" {
+			var count int64
+			if err := tx.Model(&models.WalletLimitTier{}).
+				Where("name = ? AND is_enabled = true", req.TierName).Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				return ErrWalletLimitTierNotFound
+			}
+		}
+
+		details := "override removed"
+		if req.TierName == "" && req.DailyLimit == nil && req.MonthlyLimit == nil {
+			if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.WalletLimitOverride{}).Error; err != nil {
+				return err
+			}
+		} else {
+			override := models.WalletLimitOverride{UserID: userID}
+			if err := tx.Where("user_id = ?", userID).FirstOrInit(&override).Error; err != nil {
+				return err
+			}
+			override.TierName = req.TierName
+			override.DailyLimit = req.DailyLimit
+			override.MonthlyLimit = req.MonthlyLimit
+			override.Note = req.Reason
+			override.UpdatedByID = &adminID
+			if err := tx.Save(&override).Error; err != nil {
+				return err
+			}
+			details = fmt.Sprintf("tier=%q daily=%s monthly=%s", req.TierName,
+				formatOptionalLimit(req.DailyLimit), formatOptionalLimit(req.MonthlyLimit))
+		}
+
+		return tx.Create(&models.WalletControlEvent{
+			UserID:  userID,
+			AdminID: &adminID,
+			Action:  models.WalletControlLimitsUpdated,
+			Reason:  req.Reason,
+			Details: details,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetControls(userID)
+}
+
+func formatOptionalLimit(limit *int) string {
+	if limit == nil {
+		return "tier"
+	}
+	if *limit == 0 {
+		return "unlimited"
+	}
+	return fmt.Sprint(*limit)
+}
+
+// ListEvents returns the newest control events, of one user or of everyone (userID 0)
+func (s *WalletControlService) ListEvents(userID uint, limit int) ([]models.WalletControlEvent, error) {
+	if limit <= 0 {
+		limit = walletControlEventsDefaultLimit
+	}
+	query := s.db.Order("created_at DESC, id DESC").Limit(limit)
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	events := []models.WalletControlEvent{}
+	if err := query.Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (s *WalletControlService) ListTiers() ([]models.WalletLimitTier, error) {
+	tiers := []models.WalletLimitTier{}
+	if err := s.db.Order("sort_order ASC, min_account_age_days ASC").Find(&tiers).Error; err != nil {
+		return nil, err
+	}
+	return tiers, nil
+}
+
+func (s *WalletControlService) UpsertTier(input WalletLimitTierInput) (*models.WalletLimitTier, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, ErrWalletLimitTierNameRequired
+	}
+	if input.MinAccountAgeDays < 0 || input.DailyLimit < 0 || input.MonthlyLimit < 0 {
+		return nil, ErrWalletLimitInvalid
+	}
+	isEnabled := true
+	if input.IsEnabled != nil {
+		isEnabled = *input.IsEnabled
+	}
+
+	var tier models.WalletLimitTier
+	if err := s.db.Where("name = ?", name).
+		Assign(map[string]interface{}{
+			"min_account_age_days": input.MinAccountAgeDays,
+			"daily_limit":          input.DailyLimit,
+			"monthly_limit":        input.MonthlyLimit,
+			"sort_order":           input.SortOrder,
+			"is_enabled":           isEnabled,
+		}).
+		FirstOrCreate(&tier, models.WalletLimitTier{Name: name}).Error; err != nil {
+		return nil, err
+	}
+	return &tier, nil
+}
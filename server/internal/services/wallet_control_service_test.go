@@ -0,0 +1,147 @@
+package services
+
+import (
+	"errors"
+	"rag-agent-server/internal/models"
+	"testing"
+	"time"
+)
+
+func TestResolveWalletLimits(t *testing.T) {
+	t.Parallel()
+
+	day := 24 * time.Hour
+	intPtr := func(v int) *int { return &v }
+	tiers := []models.WalletLimitTier{
+		{Name: "new_account", MinAccountAgeDays: 0, DailyLimit: 2000, MonthlyLimit: 10000, IsEnabled: true},
+		{Name: "established", MinAccountAgeDays: 30, DailyLimit: 20000, MonthlyLimit: 100000, IsEnabled: true},
+		{Name: "trusted", MinAccountAgeDays: 180, DailyLimit: 100000, IsEnabled: true},
+		{Name: "disabled", MinAccountAgeDays: 10, DailyLimit: 1, MonthlyLimit: 1, IsEnabled: false},
+	}
+
+	tests := []struct {
+		name        string
+		override    *models.WalletLimitOverride
+		age         time.Duration
+		wantTier    string
+		wantDaily   int
+		wantMonthly int
+	}{
+		{name: "new account", age: 2 * day, wantTier: "new_account", wantDaily: 2000, wantMonthly: 10000},
+		{name: "disabled tier skipped", age: 12 * day, wantTier: "new_account", wantDaily: 2000, wantMonthly: 10000},
+		{name: "oldest reached tier wins", age: 400 * day, wantTier: "trusted", wantDaily: 100000, wantMonthly: 0},
+		{
+			name:     "override pins tier",
+			override: &models.WalletLimitOverride{TierName: "new_account"},
+			age:      400 * day, wantTier: "new_account", wantDaily: 2000, wantMonthly: 10000,
+		},
+		{
+			name:     "override replaces single limit",
+			override: &models.WalletLimitOverride{DailyLimit: intPtr(500)},
+			age:      40 * day, wantTier: "established", wantDaily: 500, wantMonthly: 100000,
+		},
+		{
+			name:     "override lifts limit",
+			override: &models.WalletLimitOverride{TierName: "established", MonthlyLimit: intPtr(0)},
+			age:      1 * day, wantTier: "established", wantDaily: 20000, wantMonthly: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := resolveWalletLimits(tiers, tt.override, tt.age)
+			if got.TierName != tt.wantTier || got.DailyLimit != tt.wantDaily || got.MonthlyLimit != tt.wantMonthly {
+				t.Fatalf("resolveWalletLimits() = %+v, want tier=%s daily=%d monthly=%d",
+					got, tt.wantTier, tt.wantDaily, tt.wantMonthly)
+			}
+			if got.Overridden != (tt.override != nil) {
+				t.Fatalf("Overridden = %t, want %t", got.Overridden, tt.override != nil)
+			}
+		})
+	}
+}
+
+func TestCheckSpendingLimit(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		limits  WalletLimits
+		amount  int
+		wantErr error
+	}{
+		{name: "unlimited", limits: WalletLimits{DailyUsed: 1e6}, amount: 1e6},
+		{name: "fits exactly", limits: WalletLimits{DailyLimit: 1000, DailyUsed: 600, MonthlyLimit: 5000}, amount: 400},
+		{name: "over daily", limits: WalletLimits{DailyLimit: 1000, DailyUsed: 600}, amount: 401, wantErr: ErrSpendingLimitExceeded},
+		{name: "over monthly", limits: WalletLimits{DailyLimit: 1000, MonthlyLimit: 5000, MonthlyUsed: 4900}, amount: 200, wantErr: ErrSpendingLimitExceeded},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if err := checkSpendingLimit(tt.limits, tt.amount); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("checkSpendingLimit() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEvaluateTransferVelocity(t *testing.T) {
+	t.Parallel()
+
+	rules := transferVelocityRules{MaxRecipients: 5, MaxSenders: 5, PassThroughPercent: 80}
+	tests := []struct {
+		name string
+		in   transferVelocityInput
+		want string
+	}{
+		{name: "established accounts ignore fan-out", in: transferVelocityInput{Recipients: 20}, want: ""},
+		{name: "new sender within limit", in: transferVelocityInput{SenderNew: true, Recipients: 5}, want: ""},
+		{name: "new sender fans out", in: transferVelocityInput{SenderNew: true, Recipients: 6}, want: transferVelocityFanOut},
+		{name: "new receiver fans in", in: transferVelocityInput{ReceiverNew: true, Senders: 6}, want: transferVelocityFanIn},
+		{
+			name: "forwarding to new account",
+			in:   transferVelocityInput{SenderNew: true, ReceiverNew: true, Recipients: 1, Received: 1000, Sent: 950},
+			want: transferVelocityPassThrough,
+		},
+		{
+			name: "forwarding to established account",
+			in:   transferVelocityInput{SenderNew: true, Recipients: 1, Received: 1000, Sent: 950},
+			want: "",
+		},
+		{
+			name: "small gift received, own money sent",
+			in:   transferVelocityInput{SenderNew: true, ReceiverNew: true, Recipients: 1, Received: 50, Sent: 900},
+			want: "",
+		},
+		{
+			name: "nothing received",
+			in:   transferVelocityInput{SenderNew: true, ReceiverNew: true, Recipients: 1, Sent: 900},
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := evaluateTransferVelocity(tt.in, rules); got != tt.want {
+				t.Fatalf("evaluateTransferVelocity(%+v) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWalletChargebackDedupKey(t *testing.T) {
+	t.Parallel()
+
+	if got := walletChargebackDedupKey(orderHoldRef(7)); got != "chargeback:order:7" {
+		t.Fatalf("order chargeback key = %q", got)
+	}
+	if got := walletChargebackDedupKey(bookingHoldRef(3)); got != "chargeback:booking:3" {
+		t.Fatalf("booking chargeback key = %q", got)
+	}
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"errors"
+	"fmt"
 	"log"
 	"rag-agent-server/internal/database"
 	"rag-agent-server/internal/models"
@@ -12,6 +13,16 @@ import (
 	"gorm.io/gorm/clause"
 )
 
+// ErrInsufficientBalance is returned when the active balance cannot cover a debit
+var ErrInsufficientBalance = errors.New("insufficient balance")
+
+// Welcome bonus transactions are the only bonus rows that touch PendingBalance, so the
+// ledger audit recognizes them by description
+const (
+	walletWelcomePendingDescription   = "Welcome Bonus (Pending activation)"
+	walletWelcomeActivatedDescription = "Welcome Bonus Activated"
+)
+
 // WalletService handles wallet operations
 type WalletService struct{}
 
@@ -206,7 +217,7 @@ func (s *WalletService) getOrCreateWalletTx(tx *gorm.DB, userID uint) (*models.W
 		Type:         models.TransactionTypeBonus,
 		Amount:       50,
 		BonusAmount:  50,
-		Description:  "Welcome Bonus (Pending activation)",
+		Description:  walletWelcomePendingDescription,
 		BalanceAfter: 0,
 	}
 	if err := tx.Create(&welcomeTx).Error; err != nil {
@@ -285,7 +296,7 @@ func (s *WalletService) GetOrCreateWallet(userID uint) (*models.Wallet, error) {
 			Type:         models.TransactionTypeBonus,
 			Amount:       50,
 			BonusAmount:  50,
-			Description:  "Welcome Bonus (Pending activation)",
+			Description:  walletWelcomePendingDescription,
 			BalanceAfter: 0, // Active balance is still 0
 		}
 		if txErr := tx.Create(&welcomeTx).Error; txErr != nil {
@@ -322,6 +333,7 @@ func (s *WalletService) GetBalance(userID uint) (*models.WalletResponse, error)
 		PendingBalance:     wallet.PendingBalance,
 		FrozenBalance:      wallet.FrozenBalance,
 		FrozenBonusBalance: wallet.FrozenBonusBalance,
+		SpendingFrozen:     wallet.SpendingFrozen,
 		Currency:           "LKM",
 		CurrencyName:       "LakshMoney",
 		TotalEarned:        wallet.TotalEarned,
@@ -331,84 +343,128 @@ func (s *WalletService) GetBalance(userID uint) (*models.WalletResponse, error)
 
 // Transfer transfers Лакшми from one wallet to another
 func (s *WalletService) Transfer(fromUserID, toUserID uint, amount int, description string, bookingID *uint) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		_, err := s.transferTx(tx, fromUserID, toUserID, amount, "", description, bookingID)
+		return err
+	})
+}
+
+// transferTx moves regular LKM between users inside the caller's transaction. With a
+// dedupKey a repeated call is a no-op and reports duplicate=true. The sender's freeze,
+// limits and the velocity checks for new accounts apply.
+func (s *WalletService) transferTx(tx *gorm.DB, fromUserID, toUserID uint, amount int, dedupKey string, description string, bookingID *uint) (bool, error) {
+	return s.moveRegularTx(tx, fromUserID, toUserID, amount, dedupKey, description, bookingID, true)
+}
+
+// returnTransferTx is transferTx for giving a payer their money back: a freeze or the
+// limits of the returning wallet must not keep the refund from the customer.
+func (s *WalletService) returnTransferTx(tx *gorm.DB, fromUserID, toUserID uint, amount int, dedupKey string, description string) (bool, error) {
+	return s.moveRegularTx(tx, fromUserID, toUserID, amount, dedupKey, description, nil, false)
+}
+
+func (s *WalletService) moveRegularTx(tx *gorm.DB, fromUserID, toUserID uint, amount int, dedupKey string, description string, bookingID *uint, enforceControls bool) (bool, error) {
 	if amount <= 0 {
-		return errors.New("amount must be positive")
+		return false, errors.New("amount must be positive")
 	}
 
 	if fromUserID == toUserID {
-		return errors.New("cannot transfer to yourself")
+		return false, errors.New("cannot transfer to yourself")
 	}
+	dedupKey = strings.TrimSpace(dedupKey)
 	description = strings.TrimSpace(description)
 	if description == "" {
 		description = "Transfer"
 	}
 
-	return database.DB.Transaction(func(tx *gorm.DB) error {
-		// Lock sender's wallet to prevent concurrent overspend.
-		fromWallet, err := s.getOrCreateLockedWalletTx(tx, fromUserID)
-		if err != nil {
-			return err
-		}
+	// Lock sender's wallet to prevent concurrent overspend.
+	fromWallet, err := s.getOrCreateLockedWalletTx(tx, fromUserID)
+	if err != nil {
+		return false, err
+	}
 
-		if fromWallet.Balance < amount {
-			return errors.New("insufficient balance")
+	if dedupKey != "" {
+		var existing int64
+		if err := tx.Model(&models.WalletTransaction{}).
+			Where("wallet_id = ? AND dedup_key = ? AND type = ?", fromWallet.ID, dedupKey, models.TransactionTypeDebit).
+			Count(&existing).Error; err != nil {
+			return false, err
 		}
-
-		// Lock receiver's wallet (or create if doesn't exist).
-		toWallet, err := s.getOrCreateLockedWalletTx(tx, toUserID)
-		if err != nil {
-			return err
+		if existing > 0 {
+			log.Printf("[Wallet] Duplicate transfer blocked: wallet=%d dedup=%s", fromWallet.ID, dedupKey)
+			return true, nil
 		}
+	}
 
-		// Debit from sender
-		newFromBalance := fromWallet.Balance - amount
-		if err := tx.Model(fromWallet).Updates(map[string]interface{}{
-			"balance":     newFromBalance,
-			"total_spent": fromWallet.TotalSpent + amount,
-		}).Error; err != nil {
-			return err
+	if enforceControls {
+		if err := s.enforceOutgoingTx(tx, fromWallet, amount); err != nil {
+			return false, err
 		}
+	}
+	if fromWallet.Balance < amount {
+		return false, ErrInsufficientBalance
+	}
 
-		// Credit to receiver
-		newToBalance := toWallet.Balance + amount
-		if err := tx.Model(toWallet).Updates(map[string]interface{}{
-			"balance":      newToBalance,
-			"total_earned": toWallet.TotalEarned + amount,
-		}).Error; err != nil {
-			return err
+	// Lock receiver's wallet (or create if doesn't exist).
+	toWallet, err := s.getOrCreateLockedWalletTx(tx, toUserID)
+	if err != nil {
+		return false, err
+	}
+	if enforceControls {
+		if err := s.enforceTransferVelocityTx(tx, fromWallet, toWallet, amount); err != nil {
+			return false, err
 		}
+	}
 
-		// Record debit transaction
-		debitTx := models.WalletTransaction{
-			WalletID:        fromWallet.ID,
-			Type:            models.TransactionTypeDebit,
-			Amount:          amount,
-			Description:     description,
-			BookingID:       bookingID,
-			RelatedWalletID: &toWallet.ID,
-			BalanceAfter:    newFromBalance,
-		}
-		if err := tx.Create(&debitTx).Error; err != nil {
-			return err
-		}
+	// Debit from sender
+	newFromBalance := fromWallet.Balance - amount
+	if err := tx.Model(fromWallet).Updates(map[string]interface{}{
+		"balance":     newFromBalance,
+		"total_spent": fromWallet.TotalSpent + amount,
+	}).Error; err != nil {
+		return false, err
+	}
 
-		// Record credit transaction
-		creditTx := models.WalletTransaction{
-			WalletID:        toWallet.ID,
-			Type:            models.TransactionTypeCredit,
-			Amount:          amount,
-			Description:     description,
-			BookingID:       bookingID,
-			RelatedWalletID: &fromWallet.ID,
-			BalanceAfter:    newToBalance,
-		}
-		if err := tx.Create(&creditTx).Error; err != nil {
-			return err
-		}
+	// Credit to receiver
+	newToBalance := toWallet.Balance + amount
+	if err := tx.Model(toWallet).Updates(map[string]interface{}{
+		"balance":      newToBalance,
+		"total_earned": toWallet.TotalEarned + amount,
+	}).Error; err != nil {
+		return false, err
+	}
 
-		log.Printf("[Wallet] Transfer: %d LKM from user %d to user %d", amount, fromUserID, toUserID)
-		return nil
-	})
+	// Record debit transaction
+	debitTx := models.WalletTransaction{
+		WalletID:        fromWallet.ID,
+		Type:            models.TransactionTypeDebit,
+		Amount:          amount,
+		Description:     description,
+		BookingID:       bookingID,
+		RelatedWalletID: &toWallet.ID,
+		BalanceAfter:    newFromBalance,
+		DedupKey:        dedupKey,
+	}
+	if err := tx.Create(&debitTx).Error; err != nil {
+		return false, err
+	}
+
+	// Record credit transaction
+	creditTx := models.WalletTransaction{
+		WalletID:        toWallet.ID,
+		Type:            models.TransactionTypeCredit,
+		Amount:          amount,
+		Description:     description,
+		BookingID:       bookingID,
+		RelatedWalletID: &fromWallet.ID,
+		BalanceAfter:    newToBalance,
+		DedupKey:        dedupKey,
+	}
+	if err := tx.Create(&creditTx).Error; err != nil {
+		return false, err
+	}
+
+	log.Printf("[Wallet] Transfer: %d LKM from user %d to user %d", amount, fromUserID, toUserID)
+	return false, nil
 }
 
 // AddBonus adds bonus Лакшми to user's wallet
@@ -784,6 +840,9 @@ func (s *WalletService) spendTxWithOptions(tx *gorm.DB, userID uint, amount int,
 	if allocErr != nil {
 		return SpendAllocation{}, false, allocErr
 	}
+	if err := s.enforceOutgoingTx(tx, wallet, allocation.RegularAmount); err != nil {
+		return SpendAllocation{}, false, err
+	}
 	if wallet.Balance < allocation.RegularAmount || wallet.BonusBalance < allocation.BonusAmount {
 		return SpendAllocation{}, false, errors.New("insufficient balance")
 	}
@@ -940,7 +999,7 @@ func (s *WalletService) ActivatePendingBalance(userID uint) error {
 			Type:         models.TransactionTypeBonus,
 			Amount:       pendingAmount,
 			BonusAmount:  pendingAmount,
-			Description:  "Welcome Bonus Activated",
+			Description:  walletWelcomeActivatedDescription,
 			BalanceAfter: wallet.Balance,
 		}
 		if err := tx.Create(&activateTx).Error; err != nil {
@@ -964,6 +1023,30 @@ func (s *WalletService) ActivatePendingBalance(userID uint) error {
 	return nil
 }
 
+// holdRef links hold, release and refund transactions to the booking or order they secure
+type holdRef struct {
+	BookingID *uint
+	OrderID   *uint
+}
+
+func bookingHoldRef(bookingID uint) holdRef {
+	return holdRef{BookingID: &bookingID}
+}
+
+func orderHoldRef(orderID uint) holdRef {
+	return holdRef{OrderID: &orderID}
+}
+
+func (r holdRef) String() string {
+	if r.OrderID != nil {
+		return fmt.Sprintf("order %d", *r.OrderID)
+	}
+	if r.BookingID != nil {
+		return fmt.Sprintf("booking %d", *r.BookingID)
+	}
+	return "no reference"
+}
+
 // HoldFunds freezes funds for a booking (not spent yet)
 func (s *WalletService) HoldFunds(userID uint, amount int, bookingID uint, description string) error {
 	_, err := s.HoldFundsWithOptions(userID, amount, bookingID, description, SpendOptions{
@@ -974,8 +1057,22 @@ func (s *WalletService) HoldFunds(userID uint, amount int, bookingID uint, descr
 
 // HoldFundsWithOptions freezes funds for a booking with optional bonus usage limits.
 func (s *WalletService) HoldFundsWithOptions(userID uint, amount int, bookingID uint, description string, opts SpendOptions) (*SpendAllocation, error) {
+	var allocation SpendAllocation
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		allocation, err = s.holdFundsTx(tx, userID, amount, bookingHoldRef(bookingID), description, opts)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return &allocation, nil
+}
+
+// holdFundsTx moves funds from the active balances to the frozen ones inside the caller's transaction.
+func (s *WalletService) holdFundsTx(tx *gorm.DB, userID uint, amount int, ref holdRef, description string, opts SpendOptions) (SpendAllocation, error) {
 	if amount <= 0 {
-		return nil, errors.New("amount must be positive")
+		return SpendAllocation{}, errors.New("amount must be positive")
 	}
 	opts = normalizeSpendOptions(opts)
 	description = strings.TrimSpace(description)
@@ -983,61 +1080,57 @@ func (s *WalletService) HoldFundsWithOptions(userID uint, amount int, bookingID
 		description = "Funds hold"
 	}
 
-	var allocation SpendAllocation
-	if err := database.DB.Transaction(func(tx *gorm.DB) error {
-		var wallet models.Wallet
-		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
-			Where("user_id = ?", userID).First(&wallet).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return errors.New("wallet not found")
-			}
-			return err
-		}
-
-		currentAllocation, allocErr := calculateSpendAllocation(amount, wallet.Balance, wallet.BonusBalance, opts)
-		if allocErr != nil {
-			return allocErr
-		}
-		if wallet.Balance < currentAllocation.RegularAmount || wallet.BonusBalance < currentAllocation.BonusAmount {
-			return errors.New("insufficient balance")
+	var wallet models.Wallet
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ?", userID).First(&wallet).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return SpendAllocation{}, errors.New("wallet not found")
 		}
+		return SpendAllocation{}, err
+	}
 
-		newBalance := wallet.Balance - currentAllocation.RegularAmount
-		newBonusBalance := wallet.BonusBalance - currentAllocation.BonusAmount
-		newFrozen := wallet.FrozenBalance + currentAllocation.RegularAmount
-		newFrozenBonus := wallet.FrozenBonusBalance + currentAllocation.BonusAmount
-
-		if err := tx.Model(&wallet).Updates(map[string]interface{}{
-			"balance":              newBalance,
-			"bonus_balance":        newBonusBalance,
-			"frozen_balance":       newFrozen,
-			"frozen_bonus_balance": newFrozenBonus,
-		}).Error; err != nil {
-			return err
-		}
+	allocation, allocErr := calculateSpendAllocation(amount, wallet.Balance, wallet.BonusBalance, opts)
+	if allocErr != nil {
+		return SpendAllocation{}, allocErr
+	}
+	if err := s.enforceOutgoingTx(tx, &wallet, allocation.RegularAmount); err != nil {
+		return SpendAllocation{}, err
+	}
+	if wallet.Balance < allocation.RegularAmount || wallet.BonusBalance < allocation.BonusAmount {
+		return SpendAllocation{}, errors.New("insufficient balance")
+	}
 
-		// Record hold
-		holdTx := models.WalletTransaction{
-			WalletID:     wallet.ID,
-			Type:         models.TransactionTypeHold,
-			Amount:       amount,
-			BonusAmount:  currentAllocation.BonusAmount,
-			Description:  description,
-			BookingID:    &bookingID,
-			BalanceAfter: newBalance,
-		}
-		if err := tx.Create(&holdTx).Error; err != nil {
-			return err
-		}
+	newBalance := wallet.Balance - allocation.RegularAmount
+	newBonusBalance := wallet.BonusBalance - allocation.BonusAmount
+	newFrozen := wallet.FrozenBalance + allocation.RegularAmount
+	newFrozenBonus := wallet.FrozenBonusBalance + allocation.BonusAmount
+
+	if err := tx.Model(&wallet).Updates(map[string]interface{}{
+		"balance":              newBalance,
+		"bonus_balance":        newBonusBalance,
+		"frozen_balance":       newFrozen,
+		"frozen_bonus_balance": newFrozenBonus,
+	}).Error; err != nil {
+		return SpendAllocation{}, err
+	}
 
-		allocation = currentAllocation
-		log.Printf("[Wallet] Hold: %d LKM from user %d for booking %d (bonus=%d)", amount, userID, bookingID, currentAllocation.BonusAmount)
-		return nil
-	}); err != nil {
-		return nil, err
+	// Record hold
+	holdTx := models.WalletTransaction{
+		WalletID:     wallet.ID,
+		Type:         models.TransactionTypeHold,
+		Amount:       amount,
+		BonusAmount:  allocation.BonusAmount,
+		Description:  description,
+		BookingID:    ref.BookingID,
+		OrderID:      ref.OrderID,
+		BalanceAfter: newBalance,
+	}
+	if err := tx.Create(&holdTx).Error; err != nil {
+		return SpendAllocation{}, err
 	}
 
-	return &allocation, nil
+	log.Printf("[Wallet] Hold: %d LKM from user %d for %s (bonus=%d)", amount, userID, ref, allocation.BonusAmount)
+	return allocation, nil
 }
 
 // ReleaseFunds releases held funds to provider or back to user
@@ -1047,6 +1140,14 @@ func (s *WalletService) ReleaseFunds(userID uint, amount int, bookingID uint, to
 
 // ReleaseFundsWithSplit releases regular+bonus frozen funds to provider.
 func (s *WalletService) ReleaseFundsWithSplit(userID uint, regularAmount int, bonusAmount int, bookingID uint, toUserID uint, description string) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		return s.releaseFundsTx(tx, userID, regularAmount, bonusAmount, bookingHoldRef(bookingID), toUserID, 0, description)
+	})
+}
+
+// releaseFundsTx pays frozen funds out to the recipient. feeAmount is kept by the platform
+// wallet and is not credited to the recipient.
+func (s *WalletService) releaseFundsTx(tx *gorm.DB, userID uint, regularAmount int, bonusAmount int, ref holdRef, toUserID uint, feeAmount int, description string) error {
 	if regularAmount < 0 || bonusAmount < 0 {
 		return errors.New("amount must be non-negative")
 	}
@@ -1054,81 +1155,184 @@ func (s *WalletService) ReleaseFundsWithSplit(userID uint, regularAmount int, bo
 	if totalAmount <= 0 {
 		return errors.New("amount must be positive")
 	}
+	if feeAmount < 0 || feeAmount > totalAmount {
+		return errors.New("invalid fee amount")
+	}
 	description = strings.TrimSpace(description)
 	if description == "" {
 		description = "Funds release"
 	}
 
-	return database.DB.Transaction(func(tx *gorm.DB) error {
-		var wallet models.Wallet
-		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
-			Where("user_id = ?", userID).First(&wallet).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return errors.New("wallet not found")
-			}
-			return err
+	var wallet models.Wallet
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ?", userID).First(&wallet).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("wallet not found")
 		}
+		return err
+	}
 
-		if wallet.FrozenBalance < regularAmount || wallet.FrozenBonusBalance < bonusAmount {
-			return errors.New("insufficient frozen balance")
-		}
+	if wallet.FrozenBalance < regularAmount || wallet.FrozenBonusBalance < bonusAmount {
+		return errors.New("insufficient frozen balance")
+	}
 
-		// Reduce frozen balance
-		newFrozen := wallet.FrozenBalance - regularAmount
-		newFrozenBonus := wallet.FrozenBonusBalance - bonusAmount
-		if err := tx.Model(&wallet).Updates(map[string]interface{}{
-			"frozen_balance":       newFrozen,
-			"frozen_bonus_balance": newFrozenBonus,
-			"total_spent":          wallet.TotalSpent + totalAmount,
-		}).Error; err != nil {
-			return err
-		}
+	// Reduce frozen balance
+	newFrozen := wallet.FrozenBalance - regularAmount
+	newFrozenBonus := wallet.FrozenBonusBalance - bonusAmount
+	if err := tx.Model(&wallet).Updates(map[string]interface{}{
+		"frozen_balance":       newFrozen,
+		"frozen_bonus_balance": newFrozenBonus,
+		"total_spent":          wallet.TotalSpent + totalAmount,
+	}).Error; err != nil {
+		return err
+	}
 
-		// Record release from sender
-		releaseTx := models.WalletTransaction{
-			WalletID:     wallet.ID,
-			Type:         models.TransactionTypeRelease,
-			Amount:       totalAmount,
-			BonusAmount:  bonusAmount,
-			Description:  description,
-			BookingID:    &bookingID,
-			BalanceAfter: wallet.Balance, // Active balance unchanged
-		}
-		if err := tx.Create(&releaseTx).Error; err != nil {
-			return err
-		}
+	// Record release from sender
+	releaseTx := models.WalletTransaction{
+		WalletID:     wallet.ID,
+		Type:         models.TransactionTypeRelease,
+		Amount:       totalAmount,
+		BonusAmount:  bonusAmount,
+		Description:  description,
+		BookingID:    ref.BookingID,
+		OrderID:      ref.OrderID,
+		BalanceAfter: wallet.Balance, // Active balance unchanged
+	}
+	if err := tx.Create(&releaseTx).Error; err != nil {
+		return err
+	}
 
-		// Credit to provider
-		toWallet, err := s.getOrCreateLockedWalletTx(tx, toUserID)
-		if err != nil {
-			return err
-		}
+	// Credit to provider
+	toWallet, err := s.getOrCreateLockedWalletTx(tx, toUserID)
+	if err != nil {
+		return err
+	}
 
-		newToBalance := toWallet.Balance + totalAmount
-		if err := tx.Model(toWallet).Updates(map[string]interface{}{
-			"balance":      newToBalance,
-			"total_earned": toWallet.TotalEarned + totalAmount,
-		}).Error; err != nil {
+	payout := totalAmount - feeAmount
+	newToBalance := toWallet.Balance + payout
+	if err := tx.Model(toWallet).Updates(map[string]interface{}{
+		"balance":      newToBalance,
+		"total_earned": toWallet.TotalEarned + payout,
+	}).Error; err != nil {
+		return err
+	}
+
+	// Record credit to provider
+	creditTx := models.WalletTransaction{
+		WalletID:        toWallet.ID,
+		Type:            models.TransactionTypeCredit,
+		Amount:          payout,
+		Description:     description,
+		BookingID:       ref.BookingID,
+		OrderID:         ref.OrderID,
+		RelatedWalletID: &wallet.ID,
+		BalanceAfter:    newToBalance,
+	}
+	if payout > 0 {
+		if err := tx.Create(&creditTx).Error; err != nil {
 			return err
 		}
+	}
 
-		// Record credit to provider
-		creditTx := models.WalletTransaction{
-			WalletID:        toWallet.ID,
-			Type:            models.TransactionTypeCredit,
-			Amount:          totalAmount,
-			Description:     description,
-			BookingID:       &bookingID,
-			RelatedWalletID: &wallet.ID,
-			BalanceAfter:    newToBalance,
-		}
-		if err := tx.Create(&creditTx).Error; err != nil {
+	if feeAmount > 0 {
+		if err := s.creditPlatformFeeTx(tx, feeAmount, ref, wallet.ID, "Комиссия платформы: "+description); err != nil {
 			return err
 		}
+	}
 
-		log.Printf("[Wallet] Release: %d LKM from user %d to user %d (booking %d, bonus=%d)", totalAmount, userID, toUserID, bookingID, bonusAmount)
-		return nil
-	})
+	log.Printf("[Wallet] Release: %d LKM from user %d to user %d (%s, bonus=%d, fee=%d)", totalAmount, userID, toUserID, ref, bonusAmount, feeAmount)
+	return nil
+}
+
+// lockPlatformWalletTx locks the platform wallet, creating it on first use
+func (s *WalletService) lockPlatformWalletTx(tx *gorm.DB) (*models.Wallet, error) {
+	var platform models.Wallet
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("type = ?", models.WalletTypePlatform).First(&platform).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		platform = models.Wallet{Type: models.WalletTypePlatform}
+		err = tx.Create(&platform).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &platform, nil
+}
+
+// creditPlatformFeeTx credits a fee to the platform wallet
+func (s *WalletService) creditPlatformFeeTx(tx *gorm.DB, amount int, ref holdRef, fromWalletID uint, description string) error {
+	platform, err := s.lockPlatformWalletTx(tx)
+	if err != nil {
+		return err
+	}
+
+	newBalance := platform.Balance + amount
+	if err := tx.Model(platform).Updates(map[string]interface{}{
+		"balance":      newBalance,
+		"total_earned": platform.TotalEarned + amount,
+	}).Error; err != nil {
+		return err
+	}
+	return tx.Create(&models.WalletTransaction{
+		WalletID:        platform.ID,
+		Type:            models.TransactionTypeCredit,
+		Amount:          amount,
+		Description:     description,
+		BookingID:       ref.BookingID,
+		OrderID:         ref.OrderID,
+		RelatedWalletID: &fromWalletID,
+		BalanceAfter:    newBalance,
+	}).Error
+}
+
+// chargeBackTx takes back an earlier payout from a locked wallet, e.g. when a disputed
+// order is refunded after its escrow was already released to the seller
+func (s *WalletService) chargeBackTx(tx *gorm.DB, wallet *models.Wallet, amount int, ref holdRef, toWalletID uint, description string) error {
+	if amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+	if wallet.Balance < amount {
+		return errors.New("insufficient balance for chargeback")
+	}
+
+	newBalance := wallet.Balance - amount
+	if err := tx.Model(wallet).Updates(map[string]interface{}{
+		"balance":      newBalance,
+		"total_earned": gorm.Expr("GREATEST(total_earned - ?, 0)", amount),
+	}).Error; err != nil {
+		return err
+	}
+	wallet.Balance = newBalance
+
+	if err := tx.Create(&models.WalletTransaction{
+		WalletID:        wallet.ID,
+		Type:            models.TransactionTypeDebit,
+		Amount:          amount,
+		Description:     description,
+		BookingID:       ref.BookingID,
+		OrderID:         ref.OrderID,
+		RelatedWalletID: &toWalletID,
+		BalanceAfter:    newBalance,
+		DedupKey:        walletChargebackDedupKey(ref),
+	}).Error; err != nil {
+		return err
+	}
+
+	log.Printf("[Wallet] Chargeback: %d LKM from wallet %d (%s)", amount, wallet.ID, ref)
+	return nil
+}
+
+// walletChargebackDedupPrefix marks chargeback debits: they are taken from the user, not
+// spent by them, so spending limits ignore them
+const walletChargebackDedupPrefix = "chargeback:"
+
+func walletChargebackDedupKey(ref holdRef) string {
+	if ref.OrderID != nil {
+		return fmt.Sprintf("%sorder:%d", walletChargebackDedupPrefix, *ref.OrderID)
+	}
+	if ref.BookingID != nil {
+		return fmt.Sprintf("%sbooking:%d", walletChargebackDedupPrefix, *ref.BookingID)
+	}
+	return walletChargebackDedupPrefix
 }
 
 // RefundHold returns held funds back to user's active balance
@@ -1138,6 +1342,12 @@ func (s *WalletService) RefundHold(userID uint, amount int, bookingID uint, desc
 
 // RefundHoldWithSplit returns frozen regular+bonus funds back to original balances.
 func (s *WalletService) RefundHoldWithSplit(userID uint, regularAmount int, bonusAmount int, bookingID uint, description string) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		return s.refundHoldTx(tx, userID, regularAmount, bonusAmount, bookingHoldRef(bookingID), description)
+	})
+}
+
+func (s *WalletService) refundHoldTx(tx *gorm.DB, userID uint, regularAmount int, bonusAmount int, ref holdRef, description string) error {
 	if regularAmount < 0 || bonusAmount < 0 {
 		return errors.New("amount must be non-negative")
 	}
@@ -1150,49 +1360,48 @@ func (s *WalletService) RefundHoldWithSplit(userID uint, regularAmount int, bonu
 		description = "Hold refund"
 	}
 
-	return database.DB.Transaction(func(tx *gorm.DB) error {
-		var wallet models.Wallet
-		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
-			Where("user_id = ?", userID).First(&wallet).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return errors.New("wallet not found")
-			}
-			return err
-		}
-
-		if wallet.FrozenBalance < regularAmount || wallet.FrozenBonusBalance < bonusAmount {
-			return errors.New("insufficient frozen balance")
+	var wallet models.Wallet
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ?", userID).First(&wallet).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("wallet not found")
 		}
+		return err
+	}
 
-		newBalance := wallet.Balance + regularAmount
-		newBonusBalance := wallet.BonusBalance + bonusAmount
-		newFrozen := wallet.FrozenBalance - regularAmount
-		newFrozenBonus := wallet.FrozenBonusBalance - bonusAmount
+	if wallet.FrozenBalance < regularAmount || wallet.FrozenBonusBalance < bonusAmount {
+		return errors.New("insufficient frozen balance")
+	}
 
-		if err := tx.Model(&wallet).Updates(map[string]interface{}{
-			"balance":              newBalance,
-			"bonus_balance":        newBonusBalance,
-			"frozen_balance":       newFrozen,
-			"frozen_bonus_balance": newFrozenBonus,
-		}).Error; err != nil {
-			return err
-		}
+	newBalance := wallet.Balance + regularAmount
+	newBonusBalance := wallet.BonusBalance + bonusAmount
+	newFrozen := wallet.FrozenBalance - regularAmount
+	newFrozenBonus := wallet.FrozenBonusBalance - bonusAmount
+
+	if err := tx.Model(&wallet).Updates(map[string]interface{}{
+		"balance":              newBalance,
+		"bonus_balance":        newBonusBalance,
+		"frozen_balance":       newFrozen,
+		"frozen_bonus_balance": newFrozenBonus,
+	}).Error; err != nil {
+		return err
+	}
 
-		// Record refund
-		refundTx := models.WalletTransaction{
-			WalletID:     wallet.ID,
-			Type:         models.TransactionTypeRefund,
-			Amount:       totalAmount,
-			BonusAmount:  bonusAmount,
-			Description:  description,
-			BookingID:    &bookingID,
-			BalanceAfter: newBalance,
-		}
-		if err := tx.Create(&refundTx).Error; err != nil {
-			return err
-		}
+	// Record refund
+	refundTx := models.WalletTransaction{
+		WalletID:     wallet.ID,
+		Type:         models.TransactionTypeRefund,
+		Amount:       totalAmount,
+		BonusAmount:  bonusAmount,
+		Description:  description,
+		BookingID:    ref.BookingID,
+		OrderID:      ref.OrderID,
+		BalanceAfter: newBalance,
+	}
+	if err := tx.Create(&refundTx).Error; err != nil {
+		return err
+	}
 
-		log.Printf("[Wallet] RefundHold: %d LKM to user %d (booking %d cancelled, bonus=%d)", totalAmount, userID, bookingID, bonusAmount)
-		return nil
-	})
+	log.Printf("[Wallet] RefundHold: %d LKM to user %d (%s cancelled, bonus=%d)", totalAmount, userID, ref, bonusAmount)
+	return nil
 }
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"rag-agent-server/internal/models"
+)
+
+func TestStandingOrderPausesAfterRepeatedFailures_Integration(t *testing.T) {
+	db := setupYatraServiceIntegrationDB(t)
+	if err := db.AutoMigrate(&models.WalletStandingOrder{}); err != nil {
+		t.Fatalf("standing order automigrate failed: %v", err)
+	}
+
+	payer := createYatraIntegrationUser(t, db, "standing-payer")
+	payee := createYatraIntegrationUser(t, db, "standing-payee")
+	now := time.Now().UTC()
+	order := models.WalletStandingOrder{
+		FromUserID: payer.ID,
+		ToUserID:   payee.ID,
+		Amount:     10,
+		Frequency:  models.StandingOrderMonthly,
+		Status:     models.StandingOrderActive,
+		NextRunAt:  now,
+	}
+	if err := db.Create(&order).Error; err != nil {
+		t.Fatalf("create standing order: %v", err)
+	}
+
+	service := NewWalletStandingOrderService(NewWalletService())
+	runErr := errors.New("wallet service unavailable")
+	for i := 1; i <= maxStandingOrderFailures; i++ {
+		paused, err := service.recordFailure(order.ID, runErr, now)
+		if err != nil {
+			t.Fatalf("recordFailure() error = %v", err)
+		}
+		if (paused != nil) != (i == maxStandingOrderFailures) {
+			t.Fatalf("failure %d: paused = %v", i, paused != nil)
+		}
+	}
+
+	var reloaded models.WalletStandingOrder
+	if err := db.First(&reloaded, order.ID).Error; err != nil {
+		t.Fatalf("reload standing order: %v", err)
+	}
+	if reloaded.Status != models.StandingOrderPaused || reloaded.LastError != runErr.Error() ||
+		reloaded.FailureCount != maxStandingOrderFailures || reloaded.PausedAt == nil {
+		t.Fatalf("unexpected order after failures: %+v", reloaded)
+	}
+
+	resumed, err := service.Resume(payer.ID, order.ID)
+	if err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+	if resumed.FailureCount != 0 {
+		t.Fatalf("FailureCount after resume = %d, want 0", resumed.FailureCount)
+	}
+}
@@ -0,0 +1,340 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"rag-agent-server/internal/database"
+	"rag-agent-server/internal/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrStandingOrderNotFound  = errors.New("standing order not found")
+	ErrStandingOrderInvalid   = errors.New("invalid standing order")
+	ErrStandingOrderLimit     = errors.New("too many active standing orders")
+	ErrStandingOrderNotPaused = errors.New("standing order is not paused")
+	ErrStandingOrderFinished  = errors.New("standing order is already finished")
+)
+
+const (
+	maxActiveStandingOrders = 20
+	standingOrderBatchSize  = 100
+	// Unexpected errors are retried on the next pass until this many fail in a row
+	maxStandingOrderFailures = 3
+)
+
+// WalletStandingOrderService schedules and executes recurring LKM transfers
+type WalletStandingOrderService struct {
+	db            *gorm.DB
+	walletService *WalletService
+}
+
+func NewWalletStandingOrderService(walletService *WalletService) *WalletStandingOrderService {
+	return &WalletStandingOrderService{db: database.DB, walletService: walletService}
+}
+
+// Create schedules a new standing order for the payer
+func (s *WalletStandingOrderService) Create(fromUserID uint, req models.StandingOrderCreateRequest) (*models.WalletStandingOrder, error) {
+	now := time.Now().UTC()
+	startAt := now
+	if req.StartAt != nil {
+		startAt = req.StartAt.UTC()
+	}
+	if err := validateStandingOrder(fromUserID, req, startAt, now); err != nil {
+		return nil, err
+	}
+
+	var recipient int64
+	if err := s.db.Model(&models.User{}).Where("id = ?", req.ToUserID).Count(&recipient).Error; err != nil {
+		return nil, err
+	}
+	if recipient == 0 {
+		return nil, fmt.Errorf("%w: recipient not found", ErrStandingOrderInvalid)
+	}
+
+	var active int64
+	if err := s.db.Model(&models.WalletStandingOrder{}).
+		Where("from_user_id = ? AND status IN ?", fromUserID, []models.StandingOrderStatus{models.StandingOrderActive, models.StandingOrderPaused}).
+		Count(&active).Error; err != nil {
+		return nil, err
+	}
+	if active >= maxActiveStandingOrders {
+		return nil, ErrStandingOrderLimit
+	}
+
+	order := models.WalletStandingOrder{
+		FromUserID:  fromUserID,
+		ToUserID:    req.ToUserID,
+		Amount:      req.Amount,
+		Description: strings.TrimSpace(req.Description),
+		Frequency:   req.Frequency,
+		Status:      models.StandingOrderActive,
+		NextRunAt:   startAt,
+		AnchorDay:   startAt.Day(),
+		EndsAt:      req.EndsAt,
+		MaxRuns:     req.MaxRuns,
+	}
+	if order.Frequency == models.StandingOrderOnce {
+		order.MaxRuns = 1
+	}
+	if err := s.db.Create(&order).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func validateStandingOrder(fromUserID uint, req models.StandingOrderCreateRequest, startAt, now time.Time) error {
+	switch {
+	case req.ToUserID == 0 || req.ToUserID == fromUserID:
+		return fmt.Errorf("%w: choose another recipient", ErrStandingOrderInvalid)
+	case req.Amount <= 0:
+		return fmt.Errorf("%w: amount must be positive", ErrStandingOrderInvalid)
+	case req.MaxRuns < 0:
+		return fmt.Errorf("%w: maxRuns must not be negative", ErrStandingOrderInvalid)
+	case startAt.Before(now.Add(-time.Minute)):
+		return fmt.Errorf("%w: start time is in the past", ErrStandingOrderInvalid)
+	case req.EndsAt != nil && !req.EndsAt.After(startAt):
+		return fmt.Errorf("%w: end time must be after the start", ErrStandingOrderInvalid)
+	}
+	switch req.Frequency {
+	case models.StandingOrderOnce, models.StandingOrderWeekly, models.StandingOrderMonthly:
+		return nil
+	default:
+		return fmt.Errorf("%w: frequency must be once, weekly or monthly", ErrStandingOrderInvalid)
+	}
+}
+
+// List returns the payer's standing orders, active ones first
+func (s *WalletStandingOrderService) List(fromUserID uint, status models.StandingOrderStatus) ([]models.WalletStandingOrder, error) {
+	query := s.db.Preload("ToUser", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "spiritual_name", "karmic_name", "avatar_url")
+	}).Where("from_user_id = ?", fromUserID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	orders := []models.WalletStandingOrder{}
+	if err := query.Order("CASE WHEN status = 'active' THEN 0 WHEN status = 'paused' THEN 1 ELSE 2 END, next_run_at ASC").
+		Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// Cancel stops a standing order; executed transfers are not reverted
+func (s *WalletStandingOrderService) Cancel(fromUserID, orderID uint) (*models.WalletStandingOrder, error) {
+	return s.update(fromUserID, orderID, func(order *models.WalletStandingOrder, now time.Time) error {
+		if order.Status == models.StandingOrderCancelled || order.Status == models.StandingOrderCompleted {
+			return ErrStandingOrderFinished
+		}
+		order.Status = models.StandingOrderCancelled
+		order.CancelledAt = &now
+		return nil
+	})
+}
+
+// Resume reactivates a paused standing order. The missed run is retried on the next
+// worker pass under the same dedup key, so it can never be paid twice.
+func (s *WalletStandingOrderService) Resume(fromUserID, orderID uint) (*models.WalletStandingOrder, error) {
+	return s.update(fromUserID, orderID, func(order *models.WalletStandingOrder, now time.Time) error {
+		if order.Status != models.StandingOrderPaused {
+			return ErrStandingOrderNotPaused
+		}
+		order.Status = models.StandingOrderActive
+		order.PausedAt = nil
+		order.LastError = ""
+		order.FailureCount = 0
+		return nil
+	})
+}
+
+func (s *WalletStandingOrderService) update(fromUserID, orderID uint, apply func(*models.WalletStandingOrder, time.Time) error) (*models.WalletStandingOrder, error) {
+	var order models.WalletStandingOrder
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND from_user_id = ?", orderID, fromUserID).
+			First(&order).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrStandingOrderNotFound
+			}
+			return err
+		}
+		if err := apply(&order, time.Now().UTC()); err != nil {
+			return err
+		}
+		return tx.Model(&order).Select("status", "paused_at", "cancelled_at", "last_error", "failure_count").Updates(&order).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// StandingOrderRunResult summarizes one worker pass
+type StandingOrderRunResult struct {
+	Executed int
+	Paused   []models.WalletStandingOrder
+}
+
+// RunDue executes every active standing order that is due
+func (s *WalletStandingOrderService) RunDue(now time.Time) (StandingOrderRunResult, error) {
+	var result StandingOrderRunResult
+
+	var dueIDs []uint
+	if err := s.db.Model(&models.WalletStandingOrder{}).
+		Where("status = ? AND next_run_at <= ?", models.StandingOrderActive, now).
+		Order("next_run_at ASC").Limit(standingOrderBatchSize).
+		Pluck("id", &dueIDs).Error; err != nil {
+		return result, err
+	}
+
+	for _, id := range dueIDs {
+		order, executed, err := s.runOne(id, now)
+		if err != nil {
+			log.Printf("[StandingOrders] Run of standing order %d failed: %v", id, err)
+			paused, recordErr := s.recordFailure(id, err, now)
+			if recordErr != nil {
+				log.Printf("[StandingOrders] Failed to record failure of standing order %d: %v", id, recordErr)
+			}
+			if paused != nil {
+				result.Paused = append(result.Paused, *paused)
+			}
+			continue
+		}
+		if executed {
+			result.Executed++
+		}
+		if order != nil && order.Status == models.StandingOrderPaused {
+			result.Paused = append(result.Paused, *order)
+		}
+	}
+	return result, nil
+}
+
+// runOne executes a single due run. The dedup key is derived from the scheduled time, so
+// a retry after a crash between the transfer and the schedule update is a no-op.
+func (s *WalletStandingOrderService) runOne(id uint, now time.Time) (*models.WalletStandingOrder, bool, error) {
+	var (
+		order    models.WalletStandingOrder
+		executed bool
+	)
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, id).Error; err != nil {
+			return err
+		}
+		if order.Status != models.StandingOrderActive || order.NextRunAt.After(now) {
+			return nil
+		}
+		if order.EndsAt != nil && order.NextRunAt.After(*order.EndsAt) {
+			order.Status = models.StandingOrderCompleted
+			return tx.Model(&order).Update("status", order.Status).Error
+		}
+
+		description := order.Description
+		if description == "" {
+			description = "Регулярный перевод"
+		}
+		dedupKey := standingOrderDedupKey(order.ID, order.NextRunAt)
+		duplicate, err := s.walletService.transferTx(tx, order.FromUserID, order.ToUserID, order.Amount, dedupKey, description, nil)
+		if errors.Is(err, ErrInsufficientBalance) || errors.Is(err, ErrWalletFrozen) ||
+			errors.Is(err, ErrSpendingLimitExceeded) || errors.Is(err, ErrTransferVelocityBlocked) {
+			order.Status = models.StandingOrderPaused
+			order.PausedAt = &now
+			order.LastError = err.Error()
+			return tx.Model(&order).Select("status", "paused_at", "last_error").Updates(&order).Error
+		}
+		if err != nil {
+			return err
+		}
+		executed = !duplicate
+
+		order.RunCount++
+		order.LastRunAt = &now
+		order.LastError = ""
+		order.FailureCount = 0
+		// Runs missed while the order was paused are skipped, not paid in a burst
+		order.NextRunAt = nextStandingOrderRun(order.NextRunAt, order.Frequency, order.AnchorDay)
+		for order.Frequency != models.StandingOrderOnce && !order.NextRunAt.After(now) {
+			order.NextRunAt = nextStandingOrderRun(order.NextRunAt, order.Frequency, order.AnchorDay)
+		}
+		if standingOrderFinished(&order) {
+			order.Status = models.StandingOrderCompleted
+		}
+		return tx.Model(&order).Select("run_count", "last_run_at", "last_error", "failure_count", "next_run_at", "status").Updates(&order).Error
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return &order, executed, nil
+}
+
+// recordFailure counts a run that failed in runOne and pauses the order with the error
+// once maxStandingOrderFailures runs failed in a row. It returns the order if it paused.
+func (s *WalletStandingOrderService) recordFailure(id uint, runErr error, now time.Time) (*models.WalletStandingOrder, error) {
+	var (
+		order  models.WalletStandingOrder
+		paused bool
+	)
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, id).Error; err != nil {
+			return err
+		}
+		if order.Status != models.StandingOrderActive {
+			return nil
+		}
+		order.FailureCount++
+		order.LastError = truncateText(runErr.Error(), 500)
+		if order.FailureCount >= maxStandingOrderFailures {
+			order.Status = models.StandingOrderPaused
+			order.PausedAt = &now
+			paused = true
+		}
+		return tx.Model(&order).Select("failure_count", "last_error", "status", "paused_at").Updates(&order).Error
+	})
+	if err != nil || !paused {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func standingOrderDedupKey(orderID uint, scheduledAt time.Time) string {
+	return fmt.Sprintf("standing_order:%d:%d", orderID, scheduledAt.Unix())
+}
+
+// nextStandingOrderRun returns the run after prev. Monthly orders keep their anchor day
+// and fall back to the last day of shorter months.
+func nextStandingOrderRun(prev time.Time, frequency models.StandingOrderFrequency, anchorDay int) time.Time {
+	switch frequency {
+	case models.StandingOrderWeekly:
+		return prev.AddDate(0, 0, 7)
+	case models.StandingOrderMonthly:
+		year, month, _ := prev.Date()
+		firstOfNext := time.Date(year, month+1, 1, prev.Hour(), prev.Minute(), prev.Second(), 0, prev.Location())
+		lastDay := firstOfNext.AddDate(0, 1, -1).Day()
+		day := anchorDay
+		if day < 1 {
+			day = prev.Day()
+		}
+		if day > lastDay {
+			day = lastDay
+		}
+		return firstOfNext.AddDate(0, 0, day-1)
+	default:
+		return prev
+	}
+}
+
+func standingOrderFinished(order *models.WalletStandingOrder) bool {
+	if order.Frequency == models.StandingOrderOnce {
+		return true
+	}
+	if order.MaxRuns > 0 && order.RunCount >= order.MaxRuns {
+		return true
+	}
+	return order.EndsAt != nil && order.NextRunAt.After(*order.EndsAt)
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"errors"
+	"rag-agent-server/internal/models"
+	"testing"
+	"time"
+)
+
+func TestNextStandingOrderRun(t *testing.T) {
+	t.Parallel()
+
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 9, 30, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name      string
+		prev      time.Time
+		frequency models.StandingOrderFrequency
+		anchorDay int
+		want      time.Time
+	}{
+		{name: "weekly", prev: at(2026, 3, 28), frequency: models.StandingOrderWeekly, want: at(2026, 4, 4)},
+		{name: "monthly", prev: at(2026, 3, 15), frequency: models.StandingOrderMonthly, anchorDay: 15, want: at(2026, 4, 15)},
+		{name: "monthly clamps to short month", prev: at(2026, 1, 31), frequency: models.StandingOrderMonthly, anchorDay: 31, want: at(2026, 2, 28)},
+		{name: "monthly returns to anchor", prev: at(2026, 2, 28), frequency: models.StandingOrderMonthly, anchorDay: 31, want: at(2026, 3, 31)},
+		{name: "monthly over new year", prev: at(2026, 12, 10), frequency: models.StandingOrderMonthly, anchorDay: 10, want: at(2027, 1, 10)},
+		{name: "once does not move", prev: at(2026, 5, 1), frequency: models.StandingOrderOnce, want: at(2026, 5, 1)},
+	}
+	for _, tc := range tests {
+		if got := nextStandingOrderRun(tc.prev, tc.frequency, tc.anchorDay); !got.Equal(tc.want) {
+			t.Fatalf("%s: got %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestStandingOrderFinished(t *testing.T) {
+	t.Parallel()
+
+	endsAt := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		order models.WalletStandingOrder
+		want  bool
+	}{
+		{name: "once", order: models.WalletStandingOrder{Frequency: models.StandingOrderOnce, RunCount: 1}, want: true},
+		{name: "max runs reached", order: models.WalletStandingOrder{Frequency: models.StandingOrderWeekly, MaxRuns: 3, RunCount: 3}, want: true},
+		{name: "runs left", order: models.WalletStandingOrder{Frequency: models.StandingOrderWeekly, MaxRuns: 3, RunCount: 2}},
+		{name: "past end", order: models.WalletStandingOrder{Frequency: models.StandingOrderMonthly, EndsAt: &endsAt, NextRunAt: endsAt.AddDate(0, 0, 1)}, want: true},
+		{name: "until cancelled", order: models.WalletStandingOrder{Frequency: models.StandingOrderMonthly, RunCount: 40}},
+	}
+	for _, tc := range tests {
+		if got := standingOrderFinished(&tc.order); got != tc.want {
+			t.Fatalf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestValidateStandingOrder(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	valid := models.StandingOrderCreateRequest{ToUserID: 2, Amount: 100, Frequency: models.StandingOrderMonthly}
+	if err := validateStandingOrder(1, valid, now, now); err != nil {
+		t.Fatalf("valid request rejected: %v", err)
+	}
+
+	past := now.Add(-time.Hour)
+	tests := []models.StandingOrderCreateRequest{
+		{ToUserID: 1, Amount: 100, Frequency: models.StandingOrderMonthly},
+		{ToUserID: 2, Amount: 0, Frequency: models.StandingOrderMonthly},
+		{ToUserID: 2, Amount: 100, Frequency: "daily"},
+		{ToUserID: 2, Amount: 100, Frequency: models.StandingOrderWeekly, EndsAt: &past},
+	}
+	for _, req := range tests {
+		if err := validateStandingOrder(1, req, now, now); !errors.Is(err, ErrStandingOrderInvalid) {
+			t.Fatalf("request %+v: error = %v, want ErrStandingOrderInvalid", req, err)
+		}
+	}
+	if err := validateStandingOrder(1, valid, past, now); !errors.Is(err, ErrStandingOrderInvalid) {
+		t.Fatalf("start in the past must be rejected")
+	}
+}
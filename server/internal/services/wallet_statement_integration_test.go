@@ -0,0 +1,58 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"rag-agent-server/internal/models"
+)
+
+func TestWalletStatementFlagsDrift_Integration(t *testing.T) {
+	db := setupYatraServiceIntegrationDB(t)
+	if err := db.AutoMigrate(&models.WalletStatement{}); err != nil {
+		t.Fatalf("wallet statement automigrate failed: %v", err)
+	}
+
+	user := createYatraIntegrationUser(t, db, "statement-drift")
+	setWalletBalance(t, db, user.ID, 0)
+	var wallet models.Wallet
+	if err := db.Where("user_id = ? AND type = ?", user.ID, models.WalletTypePersonal).First(&wallet).Error; err != nil {
+		t.Fatalf("load wallet: %v", err)
+	}
+
+	now := time.Now().UTC()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for _, tx := range []models.WalletTransaction{
+		{WalletID: wallet.ID, Type: models.TransactionTypeCredit, Amount: 300, BalanceAfter: 300, Description: "top-up"},
+		{WalletID: wallet.ID, Type: models.TransactionTypeDebit, Amount: 100, BalanceAfter: 200, Description: "order"},
+	} {
+		tx := tx
+		tx.CreatedAt = monthStart.Add(time.Minute)
+		if err := db.Create(&tx).Error; err != nil {
+			t.Fatalf("create transaction: %v", err)
+		}
+	}
+
+	service := &WalletStatementService{db: db, now: time.Now}
+	month := monthStart.Format("2006-01")
+
+	// The stored balance matches the history
+	setWalletBalance(t, db, user.ID, 200)
+	report, err := service.Build(user.ID, month, "en")
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if !report.Reconciled() || report.ClosingBalance != 200 {
+		t.Fatalf("reconciled statement: closing=%d drift=%d", report.ClosingBalance, report.BalanceDrift)
+	}
+
+	// A balance update without a transaction is reported, not hidden in the closing figure
+	setWalletBalance(t, db, user.ID, 250)
+	report, err = service.Build(user.ID, month, "en")
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if report.Reconciled() || report.BalanceDrift != 50 || report.ClosingBalance != 200 {
+		t.Fatalf("drifted statement: closing=%d drift=%d", report.ClosingBalance, report.BalanceDrift)
+	}
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/unidoc/unipdf/v3/creator"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// writeWalletStatementPDF lays out the statement with the same unipdf setup and fonts as
+// the library export
+func writeWalletStatementPDF(w io.Writer, report *WalletStatementReport) error {
+	if err := ensurePDFExportReady(); err != nil {
+		return err
+	}
+	regular, err := model.NewCompositePdfFontFromTTFFile(pdfRegularFontTTF)
+	if err != nil {
+		return fmt.Errorf("load pdf font: %w", err)
+	}
+	bold := regular
+	if pdfBoldFontTTF != "" {
+		if bold, err = model.NewCompositePdfFontFromTTFFile(pdfBoldFontTTF); err != nil {
+			return fmt.Errorf("load pdf bold font: %w", err)
+		}
+	}
+
+	c := creator.New()
+	c.SetPageSize(creator.PageSizeA4)
+	c.SetPageMargins(40, 40, 50, 50)
+	c.SetLanguage(report.Language)
+	c.EnableFontSubsetting(regular)
+	if bold != regular {
+		c.EnableFontSubsetting(bold)
+	}
+	labels := walletStatementLabelsFor(report.Language)
+
+	text := func(value string, font *model.PdfFont, size float64) *creator.StyledParagraph {
+		para := c.NewStyledParagraph()
+		chunk := para.Append(value)
+		chunk.Style.Font = font
+		chunk.Style.FontSize = size
+		return para
+	}
+
+	title := text(labels.Title, bold, 18)
+	title.SetMargins(0, 0, 0, 10)
+	if err := c.Draw(title); err != nil {
+		return err
+	}
+	header := []string{
+		fmt.Sprintf("%s: %s", labels.Period, walletStatementPeriodLabel(report)),
+	}
+	if report.HolderName != "" {
+		header = append([]string{fmt.Sprintf("%s: %s", labels.Holder, report.HolderName)}, header...)
+	}
+	for _, line := range header {
+		para := text(line, regular, 10)
+		para.SetMargins(0, 0, 0, 2)
+		if err := c.Draw(para); err != nil {
+			return err
+		}
+	}
+
+	// Summary: regular and bonus columns
+	summary := c.NewTable(3)
+	summary.SetMargins(0, 0, 12, 12)
+	if err := summary.SetColumnWidths(0.5, 0.25, 0.25); err != nil {
+		return err
+	}
+	addRow := func(table *creator.Table, font *model.PdfFont, size float64, values ...string) error {
+		for i, value := range values {
+			cell := table.NewCell()
+			cell.SetIndent(2)
+			if i > 0 {
+				cell.SetHorizontalAlignment(creator.CellHorizontalAlignmentRight)
+			}
+			cell.SetBorder(creator.CellBorderSideBottom, creator.CellBorderStyleSingle, 0.3)
+			if err := cell.SetContent(text(value, font, size)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	summaryRows := [][]string{
+		{"", labels.Regular, labels.Bonus},
+		{labels.Opening, fmt.Sprint(report.OpeningBalance), fmt.Sprint(report.OpeningBonusBalance)},
+		{labels.In, signedLKM(report.RegularIn), signedLKM(report.BonusIn)},
+		{labels.Out, signedLKM(-report.RegularOut), signedLKM(-report.BonusOut)},
+		{labels.Closing, fmt.Sprint(report.ClosingBalance), fmt.Sprint(report.ClosingBonusBalance)},
+	}
+	for i, row := range summaryRows {
+		font := regular
+		if i == 0 || i == len(summaryRows)-1 {
+			font = bold
+		}
+		if err := addRow(summary, font, 10, row...); err != nil {
+			return err
+		}
+	}
+	if err := c.Draw(summary); err != nil {
+		return err
+	}
+	if !report.Reconciled() {
+		warning := text(fmt.Sprintf("%s: %s / %s", labels.Drift,
+			signedLKM(report.BalanceDrift), signedLKM(report.BonusBalanceDrift)), bold, 10)
+		warning.SetMargins(0, 0, 0, 12)
+		if err := c.Draw(warning); err != nil {
+			return err
+		}
+	}
+
+	if len(report.Lines) == 0 {
+		return finishPDF(c, w, text(labels.Empty, regular, 10))
+	}
+
+	lines := c.NewTable(5)
+	lines.EnableRowWrap(true)
+	if err := lines.SetColumnWidths(0.14, 0.48, 0.12, 0.12, 0.14); err != nil {
+		return err
+	}
+	if err := addRow(lines, bold, 9, labels.Date, labels.Operation, labels.Regular, labels.Bonus, labels.Balance); err != nil {
+		return err
+	}
+	if err := lines.SetHeaderRows(1, 1); err != nil {
+		return err
+	}
+	for _, line := range report.Lines {
+		details := []string{line.Description}
+		if line.Counterparty != "" {
+			details = append(details, line.Counterparty)
+		}
+		if line.Reference != "" {
+			details = append(details, line.Reference)
+		}
+		if err := addRow(lines, regular, 8,
+			line.Date.UTC().Format("02.01.2006 15:04"),
+			strings.Join(details, " · "),
+			signedLKM(line.RegularDelta),
+			signedLKM(line.BonusDelta),
+			fmt.Sprintf("%d / %d", line.BalanceAfter, line.BonusBalanceAfter),
+		); err != nil {
+			return err
+		}
+	}
+	return finishPDF(c, w, lines)
+}
+
+func finishPDF(c *creator.Creator, w io.Writer, last creator.Drawable) error {
+	if err := c.Draw(last); err != nil {
+		return err
+	}
+	return c.Write(w)
+}
+
+func walletStatementPeriodLabel(report *WalletStatementReport) string {
+	// PeriodEnd is exclusive; show the last covered day
+	return fmt.Sprintf("%s – %s", report.PeriodStart.Format("02.01.2006"),
+		report.PeriodEnd.Add(-1).UTC().Format("02.01.2006"))
+}
+
+func signedLKM(amount int) string {
+	if amount > 0 {
+		return fmt.Sprintf("+%d", amount)
+	}
+	return fmt.Sprint(amount)
+}
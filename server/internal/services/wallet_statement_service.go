@@ -0,0 +1,535 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"rag-agent-server/internal/database"
+	"rag-agent-server/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidStatementMonth      = errors.New("month must be YYYY-MM and not in the future")
+	ErrUnsupportedStatementFormat = errors.New("format must be one of: csv, pdf")
+)
+
+// Wallet statement formats
+const (
+	WalletStatementCSV = "csv"
+	WalletStatementPDF = "pdf"
+)
+
+var walletStatementContentTypes = map[string]string{
+	WalletStatementCSV: "text/csv; charset=utf-8",
+	WalletStatementPDF: "application/pdf",
+}
+
+const (
+	walletStatementKeyPrefix   = "wallet-statements"
+	walletStatementLinkTTL     = 15 * time.Minute
+	walletStatementReplayBatch = 500
+)
+
+// WalletStatementLine is one transaction with its effect on the spendable balances
+type WalletStatementLine struct {
+	TransactionID     uint
+	Date              time.Time
+	Type              models.TransactionType
+	Description       string
+	Amount            int
+	RegularDelta      int
+	BonusDelta        int
+	FrozenDelta       int // Regular and bonus moved into (+) or out of (-) holds
+	BalanceAfter      int
+	BonusBalanceAfter int
+	Counterparty      string
+	Reference         string // Booking or order the transaction belongs to
+}
+
+// WalletStatementReport is the content of a monthly statement. Balances are replayed from
+// the transaction history, so opening + movements always equals closing. When the stored
+// wallet balance disagrees with that history (see LKMAuditService) the drift is reported
+// alongside instead of being folded into the figures.
+type WalletStatementReport struct {
+	UserID      uint
+	WalletID    uint
+	HolderName  string
+	Month       string
+	Language    string
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+
+	OpeningBalance      int
+	OpeningBonusBalance int
+	ClosingBalance      int
+	ClosingBonusBalance int
+	RegularIn           int
+	RegularOut          int
+	BonusIn             int
+	BonusOut            int
+
+	// Stored wallet balance minus the replayed one, as of generation; 0 when they agree
+	BalanceDrift      int
+	BonusBalanceDrift int
+
+	Lines []WalletStatementLine
+
+	pending []statementRefs // Parallel to Lines until resolveReferences runs
+}
+
+func (r *WalletStatementReport) TotalIn() int  { return r.RegularIn + r.BonusIn }
+func (r *WalletStatementReport) TotalOut() int { return r.RegularOut + r.BonusOut }
+
+// Reconciled reports whether the transaction history accounts for the stored balances
+func (r *WalletStatementReport) Reconciled() bool {
+	return r.BalanceDrift == 0 && r.BonusBalanceDrift == 0
+}
+
+// FileName returns a download name such as "lkm-statement-2026-09.pdf"
+func (r *WalletStatementReport) FileName(format string) string {
+	return fmt.Sprintf("lkm-statement-%s.%s", r.Month, format)
+}
+
+// WalletStatementService builds monthly wallet statements and archives them in S3
+type WalletStatementService struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+func NewWalletStatementService() *WalletStatementService {
+	return &WalletStatementService{db: database.DB, now: time.Now}
+}
+
+// statementPeriod resolves "YYYY-MM" to a UTC period; the running month ends now
+func statementPeriod(month string, now time.Time) (time.Time, time.Time, error) {
+	start, err := time.Parse("2006-01", strings.TrimSpace(month))
+	if err != nil {
+		return time.Time{}, time.Time{}, ErrInvalidStatementMonth
+	}
+	start = start.UTC()
+	now = now.UTC()
+	if !start.Before(now) {
+		return time.Time{}, time.Time{}, ErrInvalidStatementMonth
+	}
+	end := start.AddDate(0, 1, 0)
+	if end.After(now) {
+		end = now
+	}
+	return start, end, nil
+}
+
+// Build replays the wallet up to the end of the month and collects the month's lines.
+// The rest of the history is replayed too, to compare against the stored balances.
+func (s *WalletStatementService) Build(userID uint, month, language string) (*WalletStatementReport, error) {
+	start, end, err := statementPeriod(month, s.now())
+	if err != nil {
+		return nil, err
+	}
+	if language != "en" {
+		language = "ru"
+	}
+	labels := walletStatementLabelsFor(language)
+
+	var wallet models.Wallet
+	if err := s.db.Where("user_id = ? AND type = ?", userID, models.WalletTypePersonal).First(&wallet).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		// No wallet yet: the statement is empty
+	}
+
+	report := &WalletStatementReport{
+		UserID:      userID,
+		WalletID:    wallet.ID,
+		Month:       start.Format("2006-01"),
+		Language:    language,
+		PeriodStart: start,
+		PeriodEnd:   end,
+	}
+	var holder models.User
+	if err := s.db.Select("id", "spiritual_name", "karmic_name").First(&holder, userID).Error; err == nil {
+		report.HolderName = firstNonEmpty(holder.SpiritualName, holder.KarmicName)
+	}
+
+	if wallet.ID != 0 {
+		var batch []models.WalletTransaction
+		var balances, current walletBalances
+		openingTaken := false
+		err := s.db.Where("wallet_id = ?", wallet.ID).Order("id ASC").
+			FindInBatches(&batch, walletStatementReplayBatch, func(tx *gorm.DB, _ int) error {
+				for i := range batch {
+					t := &batch[i]
+					current.apply(t)
+					if !t.CreatedAt.Before(end) {
+						continue
+					}
+					if t.CreatedAt.Before(start) {
+						balances.apply(t)
+						continue
+					}
+					if !openingTaken {
+						report.OpeningBalance, report.OpeningBonusBalance = balances.Balance, balances.BonusBalance
+						openingTaken = true
+					}
+					report.addLine(t, &balances)
+				}
+				return nil
+			}).Error
+		if err != nil {
+			return nil, err
+		}
+		if !openingTaken {
+			report.OpeningBalance, report.OpeningBonusBalance = balances.Balance, balances.BonusBalance
+		}
+		report.ClosingBalance, report.ClosingBonusBalance = balances.Balance, balances.BonusBalance
+		report.BalanceDrift = wallet.Balance - current.Balance
+		report.BonusBalanceDrift = wallet.BonusBalance - current.BonusBalance
+		if !report.Reconciled() {
+			log.Printf("[WalletStatement] Wallet %d of user %d drifted from its history: balance %+d, bonus %+d",
+				wallet.ID, userID, report.BalanceDrift, report.BonusBalanceDrift)
+		}
+	}
+
+	if err := s.resolveReferences(report, labels); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// addLine applies a transaction and records how it moved the spendable balances
+func (r *WalletStatementReport) addLine(t *models.WalletTransaction, balances *walletBalances) {
+	before := *balances
+	balances.apply(t)
+
+	line := WalletStatementLine{
+		TransactionID:     t.ID,
+		Date:              t.CreatedAt,
+		Type:              t.Type,
+		Description:       t.Description,
+		Amount:            t.Amount,
+		RegularDelta:      balances.Balance - before.Balance,
+		BonusDelta:        balances.BonusBalance - before.BonusBalance,
+		FrozenDelta:       (balances.FrozenBalance + balances.FrozenBonusBalance) - (before.FrozenBalance + before.FrozenBonusBalance),
+		BalanceAfter:      balances.Balance,
+		BonusBalanceAfter: balances.BonusBalance,
+	}
+	if line.RegularDelta > 0 {
+		r.RegularIn += line.RegularDelta
+	} else {
+		r.RegularOut -= line.RegularDelta
+	}
+	if line.BonusDelta > 0 {
+		r.BonusIn += line.BonusDelta
+	} else {
+		r.BonusOut -= line.BonusDelta
+	}
+	r.Lines = append(r.Lines, line)
+	r.pending = append(r.pending, statementRefs{related: t.RelatedWalletID, booking: t.BookingID, order: t.OrderID})
+}
+
+// resolveReferences fills counterparty names and booking/order references in bulk
+func (s *WalletStatementService) resolveReferences(report *WalletStatementReport, labels walletStatementLabels) error {
+	refs := report.pending
+	report.pending = nil
+	if len(refs) == 0 {
+		return nil
+	}
+
+	walletIDs, bookingIDs, orderIDs := []uint{}, []uint{}, []uint{}
+	for _, ref := range refs {
+		if ref.related != nil {
+			walletIDs = append(walletIDs, *ref.related)
+		}
+		if ref.booking != nil {
+			bookingIDs = append(bookingIDs, *ref.booking)
+		}
+		if ref.order != nil {
+			orderIDs = append(orderIDs, *ref.order)
+		}
+	}
+
+	counterparties := map[uint]string{}
+	if len(walletIDs) > 0 {
+		var wallets []models.Wallet
+		if err := s.db.Select("id", "type", "user_id", "organization_id").Where("id IN ?", walletIDs).Find(&wallets).Error; err != nil {
+			return err
+		}
+		userIDs, orgIDs := []uint{}, []uint{}
+		for _, w := range wallets {
+			if w.UserID != nil {
+				userIDs = append(userIDs, *w.UserID)
+			}
+			if w.OrganizationID != nil {
+				orgIDs = append(orgIDs, *w.OrganizationID)
+			}
+		}
+		userNames := map[uint]string{}
+		if len(userIDs) > 0 {
+			var users []models.User
+			if err := s.db.Select("id", "spiritual_name", "karmic_name").Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+				return err
+			}
+			for _, u := range users {
+				userNames[u.ID] = firstNonEmpty(u.SpiritualName, u.KarmicName, fmt.Sprintf("%s %d", labels.User, u.ID))
+			}
+		}
+		orgNames := map[uint]string{}
+		if len(orgIDs) > 0 {
+			var orgs []models.CharityOrganization
+			if err := s.db.Select("id", "name").Where("id IN ?", orgIDs).Find(&orgs).Error; err != nil {
+				return err
+			}
+			for _, o := range orgs {
+				orgNames[o.ID] = o.Name
+			}
+		}
+		// Charity wallets are linked from the organization side as well
+		var linkedOrgs []models.CharityOrganization
+		if err := s.db.Select("id", "name", "wallet_id").Where("wallet_id IN ?", walletIDs).Find(&linkedOrgs).Error; err != nil {
+			return err
+		}
+		for _, o := range linkedOrgs {
+			if o.WalletID != nil {
+				counterparties[*o.WalletID] = o.Name
+			}
+		}
+
+		for _, w := range wallets {
+			if _, ok := counterparties[w.ID]; ok {
+				continue
+			}
+			switch {
+			case w.Type == models.WalletTypePlatform:
+				counterparties[w.ID] = labels.Platform
+			case w.OrganizationID != nil && orgNames[*w.OrganizationID] != "":
+				counterparties[w.ID] = orgNames[*w.OrganizationID]
+			case w.UserID != nil:
+				counterparties[w.ID] = userNames[*w.UserID]
+			}
+		}
+	}
+
+	bookingRefs := map[uint]string{}
+	if len(bookingIDs) > 0 {
+		var bookings []models.ServiceBooking
+		if err := s.db.Preload("Service", func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped().Select("id", "title")
+		}).Select("id", "service_id").Where("id IN ?", bookingIDs).Find(&bookings).Error; err != nil {
+			return err
+		}
+		for _, b := range bookings {
+			ref := fmt.Sprintf("%s #%d", labels.Booking, b.ID)
+			if b.Service != nil && b.Service.Title != "" {
+				ref += ": " + b.Service.Title
+			}
+			bookingRefs[b.ID] = ref
+		}
+	}
+	orderRefs := map[uint]string{}
+	if len(orderIDs) > 0 {
+		var orders []models.Order
+		if err := s.db.Unscoped().Select("id", "order_number").Where("id IN ?", orderIDs).Find(&orders).Error; err != nil {
+			return err
+		}
+		for _, o := range orders {
+			orderRefs[o.ID] = fmt.Sprintf("%s %s", labels.Order, o.OrderNumber)
+		}
+	}
+
+	for i, ref := range refs {
+		line := &report.Lines[i]
+		if ref.related != nil {
+			line.Counterparty = counterparties[*ref.related]
+		}
+		switch {
+		case ref.order != nil:
+			line.Reference = firstNonEmpty(orderRefs[*ref.order], fmt.Sprintf("%s #%d", labels.Order, *ref.order))
+		case ref.booking != nil:
+			line.Reference = firstNonEmpty(bookingRefs[*ref.booking], fmt.Sprintf("%s #%d", labels.Booking, *ref.booking))
+		}
+	}
+	return nil
+}
+
+// Render writes the statement in the given format
+func (s *WalletStatementService) Render(report *WalletStatementReport, format string) ([]byte, error) {
+	var buf bytes.Buffer
+	switch format {
+	case WalletStatementCSV:
+		if err := writeWalletStatementCSV(&buf, report); err != nil {
+			return nil, err
+		}
+	case WalletStatementPDF:
+		if err := writeWalletStatementPDF(&buf, report); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrUnsupportedStatementFormat
+	}
+	return buf.Bytes(), nil
+}
+
+// NormalizeStatementFormat validates the format and checks that PDF rendering is available
+func NormalizeStatementFormat(format string) (string, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" {
+		format = WalletStatementCSV
+	}
+	if _, ok := walletStatementContentTypes[format]; !ok {
+		return "", ErrUnsupportedStatementFormat
+	}
+	if format == WalletStatementPDF {
+		if err := ensurePDFExportReady(); err != nil {
+			return "", err
+		}
+	}
+	return format, nil
+}
+
+func WalletStatementContentType(format string) string {
+	return walletStatementContentTypes[format]
+}
+
+// writeWalletStatementCSV writes one table; the opening and closing balances are its first and last
+// rows, followed by a "drift" row only when the statement is not reconciled
+func writeWalletStatementCSV(buf *bytes.Buffer, report *WalletStatementReport) error {
+	labels := walletStatementLabelsFor(report.Language)
+	w := csv.NewWriter(buf)
+	_ = w.Write([]string{"date", "transaction_id", "type", "description", "counterparty", "reference",
+		"amount", "regular_change", "bonus_change", "held_change", "balance_after", "bonus_balance_after"})
+	_ = w.Write([]string{report.PeriodStart.Format(time.RFC3339), "", "opening", labels.Opening, "", "",
+		"", "", "", "", strconv.Itoa(report.OpeningBalance), strconv.Itoa(report.OpeningBonusBalance)})
+	for _, line := range report.Lines {
+		_ = w.Write([]string{
+			line.Date.UTC().Format(time.RFC3339),
+			strconv.FormatUint(uint64(line.TransactionID), 10),
+			string(line.Type),
+			line.Description,
+			line.Counterparty,
+			line.Reference,
+			strconv.Itoa(line.Amount),
+			strconv.Itoa(line.RegularDelta),
+			strconv.Itoa(line.BonusDelta),
+			strconv.Itoa(line.FrozenDelta),
+			strconv.Itoa(line.BalanceAfter),
+			strconv.Itoa(line.BonusBalanceAfter),
+		})
+	}
+	_ = w.Write([]string{report.PeriodEnd.UTC().Format(time.RFC3339), "", "closing", labels.Closing, "", "",
+		"", strconv.Itoa(report.RegularIn - report.RegularOut), strconv.Itoa(report.BonusIn - report.BonusOut), "",
+		strconv.Itoa(report.ClosingBalance), strconv.Itoa(report.ClosingBonusBalance)})
+	if !report.Reconciled() {
+		_ = w.Write([]string{report.PeriodEnd.UTC().Format(time.RFC3339), "", "drift", labels.Drift, "", "",
+			"", strconv.Itoa(report.BalanceDrift), strconv.Itoa(report.BonusBalanceDrift), "", "", ""})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// Archive stores the rendered statement privately in S3 and records it. Without S3 the
+// statement is still served, only not archived.
+func (s *WalletStatementService) Archive(report *WalletStatementReport, format string, data []byte) (*models.WalletStatement, error) {
+	s3 := GetS3Service()
+	if s3 == nil {
+		return nil, nil
+	}
+	key := fmt.Sprintf("%s/%d/%s.%s", walletStatementKeyPrefix, report.UserID, report.Month, format)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := s3.UploadPrivateFile(ctx, bytes.NewReader(data), key, walletStatementContentTypes[format], int64(len(data))); err != nil {
+		return nil, err
+	}
+
+	statement := models.WalletStatement{
+		UserID:              report.UserID,
+		WalletID:            report.WalletID,
+		Month:               report.Month,
+		Format:              format,
+		PeriodStart:         report.PeriodStart,
+		PeriodEnd:           report.PeriodEnd,
+		OpeningBalance:      report.OpeningBalance,
+		OpeningBonusBalance: report.OpeningBonusBalance,
+		ClosingBalance:      report.ClosingBalance,
+		ClosingBonusBalance: report.ClosingBonusBalance,
+		BalanceDrift:        report.BalanceDrift,
+		BonusBalanceDrift:   report.BonusBalanceDrift,
+		TotalIn:             report.TotalIn(),
+		TotalOut:            report.TotalOut(),
+		TransactionCount:    len(report.Lines),
+		StorageKey:          key,
+		SizeBytes:           int64(len(data)),
+		GeneratedAt:         s.now().UTC(),
+	}
+	if err := s.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "month"}, {Name: "format"}},
+		DoUpdates: clause.AssignmentColumns([]string{"wallet_id", "period_start", "period_end",
+			"opening_balance", "opening_bonus_balance", "closing_balance", "closing_bonus_balance",
+			"balance_drift", "bonus_balance_drift", "total_in", "total_out", "transaction_count", "storage_key", "size_bytes", "generated_at", "updated_at"}),
+	}).Create(&statement).Error; err != nil {
+		return nil, err
+	}
+	return &statement, nil
+}
+
+// ListArchived returns the user's archived statements with short-lived download links
+func (s *WalletStatementService) ListArchived(userID uint) ([]models.WalletStatement, error) {
+	statements := []models.WalletStatement{}
+	if err := s.db.Where("user_id = ?", userID).Order("month DESC, format ASC").Limit(60).Find(&statements).Error; err != nil {
+		return nil, err
+	}
+	if s3 := GetS3Service(); s3 != nil {
+		for i := range statements {
+			url, err := s3.GeneratePresignedURL(context.Background(), statements[i].StorageKey, walletStatementLinkTTL)
+			if err != nil {
+				log.Printf("[WalletStatement] Could not sign %s: %v", statements[i].StorageKey, err)
+				continue
+			}
+			statements[i].DownloadURL = url
+		}
+	}
+	return statements, nil
+}
+
+// statementRefs holds the ids of a line until names are resolved
+type statementRefs struct {
+	related *uint
+	booking *uint
+	order   *uint
+}
+
+type walletStatementLabels struct {
+	Title, Holder, Period, Opening, Closing, In, Out, Regular, Bonus, Balance string
+	Date, Operation, Platform, User, Booking, Order, Empty                    string
+	Drift                                                                     string
+}
+
+func walletStatementLabelsFor(language string) walletStatementLabels {
+	if language == "en" {
+		return walletStatementLabels{
+			Title: "LKM wallet statement", Holder: "Account holder", Period: "Period",
+			Opening: "Opening balance", Closing: "Closing balance", In: "Received", Out: "Spent",
+			Regular: "Regular", Bonus: "Bonus", Balance: "Balance",
+			Date: "Date", Operation: "Operation", Platform: "VedaMatch platform", User: "User",
+			Booking: "Booking", Order: "Order", Empty: "No transactions in this period",
+			Drift: "Stored balance differs from the transaction history",
+		}
+	}
+	return walletStatementLabels{
+		Title: "Выписка по кошельку LKM", Holder: "Владелец", Period: "Период",
+		Opening: "Входящий остаток", Closing: "Исходящий остаток", In: "Поступления", Out: "Списания",
+		Regular: "Основной", Bonus: "Бонусный", Balance: "Остаток",
+		Date: "Дата", Operation: "Операция", Platform: "Платформа VedaMatch", User: "Пользователь",
+		Booking: "Бронирование", Order: "Заказ", Empty: "За период операций не было",
+		Drift: "Остаток кошелька расходится с историей операций",
+	}
+}
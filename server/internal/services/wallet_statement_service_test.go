@@ -0,0 +1,139 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"rag-agent-server/internal/models"
+	"testing"
+	"time"
+)
+
+func TestStatementPeriod(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		month     string
+		wantStart time.Time
+		wantEnd   time.Time
+		wantErr   error
+	}{
+		{name: "closed month", month: "2026-09", wantStart: time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC), wantEnd: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "running month ends now", month: "2026-10", wantStart: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), wantEnd: now},
+		{name: "future month", month: "2026-11", wantErr: ErrInvalidStatementMonth},
+		{name: "malformed", month: "09-2026", wantErr: ErrInvalidStatementMonth},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			start, end, err := statementPeriod(tt.month, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("statementPeriod(%q) error = %v, want %v", tt.month, err, tt.wantErr)
+			}
+			if err == nil && (!start.Equal(tt.wantStart) || !end.Equal(tt.wantEnd)) {
+				t.Fatalf("statementPeriod(%q) = %v..%v, want %v..%v", tt.month, start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestWalletStatementLinesSplitRegularAndBonus(t *testing.T) {
+	t.Parallel()
+
+	bookingID := uint(4)
+	report := &WalletStatementReport{Language: "en", Month: "2026-09"}
+	balances := walletBalances{Balance: 500, BonusBalance: 60}
+	report.OpeningBalance, report.OpeningBonusBalance = balances.Balance, balances.BonusBalance
+
+	for _, tx := range []models.WalletTransaction{
+		{ID: 1, Type: models.TransactionTypeHold, Amount: 120, BonusAmount: 20, BookingID: &bookingID},
+		{ID: 2, Type: models.TransactionTypeRelease, Amount: 120, BonusAmount: 20, BookingID: &bookingID},
+		{ID: 3, Type: models.TransactionTypeCredit, Amount: 300},
+		{ID: 4, Type: models.TransactionTypeBonus, Amount: 50, BonusAmount: 50, Description: walletWelcomePendingDescription},
+	} {
+		tx := tx
+		report.addLine(&tx, &balances)
+	}
+	report.ClosingBalance, report.ClosingBonusBalance = balances.Balance, balances.BonusBalance
+
+	hold := report.Lines[0]
+	if hold.RegularDelta != -100 || hold.BonusDelta != -20 || hold.FrozenDelta != 120 {
+		t.Fatalf("hold line = %+v", hold)
+	}
+	release := report.Lines[1]
+	if release.RegularDelta != 0 || release.BonusDelta != 0 || release.FrozenDelta != -120 {
+		t.Fatalf("release line = %+v", release)
+	}
+	if pending := report.Lines[3]; pending.RegularDelta != 0 || pending.BonusDelta != 0 {
+		t.Fatalf("pending bonus must not change spendable balances: %+v", pending)
+	}
+	if report.RegularIn != 300 || report.RegularOut != 100 || report.BonusIn != 0 || report.BonusOut != 20 {
+		t.Fatalf("totals in=%d/%d out=%d/%d", report.RegularIn, report.BonusIn, report.RegularOut, report.BonusOut)
+	}
+	if report.OpeningBalance+report.RegularIn-report.RegularOut != report.ClosingBalance ||
+		report.OpeningBonusBalance+report.BonusIn-report.BonusOut != report.ClosingBonusBalance {
+		t.Fatalf("opening + movements != closing: %+v", report)
+	}
+	if len(report.pending) != len(report.Lines) || report.pending[0].booking == nil {
+		t.Fatalf("references not kept for resolution: %+v", report.pending)
+	}
+}
+
+func TestWriteWalletStatementCSV(t *testing.T) {
+	t.Parallel()
+
+	report := &WalletStatementReport{
+		Language:            "en",
+		PeriodStart:         time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC),
+		PeriodEnd:           time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+		OpeningBalance:      100,
+		ClosingBalance:      150,
+		ClosingBonusBalance: 10,
+		RegularIn:           50,
+		BonusIn:             10,
+		Lines: []WalletStatementLine{{
+			TransactionID: 9, Date: time.Date(2026, 9, 3, 10, 0, 0, 0, time.UTC), Type: models.TransactionTypeCredit,
+			Description: "Session", Amount: 50, RegularDelta: 50, BalanceAfter: 150,
+			Counterparty: "Radha", Reference: "Booking #4: Yoga",
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := writeWalletStatementCSV(&buf, report); err != nil {
+		t.Fatalf("writeWalletStatementCSV() error = %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("statement is not valid CSV: %v", err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("got %d rows, want header, opening, line, closing", len(rows))
+	}
+	if rows[1][2] != "opening" || rows[1][10] != "100" {
+		t.Fatalf("opening row = %v", rows[1])
+	}
+	if rows[2][4] != "Radha" || rows[2][5] != "Booking #4: Yoga" || rows[2][7] != "50" {
+		t.Fatalf("transaction row = %v", rows[2])
+	}
+	if rows[3][2] != "closing" || rows[3][10] != "150" || rows[3][11] != "10" {
+		t.Fatalf("closing row = %v", rows[3])
+	}
+
+	// A statement whose history does not explain the stored balance says so
+	report.BalanceDrift = 25
+	buf.Reset()
+	if err := writeWalletStatementCSV(&buf, report); err != nil {
+		t.Fatalf("writeWalletStatementCSV() error = %v", err)
+	}
+	rows, err = csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("statement is not valid CSV: %v", err)
+	}
+	if len(rows) != 5 || rows[4][2] != "drift" || rows[4][7] != "25" || rows[4][8] != "0" {
+		t.Fatalf("drift row = %v", rows[len(rows)-1])
+	}
+}
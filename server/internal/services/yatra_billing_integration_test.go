@@ -17,6 +17,8 @@ func ensureYatraBillingIntegrationSchema(t *testing.T, db *gorm.DB) {
 		&models.SystemSetting{},
 		&models.Wallet{},
 		&models.WalletTransaction{},
+		&models.WalletLimitTier{},
+		&models.WalletLimitOverride{},
 		&models.YatraBillingEvent{},
 	); err != nil {
 		t.Fatalf("billing integration automigrate failed: %v", err)
@@ -51,6 +51,8 @@ func setupYatraServiceIntegrationDB(t *testing.T) *gorm.DB {
 		&models.SystemSetting{},
 		&models.Wallet{},
 		&models.WalletTransaction{},
+		&models.WalletLimitTier{},
+		&models.WalletLimitOverride{},
 		&models.YatraBillingEvent{},
 	)
 	if err != nil {
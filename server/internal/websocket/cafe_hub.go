@@ -1,7 +1,9 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"sync"
 	"time"
@@ -45,14 +47,26 @@ func (e CafeEvent) GetTargetUserIDs() []uint {
 	return []uint{e.TargetUserID}
 }
 
-// CafeRoom represents a WebSocket room for a specific cafe
+// CafeRoom represents a WebSocket room for a specific cafe. Members are keyed by
+// connection, so a user with several devices stays in the room until the last one leaves.
 type CafeRoom struct {
 	CafeID  uint
-	Staff   map[uint]*Client // Staff members connected to this cafe
-	Clients map[uint]*Client // Regular customers connected (for order updates)
+	Staff   map[*Client]struct{} // Staff connections to this cafe
+	Clients map[*Client]struct{} // Regular customer connections (for order updates)
 	mu      sync.RWMutex
 }
 
+// hasStaffUser reports whether any staff connection of the user is still in the room.
+// The caller holds room.mu.
+func (r *CafeRoom) hasStaffUser(userID uint) bool {
+	for client := range r.Staff {
+		if client.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
 // CafeHub manages WebSocket connections for cafes
 type CafeHub struct {
 	// Map of cafe ID to room
@@ -77,6 +91,12 @@ func GetCafeHub(mainHub *Hub) *CafeHub {
 			Events:  make(chan CafeEvent, 256),
 		}
 		go cafeHub.Run()
+		if mainHub != nil {
+			mainHub.cafe.Store(cafeHub)
+			if mainHub.IsClustered() {
+				go cafeHub.runPresenceHeartbeat()
+			}
+		}
 	})
 	return cafeHub
 }
@@ -92,19 +112,41 @@ func (h *CafeHub) Run() {
 func (h *CafeHub) processEvent(event CafeEvent) {
 	log.Printf("[CafeHub] Processing event: %s for cafe %d", event.Type, event.CafeID)
 
-	// If targeted to specific user, send directly
+	// If targeted to specific user, send directly (main hub routes across instances)
 	if event.TargetUserID != 0 {
 		h.sendToUser(event.TargetUserID, event)
 		return
 	}
 
+	h.deliverToRoom(event)
+
+	// Staff of the same cafe may be connected to other instances
+	if h.mainHub != nil {
+		h.mainHub.publish(clusterKindCafe, event, nil)
+	}
+}
+
+// handleRemote processes a room event published by another instance
+func (h *CafeHub) handleRemote(env ClusterEnvelope) {
+	var event CafeEvent
+	if err := json.Unmarshal(env.Payload, &event); err != nil {
+		log.Printf("[CafeHub] Invalid remote event: %v", err)
+		return
+	}
+	if event.TargetUserID != 0 {
+		return
+	}
+	h.deliverToRoom(event)
+}
+
+// deliverToRoom sends a room event to locally connected room members
+func (h *CafeHub) deliverToRoom(event CafeEvent) {
 	// Send to all staff in the cafe room
 	h.mu.RLock()
 	room, exists := h.rooms[event.CafeID]
 	h.mu.RUnlock()
 
 	if !exists {
-		log.Printf("[CafeHub] No room for cafe %d", event.CafeID)
 		return
 	}
 
@@ -115,26 +157,26 @@ func (h *CafeHub) processEvent(event CafeEvent) {
 	switch event.Type {
 	case CafeEventNewOrder, CafeEventWaiterCall:
 		// Staff-only events
-		for _, client := range room.Staff {
+		for client := range room.Staff {
 			h.sendToClient(client, event)
 		}
 	case CafeEventOrderUpdate, CafeEventOrderCancelled:
 		// Send to staff AND the customer who made the order
-		for _, client := range room.Staff {
+		for client := range room.Staff {
 			h.sendToClient(client, event)
 		}
 		// Customer notification is handled by TargetUserID in the event
 	case CafeEventMenuUpdate, CafeEventStopListUpdate:
 		// Broadcast to everyone in the room (staff + customers)
-		for _, client := range room.Staff {
+		for client := range room.Staff {
 			h.sendToClient(client, event)
 		}
-		for _, client := range room.Clients {
+		for client := range room.Clients {
 			h.sendToClient(client, event)
 		}
 	default:
 		// Default: send to staff only
-		for _, client := range room.Staff {
+		for client := range room.Staff {
 			h.sendToClient(client, event)
 		}
 	}
@@ -161,41 +203,43 @@ func (h *CafeHub) sendToUser(userID uint, event CafeEvent) {
 		return
 	}
 
-	h.mainHub.mu.RLock()
-	client, ok := h.mainHub.clients[userID]
-	h.mainHub.mu.RUnlock()
-
-	if ok {
-		h.sendToClient(client, event)
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("[CafeHub] Error marshaling event: %v", err)
+		return
 	}
+	h.mainHub.sendToUser(userID, RawMessage{Data: data})
 }
 
-// JoinCafeRoom adds a client to a cafe room
+// JoinCafeRoom adds a client connection to a cafe room
 func (h *CafeHub) JoinCafeRoom(cafeID uint, client *Client, isStaff bool) {
 	h.mu.Lock()
 	room, exists := h.rooms[cafeID]
 	if !exists {
 		room = &CafeRoom{
 			CafeID:  cafeID,
-			Staff:   make(map[uint]*Client),
-			Clients: make(map[uint]*Client),
+			Staff:   make(map[*Client]struct{}),
+			Clients: make(map[*Client]struct{}),
 		}
 		h.rooms[cafeID] = room
 	}
 	h.mu.Unlock()
 
 	room.mu.Lock()
+	firstStaffConn := false
 	if isStaff {
-		room.Staff[client.UserID] = client
+		firstStaffConn = !room.hasStaffUser(client.UserID)
+		room.Staff[client] = struct{}{}
 		log.Printf("[CafeHub] Staff %d joined cafe %d room", client.UserID, cafeID)
 	} else {
-		room.Clients[client.UserID] = client
+		room.Clients[client] = struct{}{}
 		log.Printf("[CafeHub] Client %d joined cafe %d room", client.UserID, cafeID)
 	}
 	room.mu.Unlock()
 
-	// Notify staff about new staff member
-	if isStaff {
+	// Notify staff about new staff member; further devices of the same user are silent
+	if firstStaffConn {
+		h.touchStaffPresence(cafeID, client.UserID)
 		h.Events <- CafeEvent{
 			Type:      CafeEventStaffJoined,
 			CafeID:    cafeID,
@@ -207,8 +251,9 @@ func (h *CafeHub) JoinCafeRoom(cafeID uint, client *Client, isStaff bool) {
 	}
 }
 
-// LeaveCafeRoom removes a client from a cafe room
-func (h *CafeHub) LeaveCafeRoom(cafeID uint, userID uint) {
+// LeaveCafeRoom removes a client connection from a cafe room. Staff presence is cleared
+// only when the user's last staff connection leaves.
+func (h *CafeHub) LeaveCafeRoom(cafeID uint, client *Client) {
 	h.mu.RLock()
 	room, exists := h.rooms[cafeID]
 	h.mu.RUnlock()
@@ -217,21 +262,23 @@ func (h *CafeHub) LeaveCafeRoom(cafeID uint, userID uint) {
 		return
 	}
 
+	userID := client.UserID
 	room.mu.Lock()
 	wasStaff := false
-	if _, ok := room.Staff[userID]; ok {
-		delete(room.Staff, userID)
-		wasStaff = true
+	if _, ok := room.Staff[client]; ok {
+		delete(room.Staff, client)
+		wasStaff = !room.hasStaffUser(userID)
 		log.Printf("[CafeHub] Staff %d left cafe %d room", userID, cafeID)
 	}
-	if _, ok := room.Clients[userID]; ok {
-		delete(room.Clients, userID)
+	if _, ok := room.Clients[client]; ok {
+		delete(room.Clients, client)
 		log.Printf("[CafeHub] Client %d left cafe %d room", userID, cafeID)
 	}
 	room.mu.Unlock()
 
 	// Notify staff about staff member leaving
 	if wasStaff {
+		h.removeStaffPresence(cafeID, userID)
 		h.Events <- CafeEvent{
 			Type:      CafeEventStaffLeft,
 			CafeID:    cafeID,
@@ -253,8 +300,22 @@ func (h *CafeHub) LeaveCafeRoom(cafeID uint, userID uint) {
 	h.mu.Unlock()
 }
 
-// GetConnectedStaff returns list of connected staff for a cafe
+// GetConnectedStaff returns list of connected staff for a cafe.
+// In cluster mode the shared presence registry is used so staff connected
+// to other instances are included.
 func (h *CafeHub) GetConnectedStaff(cafeID uint) []uint {
+	if staff, ok := h.clusterStaff(cafeID); ok {
+		return staff
+	}
+	return h.localStaff(cafeID)
+}
+
+// GetOnlineStaffCount returns count of online staff for a cafe
+func (h *CafeHub) GetOnlineStaffCount(cafeID uint) int {
+	return len(h.GetConnectedStaff(cafeID))
+}
+
+func (h *CafeHub) localStaff(cafeID uint) []uint {
 	h.mu.RLock()
 	room, exists := h.rooms[cafeID]
 	h.mu.RUnlock()
@@ -267,26 +328,90 @@ func (h *CafeHub) GetConnectedStaff(cafeID uint) []uint {
 	defer room.mu.RUnlock()
 
 	staff := make([]uint, 0, len(room.Staff))
-	for userID := range room.Staff {
-		staff = append(staff, userID)
+	seen := make(map[uint]struct{}, len(room.Staff))
+	for client := range room.Staff {
+		if _, ok := seen[client.UserID]; ok {
+			continue
+		}
+		seen[client.UserID] = struct{}{}
+		staff = append(staff, client.UserID)
 	}
 	return staff
 }
 
-// GetOnlineStaffCount returns count of online staff for a cafe
-func (h *CafeHub) GetOnlineStaffCount(cafeID uint) int {
-	h.mu.RLock()
-	room, exists := h.rooms[cafeID]
-	h.mu.RUnlock()
+// ===== Cluster presence =====
 
-	if !exists {
-		return 0
+func cafeStaffPresenceKey(cafeID uint) string {
+	return fmt.Sprintf("cafe:%d:staff", cafeID)
+}
+
+func (h *CafeHub) broker() ClusterBroker {
+	if h.mainHub == nil {
+		return nil
+	}
+	return h.mainHub.cluster
+}
+
+func (h *CafeHub) clusterStaff(cafeID uint) ([]uint, bool) {
+	broker := h.broker()
+	if broker == nil {
+		return nil, false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	staff, err := broker.ListPresence(ctx, cafeStaffPresenceKey(cafeID))
+	if err != nil {
+		log.Printf("[CafeHub] Presence lookup failed for cafe %d, using local view: %v", cafeID, err)
+		return nil, false
 	}
+	if staff == nil {
+		staff = []uint{}
+	}
+	return staff, true
+}
 
-	room.mu.RLock()
-	defer room.mu.RUnlock()
+func (h *CafeHub) touchStaffPresence(cafeID, userID uint) {
+	broker := h.broker()
+	if broker == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := broker.TouchPresence(ctx, cafeStaffPresenceKey(cafeID), userID, presenceTTL); err != nil {
+		log.Printf("[CafeHub] Presence update failed cafe=%d user=%d: %v", cafeID, userID, err)
+	}
+}
+
+func (h *CafeHub) removeStaffPresence(cafeID, userID uint) {
+	broker := h.broker()
+	if broker == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := broker.RemovePresence(ctx, cafeStaffPresenceKey(cafeID), userID); err != nil {
+		log.Printf("[CafeHub] Presence removal failed cafe=%d user=%d: %v", cafeID, userID, err)
+	}
+}
+
+// runPresenceHeartbeat refreshes presence of locally connected staff before it expires
+func (h *CafeHub) runPresenceHeartbeat() {
+	ticker := time.NewTicker(presenceHeartbeat)
+	defer ticker.Stop()
+	for range ticker.C {
+		h.mu.RLock()
+		cafeIDs := make([]uint, 0, len(h.rooms))
+		for cafeID := range h.rooms {
+			cafeIDs = append(cafeIDs, cafeID)
+		}
+		h.mu.RUnlock()
 
-	return len(room.Staff)
+		for _, cafeID := range cafeIDs {
+			for _, userID := range h.localStaff(cafeID) {
+				h.touchStaffPresence(cafeID, userID)
+			}
+		}
+	}
 }
 
 // RawMessage implements WSMessage for raw JSON data
@@ -302,6 +427,14 @@ func (m RawMessage) GetTargetUserIDs() []uint {
 	return nil
 }
 
+// MarshalJSON writes the pre-encoded payload as-is
+func (m RawMessage) MarshalJSON() ([]byte, error) {
+	if len(m.Data) == 0 {
+		return []byte("null"), nil
+	}
+	return m.Data, nil
+}
+
 // ===== Helper functions to send cafe events =====
 
 // NotifyNewOrder sends new order notification to cafe staff
@@ -0,0 +1,101 @@
+package websocket
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCafeRoomKeepsStaffUntilLastDeviceLeaves(t *testing.T) {
+	hub := NewHub()
+	hub.cluster = &memoryBroker{id: "a", bus: newMemoryBus()}
+	cafe := &CafeHub{rooms: make(map[uint]*CafeRoom), mainHub: hub, Events: make(chan CafeEvent, 16)}
+
+	phone := NewClient(hub, nil, 7, 101)
+	tablet := NewClient(hub, nil, 7, 202)
+	cafe.JoinCafeRoom(1, phone, true)
+	cafe.JoinCafeRoom(1, tablet, true)
+
+	drain := func() []CafeEventType {
+		var types []CafeEventType
+		for {
+			select {
+			case event := <-cafe.Events:
+				types = append(types, event.Type)
+			default:
+				return types
+			}
+		}
+	}
+	if got := drain(); !reflect.DeepEqual(got, []CafeEventType{CafeEventStaffJoined}) {
+		t.Fatalf("events after two devices joined = %v, want one staff_joined", got)
+	}
+	if got := cafe.GetConnectedStaff(1); !reflect.DeepEqual(got, []uint{7}) {
+		t.Fatalf("GetConnectedStaff() = %v, want [7]", got)
+	}
+
+	// Closing one device keeps the user in the room and present
+	cafe.LeaveCafeRoom(1, phone)
+	if got := drain(); len(got) != 0 {
+		t.Fatalf("events after one device left = %v, want none", got)
+	}
+	if got := cafe.GetConnectedStaff(1); !reflect.DeepEqual(got, []uint{7}) {
+		t.Fatalf("GetConnectedStaff() after one device left = %v, want [7]", got)
+	}
+	if got := cafe.localStaff(1); !reflect.DeepEqual(got, []uint{7}) {
+		t.Fatalf("localStaff() after one device left = %v, want [7]", got)
+	}
+
+	cafe.LeaveCafeRoom(1, tablet)
+	if got := drain(); !reflect.DeepEqual(got, []CafeEventType{CafeEventStaffLeft}) {
+		t.Fatalf("events after last device left = %v, want one staff_left", got)
+	}
+	if got := cafe.GetConnectedStaff(1); len(got) != 0 {
+		t.Fatalf("GetConnectedStaff() after last device left = %v, want none", got)
+	}
+	if _, ok := cafe.rooms[1]; ok {
+		t.Fatal("empty cafe room was not removed")
+	}
+}
+
+func TestCafeEventReachesStaffOnOtherInstance(t *testing.T) {
+	bus := newMemoryBus()
+	hubA := NewHub()
+	hubB := NewHub()
+	hubA.EnableCluster(&memoryBroker{id: "a", bus: bus})
+	hubB.EnableCluster(&memoryBroker{id: "b", bus: bus})
+
+	// Attached after the subscribers started, as GetCafeHub does in main
+	cafeB := &CafeHub{rooms: make(map[uint]*CafeRoom), mainHub: hubB, Events: make(chan CafeEvent, 16)}
+	hubB.cafe.Store(cafeB)
+	staff := NewClient(hubB, nil, 7, 101)
+	cafeB.JoinCafeRoom(3, staff, true)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		bus.mu.Lock()
+		ready := len(bus.handlers) == 2
+		bus.mu.Unlock()
+		if ready || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	hubA.publish(clusterKindCafe, CafeEvent{Type: CafeEventNewOrder, CafeID: 3, Timestamp: time.Now()}, nil)
+
+	select {
+	case msg := <-staff.Send:
+		raw, err := json.Marshal(msg)
+		if err != nil {
+			t.Fatalf("marshal delivered event: %v", err)
+		}
+		var event CafeEvent
+		if err := json.Unmarshal(raw, &event); err != nil || event.Type != CafeEventNewOrder || event.CafeID != 3 {
+			t.Fatalf("unexpected delivered event %s (err=%v)", raw, err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("cafe event was not delivered across instances")
+	}
+}
@@ -1,20 +1,86 @@
 package websocket
 
 import (
+	"fmt"
 	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/gofiber/websocket/v2"
 )
 
 type Client struct {
-	Hub    *Hub
-	Conn   *websocket.Conn
-	UserID uint
-	Send   chan WSMessage
+	Hub       *Hub
+	Conn      *websocket.Conn
+	UserID    uint
+	SessionID uint // AuthSession ID from the access token; 0 for legacy tokens
+	Send      chan WSMessage
+
+	connID uint64
+
+	// Inbound commands applied off the read loop by runCommands
+	commandsMu      sync.Mutex
+	pendingReceipts map[receiptKey]uint
+	readings        chan readingCommand
+	wake            chan struct{}
+	done            chan struct{}
+}
+
+// readingCommand is a queued joint reading command; these run in the order received
+type readingCommand struct {
+	action   string
+	roomID   uint
+	targetID uint
+	payload  interface{}
+}
+
+// receiptKey identifies a watermark; acknowledgements for the same key are coalesced
+type receiptKey struct {
+	kind   string
+	roomID uint
+	peerID uint
+}
+
+const (
+	// maxPendingReceipts bounds the distinct conversations a connection may have queued
+	maxPendingReceipts = 64
+	// maxPendingReadings bounds the joint reading commands waiting to be applied
+	maxPendingReadings = 16
+)
+
+var clientConnSeq atomic.Uint64
+
+// NewClient creates a device connection for the user
+func NewClient(hub *Hub, conn *websocket.Conn, userID uint, sessionID uint) *Client {
+	return &Client{
+		Hub:       hub,
+		Conn:      conn,
+		UserID:    userID,
+		SessionID: sessionID,
+		Send:      make(chan WSMessage, 256),
+		connID:    clientConnSeq.Add(1),
+
+		pendingReceipts: make(map[receiptKey]uint),
+		readings:        make(chan readingCommand, maxPendingReadings),
+		wake:            make(chan struct{}, 1),
+		done:            make(chan struct{}),
+	}
+}
+
+// connectionKey identifies the device connection within the user's clients.
+// Connections without a session are never considered the same device.
+func (c *Client) connectionKey() string {
+	if c.SessionID != 0 {
+		return fmt.Sprintf("s%d", c.SessionID)
+	}
+	return fmt.Sprintf("c%d", c.connID)
 }
 
 func (c *Client) ReadPump() {
+	go c.runCommands()
 	defer func() {
+		close(c.done)
 		c.Hub.Unregister <- c
 		c.Conn.Close()
 	}()
@@ -50,12 +116,101 @@ func (c *Client) ReadPump() {
 				Payload:  msg.Payload,
 				SenderID: c.UserID,
 			}
+		case "mark_delivered", "mark_read":
+			c.handleReceipt(strings.TrimPrefix(msg.Type, "mark_"), msg.RoomID, msg.TargetID, msg.Payload)
 		default:
+			if strings.HasPrefix(msg.Type, ReadingCommandPrefix) {
+				c.handleReading(strings.TrimPrefix(msg.Type, ReadingCommandPrefix), msg.RoomID, msg.TargetID, msg.Payload)
+				continue
+			}
 			log.Printf("[WS] Ignored message type: %s", msg.Type)
 		}
 	}
 }
 
+// handleReceipt queues {"type":"mark_read","roomId"|"targetId","payload":{"messageId":N}}
+// for runCommands. Acknowledgements of the same conversation are coalesced to the highest
+// message, so a client that marks every message does not pile up handler calls.
+func (c *Client) handleReceipt(kind string, roomID, peerID uint, payload interface{}) {
+	if c.Hub.receiptHandler == nil {
+		return
+	}
+	var messageID uint
+	if fields, ok := payload.(map[string]interface{}); ok {
+		if raw, ok := fields["messageId"].(float64); ok && raw > 0 {
+			messageID = uint(raw)
+		}
+	}
+
+	key := receiptKey{kind: kind, roomID: roomID, peerID: peerID}
+	c.commandsMu.Lock()
+	queued, ok := c.pendingReceipts[key]
+	switch {
+	case !ok && len(c.pendingReceipts) >= maxPendingReceipts:
+		c.commandsMu.Unlock()
+		log.Printf("[WS] Dropped %s receipt from User %d: too many pending conversations", kind, c.UserID)
+		return
+	case !ok:
+		c.pendingReceipts[key] = messageID
+	case queued != 0 && (messageID == 0 || messageID > queued):
+		// 0 means "up to the latest message" and covers any explicit ID
+		c.pendingReceipts[key] = messageID
+	}
+	c.commandsMu.Unlock()
+
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+// runCommands applies queued commands one at a time until the read loop exits,
+// then flushes what is left so acknowledgements sent right before closing count
+func (c *Client) runCommands() {
+	for {
+		select {
+		case <-c.wake:
+			c.flushReceipts()
+		case cmd := <-c.readings:
+			c.Hub.readingHandler(c.UserID, cmd.action, cmd.roomID, cmd.targetID, cmd.payload)
+		case <-c.done:
+			c.flushReceipts()
+			return
+		}
+	}
+}
+
+func (c *Client) flushReceipts() {
+	c.commandsMu.Lock()
+	pending := c.pendingReceipts
+	c.pendingReceipts = make(map[receiptKey]uint)
+	c.commandsMu.Unlock()
+
+	for key, messageID := range pending {
+		c.Hub.receiptHandler(c.UserID, key.kind, key.roomID, key.peerID, messageID)
+	}
+}
+
+// handleReading queues a joint reading command for runCommands. A client that sends
+// faster than the commands apply gets a reading_error instead of an unbounded backlog.
+func (c *Client) handleReading(action string, roomID, targetID uint, payload interface{}) {
+	if c.Hub.readingHandler == nil || roomID == 0 {
+		return
+	}
+	select {
+	case c.readings <- readingCommand{action: action, roomID: roomID, targetID: targetID, payload: payload}:
+	default:
+		log.Printf("[WS] Dropped reading_%s from User %d in Room %d: command queue is full", action, c.UserID, roomID)
+		c.Hub.BroadcastReading(ReadingEvent{
+			Type:     EventReadingError,
+			RoomID:   roomID,
+			SenderID: c.UserID,
+			Reason:   action,
+			Data:     map[string]string{"error": "Too many reading commands, slow down"},
+		}, c.UserID)
+	}
+}
+
 func (c *Client) WritePump() {
 	defer func() {
 		c.Conn.Close()
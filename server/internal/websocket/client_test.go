@@ -0,0 +1,141 @@
+package websocket
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+type receiptCall struct {
+	kind      string
+	roomID    uint
+	peerID    uint
+	messageID uint
+}
+
+func TestClientCoalescesReceiptsWhileHandlerIsBusy(t *testing.T) {
+	hub := NewHub()
+	release := make(chan struct{})
+	calls := make(chan receiptCall, 64)
+	var once sync.Once
+	hub.OnReceipt(func(userID uint, kind string, roomID, peerID, messageID uint) {
+		calls <- receiptCall{kind, roomID, peerID, messageID}
+		once.Do(func() { <-release })
+	})
+
+	client := NewClient(hub, nil, 7, 1)
+	go client.runCommands()
+
+	client.handleReceipt("read", 3, 0, map[string]interface{}{"messageId": float64(1)})
+	nextReceiptCall(t, calls)
+
+	// The handler is stuck on the first receipt; these collapse into one call
+	for id := 2; id <= 50; id++ {
+		client.handleReceipt("read", 3, 0, map[string]interface{}{"messageId": float64(id)})
+	}
+	client.handleReceipt("read", 3, 0, map[string]interface{}{"messageId": float64(10)})
+	close(release)
+
+	if got, want := nextReceiptCall(t, calls), (receiptCall{"read", 3, 0, 50}); got != want {
+		t.Fatalf("coalesced call = %+v, want %+v", got, want)
+	}
+	close(client.done)
+	select {
+	case extra := <-calls:
+		t.Fatalf("unexpected extra handler call %+v", extra)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func nextReceiptCall(t *testing.T, calls <-chan receiptCall) receiptCall {
+	t.Helper()
+	select {
+	case call := <-calls:
+		return call
+	case <-time.After(2 * time.Second):
+		t.Fatal("receipt handler was not called")
+		return receiptCall{}
+	}
+}
+
+func TestClientReceiptCoalescing(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		queued []uint
+		want   uint
+	}{
+		{name: "highest explicit id", queued: []uint{4, 9, 6}, want: 9},
+		{name: "latest wins over ids", queued: []uint{4, 0, 9}, want: 0},
+		{name: "single", queued: []uint{5}, want: 5},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			hub := NewHub()
+			hub.OnReceipt(func(uint, string, uint, uint, uint) {})
+			client := NewClient(hub, nil, 7, 1)
+			for _, id := range tt.queued {
+				client.handleReceipt("delivered", 0, 8, map[string]interface{}{"messageId": float64(id)})
+			}
+			got := client.pendingReceipts[receiptKey{kind: "delivered", peerID: 8}]
+			if len(client.pendingReceipts) != 1 || got != tt.want {
+				t.Fatalf("pending = %v, want messageId %d", client.pendingReceipts, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientQueuesReadingCommandsInOrder(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+	release := make(chan struct{})
+	actions := make(chan string, maxPendingReadings+2)
+	var once sync.Once
+	hub.OnReading(func(userID uint, action string, roomID, targetID uint, payload interface{}) {
+		actions <- action
+		once.Do(func() { <-release })
+	})
+
+	client := NewClient(hub, nil, 7, 1)
+	hub.Register <- client
+	go client.runCommands()
+	defer close(client.done)
+
+	client.handleReading("advance", 3, 0, nil)
+	if got := <-actions; got != "advance" {
+		t.Fatalf("first action = %q, want advance", got)
+	}
+
+	// The handler is busy: the queue fills up and the next command is rejected
+	for i := 0; i < maxPendingReadings; i++ {
+		client.handleReading(fmt.Sprintf("cmd%d", i), 3, 0, nil)
+	}
+	client.handleReading("overflow", 3, 0, nil)
+
+	select {
+	case msg := <-client.Send:
+		event, ok := msg.(ReadingEvent)
+		if !ok || event.Type != EventReadingError || event.Reason != "overflow" {
+			t.Fatalf("unexpected event %#v, want reading_error for overflow", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("overflowing command was not rejected")
+	}
+
+	close(release)
+	for i := 0; i < maxPendingReadings; i++ {
+		select {
+		case got := <-actions:
+			if want := fmt.Sprintf("cmd%d", i); got != want {
+				t.Fatalf("action %d = %q, want %q", i, got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("queued command %d was not applied", i)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// Cluster envelope kinds
+const (
+	clusterKindHub  = "hub"  // Pre-routed hub message, deliver to TargetUserIDs
+	clusterKindCafe = "cafe" // CafeEvent, every instance runs its own room routing
+)
+
+// Presence entries expire unless refreshed, so a crashed instance
+// stops reporting its users after presenceTTL.
+const (
+	presenceTTL       = 90 * time.Second
+	presenceHeartbeat = 30 * time.Second
+)
+
+// ClusterEnvelope is the wire format exchanged between API instances
+type ClusterEnvelope struct {
+	Origin        string          `json:"origin"`
+	Kind          string          `json:"kind"`
+	Type          string          `json:"type"`
+	SenderID      uint            `json:"senderId,omitempty"`
+	RecipientID   uint            `json:"recipientId,omitempty"`
+	RoomID        uint            `json:"roomId,omitempty"`
+	TargetUserIDs []uint          `json:"targetUserIds,omitempty"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
+// ClusterBroker fans hub traffic out to other API instances and keeps a
+// cross-instance presence registry. See RedisClusterBroker.
+type ClusterBroker interface {
+	InstanceID() string
+	Publish(ctx context.Context, env ClusterEnvelope) error
+	// Subscribe blocks, calling handle for envelopes published by other instances
+	Subscribe(ctx context.Context, handle func(ClusterEnvelope)) error
+	TouchPresence(ctx context.Context, key string, userID uint, ttl time.Duration) error
+	RemovePresence(ctx context.Context, key string, userID uint) error
+	ListPresence(ctx context.Context, key string) ([]uint, error)
+}
+
+// remoteMessage wraps an envelope received from another instance so it can
+// travel through Client.Send and be written verbatim to the socket
+type remoteMessage struct {
+	env ClusterEnvelope
+}
+
+func (m remoteMessage) GetType() string          { return m.env.Type }
+func (m remoteMessage) GetSenderID() uint        { return m.env.SenderID }
+func (m remoteMessage) GetRecipientID() uint     { return m.env.RecipientID }
+func (m remoteMessage) GetRoomID() uint          { return m.env.RoomID }
+func (m remoteMessage) GetTargetUserIDs() []uint { return m.env.TargetUserIDs }
+func (m remoteMessage) MarshalJSON() ([]byte, error) {
+	if len(m.env.Payload) == 0 {
+		return []byte("null"), nil
+	}
+	return m.env.Payload, nil
+}
+
+// EnableCluster switches the hub into cluster mode. Must be called before Run.
+func (h *Hub) EnableCluster(broker ClusterBroker) {
+	if broker == nil {
+		return
+	}
+	h.cluster = broker
+	h.outbound = make(chan ClusterEnvelope, 1024)
+	h.remote = make(chan ClusterEnvelope, 1024)
+
+	go h.runClusterPublisher()
+	go h.runClusterSubscriber()
+	log.Printf("[Hub] Cluster mode enabled (instance=%s)", broker.InstanceID())
+}
+
+// IsClustered reports whether messages are fanned out to other instances
+func (h *Hub) IsClustered() bool {
+	return h.cluster != nil
+}
+
+// publish queues a routed message for other instances. Never blocks the hub loop.
+func (h *Hub) publish(kind string, msg WSMessage, targets []uint) {
+	if h.cluster == nil {
+		return
+	}
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("[Hub] Cluster publish marshal failed type=%s: %v", msg.GetType(), err)
+		return
+	}
+	env := ClusterEnvelope{
+		Origin:        h.cluster.InstanceID(),
+		Kind:          kind,
+		Type:          msg.GetType(),
+		SenderID:      msg.GetSenderID(),
+		RecipientID:   msg.GetRecipientID(),
+		RoomID:        msg.GetRoomID(),
+		TargetUserIDs: targets,
+		Payload:       payload,
+	}
+	select {
+	case h.outbound <- env:
+	default:
+		log.Printf("[Hub] Cluster outbound queue full, dropping type=%s", env.Type)
+	}
+}
+
+func (h *Hub) runClusterPublisher() {
+	for env := range h.outbound {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := h.cluster.Publish(ctx, env); err != nil {
+			log.Printf("[Hub] Cluster publish failed type=%s: %v", env.Type, err)
+		}
+		cancel()
+	}
+}
+
+func (h *Hub) runClusterSubscriber() {
+	for {
+		err := h.cluster.Subscribe(context.Background(), func(env ClusterEnvelope) {
+			if env.Origin == h.cluster.InstanceID() {
+				return
+			}
+			switch env.Kind {
+			case clusterKindHub:
+				select {
+				case h.remote <- env:
+				default:
+					log.Printf("[Hub] Cluster inbound queue full, dropping type=%s", env.Type)
+				}
+			case clusterKindCafe:
+				if cafe := h.cafe.Load(); cafe != nil {
+					cafe.handleRemote(env)
+				}
+			}
+		})
+		log.Printf("[Hub] Cluster subscription ended: %v; reconnecting in 2s", err)
+		time.Sleep(2 * time.Second)
+	}
+}
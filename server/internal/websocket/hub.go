@@ -5,6 +5,7 @@ import (
 	"rag-agent-server/internal/database"
 	"rag-agent-server/internal/models"
 	"sync"
+	"sync/atomic"
 )
 
 type WSMessage interface {
@@ -40,14 +41,62 @@ func (t TypingWrapper) GetTargetUserIDs() []uint {
 	return nil
 }
 
+// MessageEvent types
+const (
+	EventMessageEdited    = "message_edited"
+	EventMessageDeleted   = "message_deleted"
+	EventMessageReaction  = "message_reaction"
+	EventMessageDelivered = "message_delivered"
+	EventMessageRead      = "message_read"
+)
+
+// Dating events reuse MessageEvent; Data carries the match
+const (
+	EventDatingMatch   = "dating_match"
+	EventDatingUnmatch = "dating_unmatch"
+)
+
+// MessageEvent notifies participants about a change to an existing message
+// (edit, delete, reaction). Data carries the event-specific payload.
+type MessageEvent struct {
+	Type          string      `json:"type"`
+	MessageID     uint        `json:"messageId"`
+	SenderID      uint        `json:"senderId"`
+	RecipientID   uint        `json:"recipientId,omitempty"`
+	RoomID        uint        `json:"roomId,omitempty"`
+	Data          interface{} `json:"data,omitempty"`
+	TargetUserIDs []uint      `json:"-"`
+}
+
+func (e MessageEvent) GetType() string          { return e.Type }
+func (e MessageEvent) GetSenderID() uint        { return e.SenderID }
+func (e MessageEvent) GetRecipientID() uint     { return e.RecipientID }
+func (e MessageEvent) GetRoomID() uint          { return e.RoomID }
+func (e MessageEvent) GetTargetUserIDs() []uint { return e.TargetUserIDs }
+
+// ReceiptHandler applies a delivery/read acknowledgement sent by a client.
+// kind is "delivered" or "read"; exactly one of roomID and peerID is set.
+type ReceiptHandler func(userID uint, kind string, roomID, peerID, messageID uint)
+
 type Hub struct {
-	clients    map[uint]*Client
+	// userID -> connection key -> client; a user may be connected from several devices
+	clients    map[uint]map[string]*Client
 	broadcast  chan WSMessage
 	Signal     chan SignalingMessage // Dedicated channel for direct signaling
 	RoomSignal chan RoomSignalingMessage
 	Register   chan *Client
 	Unregister chan *Client
 	mu         sync.RWMutex
+
+	receiptHandler ReceiptHandler
+	readingHandler ReadingHandler
+
+	// Cluster mode (see cluster.go); nil when running as a single instance
+	cluster  ClusterBroker
+	outbound chan ClusterEnvelope
+	remote   chan ClusterEnvelope
+	// Set by GetCafeHub, possibly after the cluster subscriber started
+	cafe atomic.Pointer[CafeHub]
 }
 
 func NewHub() *Hub {
@@ -57,7 +106,7 @@ func NewHub() *Hub {
 		RoomSignal: make(chan RoomSignalingMessage, 256),
 		Register:   make(chan *Client),
 		Unregister: make(chan *Client),
-		clients:    make(map[uint]*Client),
+		clients:    make(map[uint]map[string]*Client),
 	}
 }
 
@@ -65,74 +114,115 @@ func (h *Hub) Run() {
 	for {
 		select {
 		case client := <-h.Register:
-			h.mu.Lock()
-			h.clients[client.UserID] = client
-			h.mu.Unlock()
+			h.register(client)
 		case client := <-h.Unregister:
-			h.mu.Lock()
-			if _, ok := h.clients[client.UserID]; ok {
-				delete(h.clients, client.UserID)
-				close(client.Send)
-			}
-			h.mu.Unlock()
+			h.unregister(client)
 		case message := <-h.broadcast:
-			h.mu.RLock()
-			targetUserIDs := message.GetTargetUserIDs()
-			if len(targetUserIDs) > 0 {
-				for _, userID := range targetUserIDs {
-					client, ok := h.clients[userID]
-					if !ok {
-						continue
-					}
-					select {
-					case client.Send <- message:
-					default:
-					}
-				}
-				h.mu.RUnlock()
-				continue
-			}
-
-			for userID, client := range h.clients {
-				shouldSend := false
-				if message.GetRecipientID() != 0 {
-					if userID == message.GetRecipientID() || userID == message.GetSenderID() {
-						shouldSend = true
-					}
-				} else if message.GetRoomID() != 0 {
-					shouldSend = false
-				}
-
-				if shouldSend {
-					select {
-					case client.Send <- message:
-					default:
-					}
-				}
-			}
-			h.mu.RUnlock()
+			h.dispatch(message, resolveMessageTargets(message))
 		case msg := <-h.Signal:
 			log.Printf("[Hub] Signaling: %s from %d to %d", msg.Type, msg.SenderID, msg.TargetID)
-			h.mu.RLock()
-			if target, ok := h.clients[msg.TargetID]; ok {
-				select {
-				case target.Send <- msg:
-					log.Printf("[Hub] Forwarded %s to User %d", msg.Type, msg.TargetID)
-				default:
-					log.Printf("[Hub] User %d channel full, closing", msg.TargetID)
-					close(target.Send)
-					delete(h.clients, msg.TargetID)
-				}
-			} else {
-				log.Printf("[Hub] Target User %d not connected", msg.TargetID)
-			}
-			h.mu.RUnlock()
+			h.dispatch(msg, msg.GetTargetUserIDs())
 		case msg := <-h.RoomSignal:
 			h.handleRoomSignaling(msg)
+		case env := <-h.remote:
+			h.deliverLocal(env.TargetUserIDs, remoteMessage{env: env})
+		}
+	}
+}
+
+// register adds a device connection. A reconnect from the same auth session
+// replaces the stale connection; other sessions of the user stay connected.
+func (h *Hub) register(client *Client) {
+	key := client.connectionKey()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	devices, ok := h.clients[client.UserID]
+	if !ok {
+		devices = make(map[string]*Client)
+		h.clients[client.UserID] = devices
+	}
+	if previous, exists := devices[key]; exists && previous != client {
+		close(previous.Send)
+		log.Printf("[Hub] User %d session %s reconnected, replacing previous connection", client.UserID, key)
+	}
+	devices[key] = client
+}
+
+// unregister removes only this device connection
+func (h *Hub) unregister(client *Client) {
+	key := client.connectionKey()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	devices, ok := h.clients[client.UserID]
+	if !ok {
+		return
+	}
+	if current, exists := devices[key]; exists && current == client {
+		delete(devices, key)
+		close(client.Send)
+	}
+	if len(devices) == 0 {
+		delete(h.clients, client.UserID)
+	}
+}
+
+// OnReceipt sets the handler for client receipt acknowledgements. Must be called before clients connect.
+func (h *Hub) OnReceipt(handler ReceiptHandler) {
+	h.receiptHandler = handler
+}
+
+// ConnectionCount returns how many devices of the user are connected to this instance
+func (h *Hub) ConnectionCount(userID uint) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients[userID])
+}
+
+// resolveMessageTargets returns the users a hub message is addressed to:
+// explicit targets if set, otherwise both sides of a direct conversation.
+func resolveMessageTargets(message WSMessage) []uint {
+	if targets := message.GetTargetUserIDs(); len(targets) > 0 {
+		return targets
+	}
+	if message.GetRecipientID() != 0 {
+		return uniqueTargetUsers([]uint{message.GetRecipientID(), message.GetSenderID()})
+	}
+	return nil
+}
+
+// dispatch delivers to locally connected targets and, in cluster mode,
+// forwards the routed message to the other instances
+func (h *Hub) dispatch(message WSMessage, targets []uint) {
+	if len(targets) == 0 {
+		return
+	}
+	h.deliverLocal(targets, message)
+	h.publish(clusterKindHub, message, targets)
+}
+
+func (h *Hub) deliverLocal(targets []uint, message WSMessage) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, userID := range targets {
+		for key, client := range h.clients[userID] {
+			select {
+			case client.Send <- message:
+			default:
+				log.Printf("[Hub] User %d connection %s channel full, dropping %s", userID, key, message.GetType())
+			}
 		}
 	}
 }
 
+// sendToUser delivers a message to a single user on any instance
+func (h *Hub) sendToUser(userID uint, message WSMessage) {
+	h.dispatch(message, []uint{userID})
+}
+
 func (h *Hub) handleRoomSignaling(msg RoomSignalingMessage) {
 	if msg.RoomID == 0 || msg.SenderID == 0 {
 		return
@@ -148,18 +238,7 @@ func (h *Hub) handleRoomSignaling(msg RoomSignalingMessage) {
 			log.Printf("[Hub] Room signaling rejected: target %d is not member of room %d", msg.TargetID, msg.RoomID)
 			return
 		}
-		h.mu.RLock()
-		target, ok := h.clients[msg.TargetID]
-		h.mu.RUnlock()
-		if !ok {
-			log.Printf("[Hub] Room target user %d not connected", msg.TargetID)
-			return
-		}
-		select {
-		case target.Send <- msg:
-		default:
-			log.Printf("[Hub] Room signaling drop: target %d channel full", msg.TargetID)
-		}
+		h.dispatch(msg, []uint{msg.TargetID})
 		return
 	}
 
@@ -169,21 +248,13 @@ func (h *Hub) handleRoomSignaling(msg RoomSignalingMessage) {
 		return
 	}
 
-	h.mu.RLock()
-	defer h.mu.RUnlock()
+	targets := make([]uint, 0, len(memberIDs))
 	for _, memberID := range memberIDs {
-		if memberID == msg.SenderID {
-			continue
-		}
-		client, ok := h.clients[memberID]
-		if !ok {
-			continue
-		}
-		select {
-		case client.Send <- msg:
-		default:
+		if memberID != msg.SenderID {
+			targets = append(targets, memberID)
 		}
 	}
+	h.dispatch(msg, targets)
 }
 
 func isRoomMember(roomID uint, userID uint) bool {
@@ -222,6 +293,15 @@ func (h *Hub) Broadcast(msg models.Message, targetUserIDs ...uint) {
 	}
 }
 
+// BroadcastEvent sends a message event to the given users
+func (h *Hub) BroadcastEvent(event MessageEvent, targetUserIDs ...uint) {
+	event.TargetUserIDs = uniqueTargetUsers(targetUserIDs)
+	if len(event.TargetUserIDs) == 0 {
+		return
+	}
+	h.broadcast <- event
+}
+
 func (h *Hub) BroadcastTyping(event models.TypingEvent) {
 	h.broadcast <- TypingWrapper{TypingEvent: event}
 }
@@ -0,0 +1,234 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"rag-agent-server/internal/models"
+)
+
+// memoryBroker is an in-process ClusterBroker shared by several hubs
+type memoryBroker struct {
+	id  string
+	bus *memoryBus
+}
+
+type memoryBus struct {
+	mu       sync.Mutex
+	handlers []func(ClusterEnvelope)
+	presence map[string]map[string]uint
+}
+
+func newMemoryBus() *memoryBus {
+	return &memoryBus{presence: make(map[string]map[string]uint)}
+}
+
+func (b *memoryBroker) InstanceID() string { return b.id }
+
+func (b *memoryBroker) Publish(_ context.Context, env ClusterEnvelope) error {
+	b.bus.mu.Lock()
+	handlers := append([]func(ClusterEnvelope){}, b.bus.handlers...)
+	b.bus.mu.Unlock()
+	for _, handle := range handlers {
+		handle(env)
+	}
+	return nil
+}
+
+func (b *memoryBroker) Subscribe(ctx context.Context, handle func(ClusterEnvelope)) error {
+	b.bus.mu.Lock()
+	b.bus.handlers = append(b.bus.handlers, handle)
+	b.bus.mu.Unlock()
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (b *memoryBroker) TouchPresence(_ context.Context, key string, userID uint, _ time.Duration) error {
+	b.bus.mu.Lock()
+	defer b.bus.mu.Unlock()
+	if b.bus.presence[key] == nil {
+		b.bus.presence[key] = make(map[string]uint)
+	}
+	b.bus.presence[key][fmt.Sprintf("%s:%d", b.id, userID)] = userID
+	return nil
+}
+
+func (b *memoryBroker) RemovePresence(_ context.Context, key string, userID uint) error {
+	b.bus.mu.Lock()
+	defer b.bus.mu.Unlock()
+	delete(b.bus.presence[key], fmt.Sprintf("%s:%d", b.id, userID))
+	return nil
+}
+
+func (b *memoryBroker) ListPresence(_ context.Context, key string) ([]uint, error) {
+	b.bus.mu.Lock()
+	defer b.bus.mu.Unlock()
+	result := make([]uint, 0)
+	for _, userID := range b.bus.presence[key] {
+		result = append(result, userID)
+	}
+	return uniqueTargetUsers(result), nil
+}
+
+func TestResolveMessageTargets(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		msg  WSMessage
+		want []uint
+	}{
+		{
+			name: "explicit targets win",
+			msg:  MessageWrapper{Message: models.Message{SenderID: 1, RoomID: 5}, TargetUserIDs: []uint{2, 3}},
+			want: []uint{2, 3},
+		},
+		{
+			name: "direct message goes to both sides",
+			msg:  MessageWrapper{Message: models.Message{SenderID: 1, RecipientID: 2}},
+			want: []uint{2, 1},
+		},
+		{
+			name: "room message without targets goes nowhere",
+			msg:  MessageWrapper{Message: models.Message{SenderID: 1, RoomID: 5}},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := resolveMessageTargets(tt.msg); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("resolveMessageTargets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePresenceMembers(t *testing.T) {
+	t.Parallel()
+
+	got := parsePresenceMembers([]string{"a:1", "b:1", "b:2", "garbage", "c:x", "d:0"})
+	want := []uint{1, 2}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("parsePresenceMembers() = %v, want %v", got, want)
+	}
+}
+
+func TestClusterDeliversToClientOnOtherInstance(t *testing.T) {
+	bus := newMemoryBus()
+	hubA := NewHub()
+	hubB := NewHub()
+	hubA.EnableCluster(&memoryBroker{id: "a", bus: bus})
+	hubB.EnableCluster(&memoryBroker{id: "b", bus: bus})
+	go hubA.Run()
+	go hubB.Run()
+
+	recipient := NewClient(hubB, nil, 2, 0)
+	hubB.Register <- recipient
+
+	// Wait for both subscribers to attach
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		bus.mu.Lock()
+		ready := len(bus.handlers) == 2
+		bus.mu.Unlock()
+		if ready || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	hubA.Broadcast(models.Message{SenderID: 1, RecipientID: 2, Content: "Hare Krishna"})
+
+	select {
+	case msg := <-recipient.Send:
+		raw, err := json.Marshal(msg)
+		if err != nil {
+			t.Fatalf("marshal delivered message: %v", err)
+		}
+		var decoded models.Message
+		if err := json.Unmarshal(raw, &decoded); err != nil {
+			t.Fatalf("delivered payload is not a message: %v (%s)", err, raw)
+		}
+		if decoded.Content != "Hare Krishna" || decoded.SenderID != 1 {
+			t.Fatalf("unexpected delivered message: %+v", decoded)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("message was not delivered across instances")
+	}
+}
+
+func TestHubFansOutToAllDevicesOfUser(t *testing.T) {
+	hub := NewHub()
+	phone := NewClient(hub, nil, 7, 101)
+	miniApp := NewClient(hub, nil, 7, 202)
+	hub.register(phone)
+	hub.register(miniApp)
+
+	if got := hub.ConnectionCount(7); got != 2 {
+		t.Fatalf("ConnectionCount = %d, want 2", got)
+	}
+
+	hub.dispatch(MessageWrapper{Message: models.Message{SenderID: 1, RecipientID: 7}}, []uint{7})
+	for name, client := range map[string]*Client{"phone": phone, "miniApp": miniApp} {
+		select {
+		case <-client.Send:
+		default:
+			t.Fatalf("%s did not receive the message", name)
+		}
+	}
+
+	// Closing one device keeps the other connected
+	hub.unregister(miniApp)
+	if got := hub.ConnectionCount(7); got != 1 {
+		t.Fatalf("ConnectionCount after unregister = %d, want 1", got)
+	}
+	if _, open := <-miniApp.Send; open {
+		t.Fatal("unregistered client channel should be closed")
+	}
+
+	hub.dispatch(MessageWrapper{Message: models.Message{SenderID: 1, RecipientID: 7}}, []uint{7})
+	select {
+	case <-phone.Send:
+	default:
+		t.Fatal("remaining device did not receive the message")
+	}
+}
+
+func TestHubSameSessionReconnectReplacesConnection(t *testing.T) {
+	hub := NewHub()
+	stale := NewClient(hub, nil, 7, 101)
+	fresh := NewClient(hub, nil, 7, 101)
+	hub.register(stale)
+	hub.register(fresh)
+
+	if got := hub.ConnectionCount(7); got != 1 {
+		t.Fatalf("ConnectionCount = %d, want 1", got)
+	}
+	if _, open := <-stale.Send; open {
+		t.Fatal("replaced client channel should be closed")
+	}
+
+	// Late unregister of the stale connection must not drop the fresh one
+	hub.unregister(stale)
+	if got := hub.ConnectionCount(7); got != 1 {
+		t.Fatalf("ConnectionCount after stale unregister = %d, want 1", got)
+	}
+}
+
+func TestHubLegacyConnectionsWithoutSessionCoexist(t *testing.T) {
+	hub := NewHub()
+	hub.register(NewClient(hub, nil, 9, 0))
+	hub.register(NewClient(hub, nil, 9, 0))
+
+	if got := hub.ConnectionCount(9); got != 2 {
+		t.Fatalf("ConnectionCount = %d, want 2", got)
+	}
+}
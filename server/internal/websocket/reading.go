@@ -0,0 +1,44 @@
+package websocket
+
+// Joint reading events sent to room members
+const (
+	EventReadingState = "reading_state"
+	EventReadingError = "reading_error"
+)
+
+// ReadingCommandPrefix marks client commands for the joint reading protocol,
+// e.g. {"type":"reading_advance","roomId":1,"payload":{"expectVerse":3}}
+const ReadingCommandPrefix = "reading_"
+
+// ReadingEvent carries the joint reading state of a room; Reason names the command that changed it
+type ReadingEvent struct {
+	Type          string      `json:"type"`
+	RoomID        uint        `json:"roomId"`
+	SenderID      uint        `json:"senderId"`
+	Reason        string      `json:"reason,omitempty"`
+	Data          interface{} `json:"data,omitempty"`
+	TargetUserIDs []uint      `json:"-"`
+}
+
+func (e ReadingEvent) GetType() string          { return e.Type }
+func (e ReadingEvent) GetSenderID() uint        { return e.SenderID }
+func (e ReadingEvent) GetRecipientID() uint     { return 0 }
+func (e ReadingEvent) GetRoomID() uint          { return e.RoomID }
+func (e ReadingEvent) GetTargetUserIDs() []uint { return e.TargetUserIDs }
+
+// ReadingHandler applies a joint reading command; action is the type without ReadingCommandPrefix
+type ReadingHandler func(userID uint, action string, roomID, targetID uint, payload interface{})
+
+// OnReading sets the handler for joint reading commands. Must be called before clients connect.
+func (h *Hub) OnReading(handler ReadingHandler) {
+	h.readingHandler = handler
+}
+
+// BroadcastReading sends a joint reading event to the given users
+func (h *Hub) BroadcastReading(event ReadingEvent, targetUserIDs ...uint) {
+	event.TargetUserIDs = uniqueTargetUsers(targetUserIDs)
+	if len(event.TargetUserIDs) == 0 {
+		return
+	}
+	h.broadcast <- event
+}
@@ -0,0 +1,120 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisClusterChannel        = "ws:hub:events"
+	redisClusterPresencePrefix = "ws:presence:"
+)
+
+// RedisClusterBroker implements ClusterBroker on top of Redis pub/sub.
+// Presence is stored in sorted sets: member "<instance>:<userID>", score = expiry unix time.
+type RedisClusterBroker struct {
+	client     *redis.Client
+	instanceID string
+}
+
+// NewRedisClusterBroker creates a broker with a random instance ID
+func NewRedisClusterBroker(client *redis.Client) *RedisClusterBroker {
+	return &RedisClusterBroker{
+		client:     client,
+		instanceID: uuid.NewString(),
+	}
+}
+
+func (b *RedisClusterBroker) InstanceID() string {
+	return b.instanceID
+}
+
+func (b *RedisClusterBroker) Publish(ctx context.Context, env ClusterEnvelope) error {
+	data, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(ctx, redisClusterChannel, data).Err()
+}
+
+func (b *RedisClusterBroker) Subscribe(ctx context.Context, handle func(ClusterEnvelope)) error {
+	pubsub := b.client.Subscribe(ctx, redisClusterChannel)
+	defer pubsub.Close()
+
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return err
+	}
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-ch:
+			if !ok {
+				return errors.New("redis subscription channel closed")
+			}
+			var env ClusterEnvelope
+			if err := json.Unmarshal([]byte(msg.Payload), &env); err != nil {
+				continue
+			}
+			handle(env)
+		}
+	}
+}
+
+func (b *RedisClusterBroker) presenceMember(userID uint) string {
+	return fmt.Sprintf("%s:%d", b.instanceID, userID)
+}
+
+func (b *RedisClusterBroker) TouchPresence(ctx context.Context, key string, userID uint, ttl time.Duration) error {
+	expiresAt := float64(time.Now().Add(ttl).Unix())
+	fullKey := redisClusterPresencePrefix + key
+	pipe := b.client.TxPipeline()
+	pipe.ZAdd(ctx, fullKey, redis.Z{Score: expiresAt, Member: b.presenceMember(userID)})
+	pipe.Expire(ctx, fullKey, 2*ttl)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (b *RedisClusterBroker) RemovePresence(ctx context.Context, key string, userID uint) error {
+	return b.client.ZRem(ctx, redisClusterPresencePrefix+key, b.presenceMember(userID)).Err()
+}
+
+func (b *RedisClusterBroker) ListPresence(ctx context.Context, key string) ([]uint, error) {
+	fullKey := redisClusterPresencePrefix + key
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	if err := b.client.ZRemRangeByScore(ctx, fullKey, "-inf", "("+now).Err(); err != nil {
+		return nil, err
+	}
+	members, err := b.client.ZRange(ctx, fullKey, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	return parsePresenceMembers(members), nil
+}
+
+// parsePresenceMembers extracts unique user IDs from "<instance>:<userID>" members
+func parsePresenceMembers(members []string) []uint {
+	userIDs := make([]uint, 0, len(members))
+	for _, member := range members {
+		idx := strings.LastIndex(member, ":")
+		if idx < 0 {
+			continue
+		}
+		parsed, err := strconv.ParseUint(member[idx+1:], 10, 64)
+		if err != nil || parsed == 0 {
+			continue
+		}
+		userIDs = append(userIDs, uint(parsed))
+	}
+	return uniqueTargetUsers(userIDs)
+}
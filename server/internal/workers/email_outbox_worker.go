@@ -0,0 +1,35 @@
+package workers
+
+import (
+	"context"
+	"log"
+	"rag-agent-server/internal/database"
+	"rag-agent-server/internal/services"
+	"time"
+)
+
+// StartEmailOutboxWorker starts a background worker that delivers queued emails
+// (admin broadcasts etc.) and retries failed ones with backoff
+func StartEmailOutboxWorker() {
+	outbox := services.NewEmailOutboxService(database.DB, services.GetMailer())
+
+	// Register task in scheduler (runs every minute)
+	services.GlobalScheduler.RegisterTask("email_outbox", 1, func() {
+		processEmailOutbox(outbox)
+	})
+	log.Println("[Worker] Email Outbox Worker started (interval: 1m)")
+}
+
+func processEmailOutbox(outbox *services.EmailOutboxService) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Second)
+	defer cancel()
+
+	sent, err := outbox.ProcessDue(ctx, 200)
+	if err != nil {
+		log.Printf("[Worker] Email outbox processing failed: %v", err)
+		return
+	}
+	if sent > 0 {
+		log.Printf("[Worker] Email outbox delivered %d messages", sent)
+	}
+}
@@ -0,0 +1,34 @@
+package workers
+
+import (
+	"log"
+	"rag-agent-server/internal/services"
+	"time"
+)
+
+// StartLKMReconciliationWorker recovers top-ups with lost webhooks and writes the daily
+// reconciliation reports
+func StartLKMReconciliationWorker() {
+	reconciliation := services.NewLKMReconciliationService(services.NewLKMTopupService())
+
+	services.GlobalScheduler.RegisterTask("lkm_topup_recovery", 15, func() {
+		result, err := reconciliation.RecoverStale(time.Now().UTC())
+		if err != nil {
+			log.Printf("[Worker] Error recovering stale top-ups: %v", err)
+			return
+		}
+		if result.Credited > 0 || result.Review > 0 || result.Expired > 0 || result.Failed > 0 {
+			log.Printf("[Worker] Top-up recovery: %d credited, %d to review, %d expired, %d failed",
+				result.Credited, result.Review, result.Expired, result.Failed)
+		}
+	})
+
+	// Hourly, so a missed run catches up and reports are rebuilt until they are final
+	services.GlobalScheduler.RegisterTask("lkm_topup_reconciliation_report", 60, func() {
+		if err := reconciliation.RefreshReports(time.Now().UTC()); err != nil {
+			log.Printf("[Worker] Error building top-up reconciliation report: %v", err)
+		}
+	})
+
+	log.Println("[Worker] LKM Reconciliation Worker started (interval: 15m, report: 60m)")
+}
@@ -0,0 +1,22 @@
+package workers
+
+import (
+	"log"
+	"rag-agent-server/internal/services"
+	"time"
+)
+
+// StartOrderDisputeSLAWorker escalates marketplace disputes whose SLA timers expired
+func StartOrderDisputeSLAWorker() {
+	disputes := services.NewOrderDisputeService()
+	services.GlobalScheduler.RegisterTask("order_dispute_sla", 30, func() {
+		escalated, err := disputes.SweepSLA(time.Now().UTC())
+		if err != nil {
+			log.Printf("[Worker] Error sweeping dispute SLAs: %v", err)
+		}
+		if escalated > 0 {
+			log.Printf("[Worker] Escalated %d disputes past their SLA", escalated)
+		}
+	})
+	log.Println("[Worker] Order Dispute SLA Worker started (interval: 30m)")
+}
@@ -0,0 +1,35 @@
+package workers
+
+import (
+	"log"
+	"rag-agent-server/internal/services"
+	"time"
+)
+
+// StartOrderEscrowWorker refunds escrow orders the seller has not shipped in time
+func StartOrderEscrowWorker() {
+	orders := services.NewOrderService()
+	services.GlobalScheduler.RegisterTask("order_escrow_refunds", 60, func() {
+		refundStaleEscrows(orders)
+	})
+	log.Println("[Worker] Order Escrow Worker started (interval: 60m)")
+}
+
+func refundStaleEscrows(orders *services.OrderService) {
+	refunded, err := orders.RefundStaleEscrows(time.Now())
+	if err != nil {
+		log.Printf("[Worker] Error refunding stale escrow orders: %v", err)
+		return
+	}
+
+	push := services.GetPushService()
+	for _, order := range refunded {
+		amount := order.RegularLkmPaid + order.BonusLkmPaid
+		if err := push.SendOrderEscrowRefunded(order.BuyerID, order.ID, order.OrderNumber, amount); err != nil {
+			log.Printf("[Worker] Escrow refund push for order %d failed: %v", order.ID, err)
+		}
+	}
+	if len(refunded) > 0 {
+		log.Printf("[Worker] Refunded %d unshipped escrow orders", len(refunded))
+	}
+}
@@ -0,0 +1,33 @@
+package workers
+
+import (
+	"log"
+	"rag-agent-server/internal/services"
+	"time"
+)
+
+// StartReadingPlanReminderWorker pushes daily reading plan reminders at each user's chosen local hour
+func StartReadingPlanReminderWorker() {
+	plans := services.NewLibraryPlanService()
+	services.GlobalScheduler.RegisterTask("reading_plan_reminders", 5, func() {
+		sendReadingPlanReminders(plans)
+	})
+	log.Println("[Worker] Reading Plan Reminder Worker started (interval: 5m)")
+}
+
+func sendReadingPlanReminders(plans *services.LibraryPlanService) {
+	reminders, err := plans.ClaimDueReminders(time.Now())
+	if err != nil {
+		log.Printf("[Worker] Error claiming reading plan reminders: %v", err)
+	}
+
+	push := services.GetPushService()
+	for _, r := range reminders {
+		if err := push.SendReadingPlanReminder(r.UserID, r.EnrollmentID, r.PlanTitle, r.DayNumber); err != nil {
+			log.Printf("[Worker] Reading plan reminder for enrollment %d failed: %v", r.EnrollmentID, err)
+		}
+	}
+	if len(reminders) > 0 {
+		log.Printf("[Worker] Sent %d reading plan reminders", len(reminders))
+	}
+}
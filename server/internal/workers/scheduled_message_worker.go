@@ -0,0 +1,75 @@
+package workers
+
+import (
+	"log"
+	"rag-agent-server/internal/services"
+	"rag-agent-server/internal/websocket"
+)
+
+// StartScheduledMessageWorker starts a background worker that delivers scheduled
+// chat messages and deletes self-destructing messages once their TTL has passed
+func StartScheduledMessageWorker(hub *websocket.Hub) {
+	scheduled := services.NewScheduledMessageService()
+	actions := services.NewMessageActionService()
+
+	// Register task in scheduler (runs every minute)
+	services.GlobalScheduler.RegisterTask("scheduled_messages", 1, func() {
+		deliverScheduledMessages(scheduled, hub)
+		expireMessages(actions, hub)
+	})
+	log.Println("[Worker] Scheduled Message Worker started (interval: 1m)")
+}
+
+func deliverScheduledMessages(scheduled *services.ScheduledMessageService, hub *websocket.Hub) {
+	delivered, err := scheduled.DeliverDue(200)
+	if err != nil {
+		log.Printf("[Worker] Scheduled message delivery failed: %v", err)
+		return
+	}
+	for _, item := range delivered {
+		msg := item.Message
+		services.GetMessagePushService().Dispatch(msg, services.MessagePushOptions{
+			RoomName:      item.RoomName,
+			RoomMemberIDs: item.RoomMemberIDs,
+		})
+		if hub == nil {
+			continue
+		}
+		if msg.RoomID != 0 {
+			hub.Broadcast(msg, item.RoomMemberIDs...)
+		} else {
+			hub.Broadcast(msg)
+		}
+	}
+	if len(delivered) > 0 {
+		log.Printf("[Worker] Delivered %d scheduled messages", len(delivered))
+	}
+}
+
+func expireMessages(actions *services.MessageActionService, hub *websocket.Hub) {
+	// Messages expired before a failure are still announced
+	expired, err := actions.ExpireDueMessages(200)
+	if err != nil {
+		log.Printf("[Worker] Message expiry failed: %v", err)
+	}
+	if hub != nil {
+		for _, audience := range expired {
+			msg := audience.Message
+			hub.BroadcastEvent(websocket.MessageEvent{
+				Type:        websocket.EventMessageDeleted,
+				MessageID:   msg.ID,
+				SenderID:    msg.SenderID,
+				RecipientID: msg.RecipientID,
+				RoomID:      msg.RoomID,
+				Data: map[string]interface{}{
+					"scope":     services.MessageDeleteScopeAll,
+					"reason":    "expired",
+					"deletedAt": msg.DeletedForAllAt,
+				},
+			}, audience.TargetUserIDs...)
+		}
+	}
+	if len(expired) > 0 {
+		log.Printf("[Worker] Expired %d self-destructing messages", len(expired))
+	}
+}
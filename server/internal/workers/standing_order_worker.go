@@ -0,0 +1,50 @@
+package workers
+
+import (
+	"fmt"
+	"log"
+	"rag-agent-server/internal/database"
+	"rag-agent-server/internal/models"
+	"rag-agent-server/internal/services"
+	"time"
+)
+
+// StartStandingOrderWorker executes due scheduled and recurring wallet transfers
+func StartStandingOrderWorker() {
+	standingOrders := services.NewWalletStandingOrderService(services.NewWalletService())
+	services.GlobalScheduler.RegisterTask("wallet_standing_orders", 5, func() {
+		runStandingOrders(standingOrders)
+	})
+	log.Println("[Worker] Standing Order Worker started (interval: 5m)")
+}
+
+func runStandingOrders(standingOrders *services.WalletStandingOrderService) {
+	result, err := standingOrders.RunDue(time.Now().UTC())
+	if err != nil {
+		log.Printf("[Worker] Error running standing orders: %v", err)
+		return
+	}
+
+	push := services.GetPushService()
+	for _, order := range result.Paused {
+		if err := push.SendStandingOrderPaused(order.FromUserID, order.ID, order.Amount, recipientName(order.ToUserID)); err != nil {
+			log.Printf("[Worker] Standing order %d pause push failed: %v", order.ID, err)
+		}
+	}
+	if result.Executed > 0 || len(result.Paused) > 0 {
+		log.Printf("[Worker] Standing orders: %d executed, %d paused", result.Executed, len(result.Paused))
+	}
+}
+
+func recipientName(userID uint) string {
+	var user models.User
+	if err := database.DB.Select("id", "spiritual_name", "karmic_name").First(&user, userID).Error; err == nil {
+		if user.SpiritualName != "" {
+			return user.SpiritualName
+		}
+		if user.KarmicName != "" {
+			return user.KarmicName
+		}
+	}
+	return fmt.Sprintf("#%d", userID)
+}